@@ -0,0 +1,146 @@
+package main
+
+import (
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"sort"
+	"strings"
+)
+
+// Annotations are key-value hints attached to catalog objects using SQL
+// comments of the form "-- @key value" or "/* @key */". A comment annotates
+// the object defined on the same line, or if the comment is on a line of
+// its own, the object defined on the line following it:
+//
+//	CREATE TABLE users (
+//	    -- @seed email
+//	    contact text NOT NULL,
+//	    notes text -- @pii
+//	);
+type Annotations map[string]string
+
+// annotationIndex finds the annotations which apply to a location in a
+// single source text.
+type annotationIndex struct {
+	lineIndex
+	byLine map[int]Annotations
+	// commentOnly records lines with nothing but comments on them.
+	commentOnly map[int]bool
+	// idents are the identifiers, in order.
+	idents []identifierToken
+}
+
+func newAnnotationIndex(src string) (*annotationIndex, error) {
+
+	scan, err := pg_query.Scan(src)
+	if err != nil {
+		return nil, err
+	}
+	idx := &annotationIndex{
+		lineIndex:   newLineIndex(src),
+		byLine:      make(map[int]Annotations),
+		commentOnly: make(map[int]bool),
+		idents:      identifierTokens(src, scan.Tokens),
+	}
+	for _, tok := range scan.Tokens {
+		if tok.Token != pg_query.Token_SQL_COMMENT && tok.Token != pg_query.Token_C_COMMENT {
+			continue
+		}
+		anns := parseAnnotations(src[tok.Start:tok.End])
+		if len(anns) == 0 {
+			continue
+		}
+		line := idx.line(int(tok.Start))
+		if strings.TrimSpace(src[idx.lineIndex[line]:tok.Start]) == "" {
+			idx.commentOnly[line] = true
+		}
+		if idx.byLine[line] == nil {
+			idx.byLine[line] = make(Annotations)
+		}
+		for k, v := range anns {
+			idx.byLine[line][k] = v
+		}
+	}
+	return idx, nil
+}
+
+// lineIndex holds the offset each line of a source text starts at.
+type lineIndex []int
+
+func newLineIndex(src string) lineIndex {
+
+	idx := lineIndex{0}
+	for i, r := range src {
+		if r == '\n' {
+			idx = append(idx, i+1)
+		}
+	}
+	return idx
+}
+
+// line returns the zero-based line containing offset.
+func (l lineIndex) line(offset int) int {
+
+	return sort.Search(len(l), func(i int) bool {
+		return l[i] > offset
+	}) - 1
+}
+
+// For returns the annotations applying to an object defined at location,
+// or nil if there are none.
+func (a *annotationIndex) For(location int32) Annotations {
+
+	if a == nil || location < 0 {
+		return nil
+	}
+	var ret Annotations
+	merge := func(anns Annotations) {
+		if len(anns) == 0 {
+			return
+		}
+		if ret == nil {
+			ret = make(Annotations)
+		}
+		for k, v := range anns {
+			ret[k] = v
+		}
+	}
+	line := a.line(int(location))
+	for prev := line - 1; prev >= 0 && a.commentOnly[prev]; prev-- {
+		merge(a.byLine[prev])
+	}
+	if !a.commentOnly[line] {
+		merge(a.byLine[line])
+	}
+	return ret
+}
+
+// parseAnnotations extracts the annotations from the text of a comment.
+func parseAnnotations(comment string) Annotations {
+
+	comment = strings.TrimPrefix(comment, "--")
+	comment = strings.TrimPrefix(comment, "/*")
+	comment = strings.TrimSuffix(comment, "*/")
+	var ret Annotations
+	var key string
+	var value []string
+	flush := func() {
+		if key != "" {
+			ret[key] = strings.Join(value, " ")
+		}
+	}
+	for _, word := range strings.Fields(comment) {
+		if strings.HasPrefix(word, "@") && len(word) > 1 {
+			if ret == nil {
+				ret = make(Annotations)
+			}
+			flush()
+			key, value = word[1:], nil
+			continue
+		}
+		if key != "" {
+			value = append(value, word)
+		}
+	}
+	flush()
+	return ret
+}
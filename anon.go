@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// AnonGenerator writes masking rules for the classified columns of the
+// catalog, as the SECURITY LABELs of postgresql_anonymizer or as JSON for
+// other masking tools, so that copies of production data can be masked
+// without keeping a list of sensitive columns by hand.
+type AnonGenerator struct {
+	// Format is "sql" for SECURITY LABEL statements or "json".
+	Format string
+	// Masks override the masking rule used for a class, such as "MASKED
+	// WITH VALUE NULL".
+	Masks map[string]string
+}
+
+func NewAnonGenerator(fs *flag.FlagSet) Generator {
+
+	g := &AnonGenerator{Masks: make(map[string]string)}
+	fs.StringVar(&g.Format, "anon-format", "sql", "format of the masking rules, one of: sql, json")
+	fs.Func("anon-mask", "masking rule for a class, as class=rule, e.g. email='MASKED WITH VALUE NULL'", func(s string) error {
+		class, rule, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("expected class=rule")
+		}
+		g.Masks[class] = rule
+		return nil
+	})
+	return g
+}
+
+// anonFunctions are the postgresql_anonymizer functions masking the values
+// of string columns of each class. %s is replaced by the column's name.
+var anonFunctions = map[string]string{
+	"address": "anon.fake_address()",
+	"email":   "anon.fake_email()",
+	"name":    "anon.fake_last_name()",
+	"phone":   "anon.partial(%s, 2, $$******$$, 2)",
+	"secret":  "anon.hash(%s)",
+	"ssn":     "anon.partial(%s, 0, $$***-**-$$, 4)",
+}
+
+// AnonRule is the masking rule of a column, as written with -anon-format
+// json.
+type AnonRule struct {
+	Table  string `json:"table"`
+	Column string `json:"column"`
+	Class  string `json:"class"`
+	Rule   string `json:"rule"`
+}
+
+// rule returns the masking rule for col, or empty if none of the masks
+// suits its type.
+func (g *AnonGenerator) rule(col *Column) string {
+
+	class := col.Classification.Class
+	if rule, ok := g.Masks[class]; ok {
+		return rule
+	}
+	stringType := col.ArrayDims == 0 && (col.Type == Text || col.Type == CharacterVarying || col.Type == Character ||
+		col.Type.Name == "citext")
+	dateType := col.ArrayDims == 0 && (col.Type == Date || col.Type == Timestamp || col.Type == Timestamptz)
+	switch {
+	case stringType && anonFunctions[class] != "":
+		return "MASKED WITH FUNCTION " + strings.ReplaceAll(anonFunctions[class], "%s", QuoteIdent(col.Name))
+	case dateType && class == "birth-date":
+		return fmt.Sprintf("MASKED WITH FUNCTION anon.dnoise(%s, interval '6 months')", QuoteIdent(col.Name))
+	case !col.Attrs.NotNull && !col.Attrs.Pkey:
+		return "MASKED WITH VALUE NULL"
+	case stringType:
+		return "MASKED WITH VALUE $$CONFIDENTIAL$$"
+	}
+	return ""
+}
+
+func (g *AnonGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	if g.Format != "sql" && g.Format != "json" {
+		return fmt.Errorf("unknown anon format %q", g.Format)
+	}
+	rules := []AnonRule{}
+	bw := bufio.NewWriter(w)
+	for _, col := range FindColumns(cat) {
+		if col.Classification.Class == "" {
+			continue
+		}
+		rule := g.rule(col)
+		if rule == "" {
+			if g.Format == "sql" {
+				fmt.Fprintf(bw, "-- No mask for %s.%s of class %s and type %s, give one with -anon-mask\n",
+					TableIdent(col.Table), QuoteIdent(col.Name), col.Classification.Class, col.FormatType())
+			}
+			continue
+		}
+		if g.Format == "json" {
+			rules = append(rules, AnonRule{
+				Table:  col.Table.Schema + "." + col.Table.Name,
+				Column: col.Name,
+				Class:  col.Classification.Class,
+				Rule:   rule,
+			})
+			continue
+		}
+		fmt.Fprintf(bw, "SECURITY LABEL FOR anon ON COLUMN %s.%s IS %s;\n", TableIdent(col.Table), QuoteIdent(col.Name), QuoteLiteral(rule))
+	}
+	if g.Format == "json" {
+		enc := json.NewEncoder(bw)
+		enc.SetIndent("", "  ")
+		err := enc.Encode(rules)
+		if err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
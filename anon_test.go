@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestAnonGenerator(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE users (
+    id int PRIMARY KEY,
+    email text NOT NULL,
+    "Phone" varchar(20),
+    birth_date date,
+    api_token text NOT NULL,
+    last_ip inet,
+    ssn_hash bytea NOT NULL -- @classify ssn
+);
+`))
+	(&Classifier{Heuristics: true}).Classify(c.Catalog)
+
+	var sb strings.Builder
+	require.Nil(t, (&AnonGenerator{Format: "sql"}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `SECURITY LABEL FOR anon ON COLUMN users.email IS 'MASKED WITH FUNCTION anon.fake_email()';
+SECURITY LABEL FOR anon ON COLUMN users."Phone" IS 'MASKED WITH FUNCTION anon.partial("Phone", 2, $$******$$, 2)';
+SECURITY LABEL FOR anon ON COLUMN users.birth_date IS 'MASKED WITH FUNCTION anon.dnoise(birth_date, interval ''6 months'')';
+SECURITY LABEL FOR anon ON COLUMN users.api_token IS 'MASKED WITH FUNCTION anon.hash(api_token)';
+SECURITY LABEL FOR anon ON COLUMN users.last_ip IS 'MASKED WITH VALUE NULL';
+-- No mask for users.ssn_hash of class ssn and type bytea, give one with -anon-mask
+`, sb.String())
+
+	sb.Reset()
+	g := &AnonGenerator{Format: "json", Masks: map[string]string{"ssn": "MASKED WITH VALUE $$\\x$$"}}
+	require.Nil(t, g.Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), `"class": "ssn",
+    "rule": "MASKED WITH VALUE $$\\x$$"`)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// API is the compiler for embedders which have neither files nor HTTP.
+// Its methods take SQL as strings and return JSON, as the serve command
+// responds with it, so that they can be called across a boundary which
+// only passes strings. A failure is returned as a ServedError rather than
+// as a Go error. Nothing it does touches the filesystem.
+type API struct {
+	// NewCompiler returns the compiler each call compiles with.
+	NewCompiler func() *Compiler
+	// Classifier classifies the columns of each catalog.
+	Classifier *Classifier
+}
+
+func NewAPI() *API {
+
+	return &API{
+		NewCompiler: func() *Compiler { return NewCompiler() },
+		Classifier:  &Classifier{},
+	}
+}
+
+func (a *API) compile(src string) (*Compiler, error) {
+
+	c := a.NewCompiler()
+	err := c.Compile(src)
+	if err != nil {
+		return c, err
+	}
+	a.Classifier.Classify(c.Catalog)
+	return c, nil
+}
+
+// apiJSON marshals v, which the API's results always can be.
+func apiJSON(v any) []byte {
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		b, _ = json.Marshal(&ServedError{Error: err.Error()})
+	}
+	return b
+}
+
+// Catalog compiles src and returns its catalog, as /catalog does.
+func (a *API) Catalog(src string) []byte {
+
+	c, err := a.compile(src)
+	if err != nil {
+		return apiJSON(newServedError(c, err))
+	}
+	return apiJSON(NewServedCatalog(c.Catalog))
+}
+
+// Lint compiles src and returns its diagnostics and smells, as /lint does.
+func (a *API) Lint(src string) []byte {
+
+	c, err := a.compile(src)
+	return apiJSON(lintDiagnostics(c, err))
+}
+
+// Diff compiles the from and to of req, a DiffRequest, and returns the
+// changes between them, as /diff does.
+func (a *API) Diff(req []byte) []byte {
+
+	var dr DiffRequest
+	err := json.Unmarshal(req, &dr)
+	if err != nil {
+		return apiJSON(&ServedError{Error: err.Error()})
+	}
+	opts, err := dr.options()
+	if err != nil {
+		return apiJSON(&ServedError{Error: err.Error()})
+	}
+	from, err := a.compile(dr.From)
+	if err != nil {
+		return apiJSON(newServedError(from, fmt.Errorf("from: %w", err)))
+	}
+	to, err := a.compile(dr.To)
+	if err != nil {
+		return apiJSON(newServedError(to, fmt.Errorf("to: %w", err)))
+	}
+	return apiJSON(servedChanges(from.Catalog, to.Catalog, opts))
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestAPI(t *testing.T) {
+	api := NewAPI()
+
+	var cat ServedCatalog
+	require.Nil(t, json.Unmarshal(api.Catalog("CREATE TABLE t (id int PRIMARY KEY);"), &cat))
+	require.Len(t, cat.Schemas, 1)
+	assert.Equal(t, "t", cat.Schemas[0].Tables[0].Name)
+
+	var failed ServedError
+	require.Nil(t, json.Unmarshal(api.Catalog("ALTER TABLE missing ADD COLUMN x int;"), &failed))
+	assert.NotEmpty(t, failed.Error)
+	require.Len(t, failed.Diagnostics, 1)
+
+	var diags []*Diagnostic
+	require.Nil(t, json.Unmarshal(api.Lint("CREATE TABLE t (mood mood);"), &diags))
+	assert.Equal(t, []*Diagnostic{
+		{Line: 1, Severity: SeverityWarning, Rule: RuleUnknownType, Message: "unknown type mood, treating it as opaque"},
+	}, diags)
+
+	var changes []ServedChange
+	req, _ := json.Marshal(DiffRequest{From: "CREATE TABLE t (id int);", To: "CREATE TABLE t (id int, name text);"})
+	require.Nil(t, json.Unmarshal(api.Diff(req), &changes))
+	require.Len(t, changes, 1)
+	assert.Equal(t, []string{"ALTER TABLE t ADD COLUMN name text;"}, changes[0].SQL)
+
+	failed = ServedError{}
+	require.Nil(t, json.Unmarshal(api.Diff([]byte(`{"from": "CREATE TABLE t (", "to": ""}`)), &failed))
+	assert.Contains(t, failed.Error, "from: ")
+	failed = ServedError{}
+	require.Nil(t, json.Unmarshal(api.Diff([]byte(`not json`)), &failed))
+	assert.NotEmpty(t, failed.Error)
+}
@@ -0,0 +1,381 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// AtlasGenerator writes the catalog as an Atlas HCL schema, the desired
+// state Atlas plans migrations from. Atlas' open source edition doesn't
+// manage views, sequences or the partitions of a table, so they're left
+// out, as are the objects of extensions.
+type AtlasGenerator struct {
+	// Qualify labels every table and enum with its schema, rather than only
+	// those whose names are used in more than one schema.
+	Qualify bool
+}
+
+func NewAtlasGenerator(fs *flag.FlagSet) Generator {
+
+	g := &AtlasGenerator{}
+	fs.BoolVar(&g.Qualify, "atlas-qualify", false, "qualify every table and enum with its schema, not only those whose names clash")
+	return g
+}
+
+// atlasTypes maps Postgres types to the names Atlas gives them. Types
+// which aren't present are written as sql("...").
+var atlasTypes = map[*PostgresType]string{
+	Bigint:           "bigint",
+	Bigserial:        "bigserial",
+	Bit:              "bit",
+	BitVarying:       "bit_varying",
+	Boolean:          "boolean",
+	Box:              "box",
+	Bytea:            "bytea",
+	Character:        "character",
+	CharacterVarying: "character_varying",
+	CIDR:             "cidr",
+	Circle:           "circle",
+	Date:             "date",
+	Double:           "double_precision",
+	Inet:             "inet",
+	Integer:          "integer",
+	JSON:             "json",
+	JSONB:            "jsonb",
+	Line:             "line",
+	Lseg:             "lseg",
+	Macaddr:          "macaddr",
+	Macaddr8:         "macaddr8",
+	Money:            "money",
+	Numeric:          "numeric",
+	Path:             "path",
+	Point:            "point",
+	Polygon:          "polygon",
+	Real:             "real",
+	Serial:           "serial",
+	Smallint:         "smallint",
+	Smallserial:      "smallserial",
+	Text:             "text",
+	Time:             "time",
+	Timetz:           "timetz",
+	Timestamp:        "timestamp",
+	Timestamptz:      "timestamptz",
+	TSQuery:          "tsquery",
+	TSVector:         "tsvector",
+	UUID:             "uuid",
+	XML:              "xml",
+}
+
+func (g *AtlasGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	a := &atlasWriter{w: bufio.NewWriter(w), cat: cat, qualify: g.Qualify, enums: make(map[string]string)}
+	tableSchemas := make(map[string]int)
+	enumSchemas := make(map[string]int)
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			tableSchemas[t.Name]++
+		}
+		for _, e := range sch.Enums.List() {
+			enumSchemas[e.Name]++
+		}
+	}
+	a.clashes = func(t *Table) bool { return tableSchemas[t.Name] > 1 }
+
+	for _, sch := range cat.Schemas.List() {
+		a.printf(0, "schema %s {", hclString(sch.Name))
+		a.comment(1, sch)
+		a.printf(0, "}")
+		a.printf(0, "")
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			labels, ref := a.labels(sch.Name, e.Name, enumSchemas[e.Name] > 1)
+			a.enums[EnumIdent(e)] = "enum." + ref
+			values := make([]string, 0, len(e.Labels))
+			for _, l := range e.Labels {
+				values = append(values, hclString(l))
+			}
+			a.printf(0, "enum %s {", labels)
+			a.printf(1, "schema = schema.%s", sch.Name)
+			a.printf(1, "values = [%s]", strings.Join(values, ", "))
+			a.printf(0, "}")
+			a.printf(0, "")
+		}
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf != nil || t.Extension != "" {
+				continue
+			}
+			a.table(t)
+		}
+	}
+	return a.w.Flush()
+}
+
+type atlasWriter struct {
+	w       *bufio.Writer
+	cat     *Catalog
+	qualify bool
+	clashes func(t *Table) bool
+	// enums maps the names enums are given as column types to references to
+	// them.
+	enums map[string]string
+}
+
+func (a *atlasWriter) printf(indent int, format string, args ...any) {
+
+	a.w.WriteString(strings.Repeat("  ", indent))
+	fmt.Fprintf(a.w, format, args...)
+	a.w.WriteString("\n")
+}
+
+// labels returns the labels of the block defining the object named name in
+// schema, and how other blocks refer to it.
+func (a *atlasWriter) labels(schema, name string, clashes bool) (string, string) {
+
+	if a.qualify || clashes {
+		return hclString(schema) + " " + hclString(name), schema + "." + name
+	}
+	return hclString(name), name
+}
+
+func (a *atlasWriter) comment(indent int, obj any) {
+
+	if desc, ok := a.cat.Descriptions[obj]; ok && desc.Comment != "" {
+		a.printf(indent, "comment = %s", hclString(desc.Comment))
+	}
+}
+
+func (a *atlasWriter) tableRef(t *Table) string {
+
+	_, ref := a.labels(t.Schema, t.Name, a.clashes(t))
+	return "table." + ref
+}
+
+func (a *atlasWriter) table(t *Table) {
+
+	labels, _ := a.labels(t.Schema, t.Name, a.clashes(t))
+	a.printf(0, "table %s {", labels)
+	a.printf(1, "schema = schema.%s", t.Schema)
+	a.comment(1, t)
+	for _, col := range t.Columns.List() {
+		a.printf(1, "column %s {", hclString(col.Name))
+		a.printf(2, "null = %t", !col.Attrs.NotNull && !col.Attrs.Pkey)
+		a.printf(2, "type = %s", a.columnType(col))
+		if col.Attrs.Default != "" && !isSerial(col.Type) {
+			a.printf(2, "default = sql(%s)", hclString(col.Attrs.Default))
+		}
+		if col.Attrs.Identity != IdentityNone {
+			a.printf(2, "identity {")
+			a.printf(3, "generated = %s", strings.ReplaceAll(identityDefinition(col.Attrs.Identity), " ", "_"))
+			a.printf(2, "}")
+		}
+		a.comment(2, col)
+		a.printf(1, "}")
+	}
+	for _, con := range a.cat.Depends.TableConstraints(t) {
+		switch con.Type {
+		case ConstraintTypePrimary:
+			{
+				a.printf(1, "primary_key {")
+				a.printf(2, "columns = [%s]", a.columnRefs("", con.Constrains))
+				a.printf(1, "}")
+			}
+		case ConstraintTypeUnique:
+			{
+				a.printf(1, "unique %s {", hclString(con.Name))
+				a.printf(2, "columns = [%s]", a.columnRefs("", con.Constrains))
+				a.printf(1, "}")
+			}
+		case ConstraintTypeForeignKey:
+			{
+				a.printf(1, "foreign_key %s {", hclString(con.Name))
+				a.printf(2, "columns = [%s]", a.columnRefs("", con.Constrains))
+				a.printf(2, "ref_columns = [%s]", a.columnRefs(a.tableRef(con.Refers[0].Table)+".", con.Refers))
+				a.printf(2, "on_update = %s", strings.ReplaceAll(con.OnUpdate.String(), " ", "_"))
+				a.printf(2, "on_delete = %s", strings.ReplaceAll(con.OnDelete.String(), " ", "_"))
+				a.printf(1, "}")
+			}
+		case ConstraintTypeCheck:
+			{
+				a.printf(1, "check %s {", hclString(con.Name))
+				a.printf(2, "expr = %s", hclString(con.Expr))
+				a.printf(1, "}")
+			}
+		}
+	}
+	for _, idx := range a.cat.Depends.TableIndexes(t) {
+		a.index(idx)
+	}
+	a.partition(t)
+	a.printf(0, "}")
+	a.printf(0, "")
+}
+
+func (a *atlasWriter) index(idx *Index) {
+
+	a.printf(1, "index %s {", hclString(idx.Name))
+	if idx.Unique {
+		a.printf(2, "unique = true")
+	}
+	if idx.Method != "" && idx.Method != "btree" {
+		a.printf(2, "type = %s", strings.ToUpper(idx.Method))
+	}
+	simple := true
+	for _, elem := range idx.Elems {
+		simple = simple && elem.Column != nil && elem.Opclass == "" && !elem.Descending && !elem.NullsFirst
+	}
+	if simple {
+		cols := make(Columns, 0, len(idx.Elems))
+		for _, elem := range idx.Elems {
+			cols = append(cols, elem.Column)
+		}
+		a.printf(2, "columns = [%s]", a.columnRefs("", cols))
+	} else {
+		for _, elem := range idx.Elems {
+			a.printf(2, "on {")
+			if elem.Column != nil {
+				a.printf(3, "column = column.%s", elem.Column.Name)
+			} else {
+				a.printf(3, "expr = %s", hclString(elem.Expr))
+			}
+			if elem.Descending {
+				a.printf(3, "desc = true")
+			}
+			if elem.Opclass != "" {
+				a.printf(3, "ops = %s", hclString(elem.Opclass))
+			}
+			a.printf(2, "}")
+		}
+	}
+	if len(idx.Include) > 0 {
+		a.printf(2, "include = [%s]", a.columnRefs("", idx.Include))
+	}
+	if idx.Predicate != "" {
+		a.printf(2, "where = %s", hclString(idx.Predicate))
+	}
+	a.printf(1, "}")
+}
+
+var atlasPartitionKey = regexp.MustCompile(`^(RANGE|LIST|HASH) \((.*)\)$`)
+
+// partition writes the partition key of a partitioned table. Parts of the
+// key other than plain columns, such as expressions or columns with an
+// operator class, are written as expressions.
+func (a *atlasWriter) partition(t *Table) {
+
+	m := atlasPartitionKey.FindStringSubmatch(t.PartitionKey)
+	if m == nil {
+		return
+	}
+	a.printf(1, "partition {")
+	a.printf(2, "type = %s", m[1])
+	cols := make(map[string]*Column)
+	for _, col := range t.Columns.List() {
+		cols[QuoteIdent(col.Name)] = col
+	}
+	for _, part := range splitTopLevel(m[2]) {
+		a.printf(2, "by {")
+		if col, ok := cols[part]; ok {
+			a.printf(3, "column = column.%s", col.Name)
+		} else {
+			a.printf(3, "expr = %s", hclString(part))
+		}
+		a.printf(2, "}")
+	}
+	a.printf(1, "}")
+}
+
+// splitTopLevel splits a comma-separated list, such as the parts of a
+// partition key, ignoring commas inside parentheses or quotes.
+func splitTopLevel(s string) []string {
+
+	var parts []string
+	depth, start, quote := 0, 0, rune(0)
+	for i, r := range s {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+		case r == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	return append(parts, strings.TrimSpace(s[start:]))
+}
+
+func (a *atlasWriter) columnType(col *Column) string {
+
+	if col.ArrayDims > 0 {
+		return "sql(" + hclString(col.FormatType()) + ")"
+	}
+	if ref, ok := a.enums[col.Type.Name]; ok {
+		return ref
+	}
+	name, ok := atlasTypes[col.Type]
+	if !ok || col.Type == Interval {
+		return "sql(" + hclString(col.FormatType()) + ")"
+	}
+	switch len(col.TypeMods) {
+	case 0:
+		return name
+	case 1:
+		return fmt.Sprintf("%s(%d)", name, col.TypeMods[0])
+	default:
+		return fmt.Sprintf("%s(%d,%d)", name, col.TypeMods[0], col.TypeMods[1])
+	}
+}
+
+// columnRefs returns references to cols, prefixed with the reference to
+// their table if they're another table's.
+func (a *atlasWriter) columnRefs(table string, cols Columns) string {
+
+	refs := make([]string, 0, len(cols))
+	for _, col := range cols {
+		refs = append(refs, table+"column."+col.Name)
+	}
+	return strings.Join(refs, ", ")
+}
+
+// hclString quotes s as an HCL string, escaping the sequences which would
+// start a template.
+func hclString(s string) string {
+
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i, r := range s {
+		switch {
+		case r == '"' || r == '\\':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		case r == '\n':
+			sb.WriteString(`\n`)
+		case r == '\r':
+			sb.WriteString(`\r`)
+		case r == '\t':
+			sb.WriteString(`\t`)
+		case r < 0x20:
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		case (r == '$' || r == '%') && strings.HasPrefix(s[i+1:], "{"):
+			sb.WriteRune(r)
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestAtlasGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE SCHEMA audit;
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE TABLE users (
+		id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+		username varchar(50) NOT NULL UNIQUE,
+		balance numeric(10, 2) DEFAULT 0 CHECK (balance >= 0),
+		mood mood,
+		tags text[],
+		note text DEFAULT '${x}'
+	);
+	COMMENT ON TABLE users IS 'people who "sign in"';
+	CREATE TABLE audit.users (id serial, user_id bigint REFERENCES users (id) ON DELETE CASCADE);
+	CREATE INDEX users_lower_username ON users (lower(username) DESC);
+	CREATE INDEX users_tags ON users USING gin (tags) WHERE tags IS NOT NULL;
+	CREATE TABLE events (id int, at timestamptz) PARTITION BY RANGE (at);
+	CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&AtlasGenerator{}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `schema "public" {
+}
+
+schema "audit" {
+}
+
+enum "mood" {
+  schema = schema.public
+  values = ["happy", "sad"]
+}
+
+table "public" "users" {
+  schema = schema.public
+  comment = "people who \"sign in\""
+  column "id" {
+    null = false
+    type = bigint
+    identity {
+      generated = ALWAYS
+    }
+  }
+  column "username" {
+    null = false
+    type = character_varying(50)
+  }
+  column "balance" {
+    null = true
+    type = numeric(10,2)
+    default = sql("0")
+  }
+  column "mood" {
+    null = true
+    type = enum.mood
+  }
+  column "tags" {
+    null = true
+    type = sql("text[]")
+  }
+  column "note" {
+    null = true
+    type = text
+    default = sql("'$${x}'")
+  }
+  check "users_balance_check" {
+    expr = "balance >= 0"
+  }
+  primary_key {
+    columns = [column.id]
+  }
+  unique "users_username_key" {
+    columns = [column.username]
+  }
+  index "users_lower_username" {
+    on {
+      expr = "lower(username)"
+      desc = true
+    }
+  }
+  index "users_tags" {
+    type = GIN
+    columns = [column.tags]
+    where = "tags IS NOT NULL"
+  }
+}
+
+table "events" {
+  schema = schema.public
+  column "id" {
+    null = true
+    type = integer
+  }
+  column "at" {
+    null = true
+    type = timestamptz
+  }
+  partition {
+    type = RANGE
+    by {
+      column = column.at
+    }
+  }
+}
+
+table "audit" "users" {
+  schema = schema.audit
+  column "id" {
+    null = true
+    type = serial
+  }
+  column "user_id" {
+    null = true
+    type = bigint
+  }
+  foreign_key "users_user_id_fkey" {
+    columns = [column.user_id]
+    ref_columns = [table.public.users.column.id]
+    on_update = NO_ACTION
+    on_delete = CASCADE
+  }
+}
+
+`, sb.String())
+
+	sb.Reset()
+	require.Nil(t, (&AtlasGenerator{Qualify: true}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), `enum "public" "mood" {`)
+	assert.Contains(t, sb.String(), `table "public" "events" {`)
+	assert.Contains(t, sb.String(), `type = enum.public.mood`)
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"time"
+)
+
+// SyntheticSchema describes a schema generated to measure the compiler
+// with, standing in for the large schemas of real applications.
+type SyntheticSchema struct {
+	Tables  int
+	Columns int
+	// Seed makes the schema generated the same each time.
+	Seed uint64
+}
+
+var syntheticTypes = []string{"bigint", "integer", "text", "varchar(255)", "numeric(12, 2)", "boolean",
+	"timestamp with time zone", "date", "jsonb", "uuid", "text[]", "mood"}
+
+// SQL returns the statements creating the schema: an enum, the tables with
+// a primary key, a foreign key to an earlier table and an index each, and
+// migrations altering some of them, as a schema built up over time has.
+func (s SyntheticSchema) SQL() string {
+
+	rng := rand.New(rand.NewPCG(s.Seed, s.Seed))
+	var sb strings.Builder
+	sb.WriteString("CREATE TYPE mood AS ENUM ('happy', 'sad', 'ok');\n")
+	for i := 0; i < s.Tables; i++ {
+		fmt.Fprintf(&sb, "CREATE TABLE t%d (\n    id bigserial PRIMARY KEY", i)
+		for j := 0; j < s.Columns; j++ {
+			notNull := ""
+			if rng.IntN(3) == 0 {
+				notNull = " NOT NULL"
+			}
+			fmt.Fprintf(&sb, ",\n    c%d %s%s", j, syntheticTypes[rng.IntN(len(syntheticTypes))], notNull)
+		}
+		if i > 0 {
+			fmt.Fprintf(&sb, ",\n    parent_id bigint REFERENCES t%d (id)", rng.IntN(i))
+		}
+		sb.WriteString("\n);\n")
+		fmt.Fprintf(&sb, "CREATE INDEX t%d_c0_idx ON t%d (c0);\n", i, i)
+		if rng.IntN(4) == 0 {
+			fmt.Fprintf(&sb, "ALTER TABLE t%d ADD COLUMN added text DEFAULT 'x', ADD CONSTRAINT t%d_c0_key UNIQUE (c0);\n", i, i)
+		}
+		if rng.IntN(8) == 0 {
+			fmt.Fprintf(&sb, "ALTER TABLE t%d RENAME COLUMN c0 TO renamed;\n", i)
+		}
+	}
+	return sb.String()
+}
+
+// Changed returns the statements changing the schema, for diffing it
+// against the schema changed: columns and indexes are added to some of
+// its tables, columns are dropped from others, and a table is added.
+func (s SyntheticSchema) Changed() string {
+
+	rng := rand.New(rand.NewPCG(s.Seed, s.Seed+1))
+	var sb strings.Builder
+	for i := 0; i < s.Tables; i++ {
+		switch rng.IntN(10) {
+		case 0:
+			fmt.Fprintf(&sb, "ALTER TABLE t%d ADD COLUMN new_column integer NOT NULL DEFAULT 0;\n", i)
+		case 1:
+			fmt.Fprintf(&sb, "ALTER TABLE t%d DROP COLUMN c%d CASCADE;\n", i, 1+rng.IntN(max(s.Columns-1, 1)))
+		case 2:
+			fmt.Fprintf(&sb, "CREATE INDEX t%d_c1_idx ON t%d (c1);\n", i, i)
+		}
+	}
+	fmt.Fprintf(&sb, "CREATE TABLE added (id bigserial PRIMARY KEY, t0_id bigint REFERENCES t0 (id));\n")
+	return sb.String()
+}
+
+// compileSynthetic compiles the statements of a synthetic schema in order.
+func compileSynthetic(sql ...string) (*Compiler, error) {
+
+	c := NewCompiler()
+	for _, s := range sql {
+		err := c.Compile(s)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func runBench(args []string) error {
+
+	fs := flag.NewFlagSet("bench", flag.ExitOnError)
+	tables := fs.Int("tables", 1000, "number of tables in the schema generated")
+	columns := fs.Int("columns", 20, "number of columns of each table")
+	seed := fs.Uint64("seed", 1, "seed of the schema generated")
+	iterations := fs.Int("n", 5, "number of times to compile and diff the schema")
+	cpuProfile := fs.String("cpuprofile", "", "file to write a CPU profile to")
+	memProfile := fs.String("memprofile", "", "file to write a heap profile to, once done")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *iterations < 1 {
+		return fmt.Errorf("-n must be at least 1")
+	}
+	if *tables < 1 || *columns < 2 {
+		return fmt.Errorf("the schema needs at least 1 table of 2 columns")
+	}
+	schema := SyntheticSchema{Tables: *tables, Columns: *columns, Seed: *seed}
+	from, changes := schema.SQL(), schema.Changed()
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		err = pprof.StartCPUProfile(f)
+		if err != nil {
+			return err
+		}
+		defer pprof.StopCPUProfile()
+	}
+	var compileTime, diffTime time.Duration
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	var diffs int
+	for i := 0; i < *iterations; i++ {
+		start := time.Now()
+		fromC, err := compileSynthetic(from)
+		if err != nil {
+			return fmt.Errorf("while compiling the schema: %w", err)
+		}
+		compileTime += time.Since(start)
+		toC, err := compileSynthetic(from, changes)
+		if err != nil {
+			return fmt.Errorf("while compiling the changed schema: %w", err)
+		}
+		start = time.Now()
+		diffs = len(Diff(fromC.Catalog, toC.Catalog, DiffOptions{}))
+		diffTime += time.Since(start)
+	}
+	runtime.ReadMemStats(&after)
+
+	n := time.Duration(*iterations)
+	fmt.Printf("schema: %d tables of %d columns, %d bytes of SQL\n", *tables, *columns, len(from))
+	fmt.Printf("compile: %s per run\n", compileTime/n)
+	fmt.Printf("diff: %s per run, %d changes\n", diffTime/n, diffs)
+	fmt.Printf("allocated: %d bytes in %d allocations per run, compiling both schemas and diffing them\n",
+		(after.TotalAlloc-before.TotalAlloc)/uint64(*iterations), (after.Mallocs-before.Mallocs)/uint64(*iterations))
+
+	if *memProfile != "" {
+		f, err := os.Create(*memProfile)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		runtime.GC()
+		return pprof.WriteHeapProfile(f)
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"testing"
+)
+
+func TestSyntheticSchema(t *testing.T) {
+	schema := SyntheticSchema{Tables: 50, Columns: 10, Seed: 1}
+	assert.Equal(t, schema.SQL(), schema.SQL())
+	from, err := compileSynthetic(schema.SQL())
+	require.Nil(t, err)
+	to, err := compileSynthetic(schema.SQL(), schema.Changed())
+	require.Nil(t, err)
+	sch, _ := from.Catalog.Schemas.Get("public")
+	assert.Len(t, sch.Tables.List(), 50)
+	assert.NotEmpty(t, Diff(from.Catalog, to.Catalog, DiffOptions{}))
+}
+
+var benchSchemas = []struct {
+	name   string
+	schema SyntheticSchema
+}{
+	{"100x20", SyntheticSchema{Tables: 100, Columns: 20, Seed: 1}},
+	{"1000x20", SyntheticSchema{Tables: 1000, Columns: 20, Seed: 1}},
+}
+
+func BenchmarkCompile(b *testing.B) {
+	for _, bs := range benchSchemas {
+		sql := bs.schema.SQL()
+		b.Run(bs.name, func(b *testing.B) {
+			b.SetBytes(int64(len(sql)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := compileSynthetic(sql)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDiff(b *testing.B) {
+	for _, bs := range benchSchemas {
+		from, err := compileSynthetic(bs.schema.SQL())
+		if err != nil {
+			b.Fatal(err)
+		}
+		to, err := compileSynthetic(bs.schema.SQL(), bs.schema.Changed())
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(bs.name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				Diff(from.Catalog, to.Catalog, DiffOptions{})
+			}
+		})
+	}
+}
+
+func BenchmarkDDL(b *testing.B) {
+	c, err := compileSynthetic(benchSchemas[1].schema.SQL())
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		err := (&DDLGenerator{}).Generate(io.Discard, c.Catalog)
+		if err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"maps"
+	"slices"
+)
+
+// checkpoint is a copy of the state of a catalog, which it's restored to
+// when a statement fails partway through being applied. Objects are copied
+// by value and restored in place, so pointers to them held elsewhere still
+// point at the catalog's objects afterwards.
+type checkpoint struct {
+	cat         *Catalog
+	catalog     Catalog
+	depends     Depends
+	schemas     map[*Schema]Schema
+	tables      map[*Table]Table
+	columns     map[*Column]Column
+	attrs       map[*Column]ColumnAttributes
+	constraints map[*Constraint]Constraint
+	indexes     map[*Index]Index
+	statistics  map[*Statistics]Statistics
+	sequences   map[*Sequence]Sequence
+	raws        map[*RawStatement]RawStatement
+}
+
+func (c *Catalog) checkpoint() *checkpoint {
+
+	cp := &checkpoint{
+		cat:         c,
+		catalog:     *c,
+		depends:     *c.Depends,
+		schemas:     make(map[*Schema]Schema),
+		tables:      make(map[*Table]Table),
+		columns:     make(map[*Column]Column),
+		attrs:       make(map[*Column]ColumnAttributes),
+		constraints: make(map[*Constraint]Constraint),
+		indexes:     make(map[*Index]Index),
+		statistics:  make(map[*Statistics]Statistics),
+		sequences:   make(map[*Sequence]Sequence),
+		raws:        make(map[*RawStatement]RawStatement),
+	}
+	cp.catalog.Schemas = c.Schemas.Clone()
+	cp.catalog.EventTriggers = c.EventTriggers.Clone()
+	cp.catalog.Raw = slices.Clone(c.Raw)
+	cp.catalog.Descriptions = make(map[any]*Description, len(c.Descriptions))
+	for obj, desc := range c.Descriptions {
+		saved := *desc
+		saved.Labels = maps.Clone(desc.Labels)
+		cp.catalog.Descriptions[obj] = &saved
+	}
+	d := &cp.depends
+	d.ConstraintsByColumn = d.ConstraintsByColumn.Clone()
+	d.ConstraintsByName = maps.Clone(d.ConstraintsByName)
+	d.IndexesByColumn = d.IndexesByColumn.Clone()
+	d.IndexesByName = maps.Clone(d.IndexesByName)
+	d.StatisticsByColumn = d.StatisticsByColumn.Clone()
+	d.StatisticsByName = maps.Clone(d.StatisticsByName)
+	d.dependents = d.dependents.Clone()
+	d.dependencies = d.dependencies.Clone()
+
+	for _, sch := range c.Schemas.List() {
+		saved := *sch
+		saved.Tables, saved.Enums, saved.Sequences = sch.Tables.Clone(), sch.Enums.Clone(), sch.Sequences.Clone()
+		cp.schemas[sch] = saved
+		for _, t := range sch.Tables.List() {
+			saved := *t
+			saved.Columns = t.Columns.Clone()
+			if t.Partitions != nil {
+				saved.Partitions = t.Partitions.Clone()
+			}
+			cp.tables[t] = saved
+			for _, col := range t.Columns.List() {
+				cp.columns[col] = *col
+				cp.attrs[col] = *col.Attrs
+			}
+		}
+		for _, seq := range sch.Sequences.List() {
+			cp.sequences[seq] = *seq
+		}
+	}
+	for _, con := range c.Depends.ConstraintsByName {
+		saved := *con
+		saved.Refers = slices.Clone(con.Refers)
+		saved.Constrains = slices.Clone(con.Constrains)
+		saved.SetColumns = slices.Clone(con.SetColumns)
+		cp.constraints[con] = saved
+	}
+	for _, idx := range c.Depends.IndexesByName {
+		saved := *idx
+		saved.Elems = slices.Clone(idx.Elems)
+		saved.Include = slices.Clone(idx.Include)
+		saved.Columns = slices.Clone(idx.Columns)
+		cp.indexes[idx] = saved
+	}
+	for _, stat := range c.Depends.StatisticsByName {
+		saved := *stat
+		saved.Elems = slices.Clone(stat.Elems)
+		saved.Columns = slices.Clone(stat.Columns)
+		cp.statistics[stat] = saved
+	}
+	for _, raw := range c.Raw {
+		saved := *raw
+		saved.Columns = slices.Clone(raw.Columns)
+		saved.Depends = slices.Clone(raw.Depends)
+		cp.raws[raw] = saved
+	}
+	return cp
+}
+
+// restore puts the catalog back into the state it was in when the
+// checkpoint was taken. Objects created since are dropped from it.
+func (cp *checkpoint) restore() {
+
+	c := cp.cat
+	*c = cp.catalog
+	*c.Depends = cp.depends
+	for sch, saved := range cp.schemas {
+		*sch = saved
+	}
+	for t, saved := range cp.tables {
+		*t = saved
+	}
+	for col, saved := range cp.columns {
+		*col = saved
+		*col.Attrs = cp.attrs[col]
+	}
+	for con, saved := range cp.constraints {
+		*con = saved
+	}
+	for idx, saved := range cp.indexes {
+		*idx = saved
+	}
+	for stat, saved := range cp.statistics {
+		*stat = saved
+	}
+	for seq, saved := range cp.sequences {
+		*seq = saved
+	}
+	for raw, saved := range cp.raws {
+		*raw = saved
+	}
+}
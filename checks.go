@@ -0,0 +1,300 @@
+package main
+
+import (
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"strconv"
+)
+
+// CheckRuleKind is the kind of rule on a column's values a check
+// constraint expresses.
+type CheckRuleKind int
+
+const (
+	// CheckRuleMin and CheckRuleMax bound a number by Value, excluding it
+	// if Exclusive is set.
+	CheckRuleMin CheckRuleKind = iota
+	CheckRuleMax
+	// CheckRuleMinLength and CheckRuleMaxLength bound the length of a
+	// string by Value, which is an integer.
+	CheckRuleMinLength
+	CheckRuleMaxLength
+	// CheckRuleOneOf limits a column to Values.
+	CheckRuleOneOf
+	// CheckRulePattern requires a string to match the POSIX regular
+	// expression Value.
+	CheckRulePattern
+)
+
+// CheckRule is a rule on a single column's values, which other tools can
+// enforce too.
+type CheckRule struct {
+	Column    *Column
+	Kind      CheckRuleKind
+	Value     string
+	Values    []string
+	Exclusive bool
+}
+
+// checkBound is the tightest bound on one side of a number.
+type checkBound struct {
+	value     string
+	exclusive bool
+}
+
+// tighten replaces the bound with value if it's tighter, as a lower bound
+// or, if upper is set, an upper one.
+func (b *checkBound) tighten(value string, exclusive bool, upper bool) {
+
+	v, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return
+	}
+	if b.value != "" {
+		cur, _ := strconv.ParseFloat(b.value, 64)
+		if (upper && v > cur) || (!upper && v < cur) || (v == cur && !exclusive) {
+			return
+		}
+	}
+	b.value, b.exclusive = value, exclusive
+}
+
+// op returns the name of the comparison with the bound, given the name of
+// the exclusive comparison, such as gt, which is suffixed with e if the
+// bound is inclusive. It's empty if there is no bound.
+func (b *checkBound) op(exclusive string) string {
+
+	switch {
+	case b.value == "":
+		return ""
+	case b.exclusive:
+		return exclusive
+	default:
+		return exclusive + "e"
+	}
+}
+
+// CheckRules returns the rules the check constraint con is made of, or
+// false if any part of it isn't one. The rules understood are comparisons
+// of a column or its length with a constant, BETWEEN, IN or = ANY with a
+// list of constants, a comparison with the empty string and matching a
+// regular expression with ~, combined with AND.
+func CheckRules(con *Constraint) ([]*CheckRule, bool) {
+
+	if con.Type != ConstraintTypeCheck || con.expr == nil {
+		return nil, false
+	}
+	var rules []*CheckRule
+	ok := checkRules(con.Table, con.expr, &rules)
+	return rules, ok
+}
+
+func checkRules(t *Table, n *pg_query.Node, rules *[]*CheckRule) bool {
+
+	switch n := n.Node.(type) {
+	case *pg_query.Node_BoolExpr:
+		{
+			if n.BoolExpr.Boolop != pg_query.BoolExprType_AND_EXPR {
+				return false
+			}
+			for _, arg := range n.BoolExpr.Args {
+				if !checkRules(t, arg, rules) {
+					return false
+				}
+			}
+			return true
+		}
+	case *pg_query.Node_AExpr:
+		return checkRuleExpr(t, n.AExpr, rules)
+	}
+	return false
+}
+
+// checkComparisons reverses the comparison operators, for constants
+// compared with a column.
+var checkComparisons = map[string]string{"<": ">", "<=": ">=", ">": "<", ">=": "<=", "=": "=", "<>": "<>"}
+
+func checkRuleExpr(t *Table, e *pg_query.A_Expr, rules *[]*CheckRule) bool {
+
+	if len(e.Name) != 1 {
+		return false
+	}
+	op, err := NodeString(e.Name[0])
+	if err != nil {
+		return false
+	}
+	add := func(col *Column, kind CheckRuleKind, value string, exclusive bool) {
+		*rules = append(*rules, &CheckRule{Column: col, Kind: kind, Value: value, Exclusive: exclusive})
+	}
+	switch e.Kind {
+	case pg_query.A_Expr_Kind_AEXPR_OP:
+		{
+			lexpr, rexpr := e.Lexpr, e.Rexpr
+			if checkColumn(t, lexpr) == nil && checkLength(t, lexpr) == nil {
+				lexpr, rexpr, op = rexpr, lexpr, checkComparisons[op]
+			}
+			if col := checkColumn(t, lexpr); col != nil {
+				if s, ok := checkString(rexpr); ok {
+					switch {
+					case op == "<>" && s == "":
+						add(col, CheckRuleMinLength, "1", false)
+					case op == "=":
+						*rules = append(*rules, &CheckRule{Column: col, Kind: CheckRuleOneOf, Values: []string{s}})
+					case op == "~":
+						add(col, CheckRulePattern, s, false)
+					default:
+						return false
+					}
+					return true
+				}
+				num, ok := checkNumber(rexpr)
+				if !ok {
+					return false
+				}
+				switch op {
+				case ">", ">=":
+					add(col, CheckRuleMin, num, op == ">")
+				case "<", "<=":
+					add(col, CheckRuleMax, num, op == "<")
+				case "=":
+					*rules = append(*rules, &CheckRule{Column: col, Kind: CheckRuleOneOf, Values: []string{num}})
+				default:
+					return false
+				}
+				return true
+			}
+			col := checkLength(t, lexpr)
+			num, ok := checkNumber(rexpr)
+			if col == nil || !ok {
+				return false
+			}
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return false
+			}
+			switch op {
+			case ">":
+				add(col, CheckRuleMinLength, strconv.Itoa(n+1), false)
+			case ">=":
+				add(col, CheckRuleMinLength, num, false)
+			case "<":
+				add(col, CheckRuleMaxLength, strconv.Itoa(n-1), false)
+			case "<=":
+				add(col, CheckRuleMaxLength, num, false)
+			case "=":
+				add(col, CheckRuleMinLength, num, false)
+				add(col, CheckRuleMaxLength, num, false)
+			default:
+				return false
+			}
+			return true
+		}
+	case pg_query.A_Expr_Kind_AEXPR_BETWEEN:
+		{
+			col := checkColumn(t, e.Lexpr)
+			bounds := e.Rexpr.GetList().GetItems()
+			if col == nil || len(bounds) != 2 {
+				return false
+			}
+			low, lok := checkNumber(bounds[0])
+			high, hok := checkNumber(bounds[1])
+			if !lok || !hok {
+				return false
+			}
+			add(col, CheckRuleMin, low, false)
+			add(col, CheckRuleMax, high, false)
+			return true
+		}
+	case pg_query.A_Expr_Kind_AEXPR_IN, pg_query.A_Expr_Kind_AEXPR_OP_ANY:
+		{
+			col := checkColumn(t, e.Lexpr)
+			if col == nil || op != "=" {
+				return false
+			}
+			var items []*pg_query.Node
+			if e.Kind == pg_query.A_Expr_Kind_AEXPR_IN {
+				items = e.Rexpr.GetList().GetItems()
+			} else {
+				items = checkUncast(e.Rexpr).GetAArrayExpr().GetElements()
+			}
+			if len(items) == 0 {
+				return false
+			}
+			rule := &CheckRule{Column: col, Kind: CheckRuleOneOf}
+			for _, item := range items {
+				value, ok := checkString(item)
+				if !ok {
+					value, ok = checkNumber(item)
+				}
+				if !ok {
+					return false
+				}
+				rule.Values = append(rule.Values, value)
+			}
+			*rules = append(*rules, rule)
+			return true
+		}
+	}
+	return false
+}
+
+// checkUncast returns n without the casts around it, which pg_dump adds to
+// the constants of check constraints.
+func checkUncast(n *pg_query.Node) *pg_query.Node {
+
+	for n.GetTypeCast() != nil {
+		n = n.GetTypeCast().Arg
+	}
+	return n
+}
+
+// checkColumn returns the column of t n refers to, or nil if it's not a
+// column reference.
+func checkColumn(t *Table, n *pg_query.Node) *Column {
+
+	ref := checkUncast(n).GetColumnRef()
+	if ref == nil {
+		return nil
+	}
+	name := ref.Fields[len(ref.Fields)-1].GetString_()
+	if name == nil {
+		return nil
+	}
+	col, _ := t.Columns.Get(name.Sval)
+	return col
+}
+
+// checkLength returns the column whose length n is, or nil if it's not a
+// call of length or char_length on a column.
+func checkLength(t *Table, n *pg_query.Node) *Column {
+
+	call := checkUncast(n).GetFuncCall()
+	if call == nil || len(call.Args) != 1 || len(call.Funcname) == 0 {
+		return nil
+	}
+	name, err := NodeString(call.Funcname[len(call.Funcname)-1])
+	if err != nil || (name != "length" && name != "char_length" && name != "character_length") {
+		return nil
+	}
+	return checkColumn(t, call.Args[0])
+}
+
+func checkNumber(n *pg_query.Node) (string, bool) {
+
+	c := checkUncast(n).GetAConst()
+	switch {
+	case c.GetIval() != nil:
+		return strconv.Itoa(int(c.GetIval().Ival)), true
+	case c.GetFval() != nil:
+		return c.GetFval().Fval, true
+	}
+	return "", false
+}
+
+func checkString(n *pg_query.Node) (string, bool) {
+
+	c := checkUncast(n).GetAConst()
+	if c.GetSval() == nil {
+		return "", false
+	}
+	return c.GetSval().Sval, true
+}
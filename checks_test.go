@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestCheckRules(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE t (
+		n numeric CONSTRAINT n_range CHECK (0 < n AND n <= 9.5),
+		code varchar(10) CONSTRAINT code_length CHECK (char_length((code)::text) > 2),
+		state varchar(10) CONSTRAINT state_values CHECK (((state)::text = ANY ((ARRAY['on'::character varying, 'off'::character varying])::text[]))),
+		kind text CONSTRAINT kind_either CHECK (kind = 'a' OR kind = 'b'),
+		CONSTRAINT not_column CHECK (now() > '2000-01-01')
+	);
+	`)
+	rules := func(name string) ([]CheckRule, bool) {
+		con := constraintsByName(c)[name]
+		ptrs, ok := CheckRules(con)
+		var ret []CheckRule
+		for _, rule := range ptrs {
+			ret = append(ret, *rule)
+		}
+		return ret, ok
+	}
+	tab := assertTable(t, c, "public.t")
+	col := func(name string) *Column {
+		col, _ := tab.Columns.Get(name)
+		return col
+	}
+
+	got, ok := rules("n_range")
+	assert.True(t, ok)
+	assert.Equal(t, []CheckRule{
+		{Column: col("n"), Kind: CheckRuleMin, Value: "0", Exclusive: true},
+		{Column: col("n"), Kind: CheckRuleMax, Value: "9.5"},
+	}, got)
+	got, ok = rules("code_length")
+	assert.True(t, ok)
+	assert.Equal(t, []CheckRule{{Column: col("code"), Kind: CheckRuleMinLength, Value: "3"}}, got)
+	got, ok = rules("state_values")
+	assert.True(t, ok)
+	assert.Equal(t, []CheckRule{{Column: col("state"), Kind: CheckRuleOneOf, Values: []string{"on", "off"}}}, got)
+	_, ok = rules("kind_either")
+	assert.False(t, ok)
+	_, ok = rules("not_column")
+	assert.False(t, ok)
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Classification is how sensitive a column's data is, such as "email" or
+// "ssn", for an inventory of where personal data is kept. Columns are
+// classified by a "-- @classify <class>" annotation, and otherwise by the
+// rules and heuristics of a Classifier. "-- @classify none" keeps a column
+// from being classified.
+type Classification struct {
+	Class  string
+	Source ClassificationSource
+}
+
+type ClassificationSource string
+
+const (
+	ClassifiedByAnnotation ClassificationSource = "annotation"
+	ClassifiedByRule       ClassificationSource = "rule"
+	ClassifiedByHeuristic  ClassificationSource = "heuristic"
+)
+
+// annotationClassification returns the classification given by the
+// annotations of a column.
+func annotationClassification(anns Annotations) Classification {
+
+	class := anns["classify"]
+	if class == "" || class == "none" {
+		return Classification{}
+	}
+	return Classification{Class: class, Source: ClassifiedByAnnotation}
+}
+
+// ClassificationRule classifies the columns matching all of its predicates
+// as Class.
+type ClassificationRule struct {
+	Class string
+	Match []ColumnPredicate
+}
+
+// ParseClassificationRule parses a rule given as class=pattern, where the
+// pattern is a glob matching the column's name, qualified by its table if
+// it has a ".", or type:name matching columns of a type. For example
+// "email=*.contact_*" or "network=type:inet".
+func ParseClassificationRule(s string) (ClassificationRule, error) {
+
+	class, pattern, ok := strings.Cut(s, "=")
+	if !ok || class == "" || pattern == "" {
+		return ClassificationRule{}, fmt.Errorf("expected class=pattern, got %q", s)
+	}
+	rule := ClassificationRule{Class: class}
+	if name, ok := strings.CutPrefix(pattern, "type:"); ok {
+		t := LookupType(name)
+		if t == nil {
+			return ClassificationRule{}, fmt.Errorf("unknown type %q", name)
+		}
+		rule.Match = append(rule.Match, ColumnOfType(t))
+	} else if i := strings.LastIndex(pattern, "."); i >= 0 {
+		rule.Match = append(rule.Match, ColumnInTable(pattern[:i]), ColumnNamed(pattern[i+1:]))
+	} else {
+		rule.Match = append(rule.Match, ColumnNamed(pattern))
+	}
+	return rule, nil
+}
+
+func (r ClassificationRule) matches(col *Column) bool {
+
+	for _, pred := range r.Match {
+		if !pred(col) {
+			return false
+		}
+	}
+	return true
+}
+
+// classificationHeuristics guess the class of columns from their names,
+// ignoring case. The first matching is used, so that "email_address" is an
+// email rather than an address.
+var classificationHeuristics = []struct {
+	class    string
+	patterns []string
+}{
+	{"email", []string{"*email*"}},
+	{"secret", []string{"*password*", "*passwd*", "*secret*", "*token*", "*api_key*"}},
+	{"ssn", []string{"ssn", "*_ssn", "social_security*"}},
+	{"birth-date", []string{"*birth*", "dob"}},
+	{"phone", []string{"*phone*", "mobile", "*_mobile"}},
+	{"ip-address", []string{"ip", "*_ip", "ip_address", "*_ip_address"}},
+	{"address", []string{"*address*", "street*", "postcode", "postal_code", "zip", "zip_code"}},
+	{"name", []string{"first_name", "last_name", "full_name", "surname", "given_name", "family_name"}},
+}
+
+// Classifier classifies the columns of a catalog which aren't classified by
+// annotations, by its rules in order, then if Heuristics is set by their
+// names.
+type Classifier struct {
+	Rules      []ClassificationRule
+	Heuristics bool
+}
+
+func (cl *Classifier) classify(col *Column) Classification {
+
+	for _, rule := range cl.Rules {
+		if rule.matches(col) {
+			return Classification{Class: rule.Class, Source: ClassifiedByRule}
+		}
+	}
+	if cl.Heuristics {
+		name := strings.ToLower(col.Name)
+		for _, h := range classificationHeuristics {
+			for _, pattern := range h.patterns {
+				if ok, _ := path.Match(pattern, name); ok {
+					return Classification{Class: h.class, Source: ClassifiedByHeuristic}
+				}
+			}
+		}
+	}
+	return Classification{}
+}
+
+// Classify sets the classification of each column of cat not classified by
+// its annotations.
+func (cl *Classifier) Classify(cat *Catalog) {
+
+	cat.modify()
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			for _, col := range t.Columns.List() {
+				if _, ok := col.Annotations["classify"]; ok {
+					continue
+				}
+				col.Classification = cl.classify(col)
+			}
+		}
+	}
+}
+
+// classifierFlags registers the flags configuring a Classifier on fs.
+func classifierFlags(fs *flag.FlagSet) *Classifier {
+
+	cl := &Classifier{}
+	fs.Func("classify", "classify columns matching a pattern, as class=glob, class=table.glob or class=type:name", func(s string) error {
+		rule, err := ParseClassificationRule(s)
+		if err != nil {
+			return err
+		}
+		cl.Rules = append(cl.Rules, rule)
+		return nil
+	})
+	fs.BoolVar(&cl.Heuristics, "classify-heuristics", false, "classify columns by their names, such as email or ssn")
+	return cl
+}
+
+// ClassifiedColumn is a column listed by the classify command.
+type ClassifiedColumn struct {
+	OID    OID                  `json:"oid"`
+	Table  string               `json:"table"`
+	Column string               `json:"column"`
+	Type   string               `json:"type"`
+	Class  string               `json:"class"`
+	Source ClassificationSource `json:"source"`
+}
+
+func runClassify(args []string) error {
+
+	fs := flag.NewFlagSet("classify", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	found := []ClassifiedColumn{}
+	for _, col := range FindColumns(c.Catalog) {
+		if col.Classification.Class == "" {
+			continue
+		}
+		found = append(found, ClassifiedColumn{
+			OID:    col.OID,
+			Table:  col.Table.Schema + "." + col.Table.Name,
+			Column: col.Name,
+			Type:   col.FormatType(),
+			Class:  col.Classification.Class,
+			Source: col.Classification.Source,
+		})
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(found)
+	}
+	bw := bufio.NewWriter(w)
+	for _, col := range found {
+		fmt.Fprintf(bw, "%s.%s %s (%s)\n", col.Table, col.Column, col.Class, col.Source)
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestClassifier(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE users (
+    id int PRIMARY KEY,
+    email_address text NOT NULL,
+    -- @classify none
+    phone_model text,
+    contact text, -- @classify phone
+    home_address text,
+    last_ip inet,
+    notes text
+);
+CREATE TABLE audit (id int, who text, client inet);
+`))
+	rule, err := ParseClassificationRule("network=type:inet")
+	require.Nil(t, err)
+	named, err := ParseClassificationRule("identity=audit.who")
+	require.Nil(t, err)
+	cl := &Classifier{Rules: []ClassificationRule{named, rule}, Heuristics: true}
+	cl.Classify(c.Catalog)
+
+	classes := map[string]Classification{}
+	for _, col := range FindColumns(c.Catalog) {
+		if col.Classification.Class != "" {
+			classes[col.Table.Name+"."+col.Name] = col.Classification
+		}
+	}
+	assert.Equal(t, map[string]Classification{
+		"users.email_address": {Class: "email", Source: ClassifiedByHeuristic},
+		"users.contact":       {Class: "phone", Source: ClassifiedByAnnotation},
+		"users.home_address":  {Class: "address", Source: ClassifiedByHeuristic},
+		"users.last_ip":       {Class: "network", Source: ClassifiedByRule},
+		"audit.who":           {Class: "identity", Source: ClassifiedByRule},
+		"audit.client":        {Class: "network", Source: ClassifiedByRule},
+	}, classes)
+
+	var sb strings.Builder
+	DescribeTable(&sb, c.Catalog, findTable(c.Catalog, c.SearchPath, "audit"))
+	assert.Equal(t, `Table "public.audit"
+Column  Type     Nullable  Default  Classification
+id      integer
+who     text                        identity
+client  inet                        network
+`, sb.String())
+
+	sb.Reset()
+	require.Nil(t, (&GoGenerator{Package: "models", Flavor: GoFlavorPlain, Nullable: GoNullablePointer}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), "`db:\"email_address\" classification:\"email\"`")
+
+	_, err = ParseClassificationRule("email")
+	assert.NotNil(t, err)
+	_, err = ParseClassificationRule("x=type:nope")
+	assert.NotNil(t, err)
+}
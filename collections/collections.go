@@ -20,8 +20,10 @@ func (o *OrderedMap[K, V]) Add(key K, value V) {
 	o.m[key] = value
 }
 
+// List returns the values in the order they were added. The slice is
+// shared, so it mustn't be modified, but appending to it copies it.
 func (o *OrderedMap[K, V]) List() []V {
-	return o.slice
+	return o.slice[:len(o.slice):len(o.slice)]
 }
 
 func (o *OrderedMap[K, V]) Get(key K) (v V, ok bool) {
@@ -47,6 +49,34 @@ func (o *OrderedMap[K, V]) Remove(key K) {
 	}
 }
 
+// Clone returns a copy of o, which can be modified without affecting o.
+func (o *OrderedMap[K, V]) Clone() *OrderedMap[K, V] {
+
+	ret := &OrderedMap[K, V]{
+		slice: make([]V, len(o.slice), cap(o.slice)),
+		m:     make(map[K]V, len(o.m)),
+	}
+	copy(ret.slice, o.slice)
+	for k, v := range o.m {
+		ret.m[k] = v
+	}
+	return ret
+}
+
+// Rename moves the value stored under oldKey to newKey, keeping its
+// position. It does nothing if oldKey is absent or newKey is present.
+func (o *OrderedMap[K, V]) Rename(oldKey, newKey K) {
+	value, ok := o.m[oldKey]
+	if !ok {
+		return
+	}
+	if _, ok := o.m[newKey]; ok {
+		return
+	}
+	delete(o.m, oldKey)
+	o.m[newKey] = value
+}
+
 type Multimap[K comparable, V comparable] struct {
 	m map[K][]V
 }
@@ -75,16 +105,43 @@ func (m *Multimap[K, V]) RemoveValue(key K, value V) {
 	}
 	for i, v := range s {
 		if v == value {
-			s = append(s[:i], s[min(i+1, len(s)):]...)
+			s = append(s[:i], s[i+1:]...)
+			if len(s) == 0 {
+				delete(m.m, key)
+			} else {
+				m.m[key] = s
+			}
 			return
 		}
 	}
 }
 
+// Clone returns a copy of m, which can be modified without affecting m.
+func (m *Multimap[K, V]) Clone() *Multimap[K, V] {
+
+	ret := NewMultimap[K, V]()
+	for k, s := range m.m {
+		ret.m[k] = append([]V(nil), s...)
+	}
+	return ret
+}
+
+// Keys returns the keys which have values, in no particular order.
+func (m *Multimap[K, V]) Keys() []K {
+
+	ret := make([]K, 0, len(m.m))
+	for k := range m.m {
+		ret = append(ret, k)
+	}
+	return ret
+}
+
+// Get returns the values of key. Like OrderedMap.List, the slice is shared
+// but appending to it copies it.
 func (m *Multimap[K, V]) Get(key K) ([]V, bool) {
 
 	value, ok := m.m[key]
-	return value, ok
+	return value[:len(value):len(value)], ok
 }
 
 type BidiMultimap[L, R comparable] struct {
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Compatibility is whether an application written against one version of
+// a schema keeps working against the next, as it must during a blue/green
+// deployment, where the old application runs against the new schema until
+// it's replaced. Unlike Change.Classify, it's only concerned with what the
+// old application reads and writes, not with existing data: adding a
+// constraint which existing rows violate fails the migration rather than
+// the application, so it isn't reported.
+type Compatibility struct {
+	Compatible bool                    `json:"compatible"`
+	Problems   []*CompatibilityProblem `json:"problems"`
+}
+
+// CompatibilityProblem is a change which may break the old application.
+type CompatibilityProblem struct {
+	Change string `json:"change"`
+	Reason string `json:"reason"`
+}
+
+// CheckCompatibility returns whether applications written against from
+// can run against to.
+func CheckCompatibility(from, to *Catalog, opts DiffOptions) *Compatibility {
+
+	ret := &Compatibility{Problems: []*CompatibilityProblem{}}
+	changes := Diff(from, to, opts)
+	for _, c := range changes {
+		if c.Object == ObjectKindView && c.Kind == ChangeKindDrop && keepsViewColumns(changes, c.From.(*RawStatement)) {
+			continue
+		}
+		if reason := c.breaksOldApplications(); reason != "" {
+			ret.Problems = append(ret.Problems, &CompatibilityProblem{Change: c.String(), Reason: reason})
+		}
+	}
+	ret.Compatible = len(ret.Problems) == 0
+	return ret
+}
+
+// keepsViewColumns reports whether the view is created again by one of
+// changes with all of its columns, so that the old application's queries
+// of it still work.
+func keepsViewColumns(changes Changes, view *RawStatement) bool {
+
+	for _, c := range changes {
+		if c.Object != ObjectKindView || c.Kind != ChangeKindAdd || c.To.(*RawStatement).Name != view.Name {
+			continue
+		}
+		for _, col := range view.Columns {
+			if !slices.ContainsFunc(c.To.(*RawStatement).Columns, func(vc *ViewColumn) bool { return vc.Name == col.Name }) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// breaksOldApplications returns why applications written against the
+// schema before the change may fail after it, or empty if they won't.
+func (c *Change) breaksOldApplications() string {
+
+	switch c.Object {
+	case ObjectKindSchema, ObjectKindTable, ObjectKindColumn:
+	case ObjectKindView, ObjectKindEnum, ObjectKindSequence:
+		{
+			if c.Kind == ChangeKindDrop {
+				return fmt.Sprintf("the old application may still use the %s", c.Object)
+			}
+			if c.Object != ObjectKindEnum || c.Kind != ChangeKindAlter {
+				return ""
+			}
+			if removed := removedLabels(c.From.(*Enum), c.To.(*Enum)); len(removed) > 0 {
+				return fmt.Sprintf("the old application may write the removed labels %s", strings.Join(removed, ", "))
+			}
+			return ""
+		}
+	default:
+		return ""
+	}
+	switch c.Kind {
+	case ChangeKindDrop:
+		return fmt.Sprintf("the old application may still use the %s", c.Object)
+	case ChangeKindRename:
+		return fmt.Sprintf("the old application uses the %s's old name", c.Object)
+	case ChangeKindAdd:
+		{
+			col, ok := c.To.(*Column)
+			if ok && col.Attrs.NotNull && col.Attrs.Default == "" && !col.generatesValues() {
+				return "the old application's inserts don't set the column, which is not null and has no default"
+			}
+			return ""
+		}
+	}
+	from, ok := c.From.(*Column)
+	if !ok {
+		return ""
+	}
+	to := c.To.(*Column)
+	var reasons []string
+	if !from.SameType(to) && !wideningTypeChange(from, to) {
+		reasons = append(reasons, fmt.Sprintf("values of %s the old application writes may not convert to %s", from.FormatType(), to.FormatType()))
+	}
+	if fromDef, toDef := definitionsOf(from, to); toDef.NotNull && !fromDef.NotNull {
+		reasons = append(reasons, "the old application may write nulls")
+	}
+	if (from.Attrs.Default != "" || from.Attrs.Identity != IdentityNone) && to.Attrs.Default == "" && !to.generatesValues() {
+		reasons = append(reasons, "the old application's inserts may rely on the default")
+	}
+	if to.Attrs.Identity == IdentityAlways && from.Attrs.Identity != IdentityAlways {
+		reasons = append(reasons, "the old application's inserts may set the column, which is generated always")
+	}
+	return strings.Join(reasons, "; ")
+}
+
+func runCompat(args []string) error {
+
+	fs := flag.NewFlagSet("compat", flag.ExitOnError)
+	from := fs.String("from", "", "migrations describing the schema the old application was written against")
+	to := fs.String("to", "", "migrations describing the new schema")
+	renames := fs.String("renames", RenamesConservative.String(),
+		"how eagerly to detect renames, one of: "+strings.Join(renameDetectionNames, ", "))
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	fail := fs.Bool("fail", false, "exit with an error if the old application may not run against the new schema")
+	filter := filterFlags(fs)
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("-from and -to are required")
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	opts := DiffOptions{Filter: filter}
+	opts.Renames, err = ParseRenameDetection(*renames)
+	if err != nil {
+		return err
+	}
+	fromC, err := compile([]string{*from})
+	if err != nil {
+		return err
+	}
+	toC, err := compile([]string{*to})
+	if err != nil {
+		return err
+	}
+	compat := CheckCompatibility(fromC.Catalog, toC.Catalog, opts)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	if *format == "json" {
+		enc := json.NewEncoder(bw)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(compat)
+	} else {
+		for _, p := range compat.Problems {
+			fmt.Fprintf(bw, "%s: %s\n", p.Change, p.Reason)
+		}
+		if compat.Compatible {
+			fmt.Fprintln(bw, "compatible")
+		}
+	}
+	if err != nil {
+		return err
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	if *fail && !compat.Compatible {
+		return fmt.Errorf("%d changes may break the old application", len(compat.Problems))
+	}
+	return nil
+}
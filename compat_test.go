@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCheckCompatibility(t *testing.T) {
+	compile := func(sql string) *Catalog {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c.Catalog
+	}
+	from := compile(`
+CREATE TABLE users (id int PRIMARY KEY, name varchar(50), age smallint, email text, status text DEFAULT 'new', legacy text);
+CREATE TABLE audit (at timestamptz);
+`)
+	to := compile(`
+CREATE TABLE users (
+    id int PRIMARY KEY,
+    name varchar(20),
+    age int,
+    email text NOT NULL,
+    status text,
+    tenant int NOT NULL,
+    region text NOT NULL DEFAULT 'eu',
+    UNIQUE (email)
+);
+CREATE TABLE logins (at timestamptz);
+`)
+	compat := CheckCompatibility(from, to, DiffOptions{})
+	assert.False(t, compat.Compatible)
+	assert.Equal(t, []*CompatibilityProblem{
+		{Change: "drop column public.users.legacy", Reason: "the old application may still use the column"},
+		{Change: "drop table public.audit", Reason: "the old application may still use the table"},
+		{Change: "alter column public.users.name", Reason: "values of character varying(50) the old application writes may not convert to character varying(20)"},
+		{Change: "alter column public.users.email", Reason: "the old application may write nulls"},
+		{Change: "alter column public.users.status", Reason: "the old application's inserts may rely on the default"},
+		{Change: "add column public.users.tenant", Reason: "the old application's inserts don't set the column, which is not null and has no default"},
+	}, compat.Problems)
+
+	assert.True(t, CheckCompatibility(from, from, DiffOptions{}).Compatible)
+}
+
+func TestCheckCompatibility_ViewsEnumsAndSequences(t *testing.T) {
+	compile := func(sql string) *Catalog {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c.Catalog
+	}
+	from := compile(`
+CREATE TYPE status AS ENUM ('new', 'open', 'closed');
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE SEQUENCE ticket_numbers;
+CREATE TABLE tickets (id int PRIMARY KEY, status status, title text);
+CREATE VIEW open_tickets AS SELECT id, title FROM tickets WHERE status = 'open';
+CREATE VIEW ticket_titles AS SELECT id, title FROM tickets;
+`)
+	to := compile(`
+CREATE TYPE status AS ENUM ('new', 'closed');
+CREATE TABLE tickets (id int PRIMARY KEY, status status, title text);
+CREATE VIEW ticket_titles AS SELECT id, title FROM tickets WHERE title IS NOT NULL;
+`)
+	compat := CheckCompatibility(from, to, DiffOptions{})
+	assert.False(t, compat.Compatible)
+	assert.Equal(t, []*CompatibilityProblem{
+		{Change: "drop view public.open_tickets", Reason: "the old application may still use the view"},
+		{Change: "drop enum public.mood", Reason: "the old application may still use the enum"},
+		{Change: "alter enum public.status", Reason: "the old application may write the removed labels open"},
+		{Change: "drop sequence public.ticket_numbers", Reason: "the old application may still use the sequence"},
+	}, compat.Problems)
+
+	// Adding a label or a column of a view doesn't break anything
+	more := compile(`
+CREATE TYPE status AS ENUM ('new', 'open', 'closed', 'archived');
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE SEQUENCE ticket_numbers;
+CREATE TABLE tickets (id int PRIMARY KEY, status status, title text);
+CREATE VIEW open_tickets AS SELECT id, title, status FROM tickets WHERE status = 'open';
+CREATE VIEW ticket_titles AS SELECT id, title FROM tickets;
+`)
+	assert.Equal(t, []*CompatibilityProblem{}, CheckCompatibility(from, more, DiffOptions{}).Problems)
+}
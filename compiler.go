@@ -1,111 +1,876 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"github.com/henges/pgmodelparse/collections"
 	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"runtime"
 	"slices"
+	"strconv"
 	"strings"
 )
 
 type Compiler struct {
 	SearchPath string
 	Catalog    *Catalog
+	// TargetVersion is the major version of Postgres the statements must
+	// run on, or 0 to accept statements for any version. Statements using
+	// features the version lacks are rejected, unless WarnUnsupported is
+	// set in which case they are recorded in Warnings.
+	TargetVersion   int
+	WarnUnsupported bool
+	Warnings        []string
+	// WarningsAsErrors are the rules whose warnings Diagnostics reports as
+	// errors, or "all" for every rule.
+	WarningsAsErrors []string
+	// diagnostics are the Warnings as diagnostics, along with notes which
+	// aren't worth a warning.
+	diagnostics []*Diagnostic
+	// DiagnosticsSink, if set, is called with each diagnostic as it's
+	// reported.
+	DiagnosticsSink func(*Diagnostic)
+	// Extensions are the extensions installed, whose types are known.
+	Extensions []string
+	// Workers is how many files CompileFiles parses at once.
+	Workers int
+	// StreamSize is the size from which CompileFiles compiles a file a
+	// statement at a time with CompileReader, rather than reading it whole,
+	// or 0 to always read files whole.
+	StreamSize int64
+	// CopyRows counts the rows of COPY ... FROM stdin data loaded into each
+	// table, by qualified name, such as those in the output of pg_dump.
+	CopyRows map[string]int
+	// Psql enables psql's meta-commands and variables, for schemas built by
+	// psql scripts. Every file is then compiled with CompileReader, in which
+	// \i and \ir include files, variables set with \set or in Vars are
+	// substituted, and other meta-commands are ignored.
+	Psql bool
+	Vars map[string]string
+	// Lenient skips the parts of statements the compiler can't model yet,
+	// rather than failing. Skipped records them, along with the statements
+	// the compiler ignores.
+	Lenient bool
+	Skipped []*Skipped
+	// Migrations names the MigrationSource which CompileFiles lists and
+	// reads the migrations in directories with, or is empty to detect it.
+	Migrations string
+	// ParseCache, if set, holds files parsed by earlier compiles, which
+	// CompileFiles reuses for files which haven't changed since.
+	ParseCache *ParseCache
+	// CollapsePartitions records partitions on their parents, rather than
+	// as tables of their own, for schemas with so many that modeling each
+	// of them is too costly. Statements on a collapsed partition other than
+	// detaching or dropping it are skipped. Partitions which are themselves
+	// partitioned are still modeled as tables.
+	CollapsePartitions bool
+	// ValidateCatalog checks the catalog with Catalog.Validate after each
+	// statement, failing the statement which left it inconsistent. It's for
+	// debugging the compiler, as it makes compiling much slower.
+	ValidateCatalog bool
+	// NormalizeIdentifiers normalizes identifiers to NFC before applying
+	// statements, so that names which look the same but are made of
+	// different code points, such as those pasted from documents, are the
+	// same name. Postgres doesn't normalize them, so this models what was
+	// meant rather than what the server would do.
+	NormalizeIdentifiers bool
+	// src is the source currently being compiled, if it is known, and
+	// annotations indexes its comments. srcLine is the line of its file src
+	// starts on.
+	src         string
+	srcLine     int
+	annotations *annotationIndex
+	// copyRows holds the number of rows of data stripped from the source
+	// for each COPY ... FROM stdin not yet applied.
+	copyRows []int
+	// files are the files being compiled by compileFile, the innermost
+	// include last.
+	files []string
+	// stmt is the statement being applied.
+	stmt *pg_query.RawStmt
+	// truncations maps the identifiers Postgres truncates, once truncated,
+	// to the first identifier truncated to each.
+	truncations map[string]string
+	// lookalikes maps the skeletons of the identifiers seen, as
+	// identifierSkeleton gives them, to the first identifier with each.
+	lookalikes map[string]string
+	// defaultSchema is the schema created with the catalog.
+	defaultSchema string
+	// ctx is the context of the compile in progress, if it was given one.
+	ctx context.Context
 }
 
-func NewCompiler() *Compiler {
+func NewCompiler(opts ...CompilerOption) *Compiler {
 	c := &Compiler{
-		SearchPath: "public",
+		defaultSchema: "public",
+		Workers:       runtime.GOMAXPROCS(0),
+		StreamSize:    64 << 20,
 		Catalog: &Catalog{
-			Schemas: collections.NewOrderedMap[string, *Schema](),
-			Depends: &Depends{
-				ConstraintsByColumn: collections.NewMultimap[*Column, *Constraint](),
-				ConstraintsByName:   make(map[string]*Constraint),
-			},
+			Schemas:       collections.NewOrderedMap[string, *Schema](),
+			EventTriggers: collections.NewOrderedMap[string, *EventTrigger](),
+			Depends:       NewDepends(),
+			Descriptions:  make(map[any]*Description),
 		},
 	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	if c.SearchPath == "" {
+		c.SearchPath = c.defaultSchema
+	}
 	defaultSchema := &Schema{
-		Name:   "public",
-		Tables: collections.NewOrderedMap[string, *Table](),
+		Name:      c.defaultSchema,
+		Tables:    collections.NewOrderedMap[string, *Table](),
+		Enums:     collections.NewOrderedMap[string, *Enum](),
+		Sequences: collections.NewOrderedMap[string, *Sequence](),
 	}
 	c.Catalog.Schemas.Add(defaultSchema.Name, defaultSchema)
 	return c
 }
 
+// Compile parses src and applies its statements to the catalog. Unlike
+// ParseStatements, annotations in the source's comments are recorded.
+func (c *Compiler) Compile(src string) error {
+
+	parsed, err := ParseSource(src)
+	if err != nil {
+		return err
+	}
+	return c.Apply(parsed)
+}
+
+// withContext makes ctx the context of the compile until the function
+// returned is called. A compile already given a context keeps it, so that
+// nested compiles stop along with it.
+func (c *Compiler) withContext(ctx context.Context) func() {
+
+	if c.ctx != nil {
+		return func() {}
+	}
+	c.ctx = ctx
+	return func() { c.ctx = nil }
+}
+
+// ctxErr returns the error of the compile's context if it's done.
+func (c *Compiler) ctxErr() error {
+
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Err()
+}
+
+// ctxDone returns the channel closed when the compile's context is done,
+// which is nil if it has none.
+func (c *Compiler) ctxDone() <-chan struct{} {
+
+	if c.ctx == nil {
+		return nil
+	}
+	return c.ctx.Done()
+}
+
+// ParsedSource is a source text parsed ready to be applied to a Compiler.
+// Parsing doesn't depend on the catalog, so unlike applying statements it
+// can be done concurrently.
+type ParsedSource struct {
+	src         string
+	line        int
+	parse       *pg_query.ParseResult
+	annotations *annotationIndex
+	copyRows    []int
+}
+
+// ParseSource parses src, first stripping any data following COPY ... FROM
+// stdin statements.
+func ParseSource(src string) (*ParsedSource, error) {
+
+	src, copyRows, err := StripCopyData(src)
+	if err != nil {
+		return nil, err
+	}
+	parse, err := pg_query.Parse(src)
+	if err != nil {
+		return nil, syntaxError(src, err)
+	}
+	annotations, err := newAnnotationIndex(src)
+	if err != nil {
+		return nil, err
+	}
+	return &ParsedSource{src: src, parse: parse, annotations: annotations, copyRows: copyRows}, nil
+}
+
+// Apply applies the statements of a parsed source to the catalog.
+func (c *Compiler) Apply(p *ParsedSource) error {
+
+	if c.NormalizeIdentifiers {
+		var err error
+		p, err = p.normalizeIdentifiers()
+		if err != nil {
+			return err
+		}
+	}
+	c.src, c.srcLine, c.annotations, c.copyRows = p.src, p.line, p.annotations, p.copyRows
+	defer func() { c.src, c.srcLine, c.annotations, c.copyRows = "", 0, nil, nil }()
+	return c.ParseStatements(p.parse)
+}
+
+// CompileReaderContext compiles the statements read from r as
+// CompileReader does, stopping with ctx's error once it's done.
+func (c *Compiler) CompileReaderContext(ctx context.Context, r io.Reader) error {
+
+	defer c.withContext(ctx)()
+	return c.CompileReader(r)
+}
+
+// CompileReader compiles the statements read from r one at a time, so that
+// sources too large to hold in memory, such as the output of pg_dump, can
+// be compiled. Annotations are recorded as they are by Compile, and if Psql
+// is set meta-commands are run.
+func (c *Compiler) CompileReader(r io.Reader) error {
+
+	scanner := NewStatementScanner(r)
+	scanner.Psql = c.Psql
+	if c.Psql && c.Vars == nil {
+		c.Vars = make(map[string]string)
+	}
+	scanner.Vars = c.Vars
+	for scanner.Scan() {
+		if err := c.ctxErr(); err != nil {
+			return err
+		}
+		stmt := scanner.Statement()
+		if stmt.Meta {
+			err := c.MetaCommand(stmt.Text[stmt.Offset:])
+			if err != nil {
+				return fmt.Errorf("at line %d: %w", stmt.StartLine()+1, err)
+			}
+			continue
+		}
+		parsed, err := ParseSource(stmt.Text)
+		var ce *CompileError
+		if errors.As(err, &ce) && ce.Line > 0 {
+			ce.Line += stmt.Line
+		}
+		if err == nil {
+			parsed.line = stmt.Line
+			if stmt.CopyData {
+				parsed.copyRows = []int{stmt.CopyRows}
+			}
+			err = c.Apply(parsed)
+		}
+		if err != nil {
+			return fmt.Errorf("at line %d: %w", stmt.StartLine()+1, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// ParseStatementsContext applies the statements of parse as
+// ParseStatements does, stopping with ctx's error if it's done before they
+// have all been applied.
+func (c *Compiler) ParseStatementsContext(ctx context.Context, parse *pg_query.ParseResult) error {
+
+	defer c.withContext(ctx)()
+	return c.ParseStatements(parse)
+}
+
 func (c *Compiler) ParseStatements(parse *pg_query.ParseResult) error {
 
+	defer func() { c.stmt = nil }()
 	for _, stmt := range parse.Stmts {
-		switch p := stmt.Stmt.Node.(type) {
-		case *pg_query.Node_CreateSchemaStmt:
-			{
-				err := c.CreateSchema(p.CreateSchemaStmt)
-				if err != nil {
-					return fmt.Errorf("while creating schema: %w", err)
-				}
+		if err := c.ctxErr(); err != nil {
+			return err
+		}
+		c.stmt = stmt
+		err := c.applyStatement(stmt)
+		if err == nil && c.ValidateCatalog {
+			err = c.Catalog.Validate()
+			if err != nil {
+				err = fmt.Errorf("catalog is inconsistent after %s: %w", statementName(stmt.Stmt), err)
 			}
-		case *pg_query.Node_CreateStmt:
-			{
-				err := c.CreateTable(p.CreateStmt)
-				if err != nil {
-					return fmt.Errorf("while creating table: %w", err)
-				}
+		}
+		if err != nil {
+			return c.locateError(err)
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) applyStatement(stmt *pg_query.RawStmt) error {
+
+	if c.Catalog.Frozen() {
+		return ErrFrozen
+	}
+	err := c.CheckVersion(stmt)
+	if err != nil {
+		return err
+	}
+	c.checkIdentifiers()
+	switch p := stmt.Stmt.Node.(type) {
+	case *pg_query.Node_CreateSchemaStmt:
+		{
+			err := c.CreateSchema(p.CreateSchemaStmt)
+			if err != nil {
+				return fmt.Errorf("while creating schema: %w", err)
 			}
-		case *pg_query.Node_AlterTableStmt:
-			{
-				err := c.AlterTable(p.AlterTableStmt)
+		}
+	case *pg_query.Node_CreateStmt:
+		{
+			err := c.CreateTable(p.CreateStmt)
+			if err != nil {
+				return fmt.Errorf("while creating table: %w", err)
+			}
+		}
+	case *pg_query.Node_CreateTableAsStmt:
+		{
+			ctas := p.CreateTableAsStmt
+			if ctas.Objtype != pg_query.ObjectType_OBJECT_TABLE {
+				c.skip(statementName(stmt.Stmt), "")
+				break
+			}
+			err := c.CreateTableAs(ctas.Into, ctas.Query, ctas.IfNotExists)
+			if err != nil {
+				return fmt.Errorf("while creating table: %w", err)
+			}
+		}
+	case *pg_query.Node_SelectStmt:
+		{
+			if p.SelectStmt.IntoClause == nil {
+				c.skip(statementName(stmt.Stmt), "")
+				break
+			}
+			err := c.CreateTableAs(p.SelectStmt.IntoClause, stmt.Stmt, false)
+			if err != nil {
+				return fmt.Errorf("while creating table: %w", err)
+			}
+		}
+	case *pg_query.Node_AlterTableStmt:
+		{
+			err := c.AlterTable(p.AlterTableStmt)
+			if err != nil {
+				return fmt.Errorf("while altering table: %w", err)
+			}
+		}
+	case *pg_query.Node_RenameStmt:
+		{
+			err := c.Rename(p.RenameStmt)
+			if err != nil {
+				return fmt.Errorf("while renaming: %w", err)
+			}
+		}
+	case *pg_query.Node_AlterObjectSchemaStmt:
+		{
+			if p.AlterObjectSchemaStmt.ObjectType != pg_query.ObjectType_OBJECT_VIEW {
+				c.skip(statementName(stmt.Stmt), "")
+				break
+			}
+			err := c.SetViewSchema(p.AlterObjectSchemaStmt.Relation, p.AlterObjectSchemaStmt.Newschema, p.AlterObjectSchemaStmt.MissingOk)
+			if err != nil {
+				return fmt.Errorf("while altering view: %w", err)
+			}
+		}
+	case *pg_query.Node_CopyStmt:
+		c.Copy(p.CopyStmt)
+	case *pg_query.Node_VacuumStmt, *pg_query.Node_ReindexStmt:
+		// Maintenance doesn't change the schema
+	case *pg_query.Node_ClusterStmt:
+		{
+			err := c.Cluster(p.ClusterStmt)
+			if err != nil {
+				return fmt.Errorf("while clustering: %w", err)
+			}
+		}
+	case *pg_query.Node_IndexStmt:
+		{
+			err := c.CreateIndex(p.IndexStmt)
+			if err != nil {
+				return fmt.Errorf("while creating index: %w", err)
+			}
+		}
+	case *pg_query.Node_CreateStatsStmt:
+		{
+			err := c.CreateStatistics(p.CreateStatsStmt)
+			if err != nil {
+				return fmt.Errorf("while creating statistics: %w", err)
+			}
+		}
+	case *pg_query.Node_CreateEventTrigStmt:
+		{
+			err := c.CreateEventTrigger(p.CreateEventTrigStmt)
+			if err != nil {
+				return fmt.Errorf("while creating event trigger: %w", err)
+			}
+		}
+	case *pg_query.Node_AlterEventTrigStmt:
+		{
+			err := c.AlterEventTrigger(p.AlterEventTrigStmt)
+			if err != nil {
+				return fmt.Errorf("while altering event trigger: %w", err)
+			}
+		}
+	case *pg_query.Node_CreateEnumStmt:
+		{
+			err := c.CreateEnum(p.CreateEnumStmt)
+			if err != nil {
+				return fmt.Errorf("while creating enum: %w", err)
+			}
+		}
+	case *pg_query.Node_AlterEnumStmt:
+		{
+			err := c.AlterEnum(p.AlterEnumStmt)
+			if err != nil {
+				return fmt.Errorf("while altering enum: %w", err)
+			}
+		}
+	case *pg_query.Node_CreateSeqStmt:
+		{
+			err := c.CreateSequence(p.CreateSeqStmt)
+			if err != nil {
+				return fmt.Errorf("while creating sequence: %w", err)
+			}
+			c.skip(statementName(stmt.Stmt), "")
+		}
+	case *pg_query.Node_AlterSeqStmt:
+		{
+			err := c.AlterSequence(p.AlterSeqStmt)
+			if err != nil {
+				return fmt.Errorf("while altering sequence: %w", err)
+			}
+			c.skip(statementName(stmt.Stmt), "")
+		}
+	case *pg_query.Node_DiscardStmt:
+		{
+			target := p.DiscardStmt.Target
+			if target == pg_query.DiscardMode_DISCARD_TEMP || target == pg_query.DiscardMode_DISCARD_ALL {
+				err := c.DropTemporary()
 				if err != nil {
-					return fmt.Errorf("while altering table: %w", err)
+					return fmt.Errorf("while discarding temporary objects: %w", err)
 				}
 			}
-		case *pg_query.Node_DropStmt:
-			{
-				dropBehaviour := DropBehaviourRestrict
-				if p.DropStmt.Behavior == pg_query.DropBehavior_DROP_CASCADE {
-					dropBehaviour = DropBehaviourCascade
-				}
-				switch p.DropStmt.RemoveType {
-				case pg_query.ObjectType_OBJECT_TABLE:
-					{
-						for _, tgt := range p.DropStmt.Objects {
-							l := tgt.Node.(*pg_query.Node_List)
-							schema, table := TableNameFromNodeList(l.List)
-							err := c.DropTable(schema, table, dropBehaviour)
+		}
+	case *pg_query.Node_AlterOwnerStmt:
+		{
+			err := c.AlterOwner(p.AlterOwnerStmt)
+			if err != nil {
+				return fmt.Errorf("while changing owner: %w", err)
+			}
+		}
+	case *pg_query.Node_CommentStmt:
+		{
+			err := c.CommentOn(p.CommentStmt)
+			if err != nil {
+				return fmt.Errorf("while commenting: %w", err)
+			}
+		}
+	case *pg_query.Node_SecLabelStmt:
+		{
+			err := c.SecurityLabel(p.SecLabelStmt)
+			if err != nil {
+				return fmt.Errorf("while setting security label: %w", err)
+			}
+		}
+	case *pg_query.Node_CreateCastStmt:
+		{
+			err := c.CreateCast(p.CreateCastStmt)
+			if err != nil {
+				return fmt.Errorf("while creating cast: %w", err)
+			}
+		}
+	case *pg_query.Node_DefineStmt:
+		{
+			kind := p.DefineStmt.Kind
+			if kind != pg_query.ObjectType_OBJECT_OPERATOR && kind != pg_query.ObjectType_OBJECT_AGGREGATE {
+				c.skip("CREATE "+strings.ReplaceAll(strings.TrimPrefix(kind.String(), "OBJECT_"), "_", " "), "")
+				return nil
+			}
+			err := c.DefineOperatorOrAggregate(p.DefineStmt)
+			if err != nil {
+				return fmt.Errorf("while creating %s: %w", strings.ToLower(strings.TrimPrefix(kind.String(), "OBJECT_")), err)
+			}
+		}
+	case *pg_query.Node_ViewStmt:
+		{
+			err := c.CreateView(p.ViewStmt)
+			if err != nil {
+				return fmt.Errorf("while creating view: %w", err)
+			}
+		}
+	case *pg_query.Node_RuleStmt:
+		{
+			err := c.CreateRule(p.RuleStmt)
+			if err != nil {
+				return fmt.Errorf("while creating rule: %w", err)
+			}
+		}
+	case *pg_query.Node_DoStmt:
+		{
+			err := c.KeepRaw("DO", "", nil)
+			if err != nil {
+				return err
+			}
+		}
+	case *pg_query.Node_DropStmt:
+		{
+			dropBehaviour := DropBehaviourRestrict
+			if p.DropStmt.Behavior == pg_query.DropBehavior_DROP_CASCADE {
+				dropBehaviour = DropBehaviourCascade
+			}
+			switch p.DropStmt.RemoveType {
+			case pg_query.ObjectType_OBJECT_SCHEMA:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						name, err := NodeString(tgt)
+						if err != nil {
+							return err
+						}
+						err = c.DropSchema(name, p.DropStmt.MissingOk, dropBehaviour)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_TABLE:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						schema, table, err := TableNameFromNodeList(tgt.GetList())
+						if err != nil {
+							return err
+						}
+						err = c.DropTable(schema, table, dropBehaviour)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_INDEX:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						schema, name, err := TableNameFromNodeList(tgt.GetList())
+						if err != nil {
+							return err
+						}
+						err = c.DropIndex(schema, name, p.DropStmt.MissingOk)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_STATISTIC_EXT:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						schema, name, err := TableNameFromNodeList(tgt.GetList())
+						if err != nil {
+							return err
+						}
+						err = c.DropStatistics(schema, name, p.DropStmt.MissingOk)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_EVENT_TRIGGER:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						name, err := NodeString(tgt)
+						if err != nil {
+							return err
+						}
+						err = c.DropEventTrigger(name, p.DropStmt.MissingOk)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_CAST, pg_query.ObjectType_OBJECT_OPERATOR, pg_query.ObjectType_OBJECT_AGGREGATE:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						err := c.DropOpaque(p.DropStmt.RemoveType, tgt, p.DropStmt.MissingOk)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_FUNCTION, pg_query.ObjectType_OBJECT_PROCEDURE, pg_query.ObjectType_OBJECT_ROUTINE,
+				pg_query.ObjectType_OBJECT_TYPE, pg_query.ObjectType_OBJECT_DOMAIN:
+				{
+					skipped := false
+					for _, tgt := range p.DropStmt.Objects {
+						err := c.DropDependents(p.DropStmt.RemoveType, tgt, dropBehaviour)
+						if err != nil {
+							return err
+						}
+						if p.DropStmt.RemoveType == pg_query.ObjectType_OBJECT_TYPE {
+							names, err := NodeStrings(tgt.GetTypeName().GetNames())
+							if err != nil {
+								return err
+							}
+							ok, err := c.DropEnum(names, dropBehaviour)
 							if err != nil {
 								return err
 							}
+							if ok {
+								continue
+							}
+						}
+						skipped = true
+					}
+					if skipped {
+						c.skip("DROP "+strings.TrimPrefix(p.DropStmt.RemoveType.String(), "OBJECT_"), "")
+					}
+				}
+			case pg_query.ObjectType_OBJECT_SEQUENCE:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						names, err := NodeStrings(tgt.GetList().GetItems())
+						if err != nil {
+							return err
+						}
+						err = c.DropSequence(names, dropBehaviour)
+						if err != nil {
+							return err
+						}
+					}
+					c.skip("DROP SEQUENCE", "")
+				}
+			case pg_query.ObjectType_OBJECT_VIEW:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						names, err := NodeStrings(tgt.GetList().GetItems())
+						if err != nil {
+							return err
+						}
+						err = c.DropView(names, p.DropStmt.MissingOk, dropBehaviour)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_RULE:
+				{
+					for _, tgt := range p.DropStmt.Objects {
+						names, err := NodeStrings(tgt.GetList().GetItems())
+						if err != nil {
+							return err
 						}
+						err = c.DropRule(names, p.DropStmt.MissingOk)
+						if err != nil {
+							return err
+						}
+					}
+				}
+			case pg_query.ObjectType_OBJECT_EXTENSION:
+				{
+					names, err := NodeStrings(p.DropStmt.Objects)
+					if err != nil {
+						return err
+					}
+					err = c.DropExtension(names, p.DropStmt.MissingOk, dropBehaviour)
+					if err != nil {
+						return fmt.Errorf("while dropping extension: %w", err)
 					}
 				}
+			default:
+				c.skip("DROP "+strings.ReplaceAll(strings.TrimPrefix(p.DropStmt.RemoveType.String(), "OBJECT_"), "_", " "), "")
+			}
+		}
+	case *pg_query.Node_CreateExtensionStmt:
+		{
+			c.addExtension(p.CreateExtensionStmt.Extname)
+			c.skip(statementName(stmt.Stmt), "")
+		}
+	case *pg_query.Node_AlterExtensionContentsStmt:
+		{
+			err := c.AlterExtensionContents(p.AlterExtensionContentsStmt)
+			if err != nil {
+				return fmt.Errorf("while altering extension: %w", err)
 			}
 		}
+	default:
+		c.skip(statementName(stmt.Stmt), "")
 	}
-
 	return nil
 }
 
-func (c *Compiler) CreateSchema(stmt *pg_query.CreateSchemaStmt) error {
-	_, exists := c.Catalog.Schemas.Get(stmt.Schemaname)
-	if exists && !stmt.IfNotExists {
-		return fmt.Errorf("schema already exists")
-	} else if exists && stmt.IfNotExists {
+// Copy counts the rows of data stripped from the source for a COPY ...
+// FROM stdin. Other COPY statements don't change the catalog.
+func (c *Compiler) Copy(stmt *pg_query.CopyStmt) {
+
+	if !stmt.IsFrom || stmt.IsProgram || stmt.Filename != "" || len(c.copyRows) == 0 {
+		return
+	}
+	rows := c.copyRows[0]
+	c.copyRows = c.copyRows[1:]
+	schema := c.relationSchema(stmt.Relation.Schemaname, stmt.Relation.Relname)
+	if c.CopyRows == nil {
+		c.CopyRows = make(map[string]int)
+	}
+	c.CopyRows[schema+"."+stmt.Relation.Relname] += rows
+}
+
+// CheckVersion checks that stmt only uses features which exist in the
+// target version.
+func (c *Compiler) CheckVersion(stmt *pg_query.RawStmt) error {
+
+	if c.TargetVersion == 0 {
+		return nil
+	}
+	for _, use := range FindFeatures(c.src, stmt) {
+		if use.Feature.Since <= c.TargetVersion {
+			continue
+		}
+		msg := fmt.Sprintf("%s requires Postgres %d, but the target is %d", use.Feature.Name, use.Feature.Since, c.TargetVersion)
+		line := 0
+		if c.annotations != nil {
+			line = c.srcLine + c.annotations.line(int(use.Location)) + 1
+		}
+		if !c.WarnUnsupported {
+			if line > 0 {
+				msg += fmt.Sprintf(" (line %d)", line)
+			}
+			return &CompileError{Line: line, Rule: RuleTargetVersion, Err: errors.New(msg)}
+		}
+		c.warn(RuleTargetVersion, line, msg)
+	}
+	return nil
+}
+
+func (c *Compiler) CreateSchema(stmt *pg_query.CreateSchemaStmt) error {
+	name, owner := stmt.Schemaname, ""
+	if stmt.Authrole != nil {
+		owner = roleName(stmt.Authrole)
+		// Without a name, the schema is named after its owner
+		if name == "" {
+			name = stmt.Authrole.Rolename
+		}
+	}
+	_, exists := c.Catalog.Schemas.Get(name)
+	if exists && !stmt.IfNotExists {
+		return fmt.Errorf("schema already exists")
+	} else if exists && stmt.IfNotExists {
 		return nil
 	}
 	sch := &Schema{
-		Name:   stmt.Schemaname,
-		Tables: collections.NewOrderedMap[string, *Table](),
+		Name:      name,
+		Owner:     owner,
+		Tables:    collections.NewOrderedMap[string, *Table](),
+		Enums:     collections.NewOrderedMap[string, *Enum](),
+		Sequences: collections.NewOrderedMap[string, *Sequence](),
 	}
 	c.Catalog.Schemas.Add(sch.Name, sch)
 	return nil
 }
 
+func (c *Compiler) DropSchema(name string, missingOk bool, behav DropBehaviour) error {
+
+	sch, ok := c.Catalog.Schemas.Get(name)
+	if !ok {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("couldn't find schema %s", name)
+	}
+	if len(sch.Tables.List()) > 0 && behav != DropBehaviourCascade {
+		return fmt.Errorf("can't drop schema %s because it contains tables and cascade was not specified", name)
+	}
+	if len(sch.Enums.List()) > 0 && behav != DropBehaviourCascade {
+		return fmt.Errorf("can't drop schema %s because it contains types and cascade was not specified", name)
+	}
+	if len(sch.Sequences.List()) > 0 && behav != DropBehaviourCascade {
+		return fmt.Errorf("can't drop schema %s because it contains sequences and cascade was not specified", name)
+	}
+	raws := c.schemaRaw(name)
+	if len(raws) > 0 && behav != DropBehaviourCascade {
+		return fmt.Errorf("can't drop schema %s because it contains %ss and cascade was not specified", name, strings.ToLower(strings.TrimPrefix(raws[0].Kind, "CREATE ")))
+	}
+	for _, raw := range raws {
+		if !slices.Contains(c.Catalog.Raw, raw) {
+			// Dropped along with what it depends on
+			continue
+		}
+		c.dropDependents(c.Catalog.Depends.DependentsOf(raw))
+		c.Catalog.RemoveRaw(func(r *RawStatement) bool {
+			return r == raw
+		})
+	}
+	for _, tab := range slices.Clone(sch.Tables.List()) {
+		if _, ok := sch.Tables.Get(tab.Name); !ok {
+			// Dropped along with its parent
+			continue
+		}
+		err := c.DropTable(sch.Name, tab.Name, behav)
+		if err != nil {
+			return err
+		}
+	}
+	for _, e := range slices.Clone(sch.Enums.List()) {
+		_, err := c.DropEnum([]string{sch.Name, e.Name}, behav)
+		if err != nil {
+			return err
+		}
+	}
+	for _, s := range c.Catalog.Depends.StatisticsByName {
+		if s.Schema == name {
+			c.Catalog.Depends.RemoveStatistics(s)
+		}
+	}
+	c.Catalog.Schemas.Remove(name)
+	return nil
+}
+
+// schemaRaw returns the raw statements creating objects in schema, which
+// are the views, operators and aggregates qualified by it.
+func (c *Compiler) schemaRaw(schema string) []*RawStatement {
+
+	var ret []*RawStatement
+	for _, raw := range c.Catalog.Raw {
+		switch raw.Kind {
+		case "CREATE VIEW", "CREATE OPERATOR", "CREATE AGGREGATE":
+			if strings.HasPrefix(raw.Name, schema+".") {
+				ret = append(ret, raw)
+			}
+		}
+	}
+	return ret
+}
+
 func (c *Compiler) CreateTable(stmt *pg_query.CreateStmt) error {
 	name := stmt.Relation.Relname
-	schemaName := stmt.Relation.Schemaname
-	if schemaName == "" {
-		schemaName = c.SearchPath
+	schemaName, err := c.createSchema(stmt.Relation)
+	if err != nil {
+		return err
 	}
 	table := NewTable(name, schemaName)
-	err := c.Catalog.AddTable(table)
+	table.Unlogged = stmt.Relation.Relpersistence == persistenceUnlogged
+	table.OID = c.Catalog.newOID()
+	table.Annotations = c.annotations.For(stmt.Relation.Location)
+	table.Defined = c.sourceLocation(stmt.Relation.Location)
+	table.Options, err = setStorageOptions(nil, stmt.Options)
+	if err != nil {
+		return err
+	}
+	if stmt.Partspec != nil {
+		table.PartitionKey, err = partitionKey(stmt.Partspec)
+		if err != nil {
+			return err
+		}
+	}
+	if parent, _ := c.collapsedPartition(schemaName, name); parent != nil {
+		return fmt.Errorf("table already exists: %s", name)
+	}
+	if stmt.Partbound != nil {
+		if len(stmt.InhRelations) != 1 {
+			return fmt.Errorf("expected one table to be a partition of but got %d", len(stmt.InhRelations))
+		}
+		collapsed, err := c.CreatePartition(table, stmt.InhRelations[0].GetRangeVar(), stmt.Partbound)
+		if err != nil || collapsed {
+			return err
+		}
+	}
+	err = c.Catalog.AddTable(table)
 	if err != nil {
 		return err
 	}
@@ -113,107 +878,1120 @@ func (c *Compiler) CreateTable(stmt *pg_query.CreateStmt) error {
 		switch p := n.Node.(type) {
 		case *pg_query.Node_ColumnDef:
 			{
-				err = c.DefineColumn(table, p.ColumnDef)
+				// The columns of a partition are its parent's, which it may
+				// add constraints to
+				if col, ok := table.Columns.Get(p.ColumnDef.Colname); ok && table.PartitionOf != nil {
+					err = c.DefineConstraints(table, col.Name, p.ColumnDef.Constraints)
+				} else {
+					err = c.DefineColumn(table, p.ColumnDef)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		case *pg_query.Node_Constraint:
+			{
+				err = c.DefineConstraint(table, "", p.Constraint)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// The new table has no rows to check, so NOT VALID is ignored
+	for _, con := range c.Catalog.Depends.TableConstraints(table) {
+		con.NotValid = false
+	}
+	return nil
+}
+
+func (c *Compiler) DropTable(schema, table string, behav DropBehaviour) error {
+
+	if parent, part := c.collapsedPartition(schema, table); parent != nil {
+		parent.Partitions.Remove(part.Schema + "." + part.Name)
+		return nil
+	}
+	tab, err := c.FindTableFromSchemaAndName(schema, table)
+	if err != nil {
+		return err
+	}
+	// Partitions go with their parent, whatever depends on them
+	for _, sch := range c.Catalog.Schemas.List() {
+		for _, part := range slices.Clone(sch.Tables.List()) {
+			if part.PartitionOf == tab {
+				err = c.DropTable(part.Schema, part.Name, DropBehaviourCascade)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	// Objects on other tables depending on the table's columns, like foreign
+	// keys referring to them, may prevent it being dropped
+	deps := c.Catalog.Depends.DependentsOf(tab)
+	for _, col := range tab.Columns.List() {
+		deps = append(deps, c.Catalog.Depends.DependentsOf(col)...)
+	}
+	if behav != DropBehaviourCascade {
+		if blocking := restrictingDependents(deps, tab); blocking != "" {
+			return fmt.Errorf("can't drop table %s because other objects depend on it and cascade was not specified: %s",
+				tab.Name, blocking)
+		}
+	}
+	c.dropDependents(deps)
+	c.dropOwnedSequences(tab.Columns.List())
+	sch, _ := c.Catalog.Schemas.Get(tab.Schema) // Must be ok
+	sch.Tables.Remove(tab.Name)
+	return nil
+}
+
+func (c *Compiler) CreateIndex(stmt *pg_query.IndexStmt) error {
+
+	if c.skipCollapsedPartition(stmt.Relation) {
+		return nil
+	}
+	t, err := c.FindTableFromRangeVar(stmt.Relation)
+	if err != nil {
+		return err
+	}
+	idx := &Index{OID: c.Catalog.newOID(), Table: t, Name: stmt.Idxname, Unique: stmt.Unique, Method: stmt.AccessMethod}
+	idx.Options, err = setStorageOptions(nil, stmt.Options)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, n := range stmt.IndexParams {
+		param := n.GetIndexElem()
+		if param == nil {
+			return fmt.Errorf("expected IndexElem but got %T", n.Node)
+		}
+		opclass, err := NodeStrings(param.Opclass)
+		if err != nil {
+			return err
+		}
+		elem := &IndexElem{
+			Opclass:    strings.Join(opclass, "."),
+			Descending: param.Ordering == pg_query.SortByDir_SORTBY_DESC,
+		}
+		elem.NullsFirst = elem.Descending
+		switch param.NullsOrdering {
+		case pg_query.SortByNulls_SORTBY_NULLS_FIRST:
+			elem.NullsFirst = true
+		case pg_query.SortByNulls_SORTBY_NULLS_LAST:
+			elem.NullsFirst = false
+		}
+		for _, opt := range param.Opclassopts {
+			def := opt.GetDefElem()
+			if def == nil {
+				return fmt.Errorf("expected DefElem but got %T", opt.Node)
+			}
+			elem.OpclassOptions = append(elem.OpclassOptions, def.Defname+"="+DefElemValue(def.Arg))
+		}
+		if param.Name != "" {
+			elem.Column, err = ColumnFromColName(t, param.Name)
+			if err != nil {
+				return err
+			}
+			idx.addColumn(elem.Column)
+			names = append(names, param.Name)
+		} else {
+			elem.expr = param.Expr
+			elem.Expr, err = DeparseExpr(param.Expr)
+			if err != nil {
+				return err
+			}
+			err = columnReferences(t, param.Expr, idx.addColumn)
+			if err != nil {
+				return err
+			}
+			// Postgres names expression keys after the function called
+			name := "expr"
+			if fn := param.Expr.GetFuncCall(); fn != nil {
+				name, err = NodeString(fn.Funcname[len(fn.Funcname)-1])
+				if err != nil {
+					return err
+				}
+			}
+			names = append(names, name)
+		}
+		idx.Elems = append(idx.Elems, elem)
+	}
+	for _, n := range stmt.IndexIncludingParams {
+		param := n.GetIndexElem()
+		if param == nil || param.Name == "" {
+			return fmt.Errorf("expected a column to include but got %T", n.Node)
+		}
+		col, err := ColumnFromColName(t, param.Name)
+		if err != nil {
+			return err
+		}
+		idx.Include = append(idx.Include, col)
+		idx.addColumn(col)
+		names = append(names, param.Name)
+	}
+	if stmt.WhereClause != nil {
+		idx.where = stmt.WhereClause
+		idx.Predicate, err = DeparseExpr(stmt.WhereClause)
+		if err != nil {
+			return err
+		}
+		err = columnReferences(t, stmt.WhereClause, idx.addColumn)
+		if err != nil {
+			return err
+		}
+	}
+
+	if idx.Name == "" {
+		suffix := "idx"
+		if idx.Unique {
+			suffix = "key"
+		}
+		idx.Name = c.chooseName(t.Name, nameAddition(names), suffix, c.relationTaken(t.Schema))
+	}
+	if _, ok := c.Catalog.Depends.IndexesByName[idx.QualifiedName()]; ok {
+		if stmt.IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("index already exists: %s", idx.Name)
+	}
+	c.Catalog.Depends.AddIndex(idx)
+	return nil
+}
+
+// columnReferences calls add with each column of t referenced by expr.
+func columnReferences(t *Table, expr *pg_query.Node, add func(*Column)) error {
+
+	var err error
+	walkNodes(expr.ProtoReflect(), func(m protoreflect.Message) {
+		ref, ok := m.Interface().(*pg_query.ColumnRef)
+		if !ok || err != nil {
+			return
+		}
+		name := ref.Fields[len(ref.Fields)-1].GetString_()
+		if name == nil {
+			return
+		}
+		var col *Column
+		col, err = ColumnFromColName(t, name.Sval)
+		if err == nil {
+			add(col)
+		}
+	})
+	return err
+}
+
+func (i *Index) addColumn(col *Column) {
+
+	if !slices.Contains(i.Columns, col) {
+		i.Columns = append(i.Columns, col)
+	}
+}
+
+// renameColumnReferences renames the references to a column in expr,
+// returning the expression deparsed again.
+func renameColumnReferences(expr *pg_query.Node, oldName, newName string) (string, error) {
+
+	walkNodes(expr.ProtoReflect(), func(m protoreflect.Message) {
+		ref, ok := m.Interface().(*pg_query.ColumnRef)
+		if !ok {
+			return
+		}
+		name := ref.Fields[len(ref.Fields)-1].GetString_()
+		if name != nil && name.Sval == oldName {
+			name.Sval = newName
+		}
+	})
+	return DeparseExpr(expr)
+}
+
+// renameColumn updates the index's expressions for a column it references
+// having been renamed.
+func (i *Index) renameColumn(oldName, newName string) error {
+
+	var err error
+	for _, elem := range i.Elems {
+		if elem.expr != nil {
+			elem.Expr, err = renameColumnReferences(elem.expr, oldName, newName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if i.where != nil {
+		i.Predicate, err = renameColumnReferences(i.where, oldName, newName)
+	}
+	return err
+}
+
+func (c *Compiler) DropIndex(schema, name string, missingOk bool) error {
+
+	schema = c.relationSchema(schema, name)
+	idx, ok := c.Catalog.Depends.IndexesByName[schema+"."+name]
+	if !ok {
+		// Primary keys and unique constraints have an index of their own name
+		if con, ok := c.Catalog.Depends.IndexedConstraint(schema, name); ok {
+			return fmt.Errorf("cannot drop index %s because constraint %s on table %s requires it", name, con.Name, con.Table.Name)
+		}
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("index %s not found", name)
+	}
+	c.removeIndex(idx)
+	return nil
+}
+
+// removeIndex removes a dropped index. A table whose replica identity was
+// the index is left without one, as Postgres does, and a table clustered
+// on it is no longer.
+func (c *Compiler) removeIndex(idx *Index) {
+
+	if idx.Table.ReplicaIndex == idx {
+		idx.Table.ReplicaIdentity, idx.Table.ReplicaIndex = ReplicaIdentityNothing, nil
+	}
+	if idx.Table.ClusterIndex == idx.Name {
+		idx.Table.ClusterIndex = ""
+	}
+	c.Catalog.Depends.RemoveIndex(idx)
+}
+
+// SetReplicaIdentity handles ALTER TABLE ... REPLICA IDENTITY.
+func (c *Compiler) SetReplicaIdentity(t *Table, stmt *pg_query.ReplicaIdentityStmt) error {
+
+	switch stmt.IdentityType {
+	case "d":
+		t.ReplicaIdentity, t.ReplicaIndex = ReplicaIdentityDefault, nil
+	case "f":
+		t.ReplicaIdentity, t.ReplicaIndex = ReplicaIdentityFull, nil
+	case "n":
+		t.ReplicaIdentity, t.ReplicaIndex = ReplicaIdentityNothing, nil
+	case "i":
+		{
+			idx, ok := c.Catalog.Depends.IndexesByName[t.Schema+"."+stmt.Name]
+			if !ok || idx.Table != t {
+				return fmt.Errorf("index %s not found on table %s", stmt.Name, t.Name)
+			}
+			if !idx.Unique || idx.Predicate != "" || slices.ContainsFunc(idx.Elems, func(e *IndexElem) bool { return e.Column == nil }) {
+				return fmt.Errorf("index %s can't be used as a replica identity, as it isn't unique, is partial or has expressions", idx.Name)
+			}
+			for _, e := range idx.Elems {
+				if !e.Column.Attrs.NotNull && !e.Column.Attrs.Pkey {
+					return fmt.Errorf("index %s can't be used as a replica identity, as column %s is nullable", idx.Name, e.Column.Name)
+				}
+			}
+			t.ReplicaIdentity, t.ReplicaIndex = ReplicaIdentityIndex, idx
+		}
+	default:
+		return fmt.Errorf("unknown replica identity %q", stmt.IdentityType)
+	}
+	return nil
+}
+
+// ClusterOn records that t is clustered on the index named, as CLUSTER
+// ... USING and ALTER TABLE ... CLUSTER ON do. The index may be one of a
+// primary key or unique constraint.
+func (c *Compiler) ClusterOn(t *Table, name string) error {
+
+	if idx, ok := c.Catalog.Depends.IndexesByName[t.Schema+"."+name]; ok && idx.Table == t {
+		if idx.Predicate != "" {
+			return fmt.Errorf("cannot cluster on partial index %s", name)
+		}
+		t.ClusterIndex = name
+		return nil
+	}
+	if con, ok := c.Catalog.Depends.Constraint(t, name); ok && con.Indexed() {
+		t.ClusterIndex = name
+		return nil
+	}
+	return fmt.Errorf("index %s not found on table %s", name, t.Name)
+}
+
+// Cluster handles CLUSTER, which only changes the catalog when it names
+// the index to cluster on. Otherwise it reclusters tables on the index
+// they were last clustered on.
+func (c *Compiler) Cluster(stmt *pg_query.ClusterStmt) error {
+
+	if stmt.Relation == nil {
+		return nil
+	}
+	t, err := c.FindTableFromRangeVar(stmt.Relation)
+	if err != nil {
+		return err
+	}
+	if stmt.Indexname == "" {
+		if t.ClusterIndex == "" {
+			return fmt.Errorf("there is no previously clustered index for table %s", t.Name)
+		}
+		return nil
+	}
+	return c.ClusterOn(t, stmt.Indexname)
+}
+
+func (c *Compiler) RenameIndex(r *pg_query.RangeVar, newName string, missingOk bool) error {
+
+	schema := c.relationSchema(r.Schemaname, r.Relname)
+	idx, ok := c.Catalog.Depends.IndexesByName[schema+"."+r.Relname]
+	if !ok {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("index %s not found", r.Relname)
+	}
+	if _, ok := c.Catalog.Depends.IndexesByName[schema+"."+newName]; ok {
+		return fmt.Errorf("index already exists: %s", newName)
+	}
+	c.Catalog.Depends.RemoveIndex(idx)
+	if idx.Table.ClusterIndex == idx.Name {
+		idx.Table.ClusterIndex = newName
+	}
+	idx.Name = newName
+	c.Catalog.Depends.AddIndex(idx)
+	return nil
+}
+
+func (c *Compiler) CreateStatistics(stmt *pg_query.CreateStatsStmt) error {
+
+	if len(stmt.Relations) != 1 {
+		return fmt.Errorf("%w statistics on %d relations", ErrUnsupported, len(stmt.Relations))
+	}
+	rv := stmt.Relations[0].GetRangeVar()
+	if rv == nil {
+		return fmt.Errorf("expected RangeVar but got %T", stmt.Relations[0].Node)
+	}
+	t, err := c.FindTableFromRangeVar(rv)
+	if err != nil {
+		return err
+	}
+	kinds, err := NodeStrings(stmt.StatTypes)
+	if err != nil {
+		return err
+	}
+	s := &Statistics{OID: c.Catalog.newOID(), Table: t, Kinds: kinds}
+	var names []string
+	for _, n := range stmt.Exprs {
+		param := n.GetStatsElem()
+		if param == nil {
+			return fmt.Errorf("expected StatsElem but got %T", n.Node)
+		}
+		elem := &StatisticsElem{}
+		if param.Name != "" {
+			elem.Column, err = ColumnFromColName(t, param.Name)
+			if err != nil {
+				return err
+			}
+			s.addColumn(elem.Column)
+			names = append(names, param.Name)
+		} else {
+			elem.expr = param.Expr
+			elem.Expr, err = DeparseExpr(param.Expr)
+			if err != nil {
+				return err
+			}
+			err = columnReferences(t, param.Expr, s.addColumn)
+			if err != nil {
+				return err
+			}
+			names = append(names, "expr")
+		}
+		s.Elems = append(s.Elems, elem)
+	}
+
+	// Unnamed statistics are created with the table, named ones in the
+	// current schema unless they're qualified
+	if len(stmt.Defnames) == 0 {
+		s.Schema = t.Schema
+		base := strings.Join(append([]string{t.Name}, names...), "_") + "_stat"
+		s.Name = base
+		for i := 1; c.Catalog.Depends.StatisticsByName[s.QualifiedName()] != nil; i++ {
+			s.Name = fmt.Sprintf("%s%d", base, i)
+		}
+	} else {
+		s.Schema, s.Name, err = TableNameFromNodeList(&pg_query.List{Items: stmt.Defnames})
+		if err != nil {
+			return err
+		}
+		if s.Schema == "" {
+			s.Schema = c.SearchPath
+		}
+	}
+	if _, ok := c.Catalog.Schemas.Get(s.Schema); !ok {
+		return fmt.Errorf("no such schema: %s", s.Schema)
+	}
+	if _, ok := c.Catalog.Depends.StatisticsByName[s.QualifiedName()]; ok {
+		if stmt.IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("statistics object already exists: %s", s.Name)
+	}
+	c.Catalog.Depends.AddStatistics(s)
+	return nil
+}
+
+func (s *Statistics) addColumn(col *Column) {
+
+	if !slices.Contains(s.Columns, col) {
+		s.Columns = append(s.Columns, col)
+	}
+}
+
+// renameColumn updates the statistics' expressions for a column they
+// reference having been renamed.
+func (s *Statistics) renameColumn(oldName, newName string) error {
+
+	var err error
+	for _, elem := range s.Elems {
+		if elem.expr != nil {
+			elem.Expr, err = renameColumnReferences(elem.expr, oldName, newName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) DropStatistics(schema, name string, missingOk bool) error {
+
+	if schema == "" {
+		schema = c.SearchPath
+	}
+	s, ok := c.Catalog.Depends.StatisticsByName[schema+"."+name]
+	if !ok {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("statistics object %s not found", name)
+	}
+	c.Catalog.Depends.RemoveStatistics(s)
+	return nil
+}
+
+func (c *Compiler) RenameStatistics(names *pg_query.List, newName string, missingOk bool) error {
+
+	schema, name, err := TableNameFromNodeList(names)
+	if err != nil {
+		return err
+	}
+	if schema == "" {
+		schema = c.SearchPath
+	}
+	s, ok := c.Catalog.Depends.StatisticsByName[schema+"."+name]
+	if !ok {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("statistics object %s not found", name)
+	}
+	if _, ok := c.Catalog.Depends.StatisticsByName[schema+"."+newName]; ok {
+		return fmt.Errorf("statistics object already exists: %s", newName)
+	}
+	c.Catalog.Depends.RemoveStatistics(s)
+	s.Name = newName
+	c.Catalog.Depends.AddStatistics(s)
+	return nil
+}
+
+func (c *Compiler) CreateEventTrigger(stmt *pg_query.CreateEventTrigStmt) error {
+
+	if _, ok := c.Catalog.EventTriggers.Get(stmt.Trigname); ok {
+		return fmt.Errorf("event trigger %s already exists", stmt.Trigname)
+	}
+	function, err := NodeStrings(stmt.Funcname)
+	if err != nil {
+		return err
+	}
+	trig := &EventTrigger{
+		OID:      c.Catalog.newOID(),
+		Name:     stmt.Trigname,
+		Event:    stmt.Eventname,
+		Function: strings.Join(function, "."),
+	}
+	for _, n := range stmt.Whenclause {
+		def := n.GetDefElem()
+		if def == nil || def.Defname != "tag" {
+			return fmt.Errorf("%w event trigger filter %s", ErrUnsupported, def.GetDefname())
+		}
+		tags, err := NodeStrings(def.Arg.GetList().GetItems())
+		if err != nil {
+			return err
+		}
+		trig.Tags = append(trig.Tags, tags...)
+	}
+	c.Catalog.EventTriggers.Add(trig.Name, trig)
+	return nil
+}
+
+func (c *Compiler) AlterEventTrigger(stmt *pg_query.AlterEventTrigStmt) error {
+
+	trig, ok := c.Catalog.EventTriggers.Get(stmt.Trigname)
+	if !ok {
+		return fmt.Errorf("event trigger %s not found", stmt.Trigname)
+	}
+	switch stmt.Tgenabled {
+	case "O":
+		trig.Firing = TriggerFiringOrigin
+	case "D":
+		trig.Firing = TriggerFiringDisabled
+	case "R":
+		trig.Firing = TriggerFiringReplica
+	case "A":
+		trig.Firing = TriggerFiringAlways
+	default:
+		return fmt.Errorf("unknown trigger firing %q", stmt.Tgenabled)
+	}
+	return nil
+}
+
+func (c *Compiler) RenameEventTrigger(name, newName string) error {
+
+	trig, ok := c.Catalog.EventTriggers.Get(name)
+	if !ok {
+		return fmt.Errorf("event trigger %s not found", name)
+	}
+	if _, ok := c.Catalog.EventTriggers.Get(newName); ok {
+		return fmt.Errorf("event trigger %s already exists", newName)
+	}
+	c.Catalog.EventTriggers.Rename(name, newName)
+	trig.Name = newName
+	return nil
+}
+
+func (c *Compiler) DropEventTrigger(name string, missingOk bool) error {
+
+	if _, ok := c.Catalog.EventTriggers.Get(name); !ok {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("event trigger %s not found", name)
+	}
+	c.Catalog.EventTriggers.Remove(name)
+	return nil
+}
+
+// CreateRule records a rule verbatim, since rules aren't modeled. Rules on
+// relations other than tables are kept without a table.
+func (c *Compiler) CreateRule(stmt *pg_query.RuleStmt) error {
+
+	tab, _ := c.FindTableFromRangeVar(stmt.Relation)
+	exists := slices.ContainsFunc(c.Catalog.Raw, func(raw *RawStatement) bool {
+		return raw.Kind == "CREATE RULE" && raw.Name == stmt.Rulename && raw.Table == tab
+	})
+	if exists {
+		if !stmt.Replace {
+			return fmt.Errorf("rule %s already exists", stmt.Rulename)
+		}
+		c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+			return raw.Kind == "CREATE RULE" && raw.Name == stmt.Rulename && raw.Table == tab
+		})
+	}
+	return c.KeepRaw("CREATE RULE", stmt.Rulename, tab)
+}
+
+// DropRule drops the rule named by names, which are the rule's table,
+// optionally qualified by its schema, followed by the rule's name.
+func (c *Compiler) DropRule(names []string, missingOk bool) error {
+
+	schema, table, name := c.SearchPath, names[0], names[1]
+	if len(names) == 3 {
+		schema, table, name = names[0], names[1], names[2]
+	}
+	tab, _ := c.FindTableFromSchemaAndName(schema, table)
+	n := len(c.Catalog.Raw)
+	c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+		return raw.Kind == "CREATE RULE" && raw.Name == name && raw.Table == tab
+	})
+	if n == len(c.Catalog.Raw) && !missingOk {
+		return fmt.Errorf("rule %s on %s not found", name, table)
+	}
+	return nil
+}
+
+// KeepRaw records the statement being applied verbatim in the catalog.
+func (c *Compiler) KeepRaw(kind, name string, tab *Table, depends ...string) error {
+
+	sql, err := c.statementSQL()
+	if err != nil {
+		return err
+	}
+	c.Catalog.AddRaw(&RawStatement{Kind: kind, Name: name, Table: tab, SQL: sql, Depends: depends})
+	return nil
+}
+
+// statementSQL returns the source of the statement being applied. If the
+// source isn't known, the statement is deparsed instead.
+func (c *Compiler) statementSQL() (string, error) {
+
+	if c.src != "" {
+		start, end := statementStart(c.src, c.stmt), c.stmt.StmtLocation+c.stmt.StmtLen
+		if c.stmt.StmtLen == 0 {
+			end = int32(len(c.src))
+		}
+		return strings.TrimSpace(c.src[start:end]), nil
+	}
+	return pg_query.Deparse(&pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: c.stmt.Stmt}}})
+}
+
+// Rename handles renaming tables, columns and table constraints. Other
+// kinds of object are ignored.
+func (c *Compiler) Rename(stmt *pg_query.RenameStmt) error {
+
+	switch stmt.RenameType {
+	case pg_query.ObjectType_OBJECT_COLUMN:
+		if stmt.RelationType == pg_query.ObjectType_OBJECT_VIEW {
+			return c.RenameViewColumn(stmt.Relation, stmt.Subname, stmt.Newname, stmt.MissingOk)
+		}
+	case pg_query.ObjectType_OBJECT_TABLE, pg_query.ObjectType_OBJECT_TABCONSTRAINT:
+	case pg_query.ObjectType_OBJECT_VIEW:
+		return c.RenameView(stmt.Relation, stmt.Newname, stmt.MissingOk)
+	case pg_query.ObjectType_OBJECT_INDEX:
+		return c.RenameIndex(stmt.Relation, stmt.Newname, stmt.MissingOk)
+	case pg_query.ObjectType_OBJECT_STATISTIC_EXT:
+		return c.RenameStatistics(stmt.Object.GetList(), stmt.Newname, stmt.MissingOk)
+	case pg_query.ObjectType_OBJECT_EVENT_TRIGGER:
+		{
+			name, err := NodeString(stmt.Object)
+			if err != nil {
+				return err
+			}
+			return c.RenameEventTrigger(name, stmt.Newname)
+		}
+	case pg_query.ObjectType_OBJECT_SEQUENCE:
+		return c.RenameSequence(stmt.Relation, stmt.Newname)
+	case pg_query.ObjectType_OBJECT_TYPE:
+		{
+			names, err := NodeStrings(stmt.Object.GetList().GetItems())
+			if err != nil {
+				return err
+			}
+			return c.RenameEnum(names, stmt.Newname)
+		}
+	default:
+		return nil
+	}
+	if stmt.RenameType == pg_query.ObjectType_OBJECT_TABLE {
+		if ok, err := c.renameCollapsedPartition(stmt.Relation, stmt.Newname); ok {
+			return err
+		}
+	} else if c.skipCollapsedPartition(stmt.Relation) {
+		return nil
+	}
+	t, err := c.FindTableFromRangeVar(stmt.Relation)
+	if err != nil {
+		if stmt.MissingOk {
+			return nil
+		}
+		return err
+	}
+	switch stmt.RenameType {
+	case pg_query.ObjectType_OBJECT_TABLE:
+		{
+			sch, _ := c.Catalog.Schemas.Get(t.Schema) // Must be ok
+			if orig, ok := sch.Tables.Get(stmt.Newname); ok {
+				return fmt.Errorf("table already exists: %s%s", stmt.Newname, duplicateLocations(orig.Defined, c.stmtLocation()))
+			}
+			for _, col := range t.Columns.List() {
+				col.pinSequence()
+			}
+			err = c.renameTableInViews(t, stmt.Newname)
+			if err != nil {
+				return err
+			}
+			sch.Tables.Rename(t.Name, stmt.Newname)
+			t.Name = stmt.Newname
+		}
+	case pg_query.ObjectType_OBJECT_COLUMN:
+		{
+			col, ok := t.Columns.Get(stmt.Subname)
+			if !ok {
+				return fmt.Errorf("couldn't find column %s in table %s", stmt.Subname, t.Name)
+			}
+			if orig, ok := t.Columns.Get(stmt.Newname); ok {
+				return fmt.Errorf("column already exists: %s%s", stmt.Newname, duplicateLocations(orig.Defined, c.stmtLocation()))
+			}
+			if t.PartitionOf != nil {
+				if _, ok := t.PartitionOf.Columns.Get(col.Name); ok {
+					return fmt.Errorf("cannot rename inherited column %s", col.Name)
+				}
+			}
+			err = c.checkRenamePartitionColumn(t, stmt.Newname)
+			if err != nil {
+				return err
+			}
+			return c.renameColumn(t, col, stmt.Newname)
+		}
+	case pg_query.ObjectType_OBJECT_TABCONSTRAINT:
+		{
+			con, ok := c.Catalog.Depends.Constraint(t, stmt.Subname)
+			if !ok {
+				return fmt.Errorf("couldn't find constraint %s on table %s", stmt.Subname, t.Name)
+			}
+			if orig, ok := c.Catalog.Depends.Constraint(t, stmt.Newname); ok {
+				return fmt.Errorf("constraint already exists: %s%s", stmt.Newname, duplicateLocations(orig.Defined, c.stmtLocation()))
+			}
+			if con.Indexed() && c.relationTaken(t.Schema)(stmt.Newname) {
+				return fmt.Errorf("relation %s.%s already exists", t.Schema, stmt.Newname)
+			}
+			delete(c.Catalog.Depends.ConstraintsByName, con.Key())
+			if con.Indexed() && t.ClusterIndex == con.Name {
+				t.ClusterIndex = stmt.Newname
+			}
+			con.Name = stmt.Newname
+			if con.Index != nil {
+				con.Index.Name = con.Name
+			}
+			c.Catalog.Depends.ConstraintsByName[con.Key()] = con
+		}
+	}
+	return nil
+}
+
+// renameColumn renames col of t, and the column of the same name of each
+// of the partitions of t, updating the expressions and views referencing
+// them.
+func (c *Compiler) renameColumn(t *Table, col *Column, newName string) error {
+
+	err := c.renameColumnInViews(col, newName)
+	if err != nil {
+		return err
+	}
+	col.pinSequence()
+	t.Columns.Rename(col.Name, newName)
+	oldName := col.Name
+	col.Name = newName
+	if t.PartitionKey != "" {
+		t.PartitionKey, err = renamePartitionKeyColumn(t.PartitionKey, oldName, newName)
+		if err != nil {
+			return err
+		}
+	}
+	indexes, _ := c.Catalog.Depends.IndexesByColumn.Get(col)
+	for _, idx := range indexes {
+		err = idx.renameColumn(oldName, col.Name)
+		if err != nil {
+			return err
+		}
+	}
+	stats, _ := c.Catalog.Depends.StatisticsByColumn.Get(col)
+	for _, s := range stats {
+		err = s.renameColumn(oldName, col.Name)
+		if err != nil {
+			return err
+		}
+	}
+	cons, _ := c.Catalog.Depends.ConstraintsByColumn.Get(col)
+	for _, con := range cons {
+		if con.expr == nil {
+			continue
+		}
+		con.Expr, err = renameColumnReferences(con.expr, oldName, col.Name)
+		if err != nil {
+			return err
+		}
+	}
+	for _, part := range c.partitionsOf(t) {
+		if partCol, ok := part.Columns.Get(oldName); ok {
+			err = c.renameColumn(part, partCol, newName)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) AlterTable(stmt *pg_query.AlterTableStmt) error {
+
+	if c.skipCollapsedPartition(stmt.Relation) {
+		return nil
+	}
+	if stmt.Objtype == pg_query.ObjectType_OBJECT_INDEX && len(stmt.Cmds) == 1 &&
+		stmt.Cmds[0].GetAlterTableCmd().GetSubtype() == pg_query.AlterTableType_AT_AttachPartition {
+		// The indexes of partitions aren't related to their parents' ones
+		c.skip("ALTER INDEX ATTACH PARTITION", "")
+		return nil
+	}
+	if len(stmt.Cmds) == 1 && stmt.Cmds[0].GetAlterTableCmd().GetSubtype() == pg_query.AlterTableType_AT_ChangeOwner {
+		return c.AlterRelationOwner(stmt, roleName(stmt.Cmds[0].GetAlterTableCmd().Newowner))
+	}
+	if stmt.Objtype == pg_query.ObjectType_OBJECT_INDEX {
+		return c.AlterIndex(stmt)
+	}
+	if stmt.Objtype == pg_query.ObjectType_OBJECT_SEQUENCE {
+		return c.AlterSequenceRelation(stmt)
+	}
+	tab, err := c.FindTableFromRangeVar(stmt.Relation)
+	if err != nil {
+		return err
+	}
+	if len(stmt.Cmds) == 1 {
+		return c.alterTableCmds(tab, stmt.Cmds)
+	}
+	// Subcommands are applied together, so if one fails those before it
+	// are undone
+	cp := c.Catalog.checkpoint()
+	err = c.alterTableCmds(tab, stmt.Cmds)
+	if err != nil {
+		cp.restore()
+	}
+	return err
+}
+
+// AlterIndex applies ALTER INDEX statements other than renames, of which
+// only those setting and resetting storage parameters change the catalog.
+func (c *Compiler) AlterIndex(stmt *pg_query.AlterTableStmt) error {
+
+	schema := c.relationSchema(stmt.Relation.Schemaname, stmt.Relation.Relname)
+	idx, ok := c.Catalog.Depends.IndexesByName[schema+"."+stmt.Relation.Relname]
+	if !ok {
+		if _, ok := c.Catalog.Depends.IndexedConstraint(schema, stmt.Relation.Relname); ok {
+			c.skip("ALTER INDEX", "the indexes of constraints don't have storage parameters")
+			return nil
+		}
+		if stmt.MissingOk {
+			return nil
+		}
+		return fmt.Errorf("index %s not found", stmt.Relation.Relname)
+	}
+	opts := idx.Options
+	var err error
+	for _, cmd := range stmt.Cmds {
+		atc := cmd.GetAlterTableCmd()
+		if atc == nil {
+			return fmt.Errorf("expected AlterTableCmd but got %T", cmd.Node)
+		}
+		switch atc.Subtype {
+		case pg_query.AlterTableType_AT_SetRelOptions:
+			opts, err = setStorageOptions(opts, atc.Def.GetList().GetItems())
+		case pg_query.AlterTableType_AT_ResetRelOptions:
+			opts, err = resetStorageOptions(opts, atc.Def.GetList().GetItems())
+		default:
+			c.skip("ALTER INDEX "+upperWords(strings.TrimPrefix(atc.Subtype.String(), "AT_")), "")
+		}
+		if err != nil {
+			return err
+		}
+	}
+	idx.Options = opts
+	return nil
+}
+
+func (c *Compiler) alterTableCmds(tab *Table, cmds []*pg_query.Node) error {
+
+	var err error
+	for _, cmd := range cmds {
+		atc, ok := cmd.Node.(*pg_query.Node_AlterTableCmd)
+		if !ok {
+			return fmt.Errorf("expected AlterTableCmd but got %T", cmd.Node)
+		}
+		switch atc.AlterTableCmd.Subtype {
+		case pg_query.AlterTableType_AT_AddColumn:
+			{
+				col, ok := atc.AlterTableCmd.Def.Node.(*pg_query.Node_ColumnDef)
+				if !ok {
+					return fmt.Errorf("expected ColumnDef but got %T", atc.AlterTableCmd.Def.Node)
+				}
+				if tab.PartitionOf != nil {
+					return fmt.Errorf("cannot add column to a partition")
+				}
+				err = c.DefineColumn(tab, col.ColumnDef)
+				if err != nil {
+					return err
+				}
+				// Partitions have the columns of their parent
+				added, _ := tab.Columns.Get(col.ColumnDef.Colname)
+				err = c.addPartitionColumns(tab, added)
+				if err != nil {
+					return err
+				}
+			}
+		case pg_query.AlterTableType_AT_DropColumn:
+			{
+				name := atc.AlterTableCmd.Name
+				if tab.PartitionOf != nil {
+					if _, ok := tab.PartitionOf.Columns.Get(name); ok {
+						return fmt.Errorf("cannot drop inherited column %s", name)
+					}
+				}
+				if _, ok := tab.Columns.Get(name); ok {
+					err = c.checkDropPartitionColumn(tab, name)
+					if err != nil {
+						return err
+					}
+				}
+				err = c.DropColumn(tab, name, atc.AlterTableCmd.Behavior)
+				if err != nil {
+					return err
+				}
+				err = c.dropPartitionColumns(tab, name, atc.AlterTableCmd.Behavior)
+				if err != nil {
+					return err
+				}
+			}
+		case pg_query.AlterTableType_AT_AddConstraint:
+			{
+				conDef, ok := atc.AlterTableCmd.Def.Node.(*pg_query.Node_Constraint)
+				if !ok {
+					return fmt.Errorf("expected Constraint but got %T", atc.AlterTableCmd.Def.Node)
+				}
+				err = c.DefineConstraint(tab, "", conDef.Constraint)
+				if err != nil {
+					return err
+				}
+			}
+		case pg_query.AlterTableType_AT_ColumnDefault:
+			{
+				col, err := ColumnFromColName(tab, atc.AlterTableCmd.Name)
+				if err != nil {
+					return err
+				}
+				err = c.setDefault(col, atc.AlterTableCmd.Def)
+				if err != nil {
+					return err
+				}
+			}
+		case pg_query.AlterTableType_AT_DropConstraint:
+			{
+				cons, ok := c.Catalog.Depends.Constraint(tab, atc.AlterTableCmd.Name)
+				if !ok {
+					if atc.AlterTableCmd.MissingOk {
+						break
+					}
+					return fmt.Errorf("while dropping constraint: constraint %s not found", atc.AlterTableCmd.Name)
+				}
+				c.Catalog.Depends.RemoveConstraint(cons)
+			}
+		case pg_query.AlterTableType_AT_ValidateConstraint:
+			{
+				con, ok := c.Catalog.Depends.Constraint(tab, atc.AlterTableCmd.Name)
+				if !ok {
+					return fmt.Errorf("while validating constraint: constraint %s not found", atc.AlterTableCmd.Name)
+				}
+				if con.Type != ConstraintTypeForeignKey && con.Type != ConstraintTypeCheck {
+					return fmt.Errorf("constraint %s of relation %s is not a foreign key or check constraint", con.Name, tab.Name)
+				}
+				con.NotValid = false
+			}
+		case pg_query.AlterTableType_AT_SetNotNull:
+			{
+				col, err := ColumnFromColName(tab, atc.AlterTableCmd.Name)
+				if err != nil {
+					return err
+				}
+				col.Attrs.NotNull = true
+			}
+		case pg_query.AlterTableType_AT_AlterColumnType:
+			{
+				col, err := ColumnFromColName(tab, atc.AlterTableCmd.Name)
+				if err != nil {
+					return err
+				}
+				def, ok := atc.AlterTableCmd.Def.Node.(*pg_query.Node_ColumnDef)
+				if !ok {
+					return fmt.Errorf("expected ColumnDef but got %T", atc.AlterTableCmd.Def.Node)
+				}
+				typ, err := c.TypeFromNode(def.ColumnDef.TypeName)
 				if err != nil {
 					return err
 				}
+				col.SetType(typ, TypeModsFromNode(def.ColumnDef.TypeName), len(def.ColumnDef.TypeName.ArrayBounds))
 			}
-		case *pg_query.Node_Constraint:
+		case pg_query.AlterTableType_AT_ClusterOn:
 			{
-				err = c.DefineConstraint(table, "", p.Constraint)
+				err = c.ClusterOn(tab, atc.AlterTableCmd.Name)
 				if err != nil {
 					return err
 				}
 			}
-		}
-	}
-	return nil
-}
-
-func (c *Compiler) DropTable(schema, table string, behav DropBehaviour) error {
-
-	tab, err := c.FindTableFromSchemaAndName(schema, table)
-	if err != nil {
-		return err
-	}
-	consToRemove := make(Constraints, 0, 5)
-	for _, col := range tab.Columns.List() {
-		cons, ok := c.Catalog.Depends.ConstraintsByColumn.Get(col)
-		if !ok {
-			continue
-		}
-		for _, con := range cons {
-			if con.Type == ConstraintTypeForeignKey &&
-				slices.Contains(con.Refers, col) &&
-				behav != DropBehaviourCascade {
-				return fmt.Errorf("can't drop table %s because constraint %s refers to it and cascade was not specified",
-					tab.Name, con.Name)
+		case pg_query.AlterTableType_AT_DropCluster:
+			tab.ClusterIndex = ""
+		case pg_query.AlterTableType_AT_SetLogged, pg_query.AlterTableType_AT_SetUnLogged:
+			{
+				if tab.Temporary() {
+					return fmt.Errorf("cannot change logged status of table %s because it is temporary", tab.Name)
+				}
+				tab.Unlogged = atc.AlterTableCmd.Subtype == pg_query.AlterTableType_AT_SetUnLogged
 			}
-			consToRemove = append(consToRemove, con)
-		}
-	}
-	for _, con := range consToRemove {
-		c.Catalog.Depends.RemoveConstraint(con)
-	}
-	sch, _ := c.Catalog.Schemas.Get(tab.Schema) // Must be ok
-	sch.Tables.Remove(tab.Name)
-	return nil
-}
-
-func (c *Compiler) AlterTable(stmt *pg_query.AlterTableStmt) error {
-
-	tab, err := c.FindTableFromRangeVar(stmt.Relation)
-	if err != nil {
-		return err
-	}
-
-	for _, cmd := range stmt.Cmds {
-		atc, ok := cmd.Node.(*pg_query.Node_AlterTableCmd)
-		if !ok {
-			return fmt.Errorf("expected AlterTableCmd but got %T", cmd.Node)
-		}
-		switch atc.AlterTableCmd.Subtype {
-		case pg_query.AlterTableType_AT_AddColumn:
+		case pg_query.AlterTableType_AT_SetRelOptions:
 			{
-				col, ok := atc.AlterTableCmd.Def.Node.(*pg_query.Node_ColumnDef)
-				if !ok {
-					return fmt.Errorf("expected ColumnDef but got %T", atc.AlterTableCmd.Def.Node)
+				tab.Options, err = setStorageOptions(tab.Options, atc.AlterTableCmd.Def.GetList().GetItems())
+				if err != nil {
+					return err
 				}
-				err = c.DefineColumn(tab, col.ColumnDef)
+			}
+		case pg_query.AlterTableType_AT_ResetRelOptions:
+			{
+				tab.Options, err = resetStorageOptions(tab.Options, atc.AlterTableCmd.Def.GetList().GetItems())
 				if err != nil {
 					return err
 				}
 			}
-		case pg_query.AlterTableType_AT_DropColumn:
+		case pg_query.AlterTableType_AT_AttachPartition:
 			{
-				err = c.DropColumn(tab, atc.AlterTableCmd.Name, atc.AlterTableCmd.Behavior)
+				err = c.AttachPartition(tab, atc.AlterTableCmd.Def.GetPartitionCmd())
 				if err != nil {
 					return err
 				}
 			}
-		case pg_query.AlterTableType_AT_AddConstraint:
+		case pg_query.AlterTableType_AT_DetachPartition:
 			{
-				conDef, ok := atc.AlterTableCmd.Def.Node.(*pg_query.Node_Constraint)
+				err = c.DetachPartition(tab, atc.AlterTableCmd.Def.GetPartitionCmd())
+				if err != nil {
+					return err
+				}
+			}
+		case pg_query.AlterTableType_AT_ReplicaIdentity:
+			{
+				def, ok := atc.AlterTableCmd.Def.Node.(*pg_query.Node_ReplicaIdentityStmt)
 				if !ok {
-					return fmt.Errorf("expected Constraint but got %T", atc.AlterTableCmd.Def.Node)
+					return fmt.Errorf("expected ReplicaIdentityStmt but got %T", atc.AlterTableCmd.Def.Node)
 				}
-				err = c.DefineConstraint(tab, "", conDef.Constraint)
+				err = c.SetReplicaIdentity(tab, def.ReplicaIdentityStmt)
 				if err != nil {
 					return err
 				}
 			}
-		case pg_query.AlterTableType_AT_ColumnDefault:
+		case pg_query.AlterTableType_AT_AddIdentity:
 			{
-
+				col, err := ColumnFromColName(tab, atc.AlterTableCmd.Name)
+				if err != nil {
+					return err
+				}
+				if !col.Attrs.NotNull && !col.Attrs.Pkey {
+					return fmt.Errorf("column %s of table %s must be declared not null before identity can be added", col.Name, tab.Name)
+				}
+				err = addIdentity(col, atc.AlterTableCmd.Def.GetConstraint().GetGeneratedWhen())
+				if err != nil {
+					return err
+				}
 			}
-		case pg_query.AlterTableType_AT_DropConstraint:
+		case pg_query.AlterTableType_AT_SetIdentity:
 			{
-				cons, ok := c.Catalog.Depends.ConstraintsByName[atc.AlterTableCmd.Name]
-				if !ok {
-					return fmt.Errorf("while dropping constraint: constraint %s not found", atc.AlterTableCmd.Name)
+				col, err := ColumnFromColName(tab, atc.AlterTableCmd.Name)
+				if err != nil {
+					return err
 				}
-				c.Catalog.Depends.RemoveConstraint(cons)
-				return nil
+				err = setIdentity(col, atc.AlterTableCmd.Def.GetList().GetItems())
+				if err != nil {
+					return err
+				}
+			}
+		case pg_query.AlterTableType_AT_DropIdentity:
+			{
+				col, err := ColumnFromColName(tab, atc.AlterTableCmd.Name)
+				if err != nil {
+					return err
+				}
+				if col.Attrs.Identity == IdentityNone {
+					if atc.AlterTableCmd.MissingOk {
+						break
+					}
+					return fmt.Errorf("column %s of table %s is not an identity column", col.Name, tab.Name)
+				}
+				col.Attrs.Identity = IdentityNone
+			}
+		case pg_query.AlterTableType_AT_ChangeOwner:
+			{
+				tab.Owner = roleName(atc.AlterTableCmd.Newowner)
 			}
 		case pg_query.AlterTableType_AT_DropNotNull:
 			{
@@ -228,8 +2006,9 @@ func (c *Compiler) AlterTable(stmt *pg_query.AlterTableStmt) error {
 					return fmt.Errorf("can't drop not null constraint from nullable column %s.%s", tab.Name, col.Name)
 				}
 				col.Attrs.NotNull = false
-				return nil
 			}
+		default:
+			c.skip("ALTER TABLE "+upperWords(strings.TrimPrefix(atc.AlterTableCmd.Subtype.String(), "AT_")), "")
 		}
 	}
 	return nil
@@ -237,12 +2016,20 @@ func (c *Compiler) AlterTable(stmt *pg_query.AlterTableStmt) error {
 
 func (c *Compiler) DefineColumn(t *Table, def *pg_query.ColumnDef) error {
 	name := def.Colname
-	pgType := c.TypeFromNode(def.TypeName)
-	err := t.AddColumn(&Column{
-		Table: t,
-		Name:  name,
-		Type:  pgType,
-		Attrs: &ColumnAttributes{},
+	pgType, err := c.TypeFromNode(def.TypeName)
+	if err != nil {
+		return err
+	}
+	anns := c.annotations.For(def.Location)
+	err = t.AddColumn(&Column{
+		OID:            c.Catalog.newOID(),
+		Table:          t,
+		Name:           name,
+		ColumnType:     InternColumnType(pgType, TypeModsFromNode(def.TypeName), len(def.TypeName.ArrayBounds)),
+		Attrs:          &ColumnAttributes{},
+		Annotations:    anns,
+		Classification: annotationClassification(anns),
+		Defined:        c.sourceLocation(def.Location),
 	})
 	if err != nil {
 		return err
@@ -260,27 +2047,42 @@ func (c *Compiler) DropColumn(t *Table, colName string, behavior pg_query.DropBe
 	if !ok {
 		return fmt.Errorf("column %s does not exist", colName)
 	}
-	depends, _ := c.Catalog.Depends.ConstraintsByColumn.Get(col)
-	var funcs []func()
-	for _, con := range depends {
-		if con.DropBehaviour == DropBehaviourRestrict {
-			if behavior != pg_query.DropBehavior_DROP_CASCADE {
-				return fmt.Errorf("can't drop %s because %s depends on it", col.Name, con.Name)
-			}
-			funcs = append(funcs, func() {
-				c.Catalog.Depends.RemoveConstraint(con)
-			})
+	deps := c.Catalog.Depends.DependentsOf(col)
+	if behavior != pg_query.DropBehavior_DROP_CASCADE {
+		if blocking := restrictingDependents(deps, nil); blocking != "" {
+			return fmt.Errorf("can't drop %s because other objects depend on it: %s", col.Name, blocking)
 		}
 	}
-
-	for _, fn := range funcs {
-		fn()
-	}
+	c.dropDependents(deps)
+	c.dropOwnedSequences(Columns{col})
 	c.Catalog.Depends.ConstraintsByColumn.Remove(col)
 	t.Columns.Remove(col.Name)
 	return nil
 }
 
+// dropDependents drops the dependents of deps, and what depends on them in
+// turn.
+func (c *Compiler) dropDependents(deps []*Dependency) {
+
+	for _, dep := range deps {
+		c.dropDependents(c.Catalog.Depends.DependentsOf(dep.Dependent))
+		switch obj := dep.Dependent.(type) {
+		case *Constraint:
+			if _, ok := c.Catalog.Depends.ConstraintsByName[obj.Key()]; ok {
+				c.Catalog.Depends.RemoveConstraint(obj)
+			}
+		case *Index:
+			c.removeIndex(obj)
+		case *Statistics:
+			c.Catalog.Depends.RemoveStatistics(obj)
+		case *RawStatement:
+			c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+				return raw == obj
+			})
+		}
+	}
+}
+
 // FindTableFromRangeVar looks up an existing table from the provided RangeVar.
 func (c *Compiler) FindTableFromRangeVar(r *pg_query.RangeVar) (*Table, error) {
 
@@ -290,9 +2092,7 @@ func (c *Compiler) FindTableFromRangeVar(r *pg_query.RangeVar) (*Table, error) {
 }
 
 func (c *Compiler) FindTableFromSchemaAndName(schemaName, name string) (*Table, error) {
-	if schemaName == "" {
-		schemaName = c.SearchPath
-	}
+	schemaName = c.relationSchema(schemaName, name)
 	sch, ok := c.Catalog.Schemas.Get(schemaName)
 	if !ok {
 		return nil, fmt.Errorf("couldn't find schema %s", schemaName)
@@ -304,24 +2104,57 @@ func (c *Compiler) FindTableFromSchemaAndName(schemaName, name string) (*Table,
 	return tab, nil
 }
 
-func (c *Compiler) TypeFromNode(tn *pg_query.TypeName) *PostgresType {
+func (c *Compiler) TypeFromNode(tn *pg_query.TypeName) (*PostgresType, error) {
 
 	var parts []string
 	for _, n := range tn.Names {
-		val := StringOrPanic(n)
+		val, err := NodeString(n)
+		if err != nil {
+			return nil, err
+		}
 		if val == "pg_catalog" { // TODO is this correct
 			continue
 		}
 		parts = append(parts, val)
 	}
-	return MatchType(strings.Join(parts, "."))
+	name := strings.Join(parts, ".")
+	if t := LookupType(name); t != nil {
+		return t, nil
+	}
+	if e := c.findEnum(parts); e != nil {
+		return OpaqueType(EnumIdent(e)), nil
+	}
+	if c.extensionType(name) {
+		return OpaqueType(name), nil
+	}
+	c.warn(RuleUnknownType, c.sourceLocation(tn.Location).Line, fmt.Sprintf("unknown type %s, treating it as opaque", name))
+	return OpaqueType(name), nil
+}
+
+// TypeModsFromNode returns the integer type modifiers of tn, e.g. the
+// 50 in varchar(50) or the precision and scale in numeric(10, 2).
+func TypeModsFromNode(tn *pg_query.TypeName) []int32 {
+
+	var mods []int32
+	for _, n := range tn.Typmods {
+		ac, ok := n.Node.(*pg_query.Node_AConst)
+		if !ok {
+			continue
+		}
+		iv, ok := ac.AConst.Val.(*pg_query.A_Const_Ival)
+		if !ok {
+			continue
+		}
+		mods = append(mods, iv.Ival.Ival)
+	}
+	return mods
 }
 
 func (c *Compiler) DefineConstraints(t *Table, colName string, constraints []*pg_query.Node) error {
 	for _, n := range constraints {
 		v, ok := n.Node.(*pg_query.Node_Constraint)
 		if !ok {
-			panic("unknown how to parse node " + n.String())
+			return fmt.Errorf("expected Constraint but got %T", n.Node)
 		}
 		err := c.DefineConstraint(t, colName, v.Constraint)
 		if err != nil {
@@ -336,17 +2169,28 @@ func (c *Compiler) DefineConstraint(t *Table, colName string, v *pg_query.Constr
 	switch v.Contype {
 	case pg_query.ConstrType_CONSTR_PRIMARY:
 		{
+			if v.Indexname != "" {
+				return c.constraintUsingIndex(t, ConstraintTypePrimary, v)
+			}
 			name := v.Conname
 			if name == "" {
-				name = t.Name + "_" + "pkey"
+				name = c.chooseName(t.Name, "", "pkey", c.relationTaken(t.Schema))
+			}
+			var constrainsCols Columns
+			var err error
+			if colName != "" {
+				constrainsCols, err = ColumnsFromColNames(t, []string{colName})
+			} else {
+				var names []string
+				names, err = NodeStrings(v.Keys)
+				if err == nil {
+					constrainsCols, err = ColumnsFromColNames(t, names)
+				}
 			}
-			col, err := ColumnFromColName(t, colName)
 			if err != nil {
 				return err
 			}
-			con := &Constraint{Table: t, Name: name, Type: ConstraintTypePrimary, Constrains: Columns{col}}
-			c.Catalog.Depends.AddConstraint(con)
-			return nil
+			return c.addConstraint(&Constraint{Table: t, Name: name, Type: ConstraintTypePrimary, Constrains: constrainsCols}, v.Location)
 		}
 	case pg_query.ConstrType_CONSTR_NOTNULL:
 		{
@@ -359,19 +2203,26 @@ func (c *Compiler) DefineConstraint(t *Table, colName string, v *pg_query.Constr
 		}
 	case pg_query.ConstrType_CONSTR_DEFAULT:
 		{
-			_, err := ColumnFromColName(t, colName)
+			col, err := ColumnFromColName(t, colName)
 			if err != nil {
 				return err
 			}
-			err = c.ParseExpr(v.RawExpr)
+			return c.setDefault(col, v.RawExpr)
+		}
+	case pg_query.ConstrType_CONSTR_IDENTITY:
+		{
+			col, err := ColumnFromColName(t, colName)
 			if err != nil {
 				return err
 			}
-			//col.Attrs.ColumnDefault = v.RawExpr
-			return nil
+			col.Attrs.NotNull = true
+			return addIdentity(col, v.GeneratedWhen)
 		}
 	case pg_query.ConstrType_CONSTR_UNIQUE:
 		{
+			if v.Indexname != "" {
+				return c.constraintUsingIndex(t, ConstraintTypeUnique, v)
+			}
 			constrainsCols := make(Columns, 0, 1)
 			if colName != "" {
 				col, err := ColumnFromColName(t, colName)
@@ -381,7 +2232,10 @@ func (c *Compiler) DefineConstraint(t *Table, colName string, v *pg_query.Constr
 				constrainsCols = append(constrainsCols, col)
 			} else {
 				for _, colRef := range v.Keys {
-					colName := StringOrPanic(colRef)
+					colName, err := NodeString(colRef)
+					if err != nil {
+						return err
+					}
 					col, ok := t.Columns.Get(colName)
 					if !ok {
 						return fmt.Errorf("column %s not found", colName)
@@ -391,14 +2245,13 @@ func (c *Compiler) DefineConstraint(t *Table, colName string, v *pg_query.Constr
 			}
 			name := v.Conname
 			if name == "" {
-				name = strings.Join([]string{t.Name, constrainsCols.JoinColumnNames("_"), "key"}, "_")
+				name = c.chooseName(t.Name, nameAddition(constrainsCols.Names()), "key", c.relationTaken(t.Schema))
 			}
-			c.Catalog.Depends.AddConstraint(&Constraint{Table: t,
+			return c.addConstraint(&Constraint{Table: t,
 				Name:       name,
 				Type:       ConstraintTypeUnique,
 				Constrains: constrainsCols,
-			})
-			return nil
+			}, v.Location)
 		}
 	case pg_query.ConstrType_CONSTR_FOREIGN:
 		{
@@ -406,16 +2259,41 @@ func (c *Compiler) DefineConstraint(t *Table, colName string, v *pg_query.Constr
 			schema := v.Pktable.Schemaname
 			table := v.Pktable.Relname
 			for _, colRef := range v.PkAttrs {
-				colName := StringOrPanic(colRef)
+				colName, err := NodeString(colRef)
+				if err != nil {
+					return err
+				}
 				col, err := c.FindColumn(schema, table, colName)
 				if err != nil {
 					return fmt.Errorf("couldn't find column '%s' in table '%s'", colName, table)
 				}
 				refers = append(refers, col)
 			}
+			if len(v.PkAttrs) == 0 {
+				// Without a column list, the referenced table's primary key is
+				// referenced
+				pkTable, err := c.FindTableFromSchemaAndName(schema, table)
+				if err != nil {
+					return err
+				}
+				pkey := c.Catalog.Depends.PrimaryKey(pkTable)
+				if pkey == nil {
+					return fmt.Errorf("there is no primary key for referenced table \"%s\"", table)
+				}
+				refers = append(refers, pkey.Constrains...)
+			}
+			if len(refers) > 0 && refers[0].Table.Temporary() != t.Temporary() {
+				if t.Temporary() {
+					return fmt.Errorf("constraints on temporary tables may reference only temporary tables")
+				}
+				return fmt.Errorf("constraints on permanent tables may reference only permanent tables")
+			}
 			constrainsCols := make(Columns, 0, len(v.FkAttrs))
 			for _, colRef := range v.FkAttrs {
-				colName := StringOrPanic(colRef)
+				colName, err := NodeString(colRef)
+				if err != nil {
+					return err
+				}
 				col, ok := t.Columns.Get(colName)
 				if !ok {
 					return fmt.Errorf("column %s not found", colName)
@@ -435,27 +2313,177 @@ func (c *Compiler) DefineConstraint(t *Table, colName string, v *pg_query.Constr
 			}
 			name := v.Conname
 			if name == "" && len(constrainsCols) > 0 {
-				name = strings.Join([]string{t.Name, constrainsCols.JoinColumnNames("_"), "fkey"}, "_")
+				name = c.chooseName(t.Name, nameAddition(constrainsCols.Names()), "fkey", c.constraintTaken(t.Schema))
+			}
+			var match ForeignKeyMatch
+			switch v.FkMatchtype {
+			case "f":
+				match = ForeignKeyMatchFull
+			case "p":
+				return fmt.Errorf("MATCH PARTIAL not yet implemented")
+			}
+			var setCols Columns
+			for _, colRef := range v.FkDelSetCols {
+				colName, err := NodeString(colRef)
+				if err != nil {
+					return err
+				}
+				col, ok := t.Columns.Get(colName)
+				if !ok {
+					return fmt.Errorf("column %s not found", colName)
+				}
+				if !slices.Contains(constrainsCols, col) {
+					return fmt.Errorf("column %s referenced in ON DELETE SET action must be part of foreign key", colName)
+				}
+				setCols = append(setCols, col)
 			}
-			c.Catalog.Depends.AddConstraint(&Constraint{
+			return c.addConstraint(&Constraint{
 				Table:         t,
 				Type:          ConstraintTypeForeignKey,
 				DropBehaviour: DropBehaviourRestrict,
 				Name:          name,
 				Refers:        refers,
 				Constrains:    constrainsCols,
-			})
-			return nil
+				NotValid:      v.SkipValidation,
+				Match:         match,
+				OnUpdate:      foreignKeyActions[v.FkUpdAction],
+				OnDelete:      foreignKeyActions[v.FkDelAction],
+				SetColumns:    setCols,
+			}, v.Location)
+		}
+	case pg_query.ConstrType_CONSTR_CHECK:
+		{
+			return c.defineCheck(t, colName, v)
 		}
 	}
-	return fmt.Errorf("not yet able to process constraint type %v", v.Contype)
+	return c.unsupported(strings.TrimPrefix(v.Contype.String(), "CONSTR_")+" constraint",
+		fmt.Errorf("%w constraint type %v", ErrUnsupported, v.Contype))
 }
 
-func (c *Compiler) FindColumn(schema, table, name string) (*Column, error) {
+// defineCheck adds a check constraint. Like Postgres, unnamed constraints
+// are named after the table and the first column they refer to, numbered
+// if the name is taken.
+func (c *Compiler) defineCheck(t *Table, colName string, v *pg_query.Constraint) error {
 
-	if schema == "" {
-		schema = c.SearchPath
+	con := &Constraint{Table: t, Type: ConstraintTypeCheck, expr: v.RawExpr, NotValid: v.SkipValidation, NoInherit: v.IsNoInherit}
+	var err error
+	con.Expr, err = DeparseExpr(v.RawExpr)
+	if err != nil {
+		return fmt.Errorf("while deparsing check constraint: %w", err)
+	}
+	err = columnReferences(t, v.RawExpr, func(col *Column) {
+		if !slices.Contains(con.Constrains, col) {
+			con.Constrains = append(con.Constrains, col)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	if colName != "" && len(con.Constrains) == 0 {
+		col, err := ColumnFromColName(t, colName)
+		if err != nil {
+			return err
+		}
+		con.Constrains = append(con.Constrains, col)
+	}
+	con.Name = v.Conname
+	if con.Name == "" {
+		var col string
+		if len(con.Constrains) > 0 {
+			col = con.Constrains[0].Name
+		}
+		con.Name = c.chooseName(t.Name, col, "check", c.constraintTaken(t.Schema))
+	}
+	return c.addConstraint(con, v.Location)
+}
+
+// constraintUsingIndex handles ADD PRIMARY KEY or UNIQUE USING INDEX, which
+// makes an existing unique index the constraint's. The index is renamed to
+// the constraint's name and is no longer an index of its own.
+func (c *Compiler) constraintUsingIndex(t *Table, typ ConstraintType, v *pg_query.Constraint) error {
+
+	idx, ok := c.Catalog.Depends.IndexesByName[t.Schema+"."+v.Indexname]
+	if !ok {
+		return fmt.Errorf("index %s not found", v.Indexname)
+	}
+	switch {
+	case idx.Table != t:
+		return fmt.Errorf("index %s does not belong to table %s", idx.Name, t.Name)
+	case !idx.Unique:
+		return fmt.Errorf("%s is not a unique index", idx.Name)
+	case idx.Predicate != "":
+		return fmt.Errorf("%s is a partial index", idx.Name)
+	case idx.Method != "" && idx.Method != "btree":
+		return fmt.Errorf("index %s is not a btree", idx.Name)
 	}
+	var cols Columns
+	for i, elem := range idx.Elems {
+		if elem.Column == nil {
+			return fmt.Errorf("index %s contains expressions", idx.Name)
+		}
+		if elem.Descending || elem.NullsFirst || elem.Opclass != "" {
+			return fmt.Errorf("index %s column number %d does not have default sorting behavior", idx.Name, i+1)
+		}
+		cols = append(cols, elem.Column)
+	}
+	name := v.Conname
+	if name == "" {
+		name = idx.Name
+	}
+	err := c.addConstraint(&Constraint{Table: t, Name: name, Type: typ, Constrains: cols, Index: idx}, v.Location)
+	if err != nil {
+		return err
+	}
+	c.Catalog.Depends.RemoveIndex(idx)
+	idx.Name = name
+	if typ == ConstraintTypePrimary {
+		for _, col := range cols {
+			col.Attrs.NotNull = true
+		}
+	}
+	return nil
+}
+
+// addConstraint adds con, defined at offset in the source, unless its table
+// already has a constraint of the same name, or it has an index whose name
+// another relation in the schema has.
+func (c *Compiler) addConstraint(con *Constraint, offset int32) error {
+
+	con.OID, con.Defined = c.Catalog.newOID(), c.sourceLocation(offset)
+	if con.Type == ConstraintTypePrimary {
+		if orig := c.Catalog.Depends.PrimaryKey(con.Table); orig != nil {
+			return fmt.Errorf("multiple primary keys for table %s are not allowed%s", con.Table.Name, duplicateLocations(orig.Defined, con.Defined))
+		}
+	}
+	if orig, ok := c.Catalog.Depends.Constraint(con.Table, con.Name); ok {
+		return fmt.Errorf("constraint already exists: %s%s", con.Name, duplicateLocations(orig.Defined, con.Defined))
+	}
+	if con.Indexed() {
+		// The constraint's index is a relation of its own, unless it's an
+		// index of the same name being made the constraint's
+		if (con.Index == nil || con.Index.Name != con.Name) && c.relationTaken(con.Table.Schema)(con.Name) {
+			return fmt.Errorf("relation %s.%s already exists", con.Table.Schema, con.Name)
+		}
+		kind := "unique"
+		if con.Type == ConstraintTypePrimary {
+			kind = "primary key"
+		}
+		for i, col := range con.Constrains {
+			if slices.Contains(con.Constrains[:i], col) {
+				return fmt.Errorf("column %s appears twice in %s constraint %s", col.Name, kind, con.Name)
+			}
+		}
+	}
+	c.Catalog.Depends.AddConstraint(con)
+	if con.Indexed() && con.Index == nil {
+		c.note(RuleImplicitIndex, con.Defined.Line, fmt.Sprintf("constraint %s will create implicit index %s for table %s", con.Name, con.Name, con.Table.Name))
+	}
+	return nil
+}
+
+func (c *Compiler) FindColumn(schema, table, name string) (*Column, error) {
+
+	schema = c.relationSchema(schema, table)
 	s, ok := c.Catalog.Schemas.Get(schema)
 	if !ok {
 		return nil, fmt.Errorf("schema %s not found", schema)
@@ -471,86 +2499,90 @@ func (c *Compiler) FindColumn(schema, table, name string) (*Column, error) {
 	return col, nil
 }
 
-func (c *Compiler) ParseExpr(n *pg_query.Node) error {
+// DeparseExpr converts an expression node back into SQL text, e.g. for
+// storing column defaults.
+func DeparseExpr(n *pg_query.Node) (string, error) {
 
-	switch x := n.Node.(type) {
-	case *pg_query.Node_SqlvalueFunction:
-		{
-			// A value function is e.g. CURRENT_TIMESTAMP -
-			// looks like a value but behaves like a function
-			fmt.Println(x.SqlvalueFunction.Op)
-		}
-	case *pg_query.Node_FuncCall:
-		{
-			// Function invocation e.g. NOW()
-			fmt.Println(StringsOrPanic(x.FuncCall.Funcname))
-			// TODO: x.FuncCall.Args...
-		}
-	case *pg_query.Node_AConst:
-		{
-			if x.AConst.Isnull {
-				fmt.Println("<nil>")
-				return nil
-			}
+	res := &pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{
+		Stmt: &pg_query.Node{Node: &pg_query.Node_SelectStmt{SelectStmt: &pg_query.SelectStmt{
+			TargetList: []*pg_query.Node{pg_query.MakeResTargetNodeWithVal(n, 0)},
+		}}},
+	}}}
+	s, err := pg_query.Deparse(res)
+	if err != nil {
+		return "", fmt.Errorf("while deparsing expression: %w", err)
+	}
+	return strings.TrimPrefix(s, "SELECT "), nil
+}
 
-			// Constant default value
-			switch sv := x.AConst.Val.(type) {
-			case *pg_query.A_Const_Sval:
-				{
-					fmt.Println(sv.Sval.Sval)
-				}
-			case *pg_query.A_Const_Boolval:
-				{
-					fmt.Println(sv.Boolval.Boolval)
-				}
-			case *pg_query.A_Const_Ival:
-				{
-					fmt.Println(sv.Ival.Ival)
-				}
-			case *pg_query.A_Const_Fval:
-				{
-					fmt.Println(sv.Fval.Fval)
-				}
-			case *pg_query.A_Const_Bsval:
-				{
-					fmt.Println(sv.Bsval.Bsval)
-				}
-			}
+// DefElemValue renders the value of an option, such as the 32 in
+// "siglen=32".
+func DefElemValue(n *pg_query.Node) string {
+
+	switch v := n.Node.(type) {
+	case *pg_query.Node_Integer:
+		return strconv.Itoa(int(v.Integer.Ival))
+	case *pg_query.Node_Float:
+		return v.Float.Fval
+	case *pg_query.Node_Boolean:
+		return strconv.FormatBool(v.Boolean.Boolval)
+	case *pg_query.Node_String_:
+		return QuoteLiteral(v.String_.Sval)
+	case *pg_query.Node_TypeName:
+		names, err := NodeStrings(v.TypeName.Names)
+		if err == nil {
+			return strings.Join(names, ".")
 		}
 	}
-
-	return nil
+	return ""
 }
 
-func TableNameFromNodeList(l *pg_query.List) (schema string, table string) {
+func TableNameFromNodeList(l *pg_query.List) (schema string, table string, err error) {
 
-	if len(l.Items) == 1 {
-		table = StringOrPanic(l.Items[0])
-		return
-	}
-	if len(l.Items) == 2 {
-		schema = StringOrPanic(l.Items[0])
-		table = StringOrPanic(l.Items[1])
+	switch len(l.GetItems()) {
+	case 1:
+		table, err = NodeString(l.Items[0])
+	case 2:
+		schema, err = NodeString(l.Items[0])
+		if err == nil {
+			table, err = NodeString(l.Items[1])
+		}
 	}
 	return
 }
 
-func StringsOrPanic(ns []*pg_query.Node) []string {
+// NodeStrings returns the strings ns hold, as NodeString does.
+func NodeStrings(ns []*pg_query.Node) ([]string, error) {
 
 	ret := make([]string, 0, len(ns))
 	for _, n := range ns {
-		ret = append(ret, StringOrPanic(n))
+		s, err := NodeString(n)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, s)
 	}
-	return ret
+	return ret, nil
 }
 
-func StringOrPanic(n *pg_query.Node) string {
+// NodeString returns the string n holds, as the parts of names do, or an
+// error if n is some other node, such as the * of t.*.
+func NodeString(n *pg_query.Node) (string, error) {
 
-	s, ok := n.Node.(*pg_query.Node_String_)
+	s, ok := n.GetNode().(*pg_query.Node_String_)
 	if !ok {
-		panic("unknown how to parse node " + n.String())
+		return "", fmt.Errorf("expected a name, found %s", nodeKind(n))
+	}
+	return s.String_.Sval, nil
+}
+
+// nodeKind names the kind of node n is, for errors.
+func nodeKind(n *pg_query.Node) string {
+
+	if n.GetNode() == nil {
+		return "nothing"
 	}
-	return s.String_.Sval
+	return strings.TrimPrefix(fmt.Sprintf("%T", n.Node), "*pg_query.Node_")
 }
 
 func ColumnFromColName(t *Table, name string) (*Column, error) {
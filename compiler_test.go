@@ -1,11 +1,14 @@
 package main
 
 import (
+	"flag"
 	pg_query "github.com/pganalyze/pg_query_go/v5"
 	"github.com/samber/lo"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"io"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -13,11 +16,23 @@ func assertParse(t *testing.T, stmts string) *Compiler {
 	parse, err := pg_query.Parse(stmts)
 	require.Nil(t, err)
 	c := NewCompiler()
+	c.ValidateCatalog = true
 	err = c.ParseStatements(parse)
 	assert.Nil(t, err)
 	return c
 }
 
+// constraintsByName returns the constraints of c keyed by their names alone,
+// which the tests don't reuse across tables.
+func constraintsByName(c *Compiler) map[string]*Constraint {
+
+	ret := make(map[string]*Constraint)
+	for key, con := range c.Catalog.Depends.ConstraintsByName {
+		ret[key.Name] = con
+	}
+	return ret
+}
+
 func assertParseError(t *testing.T, stmts string, messageContains ...string) {
 	parse, err := pg_query.Parse(stmts)
 	require.Nil(t, err)
@@ -72,8 +87,9 @@ func assertConstraints(t *testing.T, c *Compiler, col *Column, cons ...Constrain
 	expectedConstraints := make(Constraints, 0, len(cons))
 	for _, con := range cons {
 		var actualConstraint *Constraint
-		actualConstraint, ok = c.Catalog.Depends.ConstraintsByName[con.Name]
+		actualConstraint, ok = constraintsByName(c)[con.Name]
 		require.True(t, ok, "no constraint with name %s found, actual constraints are: %v", con.Name, consNames)
+		con.OID, con.Defined = actualConstraint.OID, actualConstraint.Defined
 		assert.Equal(t, con, *actualConstraint)
 		expectedConstraints = append(expectedConstraints, actualConstraint)
 	}
@@ -94,7 +110,7 @@ func TestCompiler_CreateTable(t *testing.T) {
 
 	table := assertTable(t, c, "users")
 	{
-		col := assertColumn(t, table, "id", Serial, ColumnAttributes{Pkey: true})
+		col := assertColumn(t, table, "id", Serial, ColumnAttributes{Pkey: true, PkeyPosition: 1})
 		assertConstraints(t, c, col, Constraint{
 			Table:      table,
 			Name:       "users_pkey",
@@ -116,7 +132,7 @@ func TestCompiler_CreateTable(t *testing.T) {
 		assertConstraints(t, c, col)
 	}
 	{
-		col := assertColumn(t, table, "created_at", Timestamp, ColumnAttributes{})
+		col := assertColumn(t, table, "created_at", Timestamp, ColumnAttributes{Default: "current_timestamp"})
 		assertConstraints(t, c, col)
 	}
 }
@@ -152,7 +168,7 @@ func TestCompiler_CreateTable_ForeignKey(t *testing.T) {
 
 	c := assertParse(t, sql)
 	base := assertTable(t, c, "base")
-	baseId := assertColumn(t, base, "id", Bigserial, ColumnAttributes{Pkey: true})
+	baseId := assertColumn(t, base, "id", Bigserial, ColumnAttributes{Pkey: true, PkeyPosition: 1})
 
 	tab := assertTable(t, c, "referrer")
 	refersId := assertColumn(t, tab, "id", Bigint, ColumnAttributes{})
@@ -178,7 +194,7 @@ func TestCompiler_MultiColumnUniqueConstraint(t *testing.T) {
 	tab := assertTable(t, c, "unique_constrained")
 	uk1 := assertColumn(t, tab, "uk1", Integer, ColumnAttributes{NotNull: true})
 	uk2 := assertColumn(t, tab, "uk2", Integer, ColumnAttributes{NotNull: true})
-	cons, ok := c.Catalog.Depends.ConstraintsByName["unique_constrained_uk1_uk2_key"]
+	cons, ok := constraintsByName(c)["unique_constrained_uk1_uk2_key"]
 	require.True(t, ok)
 	assert.ElementsMatch(t, Columns{uk1, uk2}, cons.Constrains)
 }
@@ -222,7 +238,7 @@ func TestCompiler_AlterTable_AddConstraint_ForeignKey(t *testing.T) {
 
 	c := assertParse(t, sql)
 	base := assertTable(t, c, "base")
-	baseId := assertColumn(t, base, "id", Bigserial, ColumnAttributes{Pkey: true})
+	baseId := assertColumn(t, base, "id", Bigserial, ColumnAttributes{Pkey: true, PkeyPosition: 1})
 
 	tab := assertTable(t, c, "referrer")
 	refersId := assertColumn(t, tab, "id", Bigint, ColumnAttributes{})
@@ -236,6 +252,195 @@ func TestCompiler_AlterTable_AddConstraint_ForeignKey(t *testing.T) {
 	})
 }
 
+func TestCompiler_AlterTable_ValidateConstraint(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE base (id int PRIMARY KEY);
+	CREATE TABLE created (base_id int REFERENCES base (id), CONSTRAINT created_fk FOREIGN KEY (base_id) REFERENCES base (id) NOT VALID);
+	CREATE TABLE referrer (base_id int, other_id int);
+	ALTER TABLE referrer ADD CONSTRAINT referrer_base_fk FOREIGN KEY (base_id) REFERENCES base (id) NOT VALID;
+	ALTER TABLE referrer ADD CONSTRAINT referrer_other_fk FOREIGN KEY (other_id) REFERENCES base (id) NOT VALID;
+	ALTER TABLE referrer VALIDATE CONSTRAINT referrer_other_fk;
+	`)
+	cons := constraintsByName(c)
+	assert.False(t, cons["created_fk"].NotValid)
+	assert.True(t, cons["referrer_base_fk"].NotValid)
+	assert.False(t, cons["referrer_other_fk"].NotValid)
+	assert.Equal(t, `CONSTRAINT referrer_base_fk FOREIGN KEY (base_id) REFERENCES base (id) NOT VALID`, ConstraintDefinition(cons["referrer_base_fk"]))
+
+	assertParseError(t, `
+	CREATE TABLE base (id int PRIMARY KEY);
+	ALTER TABLE base VALIDATE CONSTRAINT missing;
+	`, "constraint missing not found")
+	assertParseError(t, `
+	CREATE TABLE base (id int PRIMARY KEY);
+	ALTER TABLE base VALIDATE CONSTRAINT base_pkey;
+	`, "is not a foreign key or check constraint")
+}
+
+func TestCompiler_ForeignKeyOptions(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE tenants (id int PRIMARY KEY);
+	CREATE TABLE users (tenant_id int, id int, PRIMARY KEY (tenant_id, id));
+	CREATE TABLE posts (
+		tenant_id int REFERENCES tenants (id) ON DELETE CASCADE,
+		author_id int,
+		CONSTRAINT posts_author_fkey FOREIGN KEY (tenant_id, author_id) REFERENCES users (tenant_id, id)
+			MATCH FULL ON UPDATE RESTRICT ON DELETE SET NULL (author_id)
+	);
+	`)
+	cons := constraintsByName(c)
+	assert.Equal(t, "CONSTRAINT posts_tenant_id_fkey FOREIGN KEY (tenant_id) REFERENCES tenants (id) ON DELETE CASCADE",
+		ConstraintDefinition(cons["posts_tenant_id_fkey"]))
+	assert.Equal(t, "CONSTRAINT posts_author_fkey FOREIGN KEY (tenant_id, author_id) REFERENCES users (tenant_id, id) MATCH FULL ON UPDATE RESTRICT ON DELETE SET NULL (author_id)",
+		ConstraintDefinition(cons["posts_author_fkey"]))
+
+	assertParseError(t, `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE posts (author_id int, editor_id int, FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL (editor_id));
+	`, "column editor_id referenced in ON DELETE SET action must be part of foreign key")
+}
+
+func TestCompiler_ForeignKeyToPrimaryKey(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE tenants (region text, id int, PRIMARY KEY (region, id));
+	CREATE TABLE posts (user_id int REFERENCES users, region text, tenant_id int, FOREIGN KEY (region, tenant_id) REFERENCES tenants);
+	CREATE TABLE nodes (id int PRIMARY KEY, parent_id int REFERENCES nodes);
+	`)
+	cons := constraintsByName(c)
+	assert.Equal(t, "CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id)",
+		ConstraintDefinition(cons["posts_user_id_fkey"]))
+	assert.Equal(t, "CONSTRAINT posts_region_tenant_id_fkey FOREIGN KEY (region, tenant_id) REFERENCES tenants (region, id)",
+		ConstraintDefinition(cons["posts_region_tenant_id_fkey"]))
+	assert.Equal(t, "CONSTRAINT nodes_parent_id_fkey FOREIGN KEY (parent_id) REFERENCES nodes (id)",
+		ConstraintDefinition(cons["nodes_parent_id_fkey"]))
+	assert.Len(t, c.Catalog.Depends.ReferencingConstraints(assertTable(t, c, "users")), 1)
+
+	assertParseError(t, `
+	CREATE TABLE users (id int);
+	CREATE TABLE posts (user_id int REFERENCES users);
+	`, `there is no primary key for referenced table "users"`)
+}
+
+func TestCompiler_CompositePrimaryKey(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE memberships (user_id int, group_id int, role text, PRIMARY KEY (group_id, user_id));
+	`)
+	tab := assertTable(t, c, "memberships")
+	assertColumn(t, tab, "user_id", Integer, ColumnAttributes{Pkey: true, PkeyPosition: 2})
+	assertColumn(t, tab, "group_id", Integer, ColumnAttributes{Pkey: true, PkeyPosition: 1})
+	assertColumn(t, tab, "role", Text, ColumnAttributes{})
+	assert.Equal(t, []string{"group_id", "user_id"}, c.Catalog.Depends.PrimaryKey(tab).Constrains.Names())
+
+	require.Nil(t, c.Compile(`ALTER TABLE memberships DROP CONSTRAINT memberships_pkey;`))
+	assertColumn(t, tab, "group_id", Integer, ColumnAttributes{})
+	assert.Nil(t, c.Catalog.Depends.PrimaryKey(tab))
+
+	assertParseError(t, `
+	CREATE TABLE memberships (user_id int PRIMARY KEY, group_id int, PRIMARY KEY (user_id, group_id));
+	`, "multiple primary keys for table memberships are not allowed")
+	assertParseError(t, `
+	CREATE TABLE memberships (user_id int, group_id int, PRIMARY KEY (user_id, user_id));
+	`, "column user_id appears twice in primary key constraint memberships_pkey")
+}
+
+func TestCompiler_Attnum(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE events (id int, payload text, at timestamptz) PARTITION BY RANGE (at);
+	ALTER TABLE events DROP COLUMN payload;
+	ALTER TABLE events ADD COLUMN kind text;
+	ALTER TABLE events RENAME COLUMN at TO occurred_at;
+	CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+	`)
+	attnums := func(tab *Table) map[string]int {
+		ret := make(map[string]int)
+		for _, col := range tab.Columns.List() {
+			ret[col.Name] = col.Attnum
+		}
+		return ret
+	}
+	events := assertTable(t, c, "events")
+	assert.Equal(t, map[string]int{"id": 1, "occurred_at": 3, "kind": 4}, attnums(events))
+	// A partition's columns are numbered afresh
+	assert.Equal(t, map[string]int{"id": 1, "occurred_at": 2, "kind": 3}, attnums(assertTable(t, c, "events_2024")))
+
+	// Numbers taken by a statement which fails are given back
+	require.NotNil(t, c.Compile(`ALTER TABLE events ADD COLUMN a int, ADD COLUMN id int;`))
+	require.Nil(t, c.Compile(`ALTER TABLE events ADD COLUMN b int;`))
+	assert.Equal(t, 5, attnums(events)["b"])
+}
+
+func TestCompiler_CheckConstraints(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE products (
+		price numeric CHECK (price > 0),
+		discount numeric CHECK (discount >= 0) CHECK (discount < 100),
+		CHECK (discount < price),
+		CONSTRAINT products_named CHECK (true) NO INHERIT
+	);
+	ALTER TABLE products ADD CONSTRAINT products_late CHECK (price < 1000) NOT VALID;
+	ALTER TABLE products RENAME COLUMN price TO unit_price;
+	ALTER TABLE products DROP COLUMN discount;
+	`)
+	tab := assertTable(t, c, "public.products")
+	var defs []string
+	for _, con := range c.Catalog.Depends.TableConstraints(tab) {
+		defs = append(defs, ConstraintDefinition(con))
+	}
+	assert.Equal(t, []string{
+		"CONSTRAINT products_late CHECK (unit_price < 1000) NOT VALID",
+		"CONSTRAINT products_named CHECK (true) NO INHERIT",
+		"CONSTRAINT products_price_check CHECK (unit_price > 0)",
+	}, defs)
+	assert.Equal(t, "unit_price", constraintsByName(c)["products_late"].Constrains.JoinColumnNames(","))
+	assert.Empty(t, constraintsByName(c)["products_named"].Constrains)
+
+	c = assertParse(t, `
+	CREATE TABLE products (discount numeric CHECK (discount >= 0) CHECK (discount < 100), price numeric, CHECK (discount < price));
+	ALTER TABLE products VALIDATE CONSTRAINT products_discount_check1;
+	`)
+	assert.Equal(t, "discount,price", constraintsByName(c)["products_discount_check2"].Constrains.JoinColumnNames(","))
+
+	assertParseError(t, "CREATE TABLE products (price numeric CHECK (cost > 0));", "column cost not found")
+}
+
+func TestCompiler_ConstraintUsingIndex(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int, email text, tenant_id int);
+	CREATE UNIQUE INDEX users_id_idx ON users (id);
+	CREATE UNIQUE INDEX users_email_idx ON users (email) INCLUDE (tenant_id);
+	ALTER TABLE users ADD CONSTRAINT users_pkey PRIMARY KEY USING INDEX users_id_idx;
+	ALTER TABLE users ADD UNIQUE USING INDEX users_email_idx;
+	`)
+	users := assertTable(t, c, "users")
+	assert.Empty(t, c.Catalog.Depends.TableIndexes(users))
+	pkey := constraintsByName(c)["users_pkey"]
+	assert.Equal(t, "users_pkey", pkey.Index.Name)
+	assert.Equal(t, "CONSTRAINT users_pkey PRIMARY KEY (id)", ConstraintDefinition(pkey))
+	assert.Equal(t, "CONSTRAINT users_email_idx UNIQUE (email) INCLUDE (tenant_id)",
+		ConstraintDefinition(constraintsByName(c)["users_email_idx"]))
+	id, _ := users.Columns.Get("id")
+	assert.True(t, id.Attrs.NotNull)
+	assert.True(t, id.Attrs.Pkey)
+
+	const table = `
+	CREATE TABLE users (id int, email text);
+	CREATE INDEX users_id_idx ON users (id);
+	CREATE UNIQUE INDEX users_email_idx ON users (email DESC);
+	CREATE UNIQUE INDEX users_lower_email_idx ON users (lower(email));
+	CREATE UNIQUE INDEX users_email_partial_idx ON users (email) WHERE id > 0;
+	`
+	assertParseError(t, table+`ALTER TABLE users ADD PRIMARY KEY USING INDEX users_id_idx;`, "users_id_idx is not a unique index")
+	assertParseError(t, table+`ALTER TABLE users ADD UNIQUE USING INDEX users_email_idx;`, "does not have default sorting behavior")
+	assertParseError(t, table+`ALTER TABLE users ADD UNIQUE USING INDEX users_lower_email_idx;`, "contains expressions")
+	assertParseError(t, table+`ALTER TABLE users ADD UNIQUE USING INDEX users_email_partial_idx;`, "is a partial index")
+	assertParseError(t, table+`ALTER TABLE users ADD UNIQUE USING INDEX missing;`, "index missing not found")
+	assertParseError(t, `
+	CREATE TABLE users (id int PRIMARY KEY);
+	DROP INDEX users_pkey;
+	`, "cannot drop index users_pkey because constraint users_pkey on table users requires it")
+}
+
 func TestCompiler_AlterTable_DropConstraint_ForeignKey(t *testing.T) {
 	const sql = `
 	CREATE TABLE base (
@@ -256,6 +461,85 @@ func TestCompiler_AlterTable_DropConstraint_ForeignKey(t *testing.T) {
 	assertConstraints(t, c, refersId)
 }
 
+func TestCompiler_ConstraintNamesPerTable(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE a (n int CONSTRAINT positive CHECK (n > 0));
+	CREATE TABLE b (n int CONSTRAINT positive CHECK (n > 0));
+	`)
+	ta, tb := assertTable(t, c, "a"), assertTable(t, c, "b")
+	require.Len(t, c.Catalog.Depends.TableConstraints(ta), 1)
+	require.Len(t, c.Catalog.Depends.TableConstraints(tb), 1)
+
+	require.Nil(t, c.Compile(`ALTER TABLE a DROP CONSTRAINT positive;`))
+	assert.Empty(t, c.Catalog.Depends.TableConstraints(ta))
+	con, ok := c.Catalog.Depends.Constraint(tb, "positive")
+	require.True(t, ok)
+	assert.Equal(t, tb, con.Table)
+
+	require.Nil(t, c.Compile(`ALTER TABLE b RENAME CONSTRAINT positive TO b_positive; ALTER TABLE a ADD CONSTRAINT b_positive CHECK (n > 0);`))
+	assert.Len(t, c.Catalog.Depends.ConstraintsNamed("b_positive"), 2)
+
+	// The indexes of primary keys and unique constraints are relations, so
+	// their names can't be reused in the schema
+	assertParseError(t, `
+	CREATE TABLE a (id int);
+	CREATE TABLE b (id int);
+	ALTER TABLE b ADD CONSTRAINT a_pkey PRIMARY KEY (id);
+	ALTER TABLE a ADD CONSTRAINT a_pkey PRIMARY KEY (id);
+	`, "relation public.a_pkey already exists")
+	assertParseError(t, `
+	CREATE TABLE a (id int CONSTRAINT a UNIQUE);
+	`, "relation public.a already exists")
+	assertParseError(t, `
+	CREATE TABLE a (id int PRIMARY KEY);
+	CREATE TABLE b (id int UNIQUE);
+	ALTER TABLE b RENAME CONSTRAINT b_id_key TO a_pkey;
+	`, "relation public.a_pkey already exists")
+}
+
+func TestCompiler_AlterTable_MultipleCommands(t *testing.T) {
+	const sql = `
+	CREATE TABLE test (
+		id int CONSTRAINT test_pkey PRIMARY KEY,
+		b text,
+		CONSTRAINT test_b_key UNIQUE (b)
+	);
+
+	ALTER TABLE test ADD COLUMN a int, ALTER COLUMN b SET NOT NULL, DROP CONSTRAINT test_b_key, ALTER COLUMN a SET DEFAULT 1;
+	`
+	c := assertParse(t, sql)
+	tab := assertTable(t, c, "test")
+	assertColumn(t, tab, "a", Integer, ColumnAttributes{Default: "1"})
+	b := assertColumn(t, tab, "b", Text, ColumnAttributes{NotNull: true})
+	assertConstraints(t, c, b)
+}
+
+func TestCompiler_AlterTable_MultipleCommands_RollsBack(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE test (
+		id int CONSTRAINT test_pkey PRIMARY KEY,
+		b text,
+		CONSTRAINT test_b_key UNIQUE (b)
+	);
+	`)
+	tab := assertTable(t, c, "test")
+	b, _ := tab.Columns.Get("b")
+
+	err := c.Compile(`ALTER TABLE test ADD COLUMN a int, ALTER COLUMN b SET NOT NULL, DROP COLUMN id, DROP CONSTRAINT test_b_key, DROP CONSTRAINT nope;`)
+	assert.ErrorContains(t, err, "constraint nope not found")
+	assert.Equal(t, []string{"id", "b"}, Columns(tab.Columns.List()).Names())
+	assertColumn(t, tab, "id", Integer, ColumnAttributes{Pkey: true, PkeyPosition: 1})
+	assertColumn(t, tab, "b", Text, ColumnAttributes{})
+	cons, _ := c.Catalog.Depends.ConstraintsByColumn.Get(b)
+	require.Len(t, cons, 1)
+	assert.Equal(t, "test_b_key", cons[0].Name)
+	assert.Contains(t, constraintsByName(c), "test_pkey")
+
+	// The table can still be altered once the failed statement is undone
+	require.Nil(t, c.Compile(`ALTER TABLE test ADD COLUMN a int, DROP CONSTRAINT IF EXISTS nope;`))
+	assert.Equal(t, []string{"id", "b", "a"}, Columns(tab.Columns.List()).Names())
+}
+
 func TestCompiler_Drop_Table(t *testing.T) {
 	const sql = `
 	CREATE SCHEMA base;
@@ -289,7 +573,507 @@ CREATE TABLE defaulters (
 `
 
 func TestCompiler_DefaultVariants(t *testing.T) {
-	assertParse(t, defaultVariants)
+	c := assertParse(t, defaultVariants)
+	tab := assertTable(t, c, "defaulters")
+	assertColumn(t, tab, "time1", Timestamptz, ColumnAttributes{Default: "now()"})
+	assertColumn(t, tab, "time2", Timestamptz, ColumnAttributes{Default: "current_timestamp"})
+	assertColumn(t, tab, "constant", Text, ColumnAttributes{Default: "'abcd'"})
+	assertColumn(t, tab, "expression", Integer, ColumnAttributes{Default: "10 + 1"})
+	assertColumn(t, tab, "nully", Text, ColumnAttributes{Default: "NULL"})
+}
+
+func TestCompiler_AlterTable_ColumnDefault(t *testing.T) {
+	const sql = `
+	CREATE TABLE test (
+		a text default 'a',
+		b text
+	);
+
+	ALTER TABLE test ALTER COLUMN a DROP DEFAULT, ALTER COLUMN b SET DEFAULT 'b';
+	`
+	c := assertParse(t, sql)
+	tab := assertTable(t, c, "test")
+	assertColumn(t, tab, "a", Text, ColumnAttributes{})
+	assertColumn(t, tab, "b", Text, ColumnAttributes{Default: "'b'"})
+}
+
+func TestCompiler_Annotations(t *testing.T) {
+	const sql = `
+	-- @audit
+	CREATE TABLE users (
+		-- @seed email
+		-- @pii high
+		contact text NOT NULL,
+		notes text, -- @pii
+		/* not an annotation */ other text
+	);
+	`
+	c := NewCompiler()
+	require.Nil(t, c.Compile(sql))
+	tab := assertTable(t, c, "users")
+	assert.Equal(t, Annotations{"audit": ""}, tab.Annotations)
+	contact, _ := tab.Columns.Get("contact")
+	assert.Equal(t, Annotations{"seed": "email", "pii": "high"}, contact.Annotations)
+	notes, _ := tab.Columns.Get("notes")
+	assert.Equal(t, Annotations{"pii": ""}, notes.Annotations)
+	other, _ := tab.Columns.Get("other")
+	assert.Nil(t, other.Annotations)
 }
 
 //func TestCompiler_
+
+func TestCompiler_Rename(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY, name text, email text);
+	ALTER TABLE users RENAME TO accounts;
+	ALTER TABLE accounts RENAME COLUMN name TO full_name;
+	ALTER TABLE accounts RENAME CONSTRAINT users_pkey TO accounts_pkey;
+	`)
+	tab := assertTable(t, c, "public.accounts")
+	assert.Equal(t, []string{"id", "full_name", "email"}, lo.Map(tab.Columns.List(), func(c *Column, _ int) string { return c.Name }))
+	col := assertColumn(t, tab, "full_name", Text, ColumnAttributes{})
+	assert.Equal(t, "full_name", col.Name)
+	_, ok := constraintsByName(c)["accounts_pkey"]
+	assert.True(t, ok)
+
+	assertParseError(t, `
+	CREATE TABLE users (id int, name text);
+	ALTER TABLE users RENAME COLUMN name TO id;
+	`, "column already exists")
+}
+
+func TestCompiler_RawStatements(t *testing.T) {
+	const sql = `
+	CREATE TABLE events (id int, kind text);
+	CREATE TABLE audit (id int);
+	CREATE RULE no_delete AS ON DELETE TO events DO INSTEAD NOTHING;
+	CREATE RULE log_insert AS ON INSERT TO audit DO ALSO NOTIFY audit;
+	DO $$ BEGIN RAISE NOTICE 'done; really'; END $$;
+	CREATE OR REPLACE RULE no_delete AS ON DELETE TO events WHERE old.kind = 'keep' DO INSTEAD NOTHING;
+	DROP TABLE audit;
+	`
+	c := NewCompiler()
+	require.Nil(t, c.Compile(sql))
+	require.Len(t, c.Catalog.Raw, 2)
+	assert.Equal(t, "DO", c.Catalog.Raw[0].Kind)
+	assert.Equal(t, "DO $$ BEGIN RAISE NOTICE 'done; really'; END $$", c.Catalog.Raw[0].SQL)
+	rule := c.Catalog.Raw[1]
+	assert.Equal(t, "no_delete", rule.Name)
+	assert.Equal(t, assertTable(t, c, "events"), rule.Table)
+	assert.Contains(t, rule.SQL, "WHERE old.kind = 'keep'")
+	assert.Empty(t, c.Skipped)
+
+	// Without the source, statements are deparsed
+	c = assertParse(t, "CREATE TABLE t (id int); CREATE RULE r AS ON UPDATE TO t DO INSTEAD NOTHING; DROP RULE r ON t;")
+	assert.Empty(t, c.Catalog.Raw)
+	c = assertParse(t, "DO $$ BEGIN END $$")
+	assert.Equal(t, "DO $$ BEGIN END $$", c.Catalog.Raw[0].SQL)
+
+	assertParseError(t, `
+	CREATE TABLE t (id int);
+	CREATE RULE r AS ON UPDATE TO t DO INSTEAD NOTHING;
+	CREATE RULE r AS ON UPDATE TO t DO INSTEAD NOTHING;
+	`, "rule r already exists")
+	assertParseError(t, "CREATE TABLE t (id int); DROP RULE r ON t;", "rule r on t not found")
+}
+
+func TestCompiler_Indexes(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int, email text, deleted_at timestamp, org int);
+	CREATE UNIQUE INDEX ON users (lower(email)) WHERE deleted_at IS NULL;
+	CREATE INDEX ON users (org, id);
+	CREATE INDEX ON users (org, id);
+	CREATE INDEX by_sum ON users USING hash ((org + id));
+	CREATE INDEX IF NOT EXISTS by_sum ON users (id);
+	`)
+	tab := assertTable(t, c, "users")
+	indexes := c.Catalog.Depends.TableIndexes(tab)
+	require.Len(t, indexes, 4)
+	assert.Equal(t, []string{"by_sum", "users_lower_key", "users_org_id_idx", "users_org_id_idx1"},
+		lo.Map(indexes, func(idx *Index, _ int) string { return idx.Name }))
+
+	lower := indexes[1]
+	assert.True(t, lower.Unique)
+	assert.Equal(t, "lower(email)", lower.Elems[0].Expr)
+	assert.Nil(t, lower.Elems[0].Column)
+	assert.Equal(t, "deleted_at IS NULL", lower.Predicate)
+	assert.Equal(t, []string{"email", "deleted_at"}, lower.Columns.Names())
+	assert.Equal(t, "hash", indexes[0].Method)
+	assert.Equal(t, "org + id", indexes[0].Elems[0].Expr)
+
+	// Renaming a column renames it in expressions, and dropping one drops
+	// the indexes using it
+	c = assertParse(t, `
+	CREATE TABLE users (id int, email text, deleted_at timestamp, org int);
+	CREATE UNIQUE INDEX active_email ON users (lower(email)) WHERE deleted_at IS NULL;
+	CREATE INDEX by_org ON users (org);
+	ALTER TABLE users RENAME COLUMN email TO address;
+	ALTER TABLE users RENAME COLUMN deleted_at TO removed_at;
+	ALTER TABLE users DROP COLUMN org;
+	ALTER INDEX active_email RENAME TO active_address;
+	`)
+	tab = assertTable(t, c, "users")
+	indexes = c.Catalog.Depends.TableIndexes(tab)
+	require.Len(t, indexes, 1)
+	assert.Equal(t, "active_address", indexes[0].Name)
+	assert.Equal(t, "lower(address)", indexes[0].Elems[0].Expr)
+	assert.Equal(t, "removed_at IS NULL", indexes[0].Predicate)
+
+	c = assertParse(t, `
+	CREATE TABLE users (id int);
+	CREATE INDEX by_id ON users (id);
+	DROP INDEX by_id;
+	DROP INDEX IF EXISTS by_id;
+	`)
+	assert.Empty(t, c.Catalog.Depends.IndexesByName)
+	assertParseError(t, "CREATE TABLE users (id int); CREATE INDEX ON users (lower(missing));", "missing")
+	assertParseError(t, "CREATE TABLE users (id int); CREATE INDEX i ON users (id); CREATE INDEX i ON users (id);", "index already exists: i")
+}
+
+func TestCompiler_IndexInclude(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE orders (id int, customer int, total numeric, placed_at timestamp);
+	CREATE UNIQUE INDEX ON orders (id) INCLUDE (customer, total);
+	CREATE INDEX ON orders (customer) INCLUDE (placed_at);
+	ALTER TABLE orders DROP COLUMN placed_at;
+	`)
+	tab := assertTable(t, c, "orders")
+	indexes := c.Catalog.Depends.TableIndexes(tab)
+	require.Len(t, indexes, 1)
+	assert.Equal(t, "orders_id_customer_total_key", indexes[0].Name)
+	assert.Equal(t, []string{"customer", "total"}, indexes[0].Include.Names())
+	assert.Equal(t, []string{"id", "customer", "total"}, indexes[0].Columns.Names())
+}
+
+func TestCompiler_IndexOpclasses(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE docs (id int, title varchar(100), body text, ts timestamp);
+	CREATE INDEX by_title ON docs (title varchar_pattern_ops, ts DESC NULLS LAST, id NULLS FIRST);
+	CREATE INDEX by_body ON docs USING gist (body public.gist_trgm_ops (siglen = 32));
+	CREATE INDEX by_ts ON docs (ts DESC NULLS FIRST, lower(title) ASC);
+	`)
+	tab := assertTable(t, c, "docs")
+	indexes := c.Catalog.Depends.TableIndexes(tab)
+	require.Len(t, indexes, 3)
+	body, title, ts := indexes[0], indexes[1], indexes[2]
+	assert.Equal(t, &IndexElem{Column: title.Elems[0].Column, Opclass: "varchar_pattern_ops"}, title.Elems[0])
+	assert.Equal(t, &IndexElem{Column: title.Elems[1].Column, Descending: true}, title.Elems[1])
+	assert.Equal(t, &IndexElem{Column: title.Elems[2].Column, NullsFirst: true}, title.Elems[2])
+	assert.Equal(t, "public.gist_trgm_ops", body.Elems[0].Opclass)
+	assert.Equal(t, []string{"siglen=32"}, body.Elems[0].OpclassOptions)
+	assert.True(t, ts.Elems[0].Descending)
+	assert.True(t, ts.Elems[0].NullsFirst)
+
+	assert.Equal(t, "CREATE INDEX by_title ON docs (title varchar_pattern_ops, ts DESC NULLS LAST, id NULLS FIRST)", IndexDefinition(title))
+	assert.Equal(t, "CREATE INDEX by_body ON docs USING gist (body public.gist_trgm_ops (siglen=32))", IndexDefinition(body))
+	assert.Equal(t, "CREATE INDEX by_ts ON docs (ts DESC, (lower(title)))", IndexDefinition(ts))
+}
+
+func TestCompiler_Cluster(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE events (id int PRIMARY KEY, kind text);
+	CREATE INDEX events_kind ON events (kind);
+	VACUUM ANALYZE events;
+	ANALYZE;
+	REINDEX TABLE events;
+	CLUSTER events USING events_kind;
+	CLUSTER events;
+	CLUSTER;
+	`)
+	assert.Empty(t, c.Skipped)
+	events := assertTable(t, c, "events")
+	assert.Equal(t, "events_kind", events.ClusterIndex)
+	var sb strings.Builder
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), "ALTER TABLE events CLUSTER ON events_kind;\n")
+
+	require.Nil(t, c.Compile(`ALTER INDEX events_kind RENAME TO by_kind`))
+	assert.Equal(t, "by_kind", events.ClusterIndex)
+	require.Nil(t, c.Compile(`DROP INDEX by_kind`))
+	assert.Empty(t, events.ClusterIndex)
+
+	// As pg_dump writes it, on the primary key's index
+	require.Nil(t, c.Compile(`ALTER TABLE ONLY events CLUSTER ON events_pkey`))
+	assert.Equal(t, "events_pkey", events.ClusterIndex)
+	require.Nil(t, c.Compile(`ALTER TABLE events RENAME CONSTRAINT events_pkey TO events_key`))
+	assert.Equal(t, "events_key", events.ClusterIndex)
+	require.Nil(t, c.Compile(`ALTER TABLE events SET WITHOUT CLUSTER`))
+	assert.Empty(t, events.ClusterIndex)
+
+	assert.ErrorContains(t, c.Compile(`CLUSTER events`), "there is no previously clustered index for table events")
+	assert.ErrorContains(t, c.Compile(`CLUSTER events USING missing`), "index missing not found on table events")
+	assert.ErrorContains(t, c.Compile(`CREATE INDEX recent ON events (id) WHERE id > 10; CLUSTER events USING recent`), "cannot cluster on partial index recent")
+}
+
+func TestCompiler_ReplicaIdentity(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, kind text);
+	CREATE UNIQUE INDEX events_id ON events (id);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;
+	CREATE TABLE logs (id int);
+	ALTER TABLE logs REPLICA IDENTITY FULL;
+	`)
+	events := assertTable(t, c, "events")
+	assert.Equal(t, ReplicaIdentityIndex, events.ReplicaIdentity)
+	assert.Equal(t, "events_id", events.ReplicaIndex.Name)
+	assert.Equal(t, ReplicaIdentityFull, assertTable(t, c, "logs").ReplicaIdentity)
+
+	// Renaming the index keeps it as the replica identity, dropping it doesn't
+	require.Nil(t, c.Compile(`ALTER INDEX events_id RENAME TO events_key`))
+	assert.Equal(t, "events_key", events.ReplicaIndex.Name)
+	require.Nil(t, c.Compile(`DROP INDEX events_key`))
+	assert.Equal(t, ReplicaIdentityNothing, events.ReplicaIdentity)
+	assert.Nil(t, events.ReplicaIndex)
+
+	assertParseError(t, `
+	CREATE TABLE t (id int NOT NULL);
+	CREATE INDEX t_id ON t (id);
+	ALTER TABLE t REPLICA IDENTITY USING INDEX t_id;
+	`, "isn't unique")
+	assertParseError(t, `
+	CREATE TABLE t (id int);
+	CREATE UNIQUE INDEX t_id ON t (id);
+	ALTER TABLE t REPLICA IDENTITY USING INDEX t_id;
+	`, "column id is nullable")
+	assertParseError(t, `
+	CREATE TABLE t (id int);
+	ALTER TABLE t REPLICA IDENTITY USING INDEX missing;
+	`, "index missing not found")
+}
+
+func TestCompiler_StorageOptions(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE events (id int, kind text) WITH (fillfactor = 70, autovacuum_enabled, oids = false);
+	CREATE INDEX events_kind ON events (kind) WITH (fillfactor = 80);
+	ALTER TABLE events SET (toast.autovacuum_enabled = off, fillfactor = 60), RESET (autovacuum_enabled);
+	ALTER TABLE events SET (autovacuum_vacuum_scale_factor = 0.05);
+	ALTER INDEX events_kind SET (deduplicate_items = off);
+	ALTER INDEX IF EXISTS missing SET (fillfactor = 90);
+	`)
+	assert.Empty(t, c.Skipped)
+	events := assertTable(t, c, "events")
+	assert.Equal(t, []string{"autovacuum_vacuum_scale_factor=0.05", "fillfactor=60", "toast.autovacuum_enabled=off"}, events.Options)
+	idx := c.Catalog.Depends.IndexesByName["public.events_kind"]
+	assert.Equal(t, []string{"deduplicate_items=off", "fillfactor=80"}, idx.Options)
+	assert.Equal(t, "CREATE INDEX events_kind ON events (kind) WITH (deduplicate_items=off, fillfactor=80)", IndexDefinition(idx))
+	assert.Equal(t, "CREATE TABLE events (\n    id integer,\n    kind text\n) WITH (autovacuum_vacuum_scale_factor=0.05, fillfactor=60, toast.autovacuum_enabled=off)",
+		TableDefinition(c.Catalog, events, events.Columns.List()))
+
+	require.Nil(t, c.Compile(`ALTER INDEX events_kind RESET (fillfactor, deduplicate_items)`))
+	assert.Empty(t, idx.Options)
+
+	// A failed statement leaves the options as they were
+	assert.NotNil(t, c.Compile(`ALTER TABLE events SET (fillfactor = 50), ADD COLUMN id int`))
+	assert.Equal(t, []string{"autovacuum_vacuum_scale_factor=0.05", "fillfactor=60", "toast.autovacuum_enabled=off"}, events.Options)
+
+	assert.ErrorContains(t, c.Compile(`ALTER INDEX missing SET (fillfactor = 90)`), "index missing not found")
+}
+
+func TestCompiler_Statistics(t *testing.T) {
+	c := assertParse(t, `
+	CREATE SCHEMA stats;
+	CREATE TABLE orders (id int, city text, zip text, placed timestamp);
+	CREATE STATISTICS stats.city_zip (ndistinct, dependencies) ON city, zip FROM orders;
+	CREATE STATISTICS ON zip, date_trunc('day', placed) FROM orders;
+	CREATE STATISTICS IF NOT EXISTS stats.city_zip ON city, zip FROM orders;
+	`)
+	tab := assertTable(t, c, "orders")
+	stats := c.Catalog.Depends.TableStatistics(tab)
+	require.Len(t, stats, 2)
+	byDay, cityZip := stats[0], stats[1]
+	assert.Equal(t, "public", byDay.Schema)
+	assert.Equal(t, "orders_zip_expr_stat", byDay.Name)
+	assert.Empty(t, byDay.Kinds)
+	assert.Equal(t, []string{"zip", "placed"}, byDay.Columns.Names())
+	assert.Equal(t, []string{"ndistinct", "dependencies"}, cityZip.Kinds)
+	assert.Equal(t, "CREATE STATISTICS stats.city_zip (ndistinct, dependencies) ON city, zip FROM orders", StatisticsDefinition(cityZip))
+
+	require.Nil(t, c.Compile(`
+	ALTER TABLE orders RENAME COLUMN placed TO placed_at;
+	ALTER STATISTICS stats.city_zip RENAME TO location;
+	`))
+	assert.Equal(t, "CREATE STATISTICS orders_zip_expr_stat ON zip, (date_trunc('day', placed_at)) FROM orders", StatisticsDefinition(byDay))
+	assert.Equal(t, "location", cityZip.Name)
+
+	// Dropping a column drops the statistics on it
+	require.Nil(t, c.Compile(`ALTER TABLE orders DROP COLUMN city`))
+	assert.Len(t, c.Catalog.Depends.TableStatistics(tab), 1)
+	require.Nil(t, c.Compile(`DROP STATISTICS orders_zip_expr_stat; DROP STATISTICS IF EXISTS missing`))
+	assert.Empty(t, c.Catalog.Depends.StatisticsByName)
+
+	assertParseError(t, `
+	CREATE TABLE t (a int, b int);
+	CREATE STATISTICS s ON a, b FROM t;
+	CREATE STATISTICS s ON a, b FROM t;
+	`, "statistics object already exists")
+}
+
+func TestCompiler_EventTriggers(t *testing.T) {
+	c := assertParse(t, `
+	CREATE EVENT TRIGGER audit ON ddl_command_end WHEN TAG IN ('CREATE TABLE', 'ALTER TABLE') EXECUTE FUNCTION audit.log_ddl();
+	CREATE EVENT TRIGGER guard ON sql_drop EXECUTE PROCEDURE forbid_drops();
+	ALTER EVENT TRIGGER guard DISABLE;
+	ALTER EVENT TRIGGER audit RENAME TO audit_ddl;
+	`)
+	triggers := c.Catalog.EventTriggers.List()
+	require.Len(t, triggers, 2)
+	// The OID is kept through the rename
+	assert.Equal(t, &EventTrigger{
+		OID:      firstOID,
+		Name:     "audit_ddl",
+		Event:    "ddl_command_end",
+		Tags:     []string{"CREATE TABLE", "ALTER TABLE"},
+		Function: "audit.log_ddl",
+	}, triggers[0])
+	assert.Equal(t, TriggerFiringDisabled, triggers[1].Firing)
+
+	require.Nil(t, c.Compile(`DROP EVENT TRIGGER guard; DROP EVENT TRIGGER IF EXISTS guard`))
+	assert.Len(t, c.Catalog.EventTriggers.List(), 1)
+
+	assertParseError(t, `
+	CREATE EVENT TRIGGER t ON ddl_command_start EXECUTE FUNCTION f();
+	CREATE EVENT TRIGGER t ON sql_drop EXECUTE FUNCTION f();
+	`, "event trigger t already exists")
+	assertParseError(t, `ALTER EVENT TRIGGER missing ENABLE ALWAYS`, "event trigger missing not found")
+}
+
+func TestCompiler_DependencyGraph(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY, email text);
+	CREATE INDEX users_email ON users (email);
+	CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));
+	CREATE RULE no_delete AS ON DELETE TO posts DO INSTEAD NOTHING;
+	`)
+	users, posts := assertTable(t, c, "public.users"), assertTable(t, c, "public.posts")
+	id, _ := users.Columns.Get("id")
+	deps := c.Catalog.Depends.DependentsOf(id)
+	require.Len(t, deps, 2)
+	assert.Equal(t, constraintsByName(c)["users_pkey"], deps[0].Dependent)
+	assert.Equal(t, DropBehaviourCascade, deps[0].Behaviour)
+	assert.Equal(t, constraintsByName(c)["posts_author_fkey"], deps[1].Dependent)
+	assert.Equal(t, DropBehaviourRestrict, deps[1].Behaviour)
+
+	// A table can be dropped along with the foreign keys within it
+	require.Nil(t, c.Compile(`CREATE TABLE tree (id int PRIMARY KEY, parent int REFERENCES tree (id)); DROP TABLE tree`))
+	assertParseError(t, `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));
+	DROP TABLE users;
+	`, "can't drop table users because other objects depend on it and cascade was not specified: constraint public.posts.posts_author_fkey")
+
+	require.Nil(t, c.Compile(`DROP TABLE posts`))
+	assert.Empty(t, c.Catalog.Raw)
+	assert.Empty(t, c.Catalog.Depends.DependentsOf(posts))
+	assert.Len(t, c.Catalog.Depends.DependentsOf(id), 1)
+	require.Nil(t, c.Compile(`DROP TABLE users`))
+	assert.Empty(t, c.Catalog.Depends.DependentsOf(users))
+	assert.Empty(t, c.Catalog.Depends.DependentsOf(id))
+}
+
+func TestCatalog_Freeze(t *testing.T) {
+	c := assertParse(t, createUsersTable+`
+	CREATE TABLE posts (id int PRIMARY KEY, author_id int REFERENCES users (id), body text);
+	CREATE INDEX posts_author_id ON posts (author_id);
+	`)
+	c.Catalog.Freeze()
+	assert.ErrorIs(t, c.Compile(`CREATE TABLE t (a int)`), ErrFrozen)
+
+	// Every method which modifies the catalog or the objects it holds panics
+	deps := c.Catalog.Depends
+	public, _ := c.Catalog.Schemas.Get("public")
+	posts := assertTable(t, c, "posts")
+	authorID, _ := posts.Columns.Get("author_id")
+	pkey := deps.PrimaryKey(posts)
+	idx := deps.IndexesByName["public.posts_author_id"]
+	stats := &Statistics{Name: "posts_stats", Schema: "public", Table: posts, Columns: Columns{authorID}}
+	modifications := map[string]func(){
+		"Catalog.AddTable":         func() { c.Catalog.AddTable(NewTable("t", "public")) },
+		"Catalog.AddRaw":           func() { c.Catalog.AddRaw(&RawStatement{Kind: "CREATE VIEW", Name: "public.v"}) },
+		"Catalog.RemoveRaw":        func() { c.Catalog.RemoveRaw(func(*RawStatement) bool { return true }) },
+		"Classifier.Classify":      func() { (&Classifier{}).Classify(c.Catalog) },
+		"Depends.AddConstraint":    func() { deps.AddConstraint(pkey) },
+		"Depends.RemoveConstraint": func() { deps.RemoveConstraint(pkey) },
+		"Depends.AddIndex":         func() { deps.AddIndex(idx) },
+		"Depends.RemoveIndex":      func() { deps.RemoveIndex(idx) },
+		"Depends.AddStatistics":    func() { deps.AddStatistics(stats) },
+		"Depends.RemoveStatistics": func() { deps.RemoveStatistics(stats) },
+		"Schema.AddTable":          func() { public.AddTable(NewTable("t", "public")) },
+		"Table.AddColumn":          func() { posts.AddColumn(&Column{Table: posts, Name: "title"}) },
+		"Column.SetType":           func() { authorID.SetType(Bigint, nil, 0) },
+		"Constraint.OnCreate":      func() { pkey.OnCreate() },
+		"Constraint.OnRemove":      func() { pkey.OnRemove() },
+	}
+	for name, modify := range modifications {
+		assert.PanicsWithValue(t, ErrFrozen, modify, name)
+	}
+	assert.NotNil(t, deps.PrimaryKey(posts))
+	assert.Len(t, deps.TableIndexes(posts), 1)
+	_, ok := posts.Columns.Get("title")
+	assert.False(t, ok)
+	assert.Equal(t, "integer", authorID.FormatType())
+
+	// Generators can run over a frozen catalog at once
+	var wg sync.WaitGroup
+	for name, ctor := range generators {
+		gen := ctor(flag.NewFlagSet(name, flag.ContinueOnError))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, gen.Generate(io.Discard, c.Catalog), name)
+		}()
+	}
+	wg.Wait()
+}
+
+func TestCompiler_SharesTypes(t *testing.T) {
+	src := `
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE TABLE a (name varchar(255), mood mood, price numeric(10, 2), tags text[]);
+	CREATE TABLE b (name varchar(255), mood mood, price numeric(10, 3), tags text);
+	`
+	c := assertParse(t, src)
+	a, b := assertTable(t, c, "a"), assertTable(t, c, "b")
+	for _, name := range []string{"name", "mood"} {
+		aCol, _ := a.Columns.Get(name)
+		bCol, _ := b.Columns.Get(name)
+		assert.Same(t, aCol.ColumnType, bCol.ColumnType)
+		assert.True(t, aCol.SameType(bCol))
+	}
+	for _, name := range []string{"price", "tags"} {
+		aCol, _ := a.Columns.Get(name)
+		bCol, _ := b.Columns.Get(name)
+		assert.False(t, aCol.SameType(bCol))
+	}
+
+	// Columns of separately compiled catalogs share types too
+	other := assertTable(t, assertParse(t, src), "a")
+	aName, _ := a.Columns.Get("name")
+	otherName, _ := other.Columns.Get("name")
+	assert.True(t, aName.SameType(otherName))
+
+	// Changing a column's type doesn't change the type it shared
+	c = assertParse(t, src+`ALTER TABLE a ALTER COLUMN price TYPE numeric(10, 3);`)
+	a, b = assertTable(t, c, "a"), assertTable(t, c, "b")
+	aPrice, _ := a.Columns.Get("price")
+	bPrice, _ := b.Columns.Get("price")
+	assert.Same(t, aPrice.ColumnType, bPrice.ColumnType)
+	aName, _ = a.Columns.Get("name")
+	assert.Equal(t, "character varying(255)", aName.FormatType())
+}
+
+func TestNodeString(t *testing.T) {
+	s, err := NodeString(pg_query.MakeStrNode("users"))
+	require.Nil(t, err)
+	assert.Equal(t, "users", s)
+	_, err = NodeString(&pg_query.Node{Node: &pg_query.Node_AStar{AStar: &pg_query.A_Star{}}})
+	assert.EqualError(t, err, "expected a name, found AStar")
+	_, err = NodeStrings([]*pg_query.Node{pg_query.MakeStrNode("public"), nil})
+	assert.EqualError(t, err, "expected a name, found nothing")
+
+	_, err = Columns{}.SingleElement()
+	assert.EqualError(t, err, "wrong number of columns: expected 1, got 0")
+	_, err = MatchType("nope")
+	assert.EqualError(t, err, "unknown type nope")
+	_, _, _, err = parseTypeString("integer + 1")
+	assert.EqualError(t, err, "invalid type integer + 1")
+}
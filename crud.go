@@ -0,0 +1,207 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"slices"
+	"strings"
+)
+
+type CRUDFormat string
+
+const (
+	// CRUDFormatSQL writes queries annotated with sqlc-style names.
+	CRUDFormatSQL CRUDFormat = "sql"
+	// CRUDFormatGo writes queries as Go string constants.
+	CRUDFormatGo CRUDFormat = "go"
+)
+
+// CRUDGenerator writes parameterized INSERT, SELECT, UPDATE and DELETE
+// statements for each table, addressing rows by primary key.
+type CRUDGenerator struct {
+	Format  CRUDFormat
+	Package string
+	// Upsert additionally generates an INSERT ... ON CONFLICT statement
+	// for each primary key and unique constraint.
+	Upsert bool
+}
+
+func NewCRUDGenerator(fs *flag.FlagSet) Generator {
+
+	g := &CRUDGenerator{Format: CRUDFormatSQL}
+	fs.Func("crud-format", "output format of the crud target, sql or go (default sql)", func(s string) error {
+		switch f := CRUDFormat(s); f {
+		case CRUDFormatSQL, CRUDFormatGo:
+			g.Format = f
+			return nil
+		}
+		return fmt.Errorf("unknown format %q", s)
+	})
+	fs.StringVar(&g.Package, "crud-package", "queries", "package name when generating crud queries as Go")
+	fs.BoolVar(&g.Upsert, "crud-upsert", false, "also generate upserts for each unique constraint")
+	return g
+}
+
+// crudQuery is a single generated statement. Kind is the sqlc result
+// annotation, e.g. :one or :exec.
+type crudQuery struct {
+	Name string
+	Kind string
+	SQL  string
+}
+
+func (g *CRUDGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	var queries []crudQuery
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			queries = append(queries, g.tableQueries(cat, tab)...)
+		}
+	}
+
+	switch g.Format {
+	case CRUDFormatGo:
+		{
+			var sb strings.Builder
+			fmt.Fprintf(&sb, "// Code generated by pgmodelgen. DO NOT EDIT.\n\npackage %s\n\n", g.Package)
+			for _, q := range queries {
+				fmt.Fprintf(&sb, "const %s = `%s`\n\n", q.Name, q.SQL)
+			}
+			formatted, err := format.Source([]byte(sb.String()))
+			if err != nil {
+				return fmt.Errorf("while formatting generated code: %w", err)
+			}
+			_, err = w.Write(formatted)
+			return err
+		}
+	default:
+		{
+			for i, q := range queries {
+				if i > 0 {
+					fmt.Fprintln(w)
+				}
+				_, err := fmt.Fprintf(w, "-- name: %s %s\n%s;\n", q.Name, q.Kind, q.SQL)
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+}
+
+func (g *CRUDGenerator) tableQueries(cat *Catalog, tab *Table) []crudQuery {
+
+	name := goStructName(tab)
+	ident := TableIdent(tab)
+	all := tab.Columns.List()
+	var insertable Columns
+	for _, col := range all {
+		if !col.generatesValues() {
+			insertable = append(insertable, col)
+		}
+	}
+	var pk Columns
+	var uniques []Columns
+	for _, con := range cat.Depends.TableConstraints(tab) {
+		switch con.Type {
+		case ConstraintTypePrimary:
+			pk = con.Constrains
+			uniques = append([]Columns{con.Constrains}, uniques...)
+		case ConstraintTypeUnique:
+			uniques = append(uniques, con.Constrains)
+		}
+	}
+
+	values := "DEFAULT VALUES"
+	if len(insertable) > 0 {
+		values = fmt.Sprintf("(%s) VALUES (%s)", quoteColumnNames(insertable), placeholders(1, len(insertable)))
+	}
+	queries := []crudQuery{{
+		Name: "Insert" + name,
+		Kind: ":one",
+		SQL:  fmt.Sprintf("INSERT INTO %s %s RETURNING %s", ident, values, quoteColumnNames(all)),
+	}}
+	if len(pk) > 0 {
+		var rest Columns
+		for _, col := range all {
+			if !slices.Contains(pk, col) {
+				rest = append(rest, col)
+			}
+		}
+		queries = append(queries, crudQuery{
+			Name: "Get" + name,
+			Kind: ":one",
+			SQL:  fmt.Sprintf("SELECT %s FROM %s WHERE %s", quoteColumnNames(all), ident, assignments(pk, 1, " AND ")),
+		})
+		if len(rest) > 0 {
+			queries = append(queries, crudQuery{
+				Name: "Update" + name,
+				Kind: ":exec",
+				SQL: fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+					ident, assignments(rest, len(pk)+1, ", "), assignments(pk, 1, " AND ")),
+			})
+		}
+		queries = append(queries, crudQuery{
+			Name: "Delete" + name,
+			Kind: ":exec",
+			SQL:  fmt.Sprintf("DELETE FROM %s WHERE %s", ident, assignments(pk, 1, " AND ")),
+		})
+	}
+	if g.Upsert {
+		for _, key := range uniques {
+			if slices.ContainsFunc(key, func(col *Column) bool { return !slices.Contains(insertable, col) }) {
+				// A key on a serial column can never conflict
+				continue
+			}
+			var sets []string
+			for _, col := range insertable {
+				if !slices.Contains(key, col) {
+					sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%[1]s", QuoteIdent(col.Name)))
+				}
+			}
+			if len(sets) == 0 {
+				// DO NOTHING wouldn't return the conflicting row, so it's
+				// updated to what it already is instead
+				sets = append(sets, fmt.Sprintf("%s = EXCLUDED.%[1]s", QuoteIdent(key[0].Name)))
+			}
+			action := "DO UPDATE SET " + strings.Join(sets, ", ")
+			queries = append(queries, crudQuery{
+				Name: "Upsert" + name + "By" + goIdent(key.JoinColumnNames("_")),
+				Kind: ":one",
+				SQL: fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) %s RETURNING %s",
+					ident, quoteColumnNames(insertable), placeholders(1, len(insertable)),
+					quoteColumnNames(key), action, quoteColumnNames(all)),
+			})
+		}
+	}
+	return queries
+}
+
+func isSerial(t *PostgresType) bool {
+
+	return t == Serial || t == Bigserial || t == Smallserial
+}
+
+// placeholders returns n comma separated parameters starting at $from.
+func placeholders(from, n int) string {
+
+	ps := make([]string, 0, n)
+	for i := range n {
+		ps = append(ps, fmt.Sprintf("$%d", from+i))
+	}
+	return strings.Join(ps, ", ")
+}
+
+// assignments returns "col = $n" for each column, numbering parameters
+// from $from and joined with sep.
+func assignments(cols Columns, from int, sep string) string {
+
+	as := make([]string, 0, len(cols))
+	for i, col := range cols {
+		as = append(as, fmt.Sprintf("%s = $%d", QuoteIdent(col.Name), from+i))
+	}
+	return strings.Join(as, sep)
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCRUDGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (
+		id SERIAL PRIMARY KEY,
+		email text NOT NULL UNIQUE,
+		name text
+	);
+
+	CREATE TABLE events (
+		payload jsonb
+	);
+
+	CREATE TABLE tags (
+		name text PRIMARY KEY
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&CRUDGenerator{Format: CRUDFormatSQL, Upsert: true}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `-- name: InsertUser :one
+INSERT INTO users (email, name) VALUES ($1, $2) RETURNING id, email, name;
+
+-- name: GetUser :one
+SELECT id, email, name FROM users WHERE id = $1;
+
+-- name: UpdateUser :exec
+UPDATE users SET email = $2, name = $3 WHERE id = $1;
+
+-- name: DeleteUser :exec
+DELETE FROM users WHERE id = $1;
+
+-- name: UpsertUserByEmail :one
+INSERT INTO users (email, name) VALUES ($1, $2) ON CONFLICT (email) DO UPDATE SET name = EXCLUDED.name RETURNING id, email, name;
+
+-- name: InsertEvent :one
+INSERT INTO events (payload) VALUES ($1) RETURNING payload;
+
+-- name: InsertTag :one
+INSERT INTO tags (name) VALUES ($1) RETURNING name;
+
+-- name: GetTag :one
+SELECT name FROM tags WHERE name = $1;
+
+-- name: DeleteTag :exec
+DELETE FROM tags WHERE name = $1;
+
+-- name: UpsertTagByName :one
+INSERT INTO tags (name) VALUES ($1) ON CONFLICT (name) DO UPDATE SET name = EXCLUDED.name RETURNING name;
+`, sb.String())
+
+	sb.Reset()
+	err = (&CRUDGenerator{Format: CRUDFormatGo, Package: "queries"}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Contains(t, sb.String(), "const DeleteUser = `DELETE FROM users WHERE id = $1`\n")
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"strings"
+)
+
+// CreateTableAs creates the table of a CREATE TABLE AS or SELECT INTO, with
+// the output columns of its query, named by the INTO clause if it names
+// them. Columns whose types can't be resolved, such as those of calls to
+// functions which aren't built in, fail the statement unless the compiler is lenient, in which case
+// they're given an opaque type.
+func (c *Compiler) CreateTableAs(into *pg_query.IntoClause, query *pg_query.Node, ifNotExists bool) error {
+
+	rv := into.Rel
+	schema, err := c.createSchema(rv)
+	if err != nil {
+		return err
+	}
+	if ifNotExists {
+		if _, err := c.FindTableFromRangeVar(rv); err == nil {
+			return nil
+		}
+	}
+	cols := c.viewColumns(query, into.ColNames)
+	if len(cols) == 0 {
+		return c.unsupported("CREATE TABLE AS", fmt.Errorf("can't resolve the columns of the query creating %s", rv.Relname))
+	}
+	t := NewTable(rv.Relname, schema)
+	t.OID = c.Catalog.newOID()
+	t.Annotations = c.annotations.For(rv.Location)
+	t.Defined = c.sourceLocation(rv.Location)
+	t.Derived = true
+	t.Unlogged = rv.Relpersistence == persistenceUnlogged
+	for _, vc := range cols {
+		col := &Column{
+			OID:        c.Catalog.newOID(),
+			Table:      t,
+			Name:       vc.Name,
+			ColumnType: InternColumnType(OpaqueType("unknown"), nil, 0),
+			Attrs:      &ColumnAttributes{},
+			Defined:    t.Defined,
+			Sources:    vc.Sources,
+		}
+		if vc.Type == "" {
+			err := c.unsupported("CREATE TABLE AS", fmt.Errorf("can't resolve the type of column %s of %s", vc.Name, rv.Relname))
+			if err != nil {
+				return err
+			}
+		} else {
+			typ, mods, dims, err := parseTypeString(vc.Type)
+			if err != nil {
+				return err
+			}
+			col.SetType(typ, mods, dims)
+		}
+		err := t.AddColumn(col)
+		if err != nil {
+			return err
+		}
+	}
+	return c.Catalog.AddTable(t)
+}
+
+// parseTypeString parses the type of a column as FormatType formats it,
+// returning the type, its modifiers and the dimensions of arrays of it.
+func parseTypeString(s string) (*PostgresType, []int32, int, error) {
+
+	res, err := pg_query.Parse("SELECT NULL::" + s)
+	if err != nil {
+		return nil, nil, 0, fmt.Errorf("invalid type %s: %w", s, err)
+	}
+	var tn *pg_query.TypeName
+	if sel := res.Stmts[0].Stmt.GetSelectStmt(); len(res.Stmts) == 1 && len(sel.GetTargetList()) == 1 {
+		tn = sel.TargetList[0].GetResTarget().GetVal().GetTypeCast().GetTypeName()
+	}
+	if tn == nil {
+		return nil, nil, 0, fmt.Errorf("invalid type %s", s)
+	}
+	dims := len(tn.ArrayBounds)
+	var names []string
+	for _, n := range tn.Names {
+		name, err := NodeString(n)
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("invalid type %s: %w", s, err)
+		}
+		if name != "pg_catalog" {
+			names = append(names, name)
+		}
+	}
+	if t := LookupType(strings.Join(names, ".")); t != nil {
+		return t, TypeModsFromNode(tn), dims, nil
+	}
+	return OpaqueType(strings.TrimSuffix(s, strings.Repeat("[]", dims))), nil, dims, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_CreateTableAs(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE orders (id bigint, customer varchar(50), total numeric(10, 2), tags text[]);
+CREATE TABLE big_orders AS SELECT id, customer AS who, total, tags FROM orders WHERE total > 100;
+CREATE TABLE IF NOT EXISTS big_orders AS SELECT 1;
+CREATE TABLE totals (customer_name, amount) AS SELECT o.customer, o.total::numeric(12, 2) FROM orders o;
+SELECT *, 'x' AS flag INTO archived FROM orders;
+`))
+	assert.Empty(t, c.Skipped)
+	describe := func(name string) []string {
+		t.Helper()
+		tab := assertTable(t, c, name)
+		assert.True(t, tab.Derived)
+		var defs []string
+		for _, col := range tab.Columns.List() {
+			defs = append(defs, ColumnDefinition(col))
+		}
+		return defs
+	}
+	assert.Equal(t, []string{"id bigint", "who character varying(50)", "total numeric(10,2)", "tags text[]"}, describe("public.big_orders"))
+	assert.Equal(t, []string{"customer_name character varying(50)", "amount numeric(12,2)"}, describe("public.totals"))
+	assert.Equal(t, []string{"id bigint", "customer character varying(50)", "total numeric(10,2)", "tags text[]", "flag text"}, describe("public.archived"))
+
+	assertParseError(t, `CREATE TABLE t AS SELECT app.next_slot() AS at`, "can't resolve the type of column at of t")
+
+	lenient := NewCompiler(WithStrictness(StrictnessLenient))
+	require.Nil(t, lenient.Compile(`CREATE TABLE t AS SELECT app.next_slot() AS at, 1 AS n`))
+	tab := assertTable(t, lenient, "public.t")
+	at, _ := tab.Columns.Get("at")
+	assert.True(t, at.Type.Opaque)
+	assert.Len(t, lenient.Skipped, 1)
+}
+
+func TestCompiler_CreateTableAsFunctions(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE posts (id int, org_id bigint, score smallint, views bigint, rating real, price numeric(8, 2), title varchar(80), at timestamp, on_day date);
+CREATE TABLE agg AS SELECT org_id, count(*) AS n FROM posts GROUP BY org_id;
+CREATE TABLE stats AS SELECT
+	sum(score) AS score_sum, sum(views) AS views_sum, sum(price) AS price_sum,
+	avg(score) AS score_avg, avg(rating) AS rating_avg,
+	min(title) AS first_title, max(at) AS last_at, array_agg(id) AS ids,
+	pg_catalog.now() AS computed_at, CURRENT_DATE AS today, date_trunc('day', at) AS day, date_trunc('month', on_day) AS month,
+	coalesce(max(price), 0) AS top_price, upper(min(title)) AS shout
+FROM posts;
+`))
+	var defs []string
+	for _, col := range assertTable(t, c, "public.agg").Columns.List() {
+		defs = append(defs, ColumnDefinition(col))
+	}
+	assert.Equal(t, []string{"org_id bigint", "n bigint"}, defs)
+	defs = nil
+	for _, col := range assertTable(t, c, "public.stats").Columns.List() {
+		defs = append(defs, ColumnDefinition(col))
+	}
+	assert.Equal(t, []string{
+		"score_sum bigint", "views_sum numeric", "price_sum numeric",
+		"score_avg numeric", "rating_avg double precision",
+		"first_title character varying", "last_at timestamp without time zone", "ids integer[]",
+		"computed_at timestamp with time zone", "today date", "day timestamp without time zone", "month timestamp with time zone",
+		"top_price numeric", "shout text",
+	}, defs)
+}
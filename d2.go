@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// D2Generator writes the catalog's tables as a D2 diagram, with a
+// sql_table shape for each table and a connection from each column of a
+// foreign key to the column it references. Columns which are part of the
+// primary key, a foreign key or a unique constraint are constrained as
+// such. Names are quoted, since D2 would take the dot between a schema and
+// a table for nesting.
+type D2Generator struct{}
+
+func NewD2Generator(_ *flag.FlagSet) Generator {
+
+	return &D2Generator{}
+}
+
+// d2Quote quotes s as a D2 string.
+func d2Quote(s string) string {
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s) + `"`
+}
+
+func (g *D2Generator) Generate(w io.Writer, cat *Catalog) error {
+
+	var tables []*Table
+	for _, sch := range cat.Schemas.List() {
+		if !systemSchema(sch.Name) {
+			tables = append(tables, sch.Tables.List()...)
+		}
+	}
+	name := func(t *Table) string {
+		return d2Quote(t.Schema + "." + t.Name)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "direction: right")
+	for _, t := range tables {
+		fmt.Fprintf(bw, "\n%s: {\n", name(t))
+		fmt.Fprintln(bw, "  shape: sql_table")
+		for _, col := range t.Columns.List() {
+			var constraints []string
+			cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+			for _, kind := range []struct {
+				typ  ConstraintType
+				name string
+			}{{ConstraintTypePrimary, "primary_key"}, {ConstraintTypeForeignKey, "foreign_key"}, {ConstraintTypeUnique, "unique"}} {
+				// Foreign keys are also found by the columns they reference
+				if slices.ContainsFunc(cons, func(con *Constraint) bool { return con.Type == kind.typ && slices.Contains(con.Constrains, col) }) {
+					constraints = append(constraints, kind.name)
+				}
+			}
+			line := d2Quote(col.Name) + ": " + d2Quote(col.FormatType())
+			switch len(constraints) {
+			case 0:
+			case 1:
+				line += " {constraint: " + constraints[0] + "}"
+			default:
+				line += " {constraint: [" + strings.Join(constraints, "; ") + "]}"
+			}
+			fmt.Fprintf(bw, "  %s\n", line)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+
+	first := true
+	for _, t := range tables {
+		for _, con := range cat.Depends.TableConstraints(t) {
+			if con.Type != ConstraintTypeForeignKey {
+				continue
+			}
+			for i, col := range con.Constrains {
+				if i >= len(con.Refers) {
+					break
+				}
+				if first {
+					fmt.Fprintln(bw)
+					first = false
+				}
+				ref := con.Refers[i]
+				fmt.Fprintf(bw, "%s.%s -> %s.%s\n", name(t), d2Quote(col.Name), name(ref.Table), d2Quote(ref.Name))
+			}
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestD2Generator_Generate(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE SCHEMA billing;
+CREATE TABLE users (id int PRIMARY KEY, "e""mail" varchar(255) UNIQUE);
+CREATE TABLE regions (region text, code int, PRIMARY KEY (region, code));
+CREATE TABLE billing.invoices (id int PRIMARY KEY, user_id int REFERENCES users (id), region text, code int,
+	FOREIGN KEY (region, code) REFERENCES regions (region, code));
+`))
+	var sb strings.Builder
+	require.Nil(t, (&D2Generator{}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `direction: right
+
+"public.users": {
+  shape: sql_table
+  "id": "integer" {constraint: primary_key}
+  "e\"mail": "character varying(255)" {constraint: unique}
+}
+
+"public.regions": {
+  shape: sql_table
+  "region": "text" {constraint: primary_key}
+  "code": "integer" {constraint: primary_key}
+}
+
+"billing.invoices": {
+  shape: sql_table
+  "id": "integer" {constraint: primary_key}
+  "user_id": "integer" {constraint: foreign_key}
+  "region": "text" {constraint: foreign_key}
+  "code": "integer" {constraint: foreign_key}
+}
+
+"billing.invoices"."region" -> "public.regions"."region"
+"billing.invoices"."code" -> "public.regions"."code"
+"billing.invoices"."user_id" -> "public.users"."id"
+`, sb.String())
+}
@@ -0,0 +1,436 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// DDLGenerator writes the catalog as a single SQL script which recreates
+// it from scratch. Indexes, statistics and foreign keys are added after all
+// tables are created so that the script doesn't depend on table order.
+type DDLGenerator struct{}
+
+func NewDDLGenerator(_ *flag.FlagSet) Generator {
+
+	return &DDLGenerator{}
+}
+
+func (g *DDLGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	bw := bufio.NewWriter(w)
+	// Owners follow the statements creating their objects
+	owner := func(kind, ident, role string) {
+		if role != "" {
+			fmt.Fprintf(bw, "%s;\n", OwnerDefinition(kind, ident, role))
+		}
+	}
+	for _, sch := range cat.Schemas.List() {
+		switch {
+		case sch.Name == TempSchema:
+			// Temporary objects are created in it by naming it
+		case sch.Name != "public" && sch.Owner != "":
+			fmt.Fprintf(bw, "CREATE SCHEMA %s AUTHORIZATION %s;\n\n", QuoteIdent(sch.Name), quoteRole(sch.Owner))
+		case sch.Name != "public":
+			fmt.Fprintf(bw, "CREATE SCHEMA %s;\n\n", QuoteIdent(sch.Name))
+		case sch.Owner != "":
+			fmt.Fprintf(bw, "%s;\n\n", OwnerDefinition("SCHEMA", "public", sch.Owner))
+		}
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			fmt.Fprintf(bw, "%s;\n", EnumDefinition(e))
+			owner("TYPE", EnumIdent(e), e.Owner)
+			fmt.Fprintln(bw)
+		}
+	}
+	var fks Constraints
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			for _, con := range cat.Depends.TableConstraints(tab) {
+				if con.Type == ConstraintTypeForeignKey {
+					fks = append(fks, con)
+				}
+			}
+			fmt.Fprintf(bw, "%s;\n", TableDefinition(cat, tab, tab.Columns.List()))
+			owner("TABLE", TableIdent(tab), tab.Owner)
+			fmt.Fprintln(bw)
+		}
+	}
+	// Partitions are attached once their parents exist, as pg_dump does
+	var attached bool
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			if tab.PartitionOf != nil {
+				fmt.Fprintf(bw, "ALTER TABLE %s ATTACH PARTITION %s %s;\n", TableIdent(tab.PartitionOf), TableIdent(tab), tab.PartitionBound)
+				attached = true
+			}
+			if tab.Partitions != nil && len(tab.Partitions.List()) > 0 {
+				fmt.Fprintf(bw, "-- %s has %s not modeled: %s\n", TableIdent(tab), countOf(len(tab.Partitions.List()), "collapsed partition"),
+					strings.Join(SummarizePartitions(tab.Partitions.List()), ", "))
+				attached = true
+			}
+		}
+	}
+	if attached {
+		fmt.Fprintln(bw)
+	}
+	// Foreign keys may depend on unique indexes
+	var indexes bool
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			for _, idx := range cat.Depends.TableIndexes(tab) {
+				fmt.Fprintf(bw, "%s;\n", IndexDefinition(idx))
+				indexes = true
+			}
+			if tab.ReplicaIdentity != ReplicaIdentityDefault {
+				fmt.Fprintf(bw, "%s;\n", ReplicaIdentityDefinition(tab))
+				indexes = true
+			}
+			if tab.ClusterIndex != "" {
+				fmt.Fprintf(bw, "ALTER TABLE %s CLUSTER ON %s;\n", TableIdent(tab), QuoteIdent(tab.ClusterIndex))
+				indexes = true
+			}
+			for _, s := range cat.Depends.TableStatistics(tab) {
+				fmt.Fprintf(bw, "%s;\n", StatisticsDefinition(s))
+				indexes = true
+			}
+		}
+	}
+	if indexes && len(fks) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, con := range fks {
+		fmt.Fprintf(bw, "ALTER TABLE %s ADD %s;\n", TableIdent(con.Table), ConstraintDefinition(con))
+	}
+	// Raw statements may depend on anything, so they come last
+	if (indexes || len(fks) > 0) && len(cat.Raw) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, raw := range cat.Raw {
+		fmt.Fprintf(bw, "%s;\n", raw.SQL)
+		if raw.Kind == "CREATE VIEW" {
+			owner("VIEW", quoteQualifiedName(raw.Name), raw.Owner)
+		}
+		fmt.Fprintln(bw)
+	}
+	// Event triggers come after everything else, so that they don't fire
+	// while the rest of the script runs
+	if (indexes || len(fks) > 0) && len(cat.Raw) == 0 && len(cat.EventTriggers.List()) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, trig := range cat.EventTriggers.List() {
+		for _, stmt := range EventTriggerDefinition(trig) {
+			fmt.Fprintf(bw, "%s;\n", stmt)
+		}
+		owner("EVENT TRIGGER", QuoteIdent(trig.Name), trig.Owner)
+		fmt.Fprintln(bw)
+	}
+	// Comments and security labels may be on any of the objects above
+	descs := Descriptions(cat)
+	if (indexes || len(fks) > 0) && len(cat.Raw) == 0 && len(cat.EventTriggers.List()) == 0 && len(descs) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, od := range descs {
+		for _, stmt := range od.Statements() {
+			fmt.Fprintf(bw, "%s;\n", stmt)
+		}
+	}
+	return bw.Flush()
+}
+
+// TableDefinition renders the CREATE TABLE statement creating t with its
+// columns in the order of cols, and its constraints other than foreign
+// keys.
+func TableDefinition(cat *Catalog, t *Table, cols Columns) string {
+
+	var defs []string
+	for _, col := range cols {
+		defs = append(defs, ColumnDefinition(col))
+	}
+	for _, con := range cat.Depends.TableConstraints(t) {
+		if con.Type != ConstraintTypeForeignKey {
+			defs = append(defs, ConstraintDefinition(con))
+		}
+	}
+	suffix := ""
+	if t.PartitionKey != "" {
+		suffix = " PARTITION BY " + t.PartitionKey
+	}
+	suffix += storageOptionsClause(t.Options)
+	create := "CREATE TABLE "
+	if t.Unlogged {
+		create = "CREATE UNLOGGED TABLE "
+	}
+	if len(defs) == 0 {
+		return fmt.Sprintf("%s%s (\n)%s", create, TableIdent(t), suffix)
+	}
+	return fmt.Sprintf("%s%s (\n    %s\n)%s", create, TableIdent(t), strings.Join(defs, ",\n    "), suffix)
+}
+
+// ColumnDefinition renders col as it would appear in CREATE TABLE.
+func ColumnDefinition(col *Column) string {
+
+	def := QuoteIdent(col.Name) + " " + col.FormatType()
+	if col.Attrs.NotNull {
+		def += " NOT NULL"
+	}
+	if col.Attrs.Default != "" {
+		def += " DEFAULT " + col.Attrs.Default
+	}
+	if col.Attrs.Identity != IdentityNone {
+		def += " GENERATED " + identityDefinition(col.Attrs.Identity) + " AS IDENTITY"
+	}
+	return def
+}
+
+func identityDefinition(i Identity) string {
+
+	if i == IdentityAlways {
+		return "ALWAYS"
+	}
+	return "BY DEFAULT"
+}
+
+// ConstraintDefinition renders con as it would appear in CREATE TABLE or
+// ALTER TABLE ... ADD.
+func ConstraintDefinition(con *Constraint) string {
+
+	def := "CONSTRAINT " + QuoteIdent(con.Name)
+	switch con.Type {
+	case ConstraintTypePrimary:
+		def += " PRIMARY KEY (" + quoteColumnNames(con.Constrains) + ")" + constraintInclude(con)
+	case ConstraintTypeUnique:
+		def += " UNIQUE (" + quoteColumnNames(con.Constrains) + ")" + constraintInclude(con)
+	case ConstraintTypeForeignKey:
+		def += " FOREIGN KEY (" + quoteColumnNames(con.Constrains) + ") REFERENCES " +
+			TableIdent(con.Refers[0].Table) + " (" + quoteColumnNames(con.Refers) + ")"
+		if con.Match == ForeignKeyMatchFull {
+			def += " MATCH FULL"
+		}
+		if con.OnUpdate != ForeignKeyActionNoAction {
+			def += " ON UPDATE " + con.OnUpdate.String()
+		}
+		if con.OnDelete != ForeignKeyActionNoAction {
+			def += " ON DELETE " + con.OnDelete.String()
+		}
+		if len(con.SetColumns) > 0 {
+			def += " (" + quoteColumnNames(con.SetColumns) + ")"
+		}
+	case ConstraintTypeCheck:
+		def += " CHECK (" + con.Expr + ")"
+		if con.NoInherit {
+			def += " NO INHERIT"
+		}
+	}
+	if con.NotValid {
+		def += " NOT VALID"
+	}
+	return def
+}
+
+// constraintInclude renders the INCLUDE clause of a constraint added using
+// a covering index.
+func constraintInclude(con *Constraint) string {
+
+	if con.Index == nil || len(con.Index.Include) == 0 {
+		return ""
+	}
+	return " INCLUDE (" + quoteColumnNames(con.Index.Include) + ")"
+}
+
+// IndexDefinition renders the CREATE INDEX statement for idx, without a
+// trailing semicolon.
+func IndexDefinition(idx *Index) string {
+
+	def := "CREATE INDEX "
+	if idx.Unique {
+		def = "CREATE UNIQUE INDEX "
+	}
+	def += QuoteIdent(idx.Name) + " ON " + TableIdent(idx.Table)
+	if idx.Method != "" && idx.Method != "btree" {
+		def += " USING " + idx.Method
+	}
+	elems := make([]string, 0, len(idx.Elems))
+	for _, elem := range idx.Elems {
+		if elem.Column != nil {
+			elems = append(elems, QuoteIdent(elem.Column.Name)+indexElemOptions(elem))
+		} else {
+			elems = append(elems, "("+elem.Expr+")"+indexElemOptions(elem))
+		}
+	}
+	def += " (" + strings.Join(elems, ", ") + ")"
+	if len(idx.Include) > 0 {
+		def += " INCLUDE (" + quoteColumnNames(idx.Include) + ")"
+	}
+	def += storageOptionsClause(idx.Options)
+	if idx.Predicate != "" {
+		def += " WHERE " + idx.Predicate
+	}
+	return def
+}
+
+// StatisticsDefinition renders the CREATE STATISTICS statement for s,
+// without a trailing semicolon.
+func StatisticsDefinition(s *Statistics) string {
+
+	def := "CREATE STATISTICS " + StatisticsIdent(s)
+	if len(s.Kinds) > 0 {
+		def += " (" + strings.Join(s.Kinds, ", ") + ")"
+	}
+	elems := make([]string, 0, len(s.Elems))
+	for _, elem := range s.Elems {
+		if elem.Column != nil {
+			elems = append(elems, QuoteIdent(elem.Column.Name))
+		} else {
+			elems = append(elems, "("+elem.Expr+")")
+		}
+	}
+	return def + " ON " + strings.Join(elems, ", ") + " FROM " + TableIdent(s.Table)
+}
+
+// EventTriggerDefinition renders the statements creating trig, without
+// trailing semicolons. A trigger which doesn't fire by default needs an
+// ALTER EVENT TRIGGER after the CREATE.
+func EventTriggerDefinition(trig *EventTrigger) []string {
+
+	def := fmt.Sprintf("CREATE EVENT TRIGGER %s ON %s", QuoteIdent(trig.Name), QuoteIdent(trig.Event))
+	if len(trig.Tags) > 0 {
+		tags := make([]string, 0, len(trig.Tags))
+		for _, tag := range trig.Tags {
+			tags = append(tags, QuoteLiteral(tag))
+		}
+		def += " WHEN TAG IN (" + strings.Join(tags, ", ") + ")"
+	}
+	def += " EXECUTE FUNCTION " + quoteQualifiedName(trig.Function) + "()"
+	if trig.Firing == TriggerFiringOrigin {
+		return []string{def}
+	}
+	return []string{def, EventTriggerFiringDefinition(trig)}
+}
+
+// EventTriggerFiringDefinition renders the statement setting when trig
+// fires, without a trailing semicolon.
+func EventTriggerFiringDefinition(trig *EventTrigger) string {
+
+	firing := "ENABLE"
+	switch trig.Firing {
+	case TriggerFiringDisabled:
+		firing = "DISABLE"
+	case TriggerFiringReplica:
+		firing = "ENABLE REPLICA"
+	case TriggerFiringAlways:
+		firing = "ENABLE ALWAYS"
+	}
+	return fmt.Sprintf("ALTER EVENT TRIGGER %s %s", QuoteIdent(trig.Name), firing)
+}
+
+// ReplicaIdentityDefinition renders the statement setting t's replica
+// identity, without a trailing semicolon.
+func ReplicaIdentityDefinition(t *Table) string {
+
+	identity := strings.ToUpper(t.ReplicaIdentity.String())
+	if t.ReplicaIdentity == ReplicaIdentityIndex {
+		identity = "USING INDEX " + QuoteIdent(t.ReplicaIndex.Name)
+	}
+	return fmt.Sprintf("ALTER TABLE %s REPLICA IDENTITY %s", TableIdent(t), identity)
+}
+
+// indexElemOptions renders the operator class and sort order of an index
+// key, as they follow the key in CREATE INDEX.
+func indexElemOptions(elem *IndexElem) string {
+
+	var def string
+	if elem.Opclass != "" {
+		def += " " + elem.Opclass
+		if len(elem.OpclassOptions) > 0 {
+			def += " (" + strings.Join(elem.OpclassOptions, ", ") + ")"
+		}
+	}
+	if elem.Descending {
+		def += " DESC"
+	}
+	if elem.NullsFirst != elem.Descending {
+		if elem.NullsFirst {
+			def += " NULLS FIRST"
+		} else {
+			def += " NULLS LAST"
+		}
+	}
+	return def
+}
+
+func quoteColumnNames(cols Columns) string {
+
+	quoted := make([]string, 0, len(cols))
+	for _, name := range cols.Names() {
+		quoted = append(quoted, QuoteIdent(name))
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// TableIdent returns the quoted name of t, qualified with its schema
+// unless it lives in public.
+func TableIdent(t *Table) string {
+
+	if t.Schema == "public" {
+		return QuoteIdent(t.Name)
+	}
+	return QuoteIdent(t.Schema) + "." + QuoteIdent(t.Name)
+}
+
+// IndexIdent returns the quoted name of idx, qualified with its schema
+// unless it lives in public.
+func IndexIdent(idx *Index) string {
+
+	if idx.Table.Schema == "public" {
+		return QuoteIdent(idx.Name)
+	}
+	return QuoteIdent(idx.Table.Schema) + "." + QuoteIdent(idx.Name)
+}
+
+func StatisticsIdent(s *Statistics) string {
+
+	if s.Schema == "public" {
+		return QuoteIdent(s.Name)
+	}
+	return QuoteIdent(s.Schema) + "." + QuoteIdent(s.Name)
+}
+
+var simpleIdent = regexp.MustCompile(`^[a-z_][a-z0-9_$]*$`)
+
+// QuoteIdent quotes s if Postgres would require it to be quoted when used
+// as an identifier, following the same rules as quote_ident.
+func QuoteIdent(s string) string {
+
+	if simpleIdent.MatchString(s) {
+		scan, err := pg_query.Scan(s)
+		if err == nil && len(scan.Tokens) == 1 {
+			kind := scan.Tokens[0].KeywordKind
+			if kind == pg_query.KeywordKind_NO_KEYWORD || kind == pg_query.KeywordKind_UNRESERVED_KEYWORD {
+				return s
+			}
+		}
+	}
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+// quoteQualifiedName quotes each part of a dotted name.
+func quoteQualifiedName(name string) string {
+
+	parts := strings.Split(name, ".")
+	for i, part := range parts {
+		parts[i] = QuoteIdent(part)
+	}
+	return strings.Join(parts, ".")
+}
+
+// QuoteLiteral quotes s as an SQL string literal.
+func QuoteLiteral(s string) string {
+
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDDLGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE SCHEMA app;
+
+	CREATE TABLE app.users (
+		id SERIAL PRIMARY KEY,
+		"User Name" VARCHAR(50) NOT NULL UNIQUE,
+		"order" int DEFAULT 1
+	);
+
+	CREATE TABLE orders (
+		id bigserial primary key,
+		user_id int not null references app.users(id)
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&DDLGenerator{}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	ddl := sb.String()
+	assert.Equal(t, `CREATE SCHEMA app;
+
+CREATE TABLE orders (
+    id bigserial,
+    user_id integer NOT NULL,
+    CONSTRAINT orders_pkey PRIMARY KEY (id)
+);
+
+CREATE TABLE app.users (
+    id serial,
+    "User Name" character varying(50) NOT NULL,
+    "order" integer DEFAULT 1,
+    CONSTRAINT "users_User Name_key" UNIQUE ("User Name"),
+    CONSTRAINT users_pkey PRIMARY KEY (id)
+);
+
+ALTER TABLE orders ADD CONSTRAINT orders_user_id_fkey FOREIGN KEY (user_id) REFERENCES app.users (id);
+`, ddl)
+
+	// The output should compile back to an equivalent catalog
+	roundTrip := assertParse(t, ddl)
+	tab := assertTable(t, roundTrip, "app.users")
+	assertColumn(t, tab, "User Name", CharacterVarying, ColumnAttributes{NotNull: true})
+	assertColumn(t, tab, "id", Serial, ColumnAttributes{Pkey: true, PkeyPosition: 1})
+	assertColumn(t, tab, "order", Integer, ColumnAttributes{Default: "1"})
+}
+
+func TestDDLGenerator_RawStatements(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+	CREATE TABLE events (id int);
+	CREATE RULE no_delete AS ON DELETE TO events DO INSTEAD NOTHING;
+	DO $$ BEGIN PERFORM 1; END $$;
+	`))
+	var sb strings.Builder
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `CREATE TABLE events (
+    id integer
+);
+
+CREATE RULE no_delete AS ON DELETE TO events DO INSTEAD NOTHING;
+
+DO $$ BEGIN PERFORM 1; END $$;
+
+`, sb.String())
+
+	roundTrip := NewCompiler()
+	require.Nil(t, roundTrip.Compile(sb.String()))
+	assert.Equal(t, c.Catalog.Raw[0].SQL, roundTrip.Catalog.Raw[0].SQL)
+	assert.Equal(t, c.Catalog.Raw[1].SQL, roundTrip.Catalog.Raw[1].SQL)
+}
+
+func TestDDLGenerator_Indexes(t *testing.T) {
+	c := assertParse(t, `
+	CREATE SCHEMA app;
+	CREATE TABLE app.users (id int, email text, deleted_at timestamp);
+	CREATE UNIQUE INDEX active_email ON app.users (lower(email)) WHERE deleted_at IS NULL;
+	CREATE INDEX ON app.users USING hash (id);
+	CREATE INDEX by_email ON app.users (email) INCLUDE (id, deleted_at);
+	ALTER TABLE app.users REPLICA IDENTITY FULL;
+	CREATE STATISTICS app.users_email (mcv) ON email, deleted_at FROM app.users;
+	`)
+	var sb strings.Builder
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `CREATE SCHEMA app;
+
+CREATE TABLE app.users (
+    id integer,
+    email text,
+    deleted_at timestamp without time zone
+);
+
+CREATE UNIQUE INDEX active_email ON app.users ((lower(email))) WHERE deleted_at IS NULL;
+CREATE INDEX by_email ON app.users (email) INCLUDE (id, deleted_at);
+CREATE INDEX users_id_idx ON app.users USING hash (id);
+ALTER TABLE app.users REPLICA IDENTITY FULL;
+CREATE STATISTICS app.users_email (mcv) ON email, deleted_at FROM app.users;
+`, sb.String())
+
+	roundTrip := assertParse(t, sb.String())
+	assert.Empty(t, Diff(c.Catalog, roundTrip.Catalog, DiffOptions{}))
+}
+
+func TestDDLGenerator_EventTriggers(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE t (id int);
+	CREATE INDEX ON t (id);
+	CREATE EVENT TRIGGER audit ON ddl_command_end WHEN TAG IN ('CREATE TABLE') EXECUTE FUNCTION "Audit".log_ddl();
+	CREATE EVENT TRIGGER replicas ON sql_drop EXECUTE FUNCTION on_drop();
+	ALTER EVENT TRIGGER replicas ENABLE REPLICA;
+	`)
+	var sb strings.Builder
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `CREATE TABLE t (
+    id integer
+);
+
+CREATE INDEX t_id_idx ON t (id);
+
+CREATE EVENT TRIGGER audit ON ddl_command_end WHEN TAG IN ('CREATE TABLE') EXECUTE FUNCTION "Audit".log_ddl();
+
+CREATE EVENT TRIGGER replicas ON sql_drop EXECUTE FUNCTION on_drop();
+ALTER EVENT TRIGGER replicas ENABLE REPLICA;
+
+`, sb.String())
+
+	roundTrip := assertParse(t, sb.String())
+	assert.Empty(t, Diff(c.Catalog, roundTrip.Catalog, DiffOptions{}))
+}
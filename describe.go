@@ -0,0 +1,168 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// DescribeTable writes a description of t in the style of psql's \d: its
+// columns followed by its constraints, indexes and statistics, the foreign
+// keys it has and those referring to it.
+func DescribeTable(w io.Writer, cat *Catalog, t *Table) {
+
+	dep := cat.Depends
+	kind := "Table"
+	if t.Unlogged {
+		kind = "Unlogged table"
+	}
+	fmt.Fprintf(w, "%s \"%s.%s\"\n", kind, t.Schema, t.Name)
+	// Classifications are only shown for tables with classified columns
+	classified := slices.ContainsFunc(t.Columns.List(), func(col *Column) bool {
+		return col.Classification.Class != ""
+	})
+	rows := [][]string{{"Column", "Type", "Nullable", "Default"}}
+	if classified {
+		rows[0] = append(rows[0], "Classification")
+	}
+	for _, col := range t.Columns.List() {
+		nullable := ""
+		if col.Attrs.NotNull || col.Attrs.Pkey {
+			nullable = "not null"
+		}
+		row := []string{col.Name, col.FormatType(), nullable, col.Attrs.Default}
+		if classified {
+			row = append(row, col.Classification.Class)
+		}
+		rows = append(rows, row)
+	}
+	writeRows(w, rows)
+
+	section := func(title string, lines []string) {
+		if len(lines) == 0 {
+			return
+		}
+		fmt.Fprintf(w, "%s:\n", title)
+		for _, line := range lines {
+			fmt.Fprintf(w, "    %s\n", line)
+		}
+	}
+	var constraints, fks, referencing, indexes, statistics []string
+	for _, con := range dep.TableConstraints(t) {
+		if con.Type == ConstraintTypeForeignKey {
+			fks = append(fks, ConstraintDefinition(con))
+		} else {
+			constraints = append(constraints, ConstraintDefinition(con))
+		}
+	}
+	for _, con := range dep.ReferencingConstraints(t) {
+		referencing = append(referencing, "TABLE "+TableIdent(con.Table)+" "+ConstraintDefinition(con))
+	}
+	for _, idx := range dep.TableIndexes(t) {
+		indexes = append(indexes, IndexDefinition(idx))
+	}
+	for _, s := range dep.TableStatistics(t) {
+		statistics = append(statistics, StatisticsDefinition(s))
+	}
+	section("Constraints", constraints)
+	section("Indexes", indexes)
+	section("Foreign-key constraints", fks)
+	section("Referenced by", referencing)
+	section("Statistics objects", statistics)
+	if t.ReplicaIdentity != ReplicaIdentityDefault {
+		fmt.Fprintf(w, "Replica identity: %s\n", t.ReplicaIdentity)
+	}
+	if len(t.Options) > 0 {
+		fmt.Fprintf(w, "Options: %s\n", strings.Join(t.Options, ", "))
+	}
+	if t.PartitionOf != nil {
+		fmt.Fprintf(w, "Partition of: %s %s\n", TableIdent(t.PartitionOf), t.PartitionBound)
+	}
+	if t.PartitionKey != "" {
+		fmt.Fprintf(w, "Partition key: %s\n", t.PartitionKey)
+	}
+	var partitions []string
+	for _, sch := range cat.Schemas.List() {
+		for _, part := range sch.Tables.List() {
+			if part.PartitionOf == t {
+				partitions = append(partitions, TableIdent(part)+" "+part.PartitionBound.String())
+			}
+		}
+	}
+	if t.Partitions != nil && len(t.Partitions.List()) > 0 {
+		partitions = append(partitions, fmt.Sprintf("%s: %s", countOf(len(t.Partitions.List()), "collapsed partition"),
+			strings.Join(SummarizePartitions(t.Partitions.List()), ", ")))
+	}
+	section("Partitions", partitions)
+}
+
+// writeRows writes rows with their cells aligned in columns.
+func writeRows(w io.Writer, rows [][]string) {
+
+	var sb strings.Builder
+	tw := tabwriter.NewWriter(&sb, 0, 4, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintln(tw, strings.Join(row, "\t"))
+	}
+	tw.Flush()
+	// Empty cells at the end of a row are still padded
+	for _, line := range strings.Split(strings.TrimSuffix(sb.String(), "\n"), "\n") {
+		fmt.Fprintln(w, strings.TrimRight(line, " "))
+	}
+}
+
+// findTable finds a table by its name, which is qualified by its schema
+// unless it's in searchPath.
+func findTable(cat *Catalog, searchPath, name string) *Table {
+
+	schema, table, ok := strings.Cut(name, ".")
+	if !ok {
+		schema, table = searchPath, name
+	}
+	sch, ok := cat.Schemas.Get(schema)
+	if !ok {
+		return nil
+	}
+	t, _ := sch.Tables.Get(table)
+	return t
+}
+
+func runDescribe(args []string) error {
+
+	fs := flag.NewFlagSet("describe", flag.ExitOnError)
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() < 2 {
+		return fmt.Errorf("expected a table followed by the input files")
+	}
+	c, err := compile(fs.Args()[1:])
+	if err != nil {
+		return err
+	}
+	t := findTable(c.Catalog, c.SearchPath, fs.Arg(0))
+	if t == nil {
+		return fmt.Errorf("couldn't find table %s", fs.Arg(0))
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	DescribeTable(bw, c.Catalog, t)
+	return bw.Flush()
+}
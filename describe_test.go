@@ -0,0 +1,49 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDescribeTable(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE SCHEMA app;
+CREATE TABLE app.users (id int PRIMARY KEY, email text NOT NULL, created timestamptz DEFAULT now());
+CREATE UNIQUE INDEX users_email ON app.users (email);
+CREATE TABLE app.teams (id int PRIMARY KEY, owner int REFERENCES app.users (id));
+CREATE TABLE posts (id int PRIMARY KEY, author int, team int REFERENCES app.teams (id));
+ALTER TABLE posts ADD CONSTRAINT posts_author_fkey FOREIGN KEY (author) REFERENCES app.users (id);
+ALTER TABLE app.users REPLICA IDENTITY FULL;
+`))
+
+	var sb strings.Builder
+	DescribeTable(&sb, c.Catalog, findTable(c.Catalog, c.SearchPath, "app.users"))
+	assert.Equal(t, `Table "app.users"
+Column   Type                      Nullable  Default
+id       integer                   not null
+email    text                      not null
+created  timestamp with time zone            now()
+Constraints:
+    CONSTRAINT users_pkey PRIMARY KEY (id)
+Indexes:
+    CREATE UNIQUE INDEX users_email ON app.users (email)
+Referenced by:
+    TABLE posts CONSTRAINT posts_author_fkey FOREIGN KEY (author) REFERENCES app.users (id)
+    TABLE app.teams CONSTRAINT teams_owner_fkey FOREIGN KEY (owner) REFERENCES app.users (id)
+Replica identity: full
+`, sb.String())
+
+	sb.Reset()
+	DescribeTable(&sb, c.Catalog, findTable(c.Catalog, c.SearchPath, "posts"))
+	assert.Contains(t, sb.String(), `Foreign-key constraints:
+    CONSTRAINT posts_author_fkey FOREIGN KEY (author) REFERENCES app.users (id)
+    CONSTRAINT posts_team_fkey FOREIGN KEY (team) REFERENCES app.teams (id)
+`)
+	assert.NotContains(t, sb.String(), "Referenced by")
+
+	assert.Nil(t, findTable(c.Catalog, c.SearchPath, "users"))
+	assert.Nil(t, findTable(c.Catalog, c.SearchPath, "nope.users"))
+}
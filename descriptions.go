@@ -0,0 +1,446 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"github.com/samber/lo"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// Description is the comment and security labels of an object, set by
+// COMMENT ON and SECURITY LABEL. Like pg_description and pg_seclabel,
+// they're kept apart from the objects, in Catalog.Descriptions.
+type Description struct {
+	Comment string
+	// Labels are keyed by the provider of each label, which is empty for a
+	// label set without naming its provider.
+	Labels map[string]string
+}
+
+func (d *Description) empty() bool {
+
+	return d.Comment == "" && len(d.Labels) == 0
+}
+
+// ObjectDescription is the description of an object, named as COMMENT ON
+// names it.
+type ObjectDescription struct {
+	// Kind is the kind of object, such as "COLUMN", and Ident its quoted
+	// name, such as "public.users.email".
+	Kind  string
+	Ident string
+	// Schema, Table and Name locate the object as in a Change.
+	Schema string
+	Table  string
+	Name   string
+	*Description
+}
+
+// Target returns what COMMENT ON and SECURITY LABEL take to name the
+// object.
+func (od *ObjectDescription) Target() string {
+
+	return od.Kind + " " + od.Ident
+}
+
+// Statements renders the statements setting the object's description,
+// without trailing semicolons.
+func (od *ObjectDescription) Statements() []string {
+
+	var stmts []string
+	if od.Comment != "" {
+		stmts = append(stmts, CommentDefinition(od.Target(), od.Comment))
+	}
+	providers := lo.Keys(od.Labels)
+	slices.Sort(providers)
+	for _, provider := range providers {
+		stmts = append(stmts, SecurityLabelDefinition(od.Target(), provider, od.Labels[provider]))
+	}
+	return stmts
+}
+
+// CommentDefinition renders the COMMENT ON statement setting the comment
+// of target, or removing it if comment is empty.
+func CommentDefinition(target, comment string) string {
+
+	if comment == "" {
+		return fmt.Sprintf("COMMENT ON %s IS NULL", target)
+	}
+	return fmt.Sprintf("COMMENT ON %s IS %s", target, QuoteLiteral(comment))
+}
+
+// SecurityLabelDefinition renders the SECURITY LABEL statement setting the
+// label of provider on target, or removing it if label is empty.
+func SecurityLabelDefinition(target, provider, label string) string {
+
+	stmt := "SECURITY LABEL"
+	if provider != "" {
+		stmt += " FOR " + QuoteIdent(provider)
+	}
+	if label == "" {
+		return fmt.Sprintf("%s ON %s IS NULL", stmt, target)
+	}
+	return fmt.Sprintf("%s ON %s IS %s", stmt, target, QuoteLiteral(label))
+}
+
+// describedObject names obj as COMMENT ON does, returning ok false for
+// objects which can't be described.
+func describedObject(obj any) (od *ObjectDescription, ok bool) {
+
+	switch o := obj.(type) {
+	case *Schema:
+		return &ObjectDescription{Kind: "SCHEMA", Ident: QuoteIdent(o.Name), Schema: o.Name}, true
+	case *Enum:
+		return &ObjectDescription{Kind: "TYPE", Ident: EnumIdent(o), Schema: o.Schema, Name: o.Name}, true
+	case *Table:
+		return &ObjectDescription{Kind: "TABLE", Ident: TableIdent(o), Schema: o.Schema, Table: o.Name}, true
+	case *Column:
+		return &ObjectDescription{Kind: "COLUMN", Ident: TableIdent(o.Table) + "." + QuoteIdent(o.Name),
+			Schema: o.Table.Schema, Table: o.Table.Name, Name: o.Name}, true
+	case *Constraint:
+		return &ObjectDescription{Kind: "CONSTRAINT", Ident: QuoteIdent(o.Name) + " ON " + TableIdent(o.Table),
+			Schema: o.Table.Schema, Table: o.Table.Name, Name: o.Name}, true
+	case *Index:
+		return &ObjectDescription{Kind: "INDEX", Ident: IndexIdent(o), Schema: o.Table.Schema, Table: o.Table.Name, Name: o.Name}, true
+	case *Statistics:
+		return &ObjectDescription{Kind: "STATISTICS", Ident: StatisticsIdent(o), Schema: o.Schema, Table: o.Table.Name, Name: o.Name}, true
+	case *RawStatement:
+		if o.Kind != "CREATE VIEW" {
+			return nil, false
+		}
+		schema, name, _ := strings.Cut(o.Name, ".")
+		return &ObjectDescription{Kind: "VIEW", Ident: quoteQualifiedName(o.Name), Schema: schema, Name: name}, true
+	case *EventTrigger:
+		return &ObjectDescription{Kind: "EVENT TRIGGER", Ident: QuoteIdent(o.Name), Name: o.Name}, true
+	}
+	return nil, false
+}
+
+// describableObjects returns the objects of cat which can have a
+// description, in the order they're created.
+func describableObjects(cat *Catalog) []any {
+
+	var ret []any
+	for _, sch := range cat.Schemas.List() {
+		ret = append(ret, sch)
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			ret = append(ret, e)
+		}
+		for _, t := range sch.Tables.List() {
+			ret = append(ret, t)
+			for _, col := range t.Columns.List() {
+				ret = append(ret, col)
+			}
+			for _, con := range cat.Depends.TableConstraints(t) {
+				ret = append(ret, con)
+			}
+			for _, idx := range cat.Depends.TableIndexes(t) {
+				ret = append(ret, idx)
+			}
+			for _, s := range cat.Depends.TableStatistics(t) {
+				ret = append(ret, s)
+			}
+		}
+	}
+	for _, raw := range cat.Raw {
+		if raw.Kind == "CREATE VIEW" {
+			ret = append(ret, raw)
+		}
+	}
+	for _, trig := range cat.EventTriggers.List() {
+		ret = append(ret, trig)
+	}
+	return ret
+}
+
+// Descriptions returns the descriptions of the objects of cat which have
+// one, in the order the objects are created.
+func Descriptions(cat *Catalog) []*ObjectDescription {
+
+	var ret []*ObjectDescription
+	for _, obj := range describableObjects(cat) {
+		desc, ok := cat.Descriptions[obj]
+		if !ok {
+			continue
+		}
+		od, _ := describedObject(obj)
+		od.Description = desc
+		ret = append(ret, od)
+	}
+	return ret
+}
+
+// describe returns the description of obj, creating it if it has none.
+func (c *Catalog) describe(obj any) *Description {
+
+	desc, ok := c.Descriptions[obj]
+	if !ok {
+		desc = &Description{}
+		c.Descriptions[obj] = desc
+	}
+	return desc
+}
+
+// CommentOn handles COMMENT ON for the objects which are modeled.
+func (c *Compiler) CommentOn(stmt *pg_query.CommentStmt) error {
+
+	obj, err := c.findDescribable("COMMENT ON", stmt.Objtype, stmt.Object)
+	if err != nil || obj == nil {
+		return err
+	}
+	desc := c.Catalog.describe(obj)
+	desc.Comment = stmt.Comment
+	if desc.empty() {
+		delete(c.Catalog.Descriptions, obj)
+	}
+	return nil
+}
+
+// SecurityLabel handles SECURITY LABEL for the objects which are modeled.
+func (c *Compiler) SecurityLabel(stmt *pg_query.SecLabelStmt) error {
+
+	obj, err := c.findDescribable("SECURITY LABEL", stmt.Objtype, stmt.Object)
+	if err != nil || obj == nil {
+		return err
+	}
+	desc := c.Catalog.describe(obj)
+	if stmt.Label == "" {
+		delete(desc.Labels, stmt.Provider)
+	} else {
+		if desc.Labels == nil {
+			desc.Labels = make(map[string]string)
+		}
+		desc.Labels[stmt.Provider] = stmt.Label
+	}
+	if desc.empty() {
+		delete(c.Catalog.Descriptions, obj)
+	}
+	return nil
+}
+
+// findDescribable returns the object of the kind given named by n, or nil
+// if objects of the kind aren't modeled, in which case the statement, what,
+// is skipped.
+func (c *Compiler) findDescribable(what string, kind pg_query.ObjectType, n *pg_query.Node) (any, error) {
+
+	// Names are the table, optionally qualified, followed by the name of
+	// the object within it
+	tableAndName := func() (*Table, string, error) {
+		names, err := NodeStrings(n.GetList().GetItems())
+		if err != nil {
+			return nil, "", err
+		}
+		if len(names) < 2 {
+			return nil, "", fmt.Errorf("expected a table and name but got %s", strings.Join(names, "."))
+		}
+		schema, table, err := TableNameFromNodeList(&pg_query.List{Items: n.GetList().GetItems()[:len(names)-1]})
+		if err != nil {
+			return nil, "", err
+		}
+		t, err := c.FindTableFromSchemaAndName(schema, table)
+		return t, names[len(names)-1], err
+	}
+	switch kind {
+	case pg_query.ObjectType_OBJECT_SCHEMA:
+		{
+			sch, ok := c.Catalog.Schemas.Get(n.GetString_().GetSval())
+			if !ok {
+				return nil, fmt.Errorf("couldn't find schema %s", n.GetString_().GetSval())
+			}
+			return sch, nil
+		}
+	case pg_query.ObjectType_OBJECT_TABLE:
+		{
+			schema, table, err := TableNameFromNodeList(n.GetList())
+			if err != nil {
+				return nil, err
+			}
+			return c.FindTableFromSchemaAndName(schema, table)
+		}
+	case pg_query.ObjectType_OBJECT_COLUMN:
+		{
+			t, name, err := tableAndName()
+			if err != nil {
+				return nil, err
+			}
+			return ColumnFromColName(t, name)
+		}
+	case pg_query.ObjectType_OBJECT_TABCONSTRAINT:
+		{
+			t, name, err := tableAndName()
+			if err != nil {
+				return nil, err
+			}
+			con, ok := c.Catalog.Depends.Constraint(t, name)
+			if !ok {
+				return nil, fmt.Errorf("couldn't find constraint %s on table %s", name, t.Name)
+			}
+			return con, nil
+		}
+	case pg_query.ObjectType_OBJECT_INDEX:
+		{
+			schema, name, err := TableNameFromNodeList(n.GetList())
+			if err != nil {
+				return nil, err
+			}
+			schema = c.relationSchema(schema, name)
+			idx, ok := c.Catalog.Depends.IndexesByName[schema+"."+name]
+			if !ok {
+				return nil, fmt.Errorf("index %s not found", name)
+			}
+			return idx, nil
+		}
+	case pg_query.ObjectType_OBJECT_STATISTIC_EXT:
+		{
+			schema, name, err := TableNameFromNodeList(n.GetList())
+			if err != nil {
+				return nil, err
+			}
+			if schema == "" {
+				schema = c.SearchPath
+			}
+			s, ok := c.Catalog.Depends.StatisticsByName[schema+"."+name]
+			if !ok {
+				return nil, fmt.Errorf("statistics object %s not found", name)
+			}
+			return s, nil
+		}
+	case pg_query.ObjectType_OBJECT_VIEW:
+		{
+			names, err := NodeStrings(n.GetList().GetItems())
+			if err != nil {
+				return nil, err
+			}
+			name := c.qualifiedName(names)
+			view := c.findOpaque("CREATE VIEW", name)
+			if view == nil {
+				return nil, fmt.Errorf("view %s not found", name)
+			}
+			return view, nil
+		}
+	case pg_query.ObjectType_OBJECT_TYPE:
+		{
+			// Types other than enums aren't modeled
+			names, err := NodeStrings(n.GetTypeName().GetNames())
+			if err != nil {
+				return nil, err
+			}
+			if e := c.findEnum(names); e != nil {
+				return e, nil
+			}
+		}
+	case pg_query.ObjectType_OBJECT_EVENT_TRIGGER:
+		{
+			trig, ok := c.Catalog.EventTriggers.Get(n.GetString_().GetSval())
+			if !ok {
+				return nil, fmt.Errorf("event trigger %s not found", n.GetString_().GetSval())
+			}
+			return trig, nil
+		}
+	}
+	c.skip(what+" "+strings.ReplaceAll(strings.TrimPrefix(kind.String(), "OBJECT_"), "_", " "), "")
+	return nil, nil
+}
+
+// diffDescriptions returns the changes to the descriptions of the objects
+// of d.to. Descriptions of dropped objects go with them, so only those of
+// objects which are kept are removed.
+func (d *differ) diffDescriptions() Changes {
+
+	// The objects of d.from are named as they're named in d.to, so that
+	// renaming an object doesn't change its description
+	counterpart := func(obj any) any {
+		switch o := obj.(type) {
+		case *Table:
+			if t, ok := d.tables[o]; ok {
+				return t
+			}
+		case *Column:
+			if col, ok := d.columns[o]; ok {
+				return col
+			}
+		case *Constraint:
+			if t, ok := d.tables[o.Table]; ok {
+				moved := *o
+				moved.Table = t
+				return &moved
+			}
+		}
+		return obj
+	}
+	var fromDescs []*ObjectDescription
+	fromTargets := make(map[string]*ObjectDescription)
+	for _, obj := range describableObjects(d.from) {
+		desc, ok := d.from.Descriptions[obj]
+		if !ok {
+			continue
+		}
+		od, _ := describedObject(counterpart(obj))
+		od.Description = desc
+		fromDescs = append(fromDescs, od)
+		fromTargets[od.Target()] = od
+	}
+	toTargets := make(map[string]bool)
+	for _, obj := range describableObjects(d.to) {
+		od, _ := describedObject(obj)
+		toTargets[od.Target()] = false
+	}
+
+	var changes Changes
+	change := func(kind ChangeKind, from, to *ObjectDescription) {
+		od := from
+		if to != nil {
+			od = to
+		}
+		changes = append(changes, &Change{Kind: kind, Object: ObjectKindDescription,
+			Schema: od.Schema, Table: od.Table, Name: od.Name, From: from, To: to})
+	}
+	for _, to := range Descriptions(d.to) {
+		toTargets[to.Target()] = true
+		from, ok := fromTargets[to.Target()]
+		switch {
+		case !ok:
+			change(ChangeKindAdd, nil, to)
+		case from.Comment != to.Comment || !maps.Equal(from.Labels, to.Labels):
+			change(ChangeKindAlter, from, to)
+		}
+	}
+	for _, from := range fromDescs {
+		if described, exists := toTargets[from.Target()]; exists && !described {
+			change(ChangeKindDrop, from, nil)
+		}
+	}
+	return changes
+}
+
+// descriptionChangeSQL returns the statements changing the description of
+// an object from from to to, either of which may be nil.
+func descriptionChangeSQL(from, to any) []string {
+
+	fromOD, _ := from.(*ObjectDescription)
+	toOD, _ := to.(*ObjectDescription)
+	fromDesc, toDesc := &Description{}, &Description{}
+	target := ""
+	if fromOD != nil {
+		fromDesc, target = fromOD.Description, fromOD.Target()
+	}
+	if toOD != nil {
+		toDesc, target = toOD.Description, toOD.Target()
+	}
+	var stmts []string
+	if fromDesc.Comment != toDesc.Comment {
+		stmts = append(stmts, CommentDefinition(target, toDesc.Comment)+";")
+	}
+	providers := lo.Uniq(append(lo.Keys(fromDesc.Labels), lo.Keys(toDesc.Labels)...))
+	slices.Sort(providers)
+	for _, provider := range providers {
+		if fromDesc.Labels[provider] != toDesc.Labels[provider] {
+			stmts = append(stmts, SecurityLabelDefinition(target, provider, toDesc.Labels[provider])+";")
+		}
+	}
+	return stmts
+}
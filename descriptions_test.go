@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const describedSchema = `
+CREATE SCHEMA app;
+CREATE TYPE app.status AS ENUM ('new', 'done');
+CREATE TABLE app.users (id int CONSTRAINT users_pkey PRIMARY KEY, email text);
+CREATE INDEX users_email_idx ON app.users (email);
+CREATE VIEW app.emails AS SELECT email FROM app.users;
+COMMENT ON SCHEMA app IS 'The application';
+COMMENT ON TYPE app.status IS 'Where it''s at';
+COMMENT ON TABLE app.users IS 'People';
+COMMENT ON COLUMN app.users.email IS 'Unverified';
+COMMENT ON CONSTRAINT users_pkey ON app.users IS 'Identity';
+COMMENT ON INDEX app.users_email_idx IS 'Lookups';
+COMMENT ON VIEW app.emails IS 'Mailing list';
+SECURITY LABEL FOR anon ON COLUMN app.users.email IS 'MASKED WITH FUNCTION anon.fake_email()';
+SECURITY LABEL ON TABLE app.users IS 'secret';
+`
+
+func TestCompiler_Descriptions(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(describedSchema))
+	require.Nil(t, c.Compile(`
+COMMENT ON INDEX app.users_email_idx IS NULL;
+COMMENT ON FUNCTION f() IS 'Unmodeled';
+`))
+	assert.Len(t, c.Skipped, 1)
+
+	var stmts []string
+	for _, od := range Descriptions(c.Catalog) {
+		stmts = append(stmts, od.Statements()...)
+	}
+	assert.Equal(t, []string{
+		"COMMENT ON SCHEMA app IS 'The application'",
+		"COMMENT ON TYPE app.status IS 'Where it''s at'",
+		"COMMENT ON TABLE app.users IS 'People'",
+		"SECURITY LABEL ON TABLE app.users IS 'secret'",
+		"COMMENT ON COLUMN app.users.email IS 'Unverified'",
+		"SECURITY LABEL FOR anon ON COLUMN app.users.email IS 'MASKED WITH FUNCTION anon.fake_email()'",
+		"COMMENT ON CONSTRAINT users_pkey ON app.users IS 'Identity'",
+		"COMMENT ON VIEW app.emails IS 'Mailing list'",
+	}, stmts)
+	assert.ErrorContains(t, c.Compile("COMMENT ON COLUMN app.users.missing IS 'x';"), "missing")
+
+	// Descriptions survive a round trip through the DDL
+	var buf bytes.Buffer
+	require.Nil(t, (&DDLGenerator{}).Generate(&buf, c.Catalog))
+	rt := NewCompiler()
+	require.Nil(t, rt.Compile(buf.String()))
+	assert.Empty(t, Diff(c.Catalog, rt.Catalog, DiffOptions{}))
+}
+
+func TestDiff_Descriptions(t *testing.T) {
+	from := NewCompiler()
+	require.Nil(t, from.Compile(describedSchema))
+	to := NewCompiler()
+	require.Nil(t, to.Compile(describedSchema))
+	require.Nil(t, to.Compile(`
+ALTER TABLE app.users RENAME COLUMN email TO address;
+COMMENT ON TABLE app.users IS NULL;
+SECURITY LABEL ON TABLE app.users IS NULL;
+SECURITY LABEL FOR anon ON COLUMN app.users.address IS 'MASKED WITH VALUE NULL';
+COMMENT ON SCHEMA public IS 'Unused';
+`))
+
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{MatchOIDs: true})
+	assert.Equal(t, []string{
+		"ALTER TABLE app.users RENAME COLUMN email TO address;",
+		"COMMENT ON SCHEMA public IS 'Unused';",
+		"SECURITY LABEL FOR anon ON COLUMN app.users.address IS 'MASKED WITH VALUE NULL';",
+		"COMMENT ON TABLE app.users IS NULL;",
+		"SECURITY LABEL ON TABLE app.users IS NULL;",
+	}, changes.SQL())
+	for _, c := range changes[1:] {
+		assert.Equal(t, ObjectKindDescription, c.Object)
+		safety, _ := c.Classify()
+		assert.Equal(t, SafetySafe, safety, c.String())
+	}
+}
@@ -0,0 +1,380 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/pganalyze/pg_query_go/v5/parser"
+	"io"
+	"slices"
+)
+
+// The rules diagnostics are reported under.
+const (
+	RuleSyntax        = "syntax"
+	RuleCompile       = "compile"
+	RuleTargetVersion = "target-version"
+	RulePsql          = "psql"
+	RuleUnsupported   = "unsupported"
+	RuleUnknownType   = "unknown-type"
+	RuleImplicitIndex = "implicit-index"
+	// RuleTruncatedIdentifier is for identifiers longer than Postgres
+	// keeps.
+	RuleTruncatedIdentifier = "truncated-identifier"
+	// RuleUnnormalizedIdentifier is for identifiers which aren't in NFC
+	// form, and RuleConfusableIdentifier for those which mix scripts or
+	// look like another.
+	RuleUnnormalizedIdentifier = "unnormalized-identifier"
+	RuleConfusableIdentifier   = "confusable-identifier"
+	// The rules of the smells command.
+	RuleWideTable            = "wide-table"
+	RuleNumberedColumns      = "numbered-columns"
+	RulePolymorphicReference = "polymorphic-reference"
+	RuleRepeatedColumns      = "repeated-columns"
+	// The rules of the unused command.
+	RuleOrphanedSequence = "orphaned-sequence"
+	RuleUnusedEnum       = "unused-enum"
+	// The rules of the names command.
+	RuleReservedKeyword  = "reserved-keyword"
+	RuleQuotedIdentifier = "quoted-identifier"
+)
+
+// ruleDescriptions describe the rules for SARIF output.
+var ruleDescriptions = map[string]string{
+	RuleSyntax:                 "The SQL can't be parsed.",
+	RuleCompile:                "The statement can't be applied to the schema built so far.",
+	RuleTargetVersion:          "The statement uses a feature the target version of Postgres lacks.",
+	RulePsql:                   "The psql meta-command isn't supported.",
+	RuleUnsupported:            "Part of the statement can't be modeled, so it was skipped.",
+	RuleUnknownType:            "The type isn't known, so values of it are treated as opaque.",
+	RuleImplicitIndex:          "The constraint creates an index of the same name.",
+	RuleTruncatedIdentifier:    "The identifier is longer than Postgres keeps, so it's truncated to 63 bytes.",
+	RuleUnnormalizedIdentifier: "The identifier isn't normalized to NFC, so it differs from the same text typed elsewhere.",
+	RuleConfusableIdentifier:   "The identifier mixes scripts or looks like another identifier.",
+	RuleWideTable:              "The table has so many columns that some likely belong in tables of their own.",
+	RuleNumberedColumns:        "The columns hold a list of values, which would be better as rows of another table.",
+	RulePolymorphicReference:   "The columns reference a row of one of several tables, which no foreign key can check.",
+	RuleRepeatedColumns:        "The tables share a group of columns, which could be moved to a table both reference.",
+	RuleOrphanedSequence:       "No column owns the sequence or takes its default from it.",
+	RuleUnusedEnum:             "No column is of the enum type.",
+	RuleReservedKeyword:        "The name is a reserved keyword, so it must be quoted wherever it's used.",
+	RuleQuotedIdentifier:       "The name has upper case letters or other characters which must be quoted wherever it's used.",
+}
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	// SeverityNote is for what's worth knowing but isn't a problem, like
+	// Postgres' notices.
+	SeverityNote Severity = "note"
+)
+
+// Diagnostic is an error or warning from compiling, located in the file it
+// occurred in where that's known.
+type Diagnostic struct {
+	File     string   `json:"file,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Severity Severity `json:"severity"`
+	Rule     string   `json:"rule"`
+	Message  string   `json:"message"`
+}
+
+// SourceLocation is where an object was defined. File is empty if the
+// source wasn't read from a file, and Line is 0 if it isn't known.
+type SourceLocation struct {
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+}
+
+func (l SourceLocation) String() string {
+
+	if l.File == "" {
+		return fmt.Sprintf("line %d", l.Line)
+	}
+	return fmt.Sprintf("%s:%d", l.File, l.Line)
+}
+
+// duplicateLocations describes where an object was first defined and where
+// it's defined again, for the error about the duplicate, or is empty if
+// where it was first defined isn't known.
+func duplicateLocations(orig, dup SourceLocation) string {
+
+	if orig.Line == 0 {
+		return ""
+	}
+	if dup.Line == 0 {
+		return fmt.Sprintf(" (first defined at %s)", orig)
+	}
+	return fmt.Sprintf(" (first defined at %s, again at %s)", orig, dup)
+}
+
+// sourceLocation returns where the text at offset in the source being
+// compiled is.
+func (c *Compiler) sourceLocation(offset int32) SourceLocation {
+
+	var loc SourceLocation
+	if len(c.files) > 0 {
+		loc.File = c.files[len(c.files)-1]
+	}
+	if c.annotations != nil && offset >= 0 {
+		loc.Line = c.srcLine + c.annotations.line(int(offset)) + 1
+	}
+	return loc
+}
+
+// CompileError is an error compiling a statement, located in its source.
+// It reads the same as the error it wraps.
+type CompileError struct {
+	File string
+	Line int
+	Rule string
+	Err  error
+}
+
+func (e *CompileError) Error() string {
+
+	return e.Err.Error()
+}
+
+func (e *CompileError) Unwrap() error {
+
+	return e.Err
+}
+
+// locateError locates err at the current statement, unless it's already
+// located.
+func (c *Compiler) locateError(err error) error {
+
+	var ce *CompileError
+	if !errors.As(err, &ce) {
+		ce = &CompileError{Rule: RuleCompile, Err: err}
+		err = ce
+	}
+	if ce.File == "" && len(c.files) > 0 {
+		ce.File = c.files[len(c.files)-1]
+	}
+	if ce.Line == 0 {
+		ce.Line = c.stmtLine()
+	}
+	return err
+}
+
+// syntaxError locates an error from parsing src at the line the parser
+// stopped on.
+func syntaxError(src string, err error) error {
+
+	ce := &CompileError{Rule: RuleSyntax, Err: err}
+	var pe *parser.Error
+	if errors.As(err, &pe) && pe.Cursorpos > 0 {
+		ce.Line = newLineIndex(src).line(pe.Cursorpos-1) + 1
+	}
+	return ce
+}
+
+// setErrorFile sets the file of err if it's a CompileError which isn't
+// already located in one.
+func setErrorFile(err error, file string) {
+
+	var ce *CompileError
+	if errors.As(err, &ce) && ce.File == "" {
+		ce.File = file
+	}
+}
+
+// stmtLine returns the line of the statement being applied, or 0 if it
+// isn't known.
+func (c *Compiler) stmtLine() int {
+
+	if c.stmt == nil || c.annotations == nil {
+		return 0
+	}
+	return c.srcLine + c.annotations.line(int(statementStart(c.src, c.stmt))) + 1
+}
+
+// stmtLocation returns where the statement being applied is.
+func (c *Compiler) stmtLocation() SourceLocation {
+
+	var loc SourceLocation
+	if len(c.files) > 0 {
+		loc.File = c.files[len(c.files)-1]
+	}
+	loc.Line = c.stmtLine()
+	return loc
+}
+
+// warn records a warning, with the line it applies to if that's known.
+func (c *Compiler) warn(rule string, line int, msg string) {
+
+	c.report(SeverityWarning, rule, line, msg)
+	if line > 0 {
+		msg += fmt.Sprintf(" (line %d)", line)
+	}
+	c.Warnings = append(c.Warnings, msg)
+}
+
+// note records a note, which unlike a warning isn't added to Warnings.
+func (c *Compiler) note(rule string, line int, msg string) {
+
+	c.report(SeverityNote, rule, line, msg)
+}
+
+func (c *Compiler) report(severity Severity, rule string, line int, msg string) {
+
+	d := &Diagnostic{Line: line, Severity: severity, Rule: rule, Message: msg}
+	if len(c.files) > 0 {
+		d.File = c.files[len(c.files)-1]
+	}
+	c.diagnostics = append(c.diagnostics, d)
+	if c.DiagnosticsSink != nil {
+		c.DiagnosticsSink(c.promote(d))
+	}
+}
+
+// Diagnostics returns the warnings, notes and statements skipped for being
+// unsupported while compiling, followed by err if compiling failed.
+// Warnings under the rules in WarningsAsErrors are returned as errors.
+func (c *Compiler) Diagnostics(err error) []*Diagnostic {
+
+	var ret []*Diagnostic
+	for _, d := range c.diagnostics {
+		ret = append(ret, c.promote(d))
+	}
+	for _, s := range c.Skipped {
+		if s.Reason != "" {
+			ret = append(ret, c.promote(s.diagnostic()))
+		}
+	}
+	if err != nil {
+		d := &Diagnostic{Severity: SeverityError, Rule: RuleCompile, Message: err.Error()}
+		var ce *CompileError
+		if errors.As(err, &ce) {
+			d.File, d.Line, d.Rule = ce.File, ce.Line, ce.Rule
+		}
+		ret = append(ret, d)
+	}
+	return ret
+}
+
+// promote returns a copy of d, as an error if it's a warning under a rule
+// in WarningsAsErrors.
+func (c *Compiler) promote(d *Diagnostic) *Diagnostic {
+
+	ret := *d
+	if ret.Severity == SeverityWarning && c.promoted(ret.Rule) {
+		ret.Severity = SeverityError
+	}
+	return &ret
+}
+
+// promoted reports whether warnings under rule are errors.
+func (c *Compiler) promoted(rule string) bool {
+
+	return slices.Contains(c.WarningsAsErrors, rule) || slices.Contains(c.WarningsAsErrors, "all")
+}
+
+// promotedWarnings returns the warnings which WarningsAsErrors makes
+// errors.
+func (c *Compiler) promotedWarnings() []*Diagnostic {
+
+	var ret []*Diagnostic
+	for _, d := range c.Diagnostics(nil) {
+		if d.Severity == SeverityError {
+			ret = append(ret, d)
+		}
+	}
+	return ret
+}
+
+// WriteDiagnostics writes diagnostics as json or sarif.
+func WriteDiagnostics(w io.Writer, diags []*Diagnostic, format string) error {
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	switch format {
+	case "json":
+		return enc.Encode(diags)
+	case "sarif":
+		return enc.Encode(sarifLog(diags))
+	}
+	return fmt.Errorf("unknown diagnostics format %q", format)
+}
+
+// The parts of the SARIF 2.1.0 format which diagnostics use.
+type (
+	sarif struct {
+		Version string     `json:"version"`
+		Schema  string     `json:"$schema"`
+		Runs    []sarifRun `json:"runs"`
+	}
+	sarifRun struct {
+		Tool    sarifTool     `json:"tool"`
+		Results []sarifResult `json:"results"`
+	}
+	sarifTool struct {
+		Driver sarifDriver `json:"driver"`
+	}
+	sarifDriver struct {
+		Name  string      `json:"name"`
+		Rules []sarifRule `json:"rules"`
+	}
+	sarifRule struct {
+		ID               string       `json:"id"`
+		ShortDescription sarifMessage `json:"shortDescription"`
+	}
+	sarifMessage struct {
+		Text string `json:"text"`
+	}
+	sarifResult struct {
+		RuleID    string          `json:"ruleId"`
+		Level     string          `json:"level"`
+		Message   sarifMessage    `json:"message"`
+		Locations []sarifLocation `json:"locations,omitempty"`
+	}
+	sarifLocation struct {
+		PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+	}
+	sarifPhysicalLocation struct {
+		ArtifactLocation sarifArtifactLocation `json:"artifactLocation"`
+		Region           *sarifRegion          `json:"region,omitempty"`
+	}
+	sarifArtifactLocation struct {
+		URI string `json:"uri"`
+	}
+	sarifRegion struct {
+		StartLine int `json:"startLine"`
+	}
+)
+
+func sarifLog(diags []*Diagnostic) *sarif {
+
+	run := sarifRun{
+		Tool:    sarifTool{Driver: sarifDriver{Name: "pgmodelgen", Rules: make([]sarifRule, 0)}},
+		Results: make([]sarifResult, 0, len(diags)),
+	}
+	var rules []string
+	for _, d := range diags {
+		if !slices.Contains(rules, d.Rule) {
+			rules = append(rules, d.Rule)
+		}
+		res := sarifResult{RuleID: d.Rule, Level: string(d.Severity), Message: sarifMessage{Text: d.Message}}
+		if d.File != "" {
+			loc := sarifLocation{PhysicalLocation: sarifPhysicalLocation{ArtifactLocation: sarifArtifactLocation{URI: d.File}}}
+			if d.Line > 0 {
+				loc.PhysicalLocation.Region = &sarifRegion{StartLine: d.Line}
+			}
+			res.Locations = append(res.Locations, loc)
+		}
+		run.Results = append(run.Results, res)
+	}
+	slices.Sort(rules)
+	for _, id := range rules {
+		run.Tool.Driver.Rules = append(run.Tool.Driver.Rules, sarifRule{ID: id, ShortDescription: sarifMessage{Text: ruleDescriptions[id]}})
+	}
+	return &sarif{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	}
+}
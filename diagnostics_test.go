@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_Diagnostics(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) string {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.WriteFile(path, []byte(sql), 0o644))
+		return path
+	}
+	first := write("001.sql", "CREATE TABLE t (a int);\n\nCREATE UNIQUE INDEX t_a ON t (a) NULLS NOT DISTINCT;\n")
+	second := write("002.sql", "CREATE TABLE u (a int);\n\nALTER TABLE missing\n    ADD COLUMN b int;\n")
+	third := write("003.sql", "CREATE TABLE v (a int);\nCREATE TABLE w (a int;\n")
+
+	for _, stream := range []bool{false, true} {
+		c := NewCompiler()
+		c.TargetVersion = 14
+		c.WarnUnsupported = true
+		if stream {
+			c.StreamSize = 1
+		}
+		err := c.CompileFiles([]string{first, second})
+		require.NotNil(t, err)
+		diags := c.Diagnostics(err)
+		require.Len(t, diags, 2)
+		assert.Equal(t, &Diagnostic{File: first, Line: 3, Severity: SeverityWarning, Rule: RuleTargetVersion,
+			Message: "NULLS NOT DISTINCT requires Postgres 15, but the target is 14"}, diags[0])
+		assert.Equal(t, second, diags[1].File)
+		assert.Equal(t, 3, diags[1].Line)
+		assert.Equal(t, SeverityError, diags[1].Severity)
+		assert.Equal(t, RuleCompile, diags[1].Rule)
+		assert.Equal(t, err.Error(), diags[1].Message)
+		// Warnings are unchanged
+		assert.Equal(t, []string{first + ": NULLS NOT DISTINCT requires Postgres 15, but the target is 14 (line 3)"}, c.Warnings)
+
+		c = NewCompiler()
+		if stream {
+			c.StreamSize = 1
+		}
+		err = c.CompileFiles([]string{third})
+		require.NotNil(t, err)
+		diags = c.Diagnostics(err)
+		require.Len(t, diags, 1)
+		assert.Equal(t, RuleSyntax, diags[0].Rule)
+		assert.Equal(t, 2, diags[0].Line)
+	}
+
+	c := NewCompiler()
+	c.Lenient = true
+	require.Nil(t, c.Compile("CREATE TABLE t (a int);\nCREATE TABLE u (\n    id int,\n    EXCLUDE USING gist (id WITH =)\n);\n"))
+	diags := c.Diagnostics(nil)
+	require.Len(t, diags, 1)
+	assert.Equal(t, RuleUnsupported, diags[0].Rule)
+	assert.Equal(t, 2, diags[0].Line)
+}
+
+func TestWriteDiagnostics(t *testing.T) {
+	diags := []*Diagnostic{
+		{File: "001.sql", Line: 3, Severity: SeverityWarning, Rule: RuleTargetVersion, Message: "too new"},
+		{Severity: SeverityError, Rule: RuleCompile, Message: "broken"},
+	}
+
+	var sb strings.Builder
+	require.Nil(t, WriteDiagnostics(&sb, diags, "json"))
+	var decoded []*Diagnostic
+	require.Nil(t, json.Unmarshal([]byte(sb.String()), &decoded))
+	assert.Equal(t, diags, decoded)
+
+	sb.Reset()
+	require.Nil(t, WriteDiagnostics(&sb, diags, "sarif"))
+	var log struct {
+		Version string
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct{ ID string }
+				}
+			}
+			Results []struct {
+				RuleID    string
+				Level     string
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct{ URI string }
+						Region           struct{ StartLine int }
+					}
+				}
+			}
+		}
+	}
+	require.Nil(t, json.Unmarshal([]byte(sb.String()), &log))
+	assert.Equal(t, "2.1.0", log.Version)
+	require.Len(t, log.Runs, 1)
+	run := log.Runs[0]
+	require.Len(t, run.Tool.Driver.Rules, 2)
+	assert.Equal(t, RuleCompile, run.Tool.Driver.Rules[0].ID)
+	require.Len(t, run.Results, 2)
+	assert.Equal(t, "warning", run.Results[0].Level)
+	assert.Equal(t, "001.sql", run.Results[0].Locations[0].PhysicalLocation.ArtifactLocation.URI)
+	assert.Equal(t, 3, run.Results[0].Locations[0].PhysicalLocation.Region.StartLine)
+	assert.Empty(t, run.Results[1].Locations)
+
+	assert.ErrorContains(t, WriteDiagnostics(&sb, diags, "xml"), "unknown diagnostics format")
+}
+
+func TestCompiler_DuplicateLocations(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) string {
+		path := filepath.Join(dir, name)
+		require.Nil(t, os.WriteFile(path, []byte(sql), 0o644))
+		return path
+	}
+	first := write("001.sql", "CREATE TABLE t (\n    a int,\n    CONSTRAINT t_a_key UNIQUE (a)\n);\n")
+	tables := write("002.sql", "\nCREATE TABLE t (b int);\n")
+	columns := write("003.sql", "ALTER TABLE t ADD COLUMN a int;\n")
+	constraints := write("004.sql", "ALTER TABLE t\n    ADD CONSTRAINT t_a_key UNIQUE (a);\n")
+
+	for _, stream := range []bool{false, true} {
+		for dup, msg := range map[string]string{
+			tables:      "table already exists: t (first defined at " + first + ":1, again at " + tables + ":2)",
+			columns:     "column already exists: a (first defined at " + first + ":2, again at " + columns + ":1)",
+			constraints: "constraint already exists: t_a_key (first defined at " + first + ":3, again at " + constraints + ":2)",
+		} {
+			c := NewCompiler()
+			if stream {
+				c.StreamSize = 1
+			}
+			err := c.CompileFiles([]string{first, dup})
+			require.NotNil(t, err)
+			assert.Contains(t, err.Error(), msg)
+		}
+	}
+
+	c := NewCompiler()
+	err := c.Compile("CREATE TABLE t (a int);\nCREATE TABLE u (b int);\nALTER TABLE u RENAME TO t;\n")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "table already exists: t (first defined at line 1, again at line 3)")
+	assert.Equal(t, SourceLocation{Line: 2}, assertTable(t, c, "u").Defined)
+}
+
+func TestCompiler_Notes(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile("CREATE TABLE t (\n    id int PRIMARY KEY,\n    mood mood,\n    embedding vector(3)\n);\n"))
+	tab := assertTable(t, c, "t")
+	mood, _ := tab.Columns.Get("mood")
+	embedding, _ := tab.Columns.Get("embedding")
+	assert.True(t, mood.Type.Opaque)
+	assert.Equal(t, "vector(3)", embedding.FormatType())
+	assert.Equal(t, []*Diagnostic{
+		{Line: 2, Severity: SeverityNote, Rule: RuleImplicitIndex, Message: "constraint t_pkey will create implicit index t_pkey for table t"},
+		{Line: 3, Severity: SeverityWarning, Rule: RuleUnknownType, Message: "unknown type mood, treating it as opaque"},
+		{Line: 4, Severity: SeverityWarning, Rule: RuleUnknownType, Message: "unknown type vector, treating it as opaque"},
+	}, c.Diagnostics(nil))
+	assert.Equal(t, []string{"unknown type mood, treating it as opaque (line 3)", "unknown type vector, treating it as opaque (line 4)"}, c.Warnings)
+	assert.Empty(t, c.promotedWarnings())
+
+	c.WarningsAsErrors = []string{RuleUnknownType}
+	promoted := c.promotedWarnings()
+	require.Len(t, promoted, 2)
+	assert.Equal(t, SeverityError, promoted[0].Severity)
+	assert.Equal(t, SeverityWarning, c.diagnostics[1].Severity)
+}
@@ -0,0 +1,1514 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"flag"
+	"fmt"
+	"github.com/henges/pgmodelparse/collections"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+type ChangeKind int
+
+const (
+	ChangeKindAdd ChangeKind = iota
+	ChangeKindDrop
+	ChangeKindAlter
+	ChangeKindRename
+)
+
+func (k ChangeKind) String() string {
+
+	switch k {
+	case ChangeKindAdd:
+		return "add"
+	case ChangeKindDrop:
+		return "drop"
+	case ChangeKindRename:
+		return "rename"
+	default:
+		return "alter"
+	}
+}
+
+type ObjectKind int
+
+const (
+	ObjectKindSchema ObjectKind = iota
+	ObjectKindTable
+	ObjectKindColumn
+	ObjectKindConstraint
+	ObjectKindIndex
+	ObjectKindStatistics
+	ObjectKindEventTrigger
+	// ObjectKindDescription is the comment and security labels of an
+	// object of any other kind.
+	ObjectKindDescription
+	ObjectKindView
+	ObjectKindEnum
+	ObjectKindSequence
+)
+
+func (k ObjectKind) String() string {
+
+	switch k {
+	case ObjectKindSchema:
+		return "schema"
+	case ObjectKindTable:
+		return "table"
+	case ObjectKindColumn:
+		return "column"
+	case ObjectKindIndex:
+		return "index"
+	case ObjectKindStatistics:
+		return "statistics"
+	case ObjectKindEventTrigger:
+		return "event trigger"
+	case ObjectKindDescription:
+		return "description"
+	case ObjectKindView:
+		return "view"
+	case ObjectKindEnum:
+		return "enum"
+	case ObjectKindSequence:
+		return "sequence"
+	default:
+		return "constraint"
+	}
+}
+
+// Change is a single difference between two catalogs. From and To are the
+// *Schema, *Table, *Column, *Constraint, *Index, *Statistics,
+// *EventTrigger, *ObjectDescription, *Enum, *Sequence or the
+// *RawStatement of a view before and after the change; From is nil for
+// added objects and To is nil for dropped ones.
+type Change struct {
+	Kind   ChangeKind
+	Object ObjectKind
+	// Schema, Table and Name locate the changed object, using its new
+	// name if it was renamed. Table is empty for schemas, and Name is
+	// empty for schemas and tables. Event triggers only have a Name, and
+	// views, enums and sequences a Schema and Name.
+	Schema string
+	Table  string
+	Name   string
+	From   any
+	To     any
+	// enumColumns are the columns of the from catalog converted to an enum
+	// which is created again.
+	enumColumns Columns
+}
+
+// Key uniquely identifies the object a change applies to.
+func (c *Change) Key() string {
+
+	return strings.Join([]string{c.Object.String(), c.Schema, c.Table, c.Name}, "\x00")
+}
+
+func (c *Change) Path() string {
+
+	var parts []string
+	if c.Schema != "" {
+		parts = append(parts, c.Schema)
+	}
+	if c.Table != "" {
+		parts = append(parts, c.Table)
+	}
+	if c.Name != "" {
+		parts = append(parts, c.Name)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (c *Change) String() string {
+
+	if c.Kind == ChangeKindRename {
+		old := *c
+		if c.Object == ObjectKindTable {
+			old.Table = c.From.(*Table).Name
+		} else {
+			old.Name = changeObjectName(c.From)
+		}
+		return fmt.Sprintf("%s %s %s to %s", c.Kind, c.Object, old.Path(), changeObjectName(c.To))
+	}
+	return fmt.Sprintf("%s %s %s", c.Kind, c.Object, c.Path())
+}
+
+func changeObjectName(obj any) string {
+
+	switch o := obj.(type) {
+	case *Table:
+		return o.Name
+	case *Column:
+		return o.Name
+	case *Constraint:
+		return o.Name
+	case *Index:
+		return o.Name
+	case *Statistics:
+		return o.Name
+	case *EventTrigger:
+		return o.Name
+	case *Enum:
+		return o.Name
+	case *Sequence:
+		return o.Name
+	case *RawStatement:
+		_, name, _ := strings.Cut(o.Name, ".")
+		return name
+	}
+	return ""
+}
+
+// SQL returns the statements which apply the change.
+func (c *Change) SQL() []string {
+
+	switch c.Object {
+	case ObjectKindSchema:
+		{
+			if c.Kind == ChangeKindAdd {
+				return []string{fmt.Sprintf("CREATE SCHEMA %s;", QuoteIdent(c.Schema))}
+			}
+			return []string{fmt.Sprintf("DROP SCHEMA %s CASCADE;", QuoteIdent(c.Schema))}
+		}
+	case ObjectKindTable:
+		{
+			switch c.Kind {
+			case ChangeKindDrop:
+				return []string{fmt.Sprintf("DROP TABLE %s;", TableIdent(c.From.(*Table)))}
+			case ChangeKindRename:
+				return []string{fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", TableIdent(c.From.(*Table)), QuoteIdent(c.To.(*Table).Name))}
+			case ChangeKindAlter:
+				return alterTableSQL(c.From, c.To.(*Table))
+			}
+			tab := c.To.(*Table)
+			defs := make([]string, 0, len(tab.Columns.List()))
+			for _, col := range tab.Columns.List() {
+				defs = append(defs, "    "+ColumnDefinition(col))
+			}
+			create := "CREATE TABLE"
+			if tab.Unlogged {
+				create = "CREATE UNLOGGED TABLE"
+			}
+			return []string{fmt.Sprintf("%s %s (\n%s\n)%s;", create, TableIdent(tab), strings.Join(defs, ",\n"), storageOptionsClause(tab.Options))}
+		}
+	case ObjectKindColumn:
+		{
+			switch c.Kind {
+			case ChangeKindAdd:
+				col := c.To.(*Column)
+				return []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", TableIdent(col.Table), ColumnDefinition(col))}
+			case ChangeKindDrop:
+				col := c.From.(*Column)
+				return []string{fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", TableIdent(col.Table), QuoteIdent(col.Name))}
+			case ChangeKindRename:
+				from, to := c.From.(*Column), c.To.(*Column)
+				return []string{fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", TableIdent(to.Table), QuoteIdent(from.Name), QuoteIdent(to.Name))}
+			}
+			from, to := c.From.(*Column), c.To.(*Column)
+			fromDef, toDef := definitionsOf(from, to)
+			name := QuoteIdent(to.Name)
+			var cmds []string
+			// An identity column can't have its default set until its
+			// identity is dropped, nor can one be added until the default is
+			if fromDef.Identity != IdentityNone && toDef.Identity == IdentityNone {
+				cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s DROP IDENTITY", name))
+			}
+			if fromDef.Type != toDef.Type {
+				typ := to.Type
+				if underlying, ok := serialTypes[typ]; ok {
+					typ = underlying
+				}
+				cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s TYPE %s", name, typ.Format(to.TypeMods)+strings.Repeat("[]", to.ArrayDims)))
+			}
+			if fromDef.NotNull != toDef.NotNull {
+				if toDef.NotNull {
+					cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s SET NOT NULL", name))
+				} else {
+					cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s DROP NOT NULL", name))
+				}
+			}
+			if fromDef.Default != toDef.Default {
+				if toDef.Default != "" {
+					cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s SET DEFAULT %s", name, toDef.Default))
+				} else {
+					cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s DROP DEFAULT", name))
+				}
+			}
+			if fromDef.Identity != toDef.Identity && toDef.Identity != IdentityNone {
+				if fromDef.Identity == IdentityNone {
+					cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s ADD GENERATED %s AS IDENTITY", name, identityDefinition(toDef.Identity)))
+				} else {
+					cmds = append(cmds, fmt.Sprintf("ALTER COLUMN %s SET GENERATED %s", name, identityDefinition(toDef.Identity)))
+				}
+			}
+			return []string{fmt.Sprintf("ALTER TABLE %s %s;", TableIdent(to.Table), strings.Join(cmds, ", "))}
+		}
+	case ObjectKindIndex:
+		{
+			switch c.Kind {
+			case ChangeKindDrop:
+				return []string{fmt.Sprintf("DROP INDEX %s;", IndexIdent(c.From.(*Index)))}
+			case ChangeKindRename:
+				return []string{fmt.Sprintf("ALTER INDEX %s RENAME TO %s;", IndexIdent(c.From.(*Index)), QuoteIdent(c.To.(*Index).Name))}
+			case ChangeKindAlter:
+				var stmts []string
+				for _, stmt := range StorageOptionsDefinitions("INDEX "+IndexIdent(c.To.(*Index)), c.From.(*Index).Options, c.To.(*Index).Options) {
+					stmts = append(stmts, stmt+";")
+				}
+				return stmts
+			}
+			return []string{IndexDefinition(c.To.(*Index)) + ";"}
+		}
+	case ObjectKindStatistics:
+		{
+			switch c.Kind {
+			case ChangeKindDrop:
+				return []string{fmt.Sprintf("DROP STATISTICS %s;", StatisticsIdent(c.From.(*Statistics)))}
+			case ChangeKindRename:
+				return []string{fmt.Sprintf("ALTER STATISTICS %s RENAME TO %s;", StatisticsIdent(c.From.(*Statistics)), QuoteIdent(c.To.(*Statistics).Name))}
+			}
+			return []string{StatisticsDefinition(c.To.(*Statistics)) + ";"}
+		}
+	case ObjectKindDescription:
+		return descriptionChangeSQL(c.From, c.To)
+	case ObjectKindView:
+		{
+			if c.Kind == ChangeKindDrop {
+				return []string{fmt.Sprintf("DROP VIEW %s;", quoteQualifiedName(c.From.(*RawStatement).Name))}
+			}
+			return []string{c.To.(*RawStatement).SQL + ";"}
+		}
+	case ObjectKindEnum:
+		return c.enumSQL()
+	case ObjectKindSequence:
+		{
+			switch c.Kind {
+			case ChangeKindDrop:
+				// Sequences owned by dropped columns are already gone
+				return []string{fmt.Sprintf("DROP SEQUENCE IF EXISTS %s;", SequenceIdent(c.From.(*Sequence)))}
+			case ChangeKindAdd:
+				return []string{SequenceDefinition(c.To.(*Sequence)) + ";"}
+			}
+			from, to := c.From.(*Sequence), c.To.(*Sequence)
+			var stmts []string
+			if from.Unlogged != to.Unlogged {
+				logged := "LOGGED"
+				if to.Unlogged {
+					logged = "UNLOGGED"
+				}
+				stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s SET %s;", SequenceIdent(to), logged))
+			}
+			if sequenceOwner(from) != sequenceOwner(to) {
+				stmts = append(stmts, fmt.Sprintf("ALTER SEQUENCE %s OWNED BY %s;", SequenceIdent(to), sequenceOwner(to)))
+			}
+			return stmts
+		}
+	case ObjectKindEventTrigger:
+		{
+			switch c.Kind {
+			case ChangeKindDrop:
+				return []string{fmt.Sprintf("DROP EVENT TRIGGER %s;", QuoteIdent(c.From.(*EventTrigger).Name))}
+			case ChangeKindRename:
+				return []string{fmt.Sprintf("ALTER EVENT TRIGGER %s RENAME TO %s;", QuoteIdent(c.From.(*EventTrigger).Name), QuoteIdent(c.To.(*EventTrigger).Name))}
+			case ChangeKindAlter:
+				return []string{EventTriggerFiringDefinition(c.To.(*EventTrigger)) + ";"}
+			}
+			var stmts []string
+			for _, stmt := range EventTriggerDefinition(c.To.(*EventTrigger)) {
+				stmts = append(stmts, stmt+";")
+			}
+			return stmts
+		}
+	default:
+		{
+			if c.Kind == ChangeKindRename {
+				from, to := c.From.(*Constraint), c.To.(*Constraint)
+				return []string{fmt.Sprintf("ALTER TABLE %s RENAME CONSTRAINT %s TO %s;", TableIdent(to.Table), QuoteIdent(from.Name), QuoteIdent(to.Name))}
+			}
+			if c.Kind == ChangeKindDrop {
+				from := c.From.(*Constraint)
+				return []string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", TableIdent(from.Table), QuoteIdent(from.Name))}
+			}
+			to := c.To.(*Constraint)
+			add := fmt.Sprintf("ALTER TABLE %s ADD %s;", TableIdent(to.Table), ConstraintDefinition(to))
+			if c.Kind == ChangeKindAlter {
+				// A validated constraint can't be made NOT VALID again, so
+				// it's added again without checking existing rows
+				if to.NotValid {
+					return []string{fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s;", TableIdent(to.Table), QuoteIdent(c.From.(*Constraint).Name)), add}
+				}
+				return []string{fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s;", TableIdent(to.Table), QuoteIdent(to.Name))}
+			}
+			return []string{add}
+		}
+	}
+}
+
+// alterTableSQL returns the statements altering a table from from, which
+// is nil for a table being added, to to. The replica identity is set
+// again whenever it's an index's, as that index may have been added again
+// under the same name.
+func alterTableSQL(from any, to *Table) []string {
+
+	fromTab, _ := from.(*Table)
+	var stmts []string
+	if fromTab == nil || fromTab.ReplicaIdentity != to.ReplicaIdentity || to.ReplicaIdentity == ReplicaIdentityIndex {
+		stmts = append(stmts, ReplicaIdentityDefinition(to)+";")
+	}
+	if fromTab != nil && fromTab.Unlogged != to.Unlogged {
+		logged := "LOGGED"
+		if to.Unlogged {
+			logged = "UNLOGGED"
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s SET %s;", TableIdent(to), logged))
+	}
+	if fromTab != nil {
+		for _, stmt := range StorageOptionsDefinitions("TABLE "+TableIdent(to), fromTab.Options, to.Options) {
+			stmts = append(stmts, stmt+";")
+		}
+	}
+	return stmts
+}
+
+// phase orders changes so that applying them in order is valid: objects
+// are dropped by their old names before anything is renamed, renames
+// happen before anything is created, and constraints and indexes are added
+// once the columns they depend on exist.
+func (c *Change) phase() int {
+
+	// Foreign keys are dropped before the unique constraints they refer
+	// to, and added after them
+	con, _ := c.From.(*Constraint)
+	if c.To != nil {
+		con, _ = c.To.(*Constraint)
+	}
+	isFK := con != nil && con.Type == ConstraintTypeForeignKey
+	switch {
+	// Descriptions are set once the objects they're on exist
+	case c.Object == ObjectKindDescription:
+		return 19
+	// Event triggers are dropped first and created last, so that they
+	// don't fire for the other changes
+	case c.Object == ObjectKindEventTrigger && c.Kind == ChangeKindDrop:
+		return 0
+	case c.Object == ObjectKindEventTrigger:
+		return 18
+	// Views are dropped before the tables they use change, and created
+	// once they have
+	case c.Object == ObjectKindView && c.Kind == ChangeKindDrop:
+		return 0
+	case c.Object == ObjectKindView:
+		return 17
+	case c.Object == ObjectKindConstraint && c.Kind == ChangeKindDrop && isFK:
+		return 0
+	case (c.Object == ObjectKindConstraint || c.Object == ObjectKindIndex || c.Object == ObjectKindStatistics) && c.Kind == ChangeKindDrop:
+		return 1
+	case c.Object == ObjectKindColumn && c.Kind == ChangeKindDrop:
+		return 2
+	case c.Object == ObjectKindTable && c.Kind == ChangeKindDrop:
+		return 3
+	case c.Object == ObjectKindEnum && c.Kind == ChangeKindDrop:
+		return 4
+	case c.Object == ObjectKindSchema && c.Kind == ChangeKindDrop:
+		return 5
+	case c.Object == ObjectKindSchema:
+		return 6
+	// Enums and sequences are created before the tables using them, and an
+	// enum is altered while its columns still have their old names
+	case (c.Object == ObjectKindEnum || c.Object == ObjectKindSequence) && c.Kind == ChangeKindAdd:
+		return 7
+	case c.Object == ObjectKindEnum:
+		return 8
+	case c.Object == ObjectKindTable && c.Kind == ChangeKindRename:
+		return 9
+	case c.Object == ObjectKindColumn && c.Kind == ChangeKindRename:
+		return 10
+	case (c.Object == ObjectKindConstraint || c.Object == ObjectKindIndex || c.Object == ObjectKindStatistics) && c.Kind == ChangeKindRename:
+		return 11
+	case c.Object == ObjectKindTable && c.Kind == ChangeKindAlter:
+		// A replica identity may use an index added with the table
+		return 14
+	case c.Object == ObjectKindTable:
+		return 12
+	case c.Object == ObjectKindColumn:
+		return 13
+	case isFK:
+		return 15
+	// Sequences are owned by columns once they exist, and dropped once no
+	// default uses them
+	case c.Object == ObjectKindSequence:
+		return 16
+	}
+	return 14
+}
+
+type Changes []*Change
+
+// Sort orders the changes so that their statements can be applied in order.
+func (cs Changes) Sort() {
+
+	slices.SortStableFunc(cs, func(a, b *Change) int {
+		return a.phase() - b.phase()
+	})
+}
+
+// SQL returns the statements applying all the changes, in order.
+func (cs Changes) SQL() []string {
+
+	var stmts []string
+	for _, c := range cs {
+		stmts = append(stmts, c.SQL()...)
+	}
+	return stmts
+}
+
+// RenameDetection controls how eagerly Diff reports a dropped object and
+// an added object as a rename of one to the other.
+type RenameDetection int
+
+const (
+	// RenamesNone never reports renames.
+	RenamesNone RenameDetection = iota
+	// RenamesHinted only reports renames marked with a "@renamed_from"
+	// annotation on the new object.
+	RenamesHinted
+	// RenamesConservative also reports renames between objects with the
+	// same definition and similar names, where there is no other candidate.
+	RenamesConservative
+	// RenamesAggressive reports renames between objects with the same
+	// definition regardless of their names, preferring similar names where
+	// there are several candidates.
+	RenamesAggressive
+)
+
+var renameDetectionNames = []string{"none", "hinted", "conservative", "aggressive"}
+
+func (r RenameDetection) String() string {
+
+	return renameDetectionNames[r]
+}
+
+func ParseRenameDetection(s string) (RenameDetection, error) {
+
+	idx := slices.Index(renameDetectionNames, s)
+	if idx < 0 {
+		return RenamesNone, fmt.Errorf("unknown rename detection %q, expected one of: %s", s, strings.Join(renameDetectionNames, ", "))
+	}
+	return RenameDetection(idx), nil
+}
+
+type DiffOptions struct {
+	Renames RenameDetection
+	// MatchOIDs treats tables and columns with the same OID as the same
+	// object, whatever their names. It's for when to was compiled from the
+	// same sources as from with more appended, so that the OIDs of the
+	// objects they share are the same.
+	MatchOIDs bool
+	// Filter, if set, leaves the objects it doesn't include out of the
+	// comparison.
+	Filter *ObjectFilter
+}
+
+// renameSimilarity is the name similarity above which RenamesConservative
+// will consider objects to be renamed.
+const renameSimilarity = 0.5
+
+// Diff returns the changes which transform from into to, sorted such that
+// their SQL can be applied in order.
+func Diff(from, to *Catalog, opts DiffOptions) Changes {
+
+	if opts.Filter != nil {
+		from, to = opts.Filter.Apply(from), opts.Filter.Apply(to)
+	}
+	d := &differ{
+		from:    from,
+		to:      to,
+		opts:    opts,
+		tables:  make(map[*Table]*Table),
+		columns: make(map[*Column]*Column),
+	}
+	// Every rename needs to be known before comparing constraints, as a
+	// foreign key may refer to a renamed table or column anywhere
+	d.matchTables()
+	for fromTab, toTab := range d.tables {
+		d.matchColumns(fromTab, toTab)
+	}
+
+	var changes Changes
+	for _, toSch := range to.Schemas.List() {
+		fromSch, ok := from.Schemas.Get(toSch.Name)
+		if !ok {
+			changes = append(changes, &Change{Kind: ChangeKindAdd, Object: ObjectKindSchema, Schema: toSch.Name, To: toSch})
+		}
+		for _, toTab := range toSch.Tables.List() {
+			fromTab := d.fromTable(toTab)
+			if fromTab == nil {
+				changes = append(changes, addTableChanges(to, toTab)...)
+				continue
+			}
+			if fromTab.Name != toTab.Name {
+				changes = append(changes, &Change{Kind: ChangeKindRename, Object: ObjectKindTable,
+					Schema: toTab.Schema, Table: toTab.Name, From: fromTab, To: toTab})
+			}
+			changes = append(changes, d.diffTable(fromTab, toTab)...)
+		}
+		if fromSch == nil {
+			continue
+		}
+		for _, fromTab := range fromSch.Tables.List() {
+			if _, ok := d.tables[fromTab]; !ok {
+				changes = append(changes, dropTableChanges(from, fromTab)...)
+			}
+		}
+	}
+	for _, fromSch := range from.Schemas.List() {
+		if _, ok := to.Schemas.Get(fromSch.Name); !ok {
+			changes = append(changes, &Change{Kind: ChangeKindDrop, Object: ObjectKindSchema, Schema: fromSch.Name, From: fromSch})
+		}
+	}
+	changes = append(changes, d.diffEventTriggers()...)
+	changes = append(changes, d.diffEnums()...)
+	changes = append(changes, d.diffSequences()...)
+	changes = append(changes, d.diffViews()...)
+	changes = append(changes, d.diffDescriptions()...)
+	changes.Sort()
+	return changes
+}
+
+// differ tracks which objects of the from catalog correspond to which
+// objects of the to catalog.
+type differ struct {
+	from, to *Catalog
+	opts     DiffOptions
+	tables   map[*Table]*Table
+	columns  map[*Column]*Column
+}
+
+func (d *differ) fromTable(toTab *Table) *Table {
+
+	for fromTab, t := range d.tables {
+		if t == toTab {
+			return fromTab
+		}
+	}
+	return nil
+}
+
+func (d *differ) matchTables() {
+
+	for _, toSch := range d.to.Schemas.List() {
+		fromSch, ok := d.from.Schemas.Get(toSch.Name)
+		if !ok {
+			continue
+		}
+		var dropped, added []*Table
+		for _, toTab := range toSch.Tables.List() {
+			if fromTab, ok := fromSch.Tables.Get(toTab.Name); ok {
+				d.tables[fromTab] = toTab
+			} else {
+				added = append(added, toTab)
+			}
+		}
+		for _, fromTab := range fromSch.Tables.List() {
+			if _, ok := toSch.Tables.Get(fromTab.Name); !ok {
+				dropped = append(dropped, fromTab)
+			}
+		}
+		if d.opts.MatchOIDs {
+			dropped, added = matchOIDs(dropped, added, func(t *Table) OID { return t.OID }, func(from, to *Table) {
+				d.tables[from] = to
+			})
+		}
+		pairs := matchRenames(d.opts.Renames, dropped, added,
+			func(t *Table) string { return t.Name },
+			func(t *Table) Annotations { return t.Annotations },
+			d.sameTable)
+		for from, to := range pairs {
+			d.tables[dropped[from]] = added[to]
+		}
+	}
+}
+
+// sameTable reports whether a and b have the same columns, ignoring their
+// order.
+func (d *differ) sameTable(a, b *Table) bool {
+
+	if len(a.Columns.List()) != len(b.Columns.List()) {
+		return false
+	}
+	for _, ac := range a.Columns.List() {
+		bc, ok := b.Columns.Get(ac.Name)
+		if !ok || !ColumnsEqual(ac, bc) {
+			return false
+		}
+	}
+	return true
+}
+
+func (d *differ) matchColumns(from, to *Table) {
+
+	var dropped, added []*Column
+	for _, toCol := range to.Columns.List() {
+		if fromCol, ok := from.Columns.Get(toCol.Name); ok {
+			d.columns[fromCol] = toCol
+		} else {
+			added = append(added, toCol)
+		}
+	}
+	for _, fromCol := range from.Columns.List() {
+		if _, ok := to.Columns.Get(fromCol.Name); !ok {
+			dropped = append(dropped, fromCol)
+		}
+	}
+	if d.opts.MatchOIDs {
+		dropped, added = matchOIDs(dropped, added, func(c *Column) OID { return c.OID }, func(from, to *Column) {
+			d.columns[from] = to
+		})
+	}
+	pairs := matchRenames(d.opts.Renames, dropped, added,
+		func(c *Column) string { return c.Name },
+		func(c *Column) Annotations { return c.Annotations },
+		func(a, b *Column) bool {
+			return sameColumnDefinition(a, b) &&
+				slices.Equal(constraintTypes(d.from, a), constraintTypes(d.to, b))
+		})
+	for from, to := range pairs {
+		d.columns[dropped[from]] = added[to]
+	}
+}
+
+// constraintTypes returns the types of the constraints on col, sorted.
+func constraintTypes(cat *Catalog, col *Column) []ConstraintType {
+
+	var ret []ConstraintType
+	cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+	for _, con := range cons {
+		if slices.Contains(con.Constrains, col) {
+			ret = append(ret, con.Type)
+		}
+	}
+	slices.Sort(ret)
+	return ret
+}
+
+// matchOIDs calls match for each dropped object with the same OID as an
+// added one, returning the objects left unmatched.
+func matchOIDs[T any](dropped, added []T, oid func(T) OID, match func(from, to T)) ([]T, []T) {
+
+	var unmatched []T
+	for _, from := range dropped {
+		idx := slices.IndexFunc(added, func(to T) bool { return oid(to) == oid(from) })
+		if idx < 0 {
+			unmatched = append(unmatched, from)
+			continue
+		}
+		match(from, added[idx])
+		added = slices.Delete(slices.Clone(added), idx, idx+1)
+	}
+	return unmatched, added
+}
+
+// matchRenames pairs each dropped object with the added object it was most
+// likely renamed to, returning the index of the added object for the index
+// of each dropped object which was renamed.
+func matchRenames[T any](mode RenameDetection, dropped, added []T, name func(T) string, annotations func(T) Annotations, same func(a, b T) bool) map[int]int {
+
+	pairs := make(map[int]int)
+	if mode == RenamesNone || len(dropped) == 0 || len(added) == 0 {
+		return pairs
+	}
+	taken := make(map[int]bool)
+	for ai, a := range added {
+		hint, ok := annotations(a)["renamed_from"]
+		if !ok {
+			continue
+		}
+		di := slices.IndexFunc(dropped, func(d T) bool { return name(d) == hint })
+		if _, paired := pairs[di]; di >= 0 && !paired {
+			pairs[di] = ai
+			taken[ai] = true
+		}
+	}
+	if mode < RenamesConservative {
+		return pairs
+	}
+
+	type candidate struct {
+		dropped, added int
+		score          float64
+	}
+	var candidates []candidate
+	droppedCount, addedCount := make(map[int]int), make(map[int]int)
+	for di, d := range dropped {
+		if _, ok := pairs[di]; ok {
+			continue
+		}
+		for ai, a := range added {
+			if taken[ai] || !same(d, a) {
+				continue
+			}
+			candidates = append(candidates, candidate{di, ai, nameSimilarity(name(d), name(a))})
+			droppedCount[di]++
+			addedCount[ai]++
+		}
+	}
+	if mode == RenamesConservative {
+		for _, c := range candidates {
+			if droppedCount[c.dropped] == 1 && addedCount[c.added] == 1 && c.score >= renameSimilarity {
+				pairs[c.dropped] = c.added
+			}
+		}
+		return pairs
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		return cmp.Compare(b.score, a.score)
+	})
+	for _, c := range candidates {
+		if _, ok := pairs[c.dropped]; ok || taken[c.added] {
+			continue
+		}
+		pairs[c.dropped] = c.added
+		taken[c.added] = true
+	}
+	return pairs
+}
+
+// nameSimilarity scores how alike two names are between 0 and 1, taking the
+// better of their edit distance and how many underscore separated words
+// they share. Either finds "username" and "user_name" similar, but only the
+// latter finds "email" and "email_address" similar.
+func nameSimilarity(a, b string) float64 {
+
+	longest := max(len(a), len(b))
+	if longest == 0 {
+		return 1
+	}
+	edit := 1 - float64(levenshtein(a, b))/float64(longest)
+
+	aWords, bWords := strings.Split(a, "_"), strings.Split(b, "_")
+	common := 0
+	for _, w := range aWords {
+		if slices.Contains(bWords, w) {
+			common++
+		}
+	}
+	words := 2 * float64(common) / float64(len(aWords)+len(bWords))
+	return max(edit, words)
+}
+
+func levenshtein(a, b string) int {
+
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func addTableChanges(cat *Catalog, tab *Table) Changes {
+
+	changes := Changes{{Kind: ChangeKindAdd, Object: ObjectKindTable, Schema: tab.Schema, Table: tab.Name, To: tab}}
+	for _, con := range cat.Depends.TableConstraints(tab) {
+		changes = append(changes, &Change{Kind: ChangeKindAdd, Object: ObjectKindConstraint,
+			Schema: tab.Schema, Table: tab.Name, Name: con.Name, To: con})
+	}
+	for _, idx := range cat.Depends.TableIndexes(tab) {
+		changes = append(changes, &Change{Kind: ChangeKindAdd, Object: ObjectKindIndex,
+			Schema: tab.Schema, Table: tab.Name, Name: idx.Name, To: idx})
+	}
+	if tab.ReplicaIdentity != ReplicaIdentityDefault {
+		changes = append(changes, &Change{Kind: ChangeKindAlter, Object: ObjectKindTable, Schema: tab.Schema, Table: tab.Name, To: tab})
+	}
+	for _, s := range cat.Depends.TableStatistics(tab) {
+		changes = append(changes, &Change{Kind: ChangeKindAdd, Object: ObjectKindStatistics,
+			Schema: tab.Schema, Table: tab.Name, Name: s.Name, To: s})
+	}
+	return changes
+}
+
+func dropTableChanges(cat *Catalog, tab *Table) Changes {
+
+	changes := Changes{{Kind: ChangeKindDrop, Object: ObjectKindTable, Schema: tab.Schema, Table: tab.Name, From: tab}}
+	for _, con := range cat.Depends.TableConstraints(tab) {
+		if con.Type == ConstraintTypeForeignKey {
+			changes = append(changes, &Change{Kind: ChangeKindDrop, Object: ObjectKindConstraint,
+				Schema: tab.Schema, Table: tab.Name, Name: con.Name, From: con})
+		}
+	}
+	return changes
+}
+
+func (d *differ) diffTable(from, to *Table) Changes {
+
+	var changes Changes
+	change := func(kind ChangeKind, object ObjectKind, name string, fromObj, toObj any) {
+		changes = append(changes, &Change{Kind: kind, Object: object,
+			Schema: to.Schema, Table: to.Name, Name: name, From: fromObj, To: toObj})
+	}
+	renamed := make(map[*Column]bool)
+	for _, fromCol := range from.Columns.List() {
+		toCol, ok := d.columns[fromCol]
+		if !ok {
+			change(ChangeKindDrop, ObjectKindColumn, fromCol.Name, fromCol, nil)
+			continue
+		}
+		renamed[toCol] = true
+		if fromCol.Name != toCol.Name {
+			change(ChangeKindRename, ObjectKindColumn, toCol.Name, fromCol, toCol)
+		}
+		if !sameColumnDefinition(fromCol, toCol) {
+			change(ChangeKindAlter, ObjectKindColumn, toCol.Name, fromCol, toCol)
+		}
+	}
+	for _, toCol := range to.Columns.List() {
+		if !renamed[toCol] {
+			change(ChangeKindAdd, ObjectKindColumn, toCol.Name, nil, toCol)
+		}
+	}
+
+	diffObjects(d.opts.Renames != RenamesNone,
+		d.from.Depends.TableConstraints(from), d.to.Depends.TableConstraints(to),
+		func(con *Constraint) string { return con.Name }, d.constraintKey,
+		func(kind ChangeKind, name string, fromObj, toObj any) {
+			change(kind, ObjectKindConstraint, name, fromObj, toObj)
+		})
+	// Whether a constraint is validated can be altered, so isn't part of its
+	// key
+	for _, toCon := range d.to.Depends.TableConstraints(to) {
+		fromCon, _ := d.from.Depends.Constraint(from, toCon.Name)
+		for _, c := range changes {
+			if c.To == toCon {
+				fromCon, _ = c.From.(*Constraint)
+			}
+		}
+		if fromCon != nil && fromCon.NotValid != toCon.NotValid {
+			change(ChangeKindAlter, ObjectKindConstraint, toCon.Name, fromCon, toCon)
+		}
+	}
+	diffObjects(d.opts.Renames != RenamesNone,
+		d.from.Depends.TableIndexes(from), d.to.Depends.TableIndexes(to),
+		func(idx *Index) string { return idx.Name }, d.indexKey,
+		func(kind ChangeKind, name string, fromObj, toObj any) {
+			change(kind, ObjectKindIndex, name, fromObj, toObj)
+		})
+	diffObjects(d.opts.Renames != RenamesNone,
+		d.from.Depends.TableStatistics(from), d.to.Depends.TableStatistics(to),
+		func(s *Statistics) string { return s.Name }, d.statisticsKey,
+		func(kind ChangeKind, name string, fromObj, toObj any) {
+			change(kind, ObjectKindStatistics, name, fromObj, toObj)
+		})
+	// Storage parameters can be altered too, so aren't part of an index's
+	// key
+	for _, toIdx := range d.to.Depends.TableIndexes(to) {
+		fromIdx := d.from.Depends.IndexesByName[from.Schema+"."+toIdx.Name]
+		if fromIdx != nil && fromIdx.Table != from {
+			fromIdx = nil
+		}
+		for _, c := range changes {
+			if c.To == toIdx {
+				fromIdx, _ = c.From.(*Index)
+			}
+		}
+		if fromIdx != nil && !slices.Equal(fromIdx.Options, toIdx.Options) {
+			change(ChangeKindAlter, ObjectKindIndex, toIdx.Name, fromIdx, toIdx)
+		}
+	}
+	if from.ReplicaIdentity != to.ReplicaIdentity || (to.ReplicaIdentity == ReplicaIdentityIndex && !sameReplicaIndex(changes, from, to)) ||
+		from.Unlogged != to.Unlogged || !slices.Equal(from.Options, to.Options) {
+		change(ChangeKindAlter, ObjectKindTable, "", from, to)
+	}
+	return changes
+}
+
+// sameReplicaIndex reports whether to's replica identity index is from's,
+// given the changes to the table's indexes. An index which is added, even
+// in place of one with the same name, needs its replica identity set again.
+func sameReplicaIndex(changes Changes, from, to *Table) bool {
+
+	for _, c := range changes {
+		if c.Object == ObjectKindIndex && c.Kind != ChangeKindAlter && c.To == to.ReplicaIndex {
+			return c.Kind == ChangeKindRename && c.From == from.ReplicaIndex
+		}
+	}
+	return from.ReplicaIndex.Name == to.ReplicaIndex.Name
+}
+
+func (d *differ) diffEventTriggers() Changes {
+
+	var changes Changes
+	diffObjects(d.opts.Renames != RenamesNone, d.from.EventTriggers.List(), d.to.EventTriggers.List(),
+		func(trig *EventTrigger) string { return trig.Name }, eventTriggerKey,
+		func(kind ChangeKind, name string, fromObj, toObj any) {
+			changes = append(changes, &Change{Kind: kind, Object: ObjectKindEventTrigger, Name: name, From: fromObj, To: toObj})
+		})
+	// Whether a trigger fires can be altered, so isn't part of its key
+	for _, toTrig := range d.to.EventTriggers.List() {
+		fromTrig, _ := d.from.EventTriggers.Get(toTrig.Name)
+		for _, c := range changes {
+			if c.To == toTrig {
+				fromTrig, _ = c.From.(*EventTrigger)
+			}
+		}
+		if fromTrig != nil && fromTrig.Firing != toTrig.Firing {
+			changes = append(changes, &Change{Kind: ChangeKindAlter, Object: ObjectKindEventTrigger, Name: toTrig.Name, From: fromTrig, To: toTrig})
+		}
+	}
+	return changes
+}
+
+// diffEnums compares the enums of schemas in both catalogs, and adds those
+// of new schemas. Those of dropped schemas are dropped with them.
+func (d *differ) diffEnums() Changes {
+
+	var changes Changes
+	for _, toSch := range d.to.Schemas.List() {
+		fromSch, _ := d.from.Schemas.Get(toSch.Name)
+		for _, toEnum := range toSch.Enums.List() {
+			var fromEnum *Enum
+			if fromSch != nil {
+				fromEnum, _ = fromSch.Enums.Get(toEnum.Name)
+			}
+			switch {
+			case fromEnum == nil:
+				changes = append(changes, &Change{Kind: ChangeKindAdd, Object: ObjectKindEnum, Schema: toEnum.Schema, Name: toEnum.Name, To: toEnum})
+			case !slices.Equal(fromEnum.Labels, toEnum.Labels):
+				{
+					c := &Change{Kind: ChangeKindAlter, Object: ObjectKindEnum, Schema: toEnum.Schema, Name: toEnum.Name, From: fromEnum, To: toEnum}
+					// Dropped columns are gone by the time the enum is
+					// altered
+					for _, col := range EnumColumns(d.from, fromEnum) {
+						if _, ok := d.columns[col]; ok {
+							c.enumColumns = append(c.enumColumns, col)
+						}
+					}
+					changes = append(changes, c)
+				}
+			}
+		}
+		if fromSch == nil {
+			continue
+		}
+		for _, fromEnum := range fromSch.Enums.List() {
+			if _, ok := toSch.Enums.Get(fromEnum.Name); !ok {
+				changes = append(changes, &Change{Kind: ChangeKindDrop, Object: ObjectKindEnum, Schema: fromEnum.Schema, Name: fromEnum.Name, From: fromEnum})
+			}
+		}
+	}
+	return changes
+}
+
+// addedLabels returns the labels added to from's, or false if from's
+// labels aren't all in to in the same order, so that the enum has to be
+// created again.
+func addedLabels(from, to *Enum) ([]string, bool) {
+
+	var added []string
+	next := 0
+	for _, l := range to.Labels {
+		if next < len(from.Labels) && from.Labels[next] == l {
+			next++
+			continue
+		}
+		if slices.Contains(from.Labels, l) {
+			return nil, false
+		}
+		added = append(added, l)
+	}
+	return added, next == len(from.Labels)
+}
+
+// removedLabels returns the labels of from which to doesn't have.
+func removedLabels(from, to *Enum) []string {
+
+	var removed []string
+	for _, l := range from.Labels {
+		if !slices.Contains(to.Labels, l) {
+			removed = append(removed, l)
+		}
+	}
+	return removed
+}
+
+// enumSQL returns the statements of a change to an enum. Labels can only
+// be added, so an enum losing or reordering labels is renamed out of the
+// way and created again, and its columns cast to the new enum through
+// text, failing if any holds a label which was removed.
+func (c *Change) enumSQL() []string {
+
+	switch c.Kind {
+	case ChangeKindAdd:
+		return []string{EnumDefinition(c.To.(*Enum)) + ";"}
+	case ChangeKindDrop:
+		return []string{fmt.Sprintf("DROP TYPE %s;", EnumIdent(c.From.(*Enum)))}
+	}
+	from, to := c.From.(*Enum), c.To.(*Enum)
+	var stmts []string
+	if added, ok := addedLabels(from, to); ok {
+		for _, l := range added {
+			idx := slices.Index(to.Labels, l)
+			if idx == 0 {
+				stmts = append(stmts, fmt.Sprintf("ALTER TYPE %s ADD VALUE %s BEFORE %s;", EnumIdent(to), QuoteLiteral(l), QuoteLiteral(to.Labels[1])))
+			} else {
+				stmts = append(stmts, fmt.Sprintf("ALTER TYPE %s ADD VALUE %s AFTER %s;", EnumIdent(to), QuoteLiteral(l), QuoteLiteral(to.Labels[idx-1])))
+			}
+		}
+		return stmts
+	}
+	old := &Enum{Schema: from.Schema, Name: from.Name + "_old"}
+	stmts = append(stmts, fmt.Sprintf("ALTER TYPE %s RENAME TO %s;", EnumIdent(from), QuoteIdent(old.Name)))
+	stmts = append(stmts, EnumDefinition(to)+";")
+	for _, col := range c.enumColumns {
+		typ := EnumIdent(to) + strings.Repeat("[]", col.ArrayDims)
+		alter := "ALTER TABLE " + TableIdent(col.Table) + " ALTER COLUMN " + QuoteIdent(col.Name)
+		if col.Attrs.Default != "" {
+			stmts = append(stmts, alter+" DROP DEFAULT;")
+		}
+		stmts = append(stmts, fmt.Sprintf("%s TYPE %s USING %s::text%s::%s;", alter, typ, QuoteIdent(col.Name), strings.Repeat("[]", col.ArrayDims), typ))
+		if col.Attrs.Default != "" {
+			stmts = append(stmts, fmt.Sprintf("%s SET DEFAULT %s;", alter, col.Attrs.Default))
+		}
+	}
+	return append(stmts, fmt.Sprintf("DROP TYPE %s;", EnumIdent(old)))
+}
+
+// diffSequences compares the sequences of schemas in both catalogs, and
+// adds those of new schemas. A sequence is added without the column owning
+// it, which may not exist yet, and then altered to be owned by it. The
+// implicit sequences of serial columns aren't recorded, so one created
+// explicitly, as pg_dump writes them, is the same as a serial column's.
+func (d *differ) diffSequences() Changes {
+
+	serials := func(cat *Catalog) map[string]bool {
+		m := make(map[string]bool)
+		for _, col := range FindColumns(cat, func(col *Column) bool { return isSerial(col.Type) }) {
+			m[col.Sequence()] = true
+		}
+		return m
+	}
+	fromSerials, toSerials := serials(d.from), serials(d.to)
+	var changes Changes
+	for _, toSch := range d.to.Schemas.List() {
+		fromSch, _ := d.from.Schemas.Get(toSch.Name)
+		for _, toSeq := range toSch.Sequences.List() {
+			var fromSeq *Sequence
+			if fromSch != nil {
+				fromSeq, _ = fromSch.Sequences.Get(toSeq.Name)
+			}
+			if fromSeq == nil && fromSerials[toSeq.Schema+"."+toSeq.Name] {
+				continue
+			}
+			if fromSeq == nil {
+				changes = append(changes, &Change{Kind: ChangeKindAdd, Object: ObjectKindSequence, Schema: toSeq.Schema, Name: toSeq.Name, To: toSeq})
+				if toSeq.OwnedBy == nil {
+					continue
+				}
+				fromSeq = &Sequence{Schema: toSeq.Schema, Name: toSeq.Name, Unlogged: toSeq.Unlogged}
+			}
+			if fromSeq.Unlogged != toSeq.Unlogged || !d.sameOwner(fromSeq, toSeq) {
+				changes = append(changes, &Change{Kind: ChangeKindAlter, Object: ObjectKindSequence, Schema: toSeq.Schema, Name: toSeq.Name, From: fromSeq, To: toSeq})
+			}
+		}
+		if fromSch == nil {
+			continue
+		}
+		for _, fromSeq := range fromSch.Sequences.List() {
+			if _, ok := toSch.Sequences.Get(fromSeq.Name); !ok && !toSerials[fromSeq.Schema+"."+fromSeq.Name] {
+				changes = append(changes, &Change{Kind: ChangeKindDrop, Object: ObjectKindSequence, Schema: fromSeq.Schema, Name: fromSeq.Name, From: fromSeq})
+			}
+		}
+	}
+	return changes
+}
+
+// sameOwner reports whether from and to are owned by the same column,
+// renamed or not.
+func (d *differ) sameOwner(from, to *Sequence) bool {
+
+	if from.OwnedBy == nil || to.OwnedBy == nil {
+		return from.OwnedBy == to.OwnedBy
+	}
+	return d.columns[from.OwnedBy] == to.OwnedBy
+}
+
+// diffViews compares the views of both catalogs by name and definition. A
+// view can't be altered but by replacing it with one with more columns, so
+// a changed view is dropped and created again. Views of extensions come
+// with them, and those of dropped schemas are dropped with them.
+func (d *differ) diffViews() Changes {
+
+	views := func(cat *Catalog) *collections.OrderedMap[string, *RawStatement] {
+		m := collections.NewOrderedMap[string, *RawStatement]()
+		for _, raw := range cat.Raw {
+			if raw.Kind == "CREATE VIEW" && raw.Extension == "" {
+				m.Add(raw.Name, raw)
+			}
+		}
+		return m
+	}
+	fromViews, toViews := views(d.from), views(d.to)
+	var changes Changes
+	change := func(kind ChangeKind, from, to *RawStatement) {
+		raw := from
+		if to != nil {
+			raw = to
+		}
+		schema, name, _ := strings.Cut(raw.Name, ".")
+		changes = append(changes, &Change{Kind: kind, Object: ObjectKindView, Schema: schema, Name: name, From: from, To: to})
+	}
+	// Views may depend on each other, so they're dropped in the reverse of
+	// the order they were created in
+	fromList := fromViews.List()
+	for i := len(fromList) - 1; i >= 0; i-- {
+		fromView := fromList[i]
+		schema, _, _ := strings.Cut(fromView.Name, ".")
+		if _, ok := d.to.Schemas.Get(schema); !ok {
+			continue
+		}
+		if toView, ok := toViews.Get(fromView.Name); !ok || d.renamedViewKey(fromView) != viewKey(toView) {
+			change(ChangeKindDrop, fromView, nil)
+		}
+	}
+	for _, toView := range toViews.List() {
+		if fromView, ok := fromViews.Get(toView.Name); !ok || d.renamedViewKey(fromView) != viewKey(toView) {
+			change(ChangeKindAdd, nil, toView)
+		}
+	}
+	return changes
+}
+
+// viewKey describes a view's definition, whatever its formatting and
+// whether it was created by CREATE OR REPLACE.
+func viewKey(view *RawStatement) string {
+
+	parsed, err := pg_query.Parse(view.SQL)
+	if err != nil || len(parsed.Stmts) != 1 || parsed.Stmts[0].Stmt.GetViewStmt() == nil {
+		return view.SQL
+	}
+	parsed.Stmts[0].Stmt.GetViewStmt().Replace = false
+	sql, err := pg_query.Deparse(parsed)
+	if err != nil {
+		return view.SQL
+	}
+	return sql
+}
+
+// renamedViewKey returns the viewKey of a view of the from catalog as it
+// is after the tables and columns it uses are renamed, since renames
+// rewrite views rather than dropping them.
+func (d *differ) renamedViewKey(view *RawStatement) string {
+
+	tables := make(map[*Table]string)
+	for fromTab, toTab := range d.tables {
+		if fromTab.Name != toTab.Name {
+			tables[fromTab] = toTab.Name
+		}
+	}
+	columns := make(map[*Column]string)
+	for fromCol, toCol := range d.columns {
+		if fromCol.Name != toCol.Name {
+			columns[fromCol] = toCol.Name
+		}
+	}
+	if len(tables) == 0 && len(columns) == 0 {
+		return viewKey(view)
+	}
+	c := NewCompiler()
+	c.Catalog = d.from
+	renamed := *view
+	err := c.rewriteView(&renamed, func(stmt *pg_query.ViewStmt) {
+		c.renameInQuery(stmt.Query, tables, columns)
+	})
+	if err != nil {
+		return viewKey(view)
+	}
+	return viewKey(&renamed)
+}
+
+func eventTriggerKey(trig *EventTrigger, _ bool) string {
+
+	return fmt.Sprintf("%s (%s) %s", trig.Event, strings.Join(trig.Tags, ","), trig.Function)
+}
+
+// diffObjects compares the constraints, indexes or statistics of a table,
+// or the event triggers of a catalog. These can't
+// be altered, so a changed object is left unmatched to be dropped and added
+// again. They also aren't renamed along with their tables or columns, so
+// if renames are enabled any with the same definition as a dropped one is
+// a rename of it. key describes an object's definition without its name.
+func diffObjects[T comparable](renames bool, fromObjs, toObjs []T, name func(T) string, key func(obj T, from bool) string,
+	change func(kind ChangeKind, name string, fromObj, toObj any)) {
+
+	matched := make(map[T]bool)
+	for _, toObj := range toObjs {
+		idx := slices.IndexFunc(fromObjs, func(o T) bool { return name(o) == name(toObj) })
+		if idx >= 0 && key(fromObjs[idx], true) == key(toObj, false) {
+			matched[fromObjs[idx]], matched[toObj] = true, true
+		}
+	}
+	if renames {
+		for _, toObj := range toObjs {
+			if matched[toObj] {
+				continue
+			}
+			for _, fromObj := range fromObjs {
+				if !matched[fromObj] && key(fromObj, true) == key(toObj, false) {
+					matched[fromObj], matched[toObj] = true, true
+					change(ChangeKindRename, name(toObj), fromObj, toObj)
+					break
+				}
+			}
+		}
+	}
+	for _, toObj := range toObjs {
+		if !matched[toObj] {
+			change(ChangeKindAdd, name(toObj), nil, toObj)
+		}
+	}
+	for _, fromObj := range fromObjs {
+		if !matched[fromObj] {
+			change(ChangeKindDrop, name(fromObj), fromObj, nil)
+		}
+	}
+}
+
+// constraintKey describes what con constrains, without its name. The
+// columns and tables of constraints in the from catalog are described by
+// the names they have in the to catalog.
+func (d *differ) constraintKey(con *Constraint, from bool) string {
+
+	colNames := func(cols Columns) string {
+		names := make([]string, 0, len(cols))
+		for _, col := range cols {
+			if to, ok := d.columns[col]; ok && from {
+				col = to
+			}
+			names = append(names, col.Name)
+		}
+		return strings.Join(names, ",")
+	}
+	key := fmt.Sprintf("%d(%s)", con.Type, colNames(con.Constrains))
+	if len(con.Refers) > 0 {
+		ref := con.Refers[0].Table
+		if to, ok := d.tables[ref]; ok && from {
+			ref = to
+		}
+		key += fmt.Sprintf(" %s.%s(%s)", ref.Schema, ref.Name, colNames(con.Refers))
+		key += fmt.Sprintf(" %d %d %d(%s)", con.Match, con.OnUpdate, con.OnDelete, colNames(con.SetColumns))
+	}
+	if con.Type == ConstraintTypeCheck {
+		key += fmt.Sprintf(" CHECK (%s) %t", con.Expr, con.NoInherit)
+	}
+	return key
+}
+
+// indexKey describes what idx indexes, without its name, in the same way
+// as constraintKey.
+func (d *differ) indexKey(idx *Index, from bool) string {
+
+	colName := func(col *Column) string {
+		if to, ok := d.columns[col]; ok && from {
+			col = to
+		}
+		return col.Name
+	}
+	elems := make([]string, 0, len(idx.Elems))
+	for _, elem := range idx.Elems {
+		if elem.Column == nil {
+			elems = append(elems, "("+elem.Expr+")"+indexElemOptions(elem))
+		} else {
+			elems = append(elems, colName(elem.Column)+indexElemOptions(elem))
+		}
+	}
+	include := make([]string, 0, len(idx.Include))
+	for _, col := range idx.Include {
+		include = append(include, colName(col))
+	}
+	return fmt.Sprintf("%t %s (%s) INCLUDE (%s) %s", idx.Unique, idx.Method,
+		strings.Join(elems, ","), strings.Join(include, ","), idx.Predicate)
+}
+
+// statisticsKey describes the statistics s collects, without its name, in
+// the same way as constraintKey.
+func (d *differ) statisticsKey(s *Statistics, from bool) string {
+
+	elems := make([]string, 0, len(s.Elems))
+	for _, elem := range s.Elems {
+		col := elem.Column
+		if col == nil {
+			elems = append(elems, "("+elem.Expr+")")
+			continue
+		}
+		if to, ok := d.columns[col]; ok && from {
+			col = to
+		}
+		elems = append(elems, col.Name)
+	}
+	return fmt.Sprintf("%s (%s) %s", s.Schema, strings.Join(s.Kinds, ","), strings.Join(elems, ","))
+}
+
+// ColumnsEqual reports whether a and b have the same name and definition,
+// ignoring the tables they belong to and any constraints on them.
+func ColumnsEqual(a, b *Column) bool {
+
+	return a.Name == b.Name && sameColumnDefinition(a, b)
+}
+
+func sameColumnDefinition(a, b *Column) bool {
+
+	aDef, bDef := definitionsOf(a, b)
+	return aDef == bDef
+}
+
+// columnDefinition is a column's type, nullability, default and identity
+// as Postgres has them, so that a serial column is the same as the integer
+// column with a nextval default it's created as, and a nextval default is
+// the same however the sequence is named.
+type columnDefinition struct {
+	Type     string
+	NotNull  bool
+	Default  string
+	Identity Identity
+}
+
+// definitionsOf returns the definitions of a and b to compare them. Serial
+// columns are only expanded when the other column isn't serial, since the
+// name of their sequence depends on the table's, which may be renamed.
+func definitionsOf(a, b *Column) (columnDefinition, columnDefinition) {
+
+	expand := isSerial(a.Type) != isSerial(b.Type)
+	return definitionOf(a, expand), definitionOf(b, expand)
+}
+
+func definitionOf(col *Column, expandSerial bool) columnDefinition {
+
+	def := columnDefinition{Type: col.FormatType(), NotNull: col.Attrs.NotNull, Default: col.Attrs.Default, Identity: col.Attrs.Identity}
+	if underlying, ok := serialTypes[col.Type]; ok && expandSerial {
+		def.Type = underlying.Format(col.TypeMods) + strings.Repeat("[]", col.ArrayDims)
+		def.NotNull = true
+		def.Default = nextvalDefault(col.Sequence())
+	} else if col.Attrs.Default != "" && col.Attrs.Sequence != "" {
+		def.Default = nextvalDefault(col.Attrs.Sequence)
+	}
+	return def
+}
+
+func runDiff(args []string) error {
+
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	from := fs.String("from", "", "migrations describing the current schema")
+	to := fs.String("to", "", "migrations describing the desired schema")
+	renames := fs.String("renames", RenamesConservative.String(),
+		"how eagerly to detect renames, one of: "+strings.Join(renameDetectionNames, ", "))
+	out := fs.String("out", "", "file to write the migration to, defaults to stdout")
+	failOn := fs.String("fail-on", "", "exit with an error if any change is at least this unsafe, one of: "+
+		strings.Join(safetyNames[1:], ", "))
+	matchOIDs := fs.Bool("match-oids", false, "match tables and columns created by the same statement, for when -to is -from with more migrations")
+	filter := filterFlags(fs)
+	phase := fs.String("phase", "", "split the migration into phases for zero downtime, writing all of them or only one of: "+
+		strings.Join(phaseNames, ", "))
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *from == "" || *to == "" {
+		return fmt.Errorf("-from and -to are required")
+	}
+	opts := DiffOptions{MatchOIDs: *matchOIDs, Filter: filter}
+	opts.Renames, err = ParseRenameDetection(*renames)
+	if err != nil {
+		return err
+	}
+	threshold := Safety(len(safetyNames))
+	if *failOn != "" {
+		threshold, err = ParseSafety(*failOn)
+		if err != nil {
+			return err
+		}
+	}
+
+	onlyPhase := Phase(-1)
+	if *phase != "" && *phase != "all" {
+		onlyPhase, err = ParsePhase(*phase)
+		if err != nil {
+			return err
+		}
+	}
+
+	fromC, err := compile([]string{*from})
+	if err != nil {
+		return err
+	}
+	toC, err := compile([]string{*to})
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	var failed []string
+	changes := Diff(fromC.Catalog, toC.Catalog, opts)
+	for _, c := range changes {
+		safety, reason := c.Classify()
+		desc := c.String()
+		if safety != SafetySafe {
+			desc += fmt.Sprintf(" (%s: %s)", safety, reason)
+		}
+		if safety >= threshold {
+			failed = append(failed, desc)
+		}
+		if *phase != "" {
+			continue
+		}
+		fmt.Fprintf(bw, "-- %s\n", desc)
+		for _, stmt := range c.SQL() {
+			fmt.Fprintln(bw, stmt)
+		}
+	}
+	if *phase != "" {
+		stmts := changes.Phased()
+		if onlyPhase >= 0 {
+			stmts = slices.DeleteFunc(stmts, func(stmt *PhasedStatement) bool { return stmt.Phase != onlyPhase })
+		}
+		err = WritePhased(bw, stmts)
+		if err != nil {
+			return err
+		}
+	}
+	err = bw.Flush()
+	if err != nil {
+		return err
+	}
+	for _, desc := range failed {
+		fmt.Fprintln(os.Stderr, desc)
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d changes are %s or worse", len(failed), *failOn)
+	}
+	return nil
+}
@@ -0,0 +1,535 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := assertParse(t, `
+	CREATE SCHEMA old;
+	CREATE TABLE users (
+		id serial PRIMARY KEY,
+		name varchar(50),
+		legacy text
+	);
+	CREATE TABLE audit (id int);
+	`)
+	to := assertParse(t, `
+	CREATE SCHEMA app;
+	CREATE TABLE users (
+		id bigserial PRIMARY KEY,
+		name varchar(100) NOT NULL DEFAULT 'anon',
+		email text UNIQUE
+	);
+	CREATE TABLE app.posts (
+		id int PRIMARY KEY,
+		user_id int REFERENCES users (id)
+	);
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{})
+	var descs []string
+	for _, c := range changes {
+		descs = append(descs, c.String())
+	}
+	assert.Equal(t, []string{
+		"drop column public.users.legacy",
+		"drop table public.audit",
+		"drop schema old",
+		"add schema app",
+		"add table app.posts",
+		"alter column public.users.id",
+		"alter column public.users.name",
+		"add column public.users.email",
+		"add constraint public.users.users_email_key",
+		"add constraint app.posts.posts_pkey",
+		"add constraint app.posts.posts_user_id_fkey",
+	}, descs)
+	assert.Equal(t, []string{
+		"ALTER TABLE users DROP COLUMN legacy;",
+		"DROP TABLE audit;",
+		"DROP SCHEMA old CASCADE;",
+		"CREATE SCHEMA app;",
+		"CREATE TABLE app.posts (\n    id integer,\n    user_id integer\n);",
+		"ALTER TABLE users ALTER COLUMN id TYPE bigint;",
+		"ALTER TABLE users ALTER COLUMN name TYPE character varying(100), ALTER COLUMN name SET NOT NULL, ALTER COLUMN name SET DEFAULT 'anon';",
+		"ALTER TABLE users ADD COLUMN email text;",
+		"ALTER TABLE users ADD CONSTRAINT users_email_key UNIQUE (email);",
+		"ALTER TABLE app.posts ADD CONSTRAINT posts_pkey PRIMARY KEY (id);",
+		"ALTER TABLE app.posts ADD CONSTRAINT posts_user_id_fkey FOREIGN KEY (user_id) REFERENCES users (id);",
+	}, changes.SQL())
+}
+
+func TestDiff_Identical(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (id serial PRIMARY KEY, name text NOT NULL);
+	CREATE TABLE posts (id int, user_id int REFERENCES users (id));
+	`
+	assert.Empty(t, Diff(assertParse(t, sql).Catalog, assertParse(t, sql).Catalog, DiffOptions{}))
+}
+
+func TestDiff_Apply(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (id serial PRIMARY KEY, name text, nickname text);
+	CREATE TABLE posts (id int, user_id int REFERENCES users (id));
+	`)
+	to := assertParse(t, `
+	CREATE TABLE users (id serial, name text NOT NULL, email text);
+	CREATE TABLE posts (id int, user_id int);
+	`)
+	c := assertParse(t, `
+	CREATE TABLE users (id serial PRIMARY KEY, name text, nickname text);
+	CREATE TABLE posts (id int, user_id int REFERENCES users (id));
+	`)
+	for _, stmt := range Diff(from.Catalog, to.Catalog, DiffOptions{}).SQL() {
+		require.Nil(t, c.Compile(stmt), stmt)
+	}
+	assert.Empty(t, Diff(c.Catalog, to.Catalog, DiffOptions{}))
+}
+
+func diffDescriptions(changes Changes) []string {
+	var descs []string
+	for _, c := range changes {
+		descs = append(descs, c.String())
+	}
+	return descs
+}
+
+func TestDiff_Renames(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (id serial PRIMARY KEY, email text UNIQUE);
+	CREATE TABLE posts (id int, author_id int REFERENCES users (id));
+	`)
+	to := assertParse(t, `
+	CREATE TABLE accounts (id serial PRIMARY KEY, email text UNIQUE);
+	CREATE TABLE posts (id int, author_id int REFERENCES accounts (id));
+	`)
+
+	// Without renames the foreign key has to be recreated to refer to the
+	// new table
+	none := []string{
+		"drop constraint public.posts.posts_author_id_fkey",
+		"drop table public.users",
+		"add table public.accounts",
+		"add constraint public.accounts.accounts_email_key",
+		"add constraint public.accounts.accounts_pkey",
+		"add constraint public.posts.posts_author_id_fkey",
+	}
+	assert.Equal(t, none, diffDescriptions(Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesNone})))
+	// The names aren't similar enough to rename conservatively
+	assert.Equal(t, none, diffDescriptions(Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})))
+
+	aggressive := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesAggressive})
+	assert.Equal(t, []string{
+		"rename table public.users to accounts",
+		"rename constraint public.accounts.users_email_key to accounts_email_key",
+		"rename constraint public.accounts.users_pkey to accounts_pkey",
+	}, diffDescriptions(aggressive))
+}
+
+func TestDiff_RenameColumns(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (
+		id serial PRIMARY KEY,
+		email text UNIQUE,
+		name text NOT NULL,
+		nickname text,
+		bio text
+	);
+	CREATE TABLE posts (id int, author_id int REFERENCES users (id));
+	`)
+	to := NewCompiler()
+	require.Nil(t, to.Compile(`
+	CREATE TABLE users (
+		user_id serial PRIMARY KEY,
+		email_address text UNIQUE,
+		full_name text NOT NULL,
+		-- @renamed_from nickname
+		handle varchar(20),
+		about text
+	);
+	CREATE TABLE posts (id int, author_id int REFERENCES users (user_id));
+	`))
+
+	assert.Equal(t, []string{
+		"drop constraint public.posts.posts_author_id_fkey",
+		"drop constraint public.users.users_email_key",
+		"drop constraint public.users.users_pkey",
+		"drop column public.users.id",
+		"drop column public.users.email",
+		"drop column public.users.name",
+		"drop column public.users.bio",
+		"rename column public.users.nickname to handle",
+		"alter column public.users.handle",
+		"add column public.users.user_id",
+		"add column public.users.email_address",
+		"add column public.users.full_name",
+		"add column public.users.about",
+		"add constraint public.users.users_email_address_key",
+		"add constraint public.users.users_pkey",
+		"add constraint public.posts.posts_author_id_fkey",
+	}, diffDescriptions(Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesHinted})))
+
+	conservative := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})
+	assert.Equal(t, []string{
+		"drop column public.users.bio",
+		"rename column public.users.id to user_id",
+		"rename column public.users.email to email_address",
+		"rename column public.users.name to full_name",
+		"rename column public.users.nickname to handle",
+		"rename constraint public.users.users_email_key to users_email_address_key",
+		"alter column public.users.handle",
+		"add column public.users.about",
+	}, diffDescriptions(conservative))
+
+	aggressive := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesAggressive})
+	assert.Contains(t, diffDescriptions(aggressive), "rename column public.users.bio to about")
+
+	// Applying the changes should reach the same schema
+	for _, stmt := range aggressive.SQL() {
+		require.Nil(t, from.Compile(stmt), stmt)
+	}
+	assert.Empty(t, Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesAggressive}))
+}
+
+func TestDiff_RenameTable(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE user_account (id serial PRIMARY KEY, name text);
+	CREATE TABLE posts (id int, author_id int REFERENCES user_account (id));
+	`)
+	to := assertParse(t, `
+	CREATE TABLE user_accounts (id serial PRIMARY KEY, name text);
+	CREATE TABLE posts (id int, author_id int REFERENCES user_accounts (id));
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})
+	assert.Equal(t, []string{
+		"ALTER TABLE user_account RENAME TO user_accounts;",
+		"ALTER TABLE user_accounts RENAME CONSTRAINT user_account_pkey TO user_accounts_pkey;",
+	}, changes.SQL())
+}
+
+func TestNameSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, nameSimilarity("id", "id"))
+	assert.Greater(t, nameSimilarity("username", "user_name"), renameSimilarity)
+	assert.Greater(t, nameSimilarity("email", "email_address"), renameSimilarity)
+	assert.Less(t, nameSimilarity("bio", "about"), renameSimilarity)
+}
+
+func TestDiff_Indexes(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (id int, email text, org int);
+	CREATE INDEX by_email ON users (lower(email));
+	CREATE INDEX by_org ON users (org);
+	CREATE INDEX old_name ON users (id);
+	CREATE INDEX covering ON users (id) INCLUDE (email);
+	`)
+	to := assertParse(t, `
+	CREATE TABLE users (id int, email text, org int);
+	CREATE INDEX by_email ON users (upper(email));
+	CREATE UNIQUE INDEX by_org ON users (org);
+	CREATE INDEX new_name ON users (id);
+	CREATE INDEX by_id_org ON users (id, org) WHERE org > 0;
+	CREATE INDEX covering ON users (id) INCLUDE (email, org);
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})
+	assert.Equal(t, []string{
+		"DROP INDEX by_email;",
+		"DROP INDEX by_org;",
+		"DROP INDEX covering;",
+		"ALTER INDEX old_name RENAME TO new_name;",
+		"CREATE INDEX by_email ON users ((upper(email)));",
+		"CREATE INDEX by_id_org ON users (id, org) WHERE org > 0;",
+		"CREATE UNIQUE INDEX by_org ON users (org);",
+		"CREATE INDEX covering ON users (id) INCLUDE (email, org);",
+	}, changes.SQL())
+	safety, _ := changes[6].Classify()
+	assert.Equal(t, SafetyIncompatible, safety)
+	safety, _ = changes[0].Classify()
+	assert.Equal(t, SafetySafe, safety)
+}
+
+func TestDiff_IndexSortOrder(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE events (id int, at timestamp, name text);
+	CREATE INDEX by_at ON events (at DESC);
+	CREATE INDEX by_name ON events (name);
+	`)
+	to := assertParse(t, `
+	CREATE TABLE events (id int, at timestamp, name text);
+	CREATE INDEX by_at ON events (at DESC NULLS FIRST);
+	CREATE INDEX by_name ON events (name text_pattern_ops);
+	`)
+	assert.Equal(t, []string{
+		"DROP INDEX by_name;",
+		"CREATE INDEX by_name ON events (name text_pattern_ops);",
+	}, Diff(from.Catalog, to.Catalog, DiffOptions{}).SQL())
+}
+
+func TestDiff_ReplicaIdentity(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, seq int NOT NULL);
+	CREATE UNIQUE INDEX events_id ON events (id);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;
+	CREATE TABLE logs (id int);
+	`)
+	to := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, seq int NOT NULL);
+	CREATE UNIQUE INDEX events_id ON events (id, seq);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;
+	CREATE TABLE logs (id int);
+	ALTER TABLE logs REPLICA IDENTITY NOTHING;
+	CREATE TABLE audit (id int);
+	ALTER TABLE audit REPLICA IDENTITY FULL;
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{})
+	assert.Equal(t, []string{
+		"DROP INDEX events_id;",
+		"CREATE TABLE audit (\n    id integer\n);",
+		"CREATE UNIQUE INDEX events_id ON events (id, seq);",
+		"ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;",
+		"ALTER TABLE logs REPLICA IDENTITY NOTHING;",
+		"ALTER TABLE audit REPLICA IDENTITY FULL;",
+	}, changes.SQL())
+	safety, reason := changes[4].Classify()
+	assert.Equal(t, SafetyIncompatible, safety)
+	assert.Contains(t, reason, "updates and deletes")
+	safety, _ = changes[5].Classify()
+	assert.Equal(t, SafetySafe, safety)
+
+	renamed := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, seq int NOT NULL);
+	CREATE UNIQUE INDEX events_key ON events (id);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_key;
+	CREATE TABLE logs (id int);
+	`)
+	assert.Equal(t, []string{"ALTER INDEX events_id RENAME TO events_key;"},
+		Diff(from.Catalog, renamed.Catalog, DiffOptions{Renames: RenamesConservative}).SQL())
+}
+
+func TestDiff_StorageOptions(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, kind text) WITH (fillfactor = 90, autovacuum_enabled = off);
+	CREATE UNIQUE INDEX events_id ON events (id) WITH (fillfactor = 90);
+	CREATE INDEX events_kind ON events (kind);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;
+	`)
+	to := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, kind text) WITH (fillfactor = 70);
+	CREATE UNIQUE INDEX events_id ON events (id);
+	CREATE INDEX by_kind ON events (kind) WITH (fillfactor = 50);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;
+	CREATE TABLE logs (id int) WITH (fillfactor = 100);
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})
+	assert.Equal(t, []string{
+		"ALTER INDEX events_kind RENAME TO by_kind;",
+		"CREATE TABLE logs (\n    id integer\n) WITH (fillfactor=100);",
+		"ALTER INDEX by_kind SET (fillfactor=50);",
+		"ALTER INDEX events_id RESET (fillfactor);",
+		"ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;",
+		"ALTER TABLE events SET (fillfactor=70);",
+		"ALTER TABLE events RESET (autovacuum_enabled);",
+	}, changes.SQL())
+	for _, c := range changes {
+		safety, _ := c.Classify()
+		assert.Equal(t, SafetySafe, safety, c.String())
+	}
+
+	// Options are compared as they're written, whichever order they're
+	// given in
+	same := assertParse(t, `
+	CREATE TABLE events (id int NOT NULL, kind text) WITH (autovacuum_enabled = 'off', fillfactor = '90');
+	CREATE UNIQUE INDEX events_id ON events (id) WITH (fillfactor = 90);
+	CREATE INDEX events_kind ON events (kind);
+	ALTER TABLE events REPLICA IDENTITY USING INDEX events_id;
+	`)
+	assert.Empty(t, Diff(from.Catalog, same.Catalog, DiffOptions{}).SQL())
+}
+
+func TestDiff_Statistics(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE orders (id int, city text, zip text);
+	CREATE STATISTICS city_zip ON city, zip FROM orders;
+	CREATE STATISTICS old_name (mcv) ON id, city FROM orders;
+	`)
+	to := assertParse(t, `
+	CREATE TABLE orders (id int, city text, zip text);
+	CREATE STATISTICS city_zip (ndistinct) ON city, zip FROM orders;
+	CREATE STATISTICS new_name (mcv) ON id, city FROM orders;
+	CREATE TABLE customers (id int, name text);
+	CREATE STATISTICS ON id, lower(name) FROM customers;
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})
+	assert.Equal(t, []string{
+		"DROP STATISTICS city_zip;",
+		"ALTER STATISTICS old_name RENAME TO new_name;",
+		"CREATE TABLE customers (\n    id integer,\n    name text\n);",
+		"CREATE STATISTICS city_zip (ndistinct) ON city, zip FROM orders;",
+		"CREATE STATISTICS customers_id_expr_stat ON id, (lower(name)) FROM customers;",
+	}, changes.SQL())
+	for _, c := range changes {
+		safety, _ := c.Classify()
+		assert.Equal(t, SafetySafe, safety, c.String())
+	}
+}
+
+func TestDiff_EventTriggers(t *testing.T) {
+	from := assertParse(t, `
+	CREATE EVENT TRIGGER audit ON ddl_command_end EXECUTE FUNCTION log_ddl();
+	CREATE EVENT TRIGGER guard ON sql_drop EXECUTE FUNCTION forbid_drops();
+	CREATE EVENT TRIGGER old_name ON ddl_command_start EXECUTE FUNCTION check_ddl();
+	`)
+	to := assertParse(t, `
+	CREATE TABLE t (id int);
+	CREATE EVENT TRIGGER audit ON ddl_command_end WHEN TAG IN ('DROP TABLE') EXECUTE FUNCTION log_ddl();
+	CREATE EVENT TRIGGER guard ON sql_drop EXECUTE FUNCTION forbid_drops();
+	ALTER EVENT TRIGGER guard DISABLE;
+	CREATE EVENT TRIGGER new_name ON ddl_command_start EXECUTE FUNCTION check_ddl();
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative})
+	assert.Equal(t, []string{
+		"DROP EVENT TRIGGER audit;",
+		"CREATE TABLE t (\n    id integer\n);",
+		"ALTER EVENT TRIGGER old_name RENAME TO new_name;",
+		"CREATE EVENT TRIGGER audit ON ddl_command_end WHEN TAG IN ('DROP TABLE') EXECUTE FUNCTION log_ddl();",
+		"ALTER EVENT TRIGGER guard DISABLE;",
+	}, changes.SQL())
+	assert.Equal(t, "rename event trigger old_name to new_name", changes[2].String())
+}
+
+func TestDiff_ConstraintValidation(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE base (id int PRIMARY KEY);
+	CREATE TABLE t (a int, b int, c int);
+	ALTER TABLE t ADD CONSTRAINT t_a_fk FOREIGN KEY (a) REFERENCES base (id) NOT VALID;
+	ALTER TABLE t ADD CONSTRAINT t_b_fk FOREIGN KEY (b) REFERENCES base (id);
+	ALTER TABLE t ADD CONSTRAINT t_c_fk FOREIGN KEY (c) REFERENCES base (id) NOT VALID;
+	`)
+	to := assertParse(t, `
+	CREATE TABLE base (id int PRIMARY KEY);
+	CREATE TABLE t (a int, b int, c int);
+	ALTER TABLE t ADD CONSTRAINT t_a_fk FOREIGN KEY (a) REFERENCES base (id);
+	ALTER TABLE t ADD CONSTRAINT t_b_fk FOREIGN KEY (b) REFERENCES base (id) NOT VALID;
+	ALTER TABLE t ADD CONSTRAINT t_c_fk FOREIGN KEY (c) REFERENCES base (id) NOT VALID;
+	`)
+	changes := Diff(from.Catalog, to.Catalog, DiffOptions{})
+	assert.Equal(t, []string{
+		"ALTER TABLE t VALIDATE CONSTRAINT t_a_fk;",
+		"ALTER TABLE t DROP CONSTRAINT t_b_fk;",
+		"ALTER TABLE t ADD CONSTRAINT t_b_fk FOREIGN KEY (b) REFERENCES base (id) NOT VALID;",
+	}, changes.SQL())
+	safety, _ := changes[0].Classify()
+	assert.Equal(t, SafetyIncompatible, safety)
+	safety, _ = changes[1].Classify()
+	assert.Equal(t, SafetySafe, safety)
+}
+
+func TestDiff_ForeignKeyOptions(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE posts (author_id int REFERENCES users (id), editor_id int REFERENCES users (id) MATCH FULL);
+	`)
+	to := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE posts (author_id int REFERENCES users (id) ON DELETE SET NULL (author_id), editor_id int REFERENCES users (id) MATCH FULL);
+	`)
+	assert.Equal(t, []string{
+		"ALTER TABLE posts DROP CONSTRAINT posts_author_id_fkey;",
+		"ALTER TABLE posts ADD CONSTRAINT posts_author_id_fkey FOREIGN KEY (author_id) REFERENCES users (id) ON DELETE SET NULL (author_id);",
+	}, Diff(from.Catalog, to.Catalog, DiffOptions{}).SQL())
+}
+
+func TestDiff_MatchOIDs(t *testing.T) {
+	const base = `
+	CREATE TABLE users (id int, name text);
+	CREATE TABLE posts (id int);
+	`
+	from := assertParse(t, base)
+	to := assertParse(t, base+`
+	ALTER TABLE users RENAME TO accounts;
+	ALTER TABLE accounts RENAME COLUMN name TO full_name;
+	ALTER TABLE accounts ALTER COLUMN full_name SET NOT NULL;
+	DROP TABLE posts;
+	CREATE TABLE posts (id int);
+	`)
+	users, _ := from.Catalog.Schemas.List()[0].Tables.Get("users")
+	assert.Equal(t, users.OID, assertTable(t, to, "accounts").OID)
+
+	// The new posts table is still matched by name
+	assert.Equal(t, []string{
+		"ALTER TABLE users RENAME TO accounts;",
+		"ALTER TABLE accounts RENAME COLUMN name TO full_name;",
+		"ALTER TABLE accounts ALTER COLUMN full_name SET NOT NULL;",
+	}, Diff(from.Catalog, to.Catalog, DiffOptions{MatchOIDs: true}).SQL())
+	assert.Equal(t, "drop table public.users", Diff(from.Catalog, to.Catalog, DiffOptions{})[0].String())
+}
+
+func TestDiff_CompositePrimaryKey(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE memberships (user_id int, group_id int, PRIMARY KEY (user_id, group_id));
+	`)
+	to := assertParse(t, `
+	CREATE TABLE memberships (user_id int, group_id int, PRIMARY KEY (group_id, user_id));
+	`)
+	assert.Equal(t, []string{
+		"ALTER TABLE memberships DROP CONSTRAINT memberships_pkey;",
+		"ALTER TABLE memberships ADD CONSTRAINT memberships_pkey PRIMARY KEY (group_id, user_id);",
+	}, Diff(from.Catalog, to.Catalog, DiffOptions{}).SQL())
+	assert.Empty(t, Diff(from.Catalog, from.Catalog, DiffOptions{}).SQL())
+}
+
+func TestDiff_ViewsEnumsAndSequences(t *testing.T) {
+	compile := func(sql string) *Catalog {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c.Catalog
+	}
+	from := compile(`
+CREATE TYPE status AS ENUM ('open', 'closed');
+CREATE TYPE priority AS ENUM ('low', 'medium', 'high');
+CREATE TABLE tickets (id int PRIMARY KEY, status status, priority priority DEFAULT 'low', tags priority[]);
+CREATE VIEW open_tickets AS SELECT id FROM tickets WHERE status = 'open';
+CREATE VIEW ticket_ids AS SELECT id FROM tickets;
+`)
+	to := compile(`
+CREATE TYPE status AS ENUM ('new', 'open', 'pending', 'closed');
+CREATE TYPE priority AS ENUM ('low', 'high');
+CREATE TYPE mood AS ENUM ('happy');
+CREATE TABLE tickets (id int PRIMARY KEY, status status, priority priority DEFAULT 'low', tags priority[]);
+CREATE SEQUENCE ticket_numbers OWNED BY tickets.id;
+CREATE OR REPLACE VIEW open_tickets AS SELECT id FROM tickets WHERE status = 'open';
+CREATE VIEW ticket_ids AS SELECT id FROM tickets WHERE id > 0;
+`)
+	assert.Equal(t, []string{
+		"DROP VIEW public.ticket_ids;",
+		"CREATE TYPE mood AS ENUM ('happy');",
+		"CREATE SEQUENCE ticket_numbers;",
+		"ALTER TYPE status ADD VALUE 'new' BEFORE 'open';",
+		"ALTER TYPE status ADD VALUE 'pending' AFTER 'open';",
+		"ALTER TYPE priority RENAME TO priority_old;",
+		"CREATE TYPE priority AS ENUM ('low', 'high');",
+		"ALTER TABLE tickets ALTER COLUMN priority DROP DEFAULT;",
+		"ALTER TABLE tickets ALTER COLUMN priority TYPE priority USING priority::text::priority;",
+		"ALTER TABLE tickets ALTER COLUMN priority SET DEFAULT 'low';",
+		"ALTER TABLE tickets ALTER COLUMN tags TYPE priority[] USING tags::text[]::priority[];",
+		"DROP TYPE priority_old;",
+		"ALTER SEQUENCE ticket_numbers OWNED BY tickets.id;",
+		"CREATE VIEW ticket_ids AS SELECT id FROM tickets WHERE id > 0;",
+	}, Diff(from, to, DiffOptions{}).SQL())
+
+	var names []string
+	for _, c := range Diff(to, from, DiffOptions{}) {
+		names = append(names, c.String())
+	}
+	assert.Equal(t, []string{
+		"drop view public.ticket_ids",
+		"drop enum public.mood",
+		"alter enum public.status",
+		"alter enum public.priority",
+		"drop sequence public.ticket_numbers",
+		"add view public.ticket_ids",
+	}, names)
+	assert.Empty(t, Diff(to, to, DiffOptions{}))
+}
@@ -0,0 +1,419 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// DjangoGenerator writes a models.py of unmanaged Django models for the
+// catalog's tables, as manage.py inspectdb would from the database. Foreign
+// keys become ForeignKey fields, or OneToOneField if their columns are
+// unique, leaving deletes to the database with DO_NOTHING. Django can't
+// model foreign keys of more than one column, so their columns are plain
+// fields. The catalog's ValueSets become TextChoices, given as the choices
+// of the fields limited to them.
+type DjangoGenerator struct {
+	// Managed lets Django's migrations manage the tables, rather than the
+	// migrations the catalog was compiled from.
+	Managed bool
+}
+
+func NewDjangoGenerator(fs *flag.FlagSet) Generator {
+
+	g := &DjangoGenerator{}
+	fs.BoolVar(&g.Managed, "django-managed", false, "let Django's migrations manage the tables")
+	return g
+}
+
+// djangoFields maps Postgres types to Django's model fields. Types not
+// present are written as TextField, with a comment.
+var djangoFields = map[*PostgresType]string{
+	Bigint:      "BigIntegerField",
+	Bigserial:   "BigAutoField",
+	Boolean:     "BooleanField",
+	Bytea:       "BinaryField",
+	Date:        "DateField",
+	Double:      "FloatField",
+	Inet:        "GenericIPAddressField",
+	Integer:     "IntegerField",
+	Interval:    "DurationField",
+	JSON:        "JSONField",
+	JSONB:       "JSONField",
+	Numeric:     "DecimalField",
+	Real:        "FloatField",
+	Serial:      "AutoField",
+	Smallint:    "SmallIntegerField",
+	Smallserial: "SmallAutoField",
+	Text:        "TextField",
+	Time:        "TimeField",
+	Timetz:      "TimeField",
+	Timestamp:   "DateTimeField",
+	Timestamptz: "DateTimeField",
+	UUID:        "UUIDField",
+	// Both have a length, which is checked below
+	Character:        "CharField",
+	CharacterVarying: "CharField",
+}
+
+// djangoIdentityFields are the fields of integer primary keys which the
+// database generates, as identity columns.
+var djangoIdentityFields = map[*PostgresType]string{
+	Bigint:   "BigAutoField",
+	Integer:  "AutoField",
+	Smallint: "SmallAutoField",
+}
+
+// pythonKeywords can't be used as attribute names.
+var pythonKeywords = []string{
+	"False", "None", "True", "and", "as", "assert", "async", "await", "break", "class", "continue",
+	"def", "del", "elif", "else", "except", "finally", "for", "from", "global", "if", "import",
+	"in", "is", "lambda", "nonlocal", "not", "or", "pass", "raise", "return", "try", "while",
+	"with", "yield",
+}
+
+func (g *DjangoGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	d := &djangoWriter{cat: cat, names: make(map[*Table]string), choices: make(map[*Column]string)}
+	var tables []*Table
+	classes := make(map[string]int)
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf == nil && t.Extension == "" {
+				tables = append(tables, t)
+				classes[pythonClassName(singularize(t.Name))]++
+			}
+		}
+	}
+	for _, t := range tables {
+		name := pythonClassName(singularize(t.Name))
+		if classes[name] > 1 && t.Schema != "public" {
+			name = pythonClassName(t.Schema) + name
+		}
+		d.names[t] = name
+	}
+
+	var body strings.Builder
+	for _, set := range cat.ValueSets() {
+		d.writeChoices(&body, set)
+	}
+	for _, t := range tables {
+		d.writeModel(&body, t, g.Managed)
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Generated by pgmodelgen.")
+	if d.arrays {
+		fmt.Fprintln(bw, "from django.contrib.postgres.fields import ArrayField")
+	}
+	fmt.Fprintln(bw, "from django.db import models")
+	bw.WriteString(body.String())
+	return bw.Flush()
+}
+
+type djangoWriter struct {
+	cat *Catalog
+	// names are the class names of the models of tables.
+	names map[*Table]string
+	// choices are the classes of the choices of the columns limited to a
+	// ValueSet.
+	choices map[*Column]string
+	// arrays is set if any field is an ArrayField, which needs importing.
+	arrays bool
+}
+
+func (d *djangoWriter) writeChoices(w io.Writer, set *ValueSet) {
+
+	name := pythonClassName(set.Name)
+	if set.Schema != "public" {
+		name = pythonClassName(set.Schema) + name
+	}
+	for _, col := range set.Columns {
+		d.choices[col] = name
+	}
+	fmt.Fprintf(w, "\n\nclass %s(models.TextChoices):\n", name)
+	if len(set.Values) == 0 {
+		fmt.Fprintln(w, "    pass")
+	}
+	seen := make(map[string]bool)
+	for _, l := range set.Values {
+		member := strings.ToUpper(pythonAttribute(l))
+		for seen[member] {
+			member += "_"
+		}
+		seen[member] = true
+		fmt.Fprintf(w, "    %s = %s\n", member, pythonString(l))
+	}
+}
+
+func (d *djangoWriter) writeModel(w io.Writer, t *Table, managed bool) {
+
+	cons := d.cat.Depends.TableConstraints(t)
+	var pkey *Constraint
+	fks := make(map[*Column]*Constraint)
+	targets := make(map[*Table]int)
+	var uniqueTogether []Columns
+	for _, con := range cons {
+		switch con.Type {
+		case ConstraintTypePrimary:
+			pkey = con
+		case ConstraintTypeUnique:
+			if len(con.Constrains) > 1 {
+				uniqueTogether = append(uniqueTogether, con.Constrains)
+			}
+		case ConstraintTypeForeignKey:
+			if len(con.Constrains) == 1 {
+				fks[con.Constrains[0]] = con
+				targets[con.Refers[0].Table]++
+			}
+		}
+	}
+
+	attrs := make(map[*Column]string)
+	for _, col := range t.Columns.List() {
+		attrs[col] = pythonAttribute(col.Name)
+		// Django appends _id to the name of a foreign key's field to name
+		// its column
+		if trimmed, ok := strings.CutSuffix(attrs[col], "_id"); ok && trimmed != "" && fks[col] != nil {
+			if _, clashes := t.Columns.Get(trimmed); !clashes {
+				attrs[col] = trimmed
+			}
+		}
+	}
+	fields := func(cols Columns) string {
+		names := make([]string, 0, len(cols))
+		for _, col := range cols {
+			names = append(names, attrs[col])
+		}
+		return pythonStrings(names)
+	}
+
+	fmt.Fprintf(w, "\n\nclass %s(models.Model):\n", d.names[t])
+	if pkey == nil {
+		fmt.Fprintln(w, "    # The table has no primary key, so Django assumes an id column.")
+	} else if len(pkey.Constrains) > 1 {
+		fmt.Fprintf(w, "    pk = models.CompositePrimaryKey(%s)\n", fields(pkey.Constrains))
+	}
+	for _, col := range t.Columns.List() {
+		attr := attrs[col]
+		primary := pkey != nil && len(pkey.Constrains) == 1 && pkey.Constrains[0] == col
+		var opts []string
+		var field, comment string
+		if con, ok := fks[col]; ok {
+			field = "ForeignKey"
+			if d.cat.Depends.UniqueColumns(Columns{col}) {
+				field = "OneToOneField"
+			}
+			target := con.Refers[0].Table
+			name, ok := d.names[target]
+			if !ok {
+				name = pythonClassName(singularize(target.Name))
+			}
+			opts = append(opts, pythonString(name), "models.DO_NOTHING")
+			if ref := con.Refers[0]; !ref.Attrs.Pkey {
+				opts = append(opts, "to_field="+pythonString(ref.Name))
+			}
+			if targets[target] > 1 {
+				opts = append(opts, "related_name="+pythonString(t.Name+"_"+attr+"_set"))
+			}
+			if attr+"_id" != col.Name {
+				opts = append(opts, "db_column="+pythonString(col.Name))
+			}
+		} else {
+			field, opts, comment = d.field(col, primary)
+		}
+		// The options of an array's field, rather than its elements', are
+		// given to the ArrayField
+		var common []string
+		if _, ok := fks[col]; !ok && attr != col.Name {
+			common = append(common, "db_column="+pythonString(col.Name))
+		}
+		if primary {
+			common = append(common, "primary_key=True")
+		} else if d.cat.Depends.UniqueColumns(Columns{col}) && field != "OneToOneField" {
+			common = append(common, "unique=True")
+		}
+		if !col.Attrs.NotNull && !col.Attrs.Pkey && !isSerial(col.Type) {
+			common = append(common, "blank=True", "null=True")
+		}
+		var def string
+		if col.ArrayDims > 0 {
+			d.arrays = true
+			def = fmt.Sprintf("ArrayField(%s)", strings.Join(append([]string{"models." + field + "(" + strings.Join(opts, ", ") + ")"}, common...), ", "))
+		} else {
+			def = fmt.Sprintf("models.%s(%s)", field, strings.Join(append(opts, common...), ", "))
+		}
+		if comment != "" {
+			def += "  # " + comment
+		}
+		fmt.Fprintf(w, "    %s = %s\n", attr, def)
+	}
+	taken := make(map[string]bool)
+	for _, attr := range attrs {
+		taken[attr] = true
+	}
+	for _, rel := range d.cat.Relationships(t) {
+		if def, attr := d.manyToMany(rel, taken); def != "" {
+			taken[attr] = true
+			fmt.Fprintf(w, "    %s = %s\n", attr, def)
+		}
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    class Meta:")
+	if !managed {
+		fmt.Fprintln(w, "        managed = False")
+	}
+	// Django quotes db_table as one name, so a schema is smuggled in by
+	// closing the quotes around it
+	table := t.Name
+	if t.Schema != "public" {
+		table = t.Schema + `"."` + t.Name
+	}
+	fmt.Fprintf(w, "        db_table = %s\n", pythonString(table))
+	if len(uniqueTogether) > 0 {
+		groups := make([]string, 0, len(uniqueTogether))
+		for _, cols := range uniqueTogether {
+			groups = append(groups, "("+fields(cols)+")")
+		}
+		fmt.Fprintf(w, "        unique_together = (%s,)\n", strings.Join(groups, ", "))
+	}
+}
+
+// manyToMany returns the ManyToManyField of rel and its attribute, unless
+// taken, or an empty string if rel isn't a many-to-many relationship
+// Django can model. The field is declared on the model referred to by the
+// join table's first foreign key, through the join table's model, except
+// for self-referencing join tables which would need their fields given.
+func (d *djangoWriter) manyToMany(rel *Relationship, taken map[string]bool) (string, string) {
+
+	if rel.Kind != RelationshipManyToMany || rel.SelfReferencing() || rel.Constraint != d.cat.JoinTable(rel.Through).Keys[0] {
+		return "", ""
+	}
+	if len(rel.Constraint.Constrains) != 1 || len(rel.ThroughConstraint.Constrains) != 1 {
+		return "", ""
+	}
+	target, ok := d.names[rel.Target]
+	through, tok := d.names[rel.Through]
+	if !ok || !tok {
+		return "", ""
+	}
+	attr := pythonAttribute(rel.Target.Name)
+	if trimmed, ok := strings.CutSuffix(rel.ThroughConstraint.Constrains[0].Name, "_id"); ok && trimmed != "" {
+		attr = pythonAttribute(pluralize(trimmed))
+	}
+	if taken[attr] {
+		attr = pythonAttribute(rel.Through.Name) + "_" + attr
+	}
+	return fmt.Sprintf("models.ManyToManyField(%s, through=%s)", pythonString(target), pythonString(through)), attr
+}
+
+// field returns the model field of a column which isn't a foreign key, its
+// options other than those every field takes, and a comment to explain it.
+func (d *djangoWriter) field(col *Column, primary bool) (string, []string, string) {
+
+	choices, ok := d.choices[col]
+	if !ok {
+		return d.typeField(col, primary)
+	}
+	// Enums are read as text
+	if _, ok := djangoFields[col.Type]; !ok {
+		return "TextField", []string{"choices=" + choices + ".choices"}, ""
+	}
+	field, opts, comment := d.typeField(col, primary)
+	return field, append(opts, "choices="+choices+".choices"), comment
+}
+
+// typeField returns the model field of col's type, as field does. Django
+// only generates the values of primary keys, so other serial and identity
+// columns are plain integers.
+func (d *djangoWriter) typeField(col *Column, primary bool) (string, []string, string) {
+
+	typ := col.Type
+	if underlying, ok := serialTypes[typ]; ok && !primary {
+		typ = underlying
+	}
+	if col.Attrs.Identity != IdentityNone && primary {
+		if field, ok := djangoIdentityFields[typ]; ok {
+			return field, nil, ""
+		}
+	}
+	field, ok := djangoFields[typ]
+	if !ok {
+		return "TextField", nil, "This field type is a guess, the column is " + col.FormatType() + "."
+	}
+	switch typ {
+	case Character, CharacterVarying:
+		if len(col.TypeMods) == 0 {
+			return "TextField", nil, ""
+		}
+		return field, []string{fmt.Sprintf("max_length=%d", col.TypeMods[0])}, ""
+	case Numeric:
+		if len(col.TypeMods) == 0 {
+			return field, []string{"max_digits=1000", "decimal_places=500"}, "The column is unconstrained numeric, so the precision is a guess."
+		}
+		scale := int32(0)
+		if len(col.TypeMods) > 1 {
+			scale = col.TypeMods[1]
+		}
+		return field, []string{fmt.Sprintf("max_digits=%d", col.TypeMods[0]), fmt.Sprintf("decimal_places=%d", scale)}, ""
+	}
+	return field, nil, ""
+}
+
+// pythonClassName converts a snake_case SQL identifier to a Python class
+// name.
+func pythonClassName(s string) string {
+
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, word := range words {
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	name := sb.String()
+	if name == "" || unicode.IsDigit([]rune(name)[0]) {
+		name = "Model" + name
+	}
+	return name
+}
+
+// pythonAttribute converts a SQL identifier to a Python attribute name.
+func pythonAttribute(s string) string {
+
+	attr := strings.Map(func(r rune) rune {
+		if r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, s)
+	if attr == "" || unicode.IsDigit([]rune(attr)[0]) {
+		attr = "field_" + attr
+	}
+	if slices.Contains(pythonKeywords, attr) {
+		attr += "_field"
+	}
+	return attr
+}
+
+// pythonString quotes s as a Python string literal.
+func pythonString(s string) string {
+
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`, "\n", `\n`, "\r", `\r`, "\t", `\t`).Replace(s) + "'"
+}
+
+func pythonStrings(ss []string) string {
+
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, pythonString(s))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,552 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// DocsSite is a static HTML site documenting a catalog, in the manner of
+// SchemaSpy but from the SQL defining the schema rather than a live
+// database. Its index lists the tables and views, which can be searched by
+// their names, their columns' names and their comments, above a diagram of
+// the foreign keys between the tables. Each table and view has a page of
+// its own, and a table's page has a diagram of the tables it references
+// and those referencing it. Pages don't load anything, so the site can be
+// opened from disk as well as served.
+type DocsSite struct {
+	// Title is the title of the index, which each page links back to.
+	Title string
+}
+
+// docsObject is a table or view as its page and the index show it.
+type docsObject struct {
+	Name    string
+	Page    string
+	Kind    string
+	View    bool
+	Comment string
+	Columns []*docsColumn
+	// Lineage is set if any of the columns have sources, as the columns of
+	// views and tables created by CREATE TABLE AS can.
+	Lineage       bool
+	Constraints   []string
+	Indexes       []string
+	Relationships []*docsRelationship
+	Definition    string
+	Diagram       template.HTML
+	// Search is the text the index's search matches against.
+	Search string
+}
+
+type docsColumn struct {
+	Name       string
+	Type       string
+	Nullable   bool
+	Default    string
+	PrimaryKey bool
+	References []docsLink
+	Sources    []docsLink
+	Comment    string
+}
+
+// docsLink is a link to the page of a table, or to one of its columns,
+// which is only a name if the table has no page.
+type docsLink struct {
+	Name string
+	Page string
+}
+
+type docsRelationship struct {
+	Kind    string
+	Target  docsLink
+	Columns string
+	Through *docsLink
+}
+
+// Pages returns the pages of the site for cat, by their file names.
+func (s *DocsSite) Pages(cat *Catalog) (map[string][]byte, error) {
+
+	pages := make(map[*Table]string)
+	var tables []*Table
+	for _, sch := range cat.Schemas.List() {
+		if systemSchema(sch.Name) {
+			continue
+		}
+		for _, t := range sch.Tables.List() {
+			tables = append(tables, t)
+			pages[t] = docsPageName(t.Schema, t.Name)
+		}
+	}
+	link := func(t *Table, suffix string) docsLink {
+		return docsLink{Name: t.Schema + "." + t.Name + suffix, Page: pages[t]}
+	}
+	comment := func(obj any) string {
+		if desc, ok := cat.Descriptions[obj]; ok {
+			return desc.Comment
+		}
+		return ""
+	}
+	sources := func(cols Columns) []docsLink {
+		var ret []docsLink
+		for _, src := range cols {
+			ret = append(ret, link(src.Table, "."+src.Name))
+		}
+		return ret
+	}
+
+	var objects []*docsObject
+	for _, t := range tables {
+		obj := &docsObject{Name: t.Schema + "." + t.Name, Page: pages[t], Kind: "Table", Comment: comment(t)}
+		if t.Unlogged {
+			obj.Kind = "Unlogged table"
+		}
+		for _, col := range t.Columns.List() {
+			dc := &docsColumn{
+				Name:       col.Name,
+				Type:       col.FormatType(),
+				Nullable:   !col.Attrs.NotNull && !col.Attrs.Pkey && col.Attrs.Identity == IdentityNone,
+				Default:    col.Attrs.Default,
+				PrimaryKey: col.Attrs.Pkey,
+				Sources:    sources(col.Sources),
+				Comment:    comment(col),
+			}
+			if seq := col.Sequence(); dc.Default == "" && seq != "" {
+				dc.Default = nextvalDefault(seq)
+			}
+			cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+			for _, con := range cons {
+				if i := slices.Index(con.Constrains, col); con.Type == ConstraintTypeForeignKey && i >= 0 && i < len(con.Refers) {
+					dc.References = append(dc.References, link(con.Refers[i].Table, "."+con.Refers[i].Name))
+				}
+			}
+			obj.Lineage = obj.Lineage || len(dc.Sources) > 0
+			obj.Columns = append(obj.Columns, dc)
+		}
+		for _, con := range cat.Depends.TableConstraints(t) {
+			obj.Constraints = append(obj.Constraints, ConstraintDefinition(con))
+		}
+		for _, idx := range cat.Depends.TableIndexes(t) {
+			obj.Indexes = append(obj.Indexes, IndexDefinition(idx))
+		}
+		for _, rel := range cat.Relationships(t) {
+			dr := &docsRelationship{
+				Kind:    rel.Kind.String(),
+				Target:  link(rel.Target, ""),
+				Columns: strings.Join(rel.Columns.Names(), ", ") + " → " + strings.Join(rel.TargetColumns.Names(), ", "),
+			}
+			if rel.Through != nil {
+				through := link(rel.Through, "")
+				dr.Through = &through
+			}
+			obj.Relationships = append(obj.Relationships, dr)
+		}
+		obj.Diagram = docsDiagram(cat, neighbourLayers(cat, t), true, pages)
+		objects = append(objects, obj)
+	}
+	for _, raw := range cat.Raw {
+		schema, name, _ := strings.Cut(raw.Name, ".")
+		if raw.Kind != "CREATE VIEW" || systemSchema(schema) {
+			continue
+		}
+		obj := &docsObject{Name: raw.Name, Page: docsPageName(schema, name), Kind: "View", View: true, Comment: comment(raw), Definition: raw.SQL}
+		for _, col := range raw.Columns {
+			dc := &docsColumn{Name: col.Name, Type: col.Type, Nullable: true, Sources: sources(col.Sources)}
+			obj.Lineage = obj.Lineage || len(dc.Sources) > 0
+			obj.Columns = append(obj.Columns, dc)
+		}
+		objects = append(objects, obj)
+	}
+	for _, obj := range objects {
+		terms := []string{obj.Name, obj.Comment}
+		for _, col := range obj.Columns {
+			terms = append(terms, col.Name, col.Comment)
+		}
+		terms = slices.DeleteFunc(terms, func(term string) bool { return term == "" })
+		obj.Search = strings.ToLower(strings.Join(terms, " "))
+	}
+
+	ret := make(map[string][]byte)
+	var buf bytes.Buffer
+	err := docsTemplates.ExecuteTemplate(&buf, "index", map[string]any{
+		"Title":   s.Title,
+		"Objects": objects,
+		"Diagram": docsDiagram(cat, dependencyLayers(cat, tables), false, pages),
+	})
+	if err != nil {
+		return nil, err
+	}
+	ret["index.html"] = slices.Clone(buf.Bytes())
+	for _, obj := range objects {
+		buf.Reset()
+		err := docsTemplates.ExecuteTemplate(&buf, "object", map[string]any{"Title": s.Title, "Object": obj})
+		if err != nil {
+			return nil, err
+		}
+		ret[obj.Page] = slices.Clone(buf.Bytes())
+	}
+	return ret, nil
+}
+
+// Write writes the pages of the site for cat to dir, creating it if it
+// doesn't exist.
+func (s *DocsSite) Write(dir string, cat *Catalog) error {
+
+	pages, err := s.Pages(cat)
+	if err != nil {
+		return err
+	}
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return err
+	}
+	for name, page := range pages {
+		err := os.WriteFile(filepath.Join(dir, name), page, 0o644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// docsPageName returns the file name of the page of the table or view
+// name in schema. Tables and views share a namespace, so they can't have
+// the same name. Bytes other than letters, digits and underscores are
+// written as - followed by their hex, so that the name is safe as both a
+// file name and a link, and the dot between the names is unambiguous.
+func docsPageName(schema, name string) string {
+
+	escape := func(s string) string {
+		var sb strings.Builder
+		for i := 0; i < len(s); i++ {
+			b := s[i]
+			if b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9' || b == '_' {
+				sb.WriteByte(b)
+			} else {
+				fmt.Fprintf(&sb, "-%02x", b)
+			}
+		}
+		return sb.String()
+	}
+	return escape(schema) + "." + escape(name) + ".html"
+}
+
+// foreignKeyEdges returns the tables each table references by its foreign
+// keys, other than itself.
+func foreignKeyEdges(cat *Catalog) map[*Table][]*Table {
+
+	refs := make(map[*Table][]*Table)
+	for _, con := range cat.Depends.ConstraintsByName {
+		if con.Type != ConstraintTypeForeignKey || len(con.Refers) == 0 {
+			continue
+		}
+		from, to := con.Table, con.Refers[0].Table
+		if from != to && !slices.Contains(refs[from], to) {
+			refs[from] = append(refs[from], to)
+		}
+	}
+	return refs
+}
+
+// dependencyLayers arranges tables in layers by how many foreign keys lie
+// between them and the furthest of the tables they depend on, so that
+// tables are drawn after those they reference.
+func dependencyLayers(cat *Catalog, tables []*Table) [][]*Table {
+
+	refs := foreignKeyEdges(cat)
+	var layers [][]*Table
+	for _, t := range tables {
+		_, depth := reachable(t, refs)
+		for len(layers) <= depth {
+			layers = append(layers, nil)
+		}
+		layers[depth] = append(layers[depth], t)
+	}
+	return layers
+}
+
+// neighbourLayers arranges t between the tables referencing it and those
+// it references. A table which does both is drawn among the latter.
+func neighbourLayers(cat *Catalog, t *Table) [][]*Table {
+
+	var referencing []*Table
+	referenced := slices.Clone(foreignKeyEdges(cat)[t])
+	for _, con := range cat.Depends.ReferencingConstraints(t) {
+		if con.Table != t && !slices.Contains(referenced, con.Table) && !slices.Contains(referencing, con.Table) {
+			referencing = append(referencing, con.Table)
+		}
+	}
+	byName := func(a, b *Table) int {
+		return strings.Compare(a.Schema+"."+a.Name, b.Schema+"."+b.Name)
+	}
+	slices.SortFunc(referencing, byName)
+	slices.SortFunc(referenced, byName)
+	var layers [][]*Table
+	for _, layer := range [][]*Table{referencing, {t}, referenced} {
+		if len(layer) > 0 {
+			layers = append(layers, layer)
+		}
+	}
+	return layers
+}
+
+// The sizes diagrams are laid out with, in pixels. Text is assumed to be
+// of a fixed width, which the diagram's font is.
+const (
+	docsCharWidth  = 7
+	docsLineHeight = 18
+	docsPadding    = 8
+	docsLayerGap   = 80
+	docsBoxGap     = 20
+)
+
+// docsDiagram draws layers of tables as an SVG, from left to right, with
+// each layer a column of boxes, and a curve for each foreign key between
+// the tables drawn. Boxes link to the tables' pages, and list their
+// columns if columns is set, in which case curves join the columns of
+// foreign keys rather than the boxes. It draws nothing if there are no
+// tables.
+func docsDiagram(cat *Catalog, layers [][]*Table, columns bool, pages map[*Table]string) template.HTML {
+
+	type box struct {
+		x, y, w, h int
+	}
+	boxes := make(map[*Table]*box)
+	width, height := 0, 0
+	for _, layer := range layers {
+		layerWidth := 0
+		for _, t := range layer {
+			chars := len(t.Schema + "." + t.Name)
+			lines := 1
+			if columns {
+				for _, col := range t.Columns.List() {
+					chars = max(chars, len(col.Name+" "+col.FormatType())+2)
+				}
+				lines += len(t.Columns.List())
+			}
+			layerWidth = max(layerWidth, chars*docsCharWidth+2*docsPadding)
+			boxes[t] = &box{x: width, h: lines*docsLineHeight + docsPadding}
+		}
+		y := 0
+		for _, t := range layer {
+			b := boxes[t]
+			b.y, b.w = y, layerWidth
+			y += b.h + docsBoxGap
+		}
+		width += layerWidth + docsLayerGap
+		height = max(height, y-docsBoxGap)
+	}
+	if len(boxes) == 0 {
+		return ""
+	}
+	width -= docsLayerGap
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="-1 -1 %d %d" font-family="monospace" font-size="12">`,
+		width+2, height+2, width+2, height+2)
+	sb.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M0,0 L10,5 L0,10 z"/></marker></defs>`)
+	// The height of the line of a box's column, or of its middle
+	rowY := func(t *Table, col *Column) int {
+		b := boxes[t]
+		if i := slices.Index(t.Columns.List(), col); columns && i >= 0 {
+			return b.y + (i+1)*docsLineHeight + docsLineHeight*3/4
+		}
+		return b.y + b.h/2
+	}
+	var cons []*Constraint
+	for _, con := range cat.Depends.ConstraintsByName {
+		if con.Type != ConstraintTypeForeignKey || len(con.Refers) == 0 || con.Table == con.Refers[0].Table {
+			continue
+		}
+		if boxes[con.Table] != nil && boxes[con.Refers[0].Table] != nil {
+			cons = append(cons, con)
+		}
+	}
+	slices.SortFunc(cons, func(a, b *Constraint) int { return strings.Compare(a.Name, b.Name) })
+	for _, con := range cons {
+		from, to := boxes[con.Table], boxes[con.Refers[0].Table]
+		y1, y2 := rowY(con.Table, con.Constrains[0]), rowY(con.Refers[0].Table, con.Refers[0])
+		x1, x2, c1, c2 := from.x+from.w, to.x, docsLayerGap/2, -docsLayerGap/2
+		switch {
+		case from.x > to.x:
+			x1, x2, c1, c2 = from.x, to.x+to.w, -docsLayerGap/2, docsLayerGap/2
+		case from.x == to.x:
+			x2, c2 = to.x+to.w, docsLayerGap/2
+		}
+		fmt.Fprintf(&sb, `<path d="M%d,%d C%d,%d %d,%d %d,%d" fill="none" stroke="#666" marker-end="url(#arrow)"><title>%s</title></path>`,
+			x1, y1, x1+c1, y1, x2+c2, y2, x2, y2, html.EscapeString(con.Name))
+	}
+	for _, layer := range layers {
+		for _, t := range layer {
+			b := boxes[t]
+			if page := pages[t]; page != "" {
+				fmt.Fprintf(&sb, `<a href="%s">`, html.EscapeString(page))
+			}
+			fmt.Fprintf(&sb, `<rect x="%d" y="%d" width="%d" height="%d" fill="#fff" stroke="#333"/>`, b.x, b.y, b.w, b.h)
+			fmt.Fprintf(&sb, `<text x="%d" y="%d" font-weight="bold">%s</text>`,
+				b.x+docsPadding, b.y+docsLineHeight*3/4+docsPadding/2, html.EscapeString(t.Schema+"."+t.Name))
+			if columns {
+				fmt.Fprintf(&sb, `<line x1="%d" y1="%d" x2="%d" y2="%d" stroke="#333"/>`,
+					b.x, b.y+docsLineHeight+docsPadding/2, b.x+b.w, b.y+docsLineHeight+docsPadding/2)
+				for i, col := range t.Columns.List() {
+					marker := "  "
+					if col.Attrs.Pkey {
+						marker = "* "
+					}
+					fmt.Fprintf(&sb, `<text x="%d" y="%d" xml:space="preserve">%s</text>`,
+						b.x+docsPadding, b.y+(i+1)*docsLineHeight+docsLineHeight*3/4+docsPadding/2, html.EscapeString(marker+col.Name+" "+col.FormatType()))
+				}
+			}
+			if pages[t] != "" {
+				sb.WriteString(`</a>`)
+			}
+		}
+	}
+	sb.WriteString(`</svg>`)
+	return template.HTML(sb.String())
+}
+
+var docsTemplates = template.Must(template.New("docs").Parse(`
+{{- define "head" -}}
+<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.}}</title>
+<style>
+body { font-family: sans-serif; margin: 2em; color: #222; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.3em 0.6em; text-align: left; vertical-align: top; }
+th { background: #f3f3f3; }
+code, pre { font-family: monospace; }
+pre { background: #f7f7f7; padding: 0.6em; overflow-x: auto; }
+input[type=search] { width: 30em; padding: 0.3em; margin-bottom: 1em; }
+.diagram { overflow-x: auto; }
+.diagram a:hover rect { fill: #eef; }
+</style>
+</head>
+<body>
+{{- end}}
+
+{{- define "link" -}}
+{{if .Page}}<a href="{{.Page}}">{{.Name}}</a>{{else}}{{.Name}}{{end}}
+{{- end}}
+
+{{- define "index" -}}
+{{template "head" .Title}}
+<h1>{{.Title}}</h1>
+<input id="search" type="search" placeholder="Search tables, views, columns and comments" autofocus>
+<table id="objects">
+<thead><tr><th>Name</th><th>Kind</th><th>Columns</th><th>Comment</th></tr></thead>
+<tbody>
+{{- range .Objects}}
+<tr data-search="{{.Search}}"><td><a href="{{.Page}}">{{.Name}}</a></td><td>{{.Kind}}</td><td>{{len .Columns}}</td><td>{{.Comment}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- with .Diagram}}
+<h2>Relationships</h2>
+<div class="diagram">{{.}}</div>
+{{- end}}
+<script>
+document.getElementById("search").addEventListener("input", function (e) {
+	var terms = e.target.value.toLowerCase().split(" ").filter(function (term) { return term !== ""; });
+	document.querySelectorAll("#objects tbody tr").forEach(function (tr) {
+		var text = tr.dataset.search;
+		tr.hidden = !terms.every(function (term) { return text.indexOf(term) >= 0; });
+	});
+});
+</script>
+</body>
+</html>
+{{end}}
+
+{{- define "object" -}}
+{{template "head" (print .Object.Name " - " .Title)}}
+{{- with .Object}}
+<p><a href="index.html">{{$.Title}}</a></p>
+<h1>{{.Kind}} {{.Name}}</h1>
+{{- with .Comment}}
+<p>{{.}}</p>
+{{- end}}
+<h2>Columns</h2>
+<table>
+<thead><tr><th>Column</th><th>Type</th>{{if not .View}}<th>Nullable</th><th>Default</th><th>Key</th>{{end}}{{if .Lineage}}<th>Sources</th>{{end}}<th>Comment</th></tr></thead>
+<tbody>
+{{- range .Columns}}
+<tr><td>{{.Name}}</td><td><code>{{.Type}}</code></td>
+{{- if not $.Object.View}}<td>{{if .Nullable}}yes{{else}}no{{end}}</td><td><code>{{.Default}}</code></td><td>{{if .PrimaryKey}}PK {{end}}{{range .References}}→ {{template "link" .}} {{end}}</td>{{end}}
+{{- if $.Object.Lineage}}<td>{{range $i, $src := .Sources}}{{if $i}}, {{end}}{{template "link" $src}}{{end}}</td>{{end}}<td>{{.Comment}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- with .Constraints}}
+<h2>Constraints</h2>
+<ul>
+{{- range .}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- with .Indexes}}
+<h2>Indexes</h2>
+<ul>
+{{- range .}}
+<li><code>{{.}}</code></li>
+{{- end}}
+</ul>
+{{- end}}
+{{- with .Relationships}}
+<h2>Relationships</h2>
+<table>
+<thead><tr><th>Kind</th><th>Table</th><th>Columns</th><th>Through</th></tr></thead>
+<tbody>
+{{- range .}}
+<tr><td>{{.Kind}}</td><td>{{template "link" .Target}}</td><td>{{.Columns}}</td><td>{{with .Through}}{{template "link" .}}{{end}}</td></tr>
+{{- end}}
+</tbody>
+</table>
+{{- end}}
+{{- with .Diagram}}
+<h2>Diagram</h2>
+<div class="diagram">{{.}}</div>
+{{- end}}
+{{- with .Definition}}
+<h2>Definition</h2>
+<pre>{{.}}</pre>
+{{- end}}
+{{- end}}
+</body>
+</html>
+{{end}}
+`))
+
+func runDocs(args []string) error {
+
+	fs := flag.NewFlagSet("docs", flag.ExitOnError)
+	title := fs.String("title", "Schema", "title of the site")
+	out := fs.String("out", "", "directory to write the site to")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *out == "" {
+		return fmt.Errorf("no output directory, set -out")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	site := &DocsSite{Title: *title}
+	return site.Write(*out, c.Catalog)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDocsSite(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE SCHEMA "odd.schema";
+CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL);
+COMMENT ON TABLE users IS 'People <who> sign in';
+COMMENT ON COLUMN users.email IS 'Where mail goes';
+CREATE TABLE posts (id int PRIMARY KEY, author_id int NOT NULL REFERENCES users (id), title text);
+CREATE TABLE tags (id int PRIMARY KEY);
+CREATE TABLE post_tags (post_id int REFERENCES posts (id), tag_id int REFERENCES tags (id), PRIMARY KEY (post_id, tag_id));
+CREATE TABLE "odd.schema"."My Table" (a int);
+CREATE VIEW post_titles AS SELECT p.title, u.email FROM posts p JOIN users u ON u.id = p.author_id;
+`))
+	site := &DocsSite{Title: "Blog"}
+	pages, err := site.Pages(c.Catalog)
+	require.Nil(t, err)
+	names := make([]string, 0, len(pages))
+	for name := range pages {
+		names = append(names, name)
+	}
+	assert.ElementsMatch(t, []string{"index.html", "public.users.html", "public.posts.html", "public.tags.html",
+		"public.post_tags.html", "odd-2eschema.My-20Table.html", "public.post_titles.html"}, names)
+
+	index := string(pages["index.html"])
+	assert.Contains(t, index, `<tr data-search="public.users people &lt;who&gt; sign in id email where mail goes"><td><a href="public.users.html">public.users</a></td><td>Table</td><td>2</td><td>People &lt;who&gt; sign in</td></tr>`)
+	assert.Contains(t, index, `<a href="odd-2eschema.My-20Table.html">odd.schema.My Table</a></td><td>Table</td>`)
+	assert.Contains(t, index, `<a href="public.post_titles.html">public.post_titles</a></td><td>View</td>`)
+	assert.Contains(t, index, `<svg`)
+	assert.Contains(t, index, `<title>posts_author_id_fkey</title>`)
+
+	posts := string(pages["public.posts.html"])
+	assert.Contains(t, posts, `<p><a href="index.html">Blog</a></p>`)
+	assert.Contains(t, posts, `<tr><td>author_id</td><td><code>integer</code></td><td>no</td><td><code></code></td><td>→ <a href="public.users.html">public.users.id</a> </td><td></td></tr>`)
+	assert.Contains(t, posts, `<li><code>CONSTRAINT posts_pkey PRIMARY KEY (id)</code></li>`)
+	assert.Contains(t, posts, `<tr><td>many to many</td><td><a href="public.tags.html">public.tags</a></td><td>id → id</td><td><a href="public.post_tags.html">public.post_tags</a></td></tr>`)
+	// The diagram has posts between the table referencing it and the one it
+	// references, and leaves out tags
+	assert.Contains(t, posts, `<a href="public.post_tags.html"><rect x="0" y="0"`)
+	assert.Contains(t, posts, `<a href="public.users.html"><rect x="444" y="0"`)
+	assert.NotContains(t, posts, `<a href="public.tags.html"><rect`)
+
+	view := string(pages["public.post_titles.html"])
+	assert.Contains(t, view, `<h1>View public.post_titles</h1>`)
+	assert.Contains(t, view, `<tr><td>email</td><td><code>text</code></td><td><a href="public.users.html">public.users.email</a></td><td></td></tr>`)
+	assert.Contains(t, view, `<pre>CREATE VIEW post_titles AS SELECT p.title`)
+
+	dir := filepath.Join(t.TempDir(), "site")
+	require.Nil(t, site.Write(dir, c.Catalog))
+	b, err := os.ReadFile(filepath.Join(dir, "public.users.html"))
+	require.Nil(t, err)
+	assert.Equal(t, pages["public.users.html"], b)
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// DownCheck is the result of verifying the down migration of a migration.
+type DownCheck struct {
+	Migration string `json:"migration"`
+	// Missing is set if the migration has no down migration, which isn't
+	// a failure.
+	Missing bool `json:"missing,omitempty"`
+	// Error is why applying the migration then its down migration failed.
+	Error string `json:"error,omitempty"`
+	// Differences are the changes which would return the catalog to its
+	// state before the migration, after applying the migration then its
+	// down migration.
+	Differences []string `json:"differences,omitempty"`
+}
+
+// Failed reports whether the down migration didn't reverse the migration.
+func (d *DownCheck) Failed() bool {
+
+	return d.Error != "" || len(d.Differences) > 0
+}
+
+// VerifyDown compiles the files in order, as CompileFiles does, checking
+// that applying each migration then its down migration leaves the catalog
+// as it was before the migration.
+//
+// Each check starts from the catalog before the migration as written by
+// the DDLGenerator, so it's compared against that same catalog compiled
+// again rather than the compiler's own.
+func (c *Compiler) VerifyDown(paths []string) ([]*DownCheck, error) {
+
+	files, err := expandPaths(paths, c.Migrations)
+	if err != nil {
+		return nil, err
+	}
+	var checks []*DownCheck
+	for _, file := range files {
+		b, err := os.ReadFile(file.path)
+		if err != nil {
+			return nil, err
+		}
+		up := file.source.Up(string(b))
+		check := &DownCheck{Migration: file.path}
+		checks = append(checks, check)
+
+		down, ok, err := file.source.Down(file.path, string(b))
+		if err != nil {
+			return nil, fmt.Errorf("while reading down migration of %s: %w", file.path, err)
+		}
+		if !ok {
+			check.Missing = true
+		} else {
+			err = c.checkDown(check, up, down)
+			if err != nil {
+				return nil, fmt.Errorf("while checking %s: %w", file.path, err)
+			}
+		}
+
+		err = c.Compile(up)
+		if err != nil {
+			return nil, fmt.Errorf("while compiling %s: %w", file.path, err)
+		}
+	}
+	return checks, nil
+}
+
+// checkDown records on check the differences made by applying up then
+// down to the compiler's catalog.
+func (c *Compiler) checkDown(check *DownCheck, up, down string) error {
+
+	var sb strings.Builder
+	err := (&DDLGenerator{}).Generate(&sb, c.Catalog)
+	if err != nil {
+		return err
+	}
+	before := NewCompiler()
+	before.Lenient = c.Lenient
+	err = before.Compile(sb.String())
+	if err != nil {
+		return fmt.Errorf("while compiling the catalog before the migration: %w", err)
+	}
+
+	after := NewCompiler()
+	after.Lenient = c.Lenient
+	err = after.Compile(sb.String())
+	if err == nil {
+		err = after.Compile(up)
+		if err != nil {
+			err = fmt.Errorf("while migrating up: %w", err)
+		}
+	}
+	if err == nil {
+		err = after.Compile(down)
+		if err != nil {
+			err = fmt.Errorf("while migrating down: %w", err)
+		}
+	}
+	if err != nil {
+		check.Error = err.Error()
+		return nil
+	}
+	for _, change := range Diff(after.Catalog, before.Catalog, DiffOptions{}) {
+		check.Differences = append(check.Differences, change.String())
+	}
+	return nil
+}
+
+func runVerifyDown(args []string) error {
+
+	fs := flag.NewFlagSet("verify-down", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	lenient := fs.Bool("lenient", false, "skip what can't be modeled yet instead of failing")
+	migrations := fs.String("migrations", "auto", "how directories of migrations are laid out, auto or one of: "+migrationSourceNames())
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	compiler := NewCompiler()
+	compiler.Lenient = *lenient
+	compiler.Migrations = *migrations
+	checks, err := compiler.VerifyDown(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	err = writeDownChecks(w, checks, *format)
+	if err != nil {
+		return err
+	}
+	failed := 0
+	for _, check := range checks {
+		if check.Failed() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d down migrations don't reverse their migration", failed)
+	}
+	return nil
+}
+
+func writeDownChecks(w io.Writer, checks []*DownCheck, format string) error {
+
+	if format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(checks)
+	}
+	bw := bufio.NewWriter(w)
+	for _, check := range checks {
+		switch {
+		case check.Missing:
+			fmt.Fprintf(bw, "%s: no down migration\n", check.Migration)
+		case check.Error != "":
+			fmt.Fprintf(bw, "%s: FAIL: %s\n", check.Migration, check.Error)
+		case len(check.Differences) > 0:
+			{
+				fmt.Fprintf(bw, "%s: FAIL: the catalog differs after migrating down\n", check.Migration)
+				for _, d := range check.Differences {
+					fmt.Fprintf(bw, "    %s\n", d)
+				}
+			}
+		default:
+			fmt.Fprintf(bw, "%s: ok\n", check.Migration)
+		}
+	}
+	return bw.Flush()
+}
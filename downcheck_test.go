@@ -0,0 +1,86 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompiler_VerifyDown(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	write("001_users.up.sql", "CREATE TABLE users (id int PRIMARY KEY, name text);\n")
+	write("001_users.down.sql", "DROP TABLE users;\n")
+	write("002_email.up.sql", "ALTER TABLE users ADD COLUMN email text;\nCREATE INDEX users_email ON users (email);\n")
+	write("002_email.down.sql", "DROP INDEX users_email;\n")
+	write("003_posts.up.sql", "CREATE TABLE posts (id int PRIMARY KEY);\n")
+	write("003_posts.down.sql", "DROP TABLE missing;\n")
+	write("004_seed.up.sql", "ALTER TABLE posts ADD COLUMN title text;\n")
+
+	c := NewCompiler()
+	checks, err := c.VerifyDown([]string{dir})
+	require.Nil(t, err)
+	require.Len(t, checks, 4)
+
+	assert.False(t, checks[0].Failed())
+	assert.True(t, checks[1].Failed())
+	assert.Equal(t, []string{"drop column public.users.email"}, checks[1].Differences)
+	assert.True(t, checks[2].Failed())
+	assert.Contains(t, checks[2].Error, "while migrating down")
+	assert.True(t, checks[3].Missing)
+	assert.False(t, checks[3].Failed())
+
+	// The migrations themselves are applied
+	_, ok := assertTable(t, c, "public.posts").Columns.Get("title")
+	assert.True(t, ok)
+}
+
+func TestCompiler_VerifyDownGoose(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "1_users.sql"), []byte(`-- +goose Up
+CREATE TABLE users (id int PRIMARY KEY);
+CREATE TABLE roles (id int PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE users;
+`), 0o644))
+
+	checks, err := NewCompiler().VerifyDown([]string{dir})
+	require.Nil(t, err)
+	require.Len(t, checks, 1)
+	assert.Equal(t, []string{"drop table public.roles"}, checks[0].Differences)
+}
+
+func TestCompiler_VerifyDownViewsEnumsAndSequences(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	write("001_users.up.sql", "CREATE TABLE users (id int PRIMARY KEY, name text);\n")
+	write("001_users.down.sql", "DROP TABLE users;\n")
+	write("002_status.up.sql", `CREATE TYPE status AS ENUM ('active', 'banned');
+CREATE SEQUENCE invoice_numbers;
+CREATE VIEW user_names AS SELECT name FROM users;
+ALTER TABLE users ADD COLUMN status status;
+`)
+	write("002_status.down.sql", "ALTER TABLE users DROP COLUMN status;\n")
+	write("003_labels.up.sql", "ALTER TYPE status ADD VALUE 'deleted';\n")
+	write("003_labels.down.sql", "\n")
+
+	checks, err := NewCompiler().VerifyDown([]string{dir})
+	require.Nil(t, err)
+	require.Len(t, checks, 3)
+	assert.False(t, checks[0].Failed())
+	assert.True(t, checks[1].Failed())
+	assert.Equal(t, []string{
+		"drop view public.user_names",
+		"drop enum public.status",
+		"drop sequence public.invoice_numbers",
+	}, checks[1].Differences)
+	assert.True(t, checks[2].Failed())
+	assert.Equal(t, []string{"alter enum public.status"}, checks[2].Differences)
+}
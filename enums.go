@@ -0,0 +1,477 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Enum is a type created by CREATE TYPE ... AS ENUM. Columns of an enum
+// have the opaque type named by EnumIdent, so that they're matched to it
+// by name.
+type Enum struct {
+	OID    OID
+	Schema string
+	Name   string
+	// Labels are the enum's values, in their sort order.
+	Labels  []string
+	Owner   string
+	Defined SourceLocation
+}
+
+// EnumIdent returns the quoted name of e, qualified with its schema unless
+// it lives in public.
+func EnumIdent(e *Enum) string {
+
+	if e.Schema == "public" {
+		return QuoteIdent(e.Name)
+	}
+	return QuoteIdent(e.Schema) + "." + QuoteIdent(e.Name)
+}
+
+// EnumDefinition renders the CREATE TYPE statement creating e.
+func EnumDefinition(e *Enum) string {
+
+	labels := make([]string, 0, len(e.Labels))
+	for _, l := range e.Labels {
+		labels = append(labels, QuoteLiteral(l))
+	}
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", EnumIdent(e), strings.Join(labels, ", "))
+}
+
+// EnumColumns returns the columns of cat's tables of type e, including
+// arrays of it.
+func EnumColumns(cat *Catalog, e *Enum) Columns {
+
+	ident := EnumIdent(e)
+	return FindColumns(cat, func(col *Column) bool {
+		return col.Type.Opaque && col.Type.Name == ident
+	})
+}
+
+// findEnum returns the enum named by names, qualified with the search
+// path if it isn't already, or nil if there isn't one.
+func (c *Compiler) findEnum(names []string) *Enum {
+
+	schema, name := c.SearchPath, names[len(names)-1]
+	if len(names) > 1 {
+		schema = names[len(names)-2]
+	}
+	sch, ok := c.Catalog.Schemas.Get(schema)
+	if !ok {
+		return nil
+	}
+	e, _ := sch.Enums.Get(name)
+	return e
+}
+
+func (c *Compiler) CreateEnum(stmt *pg_query.CreateEnumStmt) error {
+
+	names, err := NodeStrings(stmt.TypeName)
+	if err != nil {
+		return err
+	}
+	schema, name := c.SearchPath, names[len(names)-1]
+	if len(names) > 1 {
+		schema = names[len(names)-2]
+	}
+	sch, ok := c.Catalog.Schemas.Get(schema)
+	if !ok {
+		return fmt.Errorf("couldn't find schema %s", schema)
+	}
+	e := &Enum{Schema: schema, Name: name, Defined: c.stmtLocation()}
+	if orig, ok := sch.Enums.Get(name); ok {
+		return fmt.Errorf("type already exists: %s%s", name, duplicateLocations(orig.Defined, e.Defined))
+	}
+	for _, n := range stmt.Vals {
+		label, err := NodeString(n)
+		if err != nil {
+			return err
+		}
+		if slices.Contains(e.Labels, label) {
+			return fmt.Errorf("enum label %q is given more than once", label)
+		}
+		e.Labels = append(e.Labels, label)
+	}
+	e.OID = c.Catalog.newOID()
+	sch.Enums.Add(name, e)
+	return nil
+}
+
+// AlterEnum adds a label to an enum, or renames one of its labels.
+func (c *Compiler) AlterEnum(stmt *pg_query.AlterEnumStmt) error {
+
+	names, err := NodeStrings(stmt.TypeName)
+	if err != nil {
+		return err
+	}
+	e := c.findEnum(names)
+	if e == nil {
+		return fmt.Errorf("couldn't find enum %s", strings.Join(names, "."))
+	}
+	if stmt.OldVal != "" {
+		i := slices.Index(e.Labels, stmt.OldVal)
+		if i < 0 {
+			return fmt.Errorf("%q is not an existing enum label", stmt.OldVal)
+		}
+		if slices.Contains(e.Labels, stmt.NewVal) {
+			return fmt.Errorf("enum label %q already exists", stmt.NewVal)
+		}
+		e.Labels[i] = stmt.NewVal
+		return nil
+	}
+	if slices.Contains(e.Labels, stmt.NewVal) {
+		if stmt.SkipIfNewValExists {
+			return nil
+		}
+		return fmt.Errorf("enum label %q already exists", stmt.NewVal)
+	}
+	i := len(e.Labels)
+	if stmt.NewValNeighbor != "" {
+		i = slices.Index(e.Labels, stmt.NewValNeighbor)
+		if i < 0 {
+			return fmt.Errorf("%q is not an existing enum label", stmt.NewValNeighbor)
+		}
+		if stmt.NewValIsAfter {
+			i++
+		}
+	}
+	e.Labels = slices.Insert(e.Labels, i, stmt.NewVal)
+	return nil
+}
+
+// RenameEnum renames an enum, and the type of the columns using it. Other
+// types aren't modeled, so it does nothing if names isn't an enum.
+func (c *Compiler) RenameEnum(names []string, newName string) error {
+
+	e := c.findEnum(names)
+	if e == nil {
+		return nil
+	}
+	sch, _ := c.Catalog.Schemas.Get(e.Schema) // Must be ok
+	if orig, ok := sch.Enums.Get(newName); ok {
+		return fmt.Errorf("type already exists: %s%s", newName, duplicateLocations(orig.Defined, c.stmtLocation()))
+	}
+	cols := EnumColumns(c.Catalog, e)
+	sch.Enums.Rename(e.Name, newName)
+	e.Name = newName
+	for _, col := range cols {
+		col.SetType(OpaqueType(EnumIdent(e)), col.TypeMods, col.ArrayDims)
+	}
+	return nil
+}
+
+// DropEnum drops the enum named by names, and if the drop cascades the
+// columns using it. It returns false if names isn't an enum.
+func (c *Compiler) DropEnum(names []string, behav DropBehaviour) (bool, error) {
+
+	e := c.findEnum(names)
+	if e == nil {
+		return false, nil
+	}
+	cols := EnumColumns(c.Catalog, e)
+	if len(cols) > 0 && behav != DropBehaviourCascade {
+		return true, fmt.Errorf("can't drop type %s because column %s of table %s uses it and cascade was not specified",
+			e.Name, cols[0].Name, cols[0].Table.Name)
+	}
+	for _, col := range cols {
+		err := c.DropColumn(col.Table, col.Name, pg_query.DropBehavior_DROP_CASCADE)
+		if err != nil {
+			return true, err
+		}
+	}
+	sch, _ := c.Catalog.Schemas.Get(e.Schema)
+	sch.Enums.Remove(e.Name)
+	return true, nil
+}
+
+// EnumUsage is an enum listed by the enums command, with the columns using
+// it.
+type EnumUsage struct {
+	OID     OID      `json:"oid"`
+	Type    string   `json:"type"`
+	Labels  []string `json:"labels"`
+	Columns []string `json:"columns"`
+}
+
+// EnumUsages returns the enums of cat in the order of their schemas.
+func EnumUsages(cat *Catalog) []*EnumUsage {
+
+	ret := []*EnumUsage{}
+	for _, sch := range cat.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			u := &EnumUsage{OID: e.OID, Type: e.Schema + "." + e.Name, Labels: e.Labels, Columns: []string{}}
+			for _, col := range EnumColumns(cat, e) {
+				u.Columns = append(u.Columns, col.Table.Schema+"."+col.Table.Name+"."+col.Name)
+			}
+			ret = append(ret, u)
+		}
+	}
+	return ret
+}
+
+// ValueSet is a set of values columns are limited to, either by an enum or
+// by check constraints on a text column, such as CHECK (status IN
+// ('active', 'banned')), for generators to write as the enums of other
+// languages.
+type ValueSet struct {
+	Schema string
+	// Name is the enum's name, or for check constraints the singular of
+	// their table's name and their column's, such as user_status.
+	Name   string
+	Values []string
+	// Enum is the enum whose labels the values are, or nil if they're a
+	// check constraint's.
+	Enum *Enum
+	// Columns are the columns limited to the values, including arrays of
+	// an enum.
+	Columns Columns
+}
+
+type ValueSets []*ValueSet
+
+// Of returns the set of values col is limited to, or nil if it isn't.
+func (vs ValueSets) Of(col *Column) *ValueSet {
+
+	for _, set := range vs {
+		if slices.Contains(set.Columns, col) {
+			return set
+		}
+	}
+	return nil
+}
+
+// ValueSets returns the enums of the catalog in the order of their
+// schemas, followed by the sets of values check constraints limit text
+// columns to, in the order of their tables. The values of a column limited
+// by more than one constraint are those all of them allow. Sets named like
+// one before them are suffixed with _values.
+func (c *Catalog) ValueSets() ValueSets {
+
+	var sets ValueSets
+	taken := make(map[string]bool)
+	for _, sch := range c.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			sets = append(sets, &ValueSet{Schema: e.Schema, Name: e.Name, Values: e.Labels, Enum: e, Columns: EnumColumns(c, e)})
+			taken[e.Schema+"."+e.Name] = true
+		}
+	}
+	for _, sch := range c.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf != nil || t.Extension != "" {
+				continue
+			}
+			for _, col := range t.Columns.List() {
+				if (col.Type != Text && col.Type != CharacterVarying && col.Type != Character) || col.ArrayDims > 0 {
+					continue
+				}
+				var values []string
+				cons, _ := c.Depends.ConstraintsByColumn.Get(col)
+				for _, con := range cons {
+					rules, ok := CheckRules(con)
+					if !ok {
+						continue
+					}
+					for _, rule := range rules {
+						switch {
+						case rule.Kind != CheckRuleOneOf || rule.Column != col:
+						case values == nil:
+							values = rule.Values
+						default:
+							values = slices.DeleteFunc(slices.Clone(values), func(v string) bool { return !slices.Contains(rule.Values, v) })
+						}
+					}
+				}
+				if values == nil {
+					continue
+				}
+				name := singularize(t.Name) + "_" + col.Name
+				for taken[t.Schema+"."+name] {
+					name += "_values"
+				}
+				taken[t.Schema+"."+name] = true
+				sets = append(sets, &ValueSet{Schema: t.Schema, Name: name, Values: values, Columns: Columns{col}})
+			}
+		}
+	}
+	return sets
+}
+
+// EnumToLookupTable returns the statements replacing e with a lookup table
+// named table, in e's schema, holding its labels. Columns of e become text
+// columns with a foreign key to the table, so that they still only hold
+// its labels. The labels' order is kept in the table's sort_order column.
+func EnumToLookupTable(cat *Catalog, e *Enum, table string) ([]string, error) {
+
+	sch, _ := cat.Schemas.Get(e.Schema)
+	if sch == nil {
+		return nil, fmt.Errorf("couldn't find schema %s", e.Schema)
+	}
+	if _, ok := sch.Tables.Get(table); ok {
+		return nil, fmt.Errorf("table %s already exists", table)
+	}
+	lookup := &Table{Schema: e.Schema, Name: table}
+	stmts := []string{fmt.Sprintf("CREATE TABLE %s (\n    value text PRIMARY KEY,\n    sort_order int NOT NULL UNIQUE\n)", TableIdent(lookup))}
+	if len(e.Labels) > 0 {
+		var rows []string
+		for i, l := range e.Labels {
+			rows = append(rows, fmt.Sprintf("(%s, %d)", QuoteLiteral(l), i+1))
+		}
+		stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (value, sort_order) VALUES %s", TableIdent(lookup), strings.Join(rows, ", ")))
+	}
+	for _, col := range EnumColumns(cat, e) {
+		if col.ArrayDims > 0 {
+			return nil, fmt.Errorf("column %s of table %s is an array of %s, which no foreign key can check", col.Name, col.Table.Name, e.Name)
+		}
+		stmts = append(stmts, retypeColumn(col, "text")...)
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s ADD FOREIGN KEY (%s) REFERENCES %s (value)",
+			TableIdent(col.Table), QuoteIdent(col.Name), TableIdent(lookup)))
+	}
+	return append(stmts, "DROP TYPE "+EnumIdent(e)), nil
+}
+
+// LookupTableToEnum returns the statements replacing the lookup table t
+// with an enum named name, in t's schema, of labels. The labels are the
+// rows of the table, which aren't known from its definition. The columns
+// referring to the table become columns of the enum, failing if any holds
+// a value not among labels.
+func LookupTableToEnum(cat *Catalog, t *Table, name string, labels []string) ([]string, error) {
+
+	if len(labels) == 0 {
+		return nil, fmt.Errorf("the labels of table %s aren't known, give them in the order they sort", t.Name)
+	}
+	sch, _ := cat.Schemas.Get(t.Schema)
+	if _, ok := sch.Enums.Get(name); ok {
+		return nil, fmt.Errorf("type %s already exists", name)
+	}
+	if _, ok := sch.Tables.Get(name); ok {
+		// Every table has a row type of the same name
+		return nil, fmt.Errorf("type %s would conflict with table %s", name, name)
+	}
+	e := &Enum{Schema: t.Schema, Name: name, Labels: labels}
+	stmts := []string{EnumDefinition(e)}
+	for _, con := range cat.Depends.ReferencingConstraints(t) {
+		if len(con.Constrains) != 1 {
+			return nil, fmt.Errorf("foreign key %s of table %s has more than one column", con.Name, con.Table.Name)
+		}
+		stmts = append(stmts, fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT %s", TableIdent(con.Table), QuoteIdent(con.Name)))
+		stmts = append(stmts, retypeColumn(con.Constrains[0], EnumIdent(e))...)
+	}
+	return append(stmts, "DROP TABLE "+TableIdent(t)), nil
+}
+
+// retypeColumn returns the statements changing the type of col to typ by
+// casting its values, and its default if it has one.
+func retypeColumn(col *Column, typ string) []string {
+
+	alter := "ALTER TABLE " + TableIdent(col.Table) + " ALTER COLUMN " + QuoteIdent(col.Name)
+	retype := fmt.Sprintf("%s TYPE %s USING %s::%s", alter, typ, QuoteIdent(col.Name), typ)
+	if col.Attrs.Default == "" {
+		return []string{retype}
+	}
+	// The old default can't be cast when the type changes, so it's set
+	// again afterwards
+	return []string{alter + " DROP DEFAULT", retype, fmt.Sprintf("%s SET DEFAULT (%s)::%s", alter, col.Attrs.Default, typ)}
+}
+
+func runEnums(args []string) error {
+
+	fs := flag.NewFlagSet("enums", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	toTable := fs.String("to-table", "", "write the statements replacing this enum with a lookup table")
+	fromTable := fs.String("from-table", "", "write the statements replacing this lookup table with an enum")
+	name := fs.String("name", "", "name of the table or enum replacing the other, defaults to the enum's name with _values appended for -to-table")
+	labels := fs.String("labels", "", "comma separated labels of the enum replacing the table given by -from-table, in the order they sort")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *toTable != "" && *fromTable != "" {
+		return fmt.Errorf("-to-table and -from-table can't be given together")
+	}
+	if *fromTable != "" && *name == "" {
+		return fmt.Errorf("-from-table needs the enum's -name")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	var stmts []string
+	switch {
+	case *toTable != "":
+		{
+			e := c.findEnum(strings.Split(*toTable, "."))
+			if e == nil {
+				return fmt.Errorf("couldn't find enum %s", *toTable)
+			}
+			if *name == "" {
+				*name = e.Name + "_values"
+			}
+			stmts, err = EnumToLookupTable(c.Catalog, e, *name)
+		}
+	case *fromTable != "":
+		{
+			t := findTable(c.Catalog, c.SearchPath, *fromTable)
+			if t == nil {
+				return fmt.Errorf("couldn't find table %s", *fromTable)
+			}
+			var ls []string
+			if *labels != "" {
+				ls = strings.Split(*labels, ",")
+			}
+			stmts, err = LookupTableToEnum(c.Catalog, t, *name, ls)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	bw := bufio.NewWriter(w)
+	if stmts != nil {
+		for _, stmt := range stmts {
+			fmt.Fprintf(bw, "%s;\n", stmt)
+		}
+		return bw.Flush()
+	}
+	usages := EnumUsages(c.Catalog)
+	if *format == "json" {
+		enc := json.NewEncoder(bw)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(usages)
+		if err != nil {
+			return err
+		}
+		return bw.Flush()
+	}
+	for _, u := range usages {
+		fmt.Fprintf(bw, "%s (%s)\n", u.Type, strings.Join(u.Labels, ", "))
+		for _, col := range u.Columns {
+			fmt.Fprintf(bw, "    %s\n", col)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_Enums(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE SCHEMA app;
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE TYPE app.status AS ENUM ('new', 'done');
+ALTER TYPE mood ADD VALUE 'ok' BEFORE 'sad';
+ALTER TYPE mood ADD VALUE IF NOT EXISTS 'happy';
+ALTER TYPE app.status RENAME VALUE 'new' TO 'open';
+CREATE TABLE people (id int PRIMARY KEY, mood mood NOT NULL DEFAULT 'ok', moods mood[], status app.status);
+ALTER TYPE mood RENAME TO feeling;
+`))
+	assert.Empty(t, c.Warnings)
+	people := assertTable(t, c, "people")
+	col, _ := people.Columns.Get("mood")
+	assert.Equal(t, "feeling", col.FormatType())
+	col, _ = people.Columns.Get("status")
+	assert.Equal(t, "app.status", col.FormatType())
+
+	assert.Equal(t, []*EnumUsage{
+		{OID: 16384, Type: "public.feeling", Labels: []string{"happy", "ok", "sad"}, Columns: []string{"public.people.mood", "public.people.moods"}},
+		{OID: 16385, Type: "app.status", Labels: []string{"open", "done"}, Columns: []string{"public.people.status"}},
+	}, EnumUsages(c.Catalog))
+
+	assert.ErrorContains(t, c.Compile("ALTER TYPE feeling ADD VALUE 'sad';"), `enum label "sad" already exists`)
+	assert.ErrorContains(t, c.Compile("DROP TYPE app.status;"), "column status of table people uses it")
+	require.Nil(t, c.Compile("DROP TYPE app.status CASCADE;"))
+	_, ok := people.Columns.Get("status")
+	assert.False(t, ok)
+	assert.Empty(t, c.Skipped)
+}
+
+func TestEnumToLookupTable(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TYPE mood AS ENUM ('happy', 'it''s fine');
+CREATE TABLE people (id int PRIMARY KEY, mood mood NOT NULL DEFAULT 'happy');
+`))
+	e, _ := c.Catalog.Schemas.List()[0].Enums.Get("mood")
+	stmts, err := EnumToLookupTable(c.Catalog, e, "moods")
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		"CREATE TABLE moods (\n    value text PRIMARY KEY,\n    sort_order int NOT NULL UNIQUE\n)",
+		"INSERT INTO moods (value, sort_order) VALUES ('happy', 1), ('it''s fine', 2)",
+		"ALTER TABLE people ALTER COLUMN mood DROP DEFAULT",
+		"ALTER TABLE people ALTER COLUMN mood TYPE text USING mood::text",
+		"ALTER TABLE people ALTER COLUMN mood SET DEFAULT ('happy')::text",
+		"ALTER TABLE people ADD FOREIGN KEY (mood) REFERENCES moods (value)",
+		"DROP TYPE mood",
+	}, stmts)
+
+	// Applying the statements gets back to a lookup table
+	for _, stmt := range stmts {
+		require.Nil(t, c.Compile(stmt+";"))
+	}
+	moods := assertTable(t, c, "moods")
+	assert.Len(t, c.Catalog.Depends.ReferencingConstraints(moods), 1)
+
+	stmts, err = LookupTableToEnum(c.Catalog, moods, "mood", []string{"happy", "it's fine"})
+	require.Nil(t, err)
+	assert.Equal(t, []string{
+		"CREATE TYPE mood AS ENUM ('happy', 'it''s fine')",
+		"ALTER TABLE people DROP CONSTRAINT people_mood_fkey",
+		"ALTER TABLE people ALTER COLUMN mood DROP DEFAULT",
+		"ALTER TABLE people ALTER COLUMN mood TYPE mood USING mood::mood",
+		"ALTER TABLE people ALTER COLUMN mood SET DEFAULT ('happy'::text)::mood",
+		"DROP TABLE moods",
+	}, stmts)
+
+	_, err = LookupTableToEnum(c.Catalog, moods, "people", []string{"happy"})
+	assert.ErrorContains(t, err, "would conflict with table people")
+	_, err = LookupTableToEnum(c.Catalog, moods, "mood", nil)
+	assert.ErrorContains(t, err, "labels of table moods aren't known")
+}
+
+func TestCatalog_ValueSets(t *testing.T) {
+	const sql = `
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE TABLE users (
+		mood mood,
+		moods mood[],
+		status text CHECK (status IN ('active', 'banned', 'deleted')),
+		tier varchar(10) CHECK (tier = ANY (ARRAY['free', 'paid'])),
+		kind text CHECK (kind <> '') CHECK (kind IN ('a', 'b')),
+		level int CHECK (level IN (1, 2)),
+		note text CHECK (note IN ('x') OR note IS NULL)
+	);
+	CREATE TABLE user_statuses (user_status text);
+	ALTER TABLE users ADD CHECK (status IN ('active', 'banned'));
+	CREATE TABLE "user" (status text CHECK (status IN ('x')));
+	`
+	c := assertParse(t, sql)
+	var got []string
+	sets := c.Catalog.ValueSets()
+	for _, set := range sets {
+		got = append(got, fmt.Sprintf("%s.%s %v %d", set.Schema, set.Name, set.Values, len(set.Columns)))
+	}
+	assert.Equal(t, []string{
+		"public.mood [happy sad] 2",
+		"public.user_status [active banned] 1",
+		"public.user_tier [free paid] 1",
+		"public.user_kind [a b] 1",
+		"public.user_status_values [x] 1",
+	}, got)
+	public, _ := c.Catalog.Schemas.Get("public")
+	users, _ := public.Tables.Get("users")
+	status, _ := users.Columns.Get("status")
+	level, _ := users.Columns.Get("level")
+	assert.Equal(t, sets[1], sets.Of(status))
+	assert.Nil(t, sets.Of(level))
+}
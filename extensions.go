@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"slices"
+	"strings"
+)
+
+// Extensions aren't modeled beyond their names, but the tables and views
+// which belong to one are marked with it, as they're recorded by ALTER
+// EXTENSION ... ADD. pg_dump writes these statements for the objects of
+// extensions when dumping for an upgrade, and extensions' scripts run them
+// for objects created outside of the script.
+
+// AlterExtensionContents adds a table or view to an extension, or removes
+// one from it. Other kinds of object aren't modeled, so they're skipped.
+func (c *Compiler) AlterExtensionContents(stmt *pg_query.AlterExtensionContentsStmt) error {
+
+	action := "ADD"
+	if stmt.Action < 0 {
+		action = "DROP"
+	}
+	if stmt.Objtype != pg_query.ObjectType_OBJECT_TABLE && stmt.Objtype != pg_query.ObjectType_OBJECT_VIEW {
+		c.skip("ALTER EXTENSION "+action+" "+strings.ReplaceAll(strings.TrimPrefix(stmt.Objtype.String(), "OBJECT_"), "_", " "), "")
+		return nil
+	}
+	if !slices.Contains(c.Extensions, stmt.Extname) {
+		return fmt.Errorf("extension %s not found", stmt.Extname)
+	}
+	names, err := NodeStrings(stmt.Object.GetList().GetItems())
+	if err != nil {
+		return err
+	}
+	ext, err := c.memberExtension(stmt.Objtype, names)
+	if err != nil {
+		return err
+	}
+	switch {
+	case stmt.Action > 0 && *ext != "":
+		return fmt.Errorf("%s is already a member of extension %s", strings.Join(names, "."), *ext)
+	case stmt.Action < 0 && *ext != stmt.Extname:
+		return fmt.Errorf("%s is not a member of extension %s", strings.Join(names, "."), stmt.Extname)
+	}
+	if stmt.Action > 0 {
+		*ext = stmt.Extname
+	} else {
+		*ext = ""
+	}
+	return nil
+}
+
+// memberExtension returns the field recording the extension the table or
+// view named names belongs to.
+func (c *Compiler) memberExtension(typ pg_query.ObjectType, names []string) (*string, error) {
+
+	if typ == pg_query.ObjectType_OBJECT_VIEW {
+		name := c.qualifiedName(names)
+		view := c.findOpaque("CREATE VIEW", name)
+		if view == nil {
+			return nil, fmt.Errorf("view %s not found", name)
+		}
+		return &view.Extension, nil
+	}
+	var schema string
+	if len(names) > 1 {
+		schema = names[len(names)-2]
+	}
+	t, err := c.FindTableFromSchemaAndName(schema, names[len(names)-1])
+	if err != nil {
+		return nil, err
+	}
+	return &t.Extension, nil
+}
+
+// DropExtension drops the tables and views belonging to the extensions
+// named, as Postgres does, failing unless the drop cascades if anything
+// else depends on them. The extensions themselves aren't modeled beyond
+// their names, so they're forgotten and the statement is skipped.
+func (c *Compiler) DropExtension(names []string, missingOk bool, behav DropBehaviour) error {
+
+	for _, ext := range names {
+		if !slices.Contains(c.Extensions, ext) {
+			if missingOk {
+				continue
+			}
+			return fmt.Errorf("extension %s not found", ext)
+		}
+		var members []any
+		for _, raw := range c.Catalog.Raw {
+			if raw.Extension == ext {
+				members = append(members, raw)
+			}
+		}
+		for _, sch := range c.Catalog.Schemas.List() {
+			for _, t := range sch.Tables.List() {
+				if t.Extension == ext {
+					members = append(members, t)
+				}
+			}
+		}
+		if behav != DropBehaviourCascade {
+			member := func(obj any) bool {
+				if raw, ok := obj.(*RawStatement); ok && raw.Extension == ext {
+					return true
+				}
+				t := objectTable(obj)
+				return t != nil && t.Extension == ext
+			}
+			var deps []*Dependency
+			for _, obj := range members {
+				deps = append(deps, c.Catalog.Depends.DependentsOf(obj)...)
+				if t, ok := obj.(*Table); ok {
+					for _, col := range t.Columns.List() {
+						deps = append(deps, c.Catalog.Depends.DependentsOf(col)...)
+					}
+				}
+			}
+			deps = slices.DeleteFunc(deps, func(dep *Dependency) bool { return member(dep.Dependent) })
+			if blocking := restrictingDependents(deps, nil); blocking != "" {
+				return fmt.Errorf("can't drop extension %s because other objects depend on it and cascade was not specified: %s", ext, blocking)
+			}
+		}
+		// Members may depend on each other, and dropping one may drop others
+		for _, obj := range members {
+			var err error
+			switch obj := obj.(type) {
+			case *RawStatement:
+				err = c.DropView(strings.SplitN(obj.Name, ".", 2), true, DropBehaviourCascade)
+			case *Table:
+				if sch, _ := c.Catalog.Schemas.Get(obj.Schema); sch != nil {
+					if t, _ := sch.Tables.Get(obj.Name); t == obj {
+						err = c.DropTable(obj.Schema, obj.Name, DropBehaviourCascade)
+					}
+				}
+			}
+			if err != nil {
+				return err
+			}
+		}
+		c.Extensions = slices.DeleteFunc(c.Extensions, func(name string) bool { return name == ext })
+	}
+	c.skip("DROP EXTENSION", "")
+	return nil
+}
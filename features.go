@@ -0,0 +1,443 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"github.com/samber/lo"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Feature is a Postgres feature which a statement may use.
+type Feature struct {
+	Name string
+	// Since is the first major version of Postgres with the feature, or 0
+	// if every version has it.
+	Since int
+	// uses reports whether a node of a parse tree uses the feature.
+	uses func(node proto.Message) bool
+	// detail optionally describes how a node uses the feature, such as
+	// the name of the extension used.
+	detail func(node proto.Message) string
+}
+
+// Features lists the features which FindFeatures detects, ordered by the
+// version they were introduced in.
+var Features = []*Feature{
+	{Name: "extensions", uses: nodeOfType[*pg_query.CreateExtensionStmt], detail: func(n proto.Message) string {
+		return n.(*pg_query.CreateExtensionStmt).Extname
+	}},
+	{Name: "exotic types", uses: func(n proto.Message) bool {
+		_, ok := exoticTypes[typeNameOf(n)]
+		return ok
+	}, detail: typeNameOf},
+	{Name: "custom types", uses: func(n proto.Message) bool {
+		return customTypeKind(n) != ""
+	}, detail: customTypeKind},
+	{Name: "table inheritance", uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CreateStmt)
+		return ok && len(s.InhRelations) > 0 && s.Partbound == nil
+	}},
+	{Name: "unlogged tables", uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CreateStmt)
+		return ok && s.Relation.Relpersistence == "u"
+	}},
+	{Name: "tablespaces", uses: func(n proto.Message) bool {
+		switch s := n.(type) {
+		case *pg_query.CreateTableSpaceStmt:
+			return true
+		case *pg_query.CreateStmt:
+			return s.Tablespacename != ""
+		case *pg_query.IndexStmt:
+			return s.TableSpace != ""
+		}
+		return false
+	}},
+	{Name: "materialized views", uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CreateTableAsStmt)
+		return ok && s.Objtype == pg_query.ObjectType_OBJECT_MATVIEW
+	}},
+	{Name: "foreign data", uses: func(n proto.Message) bool {
+		switch n.(type) {
+		case *pg_query.CreateForeignTableStmt, *pg_query.CreateFdwStmt, *pg_query.CreateForeignServerStmt:
+			return true
+		}
+		return false
+	}},
+	{Name: "row level security", uses: func(n proto.Message) bool {
+		switch s := n.(type) {
+		case *pg_query.AlterTableCmd:
+			return s.Subtype == pg_query.AlterTableType_AT_EnableRowSecurity ||
+				s.Subtype == pg_query.AlterTableType_AT_ForceRowSecurity
+		case *pg_query.CreatePolicyStmt:
+			return true
+		}
+		return false
+	}},
+	{Name: "deferrable constraints", uses: func(n proto.Message) bool {
+		// Column constraints are followed by a separate node for DEFERRABLE
+		s, ok := n.(*pg_query.Constraint)
+		return ok && (s.Deferrable || s.Contype == pg_query.ConstrType_CONSTR_ATTR_DEFERRABLE)
+	}},
+	{Name: "exclusion constraints", uses: constraintOfType(pg_query.ConstrType_CONSTR_EXCLUSION)},
+	{Name: "triggers", uses: nodeOfType[*pg_query.CreateTrigStmt]},
+	{Name: "event triggers", uses: nodeOfType[*pg_query.CreateEventTrigStmt]},
+	{Name: "procedural languages", uses: func(n proto.Message) bool {
+		_, ok := n.(*pg_query.CreateFunctionStmt)
+		return ok && !slices.Contains([]string{"sql", "plpgsql", "internal", "c"}, functionLanguage(n))
+	}, detail: functionLanguage},
+	{Name: "declarative partitioning", Since: 10, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CreateStmt)
+		return ok && s.Partspec != nil
+	}},
+	{Name: "identity columns", Since: 10, uses: constraintOfType(pg_query.ConstrType_CONSTR_IDENTITY)},
+	{Name: "extended statistics", Since: 10, uses: nodeOfType[*pg_query.CreateStatsStmt]},
+	{Name: "logical replication", Since: 10, uses: func(n proto.Message) bool {
+		switch n.(type) {
+		case *pg_query.CreatePublicationStmt, *pg_query.CreateSubscriptionStmt:
+			return true
+		}
+		return false
+	}},
+	{Name: "hash partitioning", Since: 11, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.PartitionSpec)
+		return ok && s.Strategy == pg_query.PartitionStrategy_PARTITION_STRATEGY_HASH
+	}},
+	{Name: "default partitions", Since: 11, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.PartitionBoundSpec)
+		return ok && s.IsDefault
+	}},
+	{Name: "procedures", Since: 11, uses: func(n proto.Message) bool {
+		switch s := n.(type) {
+		case *pg_query.CreateFunctionStmt:
+			return s.IsProcedure
+		case *pg_query.CallStmt:
+			return true
+		}
+		return false
+	}},
+	{Name: "covering indexes", Since: 11, uses: func(n proto.Message) bool {
+		switch s := n.(type) {
+		case *pg_query.IndexStmt:
+			return len(s.IndexIncludingParams) > 0
+		case *pg_query.Constraint:
+			return len(s.Including) > 0
+		}
+		return false
+	}},
+	{Name: "generated columns", Since: 12, uses: constraintOfType(pg_query.ConstrType_CONSTR_GENERATED)},
+	{Name: "CTE materialization hints", Since: 12, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CommonTableExpr)
+		return ok && (s.Ctematerialized == pg_query.CTEMaterialize_CTEMaterializeAlways ||
+			s.Ctematerialized == pg_query.CTEMaterialize_CTEMaterializeNever)
+	}},
+	{Name: "REINDEX CONCURRENTLY", Since: 12, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.ReindexStmt)
+		return ok && slices.ContainsFunc(s.Params, func(p *pg_query.Node) bool {
+			return p.GetDefElem().GetDefname() == "concurrently"
+		})
+	}},
+	{Name: "FETCH FIRST WITH TIES", Since: 13, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.SelectStmt)
+		return ok && s.LimitOption == pg_query.LimitOption_LIMIT_OPTION_WITH_TIES
+	}},
+	{Name: "column compression", Since: 14, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.ColumnDef)
+		return ok && s.Compression != ""
+	}},
+	{Name: "CREATE OR REPLACE TRIGGER", Since: 14, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CreateTrigStmt)
+		return ok && s.Replace
+	}},
+	{Name: "DETACH PARTITION CONCURRENTLY", Since: 14, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.PartitionCmd)
+		return ok && s.Concurrent
+	}},
+	{Name: "CTE SEARCH and CYCLE clauses", Since: 14, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.CommonTableExpr)
+		return ok && (s.SearchClause != nil || s.CycleClause != nil)
+	}},
+	{Name: "MERGE", Since: 15, uses: nodeOfType[*pg_query.MergeStmt]},
+	{Name: "NULLS NOT DISTINCT", Since: 15, uses: func(n proto.Message) bool {
+		switch s := n.(type) {
+		case *pg_query.IndexStmt:
+			return s.NullsNotDistinct
+		case *pg_query.Constraint:
+			return s.NullsNotDistinct
+		}
+		return false
+	}},
+	{Name: "foreign key ON DELETE column lists", Since: 15, uses: func(n proto.Message) bool {
+		s, ok := n.(*pg_query.Constraint)
+		return ok && len(s.FkDelSetCols) > 0
+	}},
+	{Name: "SQL/JSON constructors and predicates", Since: 16, uses: func(n proto.Message) bool {
+		switch n.(type) {
+		case *pg_query.JsonObjectConstructor, *pg_query.JsonArrayConstructor, *pg_query.JsonArrayQueryConstructor,
+			*pg_query.JsonObjectAgg, *pg_query.JsonArrayAgg, *pg_query.JsonIsPredicate:
+			return true
+		}
+		return false
+	}},
+}
+
+// exoticTypes are the built in types which are rarely used, or which
+// are supplied by common extensions.
+var exoticTypes = lo.SliceToMap([]string{
+	"box", "circle", "line", "lseg", "path", "point", "polygon",
+	"cidr", "inet", "macaddr", "macaddr8",
+	"bit", "varbit", "money", "xml", "tsquery", "tsvector",
+	"pg_lsn", "pg_snapshot", "txid_snapshot",
+	"int4range", "int8range", "numrange", "tsrange", "tstzrange", "daterange",
+	"int4multirange", "int8multirange", "nummultirange", "tsmultirange", "tstzmultirange", "datemultirange",
+	"citext", "hstore", "ltree", "cube", "isbn", "geometry", "geography", "vector",
+}, func(name string) (string, struct{}) { return name, struct{}{} })
+
+// typeNameOf returns the unqualified name of the type n refers to, if n is
+// a type name.
+func typeNameOf(n proto.Message) string {
+
+	tn, ok := n.(*pg_query.TypeName)
+	if !ok || len(tn.Names) == 0 {
+		return ""
+	}
+	return tn.Names[len(tn.Names)-1].GetString_().GetSval()
+}
+
+func customTypeKind(n proto.Message) string {
+
+	switch n.(type) {
+	case *pg_query.CreateEnumStmt:
+		return "enum"
+	case *pg_query.CompositeTypeStmt:
+		return "composite"
+	case *pg_query.CreateDomainStmt:
+		return "domain"
+	case *pg_query.CreateRangeStmt:
+		return "range"
+	}
+	return ""
+}
+
+// functionLanguage returns the language of a function definition, which
+// defaults to sql.
+func functionLanguage(n proto.Message) string {
+
+	for _, opt := range n.(*pg_query.CreateFunctionStmt).Options {
+		if opt.GetDefElem().GetDefname() == "language" {
+			return strings.ToLower(opt.GetDefElem().GetArg().GetString_().GetSval())
+		}
+	}
+	return "sql"
+}
+
+func nodeOfType[T proto.Message](n proto.Message) bool {
+
+	_, ok := n.(T)
+	return ok
+}
+
+func constraintOfType(typ pg_query.ConstrType) func(proto.Message) bool {
+
+	return func(n proto.Message) bool {
+		c, ok := n.(*pg_query.Constraint)
+		return ok && c.Contype == typ
+	}
+}
+
+// FeatureUse is a use of a feature by a statement.
+type FeatureUse struct {
+	Feature *Feature
+	Detail  string
+	// Location is the offset in the source of the node using the feature,
+	// or of its statement if the node has no location.
+	Location int32
+}
+
+// FindFeatures returns the features used by stmt, in the order they occur.
+// src is the text stmt was parsed from if it is known, which locates the
+// statement itself more precisely.
+func FindFeatures(src string, stmt *pg_query.RawStmt) []FeatureUse {
+
+	start := statementStart(src, stmt)
+	var ret []FeatureUse
+	walkNodes(stmt.Stmt.ProtoReflect(), func(m protoreflect.Message) {
+		node := m.Interface()
+		for _, f := range Features {
+			if !f.uses(node) {
+				continue
+			}
+			loc := start
+			if fd := m.Descriptor().Fields().ByName("location"); fd != nil && m.Get(fd).Int() >= 0 {
+				loc = int32(m.Get(fd).Int())
+			}
+			use := FeatureUse{Feature: f, Location: loc}
+			if f.detail != nil {
+				use.Detail = f.detail(node)
+			}
+			ret = append(ret, use)
+		}
+	})
+	return ret
+}
+
+// statementStart returns the offset in src of stmt's first token, if src
+// is known, or else its location.
+func statementStart(src string, stmt *pg_query.RawStmt) int32 {
+
+	start := stmt.StmtLocation
+	if int(start) < len(src) {
+		// The statement's location includes the whitespace after the
+		// previous one
+		rest := src[start:]
+		start += int32(len(rest) - len(strings.TrimLeftFunc(rest, unicode.IsSpace)))
+	}
+	return start
+}
+
+// walkNodes calls fn for m and every message nested within it.
+func walkNodes(m protoreflect.Message, fn func(protoreflect.Message)) {
+
+	fn(m)
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		if fd.Message() == nil || fd.IsMap() {
+			return true
+		}
+		if fd.IsList() {
+			l := v.List()
+			for i := 0; i < l.Len(); i++ {
+				walkNodes(l.Get(i).Message(), fn)
+			}
+			return true
+		}
+		walkNodes(v.Message(), fn)
+		return true
+	})
+}
+
+// FeatureReport counts the uses of each feature and detail by a set of
+// files.
+type FeatureReport struct {
+	Feature string   `json:"feature"`
+	Detail  string   `json:"detail,omitempty"`
+	Since   int      `json:"since,omitempty"`
+	Count   int      `json:"count"`
+	Uses    []string `json:"uses"`
+}
+
+// ReportFeatures finds the features used by the statements in each of the
+// files, ordered as in Features then by detail. Uses are described as
+// "file:line".
+func ReportFeatures(paths []string) ([]*FeatureReport, error) {
+
+	files, err := expandPaths(paths, "")
+	if err != nil {
+		return nil, err
+	}
+	type key struct {
+		feature *Feature
+		detail  string
+	}
+	reports := make(map[key]*FeatureReport)
+	for _, file := range files {
+		path := file.path
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		src, _, err := StripCopyData(file.source.Up(string(b)))
+		if err != nil {
+			return nil, fmt.Errorf("while parsing %s: %w", path, err)
+		}
+		parse, err := pg_query.Parse(src)
+		if err != nil {
+			return nil, fmt.Errorf("while parsing %s: %w", path, err)
+		}
+		lines := newLineIndex(src)
+		for _, stmt := range parse.Stmts {
+			for _, use := range FindFeatures(src, stmt) {
+				k := key{use.Feature, use.Detail}
+				r, ok := reports[k]
+				if !ok {
+					r = &FeatureReport{Feature: use.Feature.Name, Detail: use.Detail, Since: use.Feature.Since}
+					reports[k] = r
+				}
+				r.Count++
+				r.Uses = append(r.Uses, fmt.Sprintf("%s:%d", path, lines.line(int(use.Location))+1))
+			}
+		}
+	}
+
+	keys := lo.Keys(reports)
+	slices.SortFunc(keys, func(a, b key) int {
+		ai, bi := slices.Index(Features, a.feature), slices.Index(Features, b.feature)
+		if ai != bi {
+			return ai - bi
+		}
+		return strings.Compare(a.detail, b.detail)
+	})
+	ret := make([]*FeatureReport, 0, len(keys))
+	for _, k := range keys {
+		ret = append(ret, reports[k])
+	}
+	return ret, nil
+}
+
+func runFeatures(args []string) error {
+
+	fs := flag.NewFlagSet("features", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	reports, err := ReportFeatures(fs.Args())
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+	bw := bufio.NewWriter(w)
+	for _, r := range reports {
+		name := r.Feature
+		if r.Detail != "" {
+			name += ": " + r.Detail
+		}
+		if r.Since > 0 {
+			name += fmt.Sprintf(" (Postgres %d+)", r.Since)
+		}
+		fmt.Fprintf(bw, "%s: %d\n", name, r.Count)
+		for _, use := range r.Uses {
+			fmt.Fprintf(bw, "    %s\n", use)
+		}
+	}
+	return bw.Flush()
+}
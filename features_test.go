@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFindFeatures(t *testing.T) {
+	const sql = `
+	CREATE TABLE measurements (
+		id int GENERATED ALWAYS AS IDENTITY,
+		reading numeric,
+		doubled numeric GENERATED ALWAYS AS (reading * 2) STORED,
+		UNIQUE NULLS NOT DISTINCT (reading)
+	) PARTITION BY HASH (id);
+	CREATE INDEX ON measurements (id) INCLUDE (reading);
+	MERGE INTO measurements m USING other o ON m.id = o.id WHEN MATCHED THEN DELETE;
+	WITH x AS MATERIALIZED (SELECT 1) SELECT * FROM x;
+	SELECT '{}' IS JSON;
+	`
+	parse, err := pg_query.Parse(sql)
+	require.Nil(t, err)
+	var found []string
+	for _, stmt := range parse.Stmts {
+		for _, use := range FindFeatures(sql, stmt) {
+			found = append(found, use.Feature.Name)
+			assert.GreaterOrEqual(t, use.Location, stmt.StmtLocation)
+		}
+	}
+	assert.ElementsMatch(t, []string{
+		"declarative partitioning",
+		"hash partitioning",
+		"identity columns",
+		"generated columns",
+		"NULLS NOT DISTINCT",
+		"covering indexes",
+		"MERGE",
+		"CTE materialization hints",
+		"SQL/JSON constructors and predicates",
+	}, found)
+}
+
+func TestCompiler_TargetVersion(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (
+		id int PRIMARY KEY,
+		email text,
+		UNIQUE NULLS NOT DISTINCT (email)
+	);
+	`
+	for _, version := range []int{0, 15, 16} {
+		c := NewCompiler()
+		c.TargetVersion = version
+		assert.Nil(t, c.Compile(sql), version)
+	}
+
+	c := NewCompiler()
+	c.TargetVersion = 14
+	err := c.Compile(sql)
+	require.NotNil(t, err)
+	assert.Equal(t, "NULLS NOT DISTINCT requires Postgres 15, but the target is 14 (line 5)", err.Error())
+
+	c = NewCompiler()
+	c.TargetVersion = 14
+	c.WarnUnsupported = true
+	require.Nil(t, c.Compile(sql))
+	assert.Equal(t, []string{"NULLS NOT DISTINCT requires Postgres 15, but the target is 14 (line 5)"}, c.Warnings)
+	assertTable(t, c, "public.users")
+}
+
+func TestReportFeatures(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "001_init.sql"), []byte(`CREATE EXTENSION postgis;
+CREATE EXTENSION citext;
+CREATE TABLE places (
+	id int PRIMARY KEY,
+	email citext,
+	location geometry,
+	parent int REFERENCES places (id) DEFERRABLE INITIALLY DEFERRED
+);
+`), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "002_rls.sql"), []byte(`ALTER TABLE places ENABLE ROW LEVEL SECURITY;
+CREATE POLICY own ON places USING (true);
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE TABLE logs (at timestamptz, body text) PARTITION BY RANGE (at);
+`), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "002_rls.down.sql"), []byte(`DROP POLICY own ON places;`), 0o644))
+
+	reports, err := ReportFeatures([]string{dir})
+	require.Nil(t, err)
+	var lines []string
+	for _, r := range reports {
+		lines = append(lines, fmt.Sprintf("%s/%s/%d: %s", r.Feature, r.Detail, r.Count, strings.Join(r.Uses, " ")))
+	}
+	init, rls := filepath.Join(dir, "001_init.sql"), filepath.Join(dir, "002_rls.sql")
+	assert.Equal(t, []string{
+		"extensions/citext/1: " + init + ":2",
+		"extensions/postgis/1: " + init + ":1",
+		"exotic types/citext/1: " + init + ":5",
+		"exotic types/geometry/1: " + init + ":6",
+		"custom types/enum/1: " + rls + ":3",
+		"row level security//2: " + rls + ":1 " + rls + ":2",
+		"deferrable constraints//1: " + init + ":7",
+		"declarative partitioning//1: " + rls + ":4",
+	}, lines)
+}
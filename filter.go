@@ -0,0 +1,133 @@
+package main
+
+import (
+	"flag"
+	"github.com/henges/pgmodelparse/collections"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+)
+
+// ObjectFilter selects the objects of catalogs to compare, so that comparing
+// migrations against a database's schema, such as one dumped by pg_dump,
+// isn't drowned out by objects the migrations don't manage: schemas of
+// other applications, objects belonging to extensions, and Postgres' own
+// schemas.
+type ObjectFilter struct {
+	// IncludeSchemas are globs matching the schemas to compare, or empty to
+	// compare every schema.
+	IncludeSchemas []string
+	// ExcludeSchemas are globs matching schemas not to compare, even if
+	// IncludeSchemas matches them.
+	ExcludeSchemas []string
+	// ExtensionObjects compares the tables and views belonging to
+	// extensions, which are otherwise left out.
+	ExtensionObjects bool
+	// SystemSchemas compares pg_catalog, information_schema and Postgres'
+	// other schemas, which are otherwise left out.
+	SystemSchemas bool
+}
+
+// systemSchema reports whether the schema named name is one Postgres
+// creates itself.
+func systemSchema(name string) bool {
+
+	return name == "pg_catalog" || name == "information_schema" || name == "pg_toast" || name == TempSchema ||
+		strings.HasPrefix(name, "pg_temp_") || strings.HasPrefix(name, "pg_toast_temp_")
+}
+
+// Schema reports whether the filter includes the schema named name.
+func (f *ObjectFilter) Schema(name string) bool {
+
+	if !f.SystemSchemas && systemSchema(name) {
+		return false
+	}
+	matches := func(globs []string) bool {
+		return slices.ContainsFunc(globs, func(glob string) bool {
+			ok, _ := path.Match(glob, name)
+			return ok
+		})
+	}
+	return (len(f.IncludeSchemas) == 0 || matches(f.IncludeSchemas)) && !matches(f.ExcludeSchemas)
+}
+
+// Table reports whether the filter includes t.
+func (f *ObjectFilter) Table(t *Table) bool {
+
+	return f.Schema(t.Schema) && (f.ExtensionObjects || t.Extension == "")
+}
+
+// raw reports whether the filter includes a raw statement. Only views and
+// the statements on tables, such as rules, belong to a schema.
+func (f *ObjectFilter) raw(raw *RawStatement) bool {
+
+	if raw.Extension != "" && !f.ExtensionObjects {
+		return false
+	}
+	if raw.Table != nil {
+		return f.Table(raw.Table)
+	}
+	if schema, _, ok := strings.Cut(raw.Name, "."); ok && raw.Kind == "CREATE VIEW" {
+		return f.Schema(schema)
+	}
+	return true
+}
+
+// Apply returns the part of cat the filter includes. The catalog returned
+// shares its objects with cat, so it's frozen, though the tables it shares
+// are only frozen once cat is.
+func (f *ObjectFilter) Apply(cat *Catalog) *Catalog {
+
+	deps := *cat.Depends
+	deps.frozen = true
+	ret := &Catalog{
+		Schemas:       collections.NewOrderedMap[string, *Schema](),
+		Depends:       &deps,
+		EventTriggers: cat.EventTriggers,
+		Descriptions:  maps.Clone(cat.Descriptions),
+		lastOID:       cat.lastOID,
+		freezable:     freezable{frozen: true},
+	}
+	for _, sch := range cat.Schemas.List() {
+		if !f.Schema(sch.Name) {
+			continue
+		}
+		filtered := *sch
+		filtered.frozen = true
+		filtered.Tables = collections.NewOrderedMap[string, *Table]()
+		for _, t := range sch.Tables.List() {
+			if f.Table(t) {
+				filtered.Tables.Add(t.Name, t)
+			}
+		}
+		ret.Schemas.Add(filtered.Name, &filtered)
+		if desc, ok := cat.Descriptions[sch]; ok {
+			delete(ret.Descriptions, sch)
+			ret.Descriptions[&filtered] = desc
+		}
+	}
+	for _, raw := range cat.Raw {
+		if f.raw(raw) {
+			ret.Raw = append(ret.Raw, raw)
+		}
+	}
+	return ret
+}
+
+// filterFlags registers the flags configuring an ObjectFilter on fs.
+func filterFlags(fs *flag.FlagSet) *ObjectFilter {
+
+	f := &ObjectFilter{}
+	fs.Func("include-schema", "compare only schemas matching the glob, which may be given more than once", func(s string) error {
+		f.IncludeSchemas = append(f.IncludeSchemas, s)
+		return nil
+	})
+	fs.Func("exclude-schema", "don't compare schemas matching the glob, which may be given more than once", func(s string) error {
+		f.ExcludeSchemas = append(f.ExcludeSchemas, s)
+		return nil
+	})
+	fs.BoolVar(&f.ExtensionObjects, "include-extension-objects", false, "compare the tables and views belonging to extensions")
+	fs.BoolVar(&f.SystemSchemas, "include-system-schemas", false, "compare pg_catalog, information_schema and other schemas of Postgres itself")
+	return f
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_ExtensionMembers(t *testing.T) {
+	c := assertParse(t, `
+	CREATE EXTENSION postgis;
+	CREATE TABLE spatial_ref_sys (srid int PRIMARY KEY, auth_name text);
+	CREATE VIEW geometry_columns AS SELECT srid FROM spatial_ref_sys;
+	ALTER EXTENSION postgis ADD TABLE spatial_ref_sys;
+	ALTER EXTENSION postgis ADD VIEW geometry_columns;
+	ALTER EXTENSION postgis ADD FUNCTION st_area(geometry);
+	CREATE TABLE places (id int, srid int REFERENCES spatial_ref_sys (srid));
+	`)
+	assert.Equal(t, "postgis", assertTable(t, c, "spatial_ref_sys").Extension)
+	assert.Equal(t, "postgis", c.findOpaque("CREATE VIEW", "public.geometry_columns").Extension)
+	assert.Equal(t, "", assertTable(t, c, "places").Extension)
+
+	assert.ErrorContains(t, c.Compile(`ALTER EXTENSION postgis ADD TABLE spatial_ref_sys`), "spatial_ref_sys is already a member of extension postgis")
+	assert.ErrorContains(t, c.Compile(`ALTER EXTENSION postgis DROP TABLE places`), "places is not a member of extension postgis")
+	assert.ErrorContains(t, c.Compile(`ALTER EXTENSION hstore ADD TABLE places`), "extension hstore not found")
+	assert.ErrorContains(t, c.Compile(`DROP EXTENSION postgis`), "can't drop extension postgis because other objects depend on it")
+
+	require.Nil(t, c.Compile(`DROP EXTENSION postgis CASCADE`))
+	sch, _ := c.Catalog.Schemas.Get("public")
+	assert.Len(t, sch.Tables.List(), 1)
+	assert.Nil(t, c.findOpaque("CREATE VIEW", "public.geometry_columns"))
+	assert.Empty(t, c.Catalog.Depends.ConstraintsByName)
+	assert.NotContains(t, c.Extensions, "postgis")
+
+	require.Nil(t, c.Compile(`DROP EXTENSION IF EXISTS postgis`))
+	assert.ErrorContains(t, c.Compile(`DROP EXTENSION postgis`), "extension postgis not found")
+}
+
+func TestObjectFilter(t *testing.T) {
+	c := assertParse(t, `
+	CREATE EXTENSION postgis;
+	CREATE SCHEMA app;
+	CREATE SCHEMA app_archive;
+	CREATE SCHEMA reporting;
+	CREATE TABLE spatial_ref_sys (srid int PRIMARY KEY);
+	ALTER EXTENSION postgis ADD TABLE spatial_ref_sys;
+	CREATE TABLE app.users (id int);
+	CREATE TABLE app_archive.users (id int);
+	CREATE TABLE reporting.totals (n int);
+	CREATE VIEW reporting.user_count AS SELECT count(*) FROM app.users;
+	COMMENT ON SCHEMA app IS 'the application';
+	`)
+	tables := func(cat *Catalog) []string {
+		var names []string
+		for _, sch := range cat.Schemas.List() {
+			for _, t := range sch.Tables.List() {
+				names = append(names, t.Schema+"."+t.Name)
+			}
+		}
+		return names
+	}
+
+	filtered := (&ObjectFilter{}).Apply(c.Catalog)
+	assert.Equal(t, []string{"app.users", "app_archive.users", "reporting.totals"}, tables(filtered))
+	assert.Len(t, filtered.Raw, 1)
+	filtered = (&ObjectFilter{ExtensionObjects: true}).Apply(c.Catalog)
+	assert.Contains(t, tables(filtered), "public.spatial_ref_sys")
+
+	filtered = (&ObjectFilter{IncludeSchemas: []string{"app*"}, ExcludeSchemas: []string{"*_archive"}}).Apply(c.Catalog)
+	assert.Equal(t, []string{"app.users"}, tables(filtered))
+	assert.Empty(t, filtered.Raw)
+	app, _ := filtered.Schemas.Get("app")
+	assert.Equal(t, "the application", filtered.Descriptions[app].Comment)
+	assert.Len(t, filtered.Descriptions, 1)
+	// The catalog filtered is left alone
+	assert.Len(t, tables(c.Catalog), 4)
+	// and can still be modified, though the filtered catalog can't be
+	assert.True(t, filtered.Frozen())
+	assert.PanicsWithValue(t, ErrFrozen, func() { app.AddTable(NewTable("t", "app")) })
+	assert.PanicsWithValue(t, ErrFrozen, func() { filtered.RemoveRaw(func(*RawStatement) bool { return true }) })
+	assert.PanicsWithValue(t, ErrFrozen, func() {
+		filtered.Depends.AddIndex(&Index{Name: "users_id", Table: assertTable(t, c, "app.users")})
+	})
+	require.Nil(t, c.Compile(`CREATE TABLE app.t (a int)`))
+
+	f := &ObjectFilter{}
+	assert.False(t, f.Schema("pg_catalog"))
+	assert.False(t, f.Schema("pg_temp_3"))
+	assert.True(t, f.Schema("pg_partman"))
+	f.SystemSchemas = true
+	assert.True(t, f.Schema("information_schema"))
+}
+
+func TestDiff_Filter(t *testing.T) {
+	from := assertParse(t, `
+	CREATE EXTENSION postgis;
+	CREATE SCHEMA partner;
+	CREATE TABLE users (id int);
+	CREATE TABLE partner.feed (id int);
+	`)
+	to := assertParse(t, `
+	CREATE EXTENSION postgis;
+	CREATE TABLE users (id int, name text);
+	CREATE TABLE spatial_ref_sys (srid int);
+	ALTER EXTENSION postgis ADD TABLE spatial_ref_sys;
+	`)
+	assert.Equal(t, []string{
+		"ALTER TABLE users ADD COLUMN name text;",
+	}, Diff(from.Catalog, to.Catalog, DiffOptions{Filter: &ObjectFilter{ExcludeSchemas: []string{"partner"}}}).SQL())
+	assert.Len(t, Diff(from.Catalog, to.Catalog, DiffOptions{}), 3)
+}
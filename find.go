@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path"
+)
+
+// ColumnPredicate selects columns for FindColumns.
+type ColumnPredicate func(*Column) bool
+
+// ColumnNamed matches columns whose name matches the glob pattern, e.g.
+// "*_id".
+func ColumnNamed(pattern string) ColumnPredicate {
+
+	return func(col *Column) bool {
+		ok, _ := path.Match(pattern, col.Name)
+		return ok
+	}
+}
+
+// ColumnInTable matches columns of tables matching the glob pattern, which
+// is matched against the table's name qualified by its schema if it has a
+// ".", and its bare name otherwise.
+func ColumnInTable(pattern string) ColumnPredicate {
+
+	return func(col *Column) bool {
+		name := col.Table.Name
+		if ok, _ := path.Match("*.*", pattern); ok {
+			name = col.Table.Schema + "." + col.Table.Name
+		}
+		ok, _ := path.Match(pattern, name)
+		return ok
+	}
+}
+
+// ColumnOfType matches columns of type t, whatever their modifiers or
+// array dimensions.
+func ColumnOfType(t *PostgresType) ColumnPredicate {
+
+	return func(col *Column) bool {
+		return col.Type == t
+	}
+}
+
+// ColumnNullable matches columns that are nullable, or that aren't if
+// nullable is false.
+func ColumnNullable(nullable bool) ColumnPredicate {
+
+	return func(col *Column) bool {
+		return !(col.Attrs.NotNull || col.Attrs.Pkey) == nullable
+	}
+}
+
+// NotColumn matches the columns pred doesn't.
+func NotColumn(pred ColumnPredicate) ColumnPredicate {
+
+	return func(col *Column) bool {
+		return !pred(col)
+	}
+}
+
+// FindColumns returns the columns of the catalog matching all of preds, in
+// the order of their schemas and tables.
+func FindColumns(cat *Catalog, preds ...ColumnPredicate) []*Column {
+
+	var ret []*Column
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+		columns:
+			for _, col := range t.Columns.List() {
+				for _, pred := range preds {
+					if !pred(col) {
+						continue columns
+					}
+				}
+				ret = append(ret, col)
+			}
+		}
+	}
+	return ret
+}
+
+// FoundColumn is a column found by the find command.
+type FoundColumn struct {
+	OID      OID    `json:"oid"`
+	Table    string `json:"table"`
+	Column   string `json:"column"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	// Class is the column's classification, if it has one.
+	Class string `json:"class,omitempty"`
+}
+
+func runFind(args []string) error {
+
+	fs := flag.NewFlagSet("find", flag.ExitOnError)
+	column := fs.String("column", "", "glob the column names must match, e.g. '*_id'")
+	table := fs.String("table", "", "glob the table names must match, qualified by schema if it has a '.'")
+	typ := fs.String("type", "", "type the columns must have")
+	notType := fs.String("not-type", "", "type the columns must not have")
+	nullable := fs.Bool("nullable", false, "only find nullable columns")
+	notNull := fs.Bool("not-null", false, "only find NOT NULL columns")
+	fail := fs.Bool("fail", false, "exit with an error if any columns are found")
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *nullable && *notNull {
+		return fmt.Errorf("-nullable and -not-null can't both be given")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+
+	var preds []ColumnPredicate
+	if *column != "" {
+		preds = append(preds, ColumnNamed(*column))
+	}
+	if *table != "" {
+		preds = append(preds, ColumnInTable(*table))
+	}
+	if *typ != "" {
+		t := LookupType(*typ)
+		if t == nil {
+			return fmt.Errorf("unknown type %q", *typ)
+		}
+		preds = append(preds, ColumnOfType(t))
+	}
+	if *notType != "" {
+		t := LookupType(*notType)
+		if t == nil {
+			return fmt.Errorf("unknown type %q", *notType)
+		}
+		preds = append(preds, NotColumn(ColumnOfType(t)))
+	}
+	if *nullable || *notNull {
+		preds = append(preds, ColumnNullable(*nullable))
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	found := []FoundColumn{}
+	for _, col := range FindColumns(c.Catalog, preds...) {
+		found = append(found, FoundColumn{
+			OID:      col.OID,
+			Table:    col.Table.Schema + "." + col.Table.Name,
+			Column:   col.Name,
+			Type:     col.FormatType(),
+			Nullable: ColumnNullable(true)(col),
+			Class:    col.Classification.Class,
+		})
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(found)
+	} else {
+		bw := bufio.NewWriter(w)
+		for _, col := range found {
+			fmt.Fprintf(bw, "%s.%s %s\n", col.Table, col.Column, col.Type)
+		}
+		err = bw.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if *fail && len(found) > 0 {
+		return fmt.Errorf("found %d columns", len(found))
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFindColumns(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE SCHEMA audit;
+CREATE TABLE users (id uuid PRIMARY KEY, team_id uuid, created_at timestamptz NOT NULL, updated_at timestamp);
+CREATE TABLE posts (id int PRIMARY KEY, author_id uuid NOT NULL, published_at timestamp(3));
+CREATE TABLE audit.events (id bigint PRIMARY KEY, user_id int, at timestamptz);
+`))
+	names := func(cols []*Column) []string {
+		var ret []string
+		for _, col := range cols {
+			ret = append(ret, col.Table.Schema+"."+col.Table.Name+"."+col.Name)
+		}
+		return ret
+	}
+
+	assert.Equal(t, []string{"public.users.team_id", "public.posts.author_id"},
+		names(FindColumns(c.Catalog, ColumnNamed("*_id"), ColumnOfType(UUID))))
+	// Every column ending in _at must be timestamptz
+	assert.Equal(t, []string{"public.users.updated_at", "public.posts.published_at"},
+		names(FindColumns(c.Catalog, ColumnNamed("*_at"), NotColumn(ColumnOfType(Timestamptz)))))
+	assert.Equal(t, []string{"audit.events.id", "audit.events.user_id", "audit.events.at"},
+		names(FindColumns(c.Catalog, ColumnInTable("audit.*"))))
+	assert.Equal(t, []string{"public.posts.author_id"},
+		names(FindColumns(c.Catalog, ColumnInTable("posts"), ColumnNullable(false), ColumnNamed("*_id"))))
+	assert.Len(t, FindColumns(c.Catalog, ColumnInTable("events")), 3)
+	assert.Empty(t, FindColumns(c.Catalog, ColumnInTable("public.events")))
+	assert.Len(t, FindColumns(c.Catalog), 10)
+
+	assert.Equal(t, Timestamptz, LookupType("TIMESTAMPTZ"))
+	assert.Nil(t, LookupType("widget"))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/samber/lo"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Fingerprints hash the canonical DDL of objects rather than the statements
+// which created them, so they don't change with formatting or with how the
+// schema was built up. Column order is significant, as it is to Postgres,
+// but the order tables, schemas and event triggers were created in isn't.
+// Annotations are included, since generated code depends on them.
+
+// TableFingerprint returns a hex encoded hash of t, its constraints,
+// indexes and statistics, and their annotations.
+func TableFingerprint(cat *Catalog, t *Table) string {
+
+	lines := []string{"table " + TableIdent(t), "annotations " + formatAnnotations(t.Annotations)}
+	for _, col := range t.Columns.List() {
+		lines = append(lines, "column "+ColumnDefinition(col), "annotations "+formatAnnotations(col.Annotations))
+	}
+	for _, con := range cat.Depends.TableConstraints(t) {
+		lines = append(lines, ConstraintDefinition(con))
+	}
+	for _, idx := range cat.Depends.TableIndexes(t) {
+		lines = append(lines, IndexDefinition(idx))
+	}
+	if t.ReplicaIdentity != ReplicaIdentityDefault {
+		lines = append(lines, ReplicaIdentityDefinition(t))
+	}
+	if len(t.Options) > 0 {
+		lines = append(lines, "with"+storageOptionsClause(t.Options))
+	}
+	if t.Unlogged {
+		lines = append(lines, "unlogged")
+	}
+	for _, s := range cat.Depends.TableStatistics(t) {
+		lines = append(lines, StatisticsDefinition(s))
+	}
+	return hashLines(lines)
+}
+
+// CatalogFingerprint returns a hex encoded hash of everything in cat.
+func CatalogFingerprint(cat *Catalog) string {
+
+	var lines, tables []string
+	for _, sch := range cat.Schemas.List() {
+		lines = append(lines, "schema "+QuoteIdent(sch.Name))
+		for _, e := range sch.Enums.List() {
+			lines = append(lines, "enum "+EnumDefinition(e))
+		}
+		for _, tab := range sch.Tables.List() {
+			tables = append(tables, TableIdent(tab)+" "+TableFingerprint(cat, tab))
+		}
+	}
+	slices.Sort(lines)
+	slices.Sort(tables)
+	lines = append(lines, tables...)
+	// Raw statements may depend on each other, so their order matters
+	for _, raw := range cat.Raw {
+		lines = append(lines, "raw "+raw.SQL)
+	}
+	var triggers []string
+	for _, trig := range cat.EventTriggers.List() {
+		triggers = append(triggers, strings.Join(EventTriggerDefinition(trig), "; "))
+	}
+	slices.Sort(triggers)
+	return hashLines(append(lines, triggers...))
+}
+
+// hashLines hashes lines, each of which may itself contain newlines.
+func hashLines(lines []string) string {
+
+	h := sha256.New()
+	for _, line := range lines {
+		// Prefixing the length means lines can't run into each other
+		fmt.Fprintf(h, "%d:%s\n", len(line), line)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func formatAnnotations(a Annotations) string {
+
+	keys := lo.Keys(a)
+	slices.Sort(keys)
+	return strings.Join(lo.Map(keys, func(k string, _ int) string {
+		return k + "=" + a[k]
+	}), " ")
+}
+
+// Fingerprints are the fingerprints of a catalog and each of its tables,
+// keyed by schema qualified name.
+type Fingerprints struct {
+	Catalog string            `json:"catalog"`
+	Tables  map[string]string `json:"tables,omitempty"`
+}
+
+func runFingerprint(args []string) error {
+
+	fs := flag.NewFlagSet("fingerprint", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	tables := fs.Bool("tables", false, "also fingerprint each table")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	fps := Fingerprints{Catalog: CatalogFingerprint(c.Catalog)}
+	var names []string
+	if *tables {
+		fps.Tables = make(map[string]string)
+		for _, sch := range c.Catalog.Schemas.List() {
+			for _, tab := range sch.Tables.List() {
+				name := tab.Schema + "." + tab.Name
+				fps.Tables[name] = TableFingerprint(c.Catalog, tab)
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(fps)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s\n", fps.Catalog)
+	for _, name := range names {
+		fmt.Fprintf(bw, "%s  %s\n", fps.Tables[name], name)
+	}
+	return bw.Flush()
+}
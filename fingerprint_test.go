@@ -0,0 +1,65 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	compile := func(sql string) *Compiler {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c
+	}
+
+	base := compile(`
+CREATE TABLE users (id int PRIMARY KEY, email text);
+CREATE INDEX users_email ON users (email);
+CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));
+`)
+	// The same schema, formatted differently and built up in another order
+	same := compile(`
+create table posts (id integer primary key);
+CREATE TABLE users (
+    id int4 PRIMARY KEY
+);
+ALTER TABLE users ADD COLUMN email TEXT;
+create index users_email on users(email);
+ALTER TABLE posts ADD COLUMN author int, ADD FOREIGN KEY (author) REFERENCES users (id);
+`)
+	assert.Equal(t, CatalogFingerprint(base.Catalog), CatalogFingerprint(same.Catalog))
+	assert.Equal(t,
+		TableFingerprint(base.Catalog, assertTable(t, base, "public.users")),
+		TableFingerprint(same.Catalog, assertTable(t, same, "public.users")))
+
+	for name, sql := range map[string]string{
+		"column order": `
+CREATE TABLE users (email text, id int PRIMARY KEY);
+CREATE INDEX users_email ON users (email);
+CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));
+`,
+		"index": `
+CREATE TABLE users (id int PRIMARY KEY, email text);
+CREATE UNIQUE INDEX users_email ON users (email);
+CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));
+`,
+		"annotation": `
+CREATE TABLE users (id int PRIMARY KEY, email text); -- @pii
+CREATE INDEX users_email ON users (email);
+CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));
+`,
+	} {
+		other := NewCompiler()
+		require.Nil(t, other.Compile(sql))
+		assert.NotEqual(t, CatalogFingerprint(base.Catalog), CatalogFingerprint(other.Catalog), name)
+		assert.NotEqual(t,
+			TableFingerprint(base.Catalog, assertTable(t, base, "public.users")),
+			TableFingerprint(other.Catalog, assertTable(t, other, "public.users")), name)
+		// Other tables are unaffected
+		assert.Equal(t,
+			TableFingerprint(base.Catalog, assertTable(t, base, "public.posts")),
+			TableFingerprint(other.Catalog, assertTable(t, other, "public.posts")), name)
+	}
+}
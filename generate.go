@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"github.com/samber/lo"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Generator writes a representation of the catalog to w.
+type Generator interface {
+	Generate(w io.Writer, cat *Catalog) error
+}
+
+// generators maps the name of a generate target to a constructor.
+// Constructors may register target-specific flags on fs; the flag
+// names should be prefixed with the target name.
+var generators = map[string]func(fs *flag.FlagSet) Generator{
+	"anon":        NewAnonGenerator,
+	"atlas":       NewAtlasGenerator,
+	"crud":        NewCRUDGenerator,
+	"csv":         NewCSVGenerator,
+	"d2":          NewD2Generator,
+	"django":      NewDjangoGenerator,
+	"go":          NewGoGenerator,
+	"hasura":      NewHasuraGenerator,
+	"jpa":         NewJPAGenerator,
+	"openlineage": NewOpenLineageGenerator,
+	"pgtap":       NewPgTAPGenerator,
+	"plantuml":    NewPlantUMLGenerator,
+	"proto":       NewProtoGenerator,
+	"rails":       NewRailsGenerator,
+	"seed":        NewSeedGenerator,
+	"sql":         NewDDLGenerator,
+	"sqlc":        NewSqlcGenerator,
+	"tsv":         NewTSVGenerator,
+	"zod":         NewZodGenerator,
+}
+
+func runGenerate(args []string) error {
+
+	fs := flag.NewFlagSet("generate", flag.ExitOnError)
+	names := lo.Keys(generators)
+	slices.Sort(names)
+	target := fs.String("target", "", "what to generate, one of: "+strings.Join(names, ", "))
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	watch := fs.Bool("watch", false, "generate again whenever the input files change")
+	run := compilerRunner(fs)
+	gens := make(map[string]Generator, len(generators))
+	for name, ctor := range generators {
+		gens[name] = ctor(fs)
+	}
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	gen, ok := gens[*target]
+	if !ok {
+		return fmt.Errorf("unknown generate target %q", *target)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+
+	cache := NewParseCache()
+	generate := func() error {
+		c, err := run(func(c *Compiler) error {
+			c.ParseCache = cache
+			return c.CompileFiles(fs.Args())
+		})
+		if err != nil {
+			return err
+		}
+		c.Catalog.Freeze()
+		// Output is only written once it's complete, so that a failure
+		// while watching leaves the last output in place
+		var buf bytes.Buffer
+		err = gen.Generate(&buf, c.Catalog)
+		if err != nil {
+			return err
+		}
+		if *out == "" {
+			_, err = buf.WriteTo(os.Stdout)
+			return err
+		}
+		return os.WriteFile(*out, buf.Bytes(), 0o644)
+	}
+	err = generate()
+	if !*watch {
+		return err
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+	}
+	Watch(fs.Args(), watchInterval, nil, func() {
+		err := generate()
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "error:", err)
+			return
+		}
+		fmt.Fprintln(os.Stderr, "generated", time.Now().Format(time.TimeOnly))
+	})
+	return nil
+}
@@ -0,0 +1,554 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/format"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type GoFlavor string
+
+const (
+	// GoFlavorPlain generates structs with db tags, suitable for sqlx
+	// and similar libraries.
+	GoFlavorPlain GoFlavor = "plain"
+	// GoFlavorGorm generates GORM models with gorm tags, association
+	// fields derived from foreign keys and TableName methods.
+	GoFlavorGorm GoFlavor = "gorm"
+)
+
+type GoNullable string
+
+const (
+	GoNullablePointer GoNullable = "pointer"
+	GoNullableSqlNull GoNullable = "sqlnull"
+)
+
+// GoGenerator writes a Go struct for each table in the catalog, and a
+// string type with a constant for each value of the catalog's ValueSets,
+// which is the type of the columns limited to them.
+type GoGenerator struct {
+	Package  string
+	Flavor   GoFlavor
+	Nullable GoNullable
+	// Validate adds validate tags for github.com/go-playground/validator,
+	// checking what the database would: the lengths of strings, the
+	// values of enums and the parts of check constraints which CheckRules
+	// understands. Values of the database/sql nullable types are only
+	// validated once a custom type func is registered for them.
+	Validate bool
+}
+
+func NewGoGenerator(fs *flag.FlagSet) Generator {
+
+	g := &GoGenerator{}
+	fs.StringVar(&g.Package, "go-package", "models", "package name of the generated Go file")
+	fs.Func("go-flavor", "kind of Go structs to generate, plain or gorm (default plain)", func(s string) error {
+		switch f := GoFlavor(s); f {
+		case GoFlavorPlain, GoFlavorGorm:
+			g.Flavor = f
+			return nil
+		}
+		return fmt.Errorf("unknown flavor %q", s)
+	})
+	fs.Func("go-null", "representation of nullable columns, pointer or sqlnull (default pointer)", func(s string) error {
+		switch n := GoNullable(s); n {
+		case GoNullablePointer, GoNullableSqlNull:
+			g.Nullable = n
+			return nil
+		}
+		return fmt.Errorf("unknown nullable representation %q", s)
+	})
+	fs.BoolVar(&g.Validate, "go-validate", false, "add validate tags checking the lengths, enum values and check constraints of columns")
+	g.Flavor = GoFlavorPlain
+	g.Nullable = GoNullablePointer
+	return g
+}
+
+// goTypes maps Postgres types to the Go type used for a non-null column.
+// Types not present are represented as strings.
+var goTypes = map[*PostgresType]string{
+	Bigint:      "int64",
+	Bigserial:   "int64",
+	Boolean:     "bool",
+	Bytea:       "[]byte",
+	Date:        "time.Time",
+	Double:      "float64",
+	Integer:     "int32",
+	JSON:        "json.RawMessage",
+	JSONB:       "json.RawMessage",
+	Real:        "float32",
+	Serial:      "int32",
+	Smallint:    "int16",
+	Smallserial: "int16",
+	Timestamp:   "time.Time",
+	Timestamptz: "time.Time",
+}
+
+// goSqlNullTypes maps Go types to their database/sql nullable wrapper.
+var goSqlNullTypes = map[string]string{
+	"bool":      "sql.NullBool",
+	"float64":   "sql.NullFloat64",
+	"int16":     "sql.NullInt16",
+	"int32":     "sql.NullInt32",
+	"int64":     "sql.NullInt64",
+	"string":    "sql.NullString",
+	"time.Time": "sql.NullTime",
+}
+
+type goStruct struct {
+	Name   string
+	Table  *Table
+	Fields []*goField
+}
+
+type goField struct {
+	Name string
+	Type string
+	Tags []string
+}
+
+func (s *goStruct) hasField(name string) bool {
+
+	return slices.ContainsFunc(s.Fields, func(f *goField) bool {
+		return f.Name == name
+	})
+}
+
+func (g *GoGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	sets := cat.ValueSets()
+	structNames := make(map[string]bool)
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			structNames[goStructName(tab)] = true
+		}
+	}
+	setTypes := make(map[*ValueSet]string)
+	for _, set := range sets {
+		name := goIdent(set.Name)
+		if set.Schema != "public" {
+			name = goIdent(set.Schema) + name
+		}
+		if structNames[name] {
+			name += "Enum"
+		}
+		setTypes[set] = name
+	}
+
+	var structs []*goStruct
+	byTable := make(map[*Table]*goStruct)
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			s := &goStruct{Name: goStructName(tab), Table: tab}
+			for _, col := range tab.Columns.List() {
+				s.Fields = append(s.Fields, g.columnField(cat, col, setTypes[sets.Of(col)]))
+			}
+			structs = append(structs, s)
+			byTable[tab] = s
+		}
+	}
+	if g.Flavor == GoFlavorGorm {
+		for _, s := range structs {
+			for _, rel := range cat.Relationships(s.Table) {
+				switch rel.Kind {
+				case RelationshipBelongsTo:
+					g.addAssociation(s, byTable[rel.Target], rel)
+				case RelationshipManyToMany:
+					g.addMany2Many(s, byTable[rel.Target], rel)
+				}
+			}
+		}
+	}
+
+	var imports []string
+	for _, s := range structs {
+		for _, f := range s.Fields {
+			for _, pkg := range []string{"database/sql", "encoding/json", "time"} {
+				if strings.Contains(f.Type, pkg[strings.LastIndex(pkg, "/")+1:]+".") && !slices.Contains(imports, pkg) {
+					imports = append(imports, pkg)
+				}
+			}
+		}
+	}
+	slices.Sort(imports)
+
+	var sb strings.Builder
+	for _, set := range sets {
+		name := setTypes[set]
+		fmt.Fprintf(&sb, "type %s string\n\n", name)
+		if len(set.Values) == 0 {
+			continue
+		}
+		fmt.Fprintln(&sb, "const (")
+		seen := make(map[string]bool)
+		for _, v := range set.Values {
+			constName := name + goIdent(v)
+			for seen[constName] {
+				constName += "_"
+			}
+			seen[constName] = true
+			fmt.Fprintf(&sb, "\t%s %s = %q\n", constName, name, v)
+		}
+		fmt.Fprintln(&sb, ")")
+		fmt.Fprintln(&sb)
+	}
+	for _, s := range structs {
+		// Neither the fields' order nor gorm's tags give the order of a
+		// composite key's columns
+		if pk := cat.Depends.PrimaryKey(s.Table); pk != nil && len(pk.Constrains) > 1 {
+			fields := make([]string, 0, len(pk.Constrains))
+			for _, col := range pk.Constrains {
+				fields = append(fields, goIdent(col.Name))
+			}
+			fmt.Fprintf(&sb, "// %s has the primary key (%s).\n", s.Name, strings.Join(fields, ", "))
+		}
+		fmt.Fprintf(&sb, "type %s struct {\n", s.Name)
+		for _, f := range s.Fields {
+			fmt.Fprintf(&sb, "\t%s %s", f.Name, f.Type)
+			if len(f.Tags) > 0 {
+				fmt.Fprintf(&sb, " `%s`", strings.Join(f.Tags, " "))
+			}
+			fmt.Fprintln(&sb)
+		}
+		fmt.Fprintln(&sb, "}")
+		fmt.Fprintln(&sb)
+		if g.Flavor == GoFlavorGorm {
+			fmt.Fprintf(&sb, "func (%s) TableName() string {\n\treturn %q\n}\n\n", s.Name, goTableName(s.Table))
+		}
+	}
+
+	var src strings.Builder
+	fmt.Fprintf(&src, "// Code generated by pgmodelgen. DO NOT EDIT.\n\npackage %s\n\n", g.Package)
+	if len(imports) > 0 {
+		fmt.Fprintln(&src, "import (")
+		for _, pkg := range imports {
+			fmt.Fprintf(&src, "\t%q\n", pkg)
+		}
+		fmt.Fprintln(&src, ")")
+		fmt.Fprintln(&src)
+	}
+	src.WriteString(sb.String())
+
+	formatted, err := format.Source([]byte(src.String()))
+	if err != nil {
+		return fmt.Errorf("while formatting generated code: %w", err)
+	}
+	_, err = w.Write(formatted)
+	return err
+}
+
+// columnField returns the field of col, which is of type setType if the
+// column is limited to a ValueSet.
+func (g *GoGenerator) columnField(cat *Catalog, col *Column, setType string) *goField {
+
+	typ, ok := goTypes[col.Type]
+	if !ok {
+		typ = "string"
+	}
+	if setType != "" {
+		typ = setType
+	}
+	if col.ArrayDims > 0 {
+		typ = strings.Repeat("[]", col.ArrayDims) + typ
+	} else if !col.Attrs.NotNull && !col.Attrs.Pkey && !strings.HasPrefix(typ, "[]") && typ != "json.RawMessage" {
+		if g.Nullable == GoNullableSqlNull {
+			if nt, ok := goSqlNullTypes[typ]; ok {
+				typ = nt
+			} else {
+				typ = "sql.Null[" + typ + "]"
+			}
+		} else {
+			typ = "*" + typ
+		}
+	}
+
+	f := &goField{Name: goIdent(col.Name), Type: typ}
+	switch g.Flavor {
+	case GoFlavorPlain:
+		f.Tags = append(f.Tags, fmt.Sprintf(`db:"%s"`, col.Name))
+	case GoFlavorGorm:
+		opts := []string{"column:" + col.Name}
+		if col.Attrs.Pkey {
+			opts = append(opts, "primaryKey")
+		}
+		opts = append(opts, "type:"+col.FormatType())
+		if col.Attrs.NotNull {
+			opts = append(opts, "not null")
+		}
+		cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+		for _, con := range cons {
+			if con.Type == ConstraintTypeUnique && slices.Contains(con.Constrains, col) {
+				opts = append(opts, "uniqueIndex:"+con.Name)
+			}
+		}
+		if col.Attrs.Default != "" {
+			opts = append(opts, "default:"+col.Attrs.Default)
+		}
+		f.Tags = append(f.Tags, fmt.Sprintf(`gorm:"%s"`, strings.Join(opts, ";")))
+	}
+	if g.Validate {
+		if rules := goValidateRules(cat, col, typ); len(rules) > 0 {
+			f.Tags = append(f.Tags, fmt.Sprintf(`validate:"%s"`, strings.Join(rules, ",")))
+		}
+	}
+	if class := col.Classification.Class; class != "" {
+		f.Tags = append(f.Tags, fmt.Sprintf(`classification:"%s"`, class))
+	}
+	return f
+}
+
+// goValidateRules returns the validator rules for col, whose field is of
+// type typ. Slices of not null columns are required, as a nil slice would
+// be written as null, and nullable columns are only checked when they're
+// set.
+func goValidateRules(cat *Catalog, col *Column, typ string) []string {
+
+	base, ok := goTypes[col.Type]
+	if !ok {
+		base = "string"
+	}
+	number := slices.Contains([]string{"int16", "int32", "int64", "float32", "float64"}, base)
+	str := base == "string"
+	var oneOf []string
+	for _, sch := range cat.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			if EnumIdent(e) == col.Type.Name {
+				oneOf = e.Labels
+			}
+		}
+	}
+	var low, high checkBound
+	minLength, maxLength := -1, -1
+	if (col.Type == CharacterVarying || col.Type == Character) && len(col.TypeMods) > 0 {
+		maxLength = int(col.TypeMods[0])
+	}
+	// Each of a constraint's rules must hold, so any of them can be
+	// checked on their own
+	cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+	for _, con := range cons {
+		rules, ok := CheckRules(con)
+		if !ok || col.ArrayDims > 0 {
+			continue
+		}
+		for _, rule := range rules {
+			if rule.Column != col {
+				continue
+			}
+			n, _ := strconv.Atoi(rule.Value)
+			switch {
+			case rule.Kind == CheckRuleMin && number:
+				low.tighten(rule.Value, rule.Exclusive, false)
+			case rule.Kind == CheckRuleMax && number:
+				high.tighten(rule.Value, rule.Exclusive, true)
+			case rule.Kind == CheckRuleMinLength && str:
+				minLength = max(minLength, n)
+			case rule.Kind == CheckRuleMaxLength && str && (maxLength < 0 || n < maxLength):
+				maxLength = n
+			case rule.Kind == CheckRuleOneOf && (number || str) && !slices.ContainsFunc(rule.Values, goValidateUnsafe):
+				if oneOf == nil {
+					oneOf = rule.Values
+				} else {
+					oneOf = slices.DeleteFunc(slices.Clone(oneOf), func(v string) bool { return !slices.Contains(rule.Values, v) })
+				}
+			}
+		}
+	}
+
+	var rules []string
+	switch {
+	case strings.HasPrefix(typ, "[]") || typ == "json.RawMessage":
+		if col.Attrs.NotNull && col.Attrs.Default == "" {
+			rules = append(rules, "required")
+		}
+	case !col.Attrs.NotNull && !col.Attrs.Pkey:
+		rules = append(rules, "omitempty")
+	}
+	if oneOf != nil && !slices.ContainsFunc(oneOf, goValidateUnsafe) {
+		values := make([]string, 0, len(oneOf))
+		for _, v := range oneOf {
+			if strings.ContainsAny(v, " \t") || v == "" {
+				v = "'" + v + "'"
+			}
+			values = append(values, v)
+		}
+		rules = append(rules, "oneof="+strings.Join(values, " "))
+	}
+	if op := low.op("gt"); op != "" {
+		rules = append(rules, op+"="+low.value)
+	}
+	if op := high.op("lt"); op != "" {
+		rules = append(rules, op+"="+high.value)
+	}
+	if str && minLength > 0 {
+		rules = append(rules, fmt.Sprintf("min=%d", minLength))
+	}
+	if str && maxLength >= 0 {
+		rules = append(rules, fmt.Sprintf("max=%d", maxLength))
+	}
+	if len(rules) == 1 && rules[0] == "omitempty" {
+		return nil
+	}
+	return rules
+}
+
+// addAssociation adds a BelongsTo field to from and a HasMany field to to
+// for the belongs-to relationship rel, or a HasOne field if rel is
+// one-to-one.
+func (g *GoGenerator) addAssociation(from, to *goStruct, rel *Relationship) {
+
+	con := rel.Constraint
+	fks := make([]string, 0, len(con.Constrains))
+	for _, col := range con.Constrains {
+		fks = append(fks, goIdent(col.Name))
+	}
+	refs := make([]string, 0, len(con.Refers))
+	for _, col := range con.Refers {
+		refs = append(refs, goIdent(col.Name))
+	}
+	tag := fmt.Sprintf(`gorm:"foreignKey:%s;references:%s"`, strings.Join(fks, ","), strings.Join(refs, ","))
+
+	belongsTo := to.Name
+	if len(con.Constrains) == 1 {
+		if name, ok := strings.CutSuffix(con.Constrains[0].Name, "_id"); ok {
+			belongsTo = goIdent(name)
+		}
+	}
+	if from.hasField(belongsTo) {
+		belongsTo += to.Name
+	}
+	from.Fields = append(from.Fields, &goField{Name: belongsTo, Type: "*" + to.Name, Tags: []string{tag}})
+
+	if rel.OneToOne {
+		hasOne := from.Name
+		if belongsTo != to.Name || to.hasField(hasOne) {
+			hasOne = belongsTo + hasOne
+		}
+		to.Fields = append(to.Fields, &goField{Name: hasOne, Type: "*" + from.Name, Tags: []string{tag}})
+		return
+	}
+	hasMany := pluralize(from.Name)
+	if belongsTo != to.Name || to.hasField(hasMany) {
+		hasMany = belongsTo + hasMany
+	}
+	to.Fields = append(to.Fields, &goField{Name: hasMany, Type: "[]" + from.Name, Tags: []string{tag}})
+}
+
+// addMany2Many adds a field to from holding the rows of to it's related to
+// through the join table of rel. The field is named after the join table's
+// column referring to to if it ends in _id, as self-referencing join
+// tables such as friendships (user_id, friend_id) need the two fields
+// telling apart.
+func (g *GoGenerator) addMany2Many(from, to *goStruct, rel *Relationship) {
+
+	names := func(cols Columns) string {
+		idents := make([]string, 0, len(cols))
+		for _, col := range cols {
+			idents = append(idents, goIdent(col.Name))
+		}
+		return strings.Join(idents, ",")
+	}
+	tag := fmt.Sprintf(`gorm:"many2many:%s;foreignKey:%s;joinForeignKey:%s;references:%s;joinReferences:%s"`,
+		goTableName(rel.Through), names(rel.Columns), names(rel.Constraint.Constrains),
+		names(rel.TargetColumns), names(rel.ThroughConstraint.Constrains))
+
+	name := pluralize(to.Name)
+	if cols := rel.ThroughConstraint.Constrains; len(cols) == 1 {
+		if trimmed, ok := strings.CutSuffix(cols[0].Name, "_id"); ok && trimmed != "" {
+			name = pluralize(goIdent(trimmed))
+		}
+	}
+	if from.hasField(name) {
+		name = goStructName(rel.Through) + name
+	}
+	from.Fields = append(from.Fields, &goField{Name: name, Type: "[]" + to.Name, Tags: []string{tag}})
+}
+
+// goValidateUnsafe reports whether v can't be one of the values of a oneof
+// rule, which has no way of escaping them.
+func goValidateUnsafe(v string) bool {
+
+	return strings.ContainsAny(v, ",|'")
+}
+
+func goTableName(t *Table) string {
+
+	if t.Schema == "public" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+func goStructName(t *Table) string {
+
+	name := goIdent(singularize(t.Name))
+	if t.Schema != "public" {
+		name = goIdent(t.Schema) + name
+	}
+	return name
+}
+
+// goInitialisms are words which are written in upper case when they
+// appear in Go identifiers.
+var goInitialisms = map[string]struct{}{
+	"api": {}, "html": {}, "http": {}, "id": {}, "ip": {}, "json": {},
+	"sql": {}, "url": {}, "uri": {}, "uuid": {}, "xml": {},
+}
+
+// goIdent converts a snake_case SQL identifier to an exported Go identifier.
+func goIdent(s string) string {
+
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for _, word := range words {
+		if _, ok := goInitialisms[strings.ToLower(word)]; ok {
+			sb.WriteString(strings.ToUpper(word))
+			continue
+		}
+		runes := []rune(word)
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	ident := sb.String()
+	if ident == "" || unicode.IsDigit([]rune(ident)[0]) {
+		ident = "X" + ident
+	}
+	return ident
+}
+
+// singularize makes a best-effort attempt at converting an English plural
+// to its singular, e.g. for deriving model names from table names.
+func singularize(s string) string {
+
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "shes"),
+		strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "xes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "s") && !strings.HasSuffix(s, "ss") &&
+		!strings.HasSuffix(s, "us") && !strings.HasSuffix(s, "is"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
+// pluralize is the inverse of singularize.
+func pluralize(s string) string {
+
+	switch {
+	case strings.HasSuffix(s, "y") && len(s) > 1 && !strings.ContainsRune("aeiou", rune(s[len(s)-2])):
+		return s[:len(s)-1] + "ies"
+	case strings.HasSuffix(s, "s"), strings.HasSuffix(s, "x"),
+		strings.HasSuffix(s, "ch"), strings.HasSuffix(s, "sh"):
+		return s + "es"
+	}
+	return s + "s"
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+const goModelsSchema = `
+CREATE TABLE users (
+	id SERIAL PRIMARY KEY,
+	username VARCHAR(50) NOT NULL UNIQUE,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE orders (
+	id bigserial primary key,
+	user_id int not null references users(id),
+	approver_id int references users(id)
+);
+`
+
+func TestGoGenerator_Plain(t *testing.T) {
+	c := assertParse(t, goModelsSchema)
+	var sb strings.Builder
+	err := (&GoGenerator{Package: "models", Flavor: GoFlavorPlain, Nullable: GoNullablePointer}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `// Code generated by pgmodelgen. DO NOT EDIT.
+
+package models
+
+import (
+	"time"
+)
+
+type User struct {
+	ID        int32      `+"`db:\"id\"`"+`
+	Username  string     `+"`db:\"username\"`"+`
+	CreatedAt *time.Time `+"`db:\"created_at\"`"+`
+}
+
+type Order struct {
+	ID         int64  `+"`db:\"id\"`"+`
+	UserID     int32  `+"`db:\"user_id\"`"+`
+	ApproverID *int32 `+"`db:\"approver_id\"`"+`
+}
+`, sb.String())
+}
+
+func TestGoGenerator_Gorm(t *testing.T) {
+	c := assertParse(t, goModelsSchema+"CREATE TABLE profiles (user_id int PRIMARY KEY REFERENCES users (id));")
+	var sb strings.Builder
+	err := (&GoGenerator{Package: "models", Flavor: GoFlavorGorm, Nullable: GoNullableSqlNull}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	// Ignore the alignment gofmt applies to struct fields
+	out := regexp.MustCompile("[ \t]+").ReplaceAllString(sb.String(), " ")
+	assert.Contains(t, out, `"database/sql"`)
+	assert.Contains(t, out, "CreatedAt sql.NullTime `gorm:\"column:created_at;type:timestamp without time zone;default:current_timestamp\"`")
+	assert.Contains(t, out, "Username string `gorm:\"column:username;type:character varying(50);not null;uniqueIndex:users_username_key\"`")
+	assert.Contains(t, out, "User *User `gorm:\"foreignKey:UserID;references:ID\"`")
+	assert.Contains(t, out, "Approver *User `gorm:\"foreignKey:ApproverID;references:ID\"`")
+	assert.Contains(t, out, "Orders []Order `gorm:\"foreignKey:UserID;references:ID\"`")
+	assert.Contains(t, out, "ApproverOrders []Order `gorm:\"foreignKey:ApproverID;references:ID\"`")
+	assert.Contains(t, out, "Profile *Profile `gorm:\"foreignKey:UserID;references:ID\"`")
+	assert.Contains(t, out, "func (Order) TableName() string {\n return \"orders\"\n}")
+}
+
+func TestGoGenerator_Validate(t *testing.T) {
+	const sql = `
+	CREATE TYPE mood AS ENUM ('happy', 'very sad');
+	CREATE TABLE users (
+		id int PRIMARY KEY,
+		username varchar(50) NOT NULL CHECK (length(username) > 2 AND username ~ '^[a-z]+$'),
+		age smallint CHECK (age >= 0 AND age < 150),
+		level int NOT NULL CHECK (level IN (1, 2, 3)),
+		mood mood NOT NULL,
+		data bytea NOT NULL,
+		score int CHECK (score > 0 OR score = -1),
+		note text,
+		status text NOT NULL CHECK (status IN ('active', 'banned'))
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&GoGenerator{Package: "models", Flavor: GoFlavorPlain, Nullable: GoNullablePointer, Validate: true}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	out := regexp.MustCompile("[ \t]+").ReplaceAllString(sb.String(), " ")
+	assert.Contains(t, out, "ID int32 `db:\"id\"`\n")
+	assert.Contains(t, out, "Username string `db:\"username\" validate:\"min=3,max=50\"`")
+	assert.Contains(t, out, "Age *int16 `db:\"age\" validate:\"omitempty,gte=0,lt=150\"`")
+	assert.Contains(t, out, "Level int32 `db:\"level\" validate:\"oneof=1 2 3\"`")
+	assert.Contains(t, out, "type Mood string\n\nconst (\n MoodHappy Mood = \"happy\"\n MoodVerySad Mood = \"very sad\"\n)\n")
+	assert.Contains(t, out, "type UserStatus string\n\nconst (\n UserStatusActive UserStatus = \"active\"\n UserStatusBanned UserStatus = \"banned\"\n)\n")
+	assert.Contains(t, out, "Mood Mood `db:\"mood\" validate:\"oneof=happy 'very sad'\"`")
+	assert.Contains(t, out, "Status UserStatus `db:\"status\" validate:\"oneof=active banned\"`")
+	assert.Contains(t, out, "Data []byte `db:\"data\" validate:\"required\"`")
+	assert.Contains(t, out, "Score *int32 `db:\"score\"`\n")
+	assert.Contains(t, out, "Note *string `db:\"note\"`\n")
+}
+
+func TestPostgresType_Format(t *testing.T) {
+	const sql = `
+	CREATE TABLE types (
+		a varchar(50),
+		b numeric(10, 2),
+		c timestamptz(3),
+		d interval day to second(3),
+		e int[][],
+		f character
+	);
+	`
+	c := assertParse(t, sql)
+	tab := assertTable(t, c, "types")
+	expected := map[string]string{
+		"a": "character varying(50)",
+		"b": "numeric(10,2)",
+		"c": "timestamp(3) with time zone",
+		"d": "interval day to second(3)",
+		"e": "integer[][]",
+		"f": "character(1)",
+	}
+	for name, typ := range expected {
+		col, ok := tab.Columns.Get(name)
+		require.True(t, ok)
+		assert.Equal(t, typ, col.FormatType())
+	}
+}
+
+func TestGoGenerator_GormMany2Many(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE groups (id int PRIMARY KEY);
+	CREATE TABLE memberships (
+		user_id int REFERENCES users (id),
+		group_id int REFERENCES groups (id),
+		PRIMARY KEY (user_id, group_id)
+	);
+	CREATE TABLE friendships (
+		user_id int REFERENCES users (id),
+		friend_id int REFERENCES users (id),
+		PRIMARY KEY (user_id, friend_id)
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&GoGenerator{Package: "models", Flavor: GoFlavorGorm, Nullable: GoNullablePointer}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	out := regexp.MustCompile("[ \t]+").ReplaceAllString(sb.String(), " ")
+	assert.Contains(t, out, "// Membership has the primary key (UserID, GroupID).\ntype Membership struct {\n")
+	assert.Contains(t, out, "Groups []Group `gorm:\"many2many:memberships;foreignKey:ID;joinForeignKey:UserID;references:ID;joinReferences:GroupID\"`")
+	assert.Contains(t, out, "Users []User `gorm:\"many2many:memberships;foreignKey:ID;joinForeignKey:GroupID;references:ID;joinReferences:UserID\"`")
+	assert.Contains(t, out, "Friends []User `gorm:\"many2many:friendships;foreignKey:ID;joinForeignKey:UserID;references:ID;joinReferences:FriendID\"`")
+	assert.Contains(t, out, "Users []User `gorm:\"many2many:friendships;foreignKey:ID;joinForeignKey:FriendID;references:ID;joinReferences:UserID\"`")
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	pb "github.com/henges/pgmodelparse/proto/pgmodelgen/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCServer serves the compiler as the CatalogService of
+// proto/pgmodelgen/v1/catalog.proto, answering as the Server's endpoints
+// do. Unlike those, each request says which version of Postgres to target
+// and whether to compile leniently.
+type GRPCServer struct {
+	pb.UnimplementedCatalogServiceServer
+	// Server limits how long compiling a request may take and classifies
+	// the columns of each submission. Its NewCompiler isn't used.
+	Server *Server
+}
+
+// Register registers the service with gs.
+func (s *GRPCServer) Register(gs *grpc.Server) {
+
+	pb.RegisterCatalogServiceServer(gs, s)
+}
+
+func (s *GRPCServer) compile(ctx context.Context, req *pb.CompileRequest) (*Compiler, error) {
+
+	strictness := StrictnessStrict
+	if req.GetLenient() {
+		strictness = StrictnessSkipUnsupported
+	}
+	server := *s.Server
+	server.NewCompiler = func() *Compiler {
+		return NewCompiler(WithTargetVersion(int(req.GetPgVersion())), WithStrictness(strictness))
+	}
+	return server.compile(ctx, req.GetSql())
+}
+
+// compileError returns the INVALID_ARGUMENT error of a compile which
+// failed, with its diagnostics in a LintResponse in the details.
+func compileError(c *Compiler, err error) error {
+
+	st := status.New(codes.InvalidArgument, err.Error())
+	withDiags, detailsErr := st.WithDetails(&pb.LintResponse{Diagnostics: protoDiagnostics(c.Diagnostics(err))})
+	if detailsErr != nil {
+		return st.Err()
+	}
+	return withDiags.Err()
+}
+
+func (s *GRPCServer) Catalog(ctx context.Context, req *pb.CompileRequest) (*pb.CatalogResponse, error) {
+
+	c, err := s.compile(ctx, req)
+	if err != nil {
+		return nil, compileError(c, err)
+	}
+	return &pb.CatalogResponse{Catalog: protoCatalog(NewServedCatalog(c.Catalog)), Diagnostics: protoDiagnostics(c.Diagnostics(nil))}, nil
+}
+
+func (s *GRPCServer) Lint(ctx context.Context, req *pb.CompileRequest) (*pb.LintResponse, error) {
+
+	c, err := s.compile(ctx, req)
+	return &pb.LintResponse{Diagnostics: protoDiagnostics(lintDiagnostics(c, err))}, nil
+}
+
+func (s *GRPCServer) Diff(ctx context.Context, req *pb.DiffRequest) (*pb.DiffResponse, error) {
+
+	opts := DiffOptions{Renames: RenamesConservative, MatchOIDs: req.GetMatchOids()}
+	if r := req.GetRenames(); r != pb.RenameDetection_RENAME_DETECTION_UNSPECIFIED {
+		opts.Renames = RenameDetection(r - 1)
+		if int(opts.Renames) >= len(renameDetectionNames) {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown rename detection %d", r)
+		}
+	}
+	from, err := s.compile(ctx, req.GetFrom())
+	if err != nil {
+		return nil, compileError(from, fmt.Errorf("from: %w", err))
+	}
+	to, err := s.compile(ctx, req.GetTo())
+	if err != nil {
+		return nil, compileError(to, fmt.Errorf("to: %w", err))
+	}
+	resp := &pb.DiffResponse{}
+	for _, change := range Diff(from.Catalog, to.Catalog, opts) {
+		safety, reason := change.Classify()
+		resp.Changes = append(resp.Changes, &pb.Change{
+			Change: change.String(),
+			Safety: pb.Safety(safety + 1),
+			Reason: reason,
+			Sql:    change.SQL(),
+		})
+	}
+	return resp, nil
+}
+
+func protoCatalog(cat *ServedCatalog) *pb.Catalog {
+
+	ret := &pb.Catalog{}
+	definitions := func(defs []ServedDefinition) []*pb.Definition {
+		var ret []*pb.Definition
+		for _, def := range defs {
+			ret = append(ret, &pb.Definition{Oid: uint32(def.OID), Name: def.Name, Definition: def.Definition})
+		}
+		return ret
+	}
+	for _, sch := range cat.Schemas {
+		schema := &pb.Schema{Name: sch.Name}
+		for _, t := range sch.Tables {
+			table := &pb.Table{
+				Oid:             uint32(t.OID),
+				Name:            t.Name,
+				Constraints:     definitions(t.Constraints),
+				Indexes:         definitions(t.Indexes),
+				ReplicaIdentity: t.ReplicaIdentity,
+			}
+			for _, col := range t.Columns {
+				table.Columns = append(table.Columns, &pb.Column{
+					Oid:      uint32(col.OID),
+					Name:     col.Name,
+					Type:     col.Type,
+					Nullable: col.Nullable,
+					Default:  col.Default,
+				})
+			}
+			schema.Tables = append(schema.Tables, table)
+		}
+		ret.Schemas = append(ret.Schemas, schema)
+	}
+	return ret
+}
+
+var protoSeverities = map[Severity]pb.Severity{
+	SeverityError:   pb.Severity_SEVERITY_ERROR,
+	SeverityWarning: pb.Severity_SEVERITY_WARNING,
+	SeverityNote:    pb.Severity_SEVERITY_NOTE,
+}
+
+func protoDiagnostics(diags []*Diagnostic) []*pb.Diagnostic {
+
+	var ret []*pb.Diagnostic
+	for _, d := range diags {
+		ret = append(ret, &pb.Diagnostic{
+			File:     d.File,
+			Line:     int32(d.Line),
+			Severity: protoSeverities[d.Severity],
+			Rule:     d.Rule,
+			Message:  d.Message,
+		})
+	}
+	return ret
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	pb "github.com/henges/pgmodelparse/proto/pgmodelgen/v1"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+func TestGRPCServer(t *testing.T) {
+	lis := bufconn.Listen(1 << 20)
+	gs := grpc.NewServer()
+	(&GRPCServer{Server: NewServer()}).Register(gs)
+	go func() { _ = gs.Serve(lis) }()
+	defer gs.Stop()
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(context.Context, string) (net.Conn, error) { return lis.Dial() }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.Nil(t, err)
+	defer conn.Close()
+	client := pb.NewCatalogServiceClient(conn)
+	ctx := context.Background()
+
+	cat, err := client.Catalog(ctx, &pb.CompileRequest{Sql: "CREATE TABLE users (id int PRIMARY KEY, name text);"})
+	require.Nil(t, err)
+	require.Len(t, cat.Catalog.Schemas, 1)
+	users := cat.Catalog.Schemas[0].Tables[0]
+	assert.Equal(t, "users", users.Name)
+	require.Len(t, users.Columns, 2)
+	assert.Equal(t, "integer", users.Columns[0].Type)
+	assert.True(t, users.Columns[1].Nullable)
+	require.Len(t, users.Constraints, 1)
+	assert.Equal(t, "CONSTRAINT users_pkey PRIMARY KEY (id)", users.Constraints[0].Definition)
+
+	_, err = client.Catalog(ctx, &pb.CompileRequest{Sql: "ALTER TABLE missing ADD COLUMN x int;"})
+	st, _ := status.FromError(err)
+	assert.Equal(t, codes.InvalidArgument, st.Code())
+	require.Len(t, st.Details(), 1)
+	diags := st.Details()[0].(*pb.LintResponse).Diagnostics
+	require.Len(t, diags, 1)
+	assert.Equal(t, pb.Severity_SEVERITY_ERROR, diags[0].Severity)
+
+	// Each request says whether to compile leniently
+	const exclusion = "CREATE TABLE t (id int, EXCLUDE USING gist (id WITH =));"
+	_, err = client.Catalog(ctx, &pb.CompileRequest{Sql: exclusion})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	_, err = client.Catalog(ctx, &pb.CompileRequest{Sql: exclusion, Lenient: true})
+	assert.Nil(t, err)
+
+	lint, err := client.Lint(ctx, &pb.CompileRequest{Sql: "CREATE TABLE t (;"})
+	require.Nil(t, err)
+	require.Len(t, lint.Diagnostics, 1)
+	assert.Equal(t, "syntax", lint.Diagnostics[0].Rule)
+
+	diff, err := client.Diff(ctx, &pb.DiffRequest{
+		From:    &pb.CompileRequest{Sql: "CREATE TABLE users (id int, name text);"},
+		To:      &pb.CompileRequest{Sql: "CREATE TABLE users (id int);"},
+		Renames: pb.RenameDetection_RENAME_DETECTION_NONE,
+	})
+	require.Nil(t, err)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "drop column public.users.name", diff.Changes[0].Change)
+	assert.Equal(t, pb.Safety_SAFETY_DESTRUCTIVE, diff.Changes[0].Safety)
+	assert.Equal(t, []string{"ALTER TABLE users DROP COLUMN name;"}, diff.Changes[0].Sql)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// HasuraGenerator writes the tables.yaml of Hasura's metadata, tracking
+// every table and view with the relationships their foreign keys imply: an
+// object relationship on the table with the foreign key, and an array
+// relationship on the table it refers to. Partitions are tracked through
+// their parents, and the objects of extensions aren't tracked.
+type HasuraGenerator struct {
+	// Roles are the roles given a stub select permission on every table,
+	// allowing every column and row, to be narrowed by hand.
+	Roles []string
+}
+
+func NewHasuraGenerator(fs *flag.FlagSet) Generator {
+
+	g := &HasuraGenerator{}
+	fs.Func("hasura-roles", "comma-separated roles to write stub select permissions for", func(s string) error {
+		g.Roles = append(g.Roles, strings.Split(s, ",")...)
+		return nil
+	})
+	return g
+}
+
+// hasuraRelationship is a relationship between tables implied by a foreign
+// key.
+type hasuraRelationship struct {
+	name string
+	con  *Constraint
+}
+
+func (g *HasuraGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	bw := bufio.NewWriter(w)
+	tracked := 0
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf != nil || t.Extension != "" {
+				continue
+			}
+			objects, arrays := hasuraRelationships(cat, t)
+			g.writeTable(bw, t.Schema, t.Name, Columns(t.Columns.List()).Names(), objects, arrays)
+			tracked++
+		}
+	}
+	for _, raw := range cat.Raw {
+		if raw.Kind != "CREATE VIEW" || raw.Extension != "" {
+			continue
+		}
+		schema, name, _ := strings.Cut(raw.Name, ".")
+		cols := make([]string, 0, len(raw.Columns))
+		for _, col := range raw.Columns {
+			cols = append(cols, col.Name)
+		}
+		g.writeTable(bw, schema, name, cols, nil, nil)
+		tracked++
+	}
+	if tracked == 0 {
+		fmt.Fprintln(bw, "[]")
+	}
+	return bw.Flush()
+}
+
+// hasuraRelationships names the relationships of t, which are named after
+// the foreign key's column if it ends in _id, and otherwise after the other
+// table. Names which clash with a column or another relationship are
+// suffixed with the foreign key's columns.
+func hasuraRelationships(cat *Catalog, t *Table) (objects, arrays []hasuraRelationship) {
+
+	taken := make(map[string]bool)
+	for _, col := range t.Columns.List() {
+		taken[col.Name] = true
+	}
+	name := func(base string, con *Constraint) string {
+		if !taken[base] {
+			taken[base] = true
+			return base
+		}
+		name := base + "_by_" + strings.Join(con.Constrains.Names(), "_")
+		for i := 2; taken[name]; i++ {
+			name = base + "_by_" + strings.Join(con.Constrains.Names(), "_") + "_" + strconv.Itoa(i)
+		}
+		taken[name] = true
+		return name
+	}
+	for _, con := range cat.Depends.TableConstraints(t) {
+		if con.Type != ConstraintTypeForeignKey {
+			continue
+		}
+		base := con.Refers[0].Table.Name
+		if len(con.Constrains) == 1 && strings.HasSuffix(con.Constrains[0].Name, "_id") {
+			base = strings.TrimSuffix(con.Constrains[0].Name, "_id")
+		}
+		objects = append(objects, hasuraRelationship{name: name(base, con), con: con})
+	}
+	for _, con := range cat.Depends.ReferencingConstraints(t) {
+		if con.Table.PartitionOf != nil || con.Table.Extension != "" {
+			continue
+		}
+		arrays = append(arrays, hasuraRelationship{name: name(con.Table.Name, con), con: con})
+	}
+	return objects, arrays
+}
+
+func (g *HasuraGenerator) writeTable(w io.Writer, schema, name string, cols []string, objects, arrays []hasuraRelationship) {
+
+	fmt.Fprintf(w, "- table:\n")
+	fmt.Fprintf(w, "    name: %s\n", yamlString(name))
+	fmt.Fprintf(w, "    schema: %s\n", yamlString(schema))
+	if len(objects) > 0 {
+		fmt.Fprintf(w, "  object_relationships:\n")
+		for _, rel := range objects {
+			fmt.Fprintf(w, "    - name: %s\n", yamlString(rel.name))
+			fmt.Fprintf(w, "      using:\n")
+			if len(rel.con.Constrains) == 1 {
+				fmt.Fprintf(w, "        foreign_key_constraint_on: %s\n", yamlString(rel.con.Constrains[0].Name))
+			} else {
+				fmt.Fprintf(w, "        foreign_key_constraint_on:\n")
+				for _, col := range rel.con.Constrains {
+					fmt.Fprintf(w, "          - %s\n", yamlString(col.Name))
+				}
+			}
+		}
+	}
+	if len(arrays) > 0 {
+		fmt.Fprintf(w, "  array_relationships:\n")
+		for _, rel := range arrays {
+			fmt.Fprintf(w, "    - name: %s\n", yamlString(rel.name))
+			fmt.Fprintf(w, "      using:\n")
+			fmt.Fprintf(w, "        foreign_key_constraint_on:\n")
+			if len(rel.con.Constrains) == 1 {
+				fmt.Fprintf(w, "          column: %s\n", yamlString(rel.con.Constrains[0].Name))
+			} else {
+				fmt.Fprintf(w, "          columns:\n")
+				for _, col := range rel.con.Constrains {
+					fmt.Fprintf(w, "            - %s\n", yamlString(col.Name))
+				}
+			}
+			fmt.Fprintf(w, "          table:\n")
+			fmt.Fprintf(w, "            name: %s\n", yamlString(rel.con.Table.Name))
+			fmt.Fprintf(w, "            schema: %s\n", yamlString(rel.con.Table.Schema))
+		}
+	}
+	if len(g.Roles) > 0 {
+		fmt.Fprintf(w, "  select_permissions:\n")
+		for _, role := range g.Roles {
+			fmt.Fprintf(w, "    - role: %s\n", yamlString(role))
+			fmt.Fprintf(w, "      permission:\n")
+			if len(cols) == 0 {
+				fmt.Fprintf(w, "        columns: []\n")
+			} else {
+				fmt.Fprintf(w, "        columns:\n")
+				for _, col := range cols {
+					fmt.Fprintf(w, "          - %s\n", yamlString(col))
+				}
+			}
+			fmt.Fprintf(w, "        filter: {}\n")
+		}
+	}
+}
+
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// yamlString returns s as a YAML scalar, quoting it unless it's an
+// identifier YAML wouldn't read as something other than a string.
+func yamlString(s string) string {
+
+	switch strings.ToLower(s) {
+	case "true", "false", "yes", "no", "on", "off", "y", "n", "null":
+		return strconv.Quote(s)
+	}
+	if yamlPlain.MatchString(s) {
+		return s
+	}
+	return strconv.Quote(s)
+}
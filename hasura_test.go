@@ -0,0 +1,121 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestHasuraGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (id int PRIMARY KEY, email text);
+	CREATE TABLE posts (
+		id int PRIMARY KEY,
+		author_id int REFERENCES users (id),
+		editor_id int REFERENCES users (id),
+		users text
+	);
+	CREATE TABLE tags (post_id int, seq int, PRIMARY KEY (post_id, seq));
+	CREATE TABLE tag_votes (post_id int, seq int, FOREIGN KEY (post_id, seq) REFERENCES tags (post_id, seq));
+	CREATE VIEW "user emails" AS SELECT email FROM users;
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&HasuraGenerator{Roles: []string{"user"}}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `- table:
+    name: users
+    schema: public
+  array_relationships:
+    - name: posts
+      using:
+        foreign_key_constraint_on:
+          column: author_id
+          table:
+            name: posts
+            schema: public
+    - name: posts_by_editor_id
+      using:
+        foreign_key_constraint_on:
+          column: editor_id
+          table:
+            name: posts
+            schema: public
+  select_permissions:
+    - role: user
+      permission:
+        columns:
+          - id
+          - email
+        filter: {}
+- table:
+    name: posts
+    schema: public
+  object_relationships:
+    - name: author
+      using:
+        foreign_key_constraint_on: author_id
+    - name: editor
+      using:
+        foreign_key_constraint_on: editor_id
+  select_permissions:
+    - role: user
+      permission:
+        columns:
+          - id
+          - author_id
+          - editor_id
+          - users
+        filter: {}
+- table:
+    name: tags
+    schema: public
+  array_relationships:
+    - name: tag_votes
+      using:
+        foreign_key_constraint_on:
+          columns:
+            - post_id
+            - seq
+          table:
+            name: tag_votes
+            schema: public
+  select_permissions:
+    - role: user
+      permission:
+        columns:
+          - post_id
+          - seq
+        filter: {}
+- table:
+    name: tag_votes
+    schema: public
+  object_relationships:
+    - name: tags
+      using:
+        foreign_key_constraint_on:
+          - post_id
+          - seq
+  select_permissions:
+    - role: user
+      permission:
+        columns:
+          - post_id
+          - seq
+        filter: {}
+- table:
+    name: "user emails"
+    schema: public
+  select_permissions:
+    - role: user
+      permission:
+        columns:
+          - email
+        filter: {}
+`, sb.String())
+
+	sb.Reset()
+	require.Nil(t, (&HasuraGenerator{}).Generate(&sb, NewCompiler().Catalog))
+	assert.Equal(t, "[]\n", sb.String())
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+)
+
+// HubTable is how central a table is to the foreign keys of a catalog.
+// References counts are of foreign keys, while the transitive counts are
+// of distinct tables, reached by following foreign keys through as many
+// tables as they lead. A self-referencing foreign key counts as a
+// reference but doesn't make the table reach itself.
+type HubTable struct {
+	Table string `json:"table"`
+	// ReferencedBy is the number of foreign keys referencing the table, and
+	// References the number it has.
+	ReferencedBy int `json:"referenced_by"`
+	References   int `json:"references"`
+	// Dependents are the tables which reference the table, directly or
+	// through others, and DependentDepth is the most foreign keys between it
+	// and one of them.
+	Dependents     int `json:"dependents"`
+	DependentDepth int `json:"dependent_depth"`
+	// Dependencies are the tables the table references, directly or
+	// through others, and DependencyDepth is the most foreign keys between
+	// it and one of them.
+	Dependencies    int `json:"dependencies"`
+	DependencyDepth int `json:"dependency_depth"`
+}
+
+// Hubs returns the tables of cat which take part in foreign keys, those
+// with the most dependents first, as the tables which changing affects the
+// most.
+func Hubs(cat *Catalog) []*HubTable {
+
+	refs, referencedBy := make(map[*Table][]*Table), make(map[*Table][]*Table)
+	hubs := make(map[*Table]*HubTable)
+	hub := func(t *Table) *HubTable {
+		if h, ok := hubs[t]; ok {
+			return h
+		}
+		h := &HubTable{Table: t.Schema + "." + t.Name}
+		hubs[t] = h
+		return h
+	}
+	for _, con := range cat.Depends.ConstraintsByName {
+		if con.Type != ConstraintTypeForeignKey || len(con.Refers) == 0 {
+			continue
+		}
+		from, to := con.Table, con.Refers[0].Table
+		hub(from).References++
+		hub(to).ReferencedBy++
+		if from != to && !slices.Contains(refs[from], to) {
+			refs[from] = append(refs[from], to)
+			referencedBy[to] = append(referencedBy[to], from)
+		}
+	}
+	var ret []*HubTable
+	for t, h := range hubs {
+		h.Dependents, h.DependentDepth = reachable(t, referencedBy)
+		h.Dependencies, h.DependencyDepth = reachable(t, refs)
+		ret = append(ret, h)
+	}
+	slices.SortFunc(ret, func(a, b *HubTable) int {
+		if a.Dependents != b.Dependents {
+			return b.Dependents - a.Dependents
+		}
+		if a.ReferencedBy != b.ReferencedBy {
+			return b.ReferencedBy - a.ReferencedBy
+		}
+		if a.Table < b.Table {
+			return -1
+		}
+		return 1
+	})
+	return ret
+}
+
+// reachable returns the number of tables reached from t by following
+// edges, and the most edges followed to reach one of them by the shortest
+// way there.
+func reachable(t *Table, edges map[*Table][]*Table) (count, depth int) {
+
+	seen := map[*Table]bool{t: true}
+	level := []*Table{t}
+	for len(level) > 0 {
+		var next []*Table
+		for _, from := range level {
+			for _, to := range edges[from] {
+				if !seen[to] {
+					seen[to] = true
+					next = append(next, to)
+				}
+			}
+		}
+		if len(next) > 0 {
+			depth++
+		}
+		count += len(next)
+		level = next
+	}
+	return count, depth
+}
+
+func runHubs(args []string) error {
+
+	fs := flag.NewFlagSet("hubs", flag.ExitOnError)
+	top := fs.Int("top", 20, "number of tables to list, or 0 for all")
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	hubs := Hubs(c.Catalog)
+	if *top > 0 && len(hubs) > *top {
+		hubs = hubs[:*top]
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		if hubs == nil {
+			hubs = []*HubTable{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(hubs)
+	}
+	bw := bufio.NewWriter(w)
+	rows := [][]string{{"Table", "Referenced by", "References", "Dependents", "Depth", "Dependencies", "Depth"}}
+	for _, h := range hubs {
+		rows = append(rows, []string{h.Table, strconv.Itoa(h.ReferencedBy), strconv.Itoa(h.References),
+			strconv.Itoa(h.Dependents), strconv.Itoa(h.DependentDepth), strconv.Itoa(h.Dependencies), strconv.Itoa(h.DependencyDepth)})
+	}
+	writeRows(bw, rows)
+	return bw.Flush()
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestHubs(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE orgs (id int PRIMARY KEY);
+CREATE TABLE users (id int PRIMARY KEY, org_id int REFERENCES orgs (id), manager_id int REFERENCES users (id));
+CREATE TABLE posts (id int PRIMARY KEY, author_id int REFERENCES users (id), editor_id int REFERENCES users (id));
+CREATE TABLE comments (id int PRIMARY KEY, post_id int REFERENCES posts (id), author_id int REFERENCES users (id));
+CREATE TABLE settings (key text PRIMARY KEY);
+`))
+	// Depths are of the shortest chains of foreign keys, so comments is one
+	// from users rather than two through posts
+	assert.Equal(t, []*HubTable{
+		{Table: "public.orgs", ReferencedBy: 1, Dependents: 3, DependentDepth: 2},
+		{Table: "public.users", ReferencedBy: 4, References: 2, Dependents: 2, DependentDepth: 1, Dependencies: 1, DependencyDepth: 1},
+		{Table: "public.posts", ReferencedBy: 1, References: 2, Dependents: 1, DependentDepth: 1, Dependencies: 2, DependencyDepth: 2},
+		{Table: "public.comments", References: 2, Dependencies: 3, DependencyDepth: 2},
+	}, Hubs(c.Catalog))
+}
@@ -0,0 +1,355 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"golang.org/x/text/unicode/norm"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// MaxIdentifierLength is the most bytes Postgres keeps of an identifier,
+// NAMEDATALEN less its terminator. Longer identifiers are truncated, which
+// the parser does for those written in statements, and the compiler does
+// for the names it makes up, in the same way as Postgres.
+const MaxIdentifierLength = 63
+
+// truncateIdentifier returns name cut to MaxIdentifierLength bytes, without
+// splitting a character.
+func truncateIdentifier(name string) string {
+
+	return clipIdentifier(name, MaxIdentifierLength)
+}
+
+// makeObjectName joins name1, name2 and label with underscores, as
+// Postgres' makeObjectName does, shortening the longer of name1 and name2
+// until the result fits in MaxIdentifierLength. name2 and label may be
+// empty.
+func makeObjectName(name1, name2, label string) string {
+
+	overhead := 0
+	if name2 != "" {
+		overhead++
+	}
+	if label != "" {
+		overhead += len(label) + 1
+	}
+	avail := MaxIdentifierLength - overhead
+	len1, len2 := len(name1), len(name2)
+	for len1+len2 > avail {
+		if len1 > len2 {
+			len1--
+		} else {
+			len2--
+		}
+	}
+	name := clipIdentifier(name1, len1)
+	if name2 != "" {
+		name += "_" + clipIdentifier(name2, len2)
+	}
+	if label != "" {
+		name += "_" + label
+	}
+	return name
+}
+
+// clipIdentifier cuts name to at most n bytes without splitting a
+// character.
+func clipIdentifier(name string, n int) string {
+
+	if n >= len(name) {
+		return name
+	}
+	for n > 0 && !utf8.RuneStart(name[n]) {
+		n--
+	}
+	return name[:n]
+}
+
+// nameAddition joins the names of the columns an index or constraint is on,
+// as Postgres does to name it, stopping once there are enough to fill a
+// name.
+func nameAddition(names []string) string {
+
+	var sb strings.Builder
+	for _, name := range names {
+		if sb.Len() > 0 {
+			sb.WriteByte('_')
+		}
+		sb.WriteString(truncateIdentifier(name))
+		if sb.Len() > MaxIdentifierLength {
+			break
+		}
+	}
+	return sb.String()
+}
+
+// chooseName makes up the name of an object from name1, name2 and label as
+// makeObjectName does, appending a number to label until the name isn't
+// taken, as Postgres' ChooseRelationName and ChooseConstraintName do. A
+// truncated name which is taken is warned about, as which object gets
+// which name then depends on the order they were created in.
+func (c *Compiler) chooseName(name1, name2, label string, taken func(name string) bool) string {
+
+	name := makeObjectName(name1, name2, label)
+	for pass := 1; taken(name); pass++ {
+		name = makeObjectName(name1, name2, label+strconv.Itoa(pass))
+	}
+	full := name1
+	if name2 != "" {
+		full += "_" + name2
+	}
+	if label != "" {
+		full += "_" + label
+	}
+	if first := makeObjectName(name1, name2, label); first != full && name != first {
+		c.warn(RuleTruncatedIdentifier, c.stmtLine(), fmt.Sprintf("name %s is truncated to %s, which is taken, so %s is used instead", full, first, name))
+	}
+	return name
+}
+
+// identifierToken is an identifier in a source text, as Postgres reads it:
+// unquoted ones folded to lower case and quoted ones without their quotes.
+type identifierToken struct {
+	offset int
+	name   string
+}
+
+// identifierTokens returns the identifiers among the tokens of src, in
+// order, including the keywords which can be used as names unquoted.
+func identifierTokens(src string, tokens []*pg_query.ScanToken) []identifierToken {
+
+	var ret []identifierToken
+	for _, tok := range tokens {
+		if tok.Token != pg_query.Token_IDENT && (tok.KeywordKind == pg_query.KeywordKind_NO_KEYWORD || tok.KeywordKind == pg_query.KeywordKind_RESERVED_KEYWORD) {
+			continue
+		}
+		name := src[tok.Start:tok.End]
+		if strings.HasPrefix(name, `"`) {
+			name = strings.ReplaceAll(name[1:len(name)-1], `""`, `"`)
+		} else {
+			name = strings.Map(asciiLower, name)
+		}
+		ret = append(ret, identifierToken{offset: int(tok.Start), name: name})
+	}
+	return ret
+}
+
+// asciiLower folds r to lower case if it's an ASCII letter, as Postgres
+// folds unquoted identifiers.
+func asciiLower(r rune) rune {
+
+	if 'A' <= r && r <= 'Z' {
+		return r + 'a' - 'A'
+	}
+	return r
+}
+
+// statementIdentifiers returns the identifiers of the statement being
+// applied.
+func (c *Compiler) statementIdentifiers() []identifierToken {
+
+	if c.annotations == nil || c.stmt == nil {
+		return nil
+	}
+	start, end := int(statementStart(c.src, c.stmt)), int(c.stmt.StmtLocation+c.stmt.StmtLen)
+	if c.stmt.StmtLen == 0 {
+		end = len(c.src)
+	}
+	idents := c.annotations.idents
+	i := sort.Search(len(idents), func(i int) bool { return idents[i].offset >= start })
+	j := sort.Search(len(idents), func(i int) bool { return idents[i].offset >= end })
+	return idents[i:j]
+}
+
+// checkIdentifiers notes the identifiers of the statement being applied
+// which Postgres truncates, as it does, and warns about those truncated to
+// the same name as a different identifier was. Identifiers which aren't
+// normalized, mix scripts or look like another are warned about the first
+// time they're seen.
+func (c *Compiler) checkIdentifiers() {
+
+	for _, ident := range c.statementIdentifiers() {
+		line := c.srcLine + c.annotations.line(ident.offset) + 1
+		if len(ident.name) > MaxIdentifierLength {
+			truncated := truncateIdentifier(ident.name)
+			c.note(RuleTruncatedIdentifier, line, fmt.Sprintf("identifier %q will be truncated to %q", ident.name, truncated))
+			if c.truncations == nil {
+				c.truncations = make(map[string]string)
+			}
+			if other, ok := c.truncations[truncated]; ok && other != ident.name {
+				c.warn(RuleTruncatedIdentifier, line, fmt.Sprintf("identifiers %q and %q are both truncated to %q", other, ident.name, truncated))
+			} else if !ok {
+				c.truncations[truncated] = ident.name
+			}
+		}
+		if c.lookalikes == nil {
+			c.lookalikes = make(map[string]string)
+		}
+		skeleton := identifierSkeleton(ident.name)
+		other, ok := c.lookalikes[skeleton]
+		if ok && other == ident.name {
+			continue
+		}
+		if !ok {
+			c.lookalikes[skeleton] = ident.name
+		}
+		if !norm.NFC.IsNormalString(ident.name) {
+			c.warn(RuleUnnormalizedIdentifier, line, fmt.Sprintf("identifier %q isn't normalized to NFC, so it's a different name from the same text typed elsewhere", ident.name))
+		}
+		if scripts := identifierScripts(ident.name); len(scripts) > 1 {
+			c.warn(RuleConfusableIdentifier, line, fmt.Sprintf("identifier %q mixes %s letters", ident.name, strings.Join(scripts, " and ")))
+		}
+		if ok {
+			c.warn(RuleConfusableIdentifier, line, fmt.Sprintf("identifiers %q and %q look alike but are different names", other, ident.name))
+		}
+	}
+}
+
+// identifierSkeleton returns what identifiers which look like name have in
+// common: name in NFKC form with the letters of other scripts which look
+// like Latin ones replaced by them.
+func identifierSkeleton(name string) string {
+
+	if isASCII(name) {
+		return name
+	}
+	return strings.Map(func(r rune) rune {
+		if latin, ok := latinLookalikes[r]; ok {
+			return latin
+		}
+		return r
+	}, norm.NFKC.String(name))
+}
+
+func isASCII(s string) bool {
+
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
+
+// latinLookalikes maps the Greek and Cyrillic letters which are hard to
+// tell from Latin ones to those Latin letters.
+var latinLookalikes = map[rune]rune{
+	'а': 'a', 'в': 'b', 'е': 'e', 'к': 'k', 'м': 'm', 'н': 'h', 'о': 'o', 'р': 'p', 'с': 'c', 'т': 't', 'у': 'y', 'х': 'x',
+	'і': 'i', 'ј': 'j', 'ѕ': 's', 'ԁ': 'd', 'ԛ': 'q', 'ԝ': 'w',
+	'А': 'A', 'В': 'B', 'Е': 'E', 'К': 'K', 'М': 'M', 'Н': 'H', 'О': 'O', 'Р': 'P', 'С': 'C', 'Т': 'T', 'Х': 'X',
+	'І': 'I', 'Ј': 'J', 'Ѕ': 'S',
+	'α': 'a', 'ι': 'i', 'κ': 'k', 'ν': 'v', 'ο': 'o', 'ρ': 'p', 'υ': 'u',
+	'Α': 'A', 'Β': 'B', 'Ε': 'E', 'Ζ': 'Z', 'Η': 'H', 'Ι': 'I', 'Κ': 'K', 'Μ': 'M', 'Ν': 'N', 'Ο': 'O', 'Ρ': 'P', 'Τ': 'T',
+	'Υ': 'Y', 'Χ': 'X',
+}
+
+// identifierScriptTables are the scripts told apart by identifierScripts. Han,
+// kana and Hangul are one, as they're written together.
+var identifierScriptTables = []struct {
+	name   string
+	tables []*unicode.RangeTable
+}{
+	{"Latin", []*unicode.RangeTable{unicode.Latin}},
+	{"Greek", []*unicode.RangeTable{unicode.Greek}},
+	{"Cyrillic", []*unicode.RangeTable{unicode.Cyrillic}},
+	{"Armenian", []*unicode.RangeTable{unicode.Armenian}},
+	{"Hebrew", []*unicode.RangeTable{unicode.Hebrew}},
+	{"Arabic", []*unicode.RangeTable{unicode.Arabic}},
+	{"CJK", []*unicode.RangeTable{unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul}},
+}
+
+// identifierScripts returns the scripts of the letters of name, in the
+// order they first appear.
+func identifierScripts(name string) []string {
+
+	if isASCII(name) {
+		return nil
+	}
+	var scripts []string
+	for _, r := range name {
+		for _, s := range identifierScriptTables {
+			if unicode.IsOneOf(s.tables, r) && !slices.Contains(scripts, s.name) {
+				scripts = append(scripts, s.name)
+			}
+		}
+	}
+	return scripts
+}
+
+// normalizeIdentifiers returns p with its identifiers normalized to NFC,
+// parsed again, or p itself if they already are.
+func (p *ParsedSource) normalizeIdentifiers() (*ParsedSource, error) {
+
+	if p.annotations == nil || !slices.ContainsFunc(p.annotations.idents, func(ident identifierToken) bool {
+		return !norm.NFC.IsNormalString(ident.name)
+	}) {
+		return p, nil
+	}
+	scan, err := pg_query.Scan(p.src)
+	if err != nil {
+		return nil, err
+	}
+	var sb strings.Builder
+	last := 0
+	for _, tok := range scan.Tokens {
+		if tok.Token != pg_query.Token_IDENT {
+			continue
+		}
+		sb.WriteString(p.src[last:tok.Start])
+		sb.WriteString(norm.NFC.String(p.src[tok.Start:tok.End]))
+		last = int(tok.End)
+	}
+	sb.WriteString(p.src[last:])
+	src := sb.String()
+	parse, err := pg_query.Parse(src)
+	if err != nil {
+		return nil, syntaxError(src, err)
+	}
+	annotations, err := newAnnotationIndex(src)
+	if err != nil {
+		return nil, err
+	}
+	return &ParsedSource{src: src, line: p.line, parse: parse, annotations: annotations, copyRows: p.copyRows}, nil
+}
+
+// relationTaken returns a func reporting whether schema has a table,
+// sequence or index of a given name, which the name of an index can't be.
+func (c *Compiler) relationTaken(schema string) func(name string) bool {
+
+	return func(name string) bool {
+		if _, ok := c.Catalog.Depends.IndexesByName[schema+"."+name]; ok {
+			return true
+		}
+		if _, ok := c.Catalog.Depends.IndexedConstraint(schema, name); ok {
+			return true
+		}
+		sch, ok := c.Catalog.Schemas.Get(schema)
+		if !ok {
+			return false
+		}
+		if _, ok := sch.Tables.Get(name); ok {
+			return true
+		}
+		_, ok = sch.Sequences.Get(name)
+		return ok
+	}
+}
+
+// constraintTaken returns a func reporting whether a constraint in schema
+// has a given name.
+func (c *Compiler) constraintTaken(schema string) func(name string) bool {
+
+	return func(name string) bool {
+		for _, con := range c.Catalog.Depends.ConstraintsNamed(name) {
+			if con.Table.Schema == schema {
+				return true
+			}
+		}
+		return false
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestMakeObjectName(t *testing.T) {
+	a, b := strings.Repeat("a", 40), strings.Repeat("b", 30)
+	assert.Equal(t, "t_id_key", makeObjectName("t", "id", "key"))
+	assert.Equal(t, "t_pkey", makeObjectName("t", "", "pkey"))
+	assert.Equal(t, strings.Repeat("a", 29)+"_"+strings.Repeat("b", 29)+"_key", makeObjectName(a, nameAddition([]string{b, b}), "key"))
+	assert.Equal(t, strings.Repeat("a", 58)+"_pkey", makeObjectName(strings.Repeat("a", 70), "", "pkey"))
+	// Characters aren't split
+	assert.Equal(t, strings.Repeat("é", 29)+"_seq", makeObjectName(strings.Repeat("é", 40), "", "seq"))
+}
+
+func TestCompiler_TruncatedNames(t *testing.T) {
+	long := strings.Repeat("x", 58)
+	table := strings.Repeat("order_line_item", 4)
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE `+table+` (id serial PRIMARY KEY);
+CREATE TABLE o (`+long+`_a int UNIQUE, `+long+`_b int UNIQUE);
+CREATE TABLE t (id int REFERENCES `+table+` (id), n int CHECK (n > 0), `+long+`_a int, `+long+`_b int);
+CREATE INDEX ON t (`+long+`_a, `+long+`_b);
+`))
+	names := func(tab string) []string {
+		var ret []string
+		for _, con := range c.Catalog.Depends.TableConstraints(assertTable(t, c, tab)) {
+			ret = append(ret, con.Name)
+		}
+		return ret
+	}
+	assert.Equal(t, []string{strings.Repeat("order_line_item", 3) + "order_line_it_pkey"}, names(table))
+	col, _ := assertTable(t, c, table).Columns.Get("id")
+	assert.Equal(t, "public."+strings.Repeat("order_line_item", 3)+"order_line__id_seq", col.Sequence())
+	assert.ElementsMatch(t, []string{"o_" + strings.Repeat("x", 57) + "_key", "o_" + strings.Repeat("x", 56) + "_key1"}, names("o"))
+	assert.ElementsMatch(t, []string{"t_id_fkey", "t_n_check"}, names("t"))
+	assert.NotNil(t, c.Catalog.Depends.IndexesByName["public.t_"+strings.Repeat("x", 57)+"_idx"])
+	assert.Equal(t, []string{"name o_" + long + "_b_key is truncated to o_" + strings.Repeat("x", 57) + "_key, which is taken, so o_" + strings.Repeat("x", 56) + "_key1 is used instead (line 3)"}, c.Warnings)
+}
+
+func TestCompiler_TruncatedIdentifiers(t *testing.T) {
+	long := strings.Repeat("customer_", 7)
+	c := NewCompiler()
+	require.Nil(t, c.Compile("CREATE TABLE \""+long+"A\" (id int);\nALTER TABLE "+long+"B ADD COLUMN n int;\n"))
+	assertTable(t, c, long[:63])
+	var notes []string
+	for _, d := range c.Diagnostics(nil) {
+		assert.Equal(t, RuleTruncatedIdentifier, d.Rule)
+		if d.Severity == SeverityNote {
+			notes = append(notes, d.Message)
+		}
+	}
+	assert.Equal(t, []string{
+		`identifier "` + long + `A" will be truncated to "` + long[:63] + `"`,
+		`identifier "` + long + `b" will be truncated to "` + long[:63] + `"`,
+	}, notes)
+	assert.Equal(t, []string{`identifiers "` + long + `A" and "` + long + `b" are both truncated to "` + long[:63] + `" (line 2)`}, c.Warnings)
+}
+
+func TestCompiler_ConfusableIdentifiers(t *testing.T) {
+	// "café" with a combining accent, and "password" with a Cyrillic "а"
+	decomposed, cyrillic := "café", "pаssword"
+	src := "CREATE TABLE accounts (password text, \"café\" text);\n" +
+		"ALTER TABLE accounts ADD COLUMN \"" + cyrillic + "\" text;\n" +
+		"ALTER TABLE accounts ADD COLUMN \"" + decomposed + "_note\" text;\n" +
+		"SELECT \"" + decomposed + "\" FROM accounts;\n"
+
+	c := NewCompiler()
+	require.Nil(t, c.Compile(src))
+	assert.Equal(t, []string{
+		`identifier "` + cyrillic + `" mixes Latin and Cyrillic letters (line 2)`,
+		`identifiers "password" and "` + cyrillic + `" look alike but are different names (line 2)`,
+		`identifier "` + decomposed + `_note" isn't normalized to NFC, so it's a different name from the same text typed elsewhere (line 3)`,
+		`identifier "` + decomposed + `" isn't normalized to NFC, so it's a different name from the same text typed elsewhere (line 4)`,
+		`identifiers "café" and "` + decomposed + `" look alike but are different names (line 4)`,
+	}, c.Warnings)
+	assertColumn(t, assertTable(t, c, "accounts"), decomposed+"_note", Text, ColumnAttributes{})
+
+	c = NewCompiler()
+	c.NormalizeIdentifiers = true
+	require.Nil(t, c.Compile(src))
+	assertColumn(t, assertTable(t, c, "accounts"), "café_note", Text, ColumnAttributes{})
+	assert.Len(t, c.Warnings, 2)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Dependent is an object which would be dropped or changed along with the
+// object it depends on, and the objects which depend on it in turn.
+type Dependent struct {
+	// Kind is the kind of object, such as "constraint" or "replica
+	// identity".
+	Kind string `json:"kind"`
+	// Name is the object's name qualified by its schema, and by its table
+	// for constraints and rules.
+	Name string `json:"name"`
+	// OID is the object's, or 0 for a replica identity.
+	OID OID `json:"oid,omitempty"`
+	// Object is the *Constraint, *Index, *Statistics, *RawStatement or
+	// *ReplicaIdentity the dependent is.
+	Object     any          `json:"-"`
+	Dependents []*Dependent `json:"dependents,omitempty"`
+}
+
+func (d *Dependent) String() string {
+
+	return d.Kind + " " + d.Name
+}
+
+// Dependents returns what dropping obj, a *Table, *Column, *Constraint,
+// *Index, *Statistics or view would affect. Objects found through more than
+// one path are only returned once, as close to obj as they can be. Triggers
+// aren't modeled, so they're never included.
+func (c *Catalog) Dependents(obj any) []*Dependent {
+
+	seen := map[any]bool{obj: true}
+	return c.dependents(obj, seen)
+}
+
+func (c *Catalog) dependents(obj any, seen map[any]bool) []*Dependent {
+
+	var ret []*Dependent
+	add := func(obj any) {
+		if seen[obj] {
+			return
+		}
+		seen[obj] = true
+		kind, name := objectName(obj)
+		ret = append(ret, &Dependent{Kind: kind, Name: name, OID: objectOID(obj), Object: obj})
+	}
+	addConstraints := func(cons Constraints) {
+		for _, con := range cons {
+			add(con)
+		}
+	}
+
+	d := c.Depends
+	deps := d.DependentsOf(obj)
+	switch obj := obj.(type) {
+	case *Table:
+		for _, col := range obj.Columns.List() {
+			deps = append(deps, d.DependentsOf(col)...)
+		}
+	case *Constraint:
+		// Foreign keys rely on the unique index of the key they reference
+		if obj.Indexed() {
+			addConstraints(d.foreignKeysOn(obj.Table, obj.Constrains))
+		}
+	case *Index:
+		{
+			if obj.Unique && len(obj.Elems) == len(obj.Columns) {
+				addConstraints(d.foreignKeysOn(obj.Table, obj.Columns))
+			}
+			if obj.Table.ReplicaIndex == obj {
+				seen[&obj.Table.ReplicaIdentity] = true
+				ret = append(ret, &Dependent{Kind: "replica identity", Name: obj.Table.Schema + "." + obj.Table.Name, Object: &obj.Table.ReplicaIdentity})
+			}
+		}
+	}
+	for _, dep := range deps {
+		add(dep.Dependent)
+	}
+	slices.SortStableFunc(ret, func(a, b *Dependent) int {
+		if a.Kind != b.Kind {
+			return strings.Compare(a.Kind, b.Kind)
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	// Everything directly dependent is marked seen before recursing, so that
+	// it isn't nested under a sibling
+	for _, dep := range ret {
+		dep.Dependents = c.dependents(dep.Object, seen)
+	}
+	return ret
+}
+
+// foreignKeysOn returns the foreign keys referencing exactly the columns
+// cols of t, in any order.
+func (d *Depends) foreignKeysOn(t *Table, cols Columns) Constraints {
+
+	var ret Constraints
+	for _, con := range d.ReferencingConstraints(t) {
+		if len(con.Refers) == len(cols) && !slices.ContainsFunc(con.Refers, func(col *Column) bool {
+			return !slices.Contains(cols, col)
+		}) {
+			ret = append(ret, con)
+		}
+	}
+	return ret
+}
+
+// findObject finds the object of the kind given for the impact command.
+// Names are qualified by schema unless they're in searchPath, and columns
+// are qualified by their table. So are constraints, unless only one table
+// has a constraint of that name.
+func findObject(c *Compiler, kind, name string) (any, error) {
+
+	qualified := func(name string) string {
+		if !strings.Contains(name, ".") {
+			return c.SearchPath + "." + name
+		}
+		return name
+	}
+	var obj any
+	switch kind {
+	case "table":
+		if t := findTable(c.Catalog, c.SearchPath, name); t != nil {
+			obj = t
+		}
+	case "column":
+		{
+			i := strings.LastIndex(name, ".")
+			if i < 0 {
+				return nil, fmt.Errorf("column %s isn't qualified by its table", name)
+			}
+			if t := findTable(c.Catalog, c.SearchPath, name[:i]); t != nil {
+				if col, ok := t.Columns.Get(name[i+1:]); ok {
+					obj = col
+				}
+			}
+		}
+	case "constraint":
+		if i := strings.LastIndex(name, "."); i >= 0 {
+			if t := findTable(c.Catalog, c.SearchPath, name[:i]); t != nil {
+				if con, ok := c.Catalog.Depends.Constraint(t, name[i+1:]); ok {
+					obj = con
+				}
+			}
+			break
+		}
+		switch cons := c.Catalog.Depends.ConstraintsNamed(name); len(cons) {
+		case 0:
+		case 1:
+			obj = cons[0]
+		default:
+			return nil, fmt.Errorf("constraint %s is ambiguous, qualify it by its table", name)
+		}
+	case "index":
+		if idx, ok := c.Catalog.Depends.IndexesByName[qualified(name)]; ok {
+			obj = idx
+		}
+	case "statistics":
+		if s, ok := c.Catalog.Depends.StatisticsByName[qualified(name)]; ok {
+			obj = s
+		}
+	case "view":
+		if view := c.findOpaque("CREATE VIEW", qualified(name)); view != nil {
+			obj = view
+		}
+	default:
+		return nil, fmt.Errorf("unknown kind of object %q, expected one of: table, column, constraint, index, statistics, view", kind)
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("couldn't find %s %s", kind, name)
+	}
+	return obj, nil
+}
+
+func writeDependents(w io.Writer, deps []*Dependent, depth int) {
+
+	for _, dep := range deps {
+		fmt.Fprintf(w, "%s%s\n", strings.Repeat("  ", depth), dep)
+		writeDependents(w, dep.Dependents, depth+1)
+	}
+}
+
+func runImpact(args []string) error {
+
+	fs := flag.NewFlagSet("impact", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() < 3 {
+		return fmt.Errorf("expected the kind and name of an object followed by the input files")
+	}
+	c, err := compile(fs.Args()[2:])
+	if err != nil {
+		return err
+	}
+	obj, err := findObject(c, fs.Arg(0), fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	deps := c.Catalog.Dependents(obj)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(deps)
+	}
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "%s %s\n", fs.Arg(0), fs.Arg(1))
+	writeDependents(bw, deps, 1)
+	return bw.Flush()
+}
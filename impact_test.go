@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestDependents(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE users (id int PRIMARY KEY, email text NOT NULL, name text);
+CREATE UNIQUE INDEX users_email ON users (email);
+CREATE STATISTICS users_stats ON email, name FROM users;
+ALTER TABLE users REPLICA IDENTITY USING INDEX users_email;
+CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id), author_email text REFERENCES users (email));
+CREATE TABLE comments (id int PRIMARY KEY, post int REFERENCES posts (id));
+CREATE RULE no_delete AS ON DELETE TO users DO INSTEAD NOTHING;
+`))
+	tree := func(obj any) string {
+		var sb strings.Builder
+		writeDependents(&sb, c.Catalog.Dependents(obj), 0)
+		return sb.String()
+	}
+	users := assertTable(t, c, "public.users")
+
+	assert.Equal(t, `constraint public.posts.posts_author_email_fkey
+constraint public.posts.posts_author_fkey
+constraint public.users.users_pkey
+index public.users_email
+  replica identity public.users
+rule public.users.no_delete
+statistics public.users_stats
+`, tree(users))
+
+	email, _ := users.Columns.Get("email")
+	assert.Equal(t, `constraint public.posts.posts_author_email_fkey
+index public.users_email
+  replica identity public.users
+statistics public.users_stats
+`, tree(email))
+
+	id, _ := users.Columns.Get("id")
+	assert.Equal(t, `constraint public.posts.posts_author_fkey
+constraint public.users.users_pkey
+`, tree(id))
+
+	assert.Equal(t, "constraint public.posts.posts_author_fkey\n", tree(constraintsByName(c)["users_pkey"]))
+	assert.Equal(t, "constraint public.posts.posts_author_email_fkey\nreplica identity public.users\n",
+		tree(c.Catalog.Depends.IndexesByName["public.users_email"]))
+	assert.Empty(t, tree(constraintsByName(c)["posts_author_fkey"]))
+
+	obj, err := findObject(c, "column", "posts.author")
+	require.Nil(t, err)
+	assert.Equal(t, "constraint public.posts.posts_author_fkey\n", tree(obj))
+	_, err = findObject(c, "index", "missing")
+	assert.EqualError(t, err, "couldn't find index missing")
+}
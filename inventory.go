@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/csv"
+	"flag"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// InventoryGenerator writes a row for each column of each table, for
+// opening in a spreadsheet: where it is, its type, whether it's nullable,
+// its default, whether it's part of the primary key, the columns it
+// references if it's part of a foreign key, and its comment. Comma
+// separates the fields, so it's written as CSV or TSV.
+type InventoryGenerator struct {
+	Comma rune
+	// NoHeader leaves out the row naming the fields.
+	NoHeader bool
+}
+
+func NewCSVGenerator(fs *flag.FlagSet) Generator {
+
+	g := &InventoryGenerator{Comma: ','}
+	fs.BoolVar(&g.NoHeader, "csv-no-header", false, "leave out the row naming the fields")
+	return g
+}
+
+func NewTSVGenerator(fs *flag.FlagSet) Generator {
+
+	g := &InventoryGenerator{Comma: '\t'}
+	fs.BoolVar(&g.NoHeader, "tsv-no-header", false, "leave out the row naming the fields")
+	return g
+}
+
+var inventoryHeader = []string{"schema", "table", "column", "position", "type", "nullable", "default", "primary_key", "references", "comment"}
+
+func (g *InventoryGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	cw := csv.NewWriter(w)
+	cw.Comma = g.Comma
+	if !g.NoHeader {
+		if err := cw.Write(inventoryHeader); err != nil {
+			return err
+		}
+	}
+	for _, sch := range cat.Schemas.List() {
+		if systemSchema(sch.Name) {
+			continue
+		}
+		for _, t := range sch.Tables.List() {
+			for _, col := range t.Columns.List() {
+				err := cw.Write(inventoryRow(cat, col))
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// inventoryRow returns the fields of col's row.
+func inventoryRow(cat *Catalog, col *Column) []string {
+
+	def := col.Attrs.Default
+	if seq := col.Sequence(); def == "" && seq != "" {
+		def = nextvalDefault(seq)
+	}
+	nullable := !col.Attrs.NotNull && !col.Attrs.Pkey && col.Attrs.Identity == IdentityNone
+	var refs []string
+	cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+	for _, con := range cons {
+		i := slices.Index(con.Constrains, col)
+		if con.Type != ConstraintTypeForeignKey || i < 0 || i >= len(con.Refers) {
+			continue
+		}
+		ref := con.Refers[i]
+		if name := ref.Table.Schema + "." + ref.Table.Name + "." + ref.Name; !slices.Contains(refs, name) {
+			refs = append(refs, name)
+		}
+	}
+	slices.Sort(refs)
+	var comment string
+	if desc, ok := cat.Descriptions[col]; ok {
+		comment = desc.Comment
+	}
+	return []string{col.Table.Schema, col.Table.Name, col.Name, strconv.Itoa(col.Attnum), col.FormatType(),
+		strconv.FormatBool(nullable), def, strconv.FormatBool(col.Attrs.Pkey), strings.Join(refs, " "), comment}
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestInventoryGenerator_Generate(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL, nickname varchar(20) DEFAULT 'anon');
+	COMMENT ON COLUMN users.email IS 'Where mail is sent, "verified"';
+	CREATE TABLE posts (id int GENERATED ALWAYS AS IDENTITY, author_id int REFERENCES users (id), body text);
+	`)
+	var sb strings.Builder
+	require.Nil(t, (&InventoryGenerator{Comma: ','}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `schema,table,column,position,type,nullable,default,primary_key,references,comment
+public,users,id,1,serial,false,nextval('public.users_id_seq'::regclass),true,,
+public,users,email,2,text,false,,false,,"Where mail is sent, ""verified"""
+public,users,nickname,3,character varying(20),true,'anon',false,,
+public,posts,id,1,integer,false,,false,,
+public,posts,author_id,2,integer,true,,false,public.users.id,
+public,posts,body,3,text,true,,false,,
+`, sb.String())
+
+	sb.Reset()
+	require.Nil(t, (&InventoryGenerator{Comma: '\t', NoHeader: true}).Generate(&sb, c.Catalog))
+	assert.True(t, strings.HasPrefix(sb.String(), "public\tusers\tid\t1\tserial\tfalse\tnextval('public.users_id_seq'::regclass)\ttrue\t\t\n"))
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// JoinStep is a join along a foreign key, from the table reached so far to
+// the next. Forward is whether it follows the foreign key from the
+// referencing table to the referenced one, rather than back.
+type JoinStep struct {
+	Constraint *Constraint
+	Forward    bool
+}
+
+// From returns the table the step joins from.
+func (s *JoinStep) From() *Table {
+
+	if s.Forward {
+		return s.Constraint.Table
+	}
+	return s.Constraint.Refers[0].Table
+}
+
+// To returns the table the step joins to.
+func (s *JoinStep) To() *Table {
+
+	if s.Forward {
+		return s.Constraint.Refers[0].Table
+	}
+	return s.Constraint.Table
+}
+
+// Condition renders the condition joining the step's tables, comparing
+// the foreign key's columns to those it references.
+func (s *JoinStep) Condition() string {
+
+	con := s.Constraint
+	var conds []string
+	for i, col := range con.Constrains {
+		from, to := TableIdent(con.Table)+"."+QuoteIdent(col.Name), TableIdent(con.Refers[i].Table)+"."+QuoteIdent(con.Refers[i].Name)
+		if !s.Forward {
+			from, to = to, from
+		}
+		conds = append(conds, from+" = "+to)
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// JoinPath is a way of joining one table to another along foreign keys.
+type JoinPath []*JoinStep
+
+// SQL renders the path as a FROM clause joining its tables.
+func (p JoinPath) SQL() string {
+
+	if len(p) == 0 {
+		return ""
+	}
+	var sb strings.Builder
+	sb.WriteString("FROM " + TableIdent(p[0].From()))
+	for _, s := range p {
+		sb.WriteString("\nJOIN " + TableIdent(s.To()) + " ON " + s.Condition())
+	}
+	return sb.String()
+}
+
+func (p JoinPath) MarshalJSON() ([]byte, error) {
+
+	type step struct {
+		From       string `json:"from"`
+		To         string `json:"to"`
+		Constraint string `json:"constraint"`
+		Condition  string `json:"condition"`
+	}
+	steps := []step{}
+	for _, s := range p {
+		steps = append(steps, step{From: TableIdent(s.From()), To: TableIdent(s.To()), Constraint: s.Constraint.Name, Condition: s.Condition()})
+	}
+	return json.Marshal(steps)
+}
+
+// joinGraph returns the steps which can be taken from each table of c,
+// following each foreign key either way, ordered by the name of their
+// foreign key. Self-referencing foreign keys lead nowhere new, so they're
+// left out.
+func (c *Catalog) joinGraph() map[*Table][]*JoinStep {
+
+	graph := make(map[*Table][]*JoinStep)
+	for _, con := range c.Depends.ConstraintsByName {
+		if con.Type != ConstraintTypeForeignKey || len(con.Refers) == 0 || con.Refers[0].Table == con.Table {
+			continue
+		}
+		graph[con.Table] = append(graph[con.Table], &JoinStep{Constraint: con, Forward: true})
+		graph[con.Refers[0].Table] = append(graph[con.Refers[0].Table], &JoinStep{Constraint: con})
+	}
+	for _, steps := range graph {
+		slices.SortFunc(steps, func(a, b *JoinStep) int {
+			if cmp := strings.Compare(a.Constraint.Name, b.Constraint.Name); cmp != 0 {
+				return cmp
+			}
+			return strings.Compare(TableIdent(a.To()), TableIdent(b.To()))
+		})
+	}
+	return graph
+}
+
+// joinDistances returns the fewest steps from each table reachable from t
+// to t.
+func joinDistances(graph map[*Table][]*JoinStep, t *Table) map[*Table]int {
+
+	dist := map[*Table]int{t: 0}
+	level := []*Table{t}
+	for len(level) > 0 {
+		var next []*Table
+		for _, from := range level {
+			for _, s := range graph[from] {
+				if _, ok := dist[s.To()]; !ok {
+					dist[s.To()] = dist[from] + 1
+					next = append(next, s.To())
+				}
+			}
+		}
+		level = next
+	}
+	return dist
+}
+
+// ShortestJoinPath returns a path joining from to to through the fewest
+// tables, or nil if there is none. Of the shortest, the one taking the
+// foreign keys first by name is returned.
+func (c *Catalog) ShortestJoinPath(from, to *Table) JoinPath {
+
+	graph := c.joinGraph()
+	dist := joinDistances(graph, to)
+	if _, ok := dist[from]; !ok || from == to {
+		return nil
+	}
+	var path JoinPath
+	for t := from; t != to; {
+		for _, s := range graph[t] {
+			if d, ok := dist[s.To()]; ok && d == dist[t]-1 {
+				path = append(path, s)
+				t = s.To()
+				break
+			}
+		}
+	}
+	return path
+}
+
+// JoinPaths returns the paths joining from to to in at most maxHops steps
+// which don't pass through a table twice, shortest first.
+func (c *Catalog) JoinPaths(from, to *Table, maxHops int) []JoinPath {
+
+	graph := c.joinGraph()
+	dist := joinDistances(graph, to)
+	var ret []JoinPath
+	seen := map[*Table]bool{from: true}
+	var walk func(t *Table, path JoinPath)
+	walk = func(t *Table, path JoinPath) {
+		if t == to {
+			ret = append(ret, slices.Clone(path))
+			return
+		}
+		for _, s := range graph[t] {
+			next := s.To()
+			// Only tables close enough to to can lead to it in time
+			if d, ok := dist[next]; !ok || seen[next] || len(path)+1+d > maxHops {
+				continue
+			}
+			seen[next] = true
+			walk(next, append(path, s))
+			seen[next] = false
+		}
+	}
+	if from != to {
+		walk(from, nil)
+	}
+	slices.SortStableFunc(ret, func(a, b JoinPath) int { return len(a) - len(b) })
+	return ret
+}
+
+func runJoins(args []string) error {
+
+	fs := flag.NewFlagSet("joins", flag.ExitOnError)
+	all := fs.Bool("all", false, "list every path within -max-hops rather than only the shortest")
+	maxHops := fs.Int("max-hops", 3, "most joins in a path listed by -all")
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() < 3 {
+		return fmt.Errorf("expected the tables to join followed by the input files")
+	}
+	c, err := compile(fs.Args()[2:])
+	if err != nil {
+		return err
+	}
+	var tables [2]*Table
+	for i := range tables {
+		tables[i] = findTable(c.Catalog, c.SearchPath, fs.Arg(i))
+		if tables[i] == nil {
+			return fmt.Errorf("table %s not found", fs.Arg(i))
+		}
+	}
+	var paths []JoinPath
+	if *all {
+		paths = c.Catalog.JoinPaths(tables[0], tables[1], *maxHops)
+	} else if path := c.Catalog.ShortestJoinPath(tables[0], tables[1]); path != nil {
+		paths = append(paths, path)
+	}
+	if len(paths) == 0 {
+		return fmt.Errorf("no foreign keys join %s to %s", fs.Arg(0), fs.Arg(1))
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(paths)
+	}
+	bw := bufio.NewWriter(w)
+	for i, path := range paths {
+		if i > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "-- %d joins\n%s\n", len(path), path.SQL())
+	}
+	return bw.Flush()
+}
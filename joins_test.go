@@ -0,0 +1,39 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestJoinPaths(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE orgs (id int PRIMARY KEY);
+CREATE TABLE users (id int PRIMARY KEY, org_id int REFERENCES orgs (id), manager_id int REFERENCES users (id));
+CREATE TABLE projects (id int PRIMARY KEY, org_id int REFERENCES orgs (id), owner_id int REFERENCES users (id));
+CREATE TABLE tasks (id int PRIMARY KEY, project_id int REFERENCES projects (id), region text, code int);
+CREATE TABLE regions (region text, code int, PRIMARY KEY (region, code));
+ALTER TABLE tasks ADD FOREIGN KEY (region, code) REFERENCES regions (region, code);
+CREATE TABLE audit (at timestamptz);
+`))
+	tasks, orgs := assertTable(t, c, "tasks"), assertTable(t, c, "orgs")
+	path := c.Catalog.ShortestJoinPath(tasks, orgs)
+	assert.Equal(t, "FROM tasks\nJOIN projects ON tasks.project_id = projects.id\nJOIN orgs ON projects.org_id = orgs.id", path.SQL())
+	assert.Equal(t, "FROM orgs\nJOIN projects ON orgs.id = projects.org_id\nJOIN tasks ON projects.id = tasks.project_id",
+		c.Catalog.ShortestJoinPath(orgs, tasks).SQL())
+	assert.Nil(t, c.Catalog.ShortestJoinPath(tasks, assertTable(t, c, "audit")))
+
+	paths := c.Catalog.JoinPaths(tasks, orgs, 3)
+	require.Len(t, paths, 2)
+	assert.Equal(t, path, paths[0])
+	assert.Equal(t, "FROM tasks\nJOIN projects ON tasks.project_id = projects.id\nJOIN users ON projects.owner_id = users.id\nJOIN orgs ON users.org_id = orgs.id", paths[1].SQL())
+	assert.Len(t, c.Catalog.JoinPaths(tasks, orgs, 2), 1)
+
+	path = c.Catalog.ShortestJoinPath(assertTable(t, c, "regions"), tasks)
+	assert.Equal(t, "FROM regions\nJOIN tasks ON regions.region = tasks.region AND regions.code = tasks.code", path.SQL())
+	b, err := json.Marshal(path)
+	require.Nil(t, err)
+	assert.JSONEq(t, `[{"from": "regions", "to": "tasks", "constraint": "tasks_region_code_fkey", "condition": "regions.region = tasks.region AND regions.code = tasks.code"}]`, string(b))
+}
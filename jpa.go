@@ -0,0 +1,454 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// JPAGenerator writes JPA entities for the catalog's tables, in Java or
+// Kotlin. Foreign keys of one column become @ManyToOne fields, or
+// @OneToOne if their column is unique, and the tables they refer to get a
+// @OneToMany field for each @ManyToOne. Foreign keys of more than one
+// column are left as plain columns. Entities with a primary key of more
+// than one column get an @IdClass, named after them with Id appended.
+// Tables without a primary key are left out, with a comment, since an
+// entity must have an @Id.
+//
+// Java only allows one public class in a file, so Java entities are
+// package-private, to be split into files of their own if they're needed
+// outside their package.
+type JPAGenerator struct {
+	// Language is "java" or "kotlin".
+	Language string
+	Package  string
+}
+
+func NewJPAGenerator(fs *flag.FlagSet) Generator {
+
+	g := &JPAGenerator{}
+	fs.StringVar(&g.Language, "jpa-language", "java", "language to write entities in, java or kotlin")
+	fs.StringVar(&g.Package, "jpa-package", "", "package of the entities")
+	return g
+}
+
+// jpaType is how a Postgres type is mapped in Java and Kotlin, and the
+// class needing importing for it, if any.
+type jpaType struct {
+	Java, Kotlin, Import string
+}
+
+var jpaTypes = map[*PostgresType]jpaType{
+	Bigint:           {Java: "Long", Kotlin: "Long"},
+	Bigserial:        {Java: "Long", Kotlin: "Long"},
+	Boolean:          {Java: "Boolean", Kotlin: "Boolean"},
+	Bytea:            {Java: "byte[]", Kotlin: "ByteArray"},
+	Character:        {Java: "String", Kotlin: "String"},
+	CharacterVarying: {Java: "String", Kotlin: "String"},
+	Date:             {Java: "LocalDate", Kotlin: "LocalDate", Import: "java.time.LocalDate"},
+	Double:           {Java: "Double", Kotlin: "Double"},
+	Integer:          {Java: "Integer", Kotlin: "Int"},
+	Numeric:          {Java: "BigDecimal", Kotlin: "BigDecimal", Import: "java.math.BigDecimal"},
+	Real:             {Java: "Float", Kotlin: "Float"},
+	Serial:           {Java: "Integer", Kotlin: "Int"},
+	Smallint:         {Java: "Short", Kotlin: "Short"},
+	Smallserial:      {Java: "Short", Kotlin: "Short"},
+	Text:             {Java: "String", Kotlin: "String"},
+	Time:             {Java: "LocalTime", Kotlin: "LocalTime", Import: "java.time.LocalTime"},
+	Timetz:           {Java: "OffsetTime", Kotlin: "OffsetTime", Import: "java.time.OffsetTime"},
+	Timestamp:        {Java: "LocalDateTime", Kotlin: "LocalDateTime", Import: "java.time.LocalDateTime"},
+	Timestamptz:      {Java: "OffsetDateTime", Kotlin: "OffsetDateTime", Import: "java.time.OffsetDateTime"},
+	UUID:             {Java: "UUID", Kotlin: "UUID", Import: "java.util.UUID"},
+}
+
+// jvmKeywords can't be used as names in Java, or in Kotlin without
+// backticks.
+var jvmKeywords = []string{
+	"abstract", "as", "assert", "boolean", "break", "byte", "case", "catch", "char", "class", "const",
+	"continue", "default", "do", "double", "else", "enum", "extends", "false", "final", "finally",
+	"float", "for", "fun", "goto", "if", "implements", "import", "in", "instanceof", "int", "interface",
+	"is", "long", "native", "new", "null", "object", "package", "private", "protected", "public",
+	"return", "short", "static", "strictfp", "super", "switch", "synchronized", "this", "throw",
+	"throws", "transient", "true", "try", "typealias", "typeof", "val", "var", "void", "volatile",
+	"when", "while",
+}
+
+// jpaField is a field of an entity, with its annotations.
+type jpaField struct {
+	Name        string
+	Type        string
+	Annotations []string
+}
+
+type jpaEntity struct {
+	Table  *Table
+	Name   string
+	Fields []*jpaField
+	// IDClass is the name of the @IdClass of entities with more than one
+	// @Id, and IDFields are its fields, in the order of the primary key.
+	IDClass  string
+	IDFields []*jpaField
+}
+
+func (e *jpaEntity) hasField(name string) bool {
+
+	return slices.ContainsFunc(e.Fields, func(f *jpaField) bool { return f.Name == name })
+}
+
+func (g *JPAGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	if g.Language != "java" && g.Language != "kotlin" {
+		return fmt.Errorf("unknown JPA language %q", g.Language)
+	}
+	kotlin := g.Language == "kotlin"
+	imports := []string{"jakarta.persistence.*"}
+	use := func(imp string) {
+		if imp != "" && !slices.Contains(imports, imp) {
+			imports = append(imports, imp)
+		}
+	}
+
+	var entities []*jpaEntity
+	var keyless []*Table
+	byTable := make(map[*Table]*jpaEntity)
+	classes := make(map[string]int)
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			switch {
+			case t.PartitionOf != nil || t.Extension != "":
+			case cat.Depends.PrimaryKey(t) == nil:
+				keyless = append(keyless, t)
+			default:
+				e := &jpaEntity{Table: t, Name: jvmIdent(singularize(t.Name), true)}
+				entities = append(entities, e)
+				byTable[t] = e
+				classes[e.Name]++
+			}
+		}
+	}
+	taken := make(map[string]bool)
+	for _, e := range entities {
+		if classes[e.Name] > 1 && e.Table.Schema != "public" {
+			e.Name = jvmIdent(e.Table.Schema, true) + e.Name
+		}
+		taken[e.Name] = true
+	}
+
+	for _, e := range entities {
+		t := e.Table
+		var pkey Columns
+		fks := make(map[*Column]*Constraint)
+		for _, con := range cat.Depends.TableConstraints(t) {
+			switch {
+			case con.Type == ConstraintTypePrimary:
+				pkey = con.Constrains
+			case con.Type == ConstraintTypeForeignKey && len(con.Constrains) == 1 && byTable[con.Refers[0].Table] != nil:
+				fks[con.Constrains[0]] = con
+			}
+		}
+		if len(pkey) > 1 {
+			e.IDClass = e.Name + "Id"
+			for taken[e.IDClass] {
+				e.IDClass += "_"
+			}
+			taken[e.IDClass] = true
+			use("java.io.Serializable")
+			if !kotlin {
+				use("java.util.Objects")
+			}
+		}
+		idFields := make(map[*Column]*jpaField)
+		for _, col := range t.Columns.List() {
+			con := fks[col]
+			// A foreign key which is also the primary key is mapped both as
+			// a column and as a relationship, which can't change it
+			if con != nil && !slices.Contains(pkey, col) {
+				continue
+			}
+			f := &jpaField{Name: jvmIdent(col.Name, false)}
+			typ := g.columnType(col, use)
+			f.Type = typ
+			if slices.Contains(pkey, col) {
+				f.Annotations = append(f.Annotations, "@Id")
+				if len(pkey) == 1 && (isSerial(col.Type) || col.Attrs.Identity != IdentityNone) {
+					f.Annotations = append(f.Annotations, "@GeneratedValue(strategy = GenerationType.IDENTITY)")
+				}
+			}
+			f.Annotations = append(f.Annotations, "@Column("+strings.Join(g.columnAttributes(cat, col), ", ")+")")
+			e.Fields = append(e.Fields, f)
+			idFields[col] = f
+		}
+		if e.IDClass != "" {
+			for _, col := range pkey {
+				e.IDFields = append(e.IDFields, &jpaField{Name: idFields[col].Name, Type: idFields[col].Type})
+			}
+		}
+		for _, col := range t.Columns.List() {
+			con := fks[col]
+			if con == nil {
+				continue
+			}
+			target := byTable[con.Refers[0].Table]
+			name := jvmIdent(strings.TrimSuffix(col.Name, "_id"), false)
+			if e.hasField(name) {
+				name += target.Name
+			}
+			relation := "@ManyToOne(fetch = FetchType.LAZY)"
+			if cat.Depends.UniqueColumns(Columns{col}) {
+				relation = "@OneToOne(fetch = FetchType.LAZY)"
+			}
+			join := []string{"name = " + javaString(col.Name)}
+			if ref := con.Refers[0]; !ref.Attrs.Pkey {
+				join = append(join, "referencedColumnName = "+javaString(ref.Name))
+			}
+			if col.Attrs.NotNull {
+				join = append(join, "nullable = false")
+			}
+			if slices.Contains(pkey, col) {
+				join = append(join, "insertable = false", "updatable = false")
+			}
+			e.Fields = append(e.Fields, &jpaField{Name: name, Type: target.Name, Annotations: []string{relation, "@JoinColumn(" + strings.Join(join, ", ") + ")"}})
+			if relation == "@ManyToOne(fetch = FetchType.LAZY)" {
+				many := jvmIdent(pluralize(singularize(t.Name)), false)
+				if target.hasField(many) {
+					many += "By" + jvmIdent(name, true)
+				}
+				listType := "List<" + e.Name + ">"
+				if kotlin {
+					listType = "MutableList<" + e.Name + ">"
+				} else {
+					use("java.util.List")
+				}
+				target.Fields = append(target.Fields, &jpaField{Name: many, Type: listType, Annotations: []string{"@OneToMany(mappedBy = " + javaString(name) + ")"}})
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "// Generated by pgmodelgen.")
+	if g.Package != "" {
+		if kotlin {
+			fmt.Fprintf(bw, "package %s\n\n", g.Package)
+		} else {
+			fmt.Fprintf(bw, "package %s;\n\n", g.Package)
+		}
+	}
+	slices.Sort(imports)
+	for _, imp := range imports {
+		if kotlin {
+			fmt.Fprintf(bw, "import %s\n", imp)
+		} else {
+			fmt.Fprintf(bw, "import %s;\n", imp)
+		}
+	}
+	for _, t := range keyless {
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "// %s has no primary key, so it has no entity.\n", TableIdent(t))
+	}
+	for _, e := range entities {
+		if kotlin {
+			g.writeKotlin(bw, e)
+		} else {
+			g.writeJava(bw, e)
+		}
+	}
+	return bw.Flush()
+}
+
+// columnType returns the type of col's field, registering what it needs
+// imported with use. Types without a mapping are strings.
+func (g *JPAGenerator) columnType(col *Column, use func(string)) string {
+
+	typ, ok := jpaTypes[col.Type]
+	if !ok {
+		typ = jpaType{Java: "String", Kotlin: "String"}
+	}
+	use(typ.Import)
+	if g.Language == "kotlin" {
+		if col.ArrayDims > 0 {
+			return "Array<" + typ.Kotlin + ">"
+		}
+		return typ.Kotlin
+	}
+	return typ.Java + strings.Repeat("[]", col.ArrayDims)
+}
+
+// columnAttributes returns the attributes of col's @Column annotation.
+// Types without a mapping to Java are given as the column's definition.
+func (g *JPAGenerator) columnAttributes(cat *Catalog, col *Column) []string {
+
+	attrs := []string{"name = " + javaString(col.Name)}
+	if col.Attrs.NotNull || col.Attrs.Pkey || isSerial(col.Type) {
+		attrs = append(attrs, "nullable = false")
+	}
+	if cat.Depends.UniqueColumns(Columns{col}) && !col.Attrs.Pkey {
+		attrs = append(attrs, "unique = true")
+	}
+	switch col.Type {
+	case Character, CharacterVarying:
+		if len(col.TypeMods) > 0 {
+			attrs = append(attrs, fmt.Sprintf("length = %d", col.TypeMods[0]))
+		}
+	case Numeric:
+		if len(col.TypeMods) > 0 {
+			attrs = append(attrs, fmt.Sprintf("precision = %d", col.TypeMods[0]))
+		}
+		if len(col.TypeMods) > 1 {
+			attrs = append(attrs, fmt.Sprintf("scale = %d", col.TypeMods[1]))
+		}
+	}
+	if _, ok := jpaTypes[col.Type]; !ok {
+		attrs = append(attrs, "columnDefinition = "+javaString(col.FormatType()))
+	}
+	return attrs
+}
+
+func (g *JPAGenerator) tableAnnotation(t *Table) string {
+
+	if t.Schema == "public" {
+		return fmt.Sprintf("@Table(name = %s)", javaString(t.Name))
+	}
+	return fmt.Sprintf("@Table(name = %s, schema = %s)", javaString(t.Name), javaString(t.Schema))
+}
+
+func (g *JPAGenerator) writeJava(w io.Writer, e *jpaEntity) {
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "@Entity")
+	fmt.Fprintln(w, g.tableAnnotation(e.Table))
+	if e.IDClass != "" {
+		fmt.Fprintf(w, "@IdClass(%s.class)\n", e.IDClass)
+	}
+	fmt.Fprintf(w, "class %s {\n", e.Name)
+	g.writeJavaFields(w, e.Fields)
+	fmt.Fprintln(w, "}")
+	if e.IDClass == "" {
+		return
+	}
+
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "class %s implements Serializable {\n", e.IDClass)
+	g.writeJavaFields(w, e.IDFields)
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    @Override")
+	fmt.Fprintln(w, "    public boolean equals(Object o) {")
+	fmt.Fprintln(w, "        if (this == o) {\n            return true;\n        }")
+	fmt.Fprintf(w, "        if (!(o instanceof %s other)) {\n            return false;\n        }\n", e.IDClass)
+	var equal, names []string
+	for _, f := range e.IDFields {
+		equal = append(equal, fmt.Sprintf("Objects.equals(%s, other.%s)", f.Name, f.Name))
+		names = append(names, f.Name)
+	}
+	fmt.Fprintf(w, "        return %s;\n", strings.Join(equal, " && "))
+	fmt.Fprintln(w, "    }")
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "    @Override")
+	fmt.Fprintf(w, "    public int hashCode() {\n        return Objects.hash(%s);\n    }\n", strings.Join(names, ", "))
+	fmt.Fprintln(w, "}")
+}
+
+// writeJavaFields writes the private fields of a class, with their
+// annotations, followed by their getters and setters.
+func (g *JPAGenerator) writeJavaFields(w io.Writer, fields []*jpaField) {
+
+	for _, f := range fields {
+		fmt.Fprintln(w)
+		for _, a := range f.Annotations {
+			fmt.Fprintf(w, "    %s\n", a)
+		}
+		fmt.Fprintf(w, "    private %s %s;\n", f.Type, f.Name)
+	}
+	for _, f := range fields {
+		accessor := jvmIdent(f.Name, true)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "    public %s get%s() {\n        return %s;\n    }\n", f.Type, accessor, f.Name)
+		fmt.Fprintln(w)
+		fmt.Fprintf(w, "    public void set%s(%s %s) {\n        this.%s = %s;\n    }\n", accessor, f.Type, f.Name, f.Name, f.Name)
+	}
+}
+
+func (g *JPAGenerator) writeKotlin(w io.Writer, e *jpaEntity) {
+
+	fmt.Fprintln(w)
+	fmt.Fprintln(w, "@Entity")
+	fmt.Fprintln(w, g.tableAnnotation(e.Table))
+	if e.IDClass != "" {
+		fmt.Fprintf(w, "@IdClass(%s::class)\n", e.IDClass)
+	}
+	fmt.Fprintf(w, "class %s {\n", e.Name)
+	for i, f := range e.Fields {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		for _, a := range f.Annotations {
+			fmt.Fprintf(w, "    %s\n", a)
+		}
+		if strings.HasPrefix(f.Type, "MutableList<") {
+			fmt.Fprintf(w, "    var %s: %s = mutableListOf()\n", f.Name, f.Type)
+		} else {
+			fmt.Fprintf(w, "    var %s: %s? = null\n", f.Name, f.Type)
+		}
+	}
+	fmt.Fprintln(w, "}")
+	if e.IDClass == "" {
+		return
+	}
+
+	// A data class compares its properties, and has a constructor without
+	// arguments as they all have defaults
+	fmt.Fprintln(w)
+	fmt.Fprintf(w, "data class %s(\n", e.IDClass)
+	for _, f := range e.IDFields {
+		fmt.Fprintf(w, "    var %s: %s? = null,\n", f.Name, f.Type)
+	}
+	fmt.Fprintln(w, ") : Serializable")
+}
+
+// jvmIdent converts a snake_case SQL identifier to a camelCase Java or
+// Kotlin identifier, starting in upper case for class names.
+func jvmIdent(s string, upper bool) string {
+
+	ident := camelCase(s, upper)
+	if slices.Contains(jvmKeywords, ident) {
+		ident += "_"
+	}
+	return ident
+}
+
+// camelCase converts a snake_case SQL identifier to camelCase, or to
+// PascalCase if upper is set, prefixing it with an x if it wouldn't start
+// with a letter.
+func camelCase(s string, upper bool) string {
+
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var sb strings.Builder
+	for i, word := range words {
+		runes := []rune(word)
+		if i == 0 && !upper {
+			sb.WriteString(strings.ToLower(word))
+			continue
+		}
+		sb.WriteRune(unicode.ToUpper(runes[0]))
+		sb.WriteString(string(runes[1:]))
+	}
+	ident := sb.String()
+	if ident == "" || unicode.IsDigit([]rune(ident)[0]) {
+		ident = "x" + ident
+		if upper {
+			ident = "X" + ident[1:]
+		}
+	}
+	return ident
+}
+
+// javaString quotes s as a Java or Kotlin string literal.
+func javaString(s string) string {
+
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`, "$", `\$`).Replace(s) + `"`
+}
@@ -0,0 +1,309 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestJPAGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (
+		id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+		email varchar(255) NOT NULL UNIQUE,
+		balance numeric(10, 2),
+		location point
+	);
+	CREATE TABLE posts (
+		id serial PRIMARY KEY,
+		author_id bigint NOT NULL REFERENCES users (id),
+		editor_id bigint REFERENCES users (id),
+		tags text[],
+		published_at timestamptz
+	);
+	CREATE TABLE post_tags (
+		post_id int REFERENCES posts (id),
+		tag text,
+		PRIMARY KEY (post_id, tag)
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&JPAGenerator{Language: "java", Package: "com.example.db"}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `// Generated by pgmodelgen.
+package com.example.db;
+
+import jakarta.persistence.*;
+import java.io.Serializable;
+import java.math.BigDecimal;
+import java.time.OffsetDateTime;
+import java.util.List;
+import java.util.Objects;
+
+@Entity
+@Table(name = "users")
+class User {
+
+    @Id
+    @GeneratedValue(strategy = GenerationType.IDENTITY)
+    @Column(name = "id", nullable = false)
+    private Long id;
+
+    @Column(name = "email", nullable = false, unique = true, length = 255)
+    private String email;
+
+    @Column(name = "balance", precision = 10, scale = 2)
+    private BigDecimal balance;
+
+    @Column(name = "location", columnDefinition = "point")
+    private String location;
+
+    @OneToMany(mappedBy = "author")
+    private List<Post> posts;
+
+    @OneToMany(mappedBy = "editor")
+    private List<Post> postsByEditor;
+
+    public Long getId() {
+        return id;
+    }
+
+    public void setId(Long id) {
+        this.id = id;
+    }
+
+    public String getEmail() {
+        return email;
+    }
+
+    public void setEmail(String email) {
+        this.email = email;
+    }
+
+    public BigDecimal getBalance() {
+        return balance;
+    }
+
+    public void setBalance(BigDecimal balance) {
+        this.balance = balance;
+    }
+
+    public String getLocation() {
+        return location;
+    }
+
+    public void setLocation(String location) {
+        this.location = location;
+    }
+
+    public List<Post> getPosts() {
+        return posts;
+    }
+
+    public void setPosts(List<Post> posts) {
+        this.posts = posts;
+    }
+
+    public List<Post> getPostsByEditor() {
+        return postsByEditor;
+    }
+
+    public void setPostsByEditor(List<Post> postsByEditor) {
+        this.postsByEditor = postsByEditor;
+    }
+}
+
+@Entity
+@Table(name = "posts")
+class Post {
+
+    @Id
+    @GeneratedValue(strategy = GenerationType.IDENTITY)
+    @Column(name = "id", nullable = false)
+    private Integer id;
+
+    @Column(name = "tags")
+    private String[] tags;
+
+    @Column(name = "published_at")
+    private OffsetDateTime publishedAt;
+
+    @ManyToOne(fetch = FetchType.LAZY)
+    @JoinColumn(name = "author_id", nullable = false)
+    private User author;
+
+    @ManyToOne(fetch = FetchType.LAZY)
+    @JoinColumn(name = "editor_id")
+    private User editor;
+
+    @OneToMany(mappedBy = "post")
+    private List<PostTag> postTags;
+
+    public Integer getId() {
+        return id;
+    }
+
+    public void setId(Integer id) {
+        this.id = id;
+    }
+
+    public String[] getTags() {
+        return tags;
+    }
+
+    public void setTags(String[] tags) {
+        this.tags = tags;
+    }
+
+    public OffsetDateTime getPublishedAt() {
+        return publishedAt;
+    }
+
+    public void setPublishedAt(OffsetDateTime publishedAt) {
+        this.publishedAt = publishedAt;
+    }
+
+    public User getAuthor() {
+        return author;
+    }
+
+    public void setAuthor(User author) {
+        this.author = author;
+    }
+
+    public User getEditor() {
+        return editor;
+    }
+
+    public void setEditor(User editor) {
+        this.editor = editor;
+    }
+
+    public List<PostTag> getPostTags() {
+        return postTags;
+    }
+
+    public void setPostTags(List<PostTag> postTags) {
+        this.postTags = postTags;
+    }
+}
+
+@Entity
+@Table(name = "post_tags")
+@IdClass(PostTagId.class)
+class PostTag {
+
+    @Id
+    @Column(name = "post_id", nullable = false)
+    private Integer postId;
+
+    @Id
+    @Column(name = "tag", nullable = false)
+    private String tag;
+
+    @ManyToOne(fetch = FetchType.LAZY)
+    @JoinColumn(name = "post_id", insertable = false, updatable = false)
+    private Post post;
+
+    public Integer getPostId() {
+        return postId;
+    }
+
+    public void setPostId(Integer postId) {
+        this.postId = postId;
+    }
+
+    public String getTag() {
+        return tag;
+    }
+
+    public void setTag(String tag) {
+        this.tag = tag;
+    }
+
+    public Post getPost() {
+        return post;
+    }
+
+    public void setPost(Post post) {
+        this.post = post;
+    }
+}
+
+class PostTagId implements Serializable {
+
+    private Integer postId;
+
+    private String tag;
+
+    public Integer getPostId() {
+        return postId;
+    }
+
+    public void setPostId(Integer postId) {
+        this.postId = postId;
+    }
+
+    public String getTag() {
+        return tag;
+    }
+
+    public void setTag(String tag) {
+        this.tag = tag;
+    }
+
+    @Override
+    public boolean equals(Object o) {
+        if (this == o) {
+            return true;
+        }
+        if (!(o instanceof PostTagId other)) {
+            return false;
+        }
+        return Objects.equals(postId, other.postId) && Objects.equals(tag, other.tag);
+    }
+
+    @Override
+    public int hashCode() {
+        return Objects.hash(postId, tag);
+    }
+}
+`, sb.String())
+
+	sb.Reset()
+	require.Nil(t, (&JPAGenerator{Language: "kotlin"}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), `import jakarta.persistence.*
+import java.io.Serializable
+`)
+	assert.Contains(t, sb.String(), `@Entity
+@Table(name = "users")
+class User {
+    @Id
+    @GeneratedValue(strategy = GenerationType.IDENTITY)
+    @Column(name = "id", nullable = false)
+    var id: Long? = null
+`)
+	assert.Contains(t, sb.String(), `    @OneToMany(mappedBy = "author")
+    var posts: MutableList<Post> = mutableListOf()
+`)
+	assert.Contains(t, sb.String(), "@IdClass(PostTagId::class)\nclass PostTag {\n")
+	assert.Contains(t, sb.String(), "data class PostTagId(\n    var postId: Int? = null,\n    var tag: String? = null,\n) : Serializable\n")
+	assert.Contains(t, sb.String(), "    var tags: Array<String>? = null\n")
+
+	assert.ErrorContains(t, (&JPAGenerator{Language: "scala"}).Generate(&sb, c.Catalog), `unknown JPA language "scala"`)
+}
+
+func TestJPAGenerator_NoPrimaryKey(t *testing.T) {
+	c := assertParse(t, `
+	CREATE SCHEMA audit;
+	CREATE TABLE users (id int PRIMARY KEY, email text UNIQUE);
+	CREATE TABLE audit.events (at timestamptz, email text REFERENCES users (email));
+	`)
+	var sb strings.Builder
+	require.Nil(t, (&JPAGenerator{Language: "java"}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), "\n// audit.events has no primary key, so it has no entity.\n")
+	assert.NotContains(t, sb.String(), "class Event")
+	assert.NotContains(t, sb.String(), "@OneToMany")
+}
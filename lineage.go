@@ -0,0 +1,221 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Column lineage is found as a view's or CREATE TABLE AS's output columns
+// are, by resolving the column references of each output column's
+// expression against the relations of the query. A reference which isn't
+// qualified is resolved to the first relation with a column of its name,
+// which Postgres would reject as ambiguous if there were several. Columns
+// used only to filter, join or group rows don't feed an output column, so
+// they aren't sources.
+
+// exprSources returns the columns of tables an output column's expression
+// takes its values from, where rels are the relations of its query and
+// ctes the common table expressions in scope. Subqueries are resolved
+// against their own relations, with the sources of their first column.
+func (c *Compiler) exprSources(n *pg_query.Node, rels []*queryRelation, ctes map[string][]*ViewColumn) Columns {
+
+	var ret Columns
+	var walk func(m protoreflect.Message)
+	walk = func(m protoreflect.Message) {
+		switch n := m.Interface().(type) {
+		case *pg_query.ColumnRef:
+			ret = appendSources(ret, columnRefSources(n, rels)...)
+			return
+		case *pg_query.SubLink:
+			if cols := c.selectColumns(n.Subselect.GetSelectStmt(), ctes); len(cols) > 0 {
+				ret = appendSources(ret, cols[0].Sources...)
+			}
+			return
+		}
+		m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+			switch {
+			case fd.Message() == nil || fd.IsMap():
+			case fd.IsList():
+				for i := 0; i < v.List().Len(); i++ {
+					walk(v.List().Get(i).Message())
+				}
+			default:
+				walk(v.Message())
+			}
+			return true
+		})
+	}
+	walk(n.ProtoReflect())
+	return ret
+}
+
+// columnRefSources returns the sources of the column ref refers to among
+// rels, or of all of their columns, or those of the relation it names, if
+// it ends in *.
+func columnRefSources(ref *pg_query.ColumnRef, rels []*queryRelation) Columns {
+
+	var names []string
+	star := false
+	for _, f := range ref.Fields {
+		if f.GetAStar() != nil {
+			star = true
+		} else if s := f.GetString_(); s != nil {
+			names = append(names, s.Sval)
+		}
+	}
+	var name string
+	if !star && len(names) > 0 {
+		name, names = names[len(names)-1], names[:len(names)-1]
+	}
+	var ret Columns
+	for _, rel := range rels {
+		if len(names) > 0 && rel.name != names[len(names)-1] {
+			continue
+		}
+		for _, col := range rel.columns {
+			if star {
+				ret = appendSources(ret, col.Sources...)
+			} else if col.Name == name {
+				return col.Sources
+			}
+		}
+	}
+	return ret
+}
+
+// appendSources appends those of cols which sources doesn't have yet.
+func appendSources(sources Columns, cols ...*Column) Columns {
+
+	for _, col := range cols {
+		if !slices.Contains(sources, col) {
+			sources = append(sources, col)
+		}
+	}
+	return sources
+}
+
+// ColumnLineage is where the values of a column of a view or of a table
+// created by CREATE TABLE AS come from.
+type ColumnLineage struct {
+	// Relation is the qualified name of the view or table, and Kind which
+	// it is.
+	Relation string `json:"relation"`
+	Kind     string `json:"kind"`
+	Column   string `json:"column"`
+	// Sources are the qualified names of the columns of tables the
+	// column's values are taken from.
+	Sources []string `json:"sources"`
+}
+
+// Lineage returns the lineage of the columns of cat's views and derived
+// tables, in the order they were created.
+func Lineage(cat *Catalog) []*ColumnLineage {
+
+	ret := []*ColumnLineage{}
+	add := func(relation, kind, column string, sources Columns) {
+		l := &ColumnLineage{Relation: relation, Kind: kind, Column: column, Sources: []string{}}
+		for _, src := range sources {
+			l.Sources = append(l.Sources, src.Table.Schema+"."+src.Table.Name+"."+src.Name)
+		}
+		ret = append(ret, l)
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if !t.Derived {
+				continue
+			}
+			for _, col := range t.Columns.List() {
+				add(t.Schema+"."+t.Name, "table", col.Name, col.Sources)
+			}
+		}
+	}
+	for _, raw := range cat.Raw {
+		if raw.Kind != "CREATE VIEW" {
+			continue
+		}
+		for _, col := range raw.Columns {
+			add(raw.Name, "view", col.Name, col.Sources)
+		}
+	}
+	return ret
+}
+
+// WriteLineageDOT writes lineage as a Graphviz graph, with a node for each
+// column and an edge from each source to the column it feeds.
+func WriteLineageDOT(w io.Writer, lineage []*ColumnLineage) error {
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph lineage {")
+	fmt.Fprintln(bw, "    rankdir=LR;")
+	fmt.Fprintln(bw, "    node [shape=box];")
+	for _, l := range lineage {
+		to := strconv.Quote(l.Relation + "." + l.Column)
+		for _, src := range l.Sources {
+			fmt.Fprintf(bw, "    %s -> %s;\n", strconv.Quote(src), to)
+		}
+		if len(l.Sources) == 0 {
+			fmt.Fprintf(bw, "    %s;\n", to)
+		}
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+func runLineage(args []string) error {
+
+	fs := flag.NewFlagSet("lineage", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json, dot")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "dot" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	lineage := Lineage(c.Catalog)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	switch *format {
+	case "json":
+		{
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(lineage)
+		}
+	case "dot":
+		return WriteLineageDOT(w, lineage)
+	}
+	bw := bufio.NewWriter(w)
+	rows := [][]string{{"Column", "Kind", "Sources"}}
+	for _, l := range lineage {
+		rows = append(rows, []string{l.Relation + "." + l.Column, l.Kind, strings.Join(l.Sources, ", ")})
+	}
+	writeRows(bw, rows)
+	return bw.Flush()
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLineage(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE users (id int PRIMARY KEY, first text, last text, org_id int);
+CREATE TABLE orgs (id int PRIMARY KEY, name text);
+CREATE TABLE admins (id int, name text);
+CREATE VIEW people AS
+    SELECT u.id, u.first || ' ' || u.last AS name, (SELECT o.name FROM orgs o WHERE o.id = u.org_id) AS org
+    FROM users u
+    UNION ALL
+    SELECT id, name, NULL FROM admins;
+CREATE TABLE people_copy AS WITH p AS (SELECT * FROM people) SELECT id AS person_id, upper(name)::text AS name FROM p;
+`))
+	lineage := Lineage(c.Catalog)
+	assert.Equal(t, []*ColumnLineage{
+		{Relation: "public.people_copy", Kind: "table", Column: "person_id", Sources: []string{"public.users.id", "public.admins.id"}},
+		{Relation: "public.people_copy", Kind: "table", Column: "name", Sources: []string{"public.users.first", "public.users.last", "public.admins.name"}},
+		{Relation: "public.people", Kind: "view", Column: "id", Sources: []string{"public.users.id", "public.admins.id"}},
+		{Relation: "public.people", Kind: "view", Column: "name", Sources: []string{"public.users.first", "public.users.last", "public.admins.name"}},
+		{Relation: "public.people", Kind: "view", Column: "org", Sources: []string{"public.orgs.name"}},
+	}, lineage)
+
+	var buf bytes.Buffer
+	require.Nil(t, WriteLineageDOT(&buf, lineage[4:]))
+	assert.Equal(t, "digraph lineage {\n    rankdir=LR;\n    node [shape=box];\n    \"public.orgs.name\" -> \"public.people.org\";\n}\n", buf.String())
+}
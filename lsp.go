@@ -0,0 +1,679 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// LanguageServer speaks the Language Server Protocol over a stream, for
+// editors to check and navigate schema files. The directory of each open
+// document is compiled as a migrations directory when the document is
+// opened or saved, and the catalog compiled is what definitions, hovers
+// and completions are drawn from. Unsaved changes are used to find what's
+// under the cursor, but aren't compiled.
+type LanguageServer struct {
+	r *bufio.Reader
+	w io.Writer
+	// NewCompiler returns the compiler each directory is compiled with.
+	NewCompiler func() *Compiler
+	// docs holds the text of open documents, by URI.
+	docs map[string]string
+	// dirs holds the compiled directories, by path.
+	dirs map[string]*lspDirectory
+	// shutdown is set once the client asks the server to shut down.
+	shutdown bool
+}
+
+type lspDirectory struct {
+	compiler *Compiler
+	files    []migrationFile
+	// published are the URIs diagnostics were last published for.
+	published []string
+}
+
+func NewLanguageServer(r io.Reader, w io.Writer) *LanguageServer {
+
+	return &LanguageServer{
+		r:           bufio.NewReader(r),
+		w:           w,
+		NewCompiler: func() *Compiler { return NewCompiler() },
+		docs:        make(map[string]string),
+		dirs:        make(map[string]*lspDirectory),
+	}
+}
+
+// The messages and structures of the protocol used by the server.
+type (
+	lspMessage struct {
+		JSONRPC string           `json:"jsonrpc"`
+		ID      *json.RawMessage `json:"id,omitempty"`
+		Method  string           `json:"method,omitempty"`
+		Params  json.RawMessage  `json:"params,omitempty"`
+		Result  json.RawMessage  `json:"result,omitempty"`
+		Error   *lspError        `json:"error,omitempty"`
+	}
+	lspError struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	}
+	lspPosition struct {
+		Line      int `json:"line"`
+		Character int `json:"character"`
+	}
+	lspRange struct {
+		Start lspPosition `json:"start"`
+		End   lspPosition `json:"end"`
+	}
+	lspLocation struct {
+		URI   string   `json:"uri"`
+		Range lspRange `json:"range"`
+	}
+	lspTextDocument struct {
+		URI  string `json:"uri"`
+		Text string `json:"text,omitempty"`
+	}
+	lspDocumentParams struct {
+		TextDocument   lspTextDocument `json:"textDocument"`
+		Position       lspPosition     `json:"position"`
+		ContentChanges []struct {
+			Text string `json:"text"`
+		} `json:"contentChanges"`
+	}
+	lspDiagnostic struct {
+		Range    lspRange `json:"range"`
+		Severity int      `json:"severity"`
+		Code     string   `json:"code"`
+		Source   string   `json:"source"`
+		Message  string   `json:"message"`
+	}
+	lspCompletionItem struct {
+		Label  string `json:"label"`
+		Kind   int    `json:"kind"`
+		Detail string `json:"detail,omitempty"`
+	}
+)
+
+// The codes of errors and kinds of completions used by the server.
+const (
+	lspMethodNotFound = -32601
+	lspInvalidRequest = -32600
+
+	lspCompletionField = 5
+	lspCompletionClass = 7
+)
+
+// Serve handles messages until the client exits or the stream ends.
+func (s *LanguageServer) Serve() error {
+
+	for {
+		msg, err := s.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+		result, rerr := s.handle(msg)
+		// Notifications have no response
+		if msg.ID == nil {
+			continue
+		}
+		resp := &lspMessage{JSONRPC: "2.0", ID: msg.ID, Error: rerr}
+		if rerr == nil {
+			resp.Result, err = json.Marshal(result)
+			if err != nil {
+				return err
+			}
+		}
+		err = s.write(resp)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (s *LanguageServer) handle(msg *lspMessage) (any, *lspError) {
+
+	var params lspDocumentParams
+	if len(msg.Params) > 0 {
+		err := json.Unmarshal(msg.Params, &params)
+		if err != nil {
+			return nil, &lspError{Code: lspInvalidRequest, Message: err.Error()}
+		}
+	}
+	uri := params.TextDocument.URI
+	switch msg.Method {
+	case "initialize":
+		return map[string]any{
+			"capabilities": map[string]any{
+				"textDocumentSync": map[string]any{
+					"openClose": true,
+					"change":    1, // the whole document
+					"save":      true,
+				},
+				"definitionProvider": true,
+				"hoverProvider":      true,
+				"completionProvider": map[string]any{"triggerCharacters": []string{"."}},
+			},
+			"serverInfo": map[string]any{"name": "pgmodelgen"},
+		}, nil
+	case "shutdown":
+		s.shutdown = true
+		return nil, nil
+	case "textDocument/didOpen":
+		{
+			s.docs[uri] = params.TextDocument.Text
+			if _, ok := s.dirs[s.docDir(uri)]; !ok {
+				s.compile(uri)
+			}
+		}
+	case "textDocument/didChange":
+		if len(params.ContentChanges) > 0 {
+			s.docs[uri] = params.ContentChanges[len(params.ContentChanges)-1].Text
+		}
+	case "textDocument/didSave":
+		s.compile(uri)
+	case "textDocument/didClose":
+		delete(s.docs, uri)
+	case "textDocument/definition":
+		return s.definition(uri, params.Position), nil
+	case "textDocument/hover":
+		return s.hover(uri, params.Position), nil
+	case "textDocument/completion":
+		return s.completion(uri, params.Position), nil
+	default:
+		if msg.ID != nil {
+			return nil, &lspError{Code: lspMethodNotFound, Message: "method not found: " + msg.Method}
+		}
+	}
+	return nil, nil
+}
+
+// compile compiles the directory of the document at uri, publishing the
+// diagnostics for each of its files.
+func (s *LanguageServer) compile(uri string) {
+
+	path := uriPath(uri)
+	dir := s.docDir(uri)
+	c := s.NewCompiler()
+	files, err := expandPaths([]string{dir}, c.Migrations)
+	if err == nil {
+		err = c.compileMigrations(files)
+	}
+	old := s.dirs[dir]
+	d := &lspDirectory{compiler: c, files: files}
+	s.dirs[dir] = d
+
+	byURI := make(map[string][]lspDiagnostic)
+	for _, diag := range c.Diagnostics(err) {
+		// Notes, such as for every primary key's index, would clutter
+		// the editor
+		if diag.Severity == SeverityNote {
+			continue
+		}
+		file := diag.File
+		if file == "" {
+			file = path
+		}
+		line := max(diag.Line-1, 0)
+		severity := 1
+		if diag.Severity == SeverityWarning {
+			severity = 2
+		}
+		u := pathURI(file)
+		byURI[u] = append(byURI[u], lspDiagnostic{
+			Range:    lspRange{Start: lspPosition{Line: line}, End: lspPosition{Line: line + 1}},
+			Severity: severity,
+			Code:     diag.Rule,
+			Source:   "pgmodelgen",
+			Message:  diag.Message,
+		})
+	}
+	// Files which no longer have diagnostics need them cleared
+	if old != nil {
+		for _, u := range old.published {
+			if _, ok := byURI[u]; !ok {
+				byURI[u] = []lspDiagnostic{}
+			}
+		}
+	}
+	uris := make([]string, 0, len(byURI))
+	for u, diags := range byURI {
+		if len(diags) > 0 {
+			d.published = append(d.published, u)
+		}
+		uris = append(uris, u)
+	}
+	slices.Sort(uris)
+	for _, u := range uris {
+		params, _ := json.Marshal(map[string]any{"uri": u, "diagnostics": byURI[u]})
+		_ = s.write(&lspMessage{JSONRPC: "2.0", Method: "textDocument/publishDiagnostics", Params: params})
+	}
+}
+
+// definition finds the CREATE TABLE of the table named at pos.
+func (s *LanguageServer) definition(uri string, pos lspPosition) *lspLocation {
+
+	d, text := s.dirs[s.docDir(uri)], s.text(uri)
+	if d == nil {
+		return nil
+	}
+	word := wordAt(text, positionOffset(text, pos))
+	t := d.resolveTable(word)
+	if t == nil {
+		return nil
+	}
+	for _, file := range d.files {
+		b, err := os.ReadFile(file.path)
+		if err != nil {
+			continue
+		}
+		parsed, err := ParseSource(file.source.Up(string(b)))
+		if err != nil {
+			continue
+		}
+		for _, stmt := range parsed.parse.Stmts {
+			create := stmt.Stmt.GetCreateStmt()
+			if create == nil || create.Relation.Relname != t.Name {
+				continue
+			}
+			schema := create.Relation.Schemaname
+			if schema == "" {
+				schema = d.compiler.SearchPath
+			}
+			if schema != t.Schema {
+				continue
+			}
+			offset := int(create.Relation.Location)
+			end := offset + len(wordAt(parsed.src, offset))
+			return &lspLocation{URI: pathURI(file.path), Range: lspRange{
+				Start: offsetPosition(parsed.src, offset),
+				End:   offsetPosition(parsed.src, end),
+			}}
+		}
+	}
+	return nil
+}
+
+// hover describes the table or column named at pos. Columns are looked
+// for in the tables named by the statement at pos.
+func (s *LanguageServer) hover(uri string, pos lspPosition) any {
+
+	d, text := s.dirs[s.docDir(uri)], s.text(uri)
+	if d == nil {
+		return nil
+	}
+	offset := positionOffset(text, pos)
+	word := wordAt(text, offset)
+	if word == "" {
+		return nil
+	}
+	var value string
+	if t := d.resolveTable(word); t != nil {
+		value = d.describeTable(t)
+	} else if col := d.resolveColumn(word, statementAt(text, offset)); col != nil {
+		value = d.describeColumn(col)
+	}
+	if value == "" {
+		return nil
+	}
+	return map[string]any{"contents": map[string]any{"kind": "markdown", "value": value}}
+}
+
+// completion completes the names of tables, and the columns of the tables
+// named by the statement at pos, or of the table before a dot.
+func (s *LanguageServer) completion(uri string, pos lspPosition) []lspCompletionItem {
+
+	d, text := s.dirs[s.docDir(uri)], s.text(uri)
+	items := []lspCompletionItem{}
+	if d == nil {
+		return items
+	}
+	offset := positionOffset(text, pos)
+	start := offset
+	for start > 0 && isIdentByte(text[start-1]) {
+		start--
+	}
+	prefix := strings.ToLower(text[start:offset])
+	columns := func(t *Table) {
+		for _, col := range t.Columns.List() {
+			if strings.HasPrefix(col.Name, prefix) {
+				items = append(items, lspCompletionItem{Label: col.Name, Kind: lspCompletionField, Detail: col.FormatType()})
+			}
+		}
+	}
+	if before, ok := strings.CutSuffix(strings.ToLower(text[:start]), "."); ok {
+		i := len(before)
+		for i > 0 && (isIdentByte(before[i-1]) || before[i-1] == '.') {
+			i--
+		}
+		if t := d.resolveTable(before[i:]); t != nil {
+			columns(t)
+			return items
+		}
+	}
+	for _, sch := range d.compiler.Catalog.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			name := t.Name
+			if t.Schema != d.compiler.SearchPath {
+				name = t.Schema + "." + t.Name
+			}
+			if strings.HasPrefix(name, prefix) {
+				items = append(items, lspCompletionItem{Label: name, Kind: lspCompletionClass, Detail: "table"})
+			}
+		}
+	}
+	for _, t := range d.statementTables(statementAt(text, offset)) {
+		columns(t)
+	}
+	return items
+}
+
+// resolveTable finds the table named, which is qualified by its schema
+// unless it's in the search path or the only table of that name.
+func (d *lspDirectory) resolveTable(name string) *Table {
+
+	parts := splitIdent(name)
+	cat := d.compiler.Catalog
+	switch len(parts) {
+	case 1:
+		{
+			if sch, ok := cat.Schemas.Get(d.compiler.SearchPath); ok {
+				if t, ok := sch.Tables.Get(parts[0]); ok {
+					return t
+				}
+			}
+			var found *Table
+			for _, sch := range cat.Schemas.List() {
+				if t, ok := sch.Tables.Get(parts[0]); ok {
+					if found != nil {
+						return nil
+					}
+					found = t
+				}
+			}
+			return found
+		}
+	case 2:
+		{
+			if sch, ok := cat.Schemas.Get(parts[0]); ok {
+				if t, ok := sch.Tables.Get(parts[1]); ok {
+					return t
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// resolveColumn finds the column named, which is either qualified by its
+// table or belongs to one of the tables named by stmt.
+func (d *lspDirectory) resolveColumn(name, stmt string) *Column {
+
+	parts := splitIdent(name)
+	if len(parts) > 1 {
+		t := d.resolveTable(strings.Join(parts[:len(parts)-1], "."))
+		if t == nil {
+			return nil
+		}
+		col, _ := t.Columns.Get(parts[len(parts)-1])
+		return col
+	}
+	for _, t := range d.statementTables(stmt) {
+		if col, ok := t.Columns.Get(parts[0]); ok {
+			return col
+		}
+	}
+	return nil
+}
+
+var lspIdent = regexp.MustCompile(`(?:"[^"]+"|[A-Za-z_][A-Za-z0-9_$]*)(?:\.(?:"[^"]+"|[A-Za-z_][A-Za-z0-9_$]*))?`)
+
+// statementTables returns the tables named in stmt, in the order they're
+// named.
+func (d *lspDirectory) statementTables(stmt string) []*Table {
+
+	var ret []*Table
+	for _, word := range lspIdent.FindAllString(stmt, -1) {
+		if t := d.resolveTable(word); t != nil && !slices.Contains(ret, t) {
+			ret = append(ret, t)
+		}
+	}
+	return ret
+}
+
+func (d *lspDirectory) describeTable(t *Table) string {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "```sql\nCREATE TABLE %s (\n", TableIdent(t))
+	var defs []string
+	for _, col := range t.Columns.List() {
+		defs = append(defs, ColumnDefinition(col))
+	}
+	for _, con := range d.compiler.Catalog.Depends.TableConstraints(t) {
+		defs = append(defs, ConstraintDefinition(con))
+	}
+	fmt.Fprintf(&sb, "    %s\n);\n```", strings.Join(defs, ",\n    "))
+	return sb.String()
+}
+
+func (d *lspDirectory) describeColumn(col *Column) string {
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "```sql\n%s\n```\n\nColumn of `%s.%s`", ColumnDefinition(col), col.Table.Schema, col.Table.Name)
+	dep := d.compiler.Catalog.Depends
+	var uses []string
+	cons, _ := dep.ConstraintsByColumn.Get(col)
+	for _, con := range cons {
+		uses = append(uses, ConstraintDefinition(con))
+	}
+	indexes, _ := dep.IndexesByColumn.Get(col)
+	for _, idx := range indexes {
+		uses = append(uses, IndexDefinition(idx))
+	}
+	if len(uses) > 0 {
+		slices.Sort(uses)
+		fmt.Fprintf(&sb, "\n\n```sql\n%s\n```", strings.Join(slices.Compact(uses), "\n"))
+	}
+	return sb.String()
+}
+
+// text returns the text of the document at uri, which is read from disk if
+// it isn't open.
+func (s *LanguageServer) text(uri string) string {
+
+	if text, ok := s.docs[uri]; ok {
+		return text
+	}
+	b, _ := os.ReadFile(uriPath(uri))
+	return string(b)
+}
+
+func (s *LanguageServer) docDir(uri string) string {
+
+	return filepath.Dir(uriPath(uri))
+}
+
+func (s *LanguageServer) read() (*lspMessage, error) {
+
+	length := -1
+	for {
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, _ := strings.Cut(line, ":")
+		if strings.EqualFold(name, "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length: %w", err)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length")
+	}
+	b := make([]byte, length)
+	_, err := io.ReadFull(s.r, b)
+	if err != nil {
+		return nil, err
+	}
+	var msg lspMessage
+	err = json.Unmarshal(b, &msg)
+	if err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+func (s *LanguageServer) write(msg *lspMessage) error {
+
+	b, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	return err
+}
+
+func uriPath(uri string) string {
+
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathURI(path string) string {
+
+	abs, err := filepath.Abs(path)
+	if err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
+
+// positionOffset converts a position, whose character counts UTF-16 code
+// units, to a byte offset in text.
+func positionOffset(text string, pos lspPosition) int {
+
+	offset := 0
+	for range pos.Line {
+		i := strings.IndexByte(text[offset:], '\n')
+		if i < 0 {
+			return len(text)
+		}
+		offset += i + 1
+	}
+	for units := 0; units < pos.Character && offset < len(text) && text[offset] != '\n'; {
+		r, size := utf8.DecodeRuneInString(text[offset:])
+		units += len(utf16.Encode([]rune{r}))
+		offset += size
+	}
+	return offset
+}
+
+func offsetPosition(text string, offset int) lspPosition {
+
+	offset = min(offset, len(text))
+	line := strings.Count(text[:offset], "\n")
+	start := strings.LastIndexByte(text[:offset], '\n') + 1
+	return lspPosition{Line: line, Character: len(utf16.Encode([]rune(text[start:offset])))}
+}
+
+func isIdentByte(b byte) bool {
+
+	return b == '_' || b == '$' || b == '"' || b >= '0' && b <= '9' || b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= 0x80
+}
+
+// wordAt returns the possibly qualified identifier around offset.
+func wordAt(text string, offset int) string {
+
+	start, end := offset, offset
+	for start > 0 && (isIdentByte(text[start-1]) || text[start-1] == '.') {
+		start--
+	}
+	for end < len(text) && (isIdentByte(text[end]) || text[end] == '.') {
+		end++
+	}
+	return strings.Trim(text[start:end], ".")
+}
+
+// splitIdent splits a possibly qualified identifier into its parts, folding
+// those which aren't quoted to lower case as Postgres does.
+func splitIdent(name string) []string {
+
+	var ret []string
+	for _, part := range strings.Split(name, ".") {
+		if unquoted, ok := strings.CutPrefix(part, `"`); ok {
+			ret = append(ret, strings.TrimSuffix(unquoted, `"`))
+		} else {
+			ret = append(ret, strings.ToLower(part))
+		}
+	}
+	return ret
+}
+
+// statementAt returns the text of the statement around offset, found by
+// looking for semicolons since the statement may not parse while it's
+// being edited.
+func statementAt(text string, offset int) string {
+
+	start := strings.LastIndexByte(text[:offset], ';') + 1
+	end := strings.IndexByte(text[offset:], ';')
+	if end < 0 {
+		return text[start:]
+	}
+	return text[start : offset+end]
+}
+
+func runLSP(args []string) error {
+
+	fs := flag.NewFlagSet("lsp", flag.ExitOnError)
+	version := fs.Int("pg-version", 0, "major version of Postgres to target, rejecting features it lacks")
+	lenient := fs.Bool("lenient", false, "skip what can't be modeled yet instead of failing")
+	migrations := fs.String("migrations", "auto", "how directories of migrations are laid out, auto or one of: "+migrationSourceNames())
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	s := NewLanguageServer(os.Stdin, os.Stdout)
+	s.NewCompiler = func() *Compiler {
+		strictness := StrictnessStrict
+		if *lenient {
+			strictness = StrictnessSkipUnsupported
+		}
+		c := NewCompiler(WithTargetVersion(*version), WithStrictness(strictness))
+		c.Migrations = *migrations
+		return c
+	}
+	err = s.Serve()
+	if err != nil {
+		return err
+	}
+	if !s.shutdown {
+		return fmt.Errorf("exited without shutting down")
+	}
+	return nil
+}
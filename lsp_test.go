@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// lspSession runs a language server over the messages given, returning the
+// messages it sent.
+func lspSession(t *testing.T, msgs ...map[string]any) []*lspMessage {
+	t.Helper()
+
+	var in bytes.Buffer
+	for i, msg := range msgs {
+		msg["jsonrpc"] = "2.0"
+		if _, ok := msg["notify"]; ok {
+			delete(msg, "notify")
+		} else {
+			msg["id"] = i
+		}
+		b, err := json.Marshal(msg)
+		require.Nil(t, err)
+		fmt.Fprintf(&in, "Content-Length: %d\r\n\r\n%s", len(b), b)
+	}
+	var out bytes.Buffer
+	require.Nil(t, NewLanguageServer(&in, &out).Serve())
+
+	var ret []*lspMessage
+	s := &LanguageServer{r: bufio.NewReader(&out)}
+	for {
+		msg, err := s.read()
+		if err != nil {
+			break
+		}
+		ret = append(ret, msg)
+	}
+	return ret
+}
+
+func lspResponse(t *testing.T, msgs []*lspMessage, id int, v any) {
+	t.Helper()
+
+	for _, msg := range msgs {
+		if msg.ID != nil && string(*msg.ID) == fmt.Sprint(id) {
+			require.Nil(t, msg.Error)
+			require.Nil(t, json.Unmarshal(msg.Result, v))
+			return
+		}
+	}
+	t.Fatalf("no response to %d", id)
+}
+
+func TestLanguageServer(t *testing.T) {
+	dir := t.TempDir()
+	users := filepath.Join(dir, "001_users.sql")
+	posts := filepath.Join(dir, "002_posts.sql")
+	require.Nil(t, os.WriteFile(users, []byte("CREATE TABLE users (\n    id int PRIMARY KEY,\n    email text NOT NULL\n);\n"), 0o644))
+	postsSQL := "CREATE TABLE posts (\n    id int PRIMARY KEY,\n    author int REFERENCES users (id)\n);\nALTER TABLE missing ADD COLUMN x int;\n"
+	require.Nil(t, os.WriteFile(posts, []byte(postsSQL), 0o644))
+	postsURI := pathURI(posts)
+	doc := map[string]any{"uri": postsURI}
+	at := func(line, character int) map[string]any {
+		return map[string]any{"textDocument": doc, "position": map[string]any{"line": line, "character": character}}
+	}
+
+	msgs := lspSession(t,
+		map[string]any{"method": "initialize", "params": map[string]any{}},
+		map[string]any{"method": "textDocument/didOpen", "notify": true,
+			"params": map[string]any{"textDocument": map[string]any{"uri": postsURI, "text": postsSQL}}},
+		// On "users" of REFERENCES users
+		map[string]any{"method": "textDocument/definition", "params": at(2, 28)},
+		map[string]any{"method": "textDocument/hover", "params": at(2, 28)},
+		// On "author"
+		map[string]any{"method": "textDocument/hover", "params": at(2, 6)},
+		map[string]any{"method": "textDocument/completion", "params": at(4, 12)},
+		map[string]any{"method": "shutdown"},
+		map[string]any{"method": "exit", "notify": true},
+	)
+
+	var diagnostics struct {
+		URI         string
+		Diagnostics []*lspDiagnostic
+	}
+	require.Equal(t, "textDocument/publishDiagnostics", msgs[1].Method)
+	require.Nil(t, json.Unmarshal(msgs[1].Params, &diagnostics))
+	assert.Equal(t, postsURI, diagnostics.URI)
+	require.Len(t, diagnostics.Diagnostics, 1)
+	assert.Equal(t, 4, diagnostics.Diagnostics[0].Range.Start.Line)
+	assert.Equal(t, RuleCompile, diagnostics.Diagnostics[0].Code)
+
+	var loc lspLocation
+	lspResponse(t, msgs, 2, &loc)
+	assert.Equal(t, lspLocation{URI: pathURI(users), Range: lspRange{
+		Start: lspPosition{Line: 0, Character: 13},
+		End:   lspPosition{Line: 0, Character: 18},
+	}}, loc)
+
+	var hover struct{ Contents struct{ Value string } }
+	lspResponse(t, msgs, 3, &hover)
+	assert.Contains(t, hover.Contents.Value, "CREATE TABLE users (\n    id integer,\n    email text NOT NULL,\n")
+	lspResponse(t, msgs, 4, &hover)
+	assert.Contains(t, hover.Contents.Value, "author integer")
+	assert.Contains(t, hover.Contents.Value, "FOREIGN KEY (author) REFERENCES users (id)")
+
+	var items []lspCompletionItem
+	lspResponse(t, msgs, 5, &items)
+	var labels []string
+	for _, item := range items {
+		labels = append(labels, item.Label)
+	}
+	assert.ElementsMatch(t, []string{"users", "posts"}, labels)
+}
+
+func TestLanguageServer_CompletionColumns(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "001.sql")
+	sql := "CREATE TABLE users (id int, email text, name text);\nSELECT users.e"
+	require.Nil(t, os.WriteFile(path, []byte("CREATE TABLE users (id int, email text, name text);\n"), 0o644))
+	uri := pathURI(path)
+
+	msgs := lspSession(t,
+		map[string]any{"method": "textDocument/didOpen", "notify": true,
+			"params": map[string]any{"textDocument": map[string]any{"uri": uri, "text": sql}}},
+		map[string]any{"method": "textDocument/completion",
+			"params": map[string]any{"textDocument": map[string]any{"uri": uri}, "position": map[string]any{"line": 1, "character": 14}}},
+		map[string]any{"method": "textDocument/formatting", "params": map[string]any{}},
+	)
+	var items []lspCompletionItem
+	lspResponse(t, msgs, 1, &items)
+	assert.Equal(t, []lspCompletionItem{{Label: "email", Kind: lspCompletionField, Detail: "text"}}, items)
+
+	last := msgs[len(msgs)-1]
+	require.NotNil(t, last.Error)
+	assert.True(t, strings.HasPrefix(last.Error.Message, "method not found"))
+}
@@ -1,40 +1,583 @@
 package main
 
 import (
+	"context"
 	_ "embed"
+	"errors"
+	"flag"
 	"fmt"
 	"github.com/davecgh/go-spew/spew"
-	pg_query "github.com/pganalyze/pg_query_go/v5"
 	"github.com/rs/zerolog/log"
-	"io"
 	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
 )
 
 func main() {
 
 	if len(os.Args) < 2 {
 		fmt.Println("Usage: pgmodelgen <file>")
+		fmt.Println("       pgmodelgen generate -target <target> [-out <file>] [-watch] <file>...")
+		fmt.Println("       pgmodelgen diff -from <path> -to <path> [-renames <mode>] [-fail-on <safety>] [-phase all|expand|backfill|contract] [-include-schema <glob>] [-exclude-schema <glob>] [-out <file>]")
+		fmt.Println("       pgmodelgen describe [-out <file>] <table> <file>...")
+		fmt.Println("       pgmodelgen find [-column <glob>] [-table <glob>] [-type <type>] [-not-type <type>] [-fail] <file>...")
+		fmt.Println("       pgmodelgen classify [-classify <class>=<glob>] [-classify-heuristics] [-format text|json] <file>...")
+		fmt.Println("       pgmodelgen enums [-to-table <enum> | -from-table <table> -labels <a,b>] [-name <name>] [-format text|json] <file>...")
+		fmt.Println("       pgmodelgen compat -from <path> -to <path> [-renames <mode>] [-include-schema <glob>] [-exclude-schema <glob>] [-format text|json] [-fail]")
+		fmt.Println("       pgmodelgen docs [-title <title>] -out <dir> <file>...")
+		fmt.Println("       pgmodelgen features [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen merge -base <path> -ours <path> -theirs <path> [-out <file>]")
+		fmt.Println("       pgmodelgen fingerprint [-tables] [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen hubs [-top <n>] [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen impact [-format text|json] [-out <file>] <kind> <name> <file>...")
+		fmt.Println("       pgmodelgen joins [-all] [-max-hops <n>] [-format text|json] [-out <file>] <table> <table> <file>...")
+		fmt.Println("       pgmodelgen lineage [-format text|json|dot] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen lsp [-lenient] [-migrations <source>]")
+		fmt.Println("       pgmodelgen names [-reserved <severity>] [-quoted <severity>] [-format text|json|sarif] [-fail] <file>...")
+		fmt.Println("       pgmodelgen owners [-expect <role>] [-fail] [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen reorder [-sql] [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen repl [<file>...]")
+		fmt.Println("       pgmodelgen serve [-addr <host:port>] [-timeout <duration>]")
+		fmt.Println("       pgmodelgen size [-rows <table>=<count>] [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen stats [-top <n>] [-format text|json] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen snapshot [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen smells [-max-columns <n>] [-min-group <n>] [-format text|json|sarif] [-fail] <file>...")
+		fmt.Println("       pgmodelgen squash [-keep <n>] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen unused [-format text|json|sarif] [-fail] <file>...")
+		fmt.Println("       pgmodelgen verify [-db <connection string>] [-psql-bin <path>] [-out <file>] <file>...")
+		fmt.Println("       pgmodelgen verify-down [-format text|json] [-out <file>] <file>...")
 		os.Exit(1)
 	}
 
-	f, err := os.Open(os.Args[1])
+	switch os.Args[1] {
+	case "generate":
+		{
+			err := runGenerate(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "diff":
+		{
+			err := runDiff(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "compat":
+		{
+			err := runCompat(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "describe":
+		{
+			err := runDescribe(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "docs":
+		{
+			err := runDocs(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "find":
+		{
+			err := runFind(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "classify":
+		{
+			err := runClassify(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "enums":
+		{
+			err := runEnums(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "features":
+		{
+			err := runFeatures(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "hubs":
+		{
+			err := runHubs(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "impact":
+		{
+			err := runImpact(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "merge":
+		{
+			err := runMerge(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "fingerprint":
+		{
+			err := runFingerprint(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "joins":
+		{
+			err := runJoins(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "lineage":
+		{
+			err := runLineage(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "lsp":
+		{
+			err := runLSP(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "owners":
+		{
+			err := runOwners(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "reorder":
+		{
+			err := runReorder(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "repl":
+		{
+			err := runRepl(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "names":
+		{
+			err := runNames(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "serve":
+		{
+			err := runServe(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "size":
+		{
+			err := runSize(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "smells":
+		{
+			err := runSmells(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "snapshot":
+		{
+			err := runSnapshot(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "stats":
+		{
+			err := runStats(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "squash":
+		{
+			err := runSquash(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "unused":
+		{
+			err := runUnused(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "verify":
+		{
+			err := runVerify(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "verify-down":
+		{
+			err := runVerifyDown(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	case "bench":
+		{
+			// Left out of the usage, as it measures pgmodelgen itself
+			err := runBench(os.Args[2:])
+			if err != nil {
+				fatal(err)
+			}
+		}
+	default:
+		{
+			compiler, err := CompileFiles(os.Args[1:2])
+			if err != nil {
+				fatal(err)
+			}
+			spew.Dump(compiler.Catalog)
+		}
+	}
+}
+
+// errReported is returned once an error has been reported in the format
+// asked for, so that it isn't reported again.
+var errReported = errors.New("errors were reported")
+
+func fatal(err error) {
+
+	if errors.Is(err, errReported) {
+		os.Exit(1)
+	}
+	log.Fatal().Err(err).Send()
+}
+
+// CompileFiles parses each of the files in order into a new Compiler. A
+// directory is expanded to the migrations inside it, in the order the
+// migration tool it's laid out for applies them. Dumps written by pg_dump
+// -Fc are recognised, and the objects in their table of contents compiled.
+func CompileFiles(paths []string) (*Compiler, error) {
+
+	compiler := NewCompiler()
+	err := compiler.CompileFiles(paths)
 	if err != nil {
-		log.Fatal().Err(err).Send()
+		return nil, err
 	}
-	defer f.Close()
-	b, err := io.ReadAll(f)
+	return compiler, nil
+}
+
+// CompileFiles parses each of the files in order, as the function of the
+// same name does. Warnings are prefixed with the file they occur in.
+//
+// Up to Workers files are parsed concurrently ahead of the file being
+// applied, while the files are applied one at a time in order. Files of at
+// least StreamSize bytes, or every file if Psql is set, are instead compiled
+// with CompileReader when their turn comes.
+func (c *Compiler) CompileFiles(paths []string) error {
+
+	if err := c.ctxErr(); err != nil {
+		return err
+	}
+	files, err := expandPaths(paths, c.Migrations)
 	if err != nil {
-		log.Fatal().Err(err).Send()
+		return err
 	}
+	return c.compileMigrations(files)
+}
 
-	compiler := NewCompiler()
-	parse, err := pg_query.Parse(string(b))
+// CompileFilesContext compiles the files as CompileFiles does, stopping
+// with ctx's error once it's done. Files are checked between statements, so
+// a statement being applied or a file being parsed when ctx is done is
+// finished first.
+func (c *Compiler) CompileFilesContext(ctx context.Context, paths []string) error {
+
+	defer c.withContext(ctx)()
+	return c.CompileFiles(paths)
+}
+
+func (c *Compiler) compileMigrations(files []migrationFile) error {
+
+	type result struct {
+		parsed *ParsedSource
+		stream bool
+		err    error
+	}
+	results := make([]chan result, len(files))
+	for i := range results {
+		results[i] = make(chan result, 1)
+	}
+	// A slot is taken for each file being parsed and freed once the file
+	// is applied, so parse trees don't pile up waiting to be applied
+	slots := make(chan struct{}, max(c.Workers, 1))
+	done := make(chan struct{})
+	defer close(done)
+	// The context is reset once the compile returns, which the producer
+	// may outlive
+	cancelled := c.ctxDone()
+	go func() {
+		for i, file := range files {
+			path := file.path
+			select {
+			case slots <- struct{}{}:
+			case <-done:
+				return
+			case <-cancelled:
+				return
+			}
+			go func() {
+				info, err := os.Stat(path)
+				if err != nil {
+					results[i] <- result{err: err}
+					return
+				}
+				// Dumps are large, but only their table of contents is read
+				if schema, ok, err := readDumpFile(path); ok || err != nil {
+					if err != nil {
+						results[i] <- result{err: err}
+						return
+					}
+					parsed, err := ParseSource(schema)
+					results[i] <- result{parsed: parsed, err: err}
+					return
+				}
+				// Only whole files are streamed, not parts of them
+				_, plain := file.source.(plainMigrations)
+				if c.Psql || (plain && c.StreamSize > 0 && info.Size() >= c.StreamSize) {
+					results[i] <- result{stream: true}
+					return
+				}
+				if parsed := c.ParseCache.get(path, info); parsed != nil {
+					results[i] <- result{parsed: parsed}
+					return
+				}
+				b, err := os.ReadFile(path)
+				if err != nil {
+					results[i] <- result{err: err}
+					return
+				}
+				parsed, err := ParseSource(file.source.Up(string(b)))
+				if err == nil {
+					c.ParseCache.put(path, info, parsed)
+				}
+				results[i] <- result{parsed: parsed, err: err}
+			}()
+		}
+	}()
+
+	for i, file := range files {
+		path := file.path
+		var res result
+		select {
+		case res = <-results[i]:
+		case <-cancelled:
+			return c.ctx.Err()
+		}
+		err := res.err
+		if err == nil {
+			warnings, diags, skipped := len(c.Warnings), len(c.diagnostics), len(c.Skipped)
+			if res.stream {
+				err = c.compileFile(path)
+			} else {
+				c.files = append(c.files, path)
+				err = c.Apply(res.parsed)
+				c.files = c.files[:len(c.files)-1]
+			}
+			for j := warnings; j < len(c.Warnings); j++ {
+				c.Warnings[j] = path + ": " + c.Warnings[j]
+			}
+			for _, d := range c.diagnostics[diags:] {
+				if d.File == "" {
+					d.File = path
+				}
+			}
+			for _, s := range c.Skipped[skipped:] {
+				if s.File == "" {
+					s.File = path
+				}
+			}
+		}
+		<-slots
+		if err != nil {
+			setErrorFile(err, path)
+			return fmt.Errorf("while compiling %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) compileFile(path string) error {
+
+	abs, err := filepath.Abs(path)
 	if err != nil {
-		log.Fatal().Err(err).Send()
+		return err
+	}
+	if slices.Contains(c.files, abs) {
+		return fmt.Errorf("%s includes itself", path)
 	}
-	err = compiler.ParseStatements(parse)
+	f, err := os.Open(path)
 	if err != nil {
-		log.Fatal().Err(err).Send()
+		return err
+	}
+	defer f.Close()
+	c.files = append(c.files, abs)
+	defer func() { c.files = c.files[:len(c.files)-1] }()
+	return c.CompileReader(f)
+}
+
+// compilerFlags registers the flags configuring a Compiler on fs. The
+// function returned compiles files using them once fs is parsed, writing
+// any warnings to stderr.
+func compilerFlags(fs *flag.FlagSet) func(paths []string) (*Compiler, error) {
+
+	run := compilerRunner(fs)
+	timeout := fs.Duration("timeout", 0, "how long compiling may take before giving up, or 0 for no limit")
+	return func(paths []string) (*Compiler, error) {
+		return run(func(c *Compiler) error {
+			ctx := context.Background()
+			if *timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, *timeout)
+				defer cancel()
+			}
+			return c.CompileFilesContext(ctx, paths)
+		})
+	}
+}
+
+// compilerRunner registers the flags configuring a Compiler on fs, as
+// compilerFlags does. The function returned configures a Compiler using
+// them once fs is parsed and passes it to compile, writing any warnings to
+// stderr.
+func compilerRunner(fs *flag.FlagSet) func(compile func(*Compiler) error) (*Compiler, error) {
+
+	version := fs.Int("pg-version", 0, "major version of Postgres to target, rejecting features it lacks")
+	warn := fs.Bool("pg-version-warn", false, "warn about features the target version lacks instead of failing")
+	workers := fs.Int("workers", runtime.GOMAXPROCS(0), "number of files to parse at once")
+	lenient := fs.Bool("lenient", false, "skip what can't be modeled yet instead of failing")
+	skipped := fs.String("skipped", "", "summarise the statements which weren't modeled to stderr, as text or json")
+	psql := fs.Bool("psql", false, "run psql meta-commands such as \\i and substitute psql variables")
+	migrations := fs.String("migrations", "auto", "how directories of migrations are laid out, auto or one of: "+migrationSourceNames())
+	errorFormat := fs.String("error-format", "text", "how to write errors and warnings to stderr, one of: text, json, sarif")
+	warningsAsErrors := fs.String("warnings-as-errors", "", "comma-separated rules whose warnings fail the run, or all")
+	searchPath := fs.String("search-path", "public", "schema unqualified names are resolved in")
+	extensions := fs.String("extensions", "", "comma-separated extensions to treat as installed, making their types known")
+	collapsePartitions := fs.Bool("collapse-partitions", false, "record partitions on their parents as a summary rather than as tables, for schemas with very many")
+	temporary := fs.Bool("temp-objects", false, "keep temporary tables, sequences and views, in the pg_temp schema, rather than dropping them once compiled")
+	normalize := fs.Bool("normalize-identifiers", false, "normalize identifiers to NFC, so that those which look the same are the same name")
+	validate := fs.Bool("validate-catalog", false, "check the catalog is consistent after each statement, for debugging pgmodelgen")
+	classifier := classifierFlags(fs)
+	vars := make(map[string]string)
+	fs.Func("v", "set a psql variable, as name=value", func(s string) error {
+		name, value, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("expected name=value")
+		}
+		vars[name] = value
+		return nil
+	})
+	return func(compile func(*Compiler) error) (*Compiler, error) {
+		if *errorFormat != "text" && *errorFormat != "json" && *errorFormat != "sarif" {
+			return nil, fmt.Errorf("unknown error format %q", *errorFormat)
+		}
+		var promote []string
+		if *warningsAsErrors != "" {
+			promote = strings.Split(*warningsAsErrors, ",")
+			for _, rule := range promote {
+				if _, ok := ruleDescriptions[rule]; !ok && rule != "all" {
+					return nil, fmt.Errorf("unknown rule %q", rule)
+				}
+			}
+		}
+		strictness := StrictnessStrict
+		if *warn {
+			strictness |= StrictnessWarnVersion
+		}
+		if *lenient {
+			strictness |= StrictnessSkipUnsupported
+		}
+		opts := []CompilerOption{WithSearchPath(*searchPath), WithTargetVersion(*version), WithStrictness(strictness)}
+		if *extensions != "" {
+			opts = append(opts, WithExtensions(strings.Split(*extensions, ",")...))
+		}
+		compiler := NewCompiler(opts...)
+		compiler.WarningsAsErrors = promote
+		compiler.Workers = *workers
+		compiler.Psql = *psql
+		compiler.Vars = vars
+		compiler.Migrations = *migrations
+		compiler.CollapsePartitions = *collapsePartitions
+		compiler.ValidateCatalog = *validate
+		compiler.NormalizeIdentifiers = *normalize
+		err := compile(compiler)
+		promoted := compiler.promotedWarnings()
+		if *errorFormat != "text" {
+			werr := WriteDiagnostics(os.Stderr, compiler.Diagnostics(err), *errorFormat)
+			if werr != nil {
+				return nil, werr
+			}
+			if err != nil || len(promoted) > 0 {
+				return nil, errReported
+			}
+		} else {
+			for _, w := range compiler.Warnings {
+				fmt.Fprintln(os.Stderr, "warning:", w)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		if len(promoted) > 0 {
+			return nil, fmt.Errorf("%d warnings treated as errors, the first being: %s", len(promoted), promoted[0].Message)
+		}
+		// The session the statements ran in is over, and its temporary
+		// objects with it
+		if !*temporary {
+			err = compiler.DropTemporary()
+			if err != nil {
+				return nil, err
+			}
+		}
+		classifier.Classify(compiler.Catalog)
+		if *skipped != "" {
+			err = WriteSkipSummary(os.Stderr, compiler.Skipped, *skipped)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return compiler, nil
 	}
-	spew.Dump(compiler.Catalog)
 }
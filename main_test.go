@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_CompileFiles(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	// Each migration depends on the one before it
+	write("000.sql", "CREATE TABLE t0 (id int PRIMARY KEY);\n")
+	for i := 1; i < 100; i++ {
+		write(fmt.Sprintf("%03d.sql", i), fmt.Sprintf(
+			"CREATE TABLE t%d (id int PRIMARY KEY, prev int REFERENCES t%d (id));\nALTER TABLE t%d ADD COLUMN c%d text;\n",
+			i, i-1, i-1, i))
+	}
+	write("050.down.sql", "DROP TABLE t50;\n")
+
+	var ddl []string
+	for _, workers := range []int{1, 8} {
+		c := NewCompiler()
+		c.Workers = workers
+		require.Nil(t, c.CompileFiles([]string{dir}))
+		assertTable(t, c, "public.t50")
+		var sb strings.Builder
+		require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+		ddl = append(ddl, sb.String())
+	}
+	assert.Equal(t, ddl[0], ddl[1])
+
+	write("042.sql", "ALTER TABLE missing ADD COLUMN x int;\n")
+	c := NewCompiler()
+	c.Workers = 8
+	err := c.CompileFiles([]string{dir})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "042.sql")
+}
+
+func TestCompiler_CompileFilesContext(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 20; i++ {
+		sql := fmt.Sprintf("CREATE TABLE t%d (id int PRIMARY KEY);\nALTER TABLE t%d ADD COLUMN c text;\n", i, i)
+		require.Nil(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%03d.sql", i)), []byte(sql), 0o644))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	c := NewCompiler()
+	assert.ErrorIs(t, c.CompileFilesContext(ctx, []string{dir}), context.Canceled)
+	assert.Empty(t, c.Catalog.Schemas.List()[0].Tables.List())
+
+	// Cancelling while a statement is applied stops before the next one
+	ctx, cancel = context.WithCancel(context.Background())
+	defer cancel()
+	c = NewCompiler(WithDiagnosticsSink(func(d *Diagnostic) {
+		if strings.Contains(d.Message, "t10_pkey") {
+			cancel()
+		}
+	}))
+	assert.ErrorIs(t, c.CompileFilesContext(ctx, []string{dir}), context.Canceled)
+	t10 := assertTable(t, c, "t10")
+	_, ok := t10.Columns.Get("c")
+	assert.False(t, ok)
+	_, ok = c.Catalog.Schemas.List()[0].Tables.Get("t11")
+	assert.False(t, ok)
+	assert.Nil(t, c.ctx)
+}
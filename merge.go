@@ -0,0 +1,237 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// Conflict is a pair of changes made on two branches which can't both be
+// applied. Ours and Theirs may contain several changes to one object, such
+// as a constraint being dropped and added again.
+type Conflict struct {
+	Ours   Changes
+	Theirs Changes
+	Reason string
+}
+
+func (c *Conflict) String() string {
+
+	return fmt.Sprintf("%s\n  ours:   %s\n  theirs: %s", c.Reason,
+		strings.Join(c.Ours.SQL(), " "), strings.Join(c.Theirs.SQL(), " "))
+}
+
+type MergeResult struct {
+	// Catalog is base with every change which didn't conflict applied.
+	Catalog *Catalog
+	// Changes are the changes applied to base, in order.
+	Changes   Changes
+	Conflicts []*Conflict
+}
+
+// Merge performs a three-way merge of the changes made to base by ours and
+// theirs. Changes to the same object on both branches are merged if they are
+// identical and reported as conflicts otherwise, as are changes to objects
+// which the other branch dropped. Conflicting changes are left out of the
+// merged catalog. Statements kept verbatim, other than views, aren't
+// compared, so it fails if either branch changed them.
+func Merge(base, ours, theirs *Catalog) (*MergeResult, error) {
+
+	for _, branch := range []struct {
+		name string
+		cat  *Catalog
+	}{{"our", ours}, {"their", theirs}} {
+		if !slices.Equal(unmergedRaw(base), unmergedRaw(branch.cat)) {
+			return nil, fmt.Errorf("can't merge the statements other than views which %s branch kept verbatim, as they aren't compared", branch.name)
+		}
+	}
+	ourChanges, theirChanges := Diff(base, ours, DiffOptions{}), Diff(base, theirs, DiffOptions{})
+	ret := &MergeResult{}
+	excluded := make(map[*Change]bool)
+	conflict := func(o, t Changes, reason string) {
+		ret.Conflicts = append(ret.Conflicts, &Conflict{Ours: o, Theirs: t, Reason: reason})
+		for _, c := range append(o, t...) {
+			excluded[c] = true
+		}
+	}
+
+	oursByKey, theirsByKey := groupChanges(ourChanges), groupChanges(theirChanges)
+	for _, o := range ourChanges {
+		oc, tc := oursByKey[o.Key()], theirsByKey[o.Key()]
+		if len(tc) == 0 || oc[0] != o {
+			continue
+		}
+		if slices.Equal(oc.SQL(), tc.SQL()) {
+			// Both branches made the same change, so only apply it once
+			for _, t := range tc {
+				excluded[t] = true
+			}
+			continue
+		}
+		if o.Kind == ChangeKindAdd && tc[0].Kind == ChangeKindAdd {
+			conflict(oc, tc, fmt.Sprintf("%s %s added differently on both branches", o.Object, o.Path()))
+		} else {
+			conflict(oc, tc, fmt.Sprintf("%s %s changed differently on both branches", o.Object, o.Path()))
+		}
+	}
+
+	dropConflicts := func(drops, others Changes, ours bool) {
+		for _, d := range drops {
+			if d.Kind != ChangeKindDrop || (d.Object != ObjectKindSchema && d.Object != ObjectKindTable) {
+				continue
+			}
+			for _, c := range others {
+				if excluded[c] || c.Key() == d.Key() || !changeAffects(c, d) {
+					continue
+				}
+				reason := fmt.Sprintf("%s %s dropped on one branch but changed on the other", d.Object, d.Path())
+				if ours {
+					conflict(Changes{d}, Changes{c}, reason)
+				} else {
+					conflict(Changes{c}, Changes{d}, reason)
+				}
+				// Keep everything inside the object, since it won't be dropped
+				for _, inner := range drops {
+					if changeWithin(inner, d) {
+						excluded[inner] = true
+					}
+				}
+			}
+		}
+	}
+	dropConflicts(ourChanges, theirChanges, true)
+	dropConflicts(theirChanges, ourChanges, false)
+
+	for _, c := range append(ourChanges, theirChanges...) {
+		if !excluded[c] {
+			ret.Changes = append(ret.Changes, c)
+		}
+	}
+	ret.Changes.Sort()
+
+	var sb strings.Builder
+	err := (&DDLGenerator{}).Generate(&sb, base)
+	if err != nil {
+		return nil, err
+	}
+	// The DDL leaves out sequences, whose options aren't known
+	for _, sch := range base.Schemas.List() {
+		for _, seq := range sch.Sequences.List() {
+			sb.WriteString(SequenceDefinition(seq) + ";\n")
+			if seq.OwnedBy != nil {
+				fmt.Fprintf(&sb, "ALTER SEQUENCE %s OWNED BY %s;\n", SequenceIdent(seq), sequenceOwner(seq))
+			}
+		}
+	}
+	for _, stmt := range ret.Changes.SQL() {
+		sb.WriteString(stmt + "\n")
+	}
+	compiler := NewCompiler()
+	err = compiler.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("while applying merged changes: %w", err)
+	}
+	ret.Catalog = compiler.Catalog
+	return ret, nil
+}
+
+// groupChanges groups changes by the object they change.
+func groupChanges(changes Changes) map[string]Changes {
+
+	ret := make(map[string]Changes)
+	for _, c := range changes {
+		ret[c.Key()] = append(ret[c.Key()], c)
+	}
+	return ret
+}
+
+// changeWithin reports whether c changes scope or an object inside it.
+// unmergedRaw returns the SQL of the statements of cat kept verbatim which
+// Diff doesn't compare.
+func unmergedRaw(cat *Catalog) []string {
+
+	var stmts []string
+	for _, raw := range cat.Raw {
+		if raw.Kind != "CREATE VIEW" {
+			stmts = append(stmts, raw.SQL)
+		}
+	}
+	return stmts
+}
+
+func changeWithin(c, scope *Change) bool {
+
+	if c.Schema != scope.Schema {
+		return false
+	}
+	return scope.Object == ObjectKindSchema || c.Table == scope.Table
+}
+
+// changeAffects reports whether c depends on the object dropped by drop,
+// either by changing something inside it or by adding a reference to it.
+func changeAffects(c, drop *Change) bool {
+
+	if changeWithin(c, drop) {
+		return true
+	}
+	con, ok := c.To.(*Constraint)
+	if !ok || con.Type != ConstraintTypeForeignKey {
+		return false
+	}
+	ref := con.Refers[0].Table
+	return ref.Schema == drop.Schema && (drop.Object == ObjectKindSchema || ref.Name == drop.Table)
+}
+
+func runMerge(args []string) error {
+
+	fs := flag.NewFlagSet("merge", flag.ExitOnError)
+	base := fs.String("base", "", "migrations of the common ancestor")
+	ours := fs.String("ours", "", "migrations of our branch")
+	theirs := fs.String("theirs", "", "migrations of their branch")
+	out := fs.String("out", "", "file to write the merged schema to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *base == "" || *ours == "" || *theirs == "" {
+		return fmt.Errorf("-base, -ours and -theirs are required")
+	}
+
+	var cats []*Catalog
+	for _, path := range []string{*base, *ours, *theirs} {
+		c, err := compile([]string{path})
+		if err != nil {
+			return err
+		}
+		cats = append(cats, c.Catalog)
+	}
+	res, err := Merge(cats[0], cats[1], cats[2])
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	err = (&DDLGenerator{}).Generate(w, res.Catalog)
+	if err != nil {
+		return err
+	}
+	for _, c := range res.Conflicts {
+		fmt.Fprintln(os.Stderr, "conflict:", c)
+	}
+	if len(res.Conflicts) > 0 {
+		return fmt.Errorf("%d conflicts", len(res.Conflicts))
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+const mergeBase = `
+CREATE TABLE users (
+	id serial PRIMARY KEY,
+	name text
+);
+CREATE TABLE posts (
+	id serial PRIMARY KEY,
+	user_id int REFERENCES users (id),
+	body text
+);
+`
+
+func TestMerge_Independent(t *testing.T) {
+	base := assertParse(t, mergeBase)
+	ours := assertParse(t, mergeBase+`
+	ALTER TABLE users ADD COLUMN email text UNIQUE;
+	ALTER TABLE posts ALTER COLUMN body SET NOT NULL;
+	`)
+	theirs := assertParse(t, mergeBase+`
+	CREATE TABLE comments (id serial PRIMARY KEY, post_id int NOT NULL REFERENCES posts (id));
+	ALTER TABLE users ALTER COLUMN name TYPE varchar(100);
+	ALTER TABLE posts ALTER COLUMN body SET NOT NULL;
+	`)
+	res, err := Merge(base.Catalog, ours.Catalog, theirs.Catalog)
+	require.Nil(t, err)
+	assert.Empty(t, res.Conflicts)
+	// The same change on both branches should only be applied once
+	assert.Len(t, res.Changes, 7)
+
+	merged := &Compiler{Catalog: res.Catalog}
+	users := assertTable(t, merged, "public.users")
+	assertColumn(t, users, "email", Text, ColumnAttributes{})
+	assertColumn(t, users, "name", CharacterVarying, ColumnAttributes{})
+	posts := assertTable(t, merged, "public.posts")
+	assertColumn(t, posts, "body", Text, ColumnAttributes{NotNull: true})
+	comments := assertTable(t, merged, "public.comments")
+	assertColumn(t, comments, "post_id", Integer, ColumnAttributes{NotNull: true})
+}
+
+func TestMerge_Conflicts(t *testing.T) {
+	base := assertParse(t, mergeBase)
+	ours := assertParse(t, mergeBase+`
+	ALTER TABLE users ALTER COLUMN name TYPE varchar(100);
+	ALTER TABLE users ADD COLUMN email text;
+	DROP TABLE posts;
+	ALTER TABLE users ADD COLUMN active bool;
+	`)
+	theirs := assertParse(t, mergeBase+`
+	ALTER TABLE users ALTER COLUMN name TYPE varchar(200);
+	ALTER TABLE users ADD COLUMN email varchar(320);
+	ALTER TABLE posts ADD COLUMN title text;
+	`)
+	res, err := Merge(base.Catalog, ours.Catalog, theirs.Catalog)
+	require.Nil(t, err)
+
+	var conflicts []string
+	for _, c := range res.Conflicts {
+		conflicts = append(conflicts, c.Reason)
+	}
+	assert.ElementsMatch(t, []string{
+		"column public.users.name changed differently on both branches",
+		"column public.users.email added differently on both branches",
+		"table public.posts dropped on one branch but changed on the other",
+	}, conflicts)
+
+	// Only the change which didn't conflict is applied
+	merged := &Compiler{Catalog: res.Catalog}
+	users := assertTable(t, merged, "public.users")
+	assertColumn(t, users, "name", Text, ColumnAttributes{})
+	assertColumn(t, users, "active", Boolean, ColumnAttributes{})
+	_, ok := users.Columns.Get("email")
+	assert.False(t, ok)
+	posts := assertTable(t, merged, "public.posts")
+	_, ok = posts.Columns.Get("title")
+	assert.False(t, ok)
+	assertColumn(t, posts, "user_id", Integer, ColumnAttributes{})
+}
+
+func TestMerge_DroppedReference(t *testing.T) {
+	base := assertParse(t, mergeBase)
+	ours := assertParse(t, mergeBase+`
+	CREATE TABLE likes (user_id int REFERENCES users (id));
+	`)
+	theirs := assertParse(t, `
+	CREATE TABLE posts (id serial PRIMARY KEY, body text);
+	`)
+	res, err := Merge(base.Catalog, ours.Catalog, theirs.Catalog)
+	require.Nil(t, err)
+	require.Len(t, res.Conflicts, 1)
+	assert.Equal(t, "table public.users dropped on one branch but changed on the other", res.Conflicts[0].Reason)
+	assertTable(t, &Compiler{Catalog: res.Catalog}, "public.users")
+}
+
+func TestMerge_EnumsViewsAndSequences(t *testing.T) {
+	base := assertParse(t, mergeBase+`
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE SEQUENCE invoice_numbers OWNED BY posts.id;
+	`)
+	ours := assertParse(t, mergeBase+`
+	CREATE TYPE mood AS ENUM ('happy', 'sad', 'bored');
+	CREATE SEQUENCE invoice_numbers OWNED BY posts.id;
+	CREATE TYPE status AS ENUM ('draft', 'published');
+	ALTER TABLE posts ADD COLUMN status status;
+	`)
+	theirs := assertParse(t, mergeBase+`
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE SEQUENCE invoice_numbers OWNED BY posts.id;
+	CREATE VIEW user_names AS SELECT name FROM users;
+	`)
+	res, err := Merge(base.Catalog, ours.Catalog, theirs.Catalog)
+	require.Nil(t, err)
+	assert.Empty(t, res.Conflicts)
+
+	merged := &Compiler{Catalog: res.Catalog}
+	sch, _ := res.Catalog.Schemas.Get("public")
+	mood, ok := sch.Enums.Get("mood")
+	require.True(t, ok)
+	assert.Equal(t, []string{"happy", "sad", "bored"}, mood.Labels)
+	_, ok = sch.Enums.Get("status")
+	assert.True(t, ok)
+	assert.NotNil(t, merged.findOpaque("CREATE VIEW", "public.user_names"))
+	seq, ok := sch.Sequences.Get("invoice_numbers")
+	require.True(t, ok)
+	id, _ := assertTable(t, merged, "public.posts").Columns.Get("id")
+	assert.Equal(t, id, seq.OwnedBy)
+
+	rules := assertParse(t, mergeBase+`
+	CREATE RULE no_deletes AS ON DELETE TO posts DO INSTEAD NOTHING;
+	`)
+	_, err = Merge(base.Catalog, base.Catalog, rules.Catalog)
+	assert.ErrorContains(t, err, "can't merge the statements other than views which their branch kept verbatim")
+}
+
+func TestCompiler_DropSchema(t *testing.T) {
+	assertParseError(t, `
+	CREATE SCHEMA app;
+	CREATE TABLE app.users (id int);
+	DROP SCHEMA app;
+	`, "app")
+	c := assertParse(t, `
+	CREATE SCHEMA app;
+	CREATE TABLE app.users (id int);
+	DROP SCHEMA app CASCADE;
+	DROP SCHEMA IF EXISTS other;
+	`)
+	_, ok := c.Catalog.Schemas.Get("app")
+	assert.False(t, ok)
+
+	// Views, operators and aggregates go with their schema
+	assertParseError(t, `
+	CREATE SCHEMA app;
+	CREATE VIEW app.answer AS SELECT 42 AS n;
+	DROP SCHEMA app;
+	`, "can't drop schema app because it contains views and cascade was not specified")
+	c = assertParse(t, `
+	CREATE TABLE users (id int);
+	CREATE SCHEMA app;
+	CREATE VIEW app.user_ids AS SELECT id FROM users;
+	CREATE VIEW user_count AS SELECT count(*) FROM app.user_ids;
+	CREATE OPERATOR app.=== (LEFTARG = int, RIGHTARG = int, FUNCTION = int4eq);
+	DROP SCHEMA app CASCADE;
+	CREATE SCHEMA app;
+	CREATE VIEW app.user_ids AS SELECT id FROM users;
+	`)
+	require.Len(t, c.Catalog.Raw, 1)
+	assert.Equal(t, "app.user_ids", c.Catalog.Raw[0].Name)
+}
+
+func TestCompiler_AlterTable_AlterColumn(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (name text, tags text);
+	ALTER TABLE users ALTER COLUMN name SET NOT NULL, ALTER COLUMN tags TYPE varchar(20)[];
+	`)
+	tab := assertTable(t, c, "public.users")
+	assertColumn(t, tab, "name", Text, ColumnAttributes{NotNull: true})
+	tags := assertColumn(t, tab, "tags", CharacterVarying, ColumnAttributes{})
+	assert.Equal(t, "character varying(20)[]", tags.FormatType())
+}
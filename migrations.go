@@ -0,0 +1,405 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"github.com/samber/lo"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// MigrationSource understands how a migration tool lays out a directory of
+// migrations.
+type MigrationSource interface {
+	// Detect reports whether the files in dir, given by name, follow the
+	// tool's conventions.
+	Detect(dir string, names []string) bool
+	// Migrations returns the files of dir which migrate up, in the order
+	// the tool applies them.
+	Migrations(dir string, names []string) ([]string, error)
+	// Up returns the part of a migration's source which migrates up, with
+	// the rest blanked out so that line numbers are unchanged.
+	Up(src string) string
+	// Down returns the source which reverses the migration at path, given
+	// its source, or false if there isn't one.
+	Down(path, src string) (string, bool, error)
+}
+
+// migrationSources maps the names accepted by -migrations to sources.
+var migrationSources = map[string]MigrationSource{
+	"atlas":  atlasMigrations{},
+	"flyway": flywayMigrations{},
+	"goose":  gooseMigrations{},
+	"plain":  plainMigrations{},
+}
+
+// detectOrder is the order sources are detected in. Atlas can also manage
+// goose and Flyway directories, so its own layout is tried after theirs,
+// and any directory is plain.
+var detectOrder = []string{"goose", "flyway", "atlas", "plain"}
+
+// migrationFile is a file to compile, with the source which knows how to
+// read it.
+type migrationFile struct {
+	path   string
+	source MigrationSource
+}
+
+// expandPaths expands directories to the migrations inside them, using the
+// source named or else the source detected. Files named directly are read
+// using the source detected for them alone.
+func expandPaths(paths []string, sourceName string) ([]migrationFile, error) {
+
+	var ret []migrationFile
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		dir, names := filepath.Dir(path), []string{filepath.Base(path)}
+		if info.IsDir() {
+			entries, err := os.ReadDir(path)
+			if err != nil {
+				return nil, err
+			}
+			dir, names = path, nil
+			for _, e := range entries {
+				if !e.IsDir() {
+					names = append(names, e.Name())
+				}
+			}
+		}
+		source, err := migrationSource(sourceName, dir, names)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			ret = append(ret, migrationFile{path: path, source: source})
+			continue
+		}
+		files, err := source.Migrations(dir, names)
+		if err != nil {
+			return nil, fmt.Errorf("while listing migrations in %s: %w", dir, err)
+		}
+		for _, f := range files {
+			ret = append(ret, migrationFile{path: filepath.Join(dir, f), source: source})
+		}
+	}
+	return ret, nil
+}
+
+// migrationSource returns the source named, or detects the source of the
+// files in dir if the name is empty or "auto".
+func migrationSource(name string, dir string, names []string) (MigrationSource, error) {
+
+	if name != "" && name != "auto" {
+		source, ok := migrationSources[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown migration source %q, expected auto or one of: %s", name, migrationSourceNames())
+		}
+		return source, nil
+	}
+	for _, name := range detectOrder {
+		if migrationSources[name].Detect(dir, names) {
+			return migrationSources[name], nil
+		}
+	}
+	return plainMigrations{}, nil
+}
+
+func migrationSourceNames() string {
+
+	names := lo.Keys(migrationSources)
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// plainMigrations are .sql files applied in lexical order, as used by
+// golang-migrate and many hand-rolled scripts. Down migrations, named
+// .down.sql, are skipped.
+type plainMigrations struct{}
+
+func (plainMigrations) Detect(string, []string) bool {
+
+	return true
+}
+
+func (plainMigrations) Migrations(_ string, names []string) ([]string, error) {
+
+	var ret []string
+	for _, name := range names {
+		if strings.HasSuffix(name, ".sql") && !strings.HasSuffix(name, ".down.sql") {
+			ret = append(ret, name)
+		}
+	}
+	slices.Sort(ret)
+	return ret, nil
+}
+
+func (plainMigrations) Up(src string) string {
+
+	return src
+}
+
+// Down reads the .down.sql file paired with a .up.sql file.
+func (plainMigrations) Down(path, _ string) (string, bool, error) {
+
+	base, ok := strings.CutSuffix(path, ".up.sql")
+	if !ok {
+		return "", false, nil
+	}
+	return readIfExists(base + ".down.sql")
+}
+
+// gooseMigrations are files named with a numeric version, such as
+// 20240101120000_create_users.sql, applied in order of version. Each holds
+// both directions, introduced by "-- +goose Up" and "-- +goose Down".
+type gooseMigrations struct{}
+
+var gooseVersion = regexp.MustCompile(`^(\d+)_.*\.sql$`)
+
+func (gooseMigrations) Detect(dir string, names []string) bool {
+
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			if gooseAnnotation(scanner.Text()) == "Up" {
+				f.Close()
+				return true
+			}
+		}
+		f.Close()
+	}
+	return false
+}
+
+func (gooseMigrations) Migrations(_ string, names []string) ([]string, error) {
+
+	versions := make(map[string]uint64)
+	for _, name := range names {
+		m := gooseVersion.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		v, err := strconv.ParseUint(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of %s: %w", name, err)
+		}
+		versions[name] = v
+	}
+	ret := lo.Keys(versions)
+	slices.SortFunc(ret, func(a, b string) int {
+		return cmp.Compare(versions[a], versions[b])
+	})
+	for i := 1; i < len(ret); i++ {
+		if versions[ret[i]] == versions[ret[i-1]] {
+			return nil, fmt.Errorf("%s and %s have the same version", ret[i-1], ret[i])
+		}
+	}
+	return ret, nil
+}
+
+func (gooseMigrations) Up(src string) string {
+
+	return gooseSection(src, "Up")
+}
+
+func (gooseMigrations) Down(_, src string) (string, bool, error) {
+
+	down := gooseSection(src, "Down")
+	return down, strings.TrimSpace(down) != "", nil
+}
+
+// gooseSection blanks out the lines of src other than those in the
+// sections for the direction given.
+func gooseSection(src, direction string) string {
+
+	lines := strings.SplitAfter(src, "\n")
+	keep := false
+	for i, line := range lines {
+		switch gooseAnnotation(line) {
+		case "Up", "Down":
+			keep = gooseAnnotation(line) == direction
+		}
+		if !keep {
+			lines[i] = strings.Repeat("\n", strings.Count(line, "\n"))
+		}
+	}
+	return strings.Join(lines, "")
+}
+
+// gooseAnnotation returns the annotation on a line, such as "Up" for
+// "-- +goose Up", or empty if there isn't one.
+func gooseAnnotation(line string) string {
+
+	rest, ok := strings.CutPrefix(strings.TrimSpace(line), "-- +goose ")
+	if !ok {
+		return ""
+	}
+	return strings.TrimSpace(rest)
+}
+
+// flywayMigrations are versioned migrations named like V1_2__create.sql,
+// applied in order of version, followed by repeatable migrations named
+// like R__views.sql, applied in order of description. Undo migrations,
+// named with U, and baseline migrations, named with B, are skipped.
+type flywayMigrations struct{}
+
+var (
+	flywayVersioned  = regexp.MustCompile(`^V(\d+(?:[._]\d+)*)__.*\.sql$`)
+	flywayRepeatable = regexp.MustCompile(`^R__(.*)\.sql$`)
+	flywayUndo       = regexp.MustCompile(`^U(\d+(?:[._]\d+)*)__.*\.sql$`)
+)
+
+func (flywayMigrations) Detect(_ string, names []string) bool {
+
+	return slices.ContainsFunc(names, func(name string) bool {
+		return flywayVersioned.MatchString(name) || flywayRepeatable.MatchString(name)
+	})
+}
+
+func (flywayMigrations) Migrations(_ string, names []string) ([]string, error) {
+
+	versions := make(map[string][]uint64)
+	var repeatable []string
+	for _, name := range names {
+		if flywayRepeatable.MatchString(name) {
+			repeatable = append(repeatable, name)
+			continue
+		}
+		m := flywayVersioned.FindStringSubmatch(name)
+		if m == nil {
+			continue
+		}
+		v, err := flywayVersion(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid version of %s: %w", name, err)
+		}
+		versions[name] = v
+	}
+	ret := lo.Keys(versions)
+	slices.SortFunc(ret, func(a, b string) int {
+		return slices.Compare(versions[a], versions[b])
+	})
+	for i := 1; i < len(ret); i++ {
+		if slices.Equal(versions[ret[i]], versions[ret[i-1]]) {
+			return nil, fmt.Errorf("%s and %s have the same version", ret[i-1], ret[i])
+		}
+	}
+	slices.SortFunc(repeatable, func(a, b string) int {
+		return strings.Compare(flywayRepeatable.FindStringSubmatch(a)[1], flywayRepeatable.FindStringSubmatch(b)[1])
+	})
+	return append(ret, repeatable...), nil
+}
+
+func (flywayMigrations) Up(src string) string {
+
+	return src
+}
+
+// Down reads the undo migration with the same version as a versioned
+// migration.
+func (flywayMigrations) Down(path, _ string) (string, bool, error) {
+
+	m := flywayVersioned.FindStringSubmatch(filepath.Base(path))
+	if m == nil {
+		return "", false, nil
+	}
+	version, err := flywayVersion(m[1])
+	if err != nil {
+		return "", false, err
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		return "", false, err
+	}
+	for _, e := range entries {
+		u := flywayUndo.FindStringSubmatch(e.Name())
+		if u == nil {
+			continue
+		}
+		if v, err := flywayVersion(u[1]); err == nil && slices.Equal(v, version) {
+			return readIfExists(filepath.Join(filepath.Dir(path), e.Name()))
+		}
+	}
+	return "", false, nil
+}
+
+// flywayVersion splits a version such as 1.2 or 1_2 into its parts.
+func flywayVersion(s string) ([]uint64, error) {
+
+	var ret []uint64
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool { return r == '.' || r == '_' }) {
+		v, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		ret = append(ret, v)
+	}
+	return ret, nil
+}
+
+// atlasMigrations are the files listed by an atlas.sum file, applied in
+// the order listed.
+type atlasMigrations struct{}
+
+func (atlasMigrations) Detect(_ string, names []string) bool {
+
+	return slices.Contains(names, "atlas.sum")
+}
+
+func (atlasMigrations) Migrations(dir string, names []string) ([]string, error) {
+
+	b, err := os.ReadFile(filepath.Join(dir, "atlas.sum"))
+	if err != nil {
+		return nil, err
+	}
+	// The first line is the hash of the whole directory
+	var ret []string
+	for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n")[1:] {
+		name, _, _ := strings.Cut(line, " ")
+		if !slices.Contains(names, name) {
+			return nil, fmt.Errorf("%s is listed in atlas.sum but doesn't exist", name)
+		}
+		ret = append(ret, name)
+	}
+	return ret, nil
+}
+
+func (atlasMigrations) Up(src string) string {
+
+	return src
+}
+
+// Atlas doesn't keep down migrations in the directory.
+func (atlasMigrations) Down(string, string) (string, bool, error) {
+
+	return "", false, nil
+}
+
+// readIfExists reads the file at path, or returns false if there isn't one.
+func readIfExists(path string) (string, bool, error) {
+
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return string(b), true, nil
+}
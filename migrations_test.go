@@ -0,0 +1,106 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMigrations(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, sql := range files {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	return dir
+}
+
+func TestExpandPaths(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		source string
+		want   []string
+	}{
+		{
+			name:  "plain",
+			files: map[string]string{"002_b.up.sql": "", "002_b.down.sql": "", "001_a.up.sql": "", "notes.txt": ""},
+			want:  []string{"001_a.up.sql", "002_b.up.sql"},
+		},
+		{
+			name: "goose",
+			files: map[string]string{
+				"10_c.sql": "-- +goose Up\n", "9_b.sql": "-- +goose Up\n", "00001_a.sql": "-- +goose Up\n", "main.go": "",
+			},
+			want: []string{"00001_a.sql", "9_b.sql", "10_c.sql"},
+		},
+		{
+			name: "flyway",
+			files: map[string]string{
+				"V1_10__c.sql": "", "V1.2__b.sql": "", "V1__a.sql": "", "U1__a.sql": "", "R__views.sql": "", "R__funcs.sql": "",
+			},
+			want: []string{"V1__a.sql", "V1.2__b.sql", "V1_10__c.sql", "R__funcs.sql", "R__views.sql"},
+		},
+		{
+			name:  "atlas",
+			files: map[string]string{"atlas.sum": "h1:abc=\n2_b.sql h1:x=\n10_a.sql h1:y=\n", "10_a.sql": "", "2_b.sql": ""},
+			want:  []string{"2_b.sql", "10_a.sql"},
+		},
+		{
+			name:   "forced",
+			files:  map[string]string{"V2__b.sql": "", "V10__a.sql": ""},
+			source: "plain",
+			want:   []string{"V10__a.sql", "V2__b.sql"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeMigrations(t, tt.files)
+			files, err := expandPaths([]string{dir}, tt.source)
+			require.Nil(t, err)
+			var names []string
+			for _, f := range files {
+				names = append(names, filepath.Base(f.path))
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+
+	_, err := expandPaths([]string{writeMigrations(t, map[string]string{"1_a.sql": "-- +goose Up\n", "01_b.sql": ""})}, "")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "have the same version")
+	_, err = expandPaths([]string{writeMigrations(t, map[string]string{"atlas.sum": "h1:abc=\n1_a.sql h1:x=\n"})}, "")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "doesn't exist")
+	_, err = expandPaths([]string{t.TempDir()}, "liquibase")
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "unknown migration source")
+}
+
+func TestCompiler_CompileFilesGoose(t *testing.T) {
+	dir := writeMigrations(t, map[string]string{
+		"20240101000000_users.sql": `-- +goose Up
+CREATE TABLE users (id int);
+
+-- +goose Down
+DROP TABLE users;
+`,
+		"20240102000000_email.sql": `-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users ADD COLUMN email text;
+-- +goose StatementEnd
+-- +goose Down
+ALTER TABLE users DROP COLUMN email;
+`,
+	})
+	c := NewCompiler()
+	c.Lenient = true
+	require.Nil(t, c.CompileFiles([]string{dir}))
+	tab := assertTable(t, c, "users")
+	assertColumn(t, tab, "email", Text, ColumnAttributes{})
+
+	// Line numbers still refer to the whole file
+	up := gooseMigrations{}.Up("-- +goose Down\nDROP TABLE a;\n-- +goose Up\nCREATE TABLE a (id int);\n")
+	assert.Equal(t, "\n\n-- +goose Up\nCREATE TABLE a (id int);\n", up)
+}
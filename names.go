@@ -0,0 +1,202 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// NameLinter finds the names of a catalog's objects which have to be
+// quoted wherever they're used: those which are keywords Postgres reserves,
+// and those which aren't lower case letters, digits and underscores. Each
+// rule is reported at the severity given for it, or not at all if that's
+// empty.
+type NameLinter struct {
+	// Reserved is the severity of names which are reserved keywords.
+	Reserved Severity
+	// Quoted is the severity of names which need quoting otherwise.
+	Quoted Severity
+}
+
+// Lint returns the names in cat needing quoting, located where the object
+// was defined, or where its table was for indexes.
+func (l *NameLinter) Lint(cat *Catalog) []*Diagnostic {
+
+	var ret []*Diagnostic
+	check := func(loc SourceLocation, kind, qualified, name string) {
+		switch {
+		case reservedKeyword(name):
+			if l.Reserved != "" {
+				ret = append(ret, &Diagnostic{File: loc.File, Line: loc.Line, Severity: l.Reserved, Rule: RuleReservedKeyword,
+					Message: fmt.Sprintf("%s %s is named by a reserved keyword, so it must always be quoted", kind, qualified)})
+			}
+		case !simpleIdent.MatchString(name):
+			if l.Quoted != "" {
+				msg := fmt.Sprintf("%s %s has a name which must always be quoted", kind, qualified)
+				if suggested := unquotedName(name); !reservedKeyword(suggested) {
+					msg += ", consider " + suggested
+				}
+				ret = append(ret, &Diagnostic{File: loc.File, Line: loc.Line, Severity: l.Quoted, Rule: RuleQuotedIdentifier, Message: msg})
+			}
+		}
+	}
+	for _, sch := range cat.Schemas.List() {
+		if systemSchema(sch.Name) {
+			continue
+		}
+		check(SourceLocation{}, "schema", QuoteIdent(sch.Name), sch.Name)
+		for _, t := range sch.Tables.List() {
+			check(t.Defined, "table", TableIdent(t), t.Name)
+			for _, col := range t.Columns.List() {
+				check(col.Defined, "column", TableIdent(t)+"."+QuoteIdent(col.Name), col.Name)
+			}
+			for _, con := range cat.Depends.TableConstraints(t) {
+				check(con.Defined, "constraint", QuoteIdent(con.Name)+" of table "+TableIdent(t), con.Name)
+			}
+			for _, idx := range cat.Depends.TableIndexes(t) {
+				check(t.Defined, "index", QuoteIdent(idx.Name)+" of table "+TableIdent(t), idx.Name)
+			}
+		}
+		for _, e := range sch.Enums.List() {
+			check(e.Defined, "enum", QuoteIdent(e.Schema)+"."+QuoteIdent(e.Name), e.Name)
+		}
+		for _, seq := range sch.Sequences.List() {
+			check(seq.Defined, "sequence", QuoteIdent(seq.Schema)+"."+QuoteIdent(seq.Name), seq.Name)
+		}
+	}
+	sort.SliceStable(ret, func(i, j int) bool {
+		return ret[i].File < ret[j].File || ret[i].File == ret[j].File && ret[i].Line < ret[j].Line
+	})
+	return ret
+}
+
+// reservedKeyword reports whether name is a keyword which can't be used as
+// a name without quoting it in every context, which is all but the
+// unreserved ones.
+func reservedKeyword(name string) bool {
+
+	scan, err := pg_query.Scan(name)
+	if err != nil || len(scan.Tokens) != 1 || int(scan.Tokens[0].End) != len(name) {
+		return false
+	}
+	kind := scan.Tokens[0].KeywordKind
+	return kind != pg_query.KeywordKind_NO_KEYWORD && kind != pg_query.KeywordKind_UNRESERVED_KEYWORD
+}
+
+// unquotedName suggests a name like name which needn't be quoted: in snake
+// case, with anything but letters and digits replaced by underscores.
+func unquotedName(name string) string {
+
+	var sb strings.Builder
+	var prev rune
+	for i, r := range name {
+		switch {
+		case 'A' <= r && r <= 'Z':
+			if i > 0 && 'a' <= prev && prev <= 'z' {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(r + 'a' - 'A')
+		case 'a' <= r && r <= 'z', '0' <= r && r <= '9':
+			sb.WriteRune(r)
+		case sb.Len() > 0 && !strings.HasSuffix(sb.String(), "_"):
+			sb.WriteByte('_')
+		}
+		prev = r
+	}
+	s := strings.Trim(sb.String(), "_")
+	if s == "" || '0' <= s[0] && s[0] <= '9' {
+		s = "_" + s
+	}
+	return s
+}
+
+// parseSeverity parses the severity a rule is reported at, where off means
+// it isn't reported.
+func parseSeverity(s string) (Severity, error) {
+
+	switch Severity(s) {
+	case SeverityError, SeverityWarning, SeverityNote:
+		return Severity(s), nil
+	case "off":
+		return "", nil
+	}
+	return "", fmt.Errorf("unknown severity %q", s)
+}
+
+func runNames(args []string) error {
+
+	fs := flag.NewFlagSet("names", flag.ExitOnError)
+	reserved := fs.String("reserved", "warning", "severity of names which are reserved keywords, one of: error, warning, note, off")
+	quoted := fs.String("quoted", "warning", "severity of names which need quoting otherwise, one of: error, warning, note, off")
+	format := fs.String("format", "text", "output format, one of: text, json, sarif")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	fail := fs.Bool("fail", false, "exit with an error if any names are reported, not only errors")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	var linter NameLinter
+	linter.Reserved, err = parseSeverity(*reserved)
+	if err != nil {
+		return err
+	}
+	linter.Quoted, err = parseSeverity(*quoted)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	names := linter.Lint(c.Catalog)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format != "text" {
+		if names == nil {
+			names = []*Diagnostic{}
+		}
+		err = WriteDiagnostics(w, names, *format)
+	} else {
+		bw := bufio.NewWriter(w)
+		for _, d := range names {
+			fmt.Fprintf(bw, "%s: %s: %s [%s]\n", SourceLocation{File: d.File, Line: d.Line}, d.Severity, d.Message, d.Rule)
+		}
+		err = bw.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	errs := 0
+	for _, d := range names {
+		if d.Severity == SeverityError {
+			errs++
+		}
+	}
+	if errs > 0 {
+		return fmt.Errorf("found %d names which are errors", errs)
+	}
+	if *fail && len(names) > 0 {
+		return fmt.Errorf("found %d names needing quoting", len(names))
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNameLinter(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`CREATE TABLE "user" (
+    id int PRIMARY KEY,
+    "firstName" text,
+    "order" int,
+    type text,
+    "e-mail" text
+);
+CREATE TYPE "Mood" AS ENUM ('ok');
+CREATE INDEX "User Names" ON "user" ("firstName");
+`))
+	assert.Equal(t, []*Diagnostic{
+		{Line: 1, Severity: SeverityError, Rule: RuleReservedKeyword, Message: `table "user" is named by a reserved keyword, so it must always be quoted`},
+		{Line: 1, Severity: SeverityWarning, Rule: RuleQuotedIdentifier, Message: `index "User Names" of table "user" has a name which must always be quoted, consider user_names`},
+		{Line: 3, Severity: SeverityWarning, Rule: RuleQuotedIdentifier, Message: `column "user"."firstName" has a name which must always be quoted, consider first_name`},
+		{Line: 4, Severity: SeverityError, Rule: RuleReservedKeyword, Message: `column "user"."order" is named by a reserved keyword, so it must always be quoted`},
+		{Line: 6, Severity: SeverityWarning, Rule: RuleQuotedIdentifier, Message: `column "user"."e-mail" has a name which must always be quoted, consider e_mail`},
+		{Line: 8, Severity: SeverityWarning, Rule: RuleQuotedIdentifier, Message: `enum public."Mood" has a name which must always be quoted, consider mood`},
+	}, (&NameLinter{Reserved: SeverityError, Quoted: SeverityWarning}).Lint(c.Catalog))
+
+	// Rules without a severity aren't reported
+	assert.Len(t, (&NameLinter{Reserved: SeverityNote}).Lint(c.Catalog), 2)
+	assert.Equal(t, "created_at", unquotedName("CreatedAt"))
+	assert.Equal(t, "_2fa", unquotedName("2FA"))
+}
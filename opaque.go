@@ -0,0 +1,289 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"slices"
+	"strings"
+)
+
+// Casts, operators and aggregates aren't modeled beyond their identity and
+// the functions and types they use, so they're kept as raw statements. The
+// functions and types themselves aren't modeled either, so references to
+// them are compared by name as written, with built in types recognised
+// whether or not they're qualified by pg_catalog.
+
+// CreateCast keeps a CREATE CAST statement.
+func (c *Compiler) CreateCast(stmt *pg_query.CreateCastStmt) error {
+
+	source, err := typeNameString(stmt.Sourcetype)
+	if err != nil {
+		return err
+	}
+	target, err := typeNameString(stmt.Targettype)
+	if err != nil {
+		return err
+	}
+	name := castName(source, target)
+	if c.findOpaque("CREATE CAST", name) != nil {
+		return fmt.Errorf("cast %s already exists", name)
+	}
+	depends := []string{typeDependency(source), typeDependency(target)}
+	if stmt.Func != nil {
+		fn, err := c.functionDependency(stmt.Func.Objname)
+		if err != nil {
+			return err
+		}
+		depends = append(depends, fn)
+	}
+	return c.KeepRaw("CREATE CAST", name, nil, depends...)
+}
+
+// aggregateFunctions and operatorFunctions are the options of CREATE
+// AGGREGATE and CREATE OPERATOR which name functions.
+var (
+	aggregateFunctions = []string{"sfunc", "finalfunc", "combinefunc", "serialfunc", "deserialfunc", "msfunc", "minvfunc", "mfinalfunc"}
+	operatorFunctions  = []string{"function", "procedure", "restrict", "join"}
+)
+
+// DefineOperatorOrAggregate keeps a CREATE OPERATOR or CREATE AGGREGATE
+// statement.
+func (c *Compiler) DefineOperatorOrAggregate(stmt *pg_query.DefineStmt) error {
+
+	kind, functions := "CREATE OPERATOR", operatorFunctions
+	if stmt.Kind == pg_query.ObjectType_OBJECT_AGGREGATE {
+		kind, functions = "CREATE AGGREGATE", aggregateFunctions
+	}
+	var args, depends []string
+	var left, right string
+	for _, n := range stmt.Definition {
+		def := n.GetDefElem()
+		if def == nil {
+			return fmt.Errorf("expected DefElem but got %T", n.Node)
+		}
+		switch {
+		case slices.Contains(functions, def.Defname):
+			{
+				fn, err := c.functionDependency(def.Arg.GetTypeName().GetNames())
+				if err != nil {
+					return err
+				}
+				depends = append(depends, fn)
+			}
+		case def.Arg.GetTypeName() != nil:
+			{
+				typ, err := typeNameString(def.Arg.GetTypeName())
+				if err != nil {
+					return err
+				}
+				depends = append(depends, typeDependency(typ))
+				switch def.Defname {
+				case "leftarg":
+					left = typ
+				case "rightarg":
+					right = typ
+				case "basetype":
+					// Old style aggregates take their argument type as an option
+					args = append(args, typ)
+				}
+			}
+		}
+	}
+	if stmt.Kind == pg_query.ObjectType_OBJECT_OPERATOR {
+		args = []string{left, right}
+	} else if len(stmt.Args) > 0 {
+		for _, n := range stmt.Args[0].GetList().GetItems() {
+			typ, err := typeNameString(n.GetFunctionParameter().GetArgType())
+			if err != nil {
+				return err
+			}
+			args = append(args, typ)
+			depends = append(depends, typeDependency(typ))
+		}
+	}
+
+	names, err := NodeStrings(stmt.Defnames)
+	if err != nil {
+		return err
+	}
+	name := signatureName(c.qualifiedName(names), args)
+	if c.findOpaque(kind, name) != nil {
+		if !stmt.Replace {
+			return fmt.Errorf("%s %s already exists", strings.ToLower(strings.TrimPrefix(kind, "CREATE ")), name)
+		}
+		c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+			return raw.Kind == kind && raw.Name == name
+		})
+	}
+	return c.KeepRaw(kind, name, nil, slices.DeleteFunc(depends, func(d string) bool { return d == "" })...)
+}
+
+// DropOpaque drops a cast, operator or aggregate, identified by the object
+// of a DROP statement.
+func (c *Compiler) DropOpaque(typ pg_query.ObjectType, obj *pg_query.Node, missingOk bool) error {
+
+	var kind, name string
+	switch typ {
+	case pg_query.ObjectType_OBJECT_CAST:
+		{
+			types := obj.GetList().GetItems()
+			if len(types) != 2 {
+				return fmt.Errorf("expected a source and target type but got %d types", len(types))
+			}
+			source, err := typeNameString(types[0].GetTypeName())
+			if err != nil {
+				return err
+			}
+			target, err := typeNameString(types[1].GetTypeName())
+			if err != nil {
+				return err
+			}
+			kind, name = "CREATE CAST", castName(source, target)
+		}
+	default:
+		{
+			kind = "CREATE OPERATOR"
+			if typ == pg_query.ObjectType_OBJECT_AGGREGATE {
+				kind = "CREATE AGGREGATE"
+			}
+			fn := obj.GetObjectWithArgs()
+			if fn == nil {
+				return fmt.Errorf("expected ObjectWithArgs but got %T", obj.Node)
+			}
+			var args []string
+			for _, arg := range fn.Objargs {
+				typ, err := typeNameString(arg.GetTypeName())
+				if err != nil {
+					return err
+				}
+				args = append(args, typ)
+			}
+			names, err := NodeStrings(fn.Objname)
+			if err != nil {
+				return err
+			}
+			name = signatureName(c.qualifiedName(names), args)
+		}
+	}
+	if c.findOpaque(kind, name) == nil {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("%s %s not found", strings.ToLower(strings.TrimPrefix(kind, "CREATE ")), name)
+	}
+	c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+		return raw.Kind == kind && raw.Name == name
+	})
+	return nil
+}
+
+// DropDependents handles a DROP FUNCTION or DROP TYPE by dropping the
+// casts, operators and aggregates using the function or type, if the drop
+// cascades. Otherwise it fails if any use it. The function or type itself
+// isn't modeled, so it's skipped.
+func (c *Compiler) DropDependents(typ pg_query.ObjectType, obj *pg_query.Node, behav DropBehaviour) error {
+
+	var dependency string
+	switch typ {
+	case pg_query.ObjectType_OBJECT_TYPE, pg_query.ObjectType_OBJECT_DOMAIN:
+		{
+			typ, err := typeNameString(obj.GetTypeName())
+			if err != nil {
+				return err
+			}
+			dependency = typeDependency(typ)
+		}
+	default:
+		{
+			fn := obj.GetObjectWithArgs()
+			if fn == nil {
+				return fmt.Errorf("expected ObjectWithArgs but got %T", obj.Node)
+			}
+			// Functions are only known by name, so dropping any overload
+			// drops everything using one
+			var err error
+			dependency, err = c.functionDependency(fn.Objname)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	for _, raw := range c.Catalog.Raw {
+		if slices.Contains(raw.Depends, dependency) && behav != DropBehaviourCascade {
+			return fmt.Errorf("can't drop %s because %s %s depends on it and cascade was not specified",
+				dependency, strings.ToLower(strings.TrimPrefix(raw.Kind, "CREATE ")), raw.Name)
+		}
+	}
+	c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+		return slices.Contains(raw.Depends, dependency)
+	})
+	return nil
+}
+
+func (c *Compiler) findOpaque(kind, name string) *RawStatement {
+
+	idx := slices.IndexFunc(c.Catalog.Raw, func(raw *RawStatement) bool {
+		return raw.Kind == kind && raw.Name == name
+	})
+	if idx < 0 {
+		return nil
+	}
+	return c.Catalog.Raw[idx]
+}
+
+// qualifiedName joins the parts of an object's name, qualifying it with
+// the current schema if it isn't already.
+func (c *Compiler) qualifiedName(names []string) string {
+
+	if len(names) == 1 {
+		return c.SearchPath + "." + names[0]
+	}
+	return strings.Join(names, ".")
+}
+
+func (c *Compiler) functionDependency(names []*pg_query.Node) (string, error) {
+
+	if len(names) == 0 {
+		return "", nil
+	}
+	parts, err := NodeStrings(names)
+	if err != nil {
+		return "", err
+	}
+	return "function " + c.qualifiedName(parts), nil
+}
+
+func typeDependency(typ string) string {
+
+	if typ == "NONE" {
+		return ""
+	}
+	return "type " + strings.TrimSuffix(typ, "[]")
+}
+
+// typeNameString names a type, without pg_catalog if it's built in, or
+// returns "NONE" for the missing argument of a prefix operator.
+func typeNameString(tn *pg_query.TypeName) (string, error) {
+
+	if tn == nil {
+		return "NONE", nil
+	}
+	names, err := NodeStrings(tn.Names)
+	if err != nil {
+		return "", err
+	}
+	if len(names) == 2 && names[0] == "pg_catalog" {
+		names = names[1:]
+	}
+	return strings.Join(names, ".") + strings.Repeat("[]", len(tn.ArrayBounds)), nil
+}
+
+func castName(source, target string) string {
+
+	return "(" + source + " AS " + target + ")"
+}
+
+func signatureName(name string, args []string) string {
+
+	return name + "(" + strings.Join(args, ", ") + ")"
+}
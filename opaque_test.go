@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_OpaqueObjects(t *testing.T) {
+	const sql = `
+	CREATE FUNCTION to_cents(money) RETURNS bigint AS 'SELECT $1::numeric * 100' LANGUAGE sql;
+	CREATE FUNCTION money_close(money, money) RETURNS bool AS 'SELECT abs($1 - $2) < 1::money' LANGUAGE sql;
+	CREATE CAST (money AS bigint) WITH FUNCTION to_cents(money);
+	CREATE OPERATOR === (LEFTARG = money, RIGHTARG = money, FUNCTION = money_close, COMMUTATOR = ===);
+	CREATE AGGREGATE total_cents(money) (SFUNC = int8pl, STYPE = bigint, FINALFUNC = public.to_cents);
+	`
+	c := NewCompiler()
+	require.Nil(t, c.Compile(sql))
+	require.Len(t, c.Catalog.Raw, 3)
+	assert.Equal(t, &RawStatement{
+		Kind:    "CREATE CAST",
+		Name:    "(money AS int8)",
+		OID:     firstOID,
+		SQL:     "CREATE CAST (money AS bigint) WITH FUNCTION to_cents(money)",
+		Depends: []string{"type money", "type int8", "function public.to_cents"},
+	}, c.Catalog.Raw[0])
+	assert.Equal(t, "public.===(money, money)", c.Catalog.Raw[1].Name)
+	assert.Equal(t, []string{"type money", "type money", "function public.money_close"}, c.Catalog.Raw[1].Depends)
+	assert.Equal(t, "public.total_cents(money)", c.Catalog.Raw[2].Name)
+
+	// Dropping a function used by others needs CASCADE, which drops them
+	err := c.Compile(`DROP FUNCTION to_cents(money)`)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cast (money AS int8) depends on it")
+	require.Nil(t, c.Compile(`DROP FUNCTION to_cents(money) CASCADE`))
+	require.Len(t, c.Catalog.Raw, 1)
+	require.Nil(t, c.Compile(`DROP OPERATOR public.=== (money, money); DROP AGGREGATE IF EXISTS total_cents(money)`))
+	assert.Empty(t, c.Catalog.Raw)
+
+	err = c.Compile(`DROP CAST (int AS money)`)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "cast (int4 AS money) not found")
+
+	c = NewCompiler()
+	require.Nil(t, c.Compile(sql))
+	var sb strings.Builder
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	roundTrip := NewCompiler()
+	require.Nil(t, roundTrip.Compile(sb.String()))
+	assert.Equal(t, c.Catalog.Raw, roundTrip.Catalog.Raw)
+}
@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// The producer and schema URLs OpenLineage requires of events and facets.
+const (
+	openLineageProducer      = "https://github.com/henges/pgmodelparse"
+	openLineageEventSchema   = "https://openlineage.io/spec/2-0-2/OpenLineage.json#/$defs/RunEvent"
+	openLineageSchemaFacet   = "https://openlineage.io/spec/facets/1-1-1/SchemaDatasetFacet.json#/$defs/SchemaDatasetFacet"
+	openLineageDocFacet      = "https://openlineage.io/spec/facets/1-0-1/DocumentationDatasetFacet.json#/$defs/DocumentationDatasetFacet"
+	openLineageLineageFacet  = "https://openlineage.io/spec/facets/1-2-0/ColumnLineageDatasetFacet.json#/$defs/ColumnLineageDatasetFacet"
+	openLineageDatasetFacet  = "https://openlineage.io/spec/facets/1-0-1/DatasetTypeDatasetFacet.json#/$defs/DatasetTypeDatasetFacet"
+	openLineageDefaultSource = "postgres://localhost:5432"
+)
+
+// OpenLineageGenerator writes the catalog as an OpenLineage run event,
+// which data catalogs such as DataHub and Marquez ingest, with a dataset
+// for each table and view as the event's outputs. Each dataset has the
+// schema, documentation and dataset type facets, and views and tables
+// created by CREATE TABLE AS also have the column lineage facet, as
+// Lineage finds it.
+type OpenLineageGenerator struct {
+	// Namespace is the namespace of the datasets, which OpenLineage names
+	// postgres://host:port for Postgres.
+	Namespace string
+	// Database is the database the schema is in, which qualifies the names
+	// of the datasets if it's set.
+	Database string
+	// Job is the name of the job the event is of.
+	Job string
+	// Time is the time of the event, or the time it's generated if it's
+	// zero.
+	Time time.Time
+}
+
+func NewOpenLineageGenerator(fs *flag.FlagSet) Generator {
+
+	g := &OpenLineageGenerator{}
+	fs.StringVar(&g.Namespace, "openlineage-namespace", openLineageDefaultSource, "namespace of the datasets, the database's address as postgres://host:port")
+	fs.StringVar(&g.Database, "openlineage-database", "", "database qualifying the names of the datasets")
+	fs.StringVar(&g.Job, "openlineage-job", "pgmodelgen", "name of the job the event is of")
+	return g
+}
+
+type openLineageEvent struct {
+	EventType string               `json:"eventType"`
+	EventTime string               `json:"eventTime"`
+	Run       openLineageRun       `json:"run"`
+	Job       openLineageJob       `json:"job"`
+	Inputs    []any                `json:"inputs"`
+	Outputs   []openLineageDataset `json:"outputs"`
+	Producer  string               `json:"producer"`
+	SchemaURL string               `json:"schemaURL"`
+}
+
+type openLineageRun struct {
+	RunID string `json:"runId"`
+}
+
+type openLineageJob struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+}
+
+type openLineageDataset struct {
+	Namespace string         `json:"namespace"`
+	Name      string         `json:"name"`
+	Facets    map[string]any `json:"facets"`
+}
+
+type openLineageFacet struct {
+	Producer  string `json:"_producer"`
+	SchemaURL string `json:"_schemaURL"`
+}
+
+type openLineageSchema struct {
+	openLineageFacet
+	Fields []openLineageField `json:"fields"`
+}
+
+type openLineageField struct {
+	Name        string `json:"name"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type openLineageDocumentation struct {
+	openLineageFacet
+	Description string `json:"description"`
+}
+
+type openLineageDatasetType struct {
+	openLineageFacet
+	DatasetType string `json:"datasetType"`
+}
+
+type openLineageColumnLineage struct {
+	openLineageFacet
+	Fields map[string]openLineageInputFields `json:"fields"`
+}
+
+type openLineageInputFields struct {
+	InputFields []openLineageInputField `json:"inputFields"`
+}
+
+type openLineageInputField struct {
+	Namespace string `json:"namespace"`
+	Name      string `json:"name"`
+	Field     string `json:"field"`
+}
+
+func (g *OpenLineageGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	facet := func(url string) openLineageFacet {
+		return openLineageFacet{Producer: openLineageProducer, SchemaURL: url}
+	}
+	lineage := make(map[string]map[string][]string)
+	for _, l := range Lineage(cat) {
+		if lineage[l.Relation] == nil {
+			lineage[l.Relation] = make(map[string][]string)
+		}
+		lineage[l.Relation][l.Column] = l.Sources
+	}
+	dataset := func(name, kind, comment string, fields []openLineageField) openLineageDataset {
+		ds := openLineageDataset{Namespace: g.Namespace, Name: g.datasetName(name), Facets: map[string]any{
+			"schema":      openLineageSchema{openLineageFacet: facet(openLineageSchemaFacet), Fields: fields},
+			"datasetType": openLineageDatasetType{openLineageFacet: facet(openLineageDatasetFacet), DatasetType: kind},
+		}}
+		if comment != "" {
+			ds.Facets["documentation"] = openLineageDocumentation{openLineageFacet: facet(openLineageDocFacet), Description: comment}
+		}
+		if cols, ok := lineage[name]; ok {
+			cl := openLineageColumnLineage{openLineageFacet: facet(openLineageLineageFacet), Fields: make(map[string]openLineageInputFields)}
+			for col, sources := range cols {
+				if len(sources) == 0 {
+					continue
+				}
+				inputs := openLineageInputFields{InputFields: []openLineageInputField{}}
+				for _, src := range sources {
+					i := strings.LastIndex(src, ".")
+					inputs.InputFields = append(inputs.InputFields, openLineageInputField{Namespace: g.Namespace, Name: g.datasetName(src[:i]), Field: src[i+1:]})
+				}
+				cl.Fields[col] = inputs
+			}
+			ds.Facets["columnLineage"] = cl
+		}
+		return ds
+	}
+	comment := func(obj any) string {
+		if desc, ok := cat.Descriptions[obj]; ok {
+			return desc.Comment
+		}
+		return ""
+	}
+
+	outputs := []openLineageDataset{}
+	for _, sch := range cat.Schemas.List() {
+		if systemSchema(sch.Name) {
+			continue
+		}
+		for _, t := range sch.Tables.List() {
+			fields := []openLineageField{}
+			for _, col := range t.Columns.List() {
+				fields = append(fields, openLineageField{Name: col.Name, Type: col.FormatType(), Description: comment(col)})
+			}
+			outputs = append(outputs, dataset(t.Schema+"."+t.Name, "TABLE", comment(t), fields))
+		}
+	}
+	for _, raw := range cat.Raw {
+		if raw.Kind != "CREATE VIEW" {
+			continue
+		}
+		fields := []openLineageField{}
+		for _, col := range raw.Columns {
+			fields = append(fields, openLineageField{Name: col.Name, Type: col.Type})
+		}
+		outputs = append(outputs, dataset(raw.Name, "VIEW", comment(raw), fields))
+	}
+
+	// The run is identified by what it outputs, so that generating the
+	// same catalog again is recognised as the same run
+	b, err := json.Marshal(outputs)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	sum[6] = sum[6]&0x0f | 0x80
+	sum[8] = sum[8]&0x3f | 0x80
+	now := g.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	event := openLineageEvent{
+		EventType: "COMPLETE",
+		EventTime: now.UTC().Format(time.RFC3339),
+		Run:       openLineageRun{RunID: fmt.Sprintf("%x-%x-%x-%x-%x", sum[0:4], sum[4:6], sum[6:8], sum[8:10], sum[10:16])},
+		Job:       openLineageJob{Namespace: g.Namespace, Name: g.Job},
+		Inputs:    []any{},
+		Outputs:   outputs,
+		Producer:  openLineageProducer,
+		SchemaURL: openLineageEventSchema,
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(event)
+}
+
+// datasetName qualifies name, the name of a table or view qualified by its
+// schema, by the generator's database if it has one.
+func (g *OpenLineageGenerator) datasetName(name string) string {
+
+	if g.Database == "" {
+		return name
+	}
+	return g.Database + "." + name
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenLineageGenerator_Generate(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY, email text);
+	COMMENT ON TABLE users IS 'People who sign in';
+	COMMENT ON COLUMN users.email IS 'Where mail is sent';
+	CREATE VIEW emails AS SELECT lower(email) AS address FROM users;
+	`)
+	g := &OpenLineageGenerator{Namespace: "postgres://db:5432", Database: "app", Job: "migrations", Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	var sb strings.Builder
+	require.Nil(t, g.Generate(&sb, c.Catalog))
+
+	var event map[string]any
+	require.Nil(t, json.Unmarshal([]byte(sb.String()), &event))
+	assert.Equal(t, "COMPLETE", event["eventType"])
+	assert.Equal(t, "2024-05-01T12:00:00Z", event["eventTime"])
+	assert.Equal(t, map[string]any{"namespace": "postgres://db:5432", "name": "migrations"}, event["job"])
+	assert.Regexp(t, `^[0-9a-f]{8}-[0-9a-f]{4}-8[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`, event["run"].(map[string]any)["runId"])
+
+	outputs := event["outputs"].([]any)
+	require.Len(t, outputs, 2)
+	users := outputs[0].(map[string]any)
+	assert.Equal(t, "app.public.users", users["name"])
+	facets := users["facets"].(map[string]any)
+	assert.Equal(t, []any{
+		map[string]any{"name": "id", "type": "integer"},
+		map[string]any{"name": "email", "type": "text", "description": "Where mail is sent"},
+	}, facets["schema"].(map[string]any)["fields"])
+	assert.Equal(t, "People who sign in", facets["documentation"].(map[string]any)["description"])
+	assert.Equal(t, "TABLE", facets["datasetType"].(map[string]any)["datasetType"])
+	assert.NotContains(t, facets, "columnLineage")
+
+	emails := outputs[1].(map[string]any)
+	assert.Equal(t, "app.public.emails", emails["name"])
+	assert.Equal(t, map[string]any{
+		"address": map[string]any{"inputFields": []any{map[string]any{"namespace": "postgres://db:5432", "name": "app.public.users", "field": "email"}}},
+	}, emails["facets"].(map[string]any)["columnLineage"].(map[string]any)["fields"])
+
+	// Generating the same catalog again is the same run
+	var again strings.Builder
+	require.Nil(t, g.Generate(&again, c.Catalog))
+	assert.Equal(t, sb.String(), again.String())
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"slices"
+	"strings"
+)
+
+// CompilerOption configures a Compiler created by NewCompiler.
+type CompilerOption func(*Compiler)
+
+// Strictness is how the compiler handles statements it can't model or the
+// target version doesn't support.
+type Strictness int
+
+const (
+	// StrictnessStrict fails on anything it can't model or the target
+	// version lacks.
+	StrictnessStrict Strictness = 0
+	// StrictnessWarnVersion warns about features the target version lacks
+	// rather than failing.
+	StrictnessWarnVersion Strictness = 1
+	// StrictnessSkipUnsupported skips what can't be modeled yet rather than
+	// failing.
+	StrictnessSkipUnsupported Strictness = 2
+	// StrictnessLenient both warns and skips.
+	StrictnessLenient = StrictnessWarnVersion | StrictnessSkipUnsupported
+)
+
+// WithSearchPath sets the schema unqualified names are resolved in.
+func WithSearchPath(schema string) CompilerOption {
+
+	return func(c *Compiler) {
+		c.SearchPath = schema
+	}
+}
+
+// WithDefaultSchema sets the schema which exists before any statements are
+// compiled, in place of public. It's also the search path unless
+// WithSearchPath is given.
+func WithDefaultSchema(name string) CompilerOption {
+
+	return func(c *Compiler) {
+		c.defaultSchema = name
+	}
+}
+
+// WithStrictness sets how unsupported statements are handled.
+func WithStrictness(s Strictness) CompilerOption {
+
+	return func(c *Compiler) {
+		c.WarnUnsupported = s&StrictnessWarnVersion != 0
+		c.Lenient = s&StrictnessSkipUnsupported != 0
+	}
+}
+
+// WithTargetVersion sets the major version of Postgres the statements must
+// run on.
+func WithTargetVersion(version int) CompilerOption {
+
+	return func(c *Compiler) {
+		c.TargetVersion = version
+	}
+}
+
+// WithExtensions declares extensions as installed, as CREATE EXTENSION
+// does, so that the types they define are known.
+func WithExtensions(names ...string) CompilerOption {
+
+	return func(c *Compiler) {
+		for _, name := range names {
+			c.addExtension(name)
+		}
+	}
+}
+
+// WithDiagnosticsSink sets a function called with each warning, note and
+// unsupported statement skipped as it's found, as they would be returned by
+// Diagnostics.
+func WithDiagnosticsSink(sink func(*Diagnostic)) CompilerOption {
+
+	return func(c *Compiler) {
+		c.DiagnosticsSink = sink
+	}
+}
+
+// extensionTypes are the types defined by common extensions. Columns of
+// them are treated as opaque, without warning that the type is unknown.
+var extensionTypes = map[string][]string{
+	"citext":  {"citext"},
+	"cube":    {"cube"},
+	"hstore":  {"hstore"},
+	"isn":     {"ean13", "isbn", "isbn13", "ismn", "ismn13", "issn", "issn13", "upc"},
+	"ltree":   {"ltree", "lquery", "ltxtquery"},
+	"postgis": {"box2d", "box3d", "geography", "geometry"},
+	"vector":  {"halfvec", "sparsevec", "vector"},
+}
+
+func (c *Compiler) addExtension(name string) {
+
+	if !slices.Contains(c.Extensions, name) {
+		c.Extensions = append(c.Extensions, name)
+	}
+}
+
+// extensionType reports whether the type named name is defined by one of
+// the compiler's extensions.
+func (c *Compiler) extensionType(name string) bool {
+
+	name = name[strings.LastIndex(name, ".")+1:]
+	for _, ext := range c.Extensions {
+		if slices.Contains(extensionTypes[ext], name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestNewCompiler_Options(t *testing.T) {
+	var sunk []*Diagnostic
+	c := NewCompiler(
+		WithDefaultSchema("app"),
+		WithTargetVersion(11),
+		WithStrictness(StrictnessLenient),
+		WithExtensions("vector"),
+		WithDiagnosticsSink(func(d *Diagnostic) { sunk = append(sunk, d) }),
+	)
+	assert.Equal(t, "app", c.SearchPath)
+	_, ok := c.Catalog.Schemas.Get("public")
+	assert.False(t, ok)
+	assert.True(t, c.Lenient)
+	assert.True(t, c.WarnUnsupported)
+
+	require.Nil(t, c.Compile("CREATE TABLE t (id int, embedding vector(3), tags ltree);\nCREATE EXTENSION ltree;\nCREATE TABLE u (tags ltree);\n"))
+	assertTable(t, c, "app.t")
+	assert.Equal(t, []string{"vector", "ltree"}, c.Extensions)
+	assert.Equal(t, []*Diagnostic{
+		{Line: 1, Severity: SeverityWarning, Rule: RuleUnknownType, Message: "unknown type ltree, treating it as opaque"},
+	}, sunk)
+	assert.Equal(t, c.Diagnostics(nil), sunk)
+
+	c = NewCompiler(WithDefaultSchema("app"), WithSearchPath("public"))
+	assert.Equal(t, "public", c.SearchPath)
+	_, ok = c.Catalog.Schemas.Get("app")
+	assert.True(t, ok)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// roleKeywords are the roles which name whoever runs the statement, rather
+// than a role of their own.
+var roleKeywords = []string{"CURRENT_ROLE", "CURRENT_USER", "SESSION_USER", "PUBLIC"}
+
+// roleName returns the name of the role spec names, or the keyword naming
+// it if it's one of roleKeywords.
+func roleName(spec *pg_query.RoleSpec) string {
+
+	switch spec.Roletype {
+	case pg_query.RoleSpecType_ROLESPEC_CURRENT_ROLE:
+		return "CURRENT_ROLE"
+	case pg_query.RoleSpecType_ROLESPEC_CURRENT_USER:
+		return "CURRENT_USER"
+	case pg_query.RoleSpecType_ROLESPEC_SESSION_USER:
+		return "SESSION_USER"
+	case pg_query.RoleSpecType_ROLESPEC_PUBLIC:
+		return "PUBLIC"
+	default:
+		return spec.Rolename
+	}
+}
+
+func quoteRole(role string) string {
+
+	if slices.Contains(roleKeywords, role) {
+		return role
+	}
+	return QuoteIdent(role)
+}
+
+// OwnerDefinition renders the statement making role the owner of the
+// object of the kind given, such as "TABLE", named by ident.
+func OwnerDefinition(kind, ident, role string) string {
+
+	return fmt.Sprintf("ALTER %s %s OWNER TO %s", kind, ident, quoteRole(role))
+}
+
+// AlterOwner handles ALTER ... OWNER TO for objects other than relations,
+// which are altered by ALTER TABLE.
+func (c *Compiler) AlterOwner(stmt *pg_query.AlterOwnerStmt) error {
+
+	role := roleName(stmt.Newowner)
+	switch stmt.ObjectType {
+	case pg_query.ObjectType_OBJECT_SCHEMA:
+		{
+			name := stmt.Object.GetString_().GetSval()
+			sch, ok := c.Catalog.Schemas.Get(name)
+			if !ok {
+				return fmt.Errorf("couldn't find schema %s", name)
+			}
+			sch.Owner = role
+		}
+	case pg_query.ObjectType_OBJECT_TYPE:
+		{
+			names, err := NodeStrings(stmt.Object.GetList().GetItems())
+			if err != nil {
+				return err
+			}
+			e := c.findEnum(names)
+			if e == nil {
+				// Other types aren't modeled
+				c.skip("ALTER TYPE OWNER TO", "")
+				return nil
+			}
+			e.Owner = role
+		}
+	case pg_query.ObjectType_OBJECT_EVENT_TRIGGER:
+		{
+			name := stmt.Object.GetString_().GetSval()
+			trig, ok := c.Catalog.EventTriggers.Get(name)
+			if !ok {
+				return fmt.Errorf("event trigger %s not found", name)
+			}
+			trig.Owner = role
+		}
+	default:
+		c.skip("ALTER "+strings.ReplaceAll(strings.TrimPrefix(stmt.ObjectType.String(), "OBJECT_"), "_", " ")+" OWNER TO", "")
+	}
+	return nil
+}
+
+// AlterRelationOwner handles ALTER TABLE ... OWNER TO, which pg_dump uses
+// for views and sequences as well as tables, so the relation may be any of
+// them whatever the statement says it is.
+func (c *Compiler) AlterRelationOwner(stmt *pg_query.AlterTableStmt, role string) error {
+
+	rv := stmt.Relation
+	if t, err := c.FindTableFromRangeVar(rv); err == nil {
+		t.Owner = role
+		return nil
+	}
+	if view := c.findOpaque("CREATE VIEW", c.relationName(rangeVarNames(rv))); view != nil {
+		view.Owner = role
+		return nil
+	}
+	if seq := c.findSequence(rv.Schemaname, rv.Relname); seq != nil {
+		seq.Owner = role
+		return nil
+	}
+	if stmt.MissingOk {
+		return nil
+	}
+	if stmt.Objtype == pg_query.ObjectType_OBJECT_MATVIEW {
+		// Materialized views aren't modeled
+		c.skip("ALTER MATERIALIZED VIEW OWNER TO", "")
+		return nil
+	}
+	return fmt.Errorf("couldn't find relation %s", c.relationName(rangeVarNames(rv)))
+}
+
+// ObjectOwner is an object and the role owning it.
+type ObjectOwner struct {
+	// Kind is the kind of object, such as "table".
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// Owner is empty if the object's owner isn't known, such as when it's
+	// owned by whoever created it.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Owners returns the owner of each object of cat which can have one.
+func Owners(cat *Catalog) []*ObjectOwner {
+
+	var ret []*ObjectOwner
+	add := func(kind, name, owner string) {
+		ret = append(ret, &ObjectOwner{Kind: kind, Name: name, Owner: owner})
+	}
+	for _, sch := range cat.Schemas.List() {
+		add("schema", sch.Name, sch.Owner)
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, e := range sch.Enums.List() {
+			add("type", e.Schema+"."+e.Name, e.Owner)
+		}
+		for _, t := range sch.Tables.List() {
+			add("table", t.Schema+"."+t.Name, t.Owner)
+		}
+		for _, seq := range sch.Sequences.List() {
+			add("sequence", seq.Schema+"."+seq.Name, seq.Owner)
+		}
+	}
+	for _, raw := range cat.Raw {
+		if raw.Kind == "CREATE VIEW" {
+			add("view", raw.Name, raw.Owner)
+		}
+	}
+	for _, trig := range cat.EventTriggers.List() {
+		add("event trigger", trig.Name, trig.Owner)
+	}
+	return ret
+}
+
+func runOwners(args []string) error {
+
+	fs := flag.NewFlagSet("owners", flag.ExitOnError)
+	expect := fs.String("expect", "", "only list the objects not owned by this role")
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	fail := fs.Bool("fail", false, "exit with an error if any objects are listed")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *fail && *expect == "" {
+		return fmt.Errorf("-fail needs -expect")
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	owners := Owners(c.Catalog)
+	if *expect != "" {
+		owners = slices.DeleteFunc(owners, func(o *ObjectOwner) bool {
+			return o.Owner == *expect
+		})
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if owners == nil {
+			owners = []*ObjectOwner{}
+		}
+		err = enc.Encode(owners)
+	} else {
+		bw := bufio.NewWriter(w)
+		for _, o := range owners {
+			owner := o.Owner
+			if owner == "" {
+				owner = "(unknown)"
+			}
+			fmt.Fprintf(bw, "%s %s: %s\n", o.Kind, o.Name, owner)
+		}
+		err = bw.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if *fail && len(owners) > 0 {
+		return fmt.Errorf("found %d objects not owned by %s", len(owners), *expect)
+	}
+	return nil
+}
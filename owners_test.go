@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_Owners(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE SCHEMA app AUTHORIZATION admin;
+CREATE SCHEMA AUTHORIZATION bob;
+CREATE TYPE app.status AS ENUM ('new', 'done');
+CREATE TABLE app.users (id int PRIMARY KEY);
+CREATE SEQUENCE app.user_ids;
+CREATE VIEW app.active AS SELECT id FROM app.users;
+ALTER SCHEMA public OWNER TO CURRENT_USER;
+ALTER TYPE app.status OWNER TO "App Owner";
+ALTER TABLE app.users OWNER TO app;
+ALTER TABLE app.user_ids OWNER TO app;
+ALTER TABLE app.active OWNER TO app;
+ALTER TABLE IF EXISTS app.missing OWNER TO app;
+ALTER FUNCTION f() OWNER TO app;
+`))
+	assert.Equal(t, []*ObjectOwner{
+		{Kind: "schema", Name: "public", Owner: "CURRENT_USER"},
+		{Kind: "schema", Name: "app", Owner: "admin"},
+		{Kind: "schema", Name: "bob", Owner: "bob"},
+		{Kind: "type", Name: "app.status", Owner: "App Owner"},
+		{Kind: "table", Name: "app.users", Owner: "app"},
+		{Kind: "sequence", Name: "app.user_ids", Owner: "app"},
+		{Kind: "view", Name: "app.active", Owner: "app"},
+	}, Owners(c.Catalog))
+	assert.ErrorContains(t, c.Compile("ALTER TABLE app.missing OWNER TO app;"), "couldn't find relation app.missing")
+
+	// Owners survive a round trip through the DDL
+	var buf bytes.Buffer
+	require.Nil(t, (&DDLGenerator{}).Generate(&buf, c.Catalog))
+	assert.Contains(t, buf.String(), "CREATE SCHEMA app AUTHORIZATION admin;\n")
+	assert.Contains(t, buf.String(), `ALTER TYPE app.status OWNER TO "App Owner";`)
+	rt := NewCompiler()
+	require.Nil(t, rt.Compile(buf.String()))
+	ownerOf := func(kind, name string) string {
+		for _, o := range Owners(rt.Catalog) {
+			if o.Kind == kind && o.Name == name {
+				return o.Owner
+			}
+		}
+		return ""
+	}
+	assert.Equal(t, "CURRENT_USER", ownerOf("schema", "public"))
+	assert.Equal(t, "App Owner", ownerOf("type", "app.status"))
+	assert.Equal(t, "app", ownerOf("table", "app.users"))
+	assert.Equal(t, "app", ownerOf("view", "app.active"))
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// SuggestColumnOrder returns the columns of t ordered to waste as little
+// space on alignment padding as possible: fixed length columns from the
+// most strictly aligned to the least, followed by those of variable length,
+// otherwise keeping the columns' order.
+func SuggestColumnOrder(t *Table) Columns {
+
+	cols := slices.Clone(t.Columns.List())
+	rank := func(col *Column) int {
+		if col.Type.Length == 0 || col.ArrayDims > 0 {
+			return 0
+		}
+		return col.Type.Align
+	}
+	slices.SortStableFunc(cols, func(a, b *Column) int {
+		return rank(b) - rank(a)
+	})
+	return cols
+}
+
+// ColumnOrderSuggestion is a table whose rows would be narrower with its
+// columns reordered.
+type ColumnOrderSuggestion struct {
+	Table string `json:"table"`
+	// Width and SuggestedWidth are the widths of the table's rows with its
+	// columns in their current and suggested orders.
+	Width          int      `json:"width"`
+	SuggestedWidth int      `json:"suggested_width"`
+	Columns        []string `json:"columns"`
+	// Rows are the rows the table is expected to hold, and Saving the
+	// bytes saved with that many, if it's known.
+	Rows   int64 `json:"rows,omitempty"`
+	Saving int64 `json:"saving,omitempty"`
+	// SQL creates the table with its columns reordered.
+	SQL string `json:"sql,omitempty"`
+	// cols are the columns in the suggested order.
+	cols Columns
+}
+
+// SuggestColumnOrders returns the tables of cat whose rows would be
+// narrower with their columns ordered by SuggestColumnOrder.
+func (e *SizeEstimator) SuggestColumnOrders(cat *Catalog) []*ColumnOrderSuggestion {
+
+	var ret []*ColumnOrderSuggestion
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			width, _ := heapRowWidth(t.Columns.List())
+			cols := SuggestColumnOrder(t)
+			suggested, _ := heapRowWidth(cols)
+			if suggested >= width {
+				continue
+			}
+			s := &ColumnOrderSuggestion{Table: t.Schema + "." + t.Name, Width: width, SuggestedWidth: suggested, Rows: e.rows(t), cols: cols}
+			for _, col := range cols {
+				s.Columns = append(s.Columns, col.Name)
+			}
+			s.Saving = heapBytes(width, s.Rows) - heapBytes(suggested, s.Rows)
+			ret = append(ret, s)
+		}
+	}
+	return ret
+}
+
+func runReorder(args []string) error {
+
+	fs := flag.NewFlagSet("reorder", flag.ExitOnError)
+	sql := fs.Bool("sql", false, "write the CREATE TABLE statements with the columns reordered")
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	suggestions := (&SizeEstimator{}).SuggestColumnOrders(c.Catalog)
+	if *sql {
+		for _, s := range suggestions {
+			t := findTable(c.Catalog, "", s.Table)
+			s.SQL = TableDefinition(c.Catalog, t, s.cols) + ";"
+		}
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		if suggestions == nil {
+			suggestions = []*ColumnOrderSuggestion{}
+		}
+		return enc.Encode(suggestions)
+	}
+	bw := bufio.NewWriter(w)
+	for _, s := range suggestions {
+		fmt.Fprintf(bw, "-- %s: rows of %d bytes could be %d", s.Table, s.Width, s.SuggestedWidth)
+		if s.Saving > 0 {
+			fmt.Fprintf(bw, ", saving %s of %d rows", formatBytes(s.Saving), s.Rows)
+		}
+		fmt.Fprintf(bw, "\n-- columns: %s\n", strings.Join(s.Columns, ", "))
+		if s.SQL != "" {
+			fmt.Fprintf(bw, "%s\n", s.SQL)
+		}
+	}
+	return bw.Flush()
+}
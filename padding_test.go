@@ -0,0 +1,42 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSuggestColumnOrders(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+-- @rows 1000000
+CREATE TABLE events (
+    active boolean NOT NULL,
+    id bigint PRIMARY KEY,
+    name text NOT NULL,
+    kind smallint NOT NULL,
+    created timestamptz NOT NULL,
+    score int NOT NULL
+);
+CREATE TABLE tidy (id bigint, n int, name text);
+`))
+	suggestions := (&SizeEstimator{}).SuggestColumnOrders(c.Catalog)
+	require.Len(t, suggestions, 1)
+	s := suggestions[0]
+	// The boolean, smallint and integer are each padded to the alignment
+	// of the column after them
+	assert.Equal(t, "public.events", s.Table)
+	assert.Equal(t, 96, s.Width)
+	assert.Equal(t, 80, s.SuggestedWidth)
+	assert.Equal(t, []string{"id", "created", "score", "kind", "active", "name"}, s.Columns)
+	assert.Equal(t, heapBytes(96, 1000000)-heapBytes(80, 1000000), s.Saving)
+	assert.Equal(t, `CREATE TABLE events (
+    id bigint,
+    created timestamp with time zone NOT NULL,
+    score integer NOT NULL,
+    kind smallint NOT NULL,
+    active boolean NOT NULL,
+    name text NOT NULL,
+    CONSTRAINT events_pkey PRIMARY KEY (id)
+)`, TableDefinition(c.Catalog, findTable(c.Catalog, "", s.Table), s.cols))
+}
@@ -0,0 +1,536 @@
+package main
+
+import (
+	"fmt"
+	"github.com/henges/pgmodelparse/collections"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// PartitionBound is the part of its parent's partition key a partition
+// holds the rows for.
+type PartitionBound struct {
+	// Default is set for the default partition, which holds the rows no
+	// other partition does.
+	Default bool
+	// In are the values of a list partition.
+	In []string
+	// From and To are the bounds of a range partition, To being exclusive.
+	From, To []string
+	// Modulus and Remainder are those of a hash partition.
+	Modulus, Remainder int
+}
+
+func (b *PartitionBound) String() string {
+
+	switch {
+	case b.Default:
+		return "DEFAULT"
+	case b.Modulus > 0:
+		return fmt.Sprintf("FOR VALUES WITH (MODULUS %d, REMAINDER %d)", b.Modulus, b.Remainder)
+	case b.From != nil:
+		return fmt.Sprintf("FOR VALUES FROM (%s) TO (%s)", strings.Join(b.From, ", "), strings.Join(b.To, ", "))
+	}
+	return fmt.Sprintf("FOR VALUES IN (%s)", strings.Join(b.In, ", "))
+}
+
+// CollapsedPartition is a partition which isn't modeled as a table of its
+// own, as Compiler.CollapsePartitions has it. Its columns are those of its
+// parent.
+type CollapsedPartition struct {
+	Schema string
+	Name   string
+	Bound  *PartitionBound
+}
+
+// partitionKey renders the PARTITION BY clause of a partitioned table,
+// without the PARTITION BY.
+func partitionKey(spec *pg_query.PartitionSpec) (string, error) {
+
+	var elems []string
+	for _, n := range spec.PartParams {
+		pe := n.GetPartitionElem()
+		if pe == nil {
+			return "", fmt.Errorf("expected PartitionElem but got %T", n.Node)
+		}
+		elem := QuoteIdent(pe.Name)
+		if pe.Expr != nil {
+			expr, err := DeparseExpr(pe.Expr)
+			if err != nil {
+				return "", err
+			}
+			elem = "(" + expr + ")"
+		}
+		collation, err := NodeStrings(pe.Collation)
+		if err != nil {
+			return "", err
+		}
+		if len(collation) > 0 {
+			elem += " COLLATE " + strings.Join(collation, ".")
+		}
+		opclass, err := NodeStrings(pe.Opclass)
+		if err != nil {
+			return "", err
+		}
+		if len(opclass) > 0 {
+			elem += " " + strings.Join(opclass, ".")
+		}
+		elems = append(elems, elem)
+	}
+	strategy := strings.TrimPrefix(spec.Strategy.String(), "PARTITION_STRATEGY_")
+	return fmt.Sprintf("%s (%s)", strategy, strings.Join(elems, ", ")), nil
+}
+
+// parsePartitionKey parses a partition key rendered by partitionKey back
+// into its PartitionSpec.
+func parsePartitionKey(key string) (*pg_query.PartitionSpec, error) {
+
+	tree, err := pg_query.Parse("CREATE TABLE t () PARTITION BY " + key)
+	if err != nil {
+		return nil, err
+	}
+	return tree.Stmts[0].Stmt.GetCreateStmt().GetPartspec(), nil
+}
+
+// partitionKeyColumns returns the names of the columns in t's partition
+// key, including those its expressions reference.
+func partitionKeyColumns(t *Table) ([]string, error) {
+
+	if t.PartitionKey == "" {
+		return nil, nil
+	}
+	spec, err := parsePartitionKey(t.PartitionKey)
+	if err != nil {
+		return nil, err
+	}
+	var ret []string
+	for _, n := range spec.PartParams {
+		pe := n.GetPartitionElem()
+		if pe.Expr == nil {
+			ret = append(ret, pe.Name)
+			continue
+		}
+		walkNodes(pe.Expr.ProtoReflect(), func(m protoreflect.Message) {
+			if ref, ok := m.Interface().(*pg_query.ColumnRef); ok {
+				if name := ref.Fields[len(ref.Fields)-1].GetString_(); name != nil {
+					ret = append(ret, name.Sval)
+				}
+			}
+		})
+	}
+	return ret, nil
+}
+
+// renamePartitionKeyColumn renames a column in the partition key given.
+func renamePartitionKeyColumn(key, oldName, newName string) (string, error) {
+
+	spec, err := parsePartitionKey(key)
+	if err != nil {
+		return "", err
+	}
+	for _, n := range spec.PartParams {
+		pe := n.GetPartitionElem()
+		if pe.Expr == nil {
+			if pe.Name == oldName {
+				pe.Name = newName
+			}
+			continue
+		}
+		if _, err := renameColumnReferences(pe.Expr, oldName, newName); err != nil {
+			return "", err
+		}
+	}
+	return partitionKey(spec)
+}
+
+// partitionsOf returns the partitions of t which are tables of their own.
+func (c *Compiler) partitionsOf(t *Table) []*Table {
+
+	var ret []*Table
+	for _, sch := range c.Catalog.Schemas.List() {
+		for _, part := range sch.Tables.List() {
+			if part.PartitionOf == t {
+				ret = append(ret, part)
+			}
+		}
+	}
+	return ret
+}
+
+// addPartitionColumns adds col, just added to t, to the partitions of t.
+func (c *Compiler) addPartitionColumns(t *Table, col *Column) error {
+
+	for _, part := range c.partitionsOf(t) {
+		partCol := c.partitionColumn(part, col)
+		err := part.AddColumn(partCol)
+		if err != nil {
+			return err
+		}
+		err = c.addPartitionColumns(part, partCol)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropPartitionColumns drops the column named, just dropped from t, from
+// the partitions of t.
+func (c *Compiler) dropPartitionColumns(t *Table, colName string, behavior pg_query.DropBehavior) error {
+
+	for _, part := range c.partitionsOf(t) {
+		err := c.DropColumn(part, colName, behavior)
+		if err != nil {
+			return err
+		}
+		err = c.dropPartitionColumns(part, colName, behavior)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkDropPartitionColumn checks that the column named can be dropped
+// from t and its partitions, which it can't be if it's in the partition
+// key of any of them.
+func (c *Compiler) checkDropPartitionColumn(t *Table, colName string) error {
+
+	keyCols, err := partitionKeyColumns(t)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(keyCols, colName) {
+		return fmt.Errorf("cannot drop column %s because it is part of the partition key of relation %s", colName, t.Name)
+	}
+	for _, part := range c.partitionsOf(t) {
+		err = c.checkDropPartitionColumn(part, colName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkRenamePartitionColumn checks that none of the partitions of t
+// already has a column of the name a column of t is being renamed to.
+func (c *Compiler) checkRenamePartitionColumn(t *Table, newName string) error {
+
+	for _, part := range c.partitionsOf(t) {
+		if orig, ok := part.Columns.Get(newName); ok {
+			return fmt.Errorf("column already exists: %s%s", newName, duplicateLocations(orig.Defined, c.stmtLocation()))
+		}
+		err := c.checkRenamePartitionColumn(part, newName)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func partitionBound(spec *pg_query.PartitionBoundSpec) (*PartitionBound, error) {
+
+	datums := func(nodes []*pg_query.Node) ([]string, error) {
+		ret := make([]string, 0, len(nodes))
+		for _, n := range nodes {
+			// MINVALUE and MAXVALUE are parsed as column references
+			if names := n.GetColumnRef().GetFields(); len(names) == 1 {
+				if name := names[0].GetString_().GetSval(); name == "minvalue" || name == "maxvalue" {
+					ret = append(ret, strings.ToUpper(name))
+					continue
+				}
+			}
+			s, err := DeparseExpr(n)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, s)
+		}
+		return ret, nil
+	}
+	b := &PartitionBound{Default: spec.IsDefault}
+	if b.Default {
+		return b, nil
+	}
+	var err error
+	switch spec.Strategy {
+	case "h":
+		b.Modulus, b.Remainder = int(spec.Modulus), int(spec.Remainder)
+	case "r":
+		{
+			b.From, err = datums(spec.Lowerdatums)
+			if err != nil {
+				return nil, err
+			}
+			b.To, err = datums(spec.Upperdatums)
+		}
+	default:
+		b.In, err = datums(spec.Listdatums)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// CreatePartition makes t, which CREATE TABLE ... PARTITION OF is
+// creating, a partition of the table named by parent with the columns of
+// the parent. If the compiler collapses partitions and t isn't
+// partitioned itself, it's recorded on the parent instead, and t is left
+// out of the catalog.
+func (c *Compiler) CreatePartition(t *Table, parent *pg_query.RangeVar, spec *pg_query.PartitionBoundSpec) (bool, error) {
+
+	p, err := c.FindTableFromRangeVar(parent)
+	if err != nil {
+		return false, err
+	}
+	if p.PartitionKey == "" {
+		return false, fmt.Errorf("table %s is not partitioned", p.Name)
+	}
+	bound, err := partitionBound(spec)
+	if err != nil {
+		return false, err
+	}
+	if c.CollapsePartitions && t.PartitionKey == "" {
+		if _, err := c.FindTableFromSchemaAndName(t.Schema, t.Name); err == nil {
+			return false, fmt.Errorf("table already exists: %s", t.Name)
+		}
+		if other, _ := c.collapsedPartition(t.Schema, t.Name); other != nil {
+			return false, fmt.Errorf("table already exists: %s", t.Name)
+		}
+		if p.Partitions == nil {
+			p.Partitions = collections.NewOrderedMap[string, *CollapsedPartition]()
+		}
+		p.Partitions.Add(t.Schema+"."+t.Name, &CollapsedPartition{Schema: t.Schema, Name: t.Name, Bound: bound})
+		return true, nil
+	}
+	for _, col := range p.Columns.List() {
+		err = t.AddColumn(c.partitionColumn(t, col))
+		if err != nil {
+			return false, err
+		}
+	}
+	t.PartitionOf, t.PartitionBound = p, bound
+	return false, nil
+}
+
+// partitionColumn returns the column of partition t inherited from col of
+// its parent. A serial column takes its values from the parent's sequence,
+// rather than having one of its own.
+func (c *Compiler) partitionColumn(t *Table, col *Column) *Column {
+
+	attrs := *col.Attrs
+	attrs.Pkey = false
+	attrs.PkeyPosition = 0
+	ret := &Column{
+		OID:        c.Catalog.newOID(),
+		Table:      t,
+		Name:       col.Name,
+		ColumnType: col.ColumnType,
+		Attrs:      &attrs,
+		Defined:    t.Defined,
+	}
+	if underlying, ok := serialTypes[col.Type]; ok {
+		ret.SetType(underlying, col.TypeMods, col.ArrayDims)
+		attrs.NotNull = true
+		attrs.Default, attrs.Sequence = nextvalDefault(col.Sequence()), col.Sequence()
+	}
+	return ret
+}
+
+// AttachPartition handles ALTER TABLE ... ATTACH PARTITION, collapsing the
+// partition into t if the compiler collapses partitions.
+func (c *Compiler) AttachPartition(t *Table, cmd *pg_query.PartitionCmd) error {
+
+	if t.PartitionKey == "" {
+		return fmt.Errorf("table %s is not partitioned", t.Name)
+	}
+	part, err := c.FindTableFromRangeVar(cmd.Name)
+	if err != nil {
+		return err
+	}
+	if part.PartitionOf != nil {
+		return fmt.Errorf("table %s is already a partition of %s", part.Name, part.PartitionOf.Name)
+	}
+	for _, col := range t.Columns.List() {
+		if _, ok := part.Columns.Get(col.Name); !ok {
+			return fmt.Errorf("table %s is missing column %s of table %s", part.Name, col.Name, t.Name)
+		}
+	}
+	bound, err := partitionBound(cmd.Bound)
+	if err != nil {
+		return err
+	}
+	if !c.CollapsePartitions || part.PartitionKey != "" {
+		part.PartitionOf, part.PartitionBound = t, bound
+		return nil
+	}
+	// What depends on the partition, such as its indexes, goes with it
+	err = c.DropTable(part.Schema, part.Name, DropBehaviourCascade)
+	if err != nil {
+		return err
+	}
+	if t.Partitions == nil {
+		t.Partitions = collections.NewOrderedMap[string, *CollapsedPartition]()
+	}
+	t.Partitions.Add(part.Schema+"."+part.Name, &CollapsedPartition{Schema: part.Schema, Name: part.Name, Bound: bound})
+	return nil
+}
+
+// DetachPartition handles ALTER TABLE ... DETACH PARTITION. A collapsed
+// partition becomes a table again, with its parent's columns.
+func (c *Compiler) DetachPartition(t *Table, cmd *pg_query.PartitionCmd) error {
+
+	schema := cmd.Name.Schemaname
+	if schema == "" {
+		schema = c.SearchPath
+	}
+	if t.Partitions != nil {
+		if _, ok := t.Partitions.Get(schema + "." + cmd.Name.Relname); ok && c.CollapsePartitions {
+			t.Partitions.Remove(schema + "." + cmd.Name.Relname)
+			part := NewTable(cmd.Name.Relname, schema)
+			part.OID = c.Catalog.newOID()
+			part.Defined = c.sourceLocation(cmd.Name.Location)
+			for _, col := range t.Columns.List() {
+				err := part.AddColumn(c.partitionColumn(part, col))
+				if err != nil {
+					return err
+				}
+			}
+			return c.Catalog.AddTable(part)
+		}
+	}
+	part, err := c.FindTableFromRangeVar(cmd.Name)
+	if err != nil {
+		return err
+	}
+	if part.PartitionOf != t {
+		return fmt.Errorf("table %s is not a partition of %s", part.Name, t.Name)
+	}
+	part.PartitionOf, part.PartitionBound = nil, nil
+	return nil
+}
+
+// collapsedPartition returns the partition named and the table it was
+// collapsed into, or nils if there's no such partition.
+func (c *Compiler) collapsedPartition(schema, name string) (*Table, *CollapsedPartition) {
+
+	if !c.CollapsePartitions {
+		return nil, nil
+	}
+	if schema == "" {
+		schema = c.SearchPath
+	}
+	for _, sch := range c.Catalog.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.Partitions == nil {
+				continue
+			}
+			if part, ok := t.Partitions.Get(schema + "." + name); ok {
+				return t, part
+			}
+		}
+	}
+	return nil, nil
+}
+
+// skipCollapsedPartition skips the statement being applied if it's on the
+// collapsed partition named by rv, reporting whether it did.
+func (c *Compiler) skipCollapsedPartition(rv *pg_query.RangeVar) bool {
+
+	if rv == nil {
+		return false
+	}
+	if parent, _ := c.collapsedPartition(rv.Schemaname, rv.Relname); parent == nil {
+		return false
+	}
+	c.skip(statementName(c.stmt.Stmt)+" on a collapsed partition", "")
+	return true
+}
+
+// renameCollapsedPartition renames the collapsed partition named by rv,
+// reporting whether there is one.
+func (c *Compiler) renameCollapsedPartition(rv *pg_query.RangeVar, newName string) (bool, error) {
+
+	parent, part := c.collapsedPartition(rv.Schemaname, rv.Relname)
+	if parent == nil {
+		return false, nil
+	}
+	if _, err := c.FindTableFromSchemaAndName(part.Schema, newName); err == nil {
+		return true, fmt.Errorf("table already exists: %s", newName)
+	}
+	if other, _ := c.collapsedPartition(part.Schema, newName); other != nil {
+		return true, fmt.Errorf("table already exists: %s", newName)
+	}
+	parent.Partitions.Rename(part.Schema+"."+part.Name, part.Schema+"."+newName)
+	part.Name = newName
+	return true, nil
+}
+
+// SummarizePartitions describes the bounds of collapsed partitions in as
+// few ranges as it can, by merging ranges which meet. The bounds of list
+// and hash partitions are only counted.
+func SummarizePartitions(parts []*CollapsedPartition) []string {
+
+	var ranges []*PartitionBound
+	var lists, hashes int
+	var hasDefault bool
+	for _, p := range parts {
+		switch {
+		case p.Bound.Default:
+			hasDefault = true
+		case p.Bound.Modulus > 0:
+			hashes++
+		case p.Bound.From != nil:
+			ranges = append(ranges, p.Bound)
+		default:
+			lists++
+		}
+	}
+	// Partitions are usually created in order, but a range may be extended
+	// at either end
+	byFrom := make(map[string]*PartitionBound, len(ranges))
+	isTo := make(map[string]bool, len(ranges))
+	for _, r := range ranges {
+		byFrom[strings.Join(r.From, ", ")] = r
+		isTo[strings.Join(r.To, ", ")] = true
+	}
+	var ret []string
+	for _, r := range ranges {
+		from := strings.Join(r.From, ", ")
+		if isTo[from] {
+			continue
+		}
+		to := strings.Join(r.To, ", ")
+		for {
+			next, ok := byFrom[to]
+			if !ok {
+				break
+			}
+			delete(byFrom, to)
+			to = strings.Join(next.To, ", ")
+		}
+		ret = append(ret, fmt.Sprintf("FROM (%s) TO (%s)", from, to))
+	}
+	if lists > 0 {
+		ret = append(ret, countOf(lists, "list partition"))
+	}
+	if hashes > 0 {
+		ret = append(ret, countOf(hashes, "hash partition"))
+	}
+	if hasDefault {
+		ret = append(ret, "DEFAULT")
+	}
+	return ret
+}
+
+func countOf(n int, noun string) string {
+
+	if n == 1 {
+		return "1 " + noun
+	}
+	return strconv.Itoa(n) + " " + noun + "s"
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_Partitions(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE events (id serial, at date NOT NULL, kind text) PARTITION BY RANGE (at);
+	CREATE TABLE events_2024 PARTITION OF events (kind NOT NULL) FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+	CREATE TABLE events_old (id int NOT NULL, at date NOT NULL, kind text);
+	ALTER TABLE events ATTACH PARTITION events_old FOR VALUES FROM (MINVALUE) TO ('2024-01-01');
+	CREATE TABLE events_rest PARTITION OF events DEFAULT;
+	`)
+	events := assertTable(t, c, "events")
+	assert.Equal(t, "RANGE (at)", events.PartitionKey)
+	current := assertTable(t, c, "events_2024")
+	assert.Equal(t, events, current.PartitionOf)
+	assert.Equal(t, "FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')", current.PartitionBound.String())
+	assertColumn(t, current, "id", Integer, ColumnAttributes{NotNull: true, Default: "nextval('public.events_id_seq'::regclass)", Sequence: "public.events_id_seq"})
+	assertColumn(t, current, "kind", Text, ColumnAttributes{NotNull: true})
+	assert.Equal(t, "FOR VALUES FROM (MINVALUE) TO ('2024-01-01')", assertTable(t, c, "events_old").PartitionBound.String())
+
+	var sb strings.Builder
+	DescribeTable(&sb, c.Catalog, events)
+	assert.Contains(t, sb.String(), "Partition key: RANGE (at)\nPartitions:\n"+
+		"    events_2024 FOR VALUES FROM ('2024-01-01') TO ('2025-01-01')\n"+
+		"    events_old FOR VALUES FROM (MINVALUE) TO ('2024-01-01')\n"+
+		"    events_rest DEFAULT\n")
+	sb.Reset()
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), ") PARTITION BY RANGE (at);\n")
+	assert.Contains(t, sb.String(), "ALTER TABLE events ATTACH PARTITION events_old FOR VALUES FROM (MINVALUE) TO ('2024-01-01');\n")
+
+	require.Nil(t, c.Compile(`ALTER TABLE events DETACH PARTITION events_old; DROP TABLE events;`))
+	assert.Nil(t, assertTable(t, c, "events_old").PartitionOf)
+	sch, _ := c.Catalog.Schemas.Get("public")
+	assert.Len(t, sch.Tables.List(), 1)
+
+	assert.ErrorContains(t, c.Compile(`CREATE TABLE p PARTITION OF events_old DEFAULT;`), "table events_old is not partitioned")
+	assert.ErrorContains(t, c.Compile(`
+	CREATE TABLE logs (id int, at date) PARTITION BY LIST (at);
+	CREATE TABLE other (id int);
+	ALTER TABLE logs ATTACH PARTITION other DEFAULT;
+	`), "table other is missing column at of table logs")
+}
+
+func TestCompiler_AlterPartitionedTable(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE events (id int, at date NOT NULL, region text) PARTITION BY RANGE (at);
+	CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01') PARTITION BY LIST (region);
+	CREATE TABLE events_2024_eu PARTITION OF events_2024 FOR VALUES IN ('eu');
+	ALTER TABLE events ADD COLUMN kind text NOT NULL DEFAULT 'click';
+	ALTER TABLE events DROP COLUMN id;
+	ALTER TABLE events RENAME COLUMN at TO occurred_on;
+	ALTER TABLE events RENAME COLUMN region TO area;
+	`)
+	events := assertTable(t, c, "events")
+	assert.Equal(t, "RANGE (occurred_on)", events.PartitionKey)
+	for _, name := range []string{"events_2024", "events_2024_eu"} {
+		part := assertTable(t, c, name)
+		assert.Equal(t, Columns(events.Columns.List()).Names(), Columns(part.Columns.List()).Names(), name)
+		assertColumn(t, part, "kind", Text, ColumnAttributes{NotNull: true, Default: "'click'"})
+	}
+	assert.Equal(t, "LIST (area)", assertTable(t, c, "events_2024").PartitionKey)
+
+	const schema = `
+	CREATE TABLE events (id int, at date NOT NULL) PARTITION BY RANGE (at);
+	CREATE TABLE events_2024 PARTITION OF events FOR VALUES FROM ('2024-01-01') TO ('2025-01-01');
+	`
+	assertParseError(t, schema+`ALTER TABLE events DROP COLUMN at;`,
+		"cannot drop column at because it is part of the partition key of relation events")
+	assertParseError(t, schema+`ALTER TABLE events_2024 ADD COLUMN kind text;`, "cannot add column to a partition")
+	assertParseError(t, schema+`ALTER TABLE events_2024 DROP COLUMN id;`, "cannot drop inherited column id")
+	assertParseError(t, schema+`ALTER TABLE events_2024 RENAME COLUMN id TO event_id;`, "cannot rename inherited column id")
+	assertParseError(t, `
+	CREATE TABLE logs (id int, at date) PARTITION BY RANGE ((at + 1));
+	ALTER TABLE logs DROP COLUMN at;
+	`, "cannot drop column at because it is part of the partition key of relation logs")
+}
+
+func TestCompiler_CollapsePartitions(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("CREATE TABLE events (id int NOT NULL, at date NOT NULL) PARTITION BY RANGE (at);\n")
+	for month := 1; month <= 12; month++ {
+		to := fmt.Sprintf("2024-%02d-01", month+1)
+		if month == 12 {
+			to = "2025-01-01"
+		}
+		fmt.Fprintf(&sb, "CREATE TABLE events_%02d PARTITION OF events FOR VALUES FROM ('2024-%02d-01') TO ('%s');\n", month, month, to)
+	}
+	sb.WriteString(`
+	CREATE TABLE events_2023 (id int NOT NULL, at date NOT NULL);
+	CREATE INDEX events_2023_at ON events_2023 (at);
+	ALTER TABLE ONLY events ATTACH PARTITION events_2023 FOR VALUES FROM ('2023-01-01') TO ('2024-01-01');
+	CREATE TABLE events_far PARTITION OF events FOR VALUES FROM ('2030-01-01') TO ('2031-01-01');
+	CREATE TABLE events_rest PARTITION OF events DEFAULT;
+	ALTER TABLE ONLY events_01 ADD CONSTRAINT events_01_pkey PRIMARY KEY (id, at);
+	CREATE INDEX events_02_at ON events_02 (at);
+	ALTER TABLE events_far RENAME TO events_2030;
+	`)
+	c := NewCompiler()
+	c.CollapsePartitions = true
+	require.Nil(t, c.Compile(sb.String()))
+
+	sch, _ := c.Catalog.Schemas.Get("public")
+	assert.Len(t, sch.Tables.List(), 1)
+	events := assertTable(t, c, "events")
+	assert.Len(t, events.Partitions.List(), 15)
+	assert.Equal(t, []string{
+		"FROM ('2023-01-01') TO ('2025-01-01')",
+		"FROM ('2030-01-01') TO ('2031-01-01')",
+		"DEFAULT",
+	}, SummarizePartitions(events.Partitions.List()))
+	assert.Empty(t, c.Catalog.Depends.IndexesByName)
+	var whats []string
+	for _, s := range SummarizeSkipped(c.Skipped) {
+		whats = append(whats, s.What)
+	}
+	assert.Equal(t, []string{"ALTER TABLE on a collapsed partition", "CREATE INDEX on a collapsed partition"}, whats)
+
+	sb.Reset()
+	require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), "-- events has 15 collapsed partitions not modeled: "+
+		"FROM ('2023-01-01') TO ('2025-01-01'), FROM ('2030-01-01') TO ('2031-01-01'), DEFAULT\n")
+
+	// Detaching a partition models it as a table again
+	assert.ErrorContains(t, c.Compile(`CREATE TABLE events_03 (id int);`), "table already exists: events_03")
+	require.Nil(t, c.Compile(`ALTER TABLE events DETACH PARTITION events_2030; DROP TABLE events_01;`))
+	detached := assertTable(t, c, "events_2030")
+	assertColumn(t, detached, "at", Date, ColumnAttributes{NotNull: true})
+	assert.Len(t, events.Partitions.List(), 13)
+
+	require.Nil(t, c.Compile(`DROP TABLE events;`))
+	_, ok := sch.Tables.Get("events")
+	assert.False(t, ok)
+	require.Nil(t, c.Compile(`CREATE TABLE events_03 (id int);`))
+}
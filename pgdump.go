@@ -0,0 +1,239 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// DumpMagic starts every archive written by pg_dump -Fc.
+const DumpMagic = "PGDMP"
+
+// The archive versions which changed the parts of the format read here.
+// Older archives, from before Postgres 9.0, aren't supported.
+const (
+	dumpVersion1_11 = 1<<16 | 11<<8
+	dumpVersion1_14 = 1<<16 | 14<<8
+	dumpVersion1_15 = 1<<16 | 15<<8
+	dumpVersion1_16 = 1<<16 | 16<<8
+)
+
+// DumpSection is the part of a restore a TOC entry belongs to.
+type DumpSection int
+
+const (
+	DumpSectionNone DumpSection = iota + 1
+	DumpSectionPreData
+	DumpSectionData
+	DumpSectionPostData
+)
+
+// DumpEntry is an entry of the table of contents of a custom format dump.
+type DumpEntry struct {
+	DumpID int
+	// Desc is the kind of object, such as "TABLE" or "INDEX", and Tag
+	// names it.
+	Desc      string
+	Tag       string
+	Namespace string
+	Section   DumpSection
+	// Defn is the SQL creating the object, which is empty for data.
+	Defn string
+	// Owner is the role owning the object, which pg_restore sets with a
+	// statement of its own.
+	Owner string
+}
+
+// ReadDumpTOC reads the table of contents of a dump written by pg_dump -Fc.
+// Only the header and table of contents are read, not the data which
+// follows them.
+func ReadDumpTOC(r io.Reader) ([]*DumpEntry, error) {
+
+	d := &dumpReader{r: bufio.NewReader(r)}
+	magic := make([]byte, len(DumpMagic))
+	d.read(magic)
+	if d.err == nil && string(magic) != DumpMagic {
+		return nil, fmt.Errorf("not a custom format dump")
+	}
+	vmaj, vmin, vrev := d.byte(), d.byte(), d.byte()
+	d.version = int(vmaj)<<16 | int(vmin)<<8 | int(vrev)
+	d.intSize, d.offSize = int(d.byte()), int(d.byte())
+	format := d.byte()
+	if d.err != nil {
+		return nil, fmt.Errorf("while reading dump header: %w", d.err)
+	}
+	if d.version < dumpVersion1_11 {
+		return nil, fmt.Errorf("dump archive version %d.%d is too old", vmaj, vmin)
+	}
+	if format != 1 {
+		return nil, fmt.Errorf("only custom format dumps can be read, not format %d", format)
+	}
+	if d.version >= dumpVersion1_15 {
+		d.byte() // compression algorithm
+	} else {
+		d.int() // compression level
+	}
+	for range 7 {
+		d.int() // creation time
+	}
+	d.str() // database name
+	d.str() // server version
+	d.str() // pg_dump version
+	if d.err != nil {
+		return nil, fmt.Errorf("while reading dump header: %w", d.err)
+	}
+
+	count := d.int()
+	entries := make([]*DumpEntry, 0, max(count, 0))
+	for i := 0; i < count && d.err == nil; i++ {
+		e := &DumpEntry{DumpID: d.int()}
+		d.int() // whether there's data
+		d.str() // catalog table oid
+		d.str() // oid
+		e.Tag, _ = d.str()
+		e.Desc, _ = d.str()
+		e.Section = DumpSection(d.int())
+		e.Defn, _ = d.str()
+		d.str() // DROP statement
+		d.str() // COPY statement
+		e.Namespace, _ = d.str()
+		d.str() // tablespace
+		if d.version >= dumpVersion1_14 {
+			d.str() // table access method
+		}
+		if d.version >= dumpVersion1_16 {
+			d.int() // relkind
+		}
+		e.Owner, _ = d.str()
+		d.str() // WITH OIDS
+		for {
+			if _, ok := d.str(); !ok || d.err != nil {
+				break
+			}
+		}
+		// The custom format follows each entry with the data's offset
+		d.read(make([]byte, 1+d.offSize))
+		entries = append(entries, e)
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("while reading dump table of contents: %w", d.err)
+	}
+	return entries, nil
+}
+
+// dumpOwnedDescs are the kinds of dump entries whose owners are modeled.
+var dumpOwnedDescs = []string{"SCHEMA", "TABLE", "VIEW", "SEQUENCE", "TYPE", "EVENT TRIGGER"}
+
+// DumpSchema returns the SQL defining the objects of a dump, in the order
+// pg_restore would create them.
+func DumpSchema(entries []*DumpEntry) string {
+
+	var sb strings.Builder
+	for _, e := range entries {
+		// The public schema already exists
+		if e.Defn == "" || e.Section == DumpSectionData || (e.Desc == "SCHEMA" && e.Tag == "public") {
+			continue
+		}
+		sb.WriteString(strings.TrimRight(e.Defn, "\n"))
+		sb.WriteString("\n")
+		if e.Owner != "" && slices.Contains(dumpOwnedDescs, e.Desc) {
+			ident := QuoteIdent(e.Tag)
+			if e.Namespace != "" {
+				ident = QuoteIdent(e.Namespace) + "." + ident
+			}
+			sb.WriteString(OwnerDefinition(e.Desc, ident, e.Owner) + ";\n")
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
+
+// dumpReader reads the values pg_dump writes, remembering the first error.
+type dumpReader struct {
+	r                *bufio.Reader
+	version          int
+	intSize, offSize int
+	err              error
+}
+
+func (d *dumpReader) read(b []byte) {
+
+	if d.err != nil {
+		return
+	}
+	_, d.err = io.ReadFull(d.r, b)
+	if d.err == io.EOF {
+		d.err = io.ErrUnexpectedEOF
+	}
+}
+
+func (d *dumpReader) byte() byte {
+
+	b := make([]byte, 1)
+	d.read(b)
+	return b[0]
+}
+
+// int reads an integer, which is a sign byte followed by its magnitude in
+// little endian order.
+func (d *dumpReader) int() int {
+
+	b := make([]byte, 1+d.intSize)
+	d.read(b)
+	var magnitude [8]byte
+	copy(magnitude[:], b[1:])
+	n := int(binary.LittleEndian.Uint64(magnitude[:]))
+	if b[0] != 0 {
+		return -n
+	}
+	return n
+}
+
+// str reads a string, returning false if it's null.
+func (d *dumpReader) str() (string, bool) {
+
+	n := d.int()
+	if n < 0 || d.err != nil {
+		return "", false
+	}
+	// A corrupt length shouldn't exhaust memory
+	if n > 1<<30 {
+		d.err = fmt.Errorf("string of %d bytes is too long", n)
+		return "", false
+	}
+	b := make([]byte, n)
+	d.read(b)
+	return string(b), true
+}
+
+// readDumpFile returns the schema of the dump at path, or false if the file
+// isn't a custom format dump.
+func readDumpFile(path string) (string, bool, error) {
+
+	f, err := os.Open(path)
+	if err != nil {
+		return "", false, err
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	if !isDump(r) {
+		return "", false, nil
+	}
+	entries, err := ReadDumpTOC(r)
+	if err != nil {
+		return "", true, err
+	}
+	return DumpSchema(entries), true, nil
+}
+
+// isDump reports whether r, which isn't advanced, starts with DumpMagic.
+func isDump(r *bufio.Reader) bool {
+
+	b, _ := r.Peek(len(DumpMagic))
+	return bytes.Equal(b, []byte(DumpMagic))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// dumpWriter writes archives in the custom format, as pg_dump does.
+type dumpWriter struct {
+	bytes.Buffer
+	vmin byte
+}
+
+func (w *dumpWriter) int(n int) {
+
+	sign := byte(0)
+	if n < 0 {
+		sign, n = 1, -n
+	}
+	w.Write([]byte{sign, byte(n), byte(n >> 8), byte(n >> 16), byte(n >> 24)})
+}
+
+func (w *dumpWriter) str(s string) {
+
+	w.int(len(s))
+	w.WriteString(s)
+}
+
+func (w *dumpWriter) null() {
+
+	w.int(-1)
+}
+
+func (w *dumpWriter) write(entries []*DumpEntry) []byte {
+
+	w.WriteString(DumpMagic)
+	w.Write([]byte{1, w.vmin, 0, 4, 8, 1})
+	if w.vmin >= 15 {
+		w.WriteByte(1)
+	} else {
+		w.int(-1)
+	}
+	for _, n := range []int{0, 0, 12, 1, 5, 124, 0} {
+		w.int(n)
+	}
+	w.str("app")
+	w.str("16.2")
+	w.str("16.2")
+	w.int(len(entries))
+	for _, e := range entries {
+		w.int(e.DumpID)
+		w.int(0)
+		w.str("1259")
+		w.str("16384")
+		w.str(e.Tag)
+		w.str(e.Desc)
+		w.int(int(e.Section))
+		w.str(e.Defn)
+		w.str("")
+		w.str("")
+		w.str(e.Namespace)
+		w.str("")
+		if w.vmin >= 14 {
+			w.str("heap")
+		}
+		if w.vmin >= 16 {
+			w.int('r')
+		}
+		w.str(e.Owner)
+		w.str("false")
+		w.str("1")
+		w.null()
+		w.Write(make([]byte, 9))
+	}
+	// Data, which isn't read, follows the table of contents
+	w.WriteString("\x01\x02\x03")
+	return w.Bytes()
+}
+
+var dumpEntries = []*DumpEntry{
+	{DumpID: 1, Desc: "ENCODING", Tag: "ENCODING", Section: DumpSectionPreData, Defn: "SET client_encoding = 'UTF8';\n"},
+	{DumpID: 2, Desc: "SCHEMA", Tag: "public", Section: DumpSectionPreData, Defn: "CREATE SCHEMA public;\n"},
+	{DumpID: 3, Desc: "SCHEMA", Tag: "app", Section: DumpSectionPreData, Defn: "CREATE SCHEMA app;\n", Owner: "app_owner"},
+	{DumpID: 4, Desc: "TABLE", Tag: "users", Namespace: "app", Section: DumpSectionPreData,
+		Defn: "CREATE TABLE app.users (\n    id integer NOT NULL,\n    email text\n);\n", Owner: "app_owner"},
+	{DumpID: 5, Desc: "TABLE DATA", Tag: "users", Namespace: "app", Section: DumpSectionData},
+	{DumpID: 6, Desc: "CONSTRAINT", Tag: "users users_pkey", Namespace: "app", Section: DumpSectionPostData,
+		Defn: "ALTER TABLE ONLY app.users\n    ADD CONSTRAINT users_pkey PRIMARY KEY (id);\n"},
+	{DumpID: 7, Desc: "INDEX", Tag: "users_email_idx", Namespace: "app", Section: DumpSectionPostData,
+		Defn: "CREATE INDEX users_email_idx ON app.users USING btree (email);\n"},
+}
+
+func TestReadDumpTOC(t *testing.T) {
+	for _, vmin := range []byte{13, 14, 15, 16} {
+		w := &dumpWriter{vmin: vmin}
+		entries, err := ReadDumpTOC(bytes.NewReader(w.write(dumpEntries)))
+		require.Nil(t, err, "version 1.%d", vmin)
+		assert.Equal(t, dumpEntries, entries, "version 1.%d", vmin)
+	}
+
+	_, err := ReadDumpTOC(bytes.NewReader((&dumpWriter{vmin: 10}).write(nil)))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "too old")
+	b := (&dumpWriter{vmin: 16}).write(dumpEntries)
+	_, err = ReadDumpTOC(bytes.NewReader(b[:200]))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "while reading dump table of contents")
+}
+
+func TestCompiler_CompileFilesDump(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "backup.dump")
+	require.Nil(t, os.WriteFile(path, (&dumpWriter{vmin: 15}).write(dumpEntries), 0o644))
+
+	c := NewCompiler()
+	require.Nil(t, c.CompileFiles([]string{path}))
+	tab := assertTable(t, c, "app.users")
+	assertColumn(t, tab, "id", Integer, ColumnAttributes{NotNull: true, Pkey: true, PkeyPosition: 1})
+	assert.Len(t, c.Catalog.Depends.TableIndexes(tab), 1)
+	assert.Equal(t, "app_owner", tab.Owner)
+}
@@ -1,41 +1,424 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"github.com/henges/pgmodelparse/collections"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
 	"slices"
 	"strings"
 )
 
+// Catalog is the schema built by compiling statements. Any number of
+// goroutines may read a catalog at once, as long as nothing modifies it,
+// which Freeze enforces once compiling is done.
 type Catalog struct {
 	Schemas *collections.OrderedMap[string, *Schema]
 	Depends *Depends
+	// Raw holds the statements kept verbatim, in the order they were
+	// applied.
+	Raw []*RawStatement
+	// EventTriggers belong to the database rather than a schema.
+	EventTriggers *collections.OrderedMap[string, *EventTrigger]
+	// Descriptions are keyed by the object described.
+	Descriptions map[any]*Description
+	// lastOID is the OID last given to an object.
+	lastOID OID
+	freezable
+}
+
+// ErrFrozen is the error applying a statement to a frozen catalog.
+var ErrFrozen = errors.New("catalog is frozen")
+
+// Freeze marks the catalog as complete. Compilers fail to apply statements
+// to a frozen catalog, and the methods of it, its dependencies, schemas and
+// tables which modify them panic, so that it's safe to read from many
+// goroutines at once, such as generators running in parallel.
+func (c *Catalog) Freeze() {
+
+	c.frozen = true
+	c.Depends.frozen = true
+	for _, sch := range c.Schemas.List() {
+		sch.frozen = true
+		for _, t := range sch.Tables.List() {
+			t.frozen = true
+		}
+	}
+}
+
+func (c *Catalog) Frozen() bool {
+
+	return c.frozen
+}
+
+// freezable is embedded in the catalog and the objects it holds, which
+// are frozen with it.
+type freezable struct {
+	frozen bool
+}
+
+// modify panics if the object is frozen, for methods which modify it.
+func (f *freezable) modify() {
+
+	if f.frozen {
+		panic(ErrFrozen)
+	}
+}
+
+// OID identifies an object of the catalog. It's kept when the object is
+// renamed, and as objects are numbered in the order they're created, the
+// same object has the same OID in catalogs compiled from sources which
+// start the same, such as a set of migrations and the same set with more
+// added. Like Postgres' OIDs for user objects, they start at 16384.
+type OID uint32
+
+const firstOID OID = 16384
+
+// newOID returns the OID of an object being created.
+func (c *Catalog) newOID() OID {
+
+	c.modify()
+
+	c.lastOID = max(c.lastOID+1, firstOID)
+	return c.lastOID
+}
+
+// EventTrigger is a trigger on DDL commands, created by CREATE EVENT
+// TRIGGER.
+type EventTrigger struct {
+	OID  OID
+	Name string
+	// Event is the event which fires the trigger, such as
+	// "ddl_command_end".
+	Event string
+	// Tags are the command tags the trigger is limited to, such as "CREATE
+	// TABLE", or empty if every command fires it.
+	Tags []string
+	// Function is the schema qualified name of the function executed, if
+	// it was qualified. Functions aren't modeled, so it isn't checked.
+	Function string
+	Firing   TriggerFiring
+	Owner    string
+}
+
+// TriggerFiring is when an enabled trigger fires, depending on the
+// session_replication_role setting.
+type TriggerFiring int
+
+const (
+	// TriggerFiringOrigin fires the trigger unless the session is a
+	// replica, which is the default.
+	TriggerFiringOrigin TriggerFiring = iota
+	TriggerFiringDisabled
+	TriggerFiringReplica
+	TriggerFiringAlways
+)
+
+func (f TriggerFiring) String() string {
+
+	switch f {
+	case TriggerFiringDisabled:
+		return "disabled"
+	case TriggerFiringReplica:
+		return "replica"
+	case TriggerFiringAlways:
+		return "always"
+	default:
+		return "origin"
+	}
+}
+
+// RawStatement is a statement which isn't modeled, such as CREATE RULE or a
+// DO block, kept verbatim so that it can be emitted again.
+type RawStatement struct {
+	// Kind is the kind of statement, such as "CREATE RULE".
+	Kind string
+	// Name and Table identify the object the statement creates, if it
+	// creates one, and OID is the object's.
+	Name  string
+	Table *Table
+	OID   OID
+	SQL   string
+	// Depends names the objects which aren't modeled that the statement
+	// uses, such as "function public.f" or "type money".
+	Depends []string
+	// Columns are the output columns of a view.
+	Columns []*ViewColumn
+	// Owner is the role owning a view, or empty if it isn't known.
+	Owner string
+	// Extension is the extension a view belongs to, or empty if it doesn't
+	// belong to one.
+	Extension string
 }
 
 type Depends struct {
 	ConstraintsByColumn *collections.Multimap[*Column, *Constraint]
-	ConstraintsByName   map[string]*Constraint
+	// ConstraintsByName is keyed by the table and name of the constraint,
+	// as the names of constraints are only unique within their table.
+	ConstraintsByName map[ConstraintKey]*Constraint
+	IndexesByColumn   *collections.Multimap[*Column, *Index]
+	// IndexesByName is keyed by the schema qualified name of the index.
+	IndexesByName      map[string]*Index
+	StatisticsByColumn *collections.Multimap[*Column, *Statistics]
+	// StatisticsByName is keyed by the schema qualified name of the
+	// statistics object.
+	StatisticsByName map[string]*Statistics
+	// dependents and dependencies are the edges of the dependency graph,
+	// keyed by the object depended on and by the dependent respectively.
+	dependents   *collections.Multimap[any, *Dependency]
+	dependencies *collections.Multimap[any, *Dependency]
+	freezable
+}
+
+// NewDepends returns an empty Depends.
+func NewDepends() *Depends {
+
+	return &Depends{
+		ConstraintsByColumn: collections.NewMultimap[*Column, *Constraint](),
+		ConstraintsByName:   make(map[ConstraintKey]*Constraint),
+		IndexesByColumn:     collections.NewMultimap[*Column, *Index](),
+		IndexesByName:       make(map[string]*Index),
+		StatisticsByColumn:  collections.NewMultimap[*Column, *Statistics](),
+		StatisticsByName:    make(map[string]*Statistics),
+		dependents:          collections.NewMultimap[any, *Dependency](),
+		dependencies:        collections.NewMultimap[any, *Dependency](),
+	}
+}
+
+// Dependency is an edge of the dependency graph, recording that Dependent
+// depends on Object. Objects are *Table, *Column, *Constraint, *Index,
+// *Statistics or *RawStatement.
+type Dependency struct {
+	Dependent any
+	Object    any
+	// Behaviour is what happens to Dependent when Object is dropped without
+	// CASCADE: either it's dropped too, or it prevents the drop.
+	Behaviour DropBehaviour
+}
+
+func (d *Depends) addDependency(dependent, obj any, behav DropBehaviour) {
+
+	d.modify()
+	dep := &Dependency{Dependent: dependent, Object: obj, Behaviour: behav}
+	d.dependents.Add(obj, dep)
+	d.dependencies.Add(dependent, dep)
+}
+
+// removeDependencies removes the edges from dependent to the objects it
+// depends on.
+func (d *Depends) removeDependencies(dependent any) {
+
+	d.modify()
+	deps, _ := d.dependencies.Get(dependent)
+	for _, dep := range deps {
+		d.dependents.RemoveValue(dep.Object, dep)
+	}
+	d.dependencies.Remove(dependent)
+}
+
+// DependentsOf returns the dependencies of objects directly depending on
+// obj, in the order they were added.
+func (d *Depends) DependentsOf(obj any) []*Dependency {
+
+	deps, _ := d.dependents.Get(obj)
+	return slices.Clone(deps)
+}
+
+// objectName returns the kind of obj and its name, qualified by its schema
+// and by its table for columns, constraints and rules.
+func objectName(obj any) (string, string) {
+
+	switch obj := obj.(type) {
+	case *Table:
+		return "table", obj.Schema + "." + obj.Name
+	case *Column:
+		return "column", obj.Table.Schema + "." + obj.Table.Name + "." + obj.Name
+	case *Constraint:
+		return "constraint", obj.Table.Schema + "." + obj.Table.Name + "." + obj.Name
+	case *Index:
+		return "index", obj.QualifiedName()
+	case *Statistics:
+		return "statistics", obj.QualifiedName()
+	case *RawStatement:
+		{
+			kind := strings.ToLower(strings.TrimPrefix(obj.Kind, "CREATE "))
+			if obj.Table != nil {
+				return kind, obj.Table.Schema + "." + obj.Table.Name + "." + obj.Name
+			}
+			return kind, obj.Name
+		}
+	default:
+		panic(fmt.Sprintf("unexpected object %T", obj))
+	}
+}
+
+// objectOID returns the OID of obj, one of the objects objectName names.
+func objectOID(obj any) OID {
+
+	switch obj := obj.(type) {
+	case *Table:
+		return obj.OID
+	case *Column:
+		return obj.OID
+	case *Constraint:
+		return obj.OID
+	case *Index:
+		return obj.OID
+	case *Statistics:
+		return obj.OID
+	case *RawStatement:
+		return obj.OID
+	default:
+		panic(fmt.Sprintf("unexpected object %T", obj))
+	}
+}
+
+// objectTable returns the table obj belongs to, or nil if it doesn't
+// belong to one.
+func objectTable(obj any) *Table {
+
+	switch obj := obj.(type) {
+	case *Table:
+		return obj
+	case *Column:
+		return obj.Table
+	case *Constraint:
+		return obj.Table
+	case *Index:
+		return obj.Table
+	case *Statistics:
+		return obj.Table
+	case *RawStatement:
+		return obj.Table
+	default:
+		return nil
+	}
 }
 
 func (d *Depends) AddConstraint(cons *Constraint) {
 
+	d.modify()
 	for _, col := range cons.Depends() {
 		d.ConstraintsByColumn.Add(col, cons)
+		d.addDependency(cons, col, cons.DropBehaviour)
 	}
-	d.ConstraintsByName[cons.Name] = cons
+	d.addDependency(cons, cons.Table, DropBehaviourCascade)
+	d.ConstraintsByName[cons.Key()] = cons
 	cons.OnCreate()
 }
 
 func (d *Depends) RemoveConstraint(cons *Constraint) {
+
+	d.modify()
 	for _, col := range cons.Depends() {
-		d.ConstraintsByColumn.Remove(col)
+		d.ConstraintsByColumn.RemoveValue(col, cons)
 	}
-	delete(d.ConstraintsByName, cons.Name)
+	d.removeDependencies(cons)
+	delete(d.ConstraintsByName, cons.Key())
 	cons.OnRemove()
 }
 
+// AddRaw records a raw statement, which depends on its table if it has one.
+func (c *Catalog) AddRaw(raw *RawStatement) {
+
+	c.modify()
+	if raw.Table != nil {
+		c.Depends.addDependency(raw, raw.Table, DropBehaviourCascade)
+	}
+	if raw.Name != "" {
+		raw.OID = c.newOID()
+	}
+	c.Raw = append(c.Raw, raw)
+}
+
+// RemoveRaw removes the raw statements for which remove returns true.
+func (c *Catalog) RemoveRaw(remove func(*RawStatement) bool) {
+
+	c.modify()
+	c.Raw = slices.DeleteFunc(c.Raw, func(raw *RawStatement) bool {
+		if !remove(raw) {
+			return false
+		}
+		c.Depends.removeDependencies(raw)
+		return true
+	})
+}
+
+func (d *Depends) AddIndex(idx *Index) {
+
+	d.modify()
+	for _, col := range idx.Columns {
+		d.IndexesByColumn.Add(col, idx)
+		d.addDependency(idx, col, DropBehaviourCascade)
+	}
+	d.addDependency(idx, idx.Table, DropBehaviourCascade)
+	d.IndexesByName[idx.QualifiedName()] = idx
+}
+
+func (d *Depends) RemoveIndex(idx *Index) {
+
+	d.modify()
+	for _, col := range idx.Columns {
+		d.IndexesByColumn.RemoveValue(col, idx)
+	}
+	d.removeDependencies(idx)
+	delete(d.IndexesByName, idx.QualifiedName())
+}
+
+// TableIndexes returns the indexes on t, ordered by name.
+func (d *Depends) TableIndexes(t *Table) []*Index {
+
+	var ret []*Index
+	for _, idx := range d.IndexesByName {
+		if idx.Table == t {
+			ret = append(ret, idx)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *Index) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
+func (d *Depends) AddStatistics(s *Statistics) {
+
+	d.modify()
+	for _, col := range s.Columns {
+		d.StatisticsByColumn.Add(col, s)
+		d.addDependency(s, col, DropBehaviourCascade)
+	}
+	d.addDependency(s, s.Table, DropBehaviourCascade)
+	d.StatisticsByName[s.QualifiedName()] = s
+}
+
+func (d *Depends) RemoveStatistics(s *Statistics) {
+
+	d.modify()
+	for _, col := range s.Columns {
+		d.StatisticsByColumn.RemoveValue(col, s)
+	}
+	d.removeDependencies(s)
+	delete(d.StatisticsByName, s.QualifiedName())
+}
+
+// TableStatistics returns the statistics objects on t, ordered by name.
+func (d *Depends) TableStatistics(t *Table) []*Statistics {
+
+	var ret []*Statistics
+	for _, s := range d.StatisticsByName {
+		if s.Table == t {
+			ret = append(ret, s)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *Statistics) int {
+		return strings.Compare(a.QualifiedName(), b.QualifiedName())
+	})
+	return ret
+}
+
 func (c *Catalog) AddTable(t *Table) error {
 
+	c.modify()
 	schema, ok := c.Schemas.Get(t.Schema)
 	if !ok {
 		return fmt.Errorf("no such schema: %s", t.Schema)
@@ -44,23 +427,96 @@ func (c *Catalog) AddTable(t *Table) error {
 }
 
 type Schema struct {
-	Name   string
+	Name string
+	// Owner is the role owning the schema, or empty if it isn't known.
+	Owner  string
 	Tables *collections.OrderedMap[string, *Table]
+	Enums  *collections.OrderedMap[string, *Enum]
+	// Sequences are only recorded by name and owner, see Sequence.
+	Sequences *collections.OrderedMap[string, *Sequence]
+	freezable
 }
 
 func (s *Schema) AddTable(t *Table) error {
-	_, ok := s.Tables.Get(t.Name)
+
+	s.modify()
+	orig, ok := s.Tables.Get(t.Name)
 	if ok {
-		return fmt.Errorf("table already exists: %s", t.Name)
+		return fmt.Errorf("table already exists: %s%s", t.Name, duplicateLocations(orig.Defined, t.Defined))
 	}
 	s.Tables.Add(t.Name, t)
 	return nil
 }
 
 type Table struct {
-	Name    string
-	Schema  string
-	Columns *collections.OrderedMap[string, *Column]
+	OID         OID
+	Name        string
+	Schema      string
+	Columns     *collections.OrderedMap[string, *Column]
+	Annotations Annotations
+	// Defined is where the table was created.
+	Defined SourceLocation
+	// ReplicaIdentity is what logical replication records to identify the
+	// rows updated or deleted, and ReplicaIndex is the index used when it's
+	// ReplicaIdentityIndex.
+	ReplicaIdentity ReplicaIdentity
+	ReplicaIndex    *Index
+	// ClusterIndex is the name of the index the table was last clustered
+	// on, which may be that of a primary key or unique constraint, or
+	// empty if it hasn't been.
+	ClusterIndex string
+	// Owner is the role owning the table, or empty if it isn't known.
+	Owner string
+	// Options are the table's storage parameters, such as "fillfactor=70",
+	// ordered by name, with their values as they'd be written in a WITH
+	// clause.
+	Options []string
+	// Unlogged is set for tables created UNLOGGED, or altered to be, whose
+	// changes aren't written to the write-ahead log.
+	Unlogged bool
+	// Derived is set for tables created from a query, by CREATE TABLE AS
+	// or SELECT INTO.
+	Derived bool
+	// Extension is the extension the table belongs to, or empty if it
+	// doesn't belong to one.
+	Extension string
+	// PartitionKey is the PARTITION BY clause of a partitioned table, such
+	// as "RANGE (created_at)", or empty if the table isn't partitioned.
+	PartitionKey string
+	// PartitionOf is the table this is a partition of, holding the rows in
+	// PartitionBound, or nil if it isn't a partition.
+	PartitionOf    *Table
+	PartitionBound *PartitionBound
+	// Partitions are the partitions collapsed into the table, by qualified
+	// name, or nil if there are none. See Compiler.CollapsePartitions.
+	Partitions *collections.OrderedMap[string, *CollapsedPartition]
+	// lastAttnum is the Attnum of the last column added.
+	lastAttnum int
+	freezable
+}
+
+type ReplicaIdentity int
+
+const (
+	// ReplicaIdentityDefault uses the primary key, if there is one.
+	ReplicaIdentityDefault ReplicaIdentity = iota
+	ReplicaIdentityFull
+	ReplicaIdentityNothing
+	ReplicaIdentityIndex
+)
+
+func (r ReplicaIdentity) String() string {
+
+	switch r {
+	case ReplicaIdentityFull:
+		return "full"
+	case ReplicaIdentityNothing:
+		return "nothing"
+	case ReplicaIdentityIndex:
+		return "index"
+	default:
+		return "default"
+	}
 }
 
 func NewTable(name, schema string) *Table {
@@ -72,29 +528,109 @@ func NewTable(name, schema string) *Table {
 }
 
 func (t *Table) AddColumn(c *Column) error {
-	_, ok := t.Columns.Get(c.Name)
+
+	t.modify()
+	orig, ok := t.Columns.Get(c.Name)
 	if ok {
-		return fmt.Errorf("column already exists: %s", c.Name)
+		return fmt.Errorf("column already exists: %s%s", c.Name, duplicateLocations(orig.Defined, c.Defined))
 	}
+	t.lastAttnum++
+	c.Attnum = t.lastAttnum
 	t.Columns.Add(c.Name, c)
 	return nil
 }
 
 type Column struct {
+	OID   OID
 	Table *Table
 	Name  string
-	Type  *PostgresType
-	Attrs *ColumnAttributes
+	*ColumnType
+	// Attnum is the column's number in its table, as pg_attribute has it:
+	// columns are numbered from 1 in the order they're added, and the
+	// numbers of dropped columns aren't reused, so that they may have gaps.
+	Attnum      int
+	Attrs       *ColumnAttributes
+	Annotations Annotations
+	// Classification is how sensitive the column's data is, if it's been
+	// classified.
+	Classification Classification
+	// Defined is where the column was added.
+	Defined SourceLocation
+	// Sources are, for a table created by CREATE TABLE AS, the columns of
+	// tables the column's values were taken from.
+	Sources Columns
+}
+
+// SameType reports whether c and other have the same type, modifiers and
+// array dimensions, which they do exactly when they share a ColumnType.
+func (c *Column) SameType(other *Column) bool {
+
+	return c.ColumnType == other.ColumnType
+}
+
+// SetType changes c's type to typ with mods and dims.
+func (c *Column) SetType(typ *PostgresType, mods []int32, dims int) {
+
+	c.Table.modify()
+	c.ColumnType = InternColumnType(typ, mods, dims)
+}
+
+// FormatType renders the column's type including its modifiers and
+// array dimensions, e.g. "numeric(10,2)" or "text[]".
+func (c *Column) FormatType() string {
+
+	return c.Type.Format(c.TypeMods) + strings.Repeat("[]", c.ArrayDims)
 }
 
 type ColumnAttributes struct {
 	NotNull bool
 	Pkey    bool
-	//ColumnDefault *pg_query.Node // TODO: parse to native type
+	// PkeyPosition is the 1-based position of the column in its table's
+	// primary key, or 0 if it isn't part of it.
+	PkeyPosition int
+	// Default is the deparsed DEFAULT expression, or empty if there is none.
+	Default string
+	// Sequence is the schema qualified name of the sequence if Default is
+	// a call of nextval on it, or if the column is serial and has been
+	// renamed since its sequence was named. See Column.Sequence.
+	Sequence string
+	// Identity is set for identity columns, which are always not null.
+	Identity Identity
 	// Other values include: char max length for varchar,
 	// decimal and timezone precision, etc...
 }
 
+// Identity is when an identity column generates its values.
+type Identity int
+
+const (
+	IdentityNone Identity = iota
+	// IdentityAlways generates every value, rejecting those inserted unless
+	// the insert overrides it.
+	IdentityAlways
+	// IdentityByDefault generates the values which aren't inserted.
+	IdentityByDefault
+)
+
+func (i Identity) String() string {
+
+	switch i {
+	case IdentityAlways:
+		return "always"
+	case IdentityByDefault:
+		return "by default"
+	default:
+		return "none"
+	}
+}
+
+// generatesValues reports whether the column's values are generated when
+// an insert doesn't give them, by being serial or an identity column.
+func (c *Column) generatesValues() bool {
+
+	return isSerial(c.Type) || c.Attrs.Identity != IdentityNone
+}
+
 type Columns []*Column
 
 func (c Columns) Names() []string {
@@ -110,32 +646,60 @@ func (c Columns) JoinColumnNames(sep string) string {
 	return strings.Join(c.Names(), sep)
 }
 
-func (c Columns) SingleElementOrPanic() *Column {
+// SingleElement returns the only column of c, or an error if there isn't
+// exactly one.
+func (c Columns) SingleElement() (*Column, error) {
 
 	if len(c) != 1 {
-		panic(fmt.Errorf("wrong number of columns: expected 1, got %d", len(c)))
+		return nil, fmt.Errorf("wrong number of columns: expected 1, got %d", len(c))
 	}
-	return c[0]
+	return c[0], nil
 }
 
 type Constraint struct {
-	Table      *Table
-	Name       string
-	Type       ConstraintType // Primary, FK, etc
-	Refers     Columns
+	OID    OID
+	Table  *Table
+	Name   string
+	Type   ConstraintType // Primary, FK, etc
+	Refers Columns
+	// Constrains are the columns of a key, or the columns a check
+	// constraint's expression refers to.
 	Constrains Columns
 	// DropBehaviour explains how this constraint should behave
 	// when one of its dependencies is dropped.
 	DropBehaviour DropBehaviour
+	// NotValid is set for a foreign key or check constraint added with NOT
+	// VALID, whose existing rows haven't been checked, until it's
+	// validated.
+	NotValid bool
+	// Expr is the normalized expression of a check constraint, and
+	// NoInherit is set if it doesn't apply to the table's children.
+	Expr      string
+	expr      *pg_query.Node
+	NoInherit bool
+	// Match, OnUpdate and OnDelete are the options of a foreign key.
+	Match    ForeignKeyMatch
+	OnUpdate ForeignKeyAction
+	OnDelete ForeignKeyAction
+	// SetColumns are the columns an ON DELETE SET NULL or SET DEFAULT
+	// action is limited to, or empty if it sets all of the key's columns.
+	SetColumns Columns
+	// Index is the index a primary key or unique constraint was added
+	// USING, which now belongs to the constraint, or nil.
+	Index *Index
+	// Defined is where the constraint was added.
+	Defined SourceLocation
 }
 
 func (c *Constraint) OnCreate() {
 
+	c.Table.modify()
 	switch c.Type {
 	case ConstraintTypePrimary:
 		{
-			for _, col := range c.Constrains {
+			for i, col := range c.Constrains {
 				col.Attrs.Pkey = true
+				col.Attrs.PkeyPosition = i + 1
 			}
 		}
 	}
@@ -143,14 +707,37 @@ func (c *Constraint) OnCreate() {
 
 func (c *Constraint) OnRemove() {
 
+	c.Table.modify()
 	switch c.Type {
 	case ConstraintTypePrimary:
 		{
 			for _, col := range c.Constrains {
 				col.Attrs.Pkey = false
+				col.Attrs.PkeyPosition = 0
 			}
 		}
 	}
+	if c.Indexed() && c.Table.ClusterIndex == c.Name {
+		c.Table.ClusterIndex = ""
+	}
+}
+
+// Indexed reports whether the constraint is enforced by an index of its
+// own name, as primary keys and unique constraints are.
+func (c *Constraint) Indexed() bool {
+
+	return c.Type == ConstraintTypePrimary || c.Type == ConstraintTypeUnique
+}
+
+// ConstraintKey identifies a constraint in Depends.ConstraintsByName.
+type ConstraintKey struct {
+	Table *Table
+	Name  string
+}
+
+func (c *Constraint) Key() ConstraintKey {
+
+	return ConstraintKey{Table: c.Table, Name: c.Name}
 }
 
 func (c *Constraint) Depends() Columns {
@@ -160,6 +747,162 @@ func (c *Constraint) Depends() Columns {
 
 type Constraints []*Constraint
 
+// TableConstraints returns the constraints defined on t, ordered by name.
+func (d *Depends) TableConstraints(t *Table) Constraints {
+
+	ret := make(Constraints, 0)
+	for _, con := range d.ConstraintsByName {
+		if con.Table == t {
+			ret = append(ret, con)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *Constraint) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
+// Constraint returns the constraint of t with the name given.
+func (d *Depends) Constraint(t *Table, name string) (*Constraint, bool) {
+
+	con, ok := d.ConstraintsByName[ConstraintKey{Table: t, Name: name}]
+	return con, ok
+}
+
+// ConstraintsNamed returns the constraints of any table with the name
+// given, ordered by their tables' names.
+func (d *Depends) ConstraintsNamed(name string) Constraints {
+
+	ret := make(Constraints, 0)
+	for key, con := range d.ConstraintsByName {
+		if key.Name == name {
+			ret = append(ret, con)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *Constraint) int {
+		return strings.Compare(a.Table.Schema+"."+a.Table.Name, b.Table.Schema+"."+b.Table.Name)
+	})
+	return ret
+}
+
+// IndexedConstraint returns the primary key or unique constraint in schema
+// whose index has the name given. Like the names of other relations, these
+// are unique within their schema.
+func (d *Depends) IndexedConstraint(schema, name string) (*Constraint, bool) {
+
+	for key, con := range d.ConstraintsByName {
+		if key.Name == name && key.Table.Schema == schema && con.Indexed() {
+			return con, true
+		}
+	}
+	return nil, false
+}
+
+// PrimaryKey returns the primary key of t, or nil if it has none.
+func (d *Depends) PrimaryKey(t *Table) *Constraint {
+
+	for _, con := range d.TableConstraints(t) {
+		if con.Type == ConstraintTypePrimary {
+			return con
+		}
+	}
+	return nil
+}
+
+// ReferencingConstraints returns the foreign keys referring to t, ordered
+// by name.
+func (d *Depends) ReferencingConstraints(t *Table) Constraints {
+
+	ret := make(Constraints, 0)
+	for _, con := range d.ConstraintsByName {
+		if con.Type == ConstraintTypeForeignKey && con.Refers[0].Table == t {
+			ret = append(ret, con)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *Constraint) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ret
+}
+
+type Index struct {
+	OID    OID
+	Table  *Table
+	Name   string
+	Unique bool
+	// Method is the index's access method, such as "btree".
+	Method string
+	Elems  []*IndexElem
+	// Include are the non-key columns stored in a covering index.
+	Include Columns
+	// Predicate is the normalized WHERE clause of a partial index, or empty
+	// if the index isn't partial.
+	Predicate string
+	// Columns are the columns the index's keys and predicate reference.
+	Columns Columns
+	// Options are the index's storage parameters, like those of a Table.
+	Options []string
+	// where is the parsed predicate, kept so that it can be deparsed again
+	// when a column it references is renamed.
+	where *pg_query.Node
+}
+
+func (i *Index) QualifiedName() string {
+
+	return i.Table.Schema + "." + i.Name
+}
+
+// IndexElem is a key of an index, either a column or an expression.
+type IndexElem struct {
+	// Column is the indexed column, or nil if the key is an expression.
+	Column *Column
+	// Expr is the normalized expression indexed, or empty if the key is a
+	// column.
+	Expr string
+	expr *pg_query.Node
+	// Opclass is the operator class of the key, such as
+	// "varchar_pattern_ops", or empty for the type's default, and
+	// OpclassOptions are its parameters, such as "siglen=32".
+	Opclass        string
+	OpclassOptions []string
+	// Descending and NullsFirst are the key's sort order. NullsFirst
+	// defaults to the same as Descending.
+	Descending bool
+	NullsFirst bool
+}
+
+// Statistics is an extended statistics object, created by CREATE
+// STATISTICS.
+type Statistics struct {
+	OID OID
+	// Schema is the schema of the statistics object, which may not be the
+	// table's.
+	Schema string
+	Name   string
+	Table  *Table
+	// Kinds are the kinds of statistics collected, such as "ndistinct", or
+	// empty if every kind is.
+	Kinds []string
+	Elems []*StatisticsElem
+	// Columns are the columns the elements reference.
+	Columns Columns
+}
+
+func (s *Statistics) QualifiedName() string {
+
+	return s.Schema + "." + s.Name
+}
+
+// StatisticsElem is a column or expression statistics are collected on.
+type StatisticsElem struct {
+	// Column is the column, or nil if the element is an expression.
+	Column *Column
+	// Expr is the normalized expression, or empty if the element is a
+	// column.
+	Expr string
+	expr *pg_query.Node
+}
+
 type DropBehaviour int
 
 const (
@@ -180,4 +923,53 @@ const (
 	ConstraintTypePrimary ConstraintType = iota
 	ConstraintTypeUnique
 	ConstraintTypeForeignKey
+	ConstraintTypeCheck
+)
+
+// ForeignKeyMatch is how a foreign key treats a key which is partly null.
+type ForeignKeyMatch int
+
+const (
+	// ForeignKeyMatchSimple doesn't check a key if any of its columns is
+	// null.
+	ForeignKeyMatchSimple ForeignKeyMatch = iota
+	// ForeignKeyMatchFull requires all of a key's columns to be null if any
+	// is.
+	ForeignKeyMatchFull
+)
+
+// ForeignKeyAction is what a foreign key does to the rows referencing a row
+// which is updated or deleted.
+type ForeignKeyAction int
+
+const (
+	ForeignKeyActionNoAction ForeignKeyAction = iota
+	ForeignKeyActionRestrict
+	ForeignKeyActionCascade
+	ForeignKeyActionSetNull
+	ForeignKeyActionSetDefault
 )
+
+var foreignKeyActions = map[string]ForeignKeyAction{
+	"a": ForeignKeyActionNoAction,
+	"r": ForeignKeyActionRestrict,
+	"c": ForeignKeyActionCascade,
+	"n": ForeignKeyActionSetNull,
+	"d": ForeignKeyActionSetDefault,
+}
+
+func (a ForeignKeyAction) String() string {
+
+	switch a {
+	case ForeignKeyActionRestrict:
+		return "RESTRICT"
+	case ForeignKeyActionCascade:
+		return "CASCADE"
+	case ForeignKeyActionSetNull:
+		return "SET NULL"
+	case ForeignKeyActionSetDefault:
+		return "SET DEFAULT"
+	default:
+		return "NO ACTION"
+	}
+}
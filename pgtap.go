@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// PgTAPGenerator writes a pgTAP test script asserting that a database has
+// the structure described by the catalog.
+type PgTAPGenerator struct{}
+
+func NewPgTAPGenerator(_ *flag.FlagSet) Generator {
+
+	return &PgTAPGenerator{}
+}
+
+func (g *PgTAPGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	var tests []string
+	add := func(fn string, args ...string) {
+		tests = append(tests, fmt.Sprintf("SELECT %s(%s);", fn, strings.Join(args, ", ")))
+	}
+	for _, sch := range cat.Schemas.List() {
+		s := pgTAPName(sch.Name)
+		if sch.Name != "public" {
+			add("has_schema", s)
+		}
+		for _, tab := range sch.Tables.List() {
+			t := pgTAPName(tab.Name)
+			add("has_table", s, t)
+			for _, col := range tab.Columns.List() {
+				c := pgTAPName(col.Name)
+				add("has_column", s, t, c)
+				typ := col.Type
+				if actual, ok := serialTypes[typ]; ok {
+					typ = actual
+				}
+				add("col_type_is", s, t, c, QuoteLiteral(typ.Format(col.TypeMods)+strings.Repeat("[]", col.ArrayDims)))
+				if col.Attrs.NotNull || col.Attrs.Pkey {
+					add("col_not_null", s, t, c)
+				} else {
+					add("col_is_null", s, t, c)
+				}
+				if col.Attrs.Default != "" || isSerial(col.Type) {
+					add("col_has_default", s, t, c)
+				} else {
+					add("col_hasnt_default", s, t, c)
+				}
+			}
+
+			hasPk := false
+			for _, con := range cat.Depends.TableConstraints(tab) {
+				cols := pgTAPNameArray(con.Constrains.Names())
+				switch con.Type {
+				case ConstraintTypePrimary:
+					hasPk = true
+					add("col_is_pk", s, t, cols)
+				case ConstraintTypeUnique:
+					add("col_is_unique", s, t, cols)
+				case ConstraintTypeForeignKey:
+					ref := con.Refers[0].Table
+					add("fk_ok", s, t, cols, pgTAPName(ref.Schema), pgTAPName(ref.Name), pgTAPNameArray(con.Refers.Names()))
+				case ConstraintTypeCheck:
+					// col_has_check can't test a check of no columns, such
+					// as CHECK (now() > '2020-01-01')
+					if len(con.Constrains) > 0 {
+						add("col_has_check", s, t, cols)
+					}
+				}
+			}
+			if hasPk {
+				add("has_pk", s, t)
+			} else {
+				add("hasnt_pk", s, t)
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "BEGIN;")
+	fmt.Fprintf(bw, "SELECT plan(%d);\n\n", len(tests))
+	for _, test := range tests {
+		fmt.Fprintln(bw, test)
+	}
+	fmt.Fprintln(bw)
+	fmt.Fprintln(bw, "SELECT * FROM finish();")
+	fmt.Fprintln(bw, "ROLLBACK;")
+	return bw.Flush()
+}
+
+// pgTAPName casts a name literal explicitly, as otherwise Postgres prefers
+// pgTAP's overloads which take a description instead of a schema.
+func pgTAPName(name string) string {
+
+	return QuoteLiteral(name) + "::name"
+}
+
+func pgTAPNameArray(names []string) string {
+
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, QuoteLiteral(name))
+	}
+	return "ARRAY[" + strings.Join(quoted, ", ") + "]::name[]"
+}
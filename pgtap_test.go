@@ -0,0 +1,70 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestPgTAPGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (
+		id serial primary key,
+		email varchar(100) not null unique
+	);
+
+	CREATE TABLE orders (
+		user_id int references users (id),
+		placed_at timestamptz default now()
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&PgTAPGenerator{}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `BEGIN;
+SELECT plan(23);
+
+SELECT has_table('public'::name, 'users'::name);
+SELECT has_column('public'::name, 'users'::name, 'id'::name);
+SELECT col_type_is('public'::name, 'users'::name, 'id'::name, 'integer');
+SELECT col_not_null('public'::name, 'users'::name, 'id'::name);
+SELECT col_has_default('public'::name, 'users'::name, 'id'::name);
+SELECT has_column('public'::name, 'users'::name, 'email'::name);
+SELECT col_type_is('public'::name, 'users'::name, 'email'::name, 'character varying(100)');
+SELECT col_not_null('public'::name, 'users'::name, 'email'::name);
+SELECT col_hasnt_default('public'::name, 'users'::name, 'email'::name);
+SELECT col_is_unique('public'::name, 'users'::name, ARRAY['email']::name[]);
+SELECT col_is_pk('public'::name, 'users'::name, ARRAY['id']::name[]);
+SELECT has_pk('public'::name, 'users'::name);
+SELECT has_table('public'::name, 'orders'::name);
+SELECT has_column('public'::name, 'orders'::name, 'user_id'::name);
+SELECT col_type_is('public'::name, 'orders'::name, 'user_id'::name, 'integer');
+SELECT col_is_null('public'::name, 'orders'::name, 'user_id'::name);
+SELECT col_hasnt_default('public'::name, 'orders'::name, 'user_id'::name);
+SELECT has_column('public'::name, 'orders'::name, 'placed_at'::name);
+SELECT col_type_is('public'::name, 'orders'::name, 'placed_at'::name, 'timestamp with time zone');
+SELECT col_is_null('public'::name, 'orders'::name, 'placed_at'::name);
+SELECT col_has_default('public'::name, 'orders'::name, 'placed_at'::name);
+SELECT fk_ok('public'::name, 'orders'::name, ARRAY['user_id']::name[], 'public'::name, 'users'::name, ARRAY['id']::name[]);
+SELECT hasnt_pk('public'::name, 'orders'::name);
+
+SELECT * FROM finish();
+ROLLBACK;
+`, sb.String())
+}
+
+func TestPgTAPGenerator_Checks(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE prices (
+		amount int CHECK (amount > 0),
+		CHECK (now() > '2020-01-01')
+	);
+	`)
+	var sb strings.Builder
+	require.Nil(t, (&PgTAPGenerator{}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), "SELECT col_has_check('public'::name, 'prices'::name, ARRAY['amount']::name[]);\n")
+	assert.Equal(t, 1, strings.Count(sb.String(), "col_has_check"))
+	assert.Contains(t, sb.String(), "SELECT plan(7);\n")
+}
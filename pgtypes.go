@@ -1,9 +1,15 @@
 package main
 
 import (
+	"fmt"
 	"github.com/samber/lo"
 	"regexp"
+	"runtime"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
+	"weak"
 )
 
 type PostgresType struct {
@@ -12,6 +18,82 @@ type PostgresType struct {
 	Description    string
 	SimpleMatches  []string
 	PatternMatches []*regexp.Regexp
+	// Length is the size of the type's values in bytes, or 0 if they're of
+	// variable length, and Align is the alignment Postgres stores them
+	// with, as pg_type's typlen and typalign.
+	Length int
+	Align  int
+	// Opaque is set for types which aren't known, such as enums, domains
+	// and the types of extensions.
+	Opaque bool
+}
+
+var opaqueTypes sync.Map
+
+// OpaqueType returns the type named name, which isn't a known type. Every
+// call with the same name returns the same type.
+func OpaqueType(name string) *PostgresType {
+
+	t, _ := opaqueTypes.LoadOrStore(name, &PostgresType{Name: name, Opaque: true})
+	return t.(*PostgresType)
+}
+
+// ColumnType is a column's type together with its modifiers and array
+// dimensions. There's one ColumnType for each distinct column type, shared
+// by every column of that type, so that the many columns of a large schema
+// with the same type, such as varchar(255), don't each hold a copy and two
+// columns have the same type exactly when they share one. It mustn't be
+// modified: use Column.SetType to change a column's type.
+type ColumnType struct {
+	Type      *PostgresType
+	TypeMods  []int32 // e.g. the length of varchar(n)
+	ArrayDims int
+}
+
+type columnTypeKey struct {
+	typ  *PostgresType
+	mods string
+	dims int
+}
+
+var (
+	columnTypesMu sync.Mutex
+	columnTypes   = make(map[columnTypeKey]weak.Pointer[ColumnType])
+)
+
+// InternColumnType returns the ColumnType of typ with mods and dims. It
+// holds the column types it returns weakly and forgets each once no column
+// uses it, so that compiling many schemas in one process doesn't keep
+// every type any of them used.
+func InternColumnType(typ *PostgresType, mods []int32, dims int) *ColumnType {
+
+	key := columnTypeKey{typ: typ, dims: dims}
+	if len(mods) > 0 {
+		parts := make([]string, len(mods))
+		for i, mod := range mods {
+			parts[i] = strconv.Itoa(int(mod))
+		}
+		key.mods = strings.Join(parts, ",")
+	}
+	columnTypesMu.Lock()
+	defer columnTypesMu.Unlock()
+	if ct := columnTypes[key].Value(); ct != nil {
+		return ct
+	}
+	ct := &ColumnType{Type: typ, ArrayDims: dims}
+	if len(mods) > 0 {
+		ct.TypeMods = slices.Clip(slices.Clone(mods))
+	}
+	ptr := weak.Make(ct)
+	columnTypes[key] = ptr
+	runtime.AddCleanup(ct, func(key columnTypeKey) {
+		columnTypesMu.Lock()
+		defer columnTypesMu.Unlock()
+		if columnTypes[key] == ptr {
+			delete(columnTypes, key)
+		}
+	}, key)
+	return ct
 }
 
 func optionally(re string) string {
@@ -28,73 +110,77 @@ const withTimeZone = "\\s*with\\s*time\\s*zone\\s*"
 var interval = "\\s*\\s*(?:" + intervalsRe + ")\\s*"
 
 var (
-	Bigint       = &PostgresType{Name: "bigint", Aliases: "int8", SimpleMatches: []string{"bigint", "int8"}, Description: "signed eight-byte integer"}
-	Bigserial    = &PostgresType{Name: "bigserial", Aliases: "serial8", SimpleMatches: []string{"bigserial", "serial8"}, Description: "autoincrementing eight-byte integer"}
-	Boolean      = &PostgresType{Name: "boolean", Aliases: "bool", SimpleMatches: []string{"boolean", "bool"}, Description: "logical Boolean (true/false)"}
-	Box          = &PostgresType{Name: "box", SimpleMatches: []string{"box"}, Description: "rectangular box on a plane"}
-	Bytea        = &PostgresType{Name: "bytea", SimpleMatches: []string{"bytea"}, Description: "binary data (“byte array”)"}
-	CIDR         = &PostgresType{Name: "cidr", SimpleMatches: []string{"cidr"}, Description: "IPv4 or IPv6 network address"}
-	Circle       = &PostgresType{Name: "circle", SimpleMatches: []string{"circle"}, Description: "circle on a plane"}
-	Date         = &PostgresType{Name: "date", SimpleMatches: []string{"date"}, Description: "calendar date (year, month, day)"}
-	Double       = &PostgresType{Name: "double precision", Aliases: "float8", SimpleMatches: []string{"double precision", "float8"}, Description: "double precision floating-point number (8 bytes)"}
-	Inet         = &PostgresType{Name: "inet", SimpleMatches: []string{"inet"}, Description: "IPv4 or IPv6 host address"}
-	Integer      = &PostgresType{Name: "integer", Aliases: "int, int4", SimpleMatches: []string{"integer", "int", "int4"}, Description: "signed four-byte integer"}
-	JSON         = &PostgresType{Name: "json", SimpleMatches: []string{"json"}, Description: "textual JSON data"}
-	JSONB        = &PostgresType{Name: "jsonb", SimpleMatches: []string{"jsonb"}, Description: "binary JSON data, decomposed"}
-	Line         = &PostgresType{Name: "line", SimpleMatches: []string{"line"}, Description: "infinite line on a plane"}
-	Lseg         = &PostgresType{Name: "lseg", SimpleMatches: []string{"lseg"}, Description: "line segment on a plane"}
-	Macaddr      = &PostgresType{Name: "macaddr", SimpleMatches: []string{"macaddr"}, Description: "MAC (Media Access Control) address"}
-	Macaddr8     = &PostgresType{Name: "macaddr8", SimpleMatches: []string{"macaddr8"}, Description: "MAC (Media Access Control) address (EUI-64 format)"}
-	Money        = &PostgresType{Name: "money", SimpleMatches: []string{"money"}, Description: "currency amount"}
-	Path         = &PostgresType{Name: "path", SimpleMatches: []string{"path"}, Description: "geometric path on a plane"}
-	PGLsn        = &PostgresType{Name: "pg_lsn", SimpleMatches: []string{"pg_lsn"}, Description: "PostgreSQL Log Sequence Number"}
-	PGSnapshot   = &PostgresType{Name: "pg_snapshot", SimpleMatches: []string{"pg_snapshot"}, Description: "user-level transaction ID snapshot"}
-	Point        = &PostgresType{Name: "point", SimpleMatches: []string{"point"}, Description: "geometric point on a plane"}
-	Polygon      = &PostgresType{Name: "polygon", SimpleMatches: []string{"polygon"}, Description: "closed geometric path on a plane"}
-	Real         = &PostgresType{Name: "real", Aliases: "float4", SimpleMatches: []string{"real", "float4"}, Description: "single precision floating-point number (4 bytes)"}
-	Smallint     = &PostgresType{Name: "smallint", Aliases: "int2", SimpleMatches: []string{"smallint", "int2"}, Description: "signed two-byte integer"}
-	Smallserial  = &PostgresType{Name: "smallserial", Aliases: "serial2", SimpleMatches: []string{"smallserial", "serial2"}, Description: "autoincrementing two-byte integer"}
-	Serial       = &PostgresType{Name: "serial", Aliases: "serial4", SimpleMatches: []string{"serial", "serial4"}, Description: "autoincrementing four-byte integer"}
-	Text         = &PostgresType{Name: "text", SimpleMatches: []string{"text"}, Description: "variable-length character string"}
-	TSQuery      = &PostgresType{Name: "tsquery", SimpleMatches: []string{"tsquery"}, Description: "text search query"}
-	TSVector     = &PostgresType{Name: "tsvector", SimpleMatches: []string{"tsvector"}, Description: "text search document"}
-	TXIDSnapshot = &PostgresType{Name: "txid_snapshot", SimpleMatches: []string{"txid_snapshot"}, Description: "user-level transaction ID snapshot (deprecated, see pg_snapshot)"}
-	UUID         = &PostgresType{Name: "uuid", SimpleMatches: []string{"uuid"}, Description: "universally unique identifier"}
-	XML          = &PostgresType{Name: "xml", SimpleMatches: []string{"xml"}, Description: "XML data"}
-
-	Bit        = &PostgresType{Name: "bit [ (n) ]", PatternMatches: []*regexp.Regexp{regexp.MustCompile("^bit" + optionally(numInBrackets) + "$")}, Description: "fixed-length bit string"}
-	BitVarying = &PostgresType{Name: "bit varying [ (n) ]", Aliases: "varbit [ (n) ]", PatternMatches: []*regexp.Regexp{
+	Bigint       = &PostgresType{Name: "bigint", Length: 8, Align: 8, Aliases: "int8", SimpleMatches: []string{"bigint", "int8"}, Description: "signed eight-byte integer"}
+	Bigserial    = &PostgresType{Name: "bigserial", Length: 8, Align: 8, Aliases: "serial8", SimpleMatches: []string{"bigserial", "serial8"}, Description: "autoincrementing eight-byte integer"}
+	Boolean      = &PostgresType{Name: "boolean", Length: 1, Align: 1, Aliases: "bool", SimpleMatches: []string{"boolean", "bool"}, Description: "logical Boolean (true/false)"}
+	Box          = &PostgresType{Name: "box", Length: 32, Align: 8, SimpleMatches: []string{"box"}, Description: "rectangular box on a plane"}
+	Bytea        = &PostgresType{Name: "bytea", Align: 4, SimpleMatches: []string{"bytea"}, Description: "binary data (“byte array”)"}
+	CIDR         = &PostgresType{Name: "cidr", Align: 4, SimpleMatches: []string{"cidr"}, Description: "IPv4 or IPv6 network address"}
+	Circle       = &PostgresType{Name: "circle", Length: 24, Align: 8, SimpleMatches: []string{"circle"}, Description: "circle on a plane"}
+	Date         = &PostgresType{Name: "date", Length: 4, Align: 4, SimpleMatches: []string{"date"}, Description: "calendar date (year, month, day)"}
+	Double       = &PostgresType{Name: "double precision", Length: 8, Align: 8, Aliases: "float8", SimpleMatches: []string{"double precision", "float8"}, Description: "double precision floating-point number (8 bytes)"}
+	Inet         = &PostgresType{Name: "inet", Align: 4, SimpleMatches: []string{"inet"}, Description: "IPv4 or IPv6 host address"}
+	Integer      = &PostgresType{Name: "integer", Length: 4, Align: 4, Aliases: "int, int4", SimpleMatches: []string{"integer", "int", "int4"}, Description: "signed four-byte integer"}
+	JSON         = &PostgresType{Name: "json", Align: 4, SimpleMatches: []string{"json"}, Description: "textual JSON data"}
+	JSONB        = &PostgresType{Name: "jsonb", Align: 4, SimpleMatches: []string{"jsonb"}, Description: "binary JSON data, decomposed"}
+	Line         = &PostgresType{Name: "line", Length: 24, Align: 8, SimpleMatches: []string{"line"}, Description: "infinite line on a plane"}
+	Lseg         = &PostgresType{Name: "lseg", Length: 32, Align: 8, SimpleMatches: []string{"lseg"}, Description: "line segment on a plane"}
+	Macaddr      = &PostgresType{Name: "macaddr", Length: 6, Align: 4, SimpleMatches: []string{"macaddr"}, Description: "MAC (Media Access Control) address"}
+	Macaddr8     = &PostgresType{Name: "macaddr8", Length: 8, Align: 4, SimpleMatches: []string{"macaddr8"}, Description: "MAC (Media Access Control) address (EUI-64 format)"}
+	Money        = &PostgresType{Name: "money", Length: 8, Align: 8, SimpleMatches: []string{"money"}, Description: "currency amount"}
+	Path         = &PostgresType{Name: "path", Align: 8, SimpleMatches: []string{"path"}, Description: "geometric path on a plane"}
+	PGLsn        = &PostgresType{Name: "pg_lsn", Length: 8, Align: 8, SimpleMatches: []string{"pg_lsn"}, Description: "PostgreSQL Log Sequence Number"}
+	PGSnapshot   = &PostgresType{Name: "pg_snapshot", Align: 8, SimpleMatches: []string{"pg_snapshot"}, Description: "user-level transaction ID snapshot"}
+	Point        = &PostgresType{Name: "point", Length: 16, Align: 8, SimpleMatches: []string{"point"}, Description: "geometric point on a plane"}
+	Polygon      = &PostgresType{Name: "polygon", Align: 8, SimpleMatches: []string{"polygon"}, Description: "closed geometric path on a plane"}
+	Real         = &PostgresType{Name: "real", Length: 4, Align: 4, Aliases: "float4", SimpleMatches: []string{"real", "float4"}, Description: "single precision floating-point number (4 bytes)"}
+	Smallint     = &PostgresType{Name: "smallint", Length: 2, Align: 2, Aliases: "int2", SimpleMatches: []string{"smallint", "int2"}, Description: "signed two-byte integer"}
+	Smallserial  = &PostgresType{Name: "smallserial", Length: 2, Align: 2, Aliases: "serial2", SimpleMatches: []string{"smallserial", "serial2"}, Description: "autoincrementing two-byte integer"}
+	Serial       = &PostgresType{Name: "serial", Length: 4, Align: 4, Aliases: "serial4", SimpleMatches: []string{"serial", "serial4"}, Description: "autoincrementing four-byte integer"}
+	Text         = &PostgresType{Name: "text", Align: 4, SimpleMatches: []string{"text"}, Description: "variable-length character string"}
+	TSQuery      = &PostgresType{Name: "tsquery", Align: 4, SimpleMatches: []string{"tsquery"}, Description: "text search query"}
+	TSVector     = &PostgresType{Name: "tsvector", Align: 4, SimpleMatches: []string{"tsvector"}, Description: "text search document"}
+	TXIDSnapshot = &PostgresType{Name: "txid_snapshot", Align: 8, SimpleMatches: []string{"txid_snapshot"}, Description: "user-level transaction ID snapshot (deprecated, see pg_snapshot)"}
+	UUID         = &PostgresType{Name: "uuid", Length: 16, Align: 1, SimpleMatches: []string{"uuid"}, Description: "universally unique identifier"}
+	XML          = &PostgresType{Name: "xml", Align: 4, SimpleMatches: []string{"xml"}, Description: "XML data"}
+
+	Bit        = &PostgresType{Name: "bit [ (n) ]", Align: 4, PatternMatches: []*regexp.Regexp{regexp.MustCompile("^bit" + optionally(numInBrackets) + "$")}, Description: "fixed-length bit string"}
+	BitVarying = &PostgresType{Name: "bit varying [ (n) ]", Align: 4, Aliases: "varbit [ (n) ]", PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^bit varying" + optionally(numInBrackets) + "$"),
 		regexp.MustCompile("^varbit" + optionally(numInBrackets) + "$"),
 	}, Description: "variable-length bit string"}
-	Character = &PostgresType{Name: "character [ (n) ]", Aliases: "char [ (n) ]", PatternMatches: []*regexp.Regexp{
+	Character = &PostgresType{Name: "character [ (n) ]", Align: 4, Aliases: "char [ (n) ]", PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^character" + optionally(numInBrackets) + "$"),
 		regexp.MustCompile("^char" + optionally(numInBrackets) + "$"),
-	}, Description: "fixed-length character string"}
-	CharacterVarying = &PostgresType{Name: "character varying [ (n) ]", Aliases: "varchar [ (n) ]", PatternMatches: []*regexp.Regexp{
+	},
+		SimpleMatches: []string{"bpchar"},
+		Description:   "fixed-length character string"}
+	CharacterVarying = &PostgresType{Name: "character varying [ (n) ]", Align: 4, Aliases: "varchar [ (n) ]", PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^character varying" + optionally(numInBrackets) + "$"),
 		regexp.MustCompile("^varchar" + optionally(numInBrackets) + "$"),
 	}, Description: "variable-length character string"}
-	Interval = &PostgresType{Name: "interval [ fields ] [ (p) ]", PatternMatches: []*regexp.Regexp{
+	Interval = &PostgresType{Name: "interval [ fields ] [ (p) ]", Length: 16, Align: 8, PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^interval" + interval + optionally(numInBrackets) + "$"),
-	}, Description: "time span"}
-	Numeric = &PostgresType{Name: "numeric [ (p, s) ]", Aliases: "decimal [ (p, s) ]", PatternMatches: []*regexp.Regexp{
+	},
+		SimpleMatches: []string{"interval"},
+		Description:   "time span"}
+	Numeric = &PostgresType{Name: "numeric [ (p, s) ]", Align: 4, Aliases: "decimal [ (p, s) ]", PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^numeric" + optionally(twoNumsInBrackets) + "$"),
 		regexp.MustCompile("^decimal" + optionally(numInBrackets) + "$"),
 	}, Description: "exact numeric of selectable precision"}
-	Time = &PostgresType{Name: "time [ (p) ] [ without time zone ]", PatternMatches: []*regexp.Regexp{
+	Time = &PostgresType{Name: "time [ (p) ] [ without time zone ]", Length: 8, Align: 8, PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^time" + optionally(numInBrackets) + optionally(withoutTimeZone) + "$"),
 	}, Description: "time of day (no time zone)"}
-	Timetz = &PostgresType{Name: "time [ (p) ] with time zone", Aliases: "timetz", PatternMatches: []*regexp.Regexp{
+	Timetz = &PostgresType{Name: "time [ (p) ] with time zone", Length: 12, Align: 8, Aliases: "timetz", PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^time" + optionally(numInBrackets) + withTimeZone + "$"),
 		regexp.MustCompile("^timetz" + optionally(numInBrackets) + "$"),
 	}, Description: "time of day, including time zone"}
-	Timestamp = &PostgresType{Name: "timestamp [ (p) ] [ without time zone ]", PatternMatches: []*regexp.Regexp{
+	Timestamp = &PostgresType{Name: "timestamp [ (p) ] [ without time zone ]", Length: 8, Align: 8, PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^timestamp" + optionally(numInBrackets) + optionally(withoutTimeZone) + "$"),
 	},
 		SimpleMatches: []string{"timestamp"},
 		Description:   "date and time (no time zone)"}
-	Timestamptz = &PostgresType{Name: "timestamp [ (p) ] with time zone", Aliases: "timestamptz", PatternMatches: []*regexp.Regexp{
+	Timestamptz = &PostgresType{Name: "timestamp [ (p) ] with time zone", Length: 8, Align: 8, Aliases: "timestamptz", PatternMatches: []*regexp.Regexp{
 		regexp.MustCompile("^timestamp" + optionally(numInBrackets) + withTimeZone + "$"),
 		regexp.MustCompile("^timestamptz" + optionally(numInBrackets) + "$"),
 	},
@@ -148,6 +234,14 @@ var pgTypes = []*PostgresType{
 	Timestamptz,
 }
 
+// serialTypes maps the serial pseudo-types to the integer type of the
+// columns they create.
+var serialTypes = map[*PostgresType]*PostgresType{
+	Serial:      Integer,
+	Bigserial:   Bigint,
+	Smallserial: Smallint,
+}
+
 var simpleMatches = lo.Associate(lo.FlatMap(pgTypes, func(item *PostgresType, index int) []lo.Entry[string, *PostgresType] {
 	return lo.Map(item.SimpleMatches, func(m string, index int) lo.Entry[string, *PostgresType] {
 		return lo.Entry[string, *PostgresType]{Key: m, Value: item}
@@ -161,7 +255,20 @@ var patternMatches = lo.FlatMap(pgTypes, func(item *PostgresType, index int) []l
 	})
 })
 
-func MatchType(s string) *PostgresType {
+// MatchType returns the built in type named s, or an error if there isn't
+// one. LookupType is the same, but returns nil instead.
+func MatchType(s string) (*PostgresType, error) {
+
+	t := LookupType(s)
+	if t == nil {
+		return nil, fmt.Errorf("unknown type %s", s)
+	}
+	return t, nil
+}
+
+// LookupType is like MatchType, but returns nil if s isn't a known type.
+func LookupType(s string) *PostgresType {
+
 	s = strings.ToLower(s)
 	if t, ok := simpleMatches[s]; ok {
 		return t
@@ -171,7 +278,66 @@ func MatchType(s string) *PostgresType {
 			return p.Value
 		}
 	}
-	panic("didn't match")
+	return nil
+}
+
+// Format renders the type with the type modifiers mods applied, in the
+// same form as Postgres' format_type, e.g. "character varying(50)".
+func (p *PostgresType) Format(mods []int32) string {
+
+	switch p {
+	case Bit, BitVarying, Character, CharacterVarying:
+		{
+			base := strings.SplitN(p.Name, " [", 2)[0]
+			if len(mods) > 0 {
+				return fmt.Sprintf("%s(%d)", base, mods[0])
+			}
+			return base
+		}
+	case Numeric:
+		{
+			switch len(mods) {
+			case 0:
+				return "numeric"
+			case 1:
+				return fmt.Sprintf("numeric(%d)", mods[0])
+			default:
+				return fmt.Sprintf("numeric(%d,%d)", mods[0], mods[1])
+			}
+		}
+	case Time, Timestamp, Timetz, Timestamptz:
+		{
+			base := strings.SplitN(p.Name, " ", 2)[0]
+			if len(mods) > 0 {
+				base = fmt.Sprintf("%s(%d)", base, mods[0])
+			}
+			if p == Timetz || p == Timestamptz {
+				return base + " with time zone"
+			}
+			return base + " without time zone"
+		}
+	case Interval:
+		{
+			base := "interval"
+			if len(mods) > 0 {
+				if fields, ok := intervalFieldMasks[mods[0]]; ok {
+					base += " " + strings.ToLower(string(fields))
+				}
+			}
+			if len(mods) > 1 {
+				base = fmt.Sprintf("%s(%d)", base, mods[1])
+			}
+			return base
+		}
+	}
+	if p.Opaque && len(mods) > 0 {
+		strs := make([]string, 0, len(mods))
+		for _, mod := range mods {
+			strs = append(strs, strconv.Itoa(int(mod)))
+		}
+		return p.Name + "(" + strings.Join(strs, ",") + ")"
+	}
+	return p.Name
 }
 
 type PostgresInterval string
@@ -208,6 +374,33 @@ var intervals = map[PostgresInterval]struct{}{
 	PostgresIntervalMinuteToSecond: {},
 }
 
+const (
+	intervalMonth  int32 = 1 << 1
+	intervalYear   int32 = 1 << 2
+	intervalDay    int32 = 1 << 3
+	intervalHour   int32 = 1 << 10
+	intervalMinute int32 = 1 << 11
+	intervalSecond int32 = 1 << 12
+)
+
+// intervalFieldMasks maps the field bitmask stored in the first type
+// modifier of an interval to the fields it was declared with.
+var intervalFieldMasks = map[int32]PostgresInterval{
+	intervalYear:                 PostgresIntervalYear,
+	intervalMonth:                PostgresIntervalMonth,
+	intervalDay:                  PostgresIntervalDay,
+	intervalHour:                 PostgresIntervalHour,
+	intervalMinute:               PostgresIntervalMinute,
+	intervalSecond:               PostgresIntervalSecond,
+	intervalYear | intervalMonth: PostgresIntervalYearToMonth,
+	intervalDay | intervalHour:   PostgresIntervalDayToHour,
+	intervalDay | intervalHour | intervalMinute:                  PostgresIntervalDayToMinute,
+	intervalDay | intervalHour | intervalMinute | intervalSecond: PostgresIntervalDayToSecond,
+	intervalHour | intervalMinute:                                PostgresIntervalHourToMinute,
+	intervalHour | intervalMinute | intervalSecond:               PostgresIntervalHourToSecond,
+	intervalMinute | intervalSecond:                              PostgresIntervalMinuteToSecond,
+}
+
 var intervalsRe = strings.Join(lo.Map(lo.Keys(intervals), func(item PostgresInterval, index int) string {
 	return strings.ToLower(string(item))
 }), "|")
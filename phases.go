@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// Phase is when a statement of a zero downtime migration is applied,
+// relative to deploying the application written against the new schema.
+type Phase int
+
+const (
+	// PhaseExpand statements add to the schema without breaking the old
+	// application, so they're applied before the new one is deployed.
+	PhaseExpand Phase = iota
+	// PhaseBackfill is where existing rows are brought up to date, once
+	// the new application is writing them. The data isn't known, so it's
+	// mostly placeholders, followed by validating what was added in the
+	// expand phase.
+	PhaseBackfill
+	// PhaseContract statements break the old application, so they're
+	// applied once it's gone.
+	PhaseContract
+)
+
+var phaseNames = []string{"expand", "backfill", "contract"}
+
+func (p Phase) String() string {
+
+	return phaseNames[p]
+}
+
+func ParsePhase(s string) (Phase, error) {
+
+	idx := slices.Index(phaseNames, s)
+	if idx < 0 {
+		return PhaseExpand, fmt.Errorf("unknown phase %q, expected one of: %s", s, strings.Join(phaseNames, ", "))
+	}
+	return Phase(idx), nil
+}
+
+// PhasedStatement is a statement of a migration split into phases.
+type PhasedStatement struct {
+	Phase Phase
+	// SQL is the statement, or a comment for a placeholder.
+	SQL string
+	// Lock describes the lock the statement holds while it rewrites or
+	// scans a table, blocking the application, or is empty if it only
+	// holds a lock briefly.
+	Lock string
+	// NoTransaction is set for statements which can't run in a
+	// transaction.
+	NoTransaction bool
+}
+
+const (
+	lockRewrite = "ACCESS EXCLUSIVE while the table is rewritten"
+	lockScan    = "ACCESS EXCLUSIVE while every row is checked"
+	lockBuild   = "ACCESS EXCLUSIVE while the index is built"
+)
+
+// Phased splits the statements applying the changes into phases, so that
+// they can be applied without downtime: added not null columns are added
+// as nullable and made not null once they're backfilled, foreign keys and
+// check constraints are added NOT VALID and validated later, and indexes
+// are built concurrently.
+// Changes which break the old application, as CheckCompatibility reports
+// them, are left to the contract phase.
+func (cs Changes) Phased() []*PhasedStatement {
+
+	var ret []*PhasedStatement
+	add := func(phase Phase, lock, sql string) {
+		ret = append(ret, &PhasedStatement{Phase: phase, SQL: sql, Lock: lock})
+	}
+	for _, c := range cs {
+		breaks := c.breaksOldApplications() != ""
+		switch {
+		case c.Object == ObjectKindColumn && c.Kind == ChangeKindAdd && breaks:
+			{
+				col := c.To.(*Column)
+				nullable := *col
+				nullable.Attrs.NotNull = false
+				add(PhaseExpand, "", fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", TableIdent(col.Table), ColumnDefinition(&nullable)))
+				add(PhaseBackfill, "", fmt.Sprintf("-- TODO: backfill %s.%s", TableIdent(col.Table), QuoteIdent(col.Name)))
+				add(PhaseContract, lockScan, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET NOT NULL;", TableIdent(col.Table), QuoteIdent(col.Name)))
+			}
+		case c.Object == ObjectKindColumn && c.Kind == ChangeKindAlter:
+			{
+				from, to := c.From.(*Column), c.To.(*Column)
+				fromDef, toDef := definitionsOf(from, to)
+				lock := ""
+				if toDef.NotNull && !fromDef.NotNull {
+					add(PhaseBackfill, "", fmt.Sprintf("-- TODO: backfill the nulls of %s.%s", TableIdent(to.Table), QuoteIdent(to.Name)))
+					lock = lockScan
+				}
+				if fromDef.Type != toDef.Type && rewritesTable(from, to) {
+					lock = lockRewrite
+				}
+				phase := PhaseExpand
+				if breaks {
+					phase = PhaseContract
+				}
+				for _, stmt := range c.SQL() {
+					add(phase, lock, stmt)
+				}
+			}
+		case c.Object == ObjectKindConstraint && c.Kind == ChangeKindAdd:
+			{
+				con := c.To.(*Constraint)
+				if (con.Type == ConstraintTypeForeignKey || con.Type == ConstraintTypeCheck) && !con.NotValid {
+					notValid := *con
+					notValid.NotValid = true
+					add(PhaseExpand, "", fmt.Sprintf("ALTER TABLE %s ADD %s;", TableIdent(con.Table), ConstraintDefinition(&notValid)))
+					add(PhaseBackfill, "", fmt.Sprintf("ALTER TABLE %s VALIDATE CONSTRAINT %s;", TableIdent(con.Table), QuoteIdent(con.Name)))
+					break
+				}
+				lock := ""
+				if con.Index == nil && (con.Type == ConstraintTypePrimary || con.Type == ConstraintTypeUnique) {
+					lock = lockBuild
+				}
+				for _, stmt := range c.SQL() {
+					add(PhaseExpand, lock, stmt)
+				}
+			}
+		case c.Object == ObjectKindIndex && c.Kind == ChangeKindAdd:
+			{
+				def := IndexDefinition(c.To.(*Index))
+				def = strings.Replace(def, "INDEX ", "INDEX CONCURRENTLY ", 1)
+				ret = append(ret, &PhasedStatement{Phase: PhaseExpand, SQL: def + ";", NoTransaction: true})
+			}
+		default:
+			{
+				phase := PhaseExpand
+				if breaks {
+					phase = PhaseContract
+				}
+				for _, stmt := range c.SQL() {
+					add(phase, "", stmt)
+				}
+			}
+		}
+	}
+	// Changes are already in an order which can be applied, so sorting
+	// them stably by phase keeps each phase in order
+	slices.SortStableFunc(ret, func(a, b *PhasedStatement) int {
+		return int(a.Phase) - int(b.Phase)
+	})
+	return ret
+}
+
+// rewritesTable reports whether changing the type of from to that of to
+// rewrites the table, rather than the values being binary coercible.
+func rewritesTable(from, to *Column) bool {
+
+	if from.ArrayDims != to.ArrayDims || !wideningTypeChange(from, to) {
+		return true
+	}
+	switch {
+	case from.Type == to.Type:
+		// Raising the length of a varchar or the precision of a numeric
+		// doesn't change how values are stored
+		return from.Type != CharacterVarying && from.Type != Numeric && from.Type != Text
+	case from.Type == CharacterVarying && to.Type == Text, from.Type == Text && to.Type == CharacterVarying:
+		return false
+	}
+	return true
+}
+
+var phaseDescriptions = []string{
+	"apply before deploying the new application",
+	"apply once the new application is deployed",
+	"apply once the old application is gone",
+}
+
+// WritePhased writes stmts as a script, with a heading for each phase and
+// a comment on each statement taking a heavy lock.
+func WritePhased(w io.Writer, stmts []*PhasedStatement) error {
+
+	var sb strings.Builder
+	for i, stmt := range stmts {
+		if i == 0 || stmts[i-1].Phase != stmt.Phase {
+			if i > 0 {
+				sb.WriteString("\n")
+			}
+			fmt.Fprintf(&sb, "-- Phase: %s, %s\n", stmt.Phase, phaseDescriptions[stmt.Phase])
+		}
+		if stmt.Lock != "" {
+			fmt.Fprintf(&sb, "-- Takes a heavy lock: %s\n", stmt.Lock)
+		}
+		if stmt.NoTransaction {
+			sb.WriteString("-- Can't run inside a transaction\n")
+		}
+		sb.WriteString(stmt.SQL + "\n")
+	}
+	_, err := io.WriteString(w, sb.String())
+	return err
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestChanges_Phased(t *testing.T) {
+	compile := func(sql string) *Catalog {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c.Catalog
+	}
+	from := compile(`
+CREATE TABLE teams (id int PRIMARY KEY);
+CREATE TABLE users (id int PRIMARY KEY, name varchar(50), score int, nickname text, legacy text);
+`)
+	to := compile(`
+CREATE TABLE teams (id int PRIMARY KEY);
+CREATE TABLE users (
+    id int PRIMARY KEY,
+    name varchar(100),
+    score bigint CHECK (score >= 0),
+    nickname text NOT NULL,
+    team int NOT NULL REFERENCES teams (id),
+    bio text
+);
+CREATE INDEX users_team_idx ON users (team);
+`)
+	var sb strings.Builder
+	require.Nil(t, WritePhased(&sb, Diff(from, to, DiffOptions{}).Phased()))
+	assert.Equal(t, `-- Phase: expand, apply before deploying the new application
+ALTER TABLE users ALTER COLUMN name TYPE character varying(100);
+-- Takes a heavy lock: ACCESS EXCLUSIVE while the table is rewritten
+ALTER TABLE users ALTER COLUMN score TYPE bigint;
+ALTER TABLE users ADD COLUMN team integer;
+ALTER TABLE users ADD COLUMN bio text;
+ALTER TABLE users ADD CONSTRAINT users_score_check CHECK (score >= 0) NOT VALID;
+-- Can't run inside a transaction
+CREATE INDEX CONCURRENTLY users_team_idx ON users (team);
+ALTER TABLE users ADD CONSTRAINT users_team_fkey FOREIGN KEY (team) REFERENCES teams (id) NOT VALID;
+
+-- Phase: backfill, apply once the new application is deployed
+-- TODO: backfill the nulls of users.nickname
+-- TODO: backfill users.team
+ALTER TABLE users VALIDATE CONSTRAINT users_score_check;
+ALTER TABLE users VALIDATE CONSTRAINT users_team_fkey;
+
+-- Phase: contract, apply once the old application is gone
+ALTER TABLE users DROP COLUMN legacy;
+-- Takes a heavy lock: ACCESS EXCLUSIVE while every row is checked
+ALTER TABLE users ALTER COLUMN nickname SET NOT NULL;
+-- Takes a heavy lock: ACCESS EXCLUSIVE while every row is checked
+ALTER TABLE users ALTER COLUMN team SET NOT NULL;
+`, sb.String())
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+)
+
+// PlantUMLGenerator writes the catalog's tables as a PlantUML class
+// diagram, with a class for each table and a relationship for each
+// foreign key, in crow's foot notation. Columns which are part of the
+// primary key, a foreign key or a unique constraint have the stereotypes
+// PK, FK and unique, and those which can't be null are marked with a *.
+// PlantUML can't escape double quotes in names, so they're written as
+// single quotes.
+type PlantUMLGenerator struct {
+	// CollapseJoinTables draws join tables which have no columns besides
+	// their keys, and which no foreign keys reference, as many-to-many
+	// relationships between the tables they join, rather than as tables of
+	// their own.
+	CollapseJoinTables bool
+}
+
+func NewPlantUMLGenerator(fs *flag.FlagSet) Generator {
+
+	g := &PlantUMLGenerator{}
+	fs.BoolVar(&g.CollapseJoinTables, "plantuml-collapse-join-tables", false, "draw join tables without other columns as many-to-many relationships")
+	return g
+}
+
+// plantUMLName returns the name of t as the diagram quotes it.
+func plantUMLName(t *Table) string {
+
+	return `"` + strings.ReplaceAll(t.Schema+"."+t.Name, `"`, `'`) + `"`
+}
+
+func (g *PlantUMLGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	collapsed := make(map[*Table]*JoinTable)
+	if g.CollapseJoinTables {
+		for _, jt := range cat.JoinTables() {
+			if len(jt.Extra) == 0 && len(cat.Depends.ReferencingConstraints(jt.Table)) == 0 {
+				collapsed[jt.Table] = jt
+			}
+		}
+	}
+	var tables []*Table
+	for _, sch := range cat.Schemas.List() {
+		if systemSchema(sch.Name) {
+			continue
+		}
+		for _, t := range sch.Tables.List() {
+			if collapsed[t] == nil {
+				tables = append(tables, t)
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "@startuml")
+	// Names are qualified by their schemas, which PlantUML would otherwise
+	// take for packages
+	fmt.Fprintln(bw, "set separator none")
+	fmt.Fprintln(bw, "hide circle")
+	fmt.Fprintln(bw, "hide empty methods")
+	fmt.Fprintln(bw, "skinparam linetype ortho")
+	for _, t := range tables {
+		fmt.Fprintf(bw, "\nclass %s <<table>> {\n", plantUMLName(t))
+		for _, col := range t.Columns.List() {
+			var stereotypes []string
+			// Foreign keys are found by the columns they reference as well as
+			// by their own
+			cons, _ := cat.Depends.ConstraintsByColumn.Get(col)
+			for _, kind := range []struct {
+				typ  ConstraintType
+				name string
+			}{{ConstraintTypePrimary, "PK"}, {ConstraintTypeForeignKey, "FK"}, {ConstraintTypeUnique, "unique"}} {
+				if slices.ContainsFunc(cons, func(con *Constraint) bool { return con.Type == kind.typ && slices.Contains(con.Constrains, col) }) {
+					stereotypes = append(stereotypes, "<<"+kind.name+">>")
+				}
+			}
+			mandatory := ""
+			if col.Attrs.NotNull || col.Attrs.Pkey || col.Attrs.Identity != IdentityNone {
+				mandatory = "* "
+			}
+			// {field} keeps types with parentheses from being taken for
+			// methods
+			line := "{field} " + mandatory + col.Name + " : " + col.FormatType()
+			if len(stereotypes) > 0 {
+				line += " " + strings.Join(stereotypes, " ")
+			}
+			fmt.Fprintf(bw, "    %s\n", line)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+
+	var relationships []string
+	for _, t := range tables {
+		for _, con := range cat.Depends.TableConstraints(t) {
+			if con.Type != ConstraintTypeForeignKey || len(con.Refers) == 0 {
+				continue
+			}
+			many, one := "}o", "|o"
+			if cat.Depends.UniqueColumns(con.Constrains) {
+				many = "|o"
+			}
+			if !slices.ContainsFunc(con.Constrains, func(col *Column) bool { return !col.Attrs.NotNull && !col.Attrs.Pkey }) {
+				one = "||"
+			}
+			relationships = append(relationships, fmt.Sprintf("%s %s--%s %s : %s",
+				plantUMLName(t), many, one, plantUMLName(con.Refers[0].Table), strings.Join(con.Constrains.Names(), ", ")))
+		}
+	}
+	for _, t := range tables {
+		// Each join table is drawn once, from the first of the tables it
+		// joins
+		for _, con := range cat.Depends.ReferencingConstraints(t) {
+			jt := collapsed[con.Table]
+			if jt == nil || jt.Keys[0] != con {
+				continue
+			}
+			relationships = append(relationships, fmt.Sprintf("%s }o--o{ %s : %s",
+				plantUMLName(t), plantUMLName(jt.Keys[1].Refers[0].Table), strings.ReplaceAll(jt.Table.Schema+"."+jt.Table.Name, `"`, `'`)))
+		}
+	}
+	if len(relationships) > 0 {
+		fmt.Fprintln(bw)
+	}
+	for _, rel := range relationships {
+		fmt.Fprintln(bw, rel)
+	}
+	fmt.Fprintln(bw, "@enduml")
+	return bw.Flush()
+}
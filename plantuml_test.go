@@ -0,0 +1,61 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestPlantUMLGenerator_Generate(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE users (id int PRIMARY KEY, email varchar(255) NOT NULL UNIQUE, manager_id int REFERENCES users (id));
+CREATE TABLE profiles (user_id int NOT NULL UNIQUE REFERENCES users (id), bio text);
+CREATE TABLE tags (id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY);
+CREATE TABLE user_tags (user_id int REFERENCES users (id), tag_id int REFERENCES tags (id), PRIMARY KEY (user_id, tag_id));
+`))
+	var sb strings.Builder
+	require.Nil(t, (&PlantUMLGenerator{}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `@startuml
+set separator none
+hide circle
+hide empty methods
+skinparam linetype ortho
+
+class "public.users" <<table>> {
+    {field} * id : integer <<PK>>
+    {field} * email : character varying(255) <<unique>>
+    {field} manager_id : integer <<FK>>
+}
+
+class "public.profiles" <<table>> {
+    {field} * user_id : integer <<FK>> <<unique>>
+    {field} bio : text
+}
+
+class "public.tags" <<table>> {
+    {field} * id : integer <<PK>>
+}
+
+class "public.user_tags" <<table>> {
+    {field} * user_id : integer <<PK>> <<FK>>
+    {field} * tag_id : integer <<PK>> <<FK>>
+}
+
+"public.users" }o--|o "public.users" : manager_id
+"public.profiles" |o--|| "public.users" : user_id
+"public.user_tags" }o--|| "public.tags" : tag_id
+"public.user_tags" }o--|| "public.users" : user_id
+@enduml
+`, sb.String())
+
+	sb.Reset()
+	require.Nil(t, (&PlantUMLGenerator{CollapseJoinTables: true}).Generate(&sb, c.Catalog))
+	assert.NotContains(t, sb.String(), `class "public.user_tags"`)
+	assert.True(t, strings.HasSuffix(sb.String(), `"public.users" }o--|o "public.users" : manager_id
+"public.profiles" |o--|| "public.users" : user_id
+"public.tags" }o--o{ "public.users" : public.user_tags
+@enduml
+`))
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+)
+
+// ProtoGenerator writes a proto3 file with an enum for each of the
+// catalog's ValueSets and a message for each table. Columns limited to a
+// set of values have its enum as their type, nullable columns are
+// optional, and arrays are repeated. Fields are numbered by their columns'
+// attnums, which aren't reused when columns are dropped, so that the
+// messages stay compatible on the wire as the schema changes.
+type ProtoGenerator struct {
+	Package string
+}
+
+func NewProtoGenerator(fs *flag.FlagSet) Generator {
+
+	g := &ProtoGenerator{}
+	fs.StringVar(&g.Package, "proto-package", "models", "package of the generated proto file")
+	return g
+}
+
+// protoTypes are the scalar types of the types which aren't strings.
+var protoTypes = map[*PostgresType]string{
+	Smallint:    "int32",
+	Integer:     "int32",
+	Smallserial: "int32",
+	Serial:      "int32",
+	Bigint:      "int64",
+	Bigserial:   "int64",
+	Real:        "float",
+	Double:      "double",
+	Boolean:     "bool",
+	Bytea:       "bytes",
+	Timestamp:   "google.protobuf.Timestamp",
+	Timestamptz: "google.protobuf.Timestamp",
+}
+
+func (g *ProtoGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	sets := cat.ValueSets()
+	names := &zodNames{counts: make(map[string]int)}
+	for _, set := range sets {
+		names.counts[set.Name]++
+	}
+	var tables []*Table
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf == nil && t.Extension == "" {
+				tables = append(tables, t)
+				names.counts[singularize(t.Name)]++
+			}
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "// Generated by pgmodelgen.")
+	fmt.Fprintln(bw, `syntax = "proto3";`)
+	fmt.Fprintln(bw)
+	fmt.Fprintf(bw, "package %s;\n", g.Package)
+	if g.usesTimestamps(tables) {
+		fmt.Fprintln(bw)
+		fmt.Fprintln(bw, `import "google/protobuf/timestamp.proto";`)
+	}
+	enums := make(map[*Column]string)
+	for _, set := range sets {
+		_, name := names.name(set.Schema, set.Name)
+		for _, col := range set.Columns {
+			enums[col] = name
+		}
+		prefix := protoConstant(name)
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "enum %s {\n", name)
+		fmt.Fprintf(bw, "  %s_UNSPECIFIED = 0;\n", prefix)
+		taken := map[string]bool{prefix + "_UNSPECIFIED": true}
+		for i, v := range set.Values {
+			value := prefix + "_" + protoConstant(v)
+			for taken[value] {
+				value += "_"
+			}
+			taken[value] = true
+			fmt.Fprintf(bw, "  %s = %d; // %s\n", value, i+1, v)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+	for _, t := range tables {
+		_, name := names.name(t.Schema, singularize(t.Name))
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "message %s {\n", name)
+		for _, col := range t.Columns.List() {
+			typ, ok := enums[col]
+			if !ok {
+				typ = protoTypes[col.Type]
+			}
+			if typ == "" {
+				typ = "string"
+			}
+			label := ""
+			switch {
+			case col.ArrayDims > 0:
+				label = "repeated "
+			case !col.Attrs.NotNull && !col.Attrs.Pkey:
+				label = "optional "
+			}
+			fmt.Fprintf(bw, "  %s%s %s = %d;\n", label, typ, protoField(col.Name), col.Attnum)
+		}
+		fmt.Fprintln(bw, "}")
+	}
+	return bw.Flush()
+}
+
+func (g *ProtoGenerator) usesTimestamps(tables []*Table) bool {
+
+	for _, t := range tables {
+		for _, col := range t.Columns.List() {
+			if col.Type == Timestamp || col.Type == Timestamptz {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// protoConstant converts s to the upper snake case of enum values.
+func protoConstant(s string) string {
+
+	var sb strings.Builder
+	runes := []rune(s)
+	for i, r := range runes {
+		switch {
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]):
+			sb.WriteByte('_')
+			sb.WriteRune(r)
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			sb.WriteRune(unicode.ToUpper(r))
+		default:
+			sb.WriteByte('_')
+		}
+	}
+	ret := strings.Trim(sb.String(), "_")
+	if ret == "" {
+		return "EMPTY"
+	}
+	return ret
+}
+
+// protoField converts a column's name to a field name, which can only
+// hold letters, digits and underscores, and can't start with a digit.
+func protoField(name string) string {
+
+	field := strings.Map(func(r rune) rune {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return unicode.ToLower(r)
+		}
+		return '_'
+	}, name)
+	if field == "" || unicode.IsDigit(rune(field[0])) || field[0] == '_' {
+		field = "x" + field
+	}
+	return field
+}
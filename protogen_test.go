@@ -0,0 +1,57 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestProtoGenerator_Generate(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TYPE mood AS ENUM ('happy', 'very sad', 'very-sad');
+	CREATE TABLE users (
+		id bigserial PRIMARY KEY,
+		dropped text,
+		name text NOT NULL,
+		status text NOT NULL CHECK (status IN ('active', 'banned')),
+		mood mood,
+		tags text[],
+		created_at timestamptz NOT NULL DEFAULT now(),
+		"Display Name" text
+	);
+	ALTER TABLE users DROP COLUMN dropped;
+	`)
+	var sb strings.Builder
+	require.Nil(t, (&ProtoGenerator{Package: "app.v1"}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `// Generated by pgmodelgen.
+syntax = "proto3";
+
+package app.v1;
+
+import "google/protobuf/timestamp.proto";
+
+enum Mood {
+  MOOD_UNSPECIFIED = 0;
+  MOOD_HAPPY = 1; // happy
+  MOOD_VERY_SAD = 2; // very sad
+  MOOD_VERY_SAD_ = 3; // very-sad
+}
+
+enum UserStatus {
+  USER_STATUS_UNSPECIFIED = 0;
+  USER_STATUS_ACTIVE = 1; // active
+  USER_STATUS_BANNED = 2; // banned
+}
+
+message User {
+  int64 id = 1;
+  string name = 3;
+  UserStatus status = 4;
+  optional Mood mood = 5;
+  repeated string tags = 6;
+  google.protobuf.Timestamp created_at = 7;
+  optional string display_name = 8;
+}
+`, sb.String())
+}
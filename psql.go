@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// MetaCommand runs a psql meta-command, such as "\i tables.sql". Only
+// includes and variables can change the catalog, so other commands are
+// ignored, with a warning for those which change how the rest of the script
+// is run.
+func (c *Compiler) MetaCommand(cmd string) error {
+
+	name, args := parseMetaCommand(cmd, c.Vars)
+	switch name {
+	case "i", "include", "ir", "include_relative":
+		{
+			if len(args) != 1 {
+				return fmt.Errorf("\\%s expects a file", name)
+			}
+			path := args[0]
+			if (name == "ir" || name == "include_relative") && len(c.files) > 0 && !filepath.IsAbs(path) {
+				path = filepath.Join(filepath.Dir(c.files[len(c.files)-1]), path)
+			}
+			err := c.compileFile(path)
+			if err != nil {
+				return fmt.Errorf("while including %s: %w", path, err)
+			}
+		}
+	case "set":
+		{
+			// Without arguments \set lists the variables
+			if len(args) == 0 {
+				return nil
+			}
+			if c.Vars == nil {
+				c.Vars = make(map[string]string)
+			}
+			c.Vars[args[0]] = strings.Join(args[1:], "")
+		}
+	case "unset":
+		{
+			if len(args) != 1 {
+				return fmt.Errorf("\\unset expects a variable")
+			}
+			delete(c.Vars, args[0])
+		}
+	case "c", "connect", "if", "elif", "else", "endif", "gexec":
+		c.warn(RulePsql, 0, fmt.Sprintf("ignoring \\%s, which isn't supported", name))
+	}
+	return nil
+}
+
+// parseMetaCommand splits a meta-command into its name and arguments. As
+// psql does, single quotes group words into one argument and arguments of
+// the form :name are replaced by the variable's value.
+func parseMetaCommand(cmd string, vars map[string]string) (string, []string) {
+
+	cmd = strings.TrimSpace(strings.TrimPrefix(cmd, "\\"))
+	name, rest, _ := strings.Cut(cmd, " ")
+	var args []string
+	for {
+		rest = strings.TrimLeft(rest, " \t")
+		if rest == "" {
+			return name, args
+		}
+		var arg strings.Builder
+		quoted := false
+		for rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			if rest[0] != '\'' {
+				arg.WriteByte(rest[0])
+				rest = rest[1:]
+				continue
+			}
+			// A doubled quote inside quotes stands for a quote
+			quoted, rest = true, rest[1:]
+			for rest != "" {
+				if rest[0] == '\'' {
+					if len(rest) > 1 && rest[1] == '\'' {
+						arg.WriteByte('\'')
+						rest = rest[2:]
+						continue
+					}
+					rest = rest[1:]
+					break
+				}
+				arg.WriteByte(rest[0])
+				rest = rest[1:]
+			}
+		}
+		a := arg.String()
+		if value, ok := vars[strings.TrimPrefix(a, ":")]; ok && !quoted && strings.HasPrefix(a, ":") {
+			a = value
+		}
+		args = append(args, a)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_Psql(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o755))
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	write("build.sql", `\set ON_ERROR_STOP on
+\pset pager off
+\echo building :schema
+CREATE SCHEMA :"schema";
+\set status 'new'
+\ir tables/users.sql
+\connect other
+`)
+	write("tables/users.sql", `CREATE TABLE :schema.users (
+	id int PRIMARY KEY,
+	status text DEFAULT :'status',
+	created text DEFAULT now()::text,
+	missing text DEFAULT ':undefined'
+)
+\g
+`)
+
+	c := NewCompiler()
+	c.Psql = true
+	c.Vars = map[string]string{"schema": "app"}
+	require.Nil(t, c.CompileFiles([]string{filepath.Join(dir, "build.sql")}))
+	tab := assertTable(t, c, "app.users")
+	assertColumn(t, tab, "status", Text, ColumnAttributes{Default: "'new'"})
+	assertColumn(t, tab, "missing", Text, ColumnAttributes{Default: "':undefined'"})
+	require.Len(t, c.Warnings, 1)
+	assert.Contains(t, c.Warnings[0], "\\connect")
+
+	write("loop.sql", "\\ir loop.sql\n")
+	c = NewCompiler()
+	c.Psql = true
+	err := c.CompileFiles([]string{filepath.Join(dir, "loop.sql")})
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "includes itself")
+}
+
+func TestParseMetaCommand(t *testing.T) {
+	name, args := parseMetaCommand(`\set greeting 'hello, it''s' :who ':who'`+"\n", map[string]string{"who": "me"})
+	assert.Equal(t, "set", name)
+	assert.Equal(t, []string{"greeting", "hello, it's", "me", ":who"}, args)
+}
+
+func TestStatementScanner_Psql(t *testing.T) {
+	scanner := NewStatementScanner(strings.NewReader("SELECT :a::int, ':a', :'b';\n\\echo done\n"))
+	scanner.Psql = true
+	scanner.Vars = map[string]string{"a": "1", "b": "it's"}
+	require.True(t, scanner.Scan())
+	assert.Equal(t, "SELECT 1::int, ':a', 'it''s';\n", scanner.Statement().Text)
+	require.True(t, scanner.Scan())
+	assert.True(t, scanner.Statement().Meta)
+	assert.Equal(t, 1, scanner.Statement().StartLine())
+	require.False(t, scanner.Scan())
+}
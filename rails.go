@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// RailsGenerator writes an ActiveRecord schema.rb equivalent of the catalog.
+type RailsGenerator struct {
+	// Version is the ActiveRecord::Schema version, e.g. 7.1
+	Version string
+}
+
+func NewRailsGenerator(fs *flag.FlagSet) Generator {
+
+	g := &RailsGenerator{}
+	fs.StringVar(&g.Version, "rails-version", "7.1", "ActiveRecord::Schema version to target")
+	return g
+}
+
+// railsTypes maps Postgres types to the ActiveRecord PostgreSQL adapter's
+// native column types. Types not present are written with t.column.
+var railsTypes = map[*PostgresType]string{
+	Bigint:           "bigint",
+	Bigserial:        "bigserial",
+	Bit:              "bit",
+	BitVarying:       "bit_varying",
+	Boolean:          "boolean",
+	Box:              "box",
+	Bytea:            "binary",
+	Character:        "string",
+	CharacterVarying: "string",
+	CIDR:             "cidr",
+	Circle:           "circle",
+	Date:             "date",
+	Double:           "float",
+	Inet:             "inet",
+	Integer:          "integer",
+	Interval:         "interval",
+	JSON:             "json",
+	JSONB:            "jsonb",
+	Line:             "line",
+	Lseg:             "lseg",
+	Macaddr:          "macaddr",
+	Money:            "money",
+	Numeric:          "decimal",
+	Path:             "path",
+	Point:            "point",
+	Polygon:          "polygon",
+	Real:             "float",
+	Serial:           "serial",
+	Smallint:         "integer",
+	Text:             "text",
+	Time:             "time",
+	Timestamp:        "datetime",
+	Timestamptz:      "timestamptz",
+	TSVector:         "tsvector",
+	UUID:             "uuid",
+	XML:              "xml",
+}
+
+func (g *RailsGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "ActiveRecord::Schema[%s].define(version: 0) do\n", g.Version)
+	for _, sch := range cat.Schemas.List() {
+		if sch.Name != "public" {
+			fmt.Fprintf(bw, "  create_schema %s\n\n", rubyString(sch.Name))
+		}
+	}
+	var fks Constraints
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			cons := cat.Depends.TableConstraints(tab)
+			g.writeTable(bw, tab, cons)
+			for _, con := range cons {
+				if con.Type == ConstraintTypeForeignKey {
+					fks = append(fks, con)
+				}
+			}
+		}
+	}
+	for _, con := range fks {
+		fmt.Fprintf(bw, "  add_foreign_key %s, %s, column: %s, primary_key: %s, name: %s\n",
+			rubyString(railsTableName(con.Table)),
+			rubyString(railsTableName(con.Refers[0].Table)),
+			rubyColumnNames(con.Constrains),
+			rubyColumnNames(con.Refers),
+			rubyString(con.Name))
+	}
+	fmt.Fprintln(bw, "end")
+	return bw.Flush()
+}
+
+func (g *RailsGenerator) writeTable(w io.Writer, tab *Table, cons Constraints) {
+
+	var pk Columns
+	for _, col := range tab.Columns.List() {
+		if col.Attrs.Pkey {
+			pk = append(pk, col)
+		}
+	}
+	slices.SortFunc(pk, func(a, b *Column) int {
+		return a.Attrs.PkeyPosition - b.Attrs.PkeyPosition
+	})
+	opts := []string{rubyString(railsTableName(tab))}
+	switch len(pk) {
+	case 0:
+		opts = append(opts, "id: false")
+	case 1:
+		if pk[0].Name != "id" {
+			opts = append(opts, "primary_key: "+rubyString(pk[0].Name))
+		}
+		if typ, ok := railsTypes[pk[0].Type]; !ok {
+			opts = append(opts, "id: "+rubyString(pk[0].Type.Name))
+		} else if typ != "bigserial" {
+			opts = append(opts, "id: :"+typ)
+		}
+	default:
+		opts = append(opts, "primary_key: "+rubyColumnNames(pk))
+	}
+	opts = append(opts, "force: :cascade")
+	fmt.Fprintf(w, "  create_table %s do |t|\n", strings.Join(opts, ", "))
+	for _, col := range tab.Columns.List() {
+		if len(pk) == 1 && col == pk[0] {
+			continue
+		}
+		fmt.Fprintf(w, "    %s\n", railsColumn(col))
+	}
+	fmt.Fprintln(w, "  end")
+	for _, con := range cons {
+		if con.Type != ConstraintTypeUnique {
+			continue
+		}
+		fmt.Fprintf(w, "  add_index %s, %s, name: %s, unique: true\n",
+			rubyString(railsTableName(tab)), rubyColumnNames(con.Constrains), rubyString(con.Name))
+	}
+	fmt.Fprintln(w)
+}
+
+func railsColumn(col *Column) string {
+
+	var opts []string
+	typ, ok := railsTypes[col.Type]
+	if ok {
+		opts = append(opts, "t."+typ+" "+rubyString(col.Name))
+	} else {
+		opts = append(opts, "t.column "+rubyString(col.Name)+", "+rubyString(col.Type.Name))
+	}
+	switch col.Type {
+	case Smallint:
+		opts = append(opts, "limit: 2")
+	case Character, CharacterVarying, Bit, BitVarying:
+		if len(col.TypeMods) > 0 {
+			opts = append(opts, fmt.Sprintf("limit: %d", col.TypeMods[0]))
+		}
+	case Numeric:
+		if len(col.TypeMods) > 0 {
+			opts = append(opts, fmt.Sprintf("precision: %d", col.TypeMods[0]))
+		}
+		if len(col.TypeMods) > 1 {
+			opts = append(opts, fmt.Sprintf("scale: %d", col.TypeMods[1]))
+		}
+	case Time, Timestamp, Timestamptz:
+		if len(col.TypeMods) > 0 {
+			opts = append(opts, fmt.Sprintf("precision: %d", col.TypeMods[0]))
+		}
+	}
+	if def := rubyDefault(col.Attrs.Default); def != "" {
+		opts = append(opts, "default: "+def)
+	}
+	if col.Attrs.NotNull || col.Attrs.Pkey {
+		opts = append(opts, "null: false")
+	}
+	if col.ArrayDims > 0 {
+		opts = append(opts, "array: true")
+	}
+	return strings.Join(opts, ", ")
+}
+
+var (
+	rubyNumericLiteral = regexp.MustCompile(`^-?\d+(\.\d+)?$`)
+	sqlStringLiteral   = regexp.MustCompile(`^'((?:[^']|'')*)'(::.+)?$`)
+)
+
+// rubyDefault converts a deparsed SQL default into the form the Rails schema
+// dumper would produce: literals for constants, and a lambda for anything
+// which has to be evaluated by the database.
+func rubyDefault(def string) string {
+
+	switch {
+	case def == "" || strings.EqualFold(def, "null"):
+		return ""
+	case def == "true" || def == "false" || rubyNumericLiteral.MatchString(def):
+		return def
+	}
+	if m := sqlStringLiteral.FindStringSubmatch(def); m != nil {
+		return rubyString(strings.ReplaceAll(m[1], "''", "'"))
+	}
+	return "-> { " + rubyString(def) + " }"
+}
+
+func railsTableName(t *Table) string {
+
+	if t.Schema == "public" {
+		return t.Name
+	}
+	return t.Schema + "." + t.Name
+}
+
+func rubyColumnNames(cols Columns) string {
+
+	if len(cols) == 1 {
+		return rubyString(cols[0].Name)
+	}
+	quoted := make([]string, 0, len(cols))
+	for _, name := range cols.Names() {
+		quoted = append(quoted, rubyString(name))
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// rubyString quotes s as a double-quoted Ruby string literal.
+func rubyString(s string) string {
+
+	return strings.ReplaceAll(strconv.Quote(s), "#{", "\\#{")
+}
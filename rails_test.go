@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRailsGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (
+		id SERIAL PRIMARY KEY,
+		username VARCHAR(50) NOT NULL UNIQUE,
+		balance NUMERIC(10, 2) DEFAULT 0,
+		tags TEXT[],
+		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	);
+
+	CREATE TABLE orders (
+		id bigserial primary key,
+		user_id int not null references users(id),
+		note text default 'it''s'
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	err := (&RailsGenerator{Version: "7.1"}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, `ActiveRecord::Schema[7.1].define(version: 0) do
+  create_table "users", id: :serial, force: :cascade do |t|
+    t.string "username", limit: 50, null: false
+    t.decimal "balance", precision: 10, scale: 2, default: 0
+    t.text "tags", array: true
+    t.datetime "created_at", default: -> { "current_timestamp" }
+  end
+  add_index "users", "username", name: "users_username_key", unique: true
+
+  create_table "orders", force: :cascade do |t|
+    t.integer "user_id", null: false
+    t.text "note", default: "it's"
+  end
+
+  add_foreign_key "orders", "users", column: "user_id", primary_key: "id", name: "orders_user_id_fkey"
+end
+`, sb.String())
+}
+
+func TestRailsGenerator_CompositePrimaryKey(t *testing.T) {
+	c := assertParse(t, `CREATE TABLE memberships (user_id int, group_id int, PRIMARY KEY (group_id, user_id));`)
+	var sb strings.Builder
+	require.Nil(t, (&RailsGenerator{Version: "7.1"}).Generate(&sb, c.Catalog))
+	assert.Contains(t, sb.String(), `  create_table "memberships", primary_key: ["group_id", "user_id"], force: :cascade do |t|
+    t.integer "user_id", null: false
+    t.integer "group_id", null: false
+  end
+`)
+}
@@ -0,0 +1,251 @@
+package main
+
+import (
+	"slices"
+)
+
+// RelationshipKind is how the rows of a relationship's table relate to the
+// rows of its target.
+type RelationshipKind int
+
+const (
+	// RelationshipBelongsTo is a foreign key of the table, so each of its
+	// rows refers to a row of the target.
+	RelationshipBelongsTo RelationshipKind = iota
+	// RelationshipHasOne is a unique foreign key of the target referring
+	// to the table, so each of its rows is referred to by at most one of
+	// the target's.
+	RelationshipHasOne
+	// RelationshipHasMany is a foreign key of the target referring to the
+	// table, which isn't unique.
+	RelationshipHasMany
+	// RelationshipManyToMany relates the table to the target through a
+	// join table, which has a foreign key to each.
+	RelationshipManyToMany
+)
+
+func (k RelationshipKind) String() string {
+
+	switch k {
+	case RelationshipBelongsTo:
+		return "belongs to"
+	case RelationshipHasOne:
+		return "has one"
+	case RelationshipHasMany:
+		return "has many"
+	case RelationshipManyToMany:
+		return "many to many"
+	}
+	return "unknown"
+}
+
+// Relationship is a relationship between the rows of two tables, from the
+// point of view of Table, derived from a foreign key.
+type Relationship struct {
+	Kind   RelationshipKind
+	Table  *Table
+	Target *Table
+	// Constraint is the foreign key the relationship is derived from: the
+	// table's for belongs-to relationships, the target's for has-one and
+	// has-many ones, and the join table's referring to the table for
+	// many-to-many ones.
+	Constraint *Constraint
+	// Columns are the columns of the table joined to TargetColumns of the
+	// target, or for many-to-many relationships, to the join table.
+	Columns       Columns
+	TargetColumns Columns
+	// OneToOne is set if the foreign key's columns are unique, so no two
+	// rows are related to the same row.
+	OneToOne bool
+	// Through is the join table of a many-to-many relationship, and
+	// ThroughConstraint its foreign key referring to the target.
+	Through           *Table
+	ThroughConstraint *Constraint
+}
+
+// SelfReferencing reports whether the relationship relates the rows of a
+// table to other rows of the same table.
+func (r *Relationship) SelfReferencing() bool {
+
+	return r.Table == r.Target
+}
+
+// Relationships returns the relationships of t: those of its foreign keys,
+// followed by those of the foreign keys referring to it, both ordered by
+// name, and then those through the join tables among the latter, as
+// JoinTable detects them. Self-referencing foreign keys give both a
+// belongs-to relationship and a has-one or has-many one.
+func (c *Catalog) Relationships(t *Table) []*Relationship {
+
+	var rels []*Relationship
+	for _, con := range c.Depends.TableConstraints(t) {
+		if con.Type != ConstraintTypeForeignKey {
+			continue
+		}
+		rels = append(rels, &Relationship{
+			Kind:          RelationshipBelongsTo,
+			Table:         t,
+			Target:        con.Refers[0].Table,
+			Constraint:    con,
+			Columns:       con.Constrains,
+			TargetColumns: con.Refers,
+			OneToOne:      c.Depends.UniqueColumns(con.Constrains),
+		})
+	}
+	referencing := c.Depends.ReferencingConstraints(t)
+	for _, con := range referencing {
+		rel := &Relationship{
+			Kind:          RelationshipHasMany,
+			Table:         t,
+			Target:        con.Table,
+			Constraint:    con,
+			Columns:       con.Refers,
+			TargetColumns: con.Constrains,
+			OneToOne:      c.Depends.UniqueColumns(con.Constrains),
+		}
+		if rel.OneToOne {
+			rel.Kind = RelationshipHasOne
+		}
+		rels = append(rels, rel)
+	}
+	for _, con := range referencing {
+		jt := c.JoinTable(con.Table)
+		if jt == nil {
+			continue
+		}
+		other := jt.Other(con)
+		rels = append(rels, &Relationship{
+			Kind:              RelationshipManyToMany,
+			Table:             t,
+			Target:            other.Refers[0].Table,
+			Constraint:        con,
+			Columns:           con.Refers,
+			TargetColumns:     other.Refers,
+			Through:           con.Table,
+			ThroughConstraint: other,
+		})
+	}
+	return rels
+}
+
+// JoinTableMaxExtra is the most columns a join table can have besides
+// those of its foreign keys, such as when a row was added or its position.
+const JoinTableMaxExtra = 2
+
+// JoinTable is a table relating the rows of two tables to each other, or
+// the rows of a table to other rows of it.
+type JoinTable struct {
+	Table *Table
+	// Keys are the two foreign keys of the table, ordered by name.
+	Keys Constraints
+	// Extra are the columns which aren't part of either key.
+	Extra Columns
+}
+
+// JoinTable returns t as a join table, or nil if it isn't one. A join table
+// has two foreign keys, whose columns make up its primary key, and at most
+// JoinTableMaxExtra other columns.
+func (c *Catalog) JoinTable(t *Table) *JoinTable {
+
+	var pkey Columns
+	var fks Constraints
+	for _, con := range c.Depends.TableConstraints(t) {
+		switch con.Type {
+		case ConstraintTypePrimary:
+			pkey = con.Constrains
+		case ConstraintTypeForeignKey:
+			fks = append(fks, con)
+		}
+	}
+	if len(fks) != 2 {
+		return nil
+	}
+	keyed := slices.Concat(fks[0].Constrains, fks[1].Constrains)
+	if len(keyed) != len(pkey) {
+		return nil
+	}
+	for _, col := range pkey {
+		if !slices.Contains(keyed, col) {
+			return nil
+		}
+	}
+	jt := &JoinTable{Table: t, Keys: fks}
+	for _, col := range t.Columns.List() {
+		if !slices.Contains(keyed, col) {
+			jt.Extra = append(jt.Extra, col)
+		}
+	}
+	if len(jt.Extra) > JoinTableMaxExtra {
+		return nil
+	}
+	return jt
+}
+
+// JoinTables returns the join tables of the catalog, in the order of their
+// schemas.
+func (c *Catalog) JoinTables() []*JoinTable {
+
+	var jts []*JoinTable
+	for _, sch := range c.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf != nil {
+				continue
+			}
+			if jt := c.JoinTable(t); jt != nil {
+				jts = append(jts, jt)
+			}
+		}
+	}
+	return jts
+}
+
+// Other returns the key of the join table which isn't con.
+func (jt *JoinTable) Other(con *Constraint) *Constraint {
+
+	if jt.Keys[0] == con {
+		return jt.Keys[1]
+	}
+	return jt.Keys[0]
+}
+
+// UniqueColumns reports whether a primary key, unique constraint or
+// unique index is on exactly cols, in any order, so that no two rows have
+// the same values in them. Partial and expression indexes aren't counted.
+func (d *Depends) UniqueColumns(cols Columns) bool {
+
+	if len(cols) == 0 {
+		return false
+	}
+	same := func(other Columns) bool {
+		if len(other) != len(cols) {
+			return false
+		}
+		for _, col := range other {
+			if !slices.Contains(cols, col) {
+				return false
+			}
+		}
+		return true
+	}
+	t := cols[0].Table
+	for _, con := range d.TableConstraints(t) {
+		if con.Indexed() && same(con.Constrains) {
+			return true
+		}
+	}
+	for _, idx := range d.TableIndexes(t) {
+		if !idx.Unique || idx.Predicate != "" {
+			continue
+		}
+		keys := make(Columns, 0, len(idx.Elems))
+		for _, elem := range idx.Elems {
+			if elem.Column != nil {
+				keys = append(keys, elem.Column)
+			}
+		}
+		if len(keys) == len(idx.Elems) && same(keys) {
+			return true
+		}
+	}
+	return false
+}
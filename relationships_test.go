@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCatalog_Relationships(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (id int PRIMARY KEY, manager_id int REFERENCES users (id));
+	CREATE TABLE profiles (id int PRIMARY KEY, user_id int NOT NULL REFERENCES users (id));
+	CREATE UNIQUE INDEX ON profiles (user_id);
+	CREATE TABLE groups (id int PRIMARY KEY);
+	CREATE TABLE memberships (
+		user_id int REFERENCES users (id),
+		group_id int REFERENCES groups (id),
+		PRIMARY KEY (group_id, user_id)
+	);
+	CREATE TABLE posts (
+		id int PRIMARY KEY,
+		author_id int REFERENCES users (id),
+		group_id int REFERENCES groups (id)
+	);
+	`
+	c := assertParse(t, sql)
+	public, _ := c.Catalog.Schemas.Get("public")
+	describe := func(name string) []string {
+		tab, _ := public.Tables.Get(name)
+		var got []string
+		for _, rel := range c.Catalog.Relationships(tab) {
+			desc := fmt.Sprintf("%s %s %v -> %v", rel.Kind, rel.Target.Name, rel.Columns.Names(), rel.TargetColumns.Names())
+			if rel.Through != nil {
+				desc += " through " + rel.Through.Name
+			}
+			if rel.OneToOne {
+				desc += " one to one"
+			}
+			if rel.SelfReferencing() {
+				desc += " self"
+			}
+			got = append(got, desc)
+		}
+		return got
+	}
+	assert.Equal(t, []string{
+		"belongs to users [manager_id] -> [id] self",
+		"has many memberships [id] -> [user_id]",
+		"has many posts [id] -> [author_id]",
+		"has one profiles [id] -> [user_id] one to one",
+		"has many users [id] -> [manager_id] self",
+		"many to many groups [id] -> [id] through memberships",
+	}, describe("users"))
+	assert.Equal(t, []string{
+		"belongs to users [user_id] -> [id] one to one",
+	}, describe("profiles"))
+	assert.Equal(t, []string{
+		"belongs to groups [group_id] -> [id]",
+		"belongs to users [user_id] -> [id]",
+	}, describe("memberships"))
+	// posts has other columns, so it's no join table
+	assert.Equal(t, []string{
+		"has many memberships [id] -> [group_id]",
+		"has many posts [id] -> [group_id]",
+		"many to many users [id] -> [id] through memberships",
+	}, describe("groups"))
+}
+
+func TestDepends_UniqueColumns(t *testing.T) {
+	const sql = `
+	CREATE TABLE t (a int, b int, c int, d int, UNIQUE (a, b));
+	CREATE UNIQUE INDEX ON t (c) WHERE c > 0;
+	CREATE UNIQUE INDEX ON t (lower(d::text));
+	`
+	c := assertParse(t, sql)
+	public, _ := c.Catalog.Schemas.Get("public")
+	tab, _ := public.Tables.Get("t")
+	col := func(name string) *Column {
+		col, _ := tab.Columns.Get(name)
+		return col
+	}
+	assert.True(t, c.Catalog.Depends.UniqueColumns(Columns{col("b"), col("a")}))
+	assert.False(t, c.Catalog.Depends.UniqueColumns(Columns{col("a")}))
+	assert.False(t, c.Catalog.Depends.UniqueColumns(Columns{col("c")}))
+	assert.False(t, c.Catalog.Depends.UniqueColumns(Columns{col("d")}))
+}
+
+func TestCatalog_JoinTables(t *testing.T) {
+	const sql = `
+	CREATE TABLE users (id int PRIMARY KEY);
+	CREATE TABLE groups (id int PRIMARY KEY);
+	CREATE TABLE memberships (
+		user_id int REFERENCES users (id),
+		group_id int REFERENCES groups (id),
+		role text,
+		joined_at timestamptz,
+		PRIMARY KEY (user_id, group_id)
+	);
+	CREATE TABLE invitations (
+		user_id int REFERENCES users (id),
+		group_id int REFERENCES groups (id),
+		role text,
+		message text,
+		sent_at timestamptz,
+		PRIMARY KEY (user_id, group_id)
+	);
+	CREATE TABLE bans (
+		id int PRIMARY KEY,
+		user_id int REFERENCES users (id),
+		group_id int REFERENCES groups (id)
+	);
+	`
+	c := assertParse(t, sql)
+	jts := c.Catalog.JoinTables()
+	require.Len(t, jts, 1)
+	assert.Equal(t, "memberships", jts[0].Table.Name)
+	assert.Equal(t, []string{"memberships_group_id_fkey", "memberships_user_id_fkey"}, []string{jts[0].Keys[0].Name, jts[0].Keys[1].Name})
+	assert.Equal(t, []string{"role", "joined_at"}, jts[0].Extra.Names())
+	assert.Equal(t, jts[0].Keys[1], jts[0].Other(jts[0].Keys[0]))
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Repl reads statements and psql style inspection commands, applying the
+// statements to a compiler's catalog and showing how each changes it.
+type Repl struct {
+	c *Compiler
+	w *bufio.Writer
+	// baseline is the catalog as it was when the REPL started, which \diff
+	// compares against.
+	baseline *Catalog
+}
+
+const replHelp = `Statements are applied to the catalog, showing the changes they make.
+  \d              list tables
+  \d NAME         describe a table
+  \dn             list schemas
+  \di             list indexes
+  \diff           show the SQL migrating from the schema loaded to the current one
+  \sql            show the DDL of the current schema
+  \i FILE         apply the statements in a file
+  \?              show this help
+  \q              quit
+`
+
+// NewRepl starts a REPL on the catalog of c.
+func NewRepl(c *Compiler, w io.Writer) (*Repl, error) {
+
+	baseline, err := snapshotCatalog(c.Catalog)
+	if err != nil {
+		return nil, err
+	}
+	return &Repl{c: c, w: bufio.NewWriter(w), baseline: baseline}, nil
+}
+
+// Run reads from r until it ends or \q is entered.
+func (r *Repl) Run(in io.Reader) error {
+
+	scanner := NewStatementScanner(in)
+	scanner.Psql = true
+	scanner.Vars = r.c.Vars
+	for {
+		fmt.Fprint(r.w, "pgmodelgen=> ")
+		err := r.w.Flush()
+		if err != nil {
+			return err
+		}
+		if !scanner.Scan() {
+			fmt.Fprintln(r.w)
+			break
+		}
+		stmt := scanner.Statement()
+		if stmt.Meta {
+			if !r.command(stmt.Text[stmt.Offset:]) {
+				break
+			}
+			continue
+		}
+		if strings.TrimSpace(stmt.Text) == "" {
+			continue
+		}
+		r.apply(stmt.Text)
+	}
+	err := scanner.Err()
+	if err != nil {
+		return err
+	}
+	return r.w.Flush()
+}
+
+// apply applies a statement, showing the changes it made. The catalog is
+// compared as written by the DDLGenerator before and after, so that only
+// what the statement changed is shown.
+func (r *Repl) apply(sql string) {
+
+	before, snapErr := snapshotCatalog(r.c.Catalog)
+	parsed, err := ParseSource(sql)
+	if err == nil {
+		err = r.c.Apply(parsed)
+	}
+	if err != nil {
+		fmt.Fprintf(r.w, "ERROR: %s\n", err)
+	}
+	if snapErr != nil {
+		return
+	}
+	after, snapErr := snapshotCatalog(r.c.Catalog)
+	if snapErr != nil {
+		return
+	}
+	changes := Diff(before, after, DiffOptions{})
+	if len(changes) == 0 && err == nil {
+		fmt.Fprintln(r.w, "no changes")
+	}
+	for _, change := range changes {
+		fmt.Fprintln(r.w, change)
+	}
+}
+
+// command runs an inspection command, returning false if it quits.
+func (r *Repl) command(cmd string) bool {
+
+	name, args := parseMetaCommand(cmd, r.c.Vars)
+	cat := r.c.Catalog
+	switch name {
+	case "q", "quit":
+		return false
+	case "?":
+		fmt.Fprint(r.w, replHelp)
+	case "d":
+		{
+			if len(args) == 0 {
+				r.listTables()
+				break
+			}
+			t := r.table(args[0])
+			if t == nil {
+				fmt.Fprintf(r.w, "Did not find any table named %q.\n", args[0])
+				break
+			}
+			DescribeTable(r.w, r.c.Catalog, t)
+		}
+	case "dn":
+		for _, sch := range cat.Schemas.List() {
+			fmt.Fprintln(r.w, sch.Name)
+		}
+	case "di":
+		for _, sch := range cat.Schemas.List() {
+			for _, t := range sch.Tables.List() {
+				for _, idx := range cat.Depends.TableIndexes(t) {
+					fmt.Fprintln(r.w, IndexDefinition(idx))
+				}
+			}
+		}
+	case "diff":
+		{
+			current, err := snapshotCatalog(cat)
+			if err != nil {
+				fmt.Fprintf(r.w, "ERROR: %s\n", err)
+				break
+			}
+			for _, change := range Diff(r.baseline, current, DiffOptions{}) {
+				fmt.Fprintf(r.w, "-- %s\n", change)
+				for _, stmt := range change.SQL() {
+					fmt.Fprintln(r.w, stmt)
+				}
+			}
+		}
+	case "sql":
+		{
+			err := (&DDLGenerator{}).Generate(r.w, cat)
+			if err != nil {
+				fmt.Fprintf(r.w, "ERROR: %s\n", err)
+			}
+		}
+	default:
+		{
+			err := r.c.MetaCommand(cmd)
+			if err != nil {
+				fmt.Fprintf(r.w, "ERROR: %s\n", err)
+			}
+		}
+	}
+	return true
+}
+
+func (r *Repl) listTables() {
+
+	rows := [][]string{{"Schema", "Name", "Columns"}}
+	for _, sch := range r.c.Catalog.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			rows = append(rows, []string{t.Schema, t.Name, fmt.Sprint(len(t.Columns.List()))})
+		}
+	}
+	writeRows(r.w, rows)
+}
+
+// table finds a table by its name, which is qualified by its schema unless
+// it's in the search path.
+func (r *Repl) table(name string) *Table {
+
+	return findTable(r.c.Catalog, r.c.SearchPath, name)
+}
+
+// snapshotCatalog returns a copy of cat, made by compiling the DDL it's
+// written as.
+func snapshotCatalog(cat *Catalog) (*Catalog, error) {
+
+	var sb strings.Builder
+	err := (&DDLGenerator{}).Generate(&sb, cat)
+	if err != nil {
+		return nil, err
+	}
+	c := NewCompiler()
+	c.Lenient = true
+	err = c.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("while copying the catalog: %w", err)
+	}
+	return c.Catalog, nil
+}
+
+func runRepl(args []string) error {
+
+	fs := flag.NewFlagSet("repl", flag.ExitOnError)
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	repl, err := NewRepl(c, os.Stdout)
+	if err != nil {
+		return err
+	}
+	fmt.Println(`Type \? for help.`)
+	return repl.Run(os.Stdin)
+}
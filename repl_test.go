@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestRepl(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile("CREATE TABLE users (id int PRIMARY KEY, email text);"))
+
+	var out strings.Builder
+	repl, err := NewRepl(c, &out)
+	require.Nil(t, err)
+	require.Nil(t, repl.Run(strings.NewReader(`ALTER TABLE users ADD COLUMN name text NOT NULL DEFAULT '';
+CREATE INDEX users_email ON users (email);
+ALTER TABLE missing ADD COLUMN x int;
+SELECT 1;
+\d
+\d users
+\d nope
+\diff
+\q
+CREATE TABLE never (id int);
+`)))
+	got := out.String()
+
+	assert.Contains(t, got, "pgmodelgen=> add column public.users.name\n")
+	assert.Contains(t, got, "pgmodelgen=> add index public.users.users_email\n")
+	assert.Contains(t, got, "pgmodelgen=> ERROR: while altering table:")
+	assert.Contains(t, got, "pgmodelgen=> no changes\n")
+	assert.Contains(t, got, "Schema  Name   Columns\npublic  users  3\n")
+	assert.Contains(t, got, `Table "public.users"
+Column  Type     Nullable  Default
+id      integer  not null
+email   text
+name    text     not null  ''
+Constraints:
+    CONSTRAINT users_pkey PRIMARY KEY (id)
+Indexes:
+    CREATE INDEX users_email ON users (email)
+`)
+	assert.Contains(t, got, `Did not find any table named "nope".`)
+	assert.Contains(t, got, "-- add column public.users.name\nALTER TABLE users ADD COLUMN name text NOT NULL DEFAULT '';\n")
+	assertTable(t, c, "public.users")
+	_, ok := c.Catalog.Schemas.List()[0].Tables.Get("never")
+	assert.False(t, ok)
+}
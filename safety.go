@@ -0,0 +1,194 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Safety classifies how applying a change affects existing data and the
+// applications using it.
+type Safety int
+
+const (
+	// SafetySafe changes can't lose data and don't break existing readers
+	// or writers.
+	SafetySafe Safety = iota
+	// SafetyIncompatible changes are backwards-incompatible: they don't
+	// lose data, but may break applications written against the old
+	// schema, or fail to apply to existing data.
+	SafetyIncompatible
+	// SafetyDestructive changes may lose data.
+	SafetyDestructive
+)
+
+var safetyNames = []string{"safe", "incompatible", "destructive"}
+
+func (s Safety) String() string {
+
+	return safetyNames[s]
+}
+
+func ParseSafety(s string) (Safety, error) {
+
+	idx := slices.Index(safetyNames, s)
+	if idx < 0 {
+		return SafetySafe, fmt.Errorf("unknown safety %q, expected one of: %s", s, strings.Join(safetyNames, ", "))
+	}
+	return Safety(idx), nil
+}
+
+// Classify returns how safe the change is to apply, and the reason for it
+// if it isn't safe.
+func (c *Change) Classify() (Safety, string) {
+
+	switch {
+	case c.Object == ObjectKindDescription:
+		return SafetySafe, ""
+	case (c.Object == ObjectKindView || c.Object == ObjectKindEnum) && c.Kind == ChangeKindDrop:
+		return SafetyIncompatible, fmt.Sprintf("queries using the %s will fail", c.Object)
+	case c.Object == ObjectKindView || c.Object == ObjectKindSequence && c.Kind != ChangeKindDrop:
+		return SafetySafe, ""
+	case c.Object == ObjectKindEnum:
+		{
+			if c.Kind == ChangeKindAlter && len(removedLabels(c.From.(*Enum), c.To.(*Enum))) > 0 {
+				return SafetyDestructive, "rows holding a removed label fail to convert"
+			}
+			return SafetySafe, ""
+		}
+	}
+	switch c.Kind {
+	case ChangeKindDrop:
+		{
+			if c.Object == ObjectKindConstraint || c.Object == ObjectKindIndex || c.Object == ObjectKindStatistics || c.Object == ObjectKindEventTrigger {
+				return SafetySafe, ""
+			}
+			return SafetyDestructive, fmt.Sprintf("the data in the %s is lost", c.Object)
+		}
+	case ChangeKindRename:
+		{
+			if c.Object == ObjectKindConstraint || c.Object == ObjectKindIndex || c.Object == ObjectKindStatistics || c.Object == ObjectKindEventTrigger {
+				return SafetySafe, ""
+			}
+			return SafetyIncompatible, fmt.Sprintf("queries using the old %s name will fail", c.Object)
+		}
+	case ChangeKindAdd:
+		{
+			switch c.Object {
+			case ObjectKindColumn:
+				{
+					col := c.To.(*Column)
+					if col.Attrs.NotNull && col.Attrs.Default == "" && !col.generatesValues() {
+						return SafetyIncompatible, "inserts which don't set the column will fail, as will adding it to a table with rows"
+					}
+				}
+			case ObjectKindConstraint:
+				if c.To.(*Constraint).NotValid {
+					return SafetyIncompatible, "writes may violate the constraint"
+				}
+				return SafetyIncompatible, "existing data or writes may violate the constraint"
+			case ObjectKindIndex:
+				if c.To.(*Index).Unique {
+					return SafetyIncompatible, "existing data or writes may violate the unique index"
+				}
+			}
+			return SafetySafe, ""
+		}
+	}
+
+	// Only the storage parameters of indexes are altered
+	if c.Object == ObjectKindEventTrigger || c.Object == ObjectKindIndex {
+		return SafetySafe, ""
+	}
+	if c.Object == ObjectKindConstraint {
+		if c.To.(*Constraint).NotValid {
+			return SafetySafe, ""
+		}
+		return SafetyIncompatible, "existing data may violate the constraint"
+	}
+	if c.Object == ObjectKindTable {
+		from, _ := c.From.(*Table)
+		if c.To.(*Table).ReplicaIdentity == ReplicaIdentityNothing && (from == nil || from.ReplicaIdentity != ReplicaIdentityNothing) {
+			return SafetyIncompatible, "updates and deletes will fail if the table is published"
+		}
+		return SafetySafe, ""
+	}
+
+	from, to := c.From.(*Column), c.To.(*Column)
+	safety, reason := SafetySafe, ""
+	worse := func(s Safety, r string) {
+		if s > safety {
+			safety, reason = s, r
+		}
+	}
+	if !from.SameType(to) && !wideningTypeChange(from, to) {
+		worse(SafetyDestructive, fmt.Sprintf("converting %s to %s may lose or reject data", from.FormatType(), to.FormatType()))
+	}
+	// Serial columns are not null even if they're not declared so
+	if fromDef, toDef := definitionsOf(from, to); toDef.NotNull && !fromDef.NotNull {
+		worse(SafetyIncompatible, "existing nulls will fail the constraint, as will writes of null")
+	}
+	if (from.Attrs.Default != "" || from.Attrs.Identity != IdentityNone) && to.Attrs.Default == "" && !to.generatesValues() {
+		worse(SafetyIncompatible, "writes relying on the default will change")
+	}
+	if to.Attrs.Identity == IdentityAlways && from.Attrs.Identity != IdentityAlways {
+		worse(SafetyIncompatible, "writes setting the column will fail unless they override the identity")
+	}
+	return safety, reason
+}
+
+// typeWidenings lists the conversions to another type which can't lose
+// data, provided the new type has no modifiers.
+var typeWidenings = map[*PostgresType][]*PostgresType{
+	Smallint:         {Integer, Bigint, Numeric},
+	Integer:          {Bigint, Numeric},
+	Bigint:           {Numeric},
+	Real:             {Double},
+	Character:        {CharacterVarying, Text},
+	CharacterVarying: {Text},
+	Text:             {CharacterVarying},
+}
+
+// wideningTypeChange reports whether every value of from's type can be
+// stored unchanged by to's type.
+func wideningTypeChange(from, to *Column) bool {
+
+	if from.ArrayDims != to.ArrayDims {
+		return false
+	}
+	fromType, toType := from.Type, to.Type
+	if t, ok := serialTypes[fromType]; ok {
+		fromType = t
+	}
+	if t, ok := serialTypes[toType]; ok {
+		toType = t
+	}
+	if fromType != toType {
+		return len(to.TypeMods) == 0 && slices.Contains(typeWidenings[fromType], toType)
+	}
+	if len(to.TypeMods) == 0 {
+		// Removing the modifiers of a type removes its limits, except for
+		// character which then has a length of 1
+		return fromType != Character
+	}
+	if len(from.TypeMods) == 0 {
+		return false
+	}
+	switch fromType {
+	case Numeric:
+		{
+			fromScale, toScale := int32(0), int32(0)
+			if len(from.TypeMods) > 1 {
+				fromScale = from.TypeMods[1]
+			}
+			if len(to.TypeMods) > 1 {
+				toScale = to.TypeMods[1]
+			}
+			return toScale >= fromScale && to.TypeMods[0]-toScale >= from.TypeMods[0]-fromScale
+		}
+	case Interval:
+		// The fields restrict which parts of the interval are kept
+		return slices.Equal(from.TypeMods, to.TypeMods)
+	}
+	return to.TypeMods[0] >= from.TypeMods[0]
+}
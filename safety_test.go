@@ -0,0 +1,109 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestChange_Classify(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (id serial PRIMARY KEY, name text);
+	CREATE TABLE audit (id int);
+	`)
+	to := assertParse(t, `
+	CREATE TABLE app_users (id serial PRIMARY KEY, name text);
+	CREATE TABLE events (id int);
+	`)
+	safety := make(map[string]Safety)
+	for _, c := range Diff(from.Catalog, to.Catalog, DiffOptions{Renames: RenamesConservative}) {
+		s, reason := c.Classify()
+		if s != SafetySafe {
+			assert.NotEmpty(t, reason, c.String())
+		}
+		safety[c.String()] = s
+	}
+	assert.Equal(t, map[string]Safety{
+		"drop table public.audit":                                         SafetyDestructive,
+		"add table public.events":                                         SafetySafe,
+		"rename table public.users to app_users":                          SafetyIncompatible,
+		"rename constraint public.app_users.users_pkey to app_users_pkey": SafetySafe,
+	}, safety)
+}
+
+func TestChange_Classify_Columns(t *testing.T) {
+	from := assertParse(t, `
+	CREATE TABLE users (
+		id serial PRIMARY KEY,
+		name varchar(50),
+		email varchar(100) NOT NULL,
+		age smallint,
+		score numeric(6,2),
+		status text DEFAULT 'active',
+		bio varchar(200),
+		legacy text
+	);
+	`)
+	to := assertParse(t, `
+	CREATE TABLE users (
+		id bigserial PRIMARY KEY,
+		name varchar(100) NOT NULL,
+		email varchar(50),
+		age bigint,
+		score numeric(8,3),
+		status text,
+		bio text,
+		nickname text,
+		joined date NOT NULL,
+		created date NOT NULL DEFAULT current_date,
+		UNIQUE (email)
+	);
+	`)
+	safety := make(map[string]Safety)
+	for _, c := range Diff(from.Catalog, to.Catalog, DiffOptions{}) {
+		safety[c.String()], _ = c.Classify()
+	}
+	assert.Equal(t, map[string]Safety{
+		"drop column public.users.legacy":             SafetyDestructive,
+		"alter column public.users.id":                SafetySafe,
+		"alter column public.users.name":              SafetyIncompatible,
+		"alter column public.users.email":             SafetyDestructive,
+		"alter column public.users.age":               SafetySafe,
+		"alter column public.users.score":             SafetySafe,
+		"alter column public.users.status":            SafetyIncompatible,
+		"alter column public.users.bio":               SafetySafe,
+		"add column public.users.nickname":            SafetySafe,
+		"add column public.users.joined":              SafetyIncompatible,
+		"add column public.users.created":             SafetySafe,
+		"add constraint public.users.users_email_key": SafetyIncompatible,
+	}, safety)
+}
+
+func TestWideningTypeChange(t *testing.T) {
+	col := func(sql string) *Column {
+		c := assertParse(t, "CREATE TABLE t (c "+sql+");")
+		col, _ := assertTable(t, c, "public.t").Columns.Get("c")
+		return col
+	}
+	for _, tc := range []struct {
+		from, to string
+		widening bool
+	}{
+		{"int", "bigint", true},
+		{"bigint", "int", false},
+		{"varchar(10)", "varchar(20)", true},
+		{"varchar(20)", "varchar(10)", false},
+		{"varchar", "varchar(10)", false},
+		{"varchar(10)", "text", true},
+		{"text", "int", false},
+		{"char(5)", "char", false},
+		{"numeric(5,2)", "numeric(6,3)", true},
+		{"numeric(5,2)", "numeric(5,3)", false},
+		{"numeric(5,2)", "numeric", true},
+		{"int[]", "bigint[]", true},
+		{"int[]", "bigint", false},
+		{"timestamp(3)", "timestamp(6)", true},
+		{"timestamp", "timestamptz", false},
+	} {
+		assert.Equal(t, tc.widening, wideningTypeChange(col(tc.from), col(tc.to)), "%s to %s", tc.from, tc.to)
+	}
+}
@@ -0,0 +1,474 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"slices"
+	"strings"
+	"time"
+)
+
+// SeedGenerator writes INSERT statements of fake data which respect the
+// catalog's types, NOT NULL, unique and foreign key constraints. Tables
+// are written in dependency order so that foreign keys are satisfied.
+//
+// The value used for a column can be overridden with a "@seed" annotation
+// naming one of seedTextGenerators, "oneof a,b,c" to choose from a fixed
+// list, or "skip" to leave the column to its default.
+type SeedGenerator struct {
+	Rows int
+	Seed uint64
+}
+
+func NewSeedGenerator(fs *flag.FlagSet) Generator {
+
+	g := &SeedGenerator{}
+	fs.IntVar(&g.Rows, "seed-rows", 10, "number of rows to generate per table")
+	fs.Uint64Var(&g.Seed, "seed-random", 1, "random seed, the same seed always generates the same data")
+	return g
+}
+
+type seedRow map[*Column]string
+
+const seedNull = "NULL"
+
+var seedEpoch = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func (g *SeedGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	order, err := SortTablesByDependency(cat)
+	if err != nil {
+		return err
+	}
+	rng := rand.New(rand.NewPCG(g.Seed, g.Seed))
+	sets := cat.ValueSets()
+	rows := make(map[*Table][]seedRow)
+	bw := bufio.NewWriter(w)
+	for _, tab := range order {
+		var cols Columns
+		for _, col := range tab.Columns.List() {
+			if col.Annotations["seed"] != "skip" {
+				cols = append(cols, col)
+			}
+		}
+		if len(cols) == 0 {
+			continue
+		}
+		cons := cat.Depends.TableConstraints(tab)
+		used := make(map[*Constraint]map[string]struct{})
+	row:
+		for i := range g.Rows {
+			for range 100 {
+				r, err := g.row(rng, tab, cols, cons, sets, rows, i)
+				if err != nil {
+					return err
+				}
+				if seedIsUnique(r, cons, used) {
+					rows[tab] = append(rows[tab], r)
+					continue row
+				}
+			}
+			fmt.Fprintf(bw, "-- Only %d unique rows could be generated for %s\n", i, TableIdent(tab))
+			break
+		}
+		if len(rows[tab]) == 0 {
+			continue
+		}
+
+		overriding := ""
+		if slices.ContainsFunc(cols, func(col *Column) bool { return col.Attrs.Identity == IdentityAlways }) {
+			overriding = " OVERRIDING SYSTEM VALUE"
+		}
+		fmt.Fprintf(bw, "INSERT INTO %s (%s)%s VALUES\n", TableIdent(tab), quoteColumnNames(cols), overriding)
+		for i, r := range rows[tab] {
+			values := make([]string, 0, len(cols))
+			for _, col := range cols {
+				values = append(values, r[col])
+			}
+			sep := ","
+			if i == len(rows[tab])-1 {
+				sep = ";"
+			}
+			fmt.Fprintf(bw, "    (%s)%s\n", strings.Join(values, ", "), sep)
+		}
+		for _, col := range cols {
+			if col.generatesValues() {
+				fmt.Fprintf(bw, "SELECT setval(pg_get_serial_sequence(%s, %s), %d);\n",
+					QuoteLiteral(TableIdent(tab)), QuoteLiteral(col.Name), len(rows[tab]))
+			}
+		}
+		fmt.Fprintln(bw)
+	}
+	return bw.Flush()
+}
+
+// row generates the i'th row of tab, choosing foreign key values from the
+// rows already generated for the referenced tables, and the values of
+// columns limited to a set of values from the set.
+func (g *SeedGenerator) row(rng *rand.Rand, tab *Table, cols Columns, cons Constraints, sets ValueSets, rows map[*Table][]seedRow, i int) (seedRow, error) {
+
+	r := make(seedRow, len(cols))
+	var fks Constraints
+	fkCols := make(map[*Column]struct{})
+	for _, con := range cons {
+		if con.Type == ConstraintTypeForeignKey {
+			fks = append(fks, con)
+			for _, col := range con.Constrains {
+				fkCols[col] = struct{}{}
+			}
+		}
+	}
+	for _, col := range cols {
+		if _, ok := fkCols[col]; ok {
+			continue
+		}
+		v, err := seedValue(rng, col, sets.Of(col), i)
+		if err != nil {
+			return nil, err
+		}
+		r[col] = v
+	}
+	for _, con := range fks {
+		candidates := rows[con.Refers[0].Table]
+		if con.Refers[0].Table == tab {
+			candidates = append(slices.Clone(candidates), r)
+		}
+		nullable := !slices.ContainsFunc(con.Constrains, func(col *Column) bool { return col.Attrs.NotNull })
+		if len(candidates) == 0 || (nullable && rng.IntN(10) == 0) {
+			if !nullable {
+				return nil, fmt.Errorf("no rows of %s to refer to from %s", con.Refers[0].Table.Name, con.Name)
+			}
+			for _, col := range con.Constrains {
+				r[col] = seedNull
+			}
+			continue
+		}
+		ref := candidates[rng.IntN(len(candidates))]
+		for k, col := range con.Constrains {
+			r[col] = ref[con.Refers[k]]
+		}
+	}
+	return r, nil
+}
+
+// seedIsUnique checks r against the values already used for each unique
+// key, recording them if r is unique.
+func seedIsUnique(r seedRow, cons Constraints, used map[*Constraint]map[string]struct{}) bool {
+
+	keys := make(map[*Constraint]string)
+	for _, con := range cons {
+		if con.Type != ConstraintTypePrimary && con.Type != ConstraintTypeUnique {
+			continue
+		}
+		values := make([]string, 0, len(con.Constrains))
+		for _, col := range con.Constrains {
+			values = append(values, r[col])
+		}
+		if slices.Contains(values, seedNull) {
+			continue
+		}
+		key := strings.Join(values, "\x00")
+		if _, ok := used[con][key]; ok {
+			return false
+		}
+		keys[con] = key
+	}
+	for con, key := range keys {
+		if used[con] == nil {
+			used[con] = make(map[string]struct{})
+		}
+		used[con][key] = struct{}{}
+	}
+	return true
+}
+
+// seedValue returns an SQL literal suitable for the i'th row of col. If
+// set isn't nil, col is limited to its values, such as the labels of an
+// enum, and one of them is chosen.
+func seedValue(rng *rand.Rand, col *Column, set *ValueSet, i int) (string, error) {
+
+	if override, ok := col.Annotations["seed"]; ok {
+		if choices, ok := strings.CutPrefix(override, "oneof "); ok {
+			options := strings.Split(choices, ",")
+			return QuoteLiteral(strings.TrimSpace(options[rng.IntN(len(options))])), nil
+		}
+		gen, ok := seedTextGenerators[override]
+		if !ok {
+			return "", fmt.Errorf("unknown seed generator %q for column %s.%s", override, col.Table.Name, col.Name)
+		}
+		return QuoteLiteral(seedTruncate(col, gen(rng, i))), nil
+	}
+	if !col.Attrs.NotNull && !col.Attrs.Pkey && rng.IntN(10) == 0 {
+		return seedNull, nil
+	}
+	if col.ArrayDims > 0 {
+		return "'{}'", nil
+	}
+	if set != nil {
+		if len(set.Values) == 0 {
+			return "", fmt.Errorf("column %s.%s is limited to no values", col.Table.Name, col.Name)
+		}
+		return QuoteLiteral(set.Values[rng.IntN(len(set.Values))]), nil
+	}
+	mod := func(n int, def int32) int32 {
+		if len(col.TypeMods) > n {
+			return col.TypeMods[n]
+		}
+		return def
+	}
+
+	switch col.Type {
+	case Serial, Bigserial, Smallserial:
+		return fmt.Sprint(i + 1), nil
+	case Smallint:
+		return fmt.Sprint(rng.IntN(1000)), nil
+	case Integer:
+		return fmt.Sprint(rng.IntN(100000)), nil
+	case Bigint:
+		return fmt.Sprint(rng.IntN(1000000)), nil
+	case Numeric, Money:
+		{
+			precision, scale := mod(0, 10), mod(1, 2)
+			if col.Type == Money {
+				precision, scale = 10, 2
+			}
+			digits := min(precision-scale, 6)
+			v := fmt.Sprint(rng.IntN(pow10(int(digits))))
+			if scale > 0 {
+				v += fmt.Sprintf(".%0*d", scale, rng.IntN(pow10(int(min(scale, 9)))))
+			}
+			return v, nil
+		}
+	case Real, Double:
+		return fmt.Sprintf("%.2f", rng.Float64()*1000), nil
+	case Boolean:
+		return fmt.Sprint(rng.IntN(2) == 0), nil
+	case Date:
+		return QuoteLiteral(seedEpoch.AddDate(0, 0, rng.IntN(1500)).Format(time.DateOnly)), nil
+	case Timestamp:
+		return QuoteLiteral(seedTime(rng).Format(time.DateTime)), nil
+	case Timestamptz:
+		return QuoteLiteral(seedTime(rng).Format(time.DateTime + "Z07:00")), nil
+	case Time, Timetz:
+		return QuoteLiteral(seedTime(rng).Format(time.TimeOnly)), nil
+	case Interval:
+		return QuoteLiteral(fmt.Sprintf("%d days", rng.IntN(365))), nil
+	case UUID:
+		return QuoteLiteral(seedUUID(rng)), nil
+	case JSON, JSONB:
+		return QuoteLiteral(fmt.Sprintf(`{"n": %d}`, i)), nil
+	case Bytea:
+		return QuoteLiteral(fmt.Sprintf(`\x%016x`, rng.Uint64())), nil
+	case Inet:
+		return QuoteLiteral(fmt.Sprintf("10.%d.%d.%d", rng.IntN(256), rng.IntN(256), rng.IntN(256))), nil
+	case CIDR:
+		return QuoteLiteral(fmt.Sprintf("10.%d.%d.0/24", rng.IntN(256), rng.IntN(256))), nil
+	case Macaddr, Macaddr8:
+		{
+			n := 6
+			if col.Type == Macaddr8 {
+				n = 8
+			}
+			parts := make([]string, 0, n)
+			for range n {
+				parts = append(parts, fmt.Sprintf("%02x", rng.IntN(256)))
+			}
+			return QuoteLiteral(strings.Join(parts, ":")), nil
+		}
+	case Bit, BitVarying:
+		{
+			var sb strings.Builder
+			for range mod(0, 8) {
+				sb.WriteByte(byte('0' + rng.IntN(2)))
+			}
+			return QuoteLiteral(sb.String()), nil
+		}
+	case Point:
+		return QuoteLiteral(fmt.Sprintf("(%d,%d)", rng.IntN(100), rng.IntN(100))), nil
+	case Box:
+		return "'(1,1),(0,0)'", nil
+	case Circle:
+		return "'<(0,0),1>'", nil
+	case Line:
+		return "'{1,-1,0}'", nil
+	case Lseg, Path:
+		return "'[(0,0),(1,1)]'", nil
+	case Polygon:
+		return "'((0,0),(1,1),(1,0))'", nil
+	case TSVector, TSQuery:
+		return QuoteLiteral(seedWord(rng)), nil
+	case XML:
+		return QuoteLiteral(fmt.Sprintf("<value>%d</value>", i)), nil
+	case PGLsn:
+		return QuoteLiteral(fmt.Sprintf("0/%X", rng.Uint32())), nil
+	case PGSnapshot, TXIDSnapshot:
+		return "'1:1:'", nil
+	}
+	return QuoteLiteral(seedTruncate(col, seedText(rng, col.Name, i))), nil
+}
+
+func pow10(n int) int {
+
+	ret := 1
+	for range n {
+		ret *= 10
+	}
+	return ret
+}
+
+func seedTime(rng *rand.Rand) time.Time {
+
+	return seedEpoch.Add(time.Duration(rng.Int64N(int64(4 * 365 * 24 * time.Hour))).Truncate(time.Second))
+}
+
+func seedUUID(rng *rand.Rand) string {
+
+	hi, lo := rng.Uint64(), rng.Uint64()
+	hi = hi&^0xf000 | 0x4000 // version 4
+	lo = lo&^(0xc<<60) | 0x8<<60
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x", hi>>32, (hi>>16)&0xffff, hi&0xffff, lo>>48, lo&0xffffffffffff)
+}
+
+// seedTruncate shortens s to fit the length of character(n) and
+// character varying(n) columns.
+func seedTruncate(col *Column, s string) string {
+
+	if (col.Type == Character || col.Type == CharacterVarying) && len(col.TypeMods) > 0 {
+		if r := []rune(s); len(r) > int(col.TypeMods[0]) {
+			return string(r[:col.TypeMods[0]])
+		}
+	}
+	return s
+}
+
+var (
+	seedFirstNames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy", "Mallory", "Niaj", "Olivia", "Peggy", "Rupert", "Sybil", "Trent", "Victor", "Walter"}
+	seedLastNames  = []string{"Smith", "Jones", "Taylor", "Brown", "Williams", "Wilson", "Johnson", "Davies", "Robinson", "Wright", "Thompson", "Evans", "Walker", "White", "Roberts", "Green", "Hall", "Wood"}
+	seedCities     = []string{"Sydney", "Melbourne", "London", "Paris", "Berlin", "Tokyo", "Toronto", "Chicago", "Madrid", "Lisbon", "Auckland", "Dublin"}
+	seedCountries  = []string{"Australia", "United Kingdom", "France", "Germany", "Japan", "Canada", "United States", "Spain", "Portugal", "New Zealand", "Ireland"}
+	seedWords      = []string{"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do", "eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi"}
+)
+
+func seedPick(rng *rand.Rand, from []string) string {
+
+	return from[rng.IntN(len(from))]
+}
+
+func seedWord(rng *rand.Rand) string {
+
+	return seedPick(rng, seedWords)
+}
+
+func seedWordsN(rng *rand.Rand, n int) string {
+
+	words := make([]string, 0, n)
+	for range n {
+		words = append(words, seedWord(rng))
+	}
+	return strings.Join(words, " ")
+}
+
+// seedTextGenerators produce realistic text values. They are chosen by
+// column name, or explicitly with a "@seed" annotation.
+var seedTextGenerators = map[string]func(rng *rand.Rand, i int) string{
+	"city":    func(rng *rand.Rand, i int) string { return seedPick(rng, seedCities) },
+	"country": func(rng *rand.Rand, i int) string { return seedPick(rng, seedCountries) },
+	"email": func(rng *rand.Rand, i int) string {
+		return fmt.Sprintf("%s%d@example.com", strings.ToLower(seedPick(rng, seedFirstNames)), i+1)
+	},
+	"first_name": func(rng *rand.Rand, i int) string { return seedPick(rng, seedFirstNames) },
+	"last_name":  func(rng *rand.Rand, i int) string { return seedPick(rng, seedLastNames) },
+	"name": func(rng *rand.Rand, i int) string {
+		return seedPick(rng, seedFirstNames) + " " + seedPick(rng, seedLastNames)
+	},
+	"paragraph": func(rng *rand.Rand, i int) string { return seedWordsN(rng, 12) },
+	"phone":     func(rng *rand.Rand, i int) string { return fmt.Sprintf("+1 555 %04d", rng.IntN(10000)) },
+	"slug":      func(rng *rand.Rand, i int) string { return fmt.Sprintf("%s-%s-%d", seedWord(rng), seedWord(rng), i+1) },
+	"title":     func(rng *rand.Rand, i int) string { return seedWordsN(rng, 3) },
+	"url": func(rng *rand.Rand, i int) string {
+		return fmt.Sprintf("https://example.com/%s/%d", seedWord(rng), i+1)
+	},
+	"uuid": func(rng *rand.Rand, i int) string { return seedUUID(rng) },
+	"word": func(rng *rand.Rand, i int) string { return seedWord(rng) },
+}
+
+// seedNameHints maps fragments of column names to the text generator used
+// for them, checked in order.
+var seedNameHints = []struct {
+	Fragment  string
+	Generator string
+}{
+	{"email", "email"},
+	{"first_name", "first_name"},
+	{"last_name", "last_name"},
+	{"surname", "last_name"},
+	{"name", "name"},
+	{"phone", "phone"},
+	{"url", "url"},
+	{"website", "url"},
+	{"city", "city"},
+	{"country", "country"},
+	{"slug", "slug"},
+	{"title", "title"},
+	{"description", "paragraph"},
+	{"body", "paragraph"},
+	{"content", "paragraph"},
+	{"notes", "paragraph"},
+}
+
+func seedText(rng *rand.Rand, colName string, i int) string {
+
+	colName = strings.ToLower(colName)
+	for _, hint := range seedNameHints {
+		if strings.Contains(colName, hint.Fragment) {
+			return seedTextGenerators[hint.Generator](rng, i)
+		}
+	}
+	return seedWordsN(rng, 2)
+}
+
+// SortTablesByDependency orders the tables of cat such that every table
+// comes after the tables its foreign keys refer to. Self-references are
+// ignored; other cycles are an error.
+func SortTablesByDependency(cat *Catalog) ([]*Table, error) {
+
+	var pending []*Table
+	deps := make(map[*Table]map[*Table]struct{})
+	for _, sch := range cat.Schemas.List() {
+		for _, tab := range sch.Tables.List() {
+			pending = append(pending, tab)
+			deps[tab] = make(map[*Table]struct{})
+			for _, con := range cat.Depends.TableConstraints(tab) {
+				if con.Type == ConstraintTypeForeignKey && con.Refers[0].Table != tab {
+					deps[tab][con.Refers[0].Table] = struct{}{}
+				}
+			}
+		}
+	}
+	ret := make([]*Table, 0, len(pending))
+	done := make(map[*Table]struct{})
+	for len(pending) > 0 {
+		idx := slices.IndexFunc(pending, func(tab *Table) bool {
+			for dep := range deps[tab] {
+				if _, ok := done[dep]; !ok {
+					return false
+				}
+			}
+			return true
+		})
+		if idx < 0 {
+			names := make([]string, 0, len(pending))
+			for _, tab := range pending {
+				names = append(names, tab.Name)
+			}
+			return nil, fmt.Errorf("foreign keys form a cycle between tables %s", strings.Join(names, ", "))
+		}
+		ret = append(ret, pending[idx])
+		done[pending[idx]] = struct{}{}
+		pending = slices.Delete(pending, idx, idx+1)
+	}
+	return ret, nil
+}
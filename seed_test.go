@@ -0,0 +1,88 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestSeedGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TABLE orders (
+		id bigserial primary key,
+		user_id int not null,
+		-- @seed oneof new, paid
+		status varchar(4) not null
+	);
+
+	CREATE TABLE users (
+		id serial primary key,
+		email text not null unique,
+		enabled boolean not null unique,
+		bio text -- @seed skip
+	);
+
+	ALTER TABLE orders ADD FOREIGN KEY (user_id) REFERENCES users (id);
+	`
+	c := NewCompiler()
+	require.Nil(t, c.Compile(sql))
+
+	var sb strings.Builder
+	err := (&SeedGenerator{Rows: 5, Seed: 1}).Generate(&sb, c.Catalog)
+	require.Nil(t, err)
+	out := sb.String()
+
+	// users must be seeded first despite being defined second, and can only
+	// have two rows because of the unique boolean
+	usersAt := strings.Index(out, "INSERT INTO users (id, email, enabled) VALUES")
+	ordersAt := strings.Index(out, "INSERT INTO orders (id, user_id, status) VALUES")
+	require.True(t, usersAt >= 0 && ordersAt >= 0, out)
+	assert.Less(t, usersAt, ordersAt)
+	assert.Contains(t, out, "-- Only 2 unique rows could be generated for users")
+	assert.Contains(t, out, "SELECT setval(pg_get_serial_sequence('users', 'id'), 2);")
+	assert.Contains(t, out, "SELECT setval(pg_get_serial_sequence('orders', 'id'), 5);")
+	assert.Contains(t, out, "@example.com")
+	for _, line := range strings.Split(out[ordersAt:], "\n")[1:6] {
+		assert.Regexp(t, `^    \(\d, [12], '(new|paid)'\)[,;]$`, line)
+	}
+
+	var again strings.Builder
+	err = (&SeedGenerator{Rows: 5, Seed: 1}).Generate(&again, c.Catalog)
+	require.Nil(t, err)
+	assert.Equal(t, out, again.String())
+}
+
+func TestSeedGenerator_ValueSets(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE TABLE people (
+		id serial PRIMARY KEY,
+		mood mood NOT NULL,
+		size text NOT NULL CHECK (size IN ('s', 'm', 'l'))
+	);
+	`))
+
+	var sb strings.Builder
+	require.Nil(t, (&SeedGenerator{Rows: 20, Seed: 1}).Generate(&sb, c.Catalog))
+	lines := strings.Split(strings.TrimSpace(sb.String()), "\n")
+	require.Equal(t, "INSERT INTO people (id, mood, size) VALUES", lines[0])
+	for _, line := range lines[1:21] {
+		values := strings.Split(strings.Trim(strings.TrimSpace(line), "(),;"), ", ")
+		require.Len(t, values, 3, line)
+		assert.Contains(t, []string{"'happy'", "'sad'"}, values[1])
+		assert.Contains(t, []string{"'s'", "'m'", "'l'"}, values[2])
+	}
+}
+
+func TestSortTablesByDependency_Cycle(t *testing.T) {
+	const sql = `
+	CREATE TABLE a (id int primary key, b_id int);
+	CREATE TABLE b (id int primary key, a_id int references a (id));
+	ALTER TABLE a ADD FOREIGN KEY (b_id) REFERENCES b (id);
+	`
+	c := assertParse(t, sql)
+	_, err := SortTablesByDependency(c.Catalog)
+	assert.ErrorContains(t, err, "foreign keys form a cycle between tables a, b")
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"slices"
+	"strings"
+)
+
+// Sequences aren't modeled, but columns taking their default from one are
+// tracked so that dropping the sequence can be checked, and so that serial
+// columns compare equal to the integer columns with a nextval default that
+// pg_dump writes them as. Sequences created by CREATE SEQUENCE are recorded
+// with the column owning them, so that those nothing uses can be found,
+// but their options aren't, so the statements are still skipped.
+
+// Sequence is a sequence created by CREATE SEQUENCE. The implicit
+// sequences of serial columns aren't recorded.
+type Sequence struct {
+	OID    OID
+	Schema string
+	Name   string
+	// OwnedBy is the column the sequence is dropped with, or nil.
+	OwnedBy *Column
+	// Owner is the role owning the sequence, which isn't OwnedBy.
+	Owner string
+	// Unlogged is set for sequences created UNLOGGED, or altered to be.
+	Unlogged bool
+	Defined  SourceLocation
+}
+
+// CreateSequence records a CREATE SEQUENCE.
+func (c *Compiler) CreateSequence(stmt *pg_query.CreateSeqStmt) error {
+
+	schema, err := c.createSchema(stmt.Sequence)
+	if err != nil {
+		return err
+	}
+	sch, ok := c.Catalog.Schemas.Get(schema)
+	if !ok {
+		return fmt.Errorf("couldn't find schema %s", schema)
+	}
+	seq := &Sequence{
+		Schema:   schema,
+		Name:     stmt.Sequence.Relname,
+		Unlogged: stmt.Sequence.Relpersistence == persistenceUnlogged,
+		Defined:  c.sourceLocation(stmt.Sequence.Location),
+	}
+	if orig, ok := sch.Sequences.Get(seq.Name); ok {
+		if stmt.IfNotExists {
+			return nil
+		}
+		return fmt.Errorf("sequence already exists: %s%s", seq.Name, duplicateLocations(orig.Defined, seq.Defined))
+	}
+	err = c.setSequenceOwner(seq, stmt.Options)
+	if err != nil {
+		return err
+	}
+	seq.OID = c.Catalog.newOID()
+	sch.Sequences.Add(seq.Name, seq)
+	return nil
+}
+
+// AlterSequence records a change of the column owning a sequence. Serial
+// columns' sequences aren't recorded, so altering one does nothing.
+func (c *Compiler) AlterSequence(stmt *pg_query.AlterSeqStmt) error {
+
+	seq := c.findSequence(stmt.Sequence.Schemaname, stmt.Sequence.Relname)
+	if seq == nil {
+		return nil
+	}
+	return c.setSequenceOwner(seq, stmt.Options)
+}
+
+// AlterSequenceRelation handles the forms of ALTER SEQUENCE which Postgres
+// treats as altering a relation, of which only SET LOGGED and SET
+// UNLOGGED are recorded.
+func (c *Compiler) AlterSequenceRelation(stmt *pg_query.AlterTableStmt) error {
+
+	seq := c.findSequence(stmt.Relation.Schemaname, stmt.Relation.Relname)
+	if seq == nil {
+		if stmt.MissingOk {
+			return nil
+		}
+		// Serial columns' sequences aren't recorded
+		c.skip("ALTER SEQUENCE", "")
+		return nil
+	}
+	for _, cmd := range stmt.Cmds {
+		atc := cmd.GetAlterTableCmd()
+		switch atc.GetSubtype() {
+		case pg_query.AlterTableType_AT_SetLogged, pg_query.AlterTableType_AT_SetUnLogged:
+			{
+				if seq.Schema == TempSchema {
+					return fmt.Errorf("cannot change logged status of sequence %s because it is temporary", seq.Name)
+				}
+				seq.Unlogged = atc.Subtype == pg_query.AlterTableType_AT_SetUnLogged
+			}
+		default:
+			c.skip("ALTER SEQUENCE "+upperWords(strings.TrimPrefix(atc.GetSubtype().String(), "AT_")), "")
+		}
+	}
+	return nil
+}
+
+func (c *Compiler) setSequenceOwner(seq *Sequence, options []*pg_query.Node) error {
+
+	for _, n := range options {
+		def := n.GetDefElem()
+		if def == nil || def.Defname != "owned_by" {
+			continue
+		}
+		names, err := NodeStrings(def.Arg.GetList().GetItems())
+		if err != nil {
+			return err
+		}
+		if len(names) == 1 && names[0] == "none" {
+			seq.OwnedBy = nil
+			continue
+		}
+		if len(names) < 2 {
+			return fmt.Errorf("invalid OWNED BY option")
+		}
+		var schema string
+		if len(names) > 2 {
+			schema = names[len(names)-3]
+		}
+		t, err := c.FindTableFromSchemaAndName(schema, names[len(names)-2])
+		if err != nil {
+			return err
+		}
+		col, ok := t.Columns.Get(names[len(names)-1])
+		if !ok {
+			return fmt.Errorf("column %s of table %s does not exist", names[len(names)-1], t.Name)
+		}
+		seq.OwnedBy = col
+	}
+	return nil
+}
+
+// SequenceIdent returns the quoted name of seq, qualified with its schema
+// unless it lives in public.
+func SequenceIdent(seq *Sequence) string {
+
+	if seq.Schema == "public" {
+		return QuoteIdent(seq.Name)
+	}
+	return QuoteIdent(seq.Schema) + "." + QuoteIdent(seq.Name)
+}
+
+// SequenceDefinition renders the CREATE SEQUENCE statement creating seq.
+// Its options aren't recorded, so it starts from the defaults. The column
+// owning it may not exist yet, so that's left to be set by altering it.
+func SequenceDefinition(seq *Sequence) string {
+
+	if seq.Unlogged {
+		return "CREATE UNLOGGED SEQUENCE " + SequenceIdent(seq)
+	}
+	return "CREATE SEQUENCE " + SequenceIdent(seq)
+}
+
+// sequenceOwner renders the OWNED BY option of seq.
+func sequenceOwner(seq *Sequence) string {
+
+	if seq.OwnedBy == nil {
+		return "NONE"
+	}
+	return TableIdent(seq.OwnedBy.Table) + "." + QuoteIdent(seq.OwnedBy.Name)
+}
+
+func (c *Compiler) findSequence(schema, name string) *Sequence {
+
+	schema = c.relationSchema(schema, name)
+	sch, ok := c.Catalog.Schemas.Get(schema)
+	if !ok {
+		return nil
+	}
+	seq, _ := sch.Sequences.Get(name)
+	return seq
+}
+
+// RenameSequence renames a recorded sequence, and the defaults of the
+// columns taking their values from it.
+func (c *Compiler) RenameSequence(r *pg_query.RangeVar, newName string) error {
+
+	schema := c.relationSchema(r.Schemaname, r.Relname)
+	sch, ok := c.Catalog.Schemas.Get(schema)
+	if !ok {
+		return fmt.Errorf("couldn't find schema %s", schema)
+	}
+	if orig, ok := sch.Sequences.Get(newName); ok {
+		return fmt.Errorf("sequence already exists: %s%s", newName, duplicateLocations(orig.Defined, c.stmtLocation()))
+	}
+	if seq, ok := sch.Sequences.Get(r.Relname); ok {
+		sch.Sequences.Rename(seq.Name, newName)
+		seq.Name = newName
+	}
+	// Defaults refer to the sequence itself rather than its name
+	oldName, name := schema+"."+r.Relname, schema+"."+newName
+	for _, col := range FindColumns(c.Catalog) {
+		if col.Attrs.Sequence == oldName && col.Attrs.Default != "" {
+			col.Attrs.Default, col.Attrs.Sequence = nextvalDefault(name), name
+		}
+	}
+	return nil
+}
+
+// dropOwnedSequences drops the sequences owned by cols, which are being
+// dropped.
+func (c *Compiler) dropOwnedSequences(cols Columns) {
+
+	for _, sch := range c.Catalog.Schemas.List() {
+		for _, seq := range slices.Clone(sch.Sequences.List()) {
+			if seq.OwnedBy != nil && slices.Contains(cols, seq.OwnedBy) {
+				sch.Sequences.Remove(seq.Name)
+			}
+		}
+	}
+}
+
+// Sequence returns the schema qualified name of the sequence the column's
+// values are taken from, or empty if there isn't one.
+func (c *Column) Sequence() string {
+
+	if c.Attrs.Sequence != "" {
+		return c.Attrs.Sequence
+	}
+	if isSerial(c.Type) {
+		return c.Table.Schema + "." + makeObjectName(c.Table.Name, c.Name, "seq")
+	}
+	return ""
+}
+
+// pinSequence records the name of a serial column's sequence before the
+// column or its table is renamed, since renaming doesn't rename the
+// sequence.
+func (c *Column) pinSequence() {
+
+	c.Attrs.Sequence = c.Sequence()
+}
+
+// setDefault sets the column's default to the expression def, or removes
+// it if def is nil.
+func (c *Compiler) setDefault(col *Column, def *pg_query.Node) error {
+
+	if def != nil && col.Attrs.Identity != IdentityNone {
+		return fmt.Errorf("column %s of table %s is an identity column", col.Name, col.Table.Name)
+	}
+	col.expandSerial()
+	col.Attrs.Default, col.Attrs.Sequence = "", ""
+	if def == nil {
+		return nil
+	}
+	var err error
+	col.Attrs.Default, err = DeparseExpr(def)
+	if err != nil {
+		return err
+	}
+	col.Attrs.Sequence = c.nextvalSequence(def)
+	return nil
+}
+
+// nextvalSequence returns the schema qualified name of the sequence if n is
+// a call of nextval on a constant, or empty otherwise.
+func (c *Compiler) nextvalSequence(n *pg_query.Node) string {
+
+	fn := n.GetFuncCall()
+	if fn == nil || len(fn.Args) != 1 {
+		return ""
+	}
+	names, err := NodeStrings(fn.Funcname)
+	if err != nil || names[len(names)-1] != "nextval" || (len(names) == 2 && names[0] != "pg_catalog") {
+		return ""
+	}
+	arg := fn.Args[0]
+	if cast := arg.GetTypeCast(); cast != nil {
+		arg = cast.Arg
+	}
+	val := arg.GetAConst().GetSval()
+	if val == nil {
+		return ""
+	}
+	return c.relationName(splitRegclass(val.Sval))
+}
+
+// nextvalDefault returns the default taking values from the sequence seq.
+func nextvalDefault(seq string) string {
+
+	return fmt.Sprintf("nextval('%s'::regclass)", strings.ReplaceAll(seq, "'", "''"))
+}
+
+// splitRegclass splits the name of a relation given as text, such as
+// 'public."Users_id_seq"', into its parts, folding unquoted parts to lower
+// case as Postgres does.
+func splitRegclass(s string) []string {
+
+	var parts []string
+	var sb strings.Builder
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		switch {
+		case ch == '"' && quoted && i+1 < len(s) && s[i+1] == '"':
+			sb.WriteByte('"')
+			i++
+		case ch == '"':
+			quoted = !quoted
+		case ch == '.' && !quoted:
+			parts = append(parts, sb.String())
+			sb.Reset()
+		case !quoted:
+			sb.WriteString(strings.ToLower(string(ch)))
+		default:
+			sb.WriteByte(ch)
+		}
+	}
+	return append(parts, sb.String())
+}
+
+// DropSequence handles DROP SEQUENCE by removing the defaults of the columns
+// using the sequence if the drop cascades, and otherwise failing if any do.
+// The sequence itself isn't modeled, so it's skipped.
+func (c *Compiler) DropSequence(names []string, behav DropBehaviour) error {
+
+	name := c.relationName(names)
+	var cols Columns
+	for _, sch := range c.Catalog.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			for _, col := range t.Columns.List() {
+				if col.Sequence() == name {
+					cols = append(cols, col)
+				}
+			}
+		}
+	}
+	for _, col := range cols {
+		if behav != DropBehaviourCascade {
+			return fmt.Errorf("can't drop sequence %s because default value for column %s of table %s depends on it and cascade was not specified",
+				name, col.Name, col.Table.Name)
+		}
+		col.expandSerial()
+		col.Attrs.Default, col.Attrs.Sequence = "", ""
+	}
+	var schema string
+	if len(names) > 1 {
+		schema = names[len(names)-2]
+	}
+	if seq := c.findSequence(schema, names[len(names)-1]); seq != nil {
+		sch, _ := c.Catalog.Schemas.Get(seq.Schema)
+		sch.Sequences.Remove(seq.Name)
+	}
+	return nil
+}
+
+// expandSerial makes a serial column the integer column it was created as,
+// once its default is changed.
+func (c *Column) expandSerial() {
+
+	if typ, ok := serialTypes[c.Type]; ok {
+		c.SetType(typ, c.TypeMods, c.ArrayDims)
+		c.Attrs.NotNull = true
+	}
+}
+
+// addIdentity makes col an identity column, generating its values always
+// or by default as when says.
+func addIdentity(col *Column, when string) error {
+
+	switch {
+	case col.Attrs.Identity != IdentityNone:
+		return fmt.Errorf("column %s of table %s is already an identity column", col.Name, col.Table.Name)
+	case col.Attrs.Default != "" || isSerial(col.Type):
+		return fmt.Errorf("column %s of table %s already has a default value", col.Name, col.Table.Name)
+	case col.ArrayDims > 0 || (col.Type != Smallint && col.Type != Integer && col.Type != Bigint):
+		return fmt.Errorf("identity column type must be smallint, integer, or bigint")
+	}
+	col.Attrs.Identity = IdentityByDefault
+	if when == "a" {
+		col.Attrs.Identity = IdentityAlways
+	}
+	return nil
+}
+
+// setIdentity handles ALTER COLUMN ... SET GENERATED and the options of an
+// identity column's sequence, which aren't modeled any more than those of
+// other sequences are.
+func setIdentity(col *Column, opts []*pg_query.Node) error {
+
+	if col.Attrs.Identity == IdentityNone {
+		return fmt.Errorf("column %s of table %s is not an identity column", col.Name, col.Table.Name)
+	}
+	for _, opt := range opts {
+		def := opt.GetDefElem()
+		if def.GetDefname() != "generated" {
+			continue
+		}
+		col.Attrs.Identity = IdentityByDefault
+		if def.GetArg().GetInteger().GetIval() == 'a' {
+			col.Attrs.Identity = IdentityAlways
+		}
+	}
+	return nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestColumn_Sequence(t *testing.T) {
+	c := assertParse(t, `
+	CREATE SCHEMA app;
+	CREATE TABLE app.users (id serial, name text);
+	CREATE SEQUENCE "Ticket_Seq";
+	CREATE TABLE tickets (id bigint DEFAULT nextval('"Ticket_Seq"'::regclass), n int DEFAULT nextval('App.Counter') + 1);
+	`)
+	id, _ := assertTable(t, c, "app.users").Columns.Get("id")
+	assert.Equal(t, "app.users_id_seq", id.Sequence())
+	tickets := assertTable(t, c, "public.tickets")
+	tid, _ := tickets.Columns.Get("id")
+	assert.Equal(t, "public.Ticket_Seq", tid.Sequence())
+	// Only defaults which are just nextval take their values from a sequence
+	n, _ := tickets.Columns.Get("n")
+	assert.Empty(t, n.Sequence())
+
+	// Renaming doesn't rename a serial column's sequence
+	require.Nil(t, c.Compile(`ALTER TABLE app.users RENAME TO accounts; ALTER TABLE app.accounts RENAME id TO account_id`))
+	assert.Equal(t, "app.users_id_seq", id.Sequence())
+
+	require.Nil(t, c.Compile(`ALTER TABLE tickets ALTER COLUMN id DROP DEFAULT`))
+	assert.Empty(t, tid.Sequence())
+}
+
+func TestCompiler_DropSequence(t *testing.T) {
+	assertParseError(t, `
+	CREATE TABLE users (id serial);
+	DROP SEQUENCE users_id_seq;
+	`, "can't drop sequence public.users_id_seq because default value for column id of table users depends on it")
+
+	c := assertParse(t, `
+	CREATE SEQUENCE counter;
+	CREATE TABLE users (id serial, n int DEFAULT nextval('counter'));
+	DROP SEQUENCE users_id_seq, public.counter CASCADE;
+	DROP SEQUENCE unused;
+	`)
+	users := assertTable(t, c, "public.users")
+	id, _ := users.Columns.Get("id")
+	assert.Equal(t, Integer, id.Type)
+	n, _ := users.Columns.Get("n")
+	assert.Empty(t, n.Attrs.Default)
+}
+
+func TestCompiler_RecordsSequences(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int);
+	CREATE SEQUENCE users_seq OWNED BY users.id;
+	CREATE SEQUENCE IF NOT EXISTS users_seq;
+	CREATE TABLE tickets (id int DEFAULT nextval('ticket_seq'));
+	CREATE SEQUENCE ticket_seq;
+	ALTER SEQUENCE ticket_seq RENAME TO tickets_id_seq;
+	`)
+	sch, _ := c.Catalog.Schemas.Get("public")
+	seq, ok := sch.Sequences.Get("users_seq")
+	require.True(t, ok)
+	id, _ := assertTable(t, c, "public.users").Columns.Get("id")
+	assert.Equal(t, id, seq.OwnedBy)
+	_, ok = sch.Sequences.Get("tickets_id_seq")
+	assert.True(t, ok)
+	tid, _ := assertTable(t, c, "public.tickets").Columns.Get("id")
+	assert.Equal(t, "public.tickets_id_seq", tid.Sequence())
+	assert.Equal(t, "nextval('public.tickets_id_seq'::regclass)", tid.Attrs.Default)
+
+	require.Nil(t, c.Compile(`ALTER SEQUENCE users_seq OWNED BY NONE; DROP TABLE users; DROP SEQUENCE tickets_id_seq CASCADE;`))
+	assert.Len(t, sch.Sequences.List(), 1)
+	require.Nil(t, c.Compile(`ALTER SEQUENCE users_seq OWNED BY tickets.id; DROP TABLE tickets;`))
+	assert.Empty(t, sch.Sequences.List())
+}
+
+func TestDiff_SerialColumns(t *testing.T) {
+	compile := func(sql string) *Catalog {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c.Catalog
+	}
+	serial := compile(`CREATE TABLE users (id serial PRIMARY KEY, name text);`)
+	// As pg_dump writes it
+	dumped := compile(`
+CREATE TABLE users (id integer NOT NULL, name text);
+CREATE SEQUENCE public.users_id_seq AS integer;
+ALTER TABLE ONLY users ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);
+ALTER TABLE ONLY users ADD CONSTRAINT users_pkey PRIMARY KEY (id);
+`)
+	assert.Empty(t, Diff(serial, dumped, DiffOptions{}))
+	assert.Empty(t, Diff(dumped, serial, DiffOptions{}))
+
+	other := compile(`CREATE TABLE users (id integer NOT NULL DEFAULT nextval('other_seq'), name text, PRIMARY KEY (id));`)
+	changes := Diff(serial, other, DiffOptions{})
+	require.Len(t, changes, 1)
+	assert.Equal(t, []string{"ALTER TABLE users ALTER COLUMN id SET DEFAULT nextval('public.other_seq'::regclass);"}, changes[0].SQL())
+}
+
+func TestCompiler_IdentityColumns(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id serial PRIMARY KEY, n int GENERATED BY DEFAULT AS IDENTITY);
+	ALTER TABLE users ALTER COLUMN id DROP DEFAULT;
+	DROP SEQUENCE users_id_seq;
+	ALTER TABLE users ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY;
+	`)
+	users := assertTable(t, c, "public.users")
+	id, _ := users.Columns.Get("id")
+	assert.Equal(t, Integer, id.Type)
+	assert.Equal(t, IdentityAlways, id.Attrs.Identity)
+	n, _ := users.Columns.Get("n")
+	assert.True(t, n.Attrs.NotNull)
+	assert.Equal(t, IdentityByDefault, n.Attrs.Identity)
+
+	require.Nil(t, c.Compile(`
+	ALTER TABLE users ALTER COLUMN id SET GENERATED BY DEFAULT, ALTER COLUMN id RESTART WITH 100;
+	ALTER TABLE users ALTER COLUMN n DROP IDENTITY, ALTER COLUMN n DROP IDENTITY IF EXISTS;
+	`))
+	assert.Equal(t, IdentityByDefault, id.Attrs.Identity)
+	assert.Equal(t, IdentityNone, n.Attrs.Identity)
+
+	assert.ErrorContains(t, c.Compile(`ALTER TABLE users ALTER COLUMN id SET DEFAULT 1;`), "column id of table users is an identity column")
+	assert.ErrorContains(t, c.Compile(`ALTER TABLE users ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY;`), "column id of table users is already an identity column")
+	assert.ErrorContains(t, c.Compile(`ALTER TABLE users ALTER COLUMN n DROP IDENTITY;`), "column n of table users is not an identity column")
+	assert.ErrorContains(t, c.Compile(`ALTER TABLE users ADD COLUMN m text NOT NULL, ALTER COLUMN m ADD GENERATED ALWAYS AS IDENTITY;`),
+		"identity column type must be smallint, integer, or bigint")
+	assert.ErrorContains(t, c.Compile(`ALTER TABLE users ADD COLUMN m int, ALTER COLUMN m ADD GENERATED ALWAYS AS IDENTITY;`),
+		"column m of table users must be declared not null before identity can be added")
+	_, ok := users.Columns.Get("m")
+	assert.False(t, ok)
+}
+
+func TestDiff_IdentityColumns(t *testing.T) {
+	compile := func(sql string) *Catalog {
+		t.Helper()
+		c := NewCompiler()
+		require.Nil(t, c.Compile(sql))
+		return c.Catalog
+	}
+	serial := compile(`CREATE TABLE users (id serial PRIMARY KEY);`)
+	always := compile(`CREATE TABLE users (id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY);`)
+	byDefault := compile(`CREATE TABLE users (id int GENERATED BY DEFAULT AS IDENTITY PRIMARY KEY);`)
+
+	changes := Diff(serial, always, DiffOptions{})
+	require.Len(t, changes, 1)
+	assert.Equal(t, []string{"ALTER TABLE users ALTER COLUMN id DROP DEFAULT, ALTER COLUMN id ADD GENERATED ALWAYS AS IDENTITY;"}, changes[0].SQL())
+	safety, reason := changes[0].Classify()
+	assert.Equal(t, SafetyIncompatible, safety)
+	assert.Equal(t, "writes setting the column will fail unless they override the identity", reason)
+
+	changes = Diff(serial, byDefault, DiffOptions{})
+	require.Len(t, changes, 1)
+	safety, _ = changes[0].Classify()
+	assert.Equal(t, SafetySafe, safety)
+
+	changes = Diff(always, byDefault, DiffOptions{})
+	require.Len(t, changes, 1)
+	assert.Equal(t, []string{"ALTER TABLE users ALTER COLUMN id SET GENERATED BY DEFAULT;"}, changes[0].SQL())
+
+	changes = Diff(byDefault, serial, DiffOptions{})
+	require.Len(t, changes, 1)
+	assert.Equal(t, []string{"ALTER TABLE users ALTER COLUMN id DROP IDENTITY, ALTER COLUMN id SET DEFAULT nextval('public.users_id_seq'::regclass);"}, changes[0].SQL())
+
+	tab := assertTable(t, &Compiler{Catalog: always}, "public.users")
+	col, _ := tab.Columns.Get("id")
+	assert.Equal(t, "id integer NOT NULL GENERATED ALWAYS AS IDENTITY", ColumnDefinition(col))
+}
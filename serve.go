@@ -0,0 +1,353 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"google.golang.org/grpc"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// Server serves the compiler over HTTP, for tools which can't embed it.
+// Each endpoint takes SQL in a POST body and responds with JSON:
+//
+//	/catalog compiles the body and responds with the catalog
+//	/lint compiles the body and responds with its diagnostics and smells
+//	/diff compiles the from and to of a DiffRequest and responds with the
+//	changes between them
+//
+// A body which fails to compile gets a 422 response with the diagnostics.
+type Server struct {
+	// NewCompiler returns the compiler each submission is compiled with.
+	NewCompiler func() *Compiler
+	// MaxBytes limits the size of request bodies, or is 0 for no limit.
+	MaxBytes int64
+	// Timeout limits how long compiling a request may take, or is 0 for no
+	// limit.
+	Timeout time.Duration
+	// Classifier classifies the columns of each submission.
+	Classifier *Classifier
+	mux        *http.ServeMux
+}
+
+func NewServer() *Server {
+
+	s := &Server{
+		NewCompiler: func() *Compiler { return NewCompiler() },
+		MaxBytes:    16 << 20,
+		Classifier:  &Classifier{},
+		mux:         http.NewServeMux(),
+	}
+	s.mux.HandleFunc("POST /catalog", s.serveCatalog)
+	s.mux.HandleFunc("POST /lint", s.serveLint)
+	s.mux.HandleFunc("POST /diff", s.serveDiff)
+	return s
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	s.mux.ServeHTTP(w, r)
+}
+
+// DiffRequest is the body of a request to /diff.
+type DiffRequest struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	// Renames is how eagerly renames are detected, as for the diff
+	// command's -renames flag.
+	Renames   string `json:"renames,omitempty"`
+	MatchOIDs bool   `json:"match_oids,omitempty"`
+}
+
+// ServedChange is a change in the response to a request to /diff.
+type ServedChange struct {
+	Change string   `json:"change"`
+	Safety string   `json:"safety"`
+	Reason string   `json:"reason,omitempty"`
+	SQL    []string `json:"sql"`
+}
+
+// ServedError is the response to a request which failed.
+type ServedError struct {
+	Error       string        `json:"error"`
+	Diagnostics []*Diagnostic `json:"diagnostics,omitempty"`
+}
+
+// ServedCatalog is the catalog as the /catalog endpoint serves it.
+type ServedCatalog struct {
+	Schemas []ServedSchema `json:"schemas"`
+}
+
+type ServedSchema struct {
+	Name   string        `json:"name"`
+	Tables []ServedTable `json:"tables"`
+}
+
+type ServedTable struct {
+	OID             OID                `json:"oid"`
+	Name            string             `json:"name"`
+	Columns         []ServedColumn     `json:"columns"`
+	Constraints     []ServedDefinition `json:"constraints,omitempty"`
+	Indexes         []ServedDefinition `json:"indexes,omitempty"`
+	ReplicaIdentity string             `json:"replica_identity,omitempty"`
+}
+
+type ServedColumn struct {
+	OID      OID    `json:"oid"`
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Nullable bool   `json:"nullable"`
+	Default  string `json:"default,omitempty"`
+	Class    string `json:"class,omitempty"`
+}
+
+// ServedDefinition is a constraint or index, with the SQL defining it.
+type ServedDefinition struct {
+	OID        OID    `json:"oid"`
+	Name       string `json:"name"`
+	Definition string `json:"definition"`
+}
+
+// NewServedCatalog returns cat as it's served as JSON.
+func NewServedCatalog(cat *Catalog) *ServedCatalog {
+
+	ret := &ServedCatalog{Schemas: []ServedSchema{}}
+	for _, sch := range cat.Schemas.List() {
+		schema := ServedSchema{Name: sch.Name, Tables: []ServedTable{}}
+		for _, t := range sch.Tables.List() {
+			table := ServedTable{OID: t.OID, Name: t.Name, Columns: []ServedColumn{}}
+			for _, col := range t.Columns.List() {
+				table.Columns = append(table.Columns, ServedColumn{
+					OID:      col.OID,
+					Name:     col.Name,
+					Type:     col.FormatType(),
+					Nullable: ColumnNullable(true)(col),
+					Default:  col.Attrs.Default,
+					Class:    col.Classification.Class,
+				})
+			}
+			for _, con := range cat.Depends.TableConstraints(t) {
+				table.Constraints = append(table.Constraints, ServedDefinition{OID: con.OID, Name: con.Name, Definition: ConstraintDefinition(con)})
+			}
+			for _, idx := range cat.Depends.TableIndexes(t) {
+				table.Indexes = append(table.Indexes, ServedDefinition{OID: idx.OID, Name: idx.Name, Definition: IndexDefinition(idx)})
+			}
+			if t.ReplicaIdentity != ReplicaIdentityDefault {
+				table.ReplicaIdentity = t.ReplicaIdentity.String()
+			}
+			schema.Tables = append(schema.Tables, table)
+		}
+		ret.Schemas = append(ret.Schemas, schema)
+	}
+	return ret
+}
+
+// compile compiles src with a new compiler, bounded by the server's
+// Timeout.
+func (s *Server) compile(ctx context.Context, src string) (*Compiler, error) {
+
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+	c := s.NewCompiler()
+	parsed, err := ParseSource(src)
+	if err != nil {
+		return c, err
+	}
+	defer c.withContext(ctx)()
+	err = c.Apply(parsed)
+	if err != nil {
+		return c, err
+	}
+	s.Classifier.Classify(c.Catalog)
+	return c, nil
+}
+
+// readBody reads the request's body, responding with an error and returning
+// false if it can't.
+func (s *Server) readBody(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+
+	body := r.Body
+	if s.MaxBytes > 0 {
+		body = http.MaxBytesReader(w, body, s.MaxBytes)
+	}
+	b, err := io.ReadAll(body)
+	if err != nil {
+		status := http.StatusBadRequest
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		writeJSON(w, status, &ServedError{Error: err.Error()})
+		return nil, false
+	}
+	return b, true
+}
+
+func (s *Server) serveCatalog(w http.ResponseWriter, r *http.Request) {
+
+	b, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	c, err := s.compile(r.Context(), string(b))
+	if err != nil {
+		writeCompileError(w, c, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, NewServedCatalog(c.Catalog))
+}
+
+// serveLint responds with the diagnostics of compiling the body, including
+// the error if it fails to compile, which isn't itself a failed request, and
+// otherwise the smells of its tables.
+func (s *Server) serveLint(w http.ResponseWriter, r *http.Request) {
+
+	b, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	c, err := s.compile(r.Context(), string(b))
+	writeJSON(w, http.StatusOK, lintDiagnostics(c, err))
+}
+
+// lintDiagnostics returns the diagnostics of a compile which returned err,
+// followed by the smells of its tables if it succeeded.
+func lintDiagnostics(c *Compiler, err error) []*Diagnostic {
+
+	diags := c.Diagnostics(err)
+	if err == nil {
+		diags = append(diags, (&SmellDetector{MaxColumns: 50, MinGroup: 3}).Detect(c.Catalog)...)
+	}
+	if diags == nil {
+		diags = []*Diagnostic{}
+	}
+	return diags
+}
+
+func (s *Server) serveDiff(w http.ResponseWriter, r *http.Request) {
+
+	b, ok := s.readBody(w, r)
+	if !ok {
+		return
+	}
+	var req DiffRequest
+	err := json.Unmarshal(b, &req)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &ServedError{Error: err.Error()})
+		return
+	}
+	opts, err := req.options()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, &ServedError{Error: err.Error()})
+		return
+	}
+	from, err := s.compile(r.Context(), req.From)
+	if err != nil {
+		writeCompileError(w, from, fmt.Errorf("from: %w", err))
+		return
+	}
+	to, err := s.compile(r.Context(), req.To)
+	if err != nil {
+		writeCompileError(w, to, fmt.Errorf("to: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusOK, servedChanges(from.Catalog, to.Catalog, opts))
+}
+
+// options returns the options the request asks to diff with.
+func (req *DiffRequest) options() (DiffOptions, error) {
+
+	opts := DiffOptions{Renames: RenamesConservative, MatchOIDs: req.MatchOIDs}
+	if req.Renames != "" {
+		var err error
+		opts.Renames, err = ParseRenameDetection(req.Renames)
+		if err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// servedChanges returns the changes from from to to as they're served.
+func servedChanges(from, to *Catalog, opts DiffOptions) []ServedChange {
+
+	changes := []ServedChange{}
+	for _, change := range Diff(from, to, opts) {
+		safety, reason := change.Classify()
+		changes = append(changes, ServedChange{Change: change.String(), Safety: safety.String(), Reason: reason, SQL: change.SQL()})
+	}
+	return changes
+}
+
+func writeCompileError(w http.ResponseWriter, c *Compiler, err error) {
+
+	status := http.StatusUnprocessableEntity
+	if errors.Is(err, context.DeadlineExceeded) {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, newServedError(c, err))
+}
+
+// newServedError returns the error of a compile which failed.
+func newServedError(c *Compiler, err error) *ServedError {
+
+	return &ServedError{Error: err.Error(), Diagnostics: c.Diagnostics(err)}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(v)
+}
+
+func runServe(args []string) error {
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8080", "address to listen on")
+	grpcAddr := fs.String("grpc-addr", "", "address to also serve the gRPC CatalogService on, if any")
+	version := fs.Int("pg-version", 0, "major version of Postgres to target, rejecting features it lacks")
+	lenient := fs.Bool("lenient", false, "skip what can't be modeled yet instead of failing")
+	maxBytes := fs.Int64("max-bytes", 16<<20, "largest request body accepted, or 0 for no limit")
+	timeout := fs.Duration("timeout", 30*time.Second, "how long compiling a request may take, or 0 for no limit")
+	classifier := classifierFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	s := NewServer()
+	s.NewCompiler = func() *Compiler {
+		strictness := StrictnessStrict
+		if *lenient {
+			strictness = StrictnessSkipUnsupported
+		}
+		return NewCompiler(WithTargetVersion(*version), WithStrictness(strictness))
+	}
+	s.MaxBytes = *maxBytes
+	s.Timeout = *timeout
+	s.Classifier = classifier
+	srv := &http.Server{Addr: *addr, Handler: s, ReadHeaderTimeout: 10 * time.Second}
+	if *grpcAddr == "" {
+		return srv.ListenAndServe()
+	}
+	lis, err := net.Listen("tcp", *grpcAddr)
+	if err != nil {
+		return err
+	}
+	gs := grpc.NewServer()
+	(&GRPCServer{Server: s}).Register(gs)
+	errs := make(chan error, 2)
+	go func() { errs <- gs.Serve(lis) }()
+	go func() { errs <- srv.ListenAndServe() }()
+	return <-errs
+}
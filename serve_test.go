@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	s := NewServer()
+	post := func(path, body string, v any) int {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, path, strings.NewReader(body)))
+		assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+		require.Nil(t, json.Unmarshal(rec.Body.Bytes(), v))
+		return rec.Code
+	}
+
+	var cat ServedCatalog
+	assert.Equal(t, http.StatusOK, post("/catalog", "CREATE TABLE t (id int PRIMARY KEY, name text NOT NULL, note text DEFAULT 'none');", &cat))
+	require.Len(t, cat.Schemas, 1)
+	require.Len(t, cat.Schemas[0].Tables, 1)
+	table := cat.Schemas[0].Tables[0]
+	assert.Equal(t, "t", table.Name)
+	assert.Equal(t, []ServedColumn{
+		{OID: table.Columns[0].OID, Name: "id", Type: "integer"},
+		{OID: table.Columns[1].OID, Name: "name", Type: "text"},
+		{OID: table.Columns[2].OID, Name: "note", Type: "text", Nullable: true, Default: "'none'"},
+	}, table.Columns)
+	assert.Equal(t, []ServedDefinition{{OID: table.Constraints[0].OID, Name: "t_pkey", Definition: "CONSTRAINT t_pkey PRIMARY KEY (id)"}}, table.Constraints)
+
+	var failed ServedError
+	assert.Equal(t, http.StatusUnprocessableEntity, post("/catalog", "ALTER TABLE missing ADD COLUMN x int;", &failed))
+	require.Len(t, failed.Diagnostics, 1)
+	assert.Equal(t, SeverityError, failed.Diagnostics[0].Severity)
+
+	var diags []*Diagnostic
+	assert.Equal(t, http.StatusOK, post("/lint", "CREATE TABLE t (mood mood);", &diags))
+	assert.Equal(t, []*Diagnostic{
+		{Line: 1, Severity: SeverityWarning, Rule: RuleUnknownType, Message: "unknown type mood, treating it as opaque"},
+	}, diags)
+
+	var changes []ServedChange
+	req, _ := json.Marshal(DiffRequest{From: "CREATE TABLE t (id int);", To: "CREATE TABLE t (id int, name text NOT NULL);"})
+	assert.Equal(t, http.StatusOK, post("/diff", string(req), &changes))
+	require.Len(t, changes, 1)
+	assert.Equal(t, "ALTER TABLE t ADD COLUMN name text NOT NULL;", strings.Join(changes[0].SQL, "\n"))
+	assert.NotEqual(t, SafetySafe.String(), changes[0].Safety)
+
+	assert.Equal(t, http.StatusBadRequest, post("/diff", `{"renames": "sometimes"}`, &failed))
+	assert.Contains(t, failed.Error, "unknown rename detection")
+
+	s.MaxBytes = 8
+	assert.Equal(t, http.StatusRequestEntityTooLarge, post("/lint", "CREATE TABLE t (id int);", &failed))
+}
@@ -0,0 +1,320 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Sizes are estimated as Postgres lays out heap and btree pages, from the
+// lengths and alignments of the columns' types. The widths of variable length values are
+// guesses, which a column's "@avg-width <bytes>" annotation overrides, and
+// the rows a table holds are given by its "@rows <count>" annotation or
+// SizeEstimator.Rows. Values large enough to be stored out of line in the
+// table's TOAST table are counted there, uncompressed.
+
+const (
+	pageSize       = 8192
+	pageHeaderSize = 24
+	// tupleHeaderSize is the size of a heap tuple's header, before its null
+	// bitmap.
+	tupleHeaderSize = 23
+	itemIDSize      = 4
+	maxAlign        = 8
+	// toastThreshold is the width above which values are moved out of
+	// line, leaving a pointer of toastPointerSize.
+	toastThreshold   = 2032
+	toastPointerSize = 18
+	// indexTupleHeaderSize and btreeSpecialSize are the overheads of btree
+	// index tuples and pages, whose leaves are filled to btreeFillFactor.
+	indexTupleHeaderSize = 8
+	btreeSpecialSize     = 16
+	btreeFillFactor      = 0.9
+)
+
+// avgWidths are the guessed average widths of the values of variable
+// length types, less their length headers.
+var avgWidths = map[*PostgresType]int{
+	Bytea:    32,
+	CIDR:     7,
+	Inet:     7,
+	JSON:     128,
+	JSONB:    128,
+	Text:     32,
+	TSQuery:  64,
+	TSVector: 64,
+	XML:      128,
+}
+
+// columnWidth returns the estimated width of col's values within a row, the
+// alignment they need and the width of the part of them stored in the
+// TOAST table.
+func columnWidth(col *Column) (width, align, toasted int) {
+
+	if col.Type.Length > 0 && col.ArrayDims == 0 {
+		if _, ok := col.Annotations["avg-width"]; !ok {
+			return col.Type.Length, col.Type.Align, 0
+		}
+	}
+	avg := 16
+	switch {
+	case col.Type == Character && len(col.TypeMods) > 0:
+		avg = int(col.TypeMods[0])
+	case col.Type == CharacterVarying && len(col.TypeMods) > 0:
+		avg = int(col.TypeMods[0]+1) / 2
+	case col.Type == Numeric && len(col.TypeMods) > 0:
+		avg = 2 + 2*((int(col.TypeMods[0])+3)/4)
+	case col.Type == Numeric:
+		avg = 8
+	case col.Type == Bit || col.Type == BitVarying:
+		if len(col.TypeMods) > 0 {
+			avg = 4 + (int(col.TypeMods[0])+7)/8
+		}
+	default:
+		if w, ok := avgWidths[col.Type]; ok {
+			avg = w
+		} else if col.Type.Length > 0 {
+			avg = col.Type.Length
+		}
+	}
+	if col.ArrayDims > 0 {
+		// Arrays are guessed to hold a few elements
+		avg = 16 + 8*col.ArrayDims + 4*avg
+	}
+	if n, err := strconv.Atoi(col.Annotations["avg-width"]); err == nil {
+		avg = n
+	}
+	switch {
+	case avg > toastThreshold:
+		return toastPointerSize, 1, avg
+	case avg < 127:
+		// Short values have a single byte header and aren't aligned
+		return avg + 1, 1, 0
+	default:
+		return avg + 4, 4, 0
+	}
+}
+
+func alignTo(n, align int) int {
+
+	return (n + align - 1) / align * align
+}
+
+func alignTo64(n int64, align int64) int64 {
+
+	return (n + align - 1) / align * align
+}
+
+// TableSize is the estimated size of a table.
+type TableSize struct {
+	Table string `json:"table"`
+	// RowWidth is the width of a row in the table's pages, including its
+	// header.
+	RowWidth int   `json:"row_width"`
+	Rows     int64 `json:"rows"`
+	// TableBytes, IndexBytes and ToastBytes are the sizes of the table, of
+	// all of its indexes and of its TOAST table for Rows rows.
+	TableBytes int64       `json:"table_bytes"`
+	IndexBytes int64       `json:"index_bytes"`
+	ToastBytes int64       `json:"toast_bytes"`
+	Indexes    []IndexSize `json:"indexes,omitempty"`
+}
+
+type IndexSize struct {
+	Name  string `json:"name"`
+	Bytes int64  `json:"bytes"`
+}
+
+// SizeEstimator estimates the sizes of a catalog's tables.
+type SizeEstimator struct {
+	// Rows are the rows each table is expected to hold, by name qualified
+	// by its schema, overriding the table's "@rows" annotation.
+	Rows map[string]int64
+}
+
+func (e *SizeEstimator) rows(t *Table) int64 {
+
+	if n, ok := e.Rows[t.Schema+"."+t.Name]; ok {
+		return n
+	}
+	n, _ := strconv.ParseInt(t.Annotations["rows"], 10, 64)
+	return n
+}
+
+// pages returns the pages needed to hold rows items which fit perPage to a
+// page.
+func pages(rows int64, perPage int) int64 {
+
+	perPage = max(perPage, 1)
+	return (rows + int64(perPage) - 1) / int64(perPage)
+}
+
+// heapRowWidth returns the width of a row of a table with the columns cols
+// in its pages, and the width of the values of the row stored in its TOAST
+// table.
+func heapRowWidth(cols Columns) (int, int64) {
+
+	header := tupleHeaderSize
+	if slices.ContainsFunc(cols, ColumnNullable(true)) {
+		header += (len(cols) + 7) / 8
+	}
+	width := alignTo(header, maxAlign)
+	var toasted int64
+	for _, col := range cols {
+		w, align, toast := columnWidth(col)
+		width = alignTo(width, align) + w
+		toasted += int64(toast)
+	}
+	return alignTo(width, maxAlign), toasted
+}
+
+// heapBytes returns the size of a table of rows rows of width bytes.
+func heapBytes(width int, rows int64) int64 {
+
+	return pages(rows, (pageSize-pageHeaderSize)/(width+itemIDSize)) * pageSize
+}
+
+// Estimate returns the estimated sizes of the tables of cat, in the order
+// of their schemas.
+func (e *SizeEstimator) Estimate(cat *Catalog) []*TableSize {
+
+	var ret []*TableSize
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			ret = append(ret, e.estimateTable(cat, t))
+		}
+	}
+	return ret
+}
+
+func (e *SizeEstimator) estimateTable(cat *Catalog, t *Table) *TableSize {
+
+	size := &TableSize{Table: t.Schema + "." + t.Name, Rows: e.rows(t)}
+	var toasted int64
+	size.RowWidth, toasted = heapRowWidth(t.Columns.List())
+	size.TableBytes = heapBytes(size.RowWidth, size.Rows)
+	size.ToastBytes = alignTo64(toasted*size.Rows, pageSize)
+
+	index := func(name string, cols Columns, exprs int) {
+		width := indexTupleHeaderSize
+		for _, col := range cols {
+			w, align, _ := columnWidth(col)
+			width = alignTo(width, align) + w
+		}
+		// Expressions are guessed to be as wide as a bigint
+		width += 8 * exprs
+		width = alignTo(width, maxAlign)
+		perPage := int(float64(pageSize-pageHeaderSize-btreeSpecialSize) * btreeFillFactor / float64(width+itemIDSize))
+		// The root and internal pages of a btree are a small fraction of
+		// its leaves, so they aren't counted, except for its metapage
+		bytes := (pages(size.Rows, perPage) + 1) * pageSize
+		size.Indexes = append(size.Indexes, IndexSize{Name: name, Bytes: bytes})
+		size.IndexBytes += bytes
+	}
+	for _, con := range cat.Depends.TableConstraints(t) {
+		if con.Type == ConstraintTypePrimary || con.Type == ConstraintTypeUnique {
+			cols := con.Constrains
+			if con.Index != nil {
+				cols = append(cols[:len(cols):len(cols)], con.Index.Include...)
+			}
+			index(con.Name, cols, 0)
+		}
+	}
+	for _, idx := range cat.Depends.TableIndexes(t) {
+		var cols Columns
+		exprs := 0
+		for _, elem := range idx.Elems {
+			if elem.Column != nil {
+				cols = append(cols, elem.Column)
+			} else {
+				exprs++
+			}
+		}
+		index(idx.Name, append(cols, idx.Include...), exprs)
+	}
+	return size
+}
+
+// formatBytes formats n in the largest unit it's at least one of, as
+// pg_size_pretty does.
+func formatBytes(n int64) string {
+
+	units := []string{"bytes", "kB", "MB", "GB", "TB"}
+	i := 0
+	for ; i < len(units)-1 && n >= 10*1024; i++ {
+		n = (n + 512) / 1024
+	}
+	return fmt.Sprintf("%d %s", n, units[i])
+}
+
+func runSize(args []string) error {
+
+	fs := flag.NewFlagSet("size", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	rowCounts := make(map[string]int64)
+	fs.Func("rows", "rows a table is expected to hold, as table=count, qualified by schema unless it's in the search path", func(s string) error {
+		name, count, ok := strings.Cut(s, "=")
+		if !ok {
+			return fmt.Errorf("expected table=count")
+		}
+		n, err := strconv.ParseInt(count, 10, 64)
+		if err != nil || n < 0 {
+			return fmt.Errorf("invalid row count %q", count)
+		}
+		rowCounts[name] = n
+		return nil
+	})
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	e := &SizeEstimator{Rows: make(map[string]int64)}
+	for name, n := range rowCounts {
+		if !strings.Contains(name, ".") {
+			name = c.SearchPath + "." + name
+		}
+		e.Rows[name] = n
+	}
+	sizes := e.Estimate(c.Catalog)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(sizes)
+	}
+	bw := bufio.NewWriter(w)
+	rows := [][]string{{"Table", "Row width", "Rows", "Table", "Indexes", "TOAST"}}
+	for _, s := range sizes {
+		rows = append(rows, []string{s.Table, strconv.Itoa(s.RowWidth), strconv.FormatInt(s.Rows, 10),
+			formatBytes(s.TableBytes), formatBytes(s.IndexBytes), formatBytes(s.ToastBytes)})
+	}
+	writeRows(bw, rows)
+	return bw.Flush()
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSizeEstimator(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TABLE events (id bigint PRIMARY KEY, name text, active boolean);
+-- @rows 1000
+CREATE TABLE docs (
+    id int NOT NULL,
+    body text NOT NULL, -- @avg-width 10000
+    code char(3) NOT NULL
+);
+CREATE INDEX docs_lower_code ON docs (lower(code));
+`))
+	sizes := (&SizeEstimator{Rows: map[string]int64{"public.events": 1_000_000}}).Estimate(c.Catalog)
+	require.Len(t, sizes, 2)
+	// 24 byte header with its null bitmap, 8 for id, 33 for name and 1 for
+	// active, padded to 72
+	assert.Equal(t, &TableSize{
+		Table:      "public.events",
+		RowWidth:   72,
+		Rows:       1_000_000,
+		TableBytes: 9346 * pageSize,
+		IndexBytes: 2734 * pageSize,
+		Indexes:    []IndexSize{{Name: "events_pkey", Bytes: 2734 * pageSize}},
+	}, sizes[0])
+	// The body is stored out of line, leaving a pointer
+	assert.Equal(t, &TableSize{
+		Table:      "public.docs",
+		RowWidth:   56,
+		Rows:       1000,
+		TableBytes: 8 * pageSize,
+		IndexBytes: 4 * pageSize,
+		ToastBytes: 1221 * pageSize,
+		Indexes:    []IndexSize{{Name: "docs_lower_code", Bytes: 4 * pageSize}},
+	}, sizes[1])
+
+	assert.Equal(t, "9000 bytes", formatBytes(9000))
+	assert.Equal(t, "73 MB", formatBytes(9346*pageSize))
+}
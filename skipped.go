@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"encoding/json"
+	"errors"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// ErrUnsupported is wrapped by the errors for constructs the compiler can't
+// model yet, which Lenient compilers skip.
+var ErrUnsupported = errors.New("not yet able to process")
+
+// Skipped is a statement, or a part of one, which wasn't modeled in the
+// catalog.
+type Skipped struct {
+	// What is the kind of statement or construct skipped, such as "CREATE
+	// FUNCTION".
+	What string
+	// Reason is why it was skipped, if the compiler doesn't simply ignore
+	// it.
+	Reason string
+	// File and Line locate the statement, if they are known.
+	File string
+	Line int
+}
+
+func (s *Skipped) Location() string {
+
+	switch {
+	case s.File != "" && s.Line > 0:
+		return fmt.Sprintf("%s:%d", s.File, s.Line)
+	case s.File != "":
+		return s.File
+	case s.Line > 0:
+		return fmt.Sprintf("line %d", s.Line)
+	}
+	return "unknown location"
+}
+
+// skip records that the compiler didn't model what in the current
+// statement.
+func (c *Compiler) skip(what, reason string) {
+
+	s := &Skipped{What: what, Reason: reason}
+	if len(c.files) > 0 {
+		s.File = c.files[len(c.files)-1]
+	}
+	s.Line = c.stmtLine()
+	c.Skipped = append(c.Skipped, s)
+	if s.Reason != "" && c.DiagnosticsSink != nil {
+		c.DiagnosticsSink(c.promote(s.diagnostic()))
+	}
+}
+
+// diagnostic returns the warning that s was skipped for being unsupported.
+func (s *Skipped) diagnostic() *Diagnostic {
+
+	return &Diagnostic{File: s.File, Line: s.Line, Severity: SeverityWarning, Rule: RuleUnsupported,
+		Message: fmt.Sprintf("skipped %s: %s", s.What, s.Reason)}
+}
+
+// unsupported handles the compiler being unable to model what, returning
+// err unless the compiler is Lenient, in which case it's skipped.
+func (c *Compiler) unsupported(what string, err error) error {
+
+	if !c.Lenient {
+		return err
+	}
+	c.skip(what, err.Error())
+	return nil
+}
+
+// statementNames overrides the names derived from the type of a statement's
+// node where they're unclear.
+var statementNames = map[string]string{
+	"AlterSeqStmt":      "ALTER SEQUENCE",
+	"AlterStatsStmt":    "ALTER STATISTICS",
+	"CompositeTypeStmt": "CREATE TYPE AS",
+	"CreateEnumStmt":    "CREATE TYPE AS ENUM",
+	"CreatePLangStmt":   "CREATE LANGUAGE",
+	"CreateSeqStmt":     "CREATE SEQUENCE",
+	"CreateTrigStmt":    "CREATE TRIGGER",
+	"IndexStmt":         "CREATE INDEX",
+	"TransactionStmt":   "BEGIN, COMMIT OR ROLLBACK",
+	"VariableSetStmt":   "SET",
+	"ViewStmt":          "CREATE VIEW",
+}
+
+// statementName describes the kind of statement stmt is, such as "CREATE
+// FUNCTION".
+func statementName(stmt *pg_query.Node) string {
+
+	typ := strings.TrimPrefix(fmt.Sprintf("%T", stmt.Node), "*pg_query.Node_")
+	if name, ok := statementNames[typ]; ok {
+		return name
+	}
+	return upperWords(strings.TrimSuffix(typ, "Stmt"))
+}
+
+// upperWords converts a camel case name to upper case words, for example
+// "CreateFunction" to "CREATE FUNCTION".
+func upperWords(name string) string {
+
+	var sb strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteRune(unicode.ToUpper(r))
+	}
+	return sb.String()
+}
+
+// SkipSummary summarises the statements or constructs of one kind which
+// were skipped.
+type SkipSummary struct {
+	What  string `json:"what"`
+	Count int    `json:"count"`
+	// Reasons are the distinct reasons they were skipped, if the compiler
+	// doesn't simply ignore them.
+	Reasons []string `json:"reasons,omitempty"`
+	// Examples are the locations of the first few.
+	Examples []string `json:"examples"`
+}
+
+const skipExamples = 3
+
+// SummarizeSkipped groups skipped statements by kind, most frequent first.
+func SummarizeSkipped(skipped []*Skipped) []*SkipSummary {
+
+	var ret []*SkipSummary
+	byWhat := make(map[string]*SkipSummary)
+	for _, s := range skipped {
+		sum, ok := byWhat[s.What]
+		if !ok {
+			sum = &SkipSummary{What: s.What}
+			byWhat[s.What] = sum
+			ret = append(ret, sum)
+		}
+		sum.Count++
+		if s.Reason != "" && !slices.Contains(sum.Reasons, s.Reason) {
+			sum.Reasons = append(sum.Reasons, s.Reason)
+		}
+		if len(sum.Examples) < skipExamples {
+			sum.Examples = append(sum.Examples, s.Location())
+		}
+	}
+	slices.SortStableFunc(ret, func(a, b *SkipSummary) int {
+		return cmp.Compare(b.Count, a.Count)
+	})
+	return ret
+}
+
+// WriteSkipSummary writes the summary of skipped statements in the format
+// given, either "text" or "json".
+func WriteSkipSummary(w io.Writer, skipped []*Skipped, format string) error {
+
+	summary := SummarizeSkipped(skipped)
+	switch format {
+	case "json":
+		{
+			if summary == nil {
+				summary = []*SkipSummary{}
+			}
+			enc := json.NewEncoder(w)
+			enc.SetIndent("", "  ")
+			return enc.Encode(summary)
+		}
+	case "text":
+		{
+			bw := bufio.NewWriter(w)
+			for _, s := range summary {
+				examples := strings.Join(s.Examples, ", ")
+				if s.Count > len(s.Examples) {
+					examples += ", ..."
+				}
+				fmt.Fprintf(bw, "%s: %d (%s)\n", s.What, s.Count, examples)
+				for _, r := range s.Reasons {
+					fmt.Fprintf(bw, "    %s\n", r)
+				}
+			}
+			return bw.Flush()
+		}
+	}
+	return fmt.Errorf("unknown format %q, expected text or json", format)
+}
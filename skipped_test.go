@@ -0,0 +1,55 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCompiler_Skipped(t *testing.T) {
+	const sql = `CREATE TABLE t (
+	id int, EXCLUDE USING gist (id WITH =),
+	n int
+);
+CREATE SEQUENCE s;
+CREATE SEQUENCE s2;
+CREATE FUNCTION f() RETURNS int AS 'SELECT 1' LANGUAGE sql;
+ALTER TABLE t ALTER COLUMN n SET STATISTICS 100;
+DROP SEQUENCE s;
+`
+	err := NewCompiler().Compile(sql)
+	require.NotNil(t, err)
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	c := NewCompiler()
+	c.Lenient = true
+	require.Nil(t, c.Compile(sql))
+	tab := assertTable(t, c, "public.t")
+	assertColumn(t, tab, "id", Integer, ColumnAttributes{})
+
+	summary := SummarizeSkipped(c.Skipped)
+	require.Len(t, summary, 5)
+	assert.Equal(t, &SkipSummary{What: "CREATE SEQUENCE", Count: 2, Examples: []string{"line 5", "line 6"}}, summary[0])
+	assert.Equal(t, &SkipSummary{
+		What:     "EXCLUSION constraint",
+		Count:    1,
+		Reasons:  []string{"not yet able to process constraint type CONSTR_EXCLUSION"},
+		Examples: []string{"line 1"},
+	}, summary[1])
+	var whats []string
+	for _, s := range summary[2:] {
+		whats = append(whats, s.What)
+	}
+	assert.Equal(t, []string{"CREATE FUNCTION", "ALTER TABLE SET STATISTICS", "DROP SEQUENCE"}, whats)
+
+	var buf bytes.Buffer
+	require.Nil(t, WriteSkipSummary(&buf, c.Skipped, "text"))
+	assert.Contains(t, buf.String(), "CREATE SEQUENCE: 2 (line 5, line 6)\n")
+	buf.Reset()
+	require.Nil(t, WriteSkipSummary(&buf, c.Skipped, "json"))
+	var decoded []*SkipSummary
+	require.Nil(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, summary, decoded)
+}
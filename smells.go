@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// SmellDetector finds what are likely flaws in the design of a catalog's
+// tables. None are necessarily wrong, so they're reported as notes.
+type SmellDetector struct {
+	// MaxColumns is the number of columns above which a table is wide.
+	MaxColumns int
+	// MinGroup is the fewest columns shared by two tables which are
+	// reported as a repeated group.
+	MinGroup int
+}
+
+// groupIgnored are columns which so many tables have that sharing them
+// means nothing.
+var groupIgnored = []string{"id", "created_at", "updated_at", "deleted_at", "created", "updated", "version"}
+
+// Detect returns the smells of cat's tables, located where the table or
+// column concerned was defined.
+func (d *SmellDetector) Detect(cat *Catalog) []*Diagnostic {
+
+	var ret []*Diagnostic
+	report := func(rule string, loc SourceLocation, format string, args ...any) {
+		ret = append(ret, &Diagnostic{File: loc.File, Line: loc.Line, Severity: SeverityNote, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	var tables []*Table
+	for _, sch := range cat.Schemas.List() {
+		tables = append(tables, sch.Tables.List()...)
+	}
+	for i, t := range tables {
+		cols := t.Columns.List()
+		if d.MaxColumns > 0 && len(cols) > d.MaxColumns {
+			report(RuleWideTable, t.Defined, "table %s has %d columns, consider moving those rarely used together to tables of their own", TableIdent(t), len(cols))
+		}
+		for _, group := range numberedColumns(cols) {
+			report(RuleNumberedColumns, group[0].Defined, "columns %s of table %s are numbered, consider a table with a row for each instead",
+				columnNames(group), TableIdent(t))
+		}
+		for _, pair := range polymorphicReferences(cat, cols) {
+			report(RulePolymorphicReference, pair[1].Defined,
+				"columns %s and %s of table %s look like a reference to one of several tables, which no foreign key can check; consider a nullable foreign key column for each table instead",
+				pair[0].Name, pair[1].Name, TableIdent(t))
+		}
+		for _, prev := range tables[:i] {
+			if shared := sharedColumns(prev, t); d.MinGroup > 0 && len(shared) >= d.MinGroup {
+				report(RuleRepeatedColumns, shared[0].Defined, "columns %s of table %s repeat those of table %s, consider moving them to a table both reference",
+					columnNames(shared), TableIdent(t), TableIdent(prev))
+			}
+		}
+	}
+	return ret
+}
+
+func columnNames(cols Columns) string {
+
+	var names []string
+	for _, col := range cols {
+		names = append(names, col.Name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// numberedColumns returns the groups of at least two columns whose names
+// differ only in the number they end in, such as address1 and address2.
+func numberedColumns(cols Columns) []Columns {
+
+	groups := make(map[string]Columns)
+	var order []string
+	for _, col := range cols {
+		stem := strings.TrimRight(col.Name, "0123456789")
+		if stem == col.Name || stem == "" {
+			continue
+		}
+		stem = strings.TrimSuffix(stem, "_")
+		if _, ok := groups[stem]; !ok {
+			order = append(order, stem)
+		}
+		groups[stem] = append(groups[stem], col)
+	}
+	var ret []Columns
+	for _, stem := range order {
+		if len(groups[stem]) > 1 {
+			ret = append(ret, groups[stem])
+		}
+	}
+	return ret
+}
+
+// polymorphicReferences returns the pairs of columns named like X_type and
+// X_id, where X_id isn't a foreign key.
+func polymorphicReferences(cat *Catalog, cols Columns) [][2]*Column {
+
+	var ret [][2]*Column
+	for _, typ := range cols {
+		prefix, ok := strings.CutSuffix(typ.Name, "_type")
+		if !ok || prefix == "" {
+			continue
+		}
+		i := slices.IndexFunc(cols, func(col *Column) bool { return col.Name == prefix+"_id" })
+		if i < 0 {
+			continue
+		}
+		cons, _ := cat.Depends.ConstraintsByColumn.Get(cols[i])
+		if slices.ContainsFunc(cons, func(con *Constraint) bool {
+			return con.Type == ConstraintTypeForeignKey && con.Table == cols[i].Table
+		}) {
+			continue
+		}
+		ret = append(ret, [2]*Column{typ, cols[i]})
+	}
+	return ret
+}
+
+// sharedColumns returns the columns of t which a has too, of the same
+// type, leaving out those of their primary keys and those in groupIgnored.
+func sharedColumns(a, t *Table) Columns {
+
+	var ret Columns
+	for _, col := range t.Columns.List() {
+		if col.Attrs.Pkey || slices.Contains(groupIgnored, col.Name) {
+			continue
+		}
+		other, ok := a.Columns.Get(col.Name)
+		if ok && !other.Attrs.Pkey && other.SameType(col) {
+			ret = append(ret, col)
+		}
+	}
+	return ret
+}
+
+func runSmells(args []string) error {
+
+	fs := flag.NewFlagSet("smells", flag.ExitOnError)
+	maxColumns := fs.Int("max-columns", 50, "number of columns above which a table is wide, or 0 to allow any")
+	minGroup := fs.Int("min-group", 3, "fewest shared columns reported as repeated between tables, or 0 to not report them")
+	format := fs.String("format", "text", "output format, one of: text, json, sarif")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	fail := fs.Bool("fail", false, "exit with an error if any smells are found")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	smells := (&SmellDetector{MaxColumns: *maxColumns, MinGroup: *minGroup}).Detect(c.Catalog)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format != "text" {
+		if smells == nil {
+			smells = []*Diagnostic{}
+		}
+		err = WriteDiagnostics(w, smells, *format)
+	} else {
+		bw := bufio.NewWriter(w)
+		for _, d := range smells {
+			fmt.Fprintf(bw, "%s: %s [%s]\n", SourceLocation{File: d.File, Line: d.Line}, d.Message, d.Rule)
+		}
+		err = bw.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if *fail && len(smells) > 0 {
+		return fmt.Errorf("found %d smells", len(smells))
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestSmellDetector(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`CREATE TABLE customers (
+    id int PRIMARY KEY,
+    name text,
+    street text,
+    city text,
+    postcode text,
+    phone1 text,
+    phone2 text,
+    phone_3 text
+);
+CREATE TABLE comments (
+    id int PRIMARY KEY,
+    commentable_type text NOT NULL,
+    commentable_id int NOT NULL,
+    author_type text,
+    author_id int REFERENCES customers (id)
+);
+CREATE TABLE suppliers (
+    id int PRIMARY KEY,
+    street text,
+    city text,
+    postcode varchar(10),
+    created_at timestamptz
+);
+CREATE TABLE warehouses (id int PRIMARY KEY, street text, city text, postcode varchar(10), created_at timestamptz);
+`))
+	smells := (&SmellDetector{MaxColumns: 7, MinGroup: 2}).Detect(c.Catalog)
+	assert.Equal(t, []*Diagnostic{
+		{Line: 1, Severity: SeverityNote, Rule: RuleWideTable,
+			Message: "table customers has 8 columns, consider moving those rarely used together to tables of their own"},
+		{Line: 7, Severity: SeverityNote, Rule: RuleNumberedColumns,
+			Message: "columns phone1, phone2, phone_3 of table customers are numbered, consider a table with a row for each instead"},
+		{Line: 14, Severity: SeverityNote, Rule: RulePolymorphicReference,
+			Message: "columns commentable_type and commentable_id of table comments look like a reference to one of several tables, which no foreign key can check; consider a nullable foreign key column for each table instead"},
+		{Line: 20, Severity: SeverityNote, Rule: RuleRepeatedColumns,
+			Message: "columns street, city of table suppliers repeat those of table customers, consider moving them to a table both reference"},
+		{Line: 25, Severity: SeverityNote, Rule: RuleRepeatedColumns,
+			Message: "columns street, city of table warehouses repeat those of table customers, consider moving them to a table both reference"},
+		{Line: 25, Severity: SeverityNote, Rule: RuleRepeatedColumns,
+			Message: "columns street, city, postcode of table warehouses repeat those of table suppliers, consider moving them to a table both reference"},
+	}, smells)
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+)
+
+// CatalogSnapshot renders everything in cat as text for golden files, so
+// that tests of a schema can compare it against the snapshot they expect
+// and show what changed as a readable diff. Like fingerprints, snapshots
+// don't change with formatting or with the order independent objects were
+// created in: schemas, tables and the objects on each table are sorted, but
+// columns keep their order. OIDs and where objects were defined are left
+// out.
+func CatalogSnapshot(cat *Catalog) string {
+
+	var sb strings.Builder
+	line := func(indent int, format string, args ...any) {
+		sb.WriteString(strings.Repeat("  ", indent))
+		fmt.Fprintf(&sb, format, args...)
+		sb.WriteString("\n")
+	}
+	owned := func(s, owner string) string {
+		if owner != "" {
+			return s + " owner " + owner
+		}
+		return s
+	}
+	annotated := func(indent int, a Annotations) {
+		if len(a) > 0 {
+			line(indent, "annotations %s", formatAnnotations(a))
+		}
+	}
+
+	schemas := slices.Clone(cat.Schemas.List())
+	slices.SortFunc(schemas, func(a, b *Schema) int { return strings.Compare(a.Name, b.Name) })
+	for _, sch := range schemas {
+		line(0, "%s", owned("schema "+QuoteIdent(sch.Name), sch.Owner))
+		enums := slices.Clone(sch.Enums.List())
+		slices.SortFunc(enums, func(a, b *Enum) int { return strings.Compare(a.Name, b.Name) })
+		for _, e := range enums {
+			line(1, "%s", owned(EnumDefinition(e), e.Owner))
+		}
+		tables := slices.Clone(sch.Tables.List())
+		slices.SortFunc(tables, func(a, b *Table) int { return strings.Compare(a.Name, b.Name) })
+		for _, t := range tables {
+			kind := "table "
+			if t.Unlogged {
+				kind = "unlogged table "
+			}
+			line(1, "%s", owned(kind+TableIdent(t), t.Owner))
+			annotated(2, t.Annotations)
+			for _, col := range t.Columns.List() {
+				line(2, "column %s", ColumnDefinition(col))
+				annotated(3, col.Annotations)
+			}
+			var defs []string
+			for _, con := range cat.Depends.TableConstraints(t) {
+				defs = append(defs, ConstraintDefinition(con))
+			}
+			for _, idx := range cat.Depends.TableIndexes(t) {
+				defs = append(defs, IndexDefinition(idx))
+			}
+			for _, s := range cat.Depends.TableStatistics(t) {
+				defs = append(defs, StatisticsDefinition(s))
+			}
+			slices.Sort(defs)
+			for _, def := range defs {
+				line(2, "%s", def)
+			}
+			switch t.ReplicaIdentity {
+			case ReplicaIdentityDefault:
+			case ReplicaIdentityIndex:
+				line(2, "replica identity using index %s", QuoteIdent(t.ReplicaIndex.Name))
+			default:
+				line(2, "replica identity %s", t.ReplicaIdentity)
+			}
+			if t.ClusterIndex != "" {
+				line(2, "cluster on %s", QuoteIdent(t.ClusterIndex))
+			}
+			if len(t.Options) > 0 {
+				line(2, "with (%s)", strings.Join(t.Options, ", "))
+			}
+			if t.PartitionKey != "" {
+				line(2, "partition by %s", t.PartitionKey)
+			}
+			if t.PartitionOf != nil {
+				line(2, "partition of %s %s", TableIdent(t.PartitionOf), t.PartitionBound)
+			}
+			if t.Partitions != nil && len(t.Partitions.List()) > 0 {
+				line(2, "%s: %s", countOf(len(t.Partitions.List()), "collapsed partition"),
+					strings.Join(SummarizePartitions(t.Partitions.List()), ", "))
+			}
+		}
+	}
+	// Raw statements may depend on each other, so their order matters
+	for _, raw := range cat.Raw {
+		line(0, "%s", owned("raw "+raw.SQL, raw.Owner))
+	}
+	var triggers []string
+	for _, trig := range cat.EventTriggers.List() {
+		triggers = append(triggers, owned("event trigger "+strings.Join(EventTriggerDefinition(trig), "; "), trig.Owner))
+	}
+	slices.Sort(triggers)
+	for _, trig := range triggers {
+		line(0, "%s", trig)
+	}
+	var descs []string
+	for _, od := range Descriptions(cat) {
+		descs = append(descs, od.Statements()...)
+	}
+	slices.Sort(descs)
+	for _, desc := range descs {
+		line(0, "%s", desc)
+	}
+	return sb.String()
+}
+
+func runSnapshot(args []string) error {
+
+	fs := flag.NewFlagSet("snapshot", flag.ExitOnError)
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = io.WriteString(w, CatalogSnapshot(c.Catalog))
+	return err
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"github.com/henges/pgmodelparse/testutil"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const snapshotSchema = `
+CREATE SCHEMA app;
+CREATE TYPE app.mood AS ENUM ('happy', 'sad');
+CREATE TABLE app.users (
+    id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    email text NOT NULL,
+    mood app.mood DEFAULT 'happy'
+);
+CREATE UNIQUE INDEX users_email_key ON app.users (email);
+CREATE TABLE app.posts (
+    id serial PRIMARY KEY,
+    user_id bigint NOT NULL REFERENCES app.users (id),
+    title varchar(200)
+);
+CREATE INDEX posts_user_id_idx ON app.posts (user_id);
+ALTER TABLE app.posts CLUSTER ON posts_user_id_idx;
+ALTER TABLE app.users REPLICA IDENTITY FULL;
+COMMENT ON TABLE app.users IS 'people who sign in';
+`
+
+func TestCatalogSnapshot(t *testing.T) {
+	c := assertParse(t, snapshotSchema)
+	snapshot := CatalogSnapshot(c.Catalog)
+	testutil.AssertCatalogGolden(t, testutil.Snapshot(snapshot), "testdata/snapshot.golden")
+
+	// Creating the same tables in another order doesn't change the snapshot
+	reordered := assertParse(t, `
+	CREATE SCHEMA app;
+	CREATE TYPE app.mood AS ENUM ('happy', 'sad');
+	CREATE TABLE app.users (
+	    id bigint GENERATED ALWAYS AS IDENTITY,
+	    email text NOT NULL,
+	    mood app.mood DEFAULT 'happy'
+	);
+	CREATE TABLE app.posts (id serial PRIMARY KEY, user_id bigint NOT NULL, title varchar(200));
+	CREATE INDEX posts_user_id_idx ON app.posts (user_id);
+	ALTER TABLE app.posts CLUSTER ON posts_user_id_idx;
+	CREATE UNIQUE INDEX users_email_key ON app.users (email);
+	ALTER TABLE app.users ADD PRIMARY KEY (id), REPLICA IDENTITY FULL;
+	ALTER TABLE app.posts ADD FOREIGN KEY (user_id) REFERENCES app.users (id);
+	COMMENT ON TABLE app.users IS 'people who sign in';
+	`)
+	assert.Equal(t, snapshot, CatalogSnapshot(reordered.Catalog))
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// SqlcGenerator writes a sqlc.yaml which uses a schema generated by the
+// sql target, so that sqlc and pgmodelgen share the same source of truth:
+//
+//	pgmodelgen generate -target sql -out schema.sql migrations/*.sql
+//	pgmodelgen generate -target sqlc -out sqlc.yaml migrations/*.sql
+type SqlcGenerator struct {
+	Schema  string
+	Queries string
+	Package string
+	Out     string
+}
+
+func NewSqlcGenerator(fs *flag.FlagSet) Generator {
+
+	g := &SqlcGenerator{}
+	fs.StringVar(&g.Schema, "sqlc-schema", "schema.sql", "path of the schema generated by the sql target, relative to sqlc.yaml")
+	fs.StringVar(&g.Queries, "sqlc-queries", "queries", "path of the sqlc queries, relative to sqlc.yaml")
+	fs.StringVar(&g.Package, "sqlc-package", "db", "package name of the code sqlc generates")
+	fs.StringVar(&g.Out, "sqlc-out", "db", "directory sqlc generates code into, relative to sqlc.yaml")
+	return g
+}
+
+func (g *SqlcGenerator) Generate(w io.Writer, _ *Catalog) error {
+
+	_, err := fmt.Fprintf(w, `# Generated by pgmodelgen.
+version: "2"
+sql:
+  - engine: "postgresql"
+    schema: %s
+    queries: %s
+    gen:
+      go:
+        package: %s
+        out: %s
+`, strconv.Quote(g.Schema), strconv.Quote(g.Queries), strconv.Quote(g.Package), strconv.Quote(g.Out))
+	return err
+}
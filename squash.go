@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// squashable are the kinds of skipped statement which change neither the
+// schema nor its data, so that squashing loses nothing by leaving them out.
+var squashable = []string{"BEGIN, COMMIT OR ROLLBACK", "SET", "LOCK", "VACUUM"}
+
+// Squash compiles the migrations of paths, except for the last keep, and
+// writes the schema they create as DDL. The up parts of the last keep
+// migrations follow unchanged, so that recent migrations which may not
+// have been applied everywhere yet are still applied separately.
+//
+// Statements the compiler skips, such as CREATE SEQUENCE, CREATE FUNCTION
+// or INSERT, aren't in the catalog, so the DDL would leave them out. It
+// fails rather than lose them, naming where they are so that the
+// migrations holding them can be kept.
+func (c *Compiler) Squash(w io.Writer, paths []string, keep int) error {
+
+	if keep < 0 {
+		return fmt.Errorf("can't keep %d migrations", keep)
+	}
+	files, err := expandPaths(paths, c.Migrations)
+	if err != nil {
+		return err
+	}
+	split := max(len(files)-keep, 0)
+	skipped := len(c.Skipped)
+	err = c.compileMigrations(files[:split])
+	if err != nil {
+		return err
+	}
+	lost := slices.DeleteFunc(slices.Clone(c.Skipped[skipped:]), func(s *Skipped) bool {
+		return slices.Contains(squashable, s.What)
+	})
+	if len(lost) > 0 {
+		var kinds []string
+		for _, sum := range SummarizeSkipped(lost) {
+			kinds = append(kinds, fmt.Sprintf("%s at %s", sum.What, strings.Join(sum.Examples, ", ")))
+		}
+		return fmt.Errorf("squashing would lose what isn't modeled, keep the migrations with it: %s", strings.Join(kinds, "; "))
+	}
+
+	bw := bufio.NewWriter(w)
+	err = (&DDLGenerator{}).Generate(bw, c.Catalog)
+	if err != nil {
+		return err
+	}
+	for _, file := range files[split:] {
+		if _, ok, _ := readDumpFile(file.path); ok {
+			return fmt.Errorf("can't keep %s as it's a dump", file.path)
+		}
+		b, err := os.ReadFile(file.path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(bw, "-- %s\n", filepath.Base(file.path))
+		fmt.Fprintf(bw, "%s\n\n", strings.TrimSpace(file.source.Up(string(b))))
+	}
+	return bw.Flush()
+}
+
+func runSquash(args []string) error {
+
+	fs := flag.NewFlagSet("squash", flag.ExitOnError)
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	keep := fs.Int("keep", 0, "number of the most recent migrations to keep as they are")
+	run := compilerRunner(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	_, err = run(func(c *Compiler) error {
+		return c.Squash(w, fs.Args(), *keep)
+	})
+	return err
+}
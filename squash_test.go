@@ -0,0 +1,87 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_Squash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	write("001.sql", "CREATE TABLE users (id int PRIMARY KEY, name text);\n")
+	write("002.sql", "ALTER TABLE users ADD COLUMN email text;\nCREATE UNIQUE INDEX users_email ON users (email);\n")
+	write("003.sql", "CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id));\n")
+	write("004.sql", "ALTER TABLE users DROP COLUMN name;\n")
+
+	full, err := CompileFiles([]string{dir})
+	require.Nil(t, err)
+
+	for _, keep := range []int{0, 2, 10} {
+		var sb strings.Builder
+		require.Nil(t, NewCompiler().Squash(&sb, []string{dir}, keep))
+
+		squashed := NewCompiler()
+		require.Nil(t, squashed.Compile(sb.String()), sb.String())
+		assert.Empty(t, Diff(full.Catalog, squashed.Catalog, DiffOptions{}), "keep %d", keep)
+		if keep == 2 {
+			assert.Contains(t, sb.String(), "-- 003.sql\nCREATE TABLE posts")
+			assert.True(t, strings.HasSuffix(sb.String(), "-- 004.sql\nALTER TABLE users DROP COLUMN name;\n\n"))
+		}
+	}
+
+	assert.ErrorContains(t, NewCompiler().Squash(&strings.Builder{}, []string{dir}, -1), "can't keep -1")
+}
+
+func TestCompiler_SquashGoose(t *testing.T) {
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "1_users.sql"), []byte(`-- +goose Up
+CREATE TABLE users (id int PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE users;
+`), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "2_posts.sql"), []byte(`-- +goose Up
+CREATE TABLE posts (id int PRIMARY KEY);
+
+-- +goose Down
+DROP TABLE posts;
+`), 0o644))
+
+	var sb strings.Builder
+	require.Nil(t, NewCompiler().Squash(&sb, []string{dir}, 1))
+	assert.Contains(t, sb.String(), "CREATE TABLE users (")
+	// Only the up part of a kept migration is kept
+	assert.Contains(t, sb.String(), "-- 2_posts.sql\n-- +goose Up\nCREATE TABLE posts (id int PRIMARY KEY);\n\n")
+	assert.NotContains(t, sb.String(), "DROP TABLE")
+}
+
+func TestCompiler_SquashSkipped(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	write("001.sql", "BEGIN;\nSET lock_timeout = '1s';\nCREATE TABLE users (id int PRIMARY KEY);\nCOMMIT;\n")
+	write("002.sql", "CREATE SEQUENCE invoice_numbers START 1000;\n")
+	write("003.sql", `CREATE FUNCTION touch() RETURNS trigger LANGUAGE plpgsql AS $$ BEGIN RETURN NEW; END $$;
+CREATE TRIGGER users_touch BEFORE UPDATE ON users FOR EACH ROW EXECUTE FUNCTION touch();
+`)
+
+	err := NewCompiler().Squash(&strings.Builder{}, []string{dir}, 0)
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "CREATE SEQUENCE at "+filepath.Join(dir, "002.sql")+":1")
+	assert.Contains(t, err.Error(), "CREATE FUNCTION at "+filepath.Join(dir, "003.sql")+":1")
+	assert.Contains(t, err.Error(), "CREATE TRIGGER at "+filepath.Join(dir, "003.sql")+":2")
+
+	// Keeping the migrations with them squashes the rest, whose transaction
+	// control and settings aren't lost
+	var sb strings.Builder
+	require.Nil(t, NewCompiler().Squash(&sb, []string{dir}, 2))
+	assert.Contains(t, sb.String(), "CREATE TABLE users (")
+	assert.Contains(t, sb.String(), "-- 002.sql\nCREATE SEQUENCE invoice_numbers START 1000;")
+}
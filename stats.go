@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// StatsReport summarises a catalog for reviewing it at a glance: what each
+// schema holds, and which tables are the widest and the most connected by
+// foreign keys.
+type StatsReport struct {
+	Schemas []*SchemaStats `json:"schemas"`
+	// Widest are the tables with the most columns.
+	Widest []TableCount `json:"widest"`
+	// FanIn are the tables referenced by the most foreign keys, and FanOut
+	// those with the most foreign keys of their own.
+	FanIn  []TableCount `json:"fan_in"`
+	FanOut []TableCount `json:"fan_out"`
+}
+
+// SchemaStats counts the objects in a schema. Indexes are those created by
+// CREATE INDEX, not those of primary keys and unique constraints, and
+// Sequences include those of serial columns.
+type SchemaStats struct {
+	Schema      string           `json:"schema"`
+	Tables      int              `json:"tables"`
+	Columns     int              `json:"columns"`
+	Indexes     int              `json:"indexes"`
+	Constraints ConstraintCounts `json:"constraints"`
+	Enums       int              `json:"enums"`
+	EnumLabels  int              `json:"enum_labels"`
+	Sequences   int              `json:"sequences"`
+}
+
+// ConstraintCounts counts constraints by their type.
+type ConstraintCounts struct {
+	PrimaryKey int `json:"primary_key"`
+	Unique     int `json:"unique"`
+	ForeignKey int `json:"foreign_key"`
+	Check      int `json:"check"`
+}
+
+// TableCount is a table and the number of something it has.
+type TableCount struct {
+	Table string `json:"table"`
+	Count int    `json:"count"`
+}
+
+// Stats returns the report on cat, listing up to top tables as the widest
+// and the most connected.
+func Stats(cat *Catalog, top int) *StatsReport {
+
+	report := &StatsReport{Schemas: []*SchemaStats{}}
+	bySchema := make(map[string]*SchemaStats)
+	var tables []*Table
+	for _, sch := range cat.Schemas.List() {
+		if systemSchema(sch.Name) {
+			continue
+		}
+		s := &SchemaStats{Schema: sch.Name, Tables: len(sch.Tables.List()), Enums: len(sch.Enums.List()), Sequences: len(sch.Sequences.List())}
+		for _, t := range sch.Tables.List() {
+			for _, col := range t.Columns.List() {
+				s.Columns++
+				// Serial columns' sequences aren't modeled on their own
+				if isSerial(col.Type) {
+					s.Sequences++
+				}
+			}
+			tables = append(tables, t)
+		}
+		for _, e := range sch.Enums.List() {
+			s.EnumLabels += len(e.Labels)
+		}
+		report.Schemas = append(report.Schemas, s)
+		bySchema[sch.Name] = s
+	}
+	for _, idx := range cat.Depends.IndexesByName {
+		if s, ok := bySchema[idx.Table.Schema]; ok {
+			s.Indexes++
+		}
+	}
+	fanIn, fanOut := make(map[*Table]int), make(map[*Table]int)
+	for _, con := range cat.Depends.ConstraintsByName {
+		s, ok := bySchema[con.Table.Schema]
+		if !ok {
+			continue
+		}
+		switch con.Type {
+		case ConstraintTypePrimary:
+			s.Constraints.PrimaryKey++
+		case ConstraintTypeUnique:
+			s.Constraints.Unique++
+		case ConstraintTypeForeignKey:
+			s.Constraints.ForeignKey++
+			fanOut[con.Table]++
+			if len(con.Refers) > 0 {
+				fanIn[con.Refers[0].Table]++
+			}
+		case ConstraintTypeCheck:
+			s.Constraints.Check++
+		}
+	}
+	report.Widest = topTables(tables, top, func(t *Table) int { return len(t.Columns.List()) })
+	report.FanIn = topTables(tables, top, func(t *Table) int { return fanIn[t] })
+	report.FanOut = topTables(tables, top, func(t *Table) int { return fanOut[t] })
+	return report
+}
+
+// topTables returns up to n of tables with the highest counts, leaving out
+// those counting zero, with ties in the order the tables were given.
+func topTables(tables []*Table, n int, count func(*Table) int) []TableCount {
+
+	ret := []TableCount{}
+	for _, t := range tables {
+		if c := count(t); c > 0 {
+			ret = append(ret, TableCount{Table: t.Schema + "." + t.Name, Count: c})
+		}
+	}
+	slices.SortStableFunc(ret, func(a, b TableCount) int { return b.Count - a.Count })
+	if len(ret) > n {
+		ret = ret[:n]
+	}
+	return ret
+}
+
+// WriteText writes the report as tables for reading in a terminal.
+func (r *StatsReport) WriteText(w io.Writer) error {
+
+	bw := bufio.NewWriter(w)
+	rows := [][]string{{"Schema", "Tables", "Columns", "Indexes", "Primary keys", "Unique", "Foreign keys", "Checks", "Enums", "Sequences"}}
+	for _, s := range r.Schemas {
+		rows = append(rows, []string{s.Schema, strconv.Itoa(s.Tables), strconv.Itoa(s.Columns), strconv.Itoa(s.Indexes),
+			strconv.Itoa(s.Constraints.PrimaryKey), strconv.Itoa(s.Constraints.Unique), strconv.Itoa(s.Constraints.ForeignKey),
+			strconv.Itoa(s.Constraints.Check), fmt.Sprintf("%d (%d labels)", s.Enums, s.EnumLabels), strconv.Itoa(s.Sequences)})
+	}
+	writeRows(bw, rows)
+	for _, list := range []struct {
+		title, count string
+		tables       []TableCount
+	}{
+		{"Widest tables", "Columns", r.Widest},
+		{"Most referenced tables", "Foreign keys in", r.FanIn},
+		{"Most referencing tables", "Foreign keys out", r.FanOut},
+	} {
+		if len(list.tables) == 0 {
+			continue
+		}
+		fmt.Fprintf(bw, "\n%s\n%s\n", list.title, strings.Repeat("-", len(list.title)))
+		rows := [][]string{{"Table", list.count}}
+		for _, tc := range list.tables {
+			rows = append(rows, []string{tc.Table, strconv.Itoa(tc.Count)})
+		}
+		writeRows(bw, rows)
+	}
+	return bw.Flush()
+}
+
+func runStats(args []string) error {
+
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	top := fs.Int("top", 10, "number of tables to list as the widest and most connected")
+	format := fs.String("format", "text", "output format, one of: text, json")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	report := Stats(c.Catalog, *top)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format == "json" {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.WriteText(w)
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestStats(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE TABLE users (id serial PRIMARY KEY, email text UNIQUE, mood mood);
+CREATE TABLE posts (id int PRIMARY KEY, author_id int REFERENCES users (id), title text CHECK (title <> ''));
+CREATE TABLE comments (id int PRIMARY KEY, post_id int REFERENCES posts (id), author_id int REFERENCES users (id));
+CREATE INDEX ON comments (post_id);
+CREATE SCHEMA audit;
+CREATE TABLE audit.log (at timestamptz);
+`))
+	report := Stats(c.Catalog, 2)
+	assert.Equal(t, []*SchemaStats{
+		{Schema: "public", Tables: 3, Columns: 9, Indexes: 1, Constraints: ConstraintCounts{PrimaryKey: 3, Unique: 1, ForeignKey: 3, Check: 1},
+			Enums: 1, EnumLabels: 2, Sequences: 1},
+		{Schema: "audit", Tables: 1, Columns: 1},
+	}, report.Schemas)
+	assert.Equal(t, []TableCount{{Table: "public.users", Count: 3}, {Table: "public.posts", Count: 3}}, report.Widest)
+	assert.Equal(t, []TableCount{{Table: "public.users", Count: 2}, {Table: "public.posts", Count: 1}}, report.FanIn)
+	assert.Equal(t, []TableCount{{Table: "public.comments", Count: 2}, {Table: "public.posts", Count: 1}}, report.FanOut)
+
+	var buf bytes.Buffer
+	require.Nil(t, report.WriteText(&buf))
+	assert.Contains(t, buf.String(), "public  3       9        1        3             1       3             1       1 (2 labels)  1\n")
+	assert.Contains(t, buf.String(), "Most referenced tables\n----------------------\nTable         Foreign keys in\npublic.users  2\n")
+}
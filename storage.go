@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// storageOptionName returns the name of the storage parameter def sets,
+// qualified by its namespace if it has one.
+func storageOptionName(def *pg_query.DefElem) string {
+
+	if def.Defnamespace != "" {
+		return def.Defnamespace + "." + def.Defname
+	}
+	return def.Defname
+}
+
+var storageOptionWord = regexp.MustCompile(`^([A-Za-z_][A-Za-z0-9_]*|-?[0-9]+(\.[0-9]+)?)$`)
+
+// storageOptionValue renders the value of the storage parameter def sets,
+// which is true if it's given without one.
+func storageOptionValue(def *pg_query.DefElem) (string, error) {
+
+	if def.Arg == nil {
+		return "true", nil
+	}
+	value := DefElemValue(def.Arg)
+	if v, ok := def.Arg.Node.(*pg_query.Node_String_); ok {
+		value = v.String_.Sval
+	} else if value == "" {
+		return "", fmt.Errorf("unexpected value %T for storage parameter %s", def.Arg.Node, storageOptionName(def))
+	}
+	if !storageOptionWord.MatchString(value) {
+		value = QuoteLiteral(value)
+	}
+	return value, nil
+}
+
+// setStorageOptions returns opts with the storage parameters in defs set,
+// as CREATE ... WITH or ALTER ... SET give them. opts isn't modified, so
+// that checkpoints of the objects holding it stay intact.
+func setStorageOptions(opts []string, defs []*pg_query.Node) ([]string, error) {
+
+	opts = slices.Clone(opts)
+	for _, n := range defs {
+		def := n.GetDefElem()
+		if def == nil {
+			return nil, fmt.Errorf("expected DefElem but got %T", n.Node)
+		}
+		name := storageOptionName(def)
+		// Tables can't have OIDs, but WITH (oids = false) is still
+		// accepted
+		if name == "oids" {
+			continue
+		}
+		value, err := storageOptionValue(def)
+		if err != nil {
+			return nil, err
+		}
+		opts = slices.DeleteFunc(opts, func(opt string) bool { return storageOptionKey(opt) == name })
+		opts = append(opts, name+"="+value)
+	}
+	slices.Sort(opts)
+	return opts, nil
+}
+
+// resetStorageOptions returns opts without the storage parameters in defs,
+// as ALTER ... RESET gives them.
+func resetStorageOptions(opts []string, defs []*pg_query.Node) ([]string, error) {
+
+	opts = slices.Clone(opts)
+	for _, n := range defs {
+		def := n.GetDefElem()
+		if def == nil {
+			return nil, fmt.Errorf("expected DefElem but got %T", n.Node)
+		}
+		name := storageOptionName(def)
+		opts = slices.DeleteFunc(opts, func(opt string) bool { return storageOptionKey(opt) == name })
+	}
+	return opts, nil
+}
+
+// storageOptionKey returns the name of the storage parameter opt sets.
+func storageOptionKey(opt string) string {
+
+	name, _, _ := strings.Cut(opt, "=")
+	return name
+}
+
+// storageOptionChanges returns the storage parameters of to which aren't
+// set the same way in from, and the names of those of from which to
+// doesn't have.
+func storageOptionChanges(from, to []string) (set, reset []string) {
+
+	for _, opt := range to {
+		if !slices.Contains(from, opt) {
+			set = append(set, opt)
+		}
+	}
+	for _, opt := range from {
+		name := storageOptionKey(opt)
+		if !slices.ContainsFunc(to, func(o string) bool { return storageOptionKey(o) == name }) {
+			reset = append(reset, name)
+		}
+	}
+	return set, reset
+}
+
+// storageOptionsClause renders the WITH clause of a table or index with
+// the storage parameters opts, which is empty if there are none.
+func storageOptionsClause(opts []string) string {
+
+	if len(opts) == 0 {
+		return ""
+	}
+	return " WITH (" + strings.Join(opts, ", ") + ")"
+}
+
+// StorageOptionsDefinitions renders the ALTER statements changing the
+// storage parameters of the table or index named by what, such as
+// `TABLE "t"`, from from to to, without trailing semicolons.
+func StorageOptionsDefinitions(what string, from, to []string) []string {
+
+	var stmts []string
+	set, reset := storageOptionChanges(from, to)
+	if len(set) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER %s SET (%s)", what, strings.Join(set, ", ")))
+	}
+	if len(reset) > 0 {
+		stmts = append(stmts, fmt.Sprintf("ALTER %s RESET (%s)", what, strings.Join(reset, ", ")))
+	}
+	return stmts
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"io"
+	"strings"
+)
+
+// Statement is a single statement split from a stream of SQL. Its text
+// includes the comments and whitespace preceding the statement, so that
+// annotations in them are kept, and any comment following its semicolon on
+// the same line.
+type Statement struct {
+	Text string
+	// Line is the zero-based line of the stream Text starts on.
+	Line int
+	// Offset is where the statement itself starts in Text, after the
+	// comments and whitespace preceding it.
+	Offset int
+	// CopyData is set if the statement is a COPY ... FROM stdin, in which
+	// case the CopyRows rows of data following it have been skipped.
+	CopyData bool
+	CopyRows int
+	// Meta is set if the statement is a psql meta-command, such as \i.
+	Meta bool
+}
+
+// StartLine returns the zero-based line of the stream the statement itself
+// starts on.
+func (s Statement) StartLine() int {
+
+	return s.Line + strings.Count(s.Text[:s.Offset], "\n")
+}
+
+// StatementScanner splits a stream of SQL into statements as it's read, so
+// that the whole stream never has to be held in memory. As psql does, it
+// ignores semicolons in quotes, comments, parentheses and the bodies of
+// BEGIN ATOMIC functions, and skips the data following COPY ... FROM stdin.
+type StatementScanner struct {
+	// Psql enables psql's meta-commands, which are returned as statements of
+	// their own, and the substitution of psql variables set in Vars.
+	Psql bool
+	Vars map[string]string
+	r    *bufio.Reader
+	line int
+	stmt Statement
+	err  error
+	// first is the first word of the statement last split, in lower case.
+	first string
+}
+
+func NewStatementScanner(r io.Reader) *StatementScanner {
+
+	return &StatementScanner{r: bufio.NewReaderSize(r, 64*1024)}
+}
+
+// Scan advances to the next statement, returning false once the stream is
+// exhausted or reading it fails.
+func (s *StatementScanner) Scan() bool {
+
+	if s.err != nil {
+		return false
+	}
+	for {
+		stmt, ok, err := s.split()
+		if err != nil && err != io.EOF {
+			s.err = err
+			return false
+		}
+		if ok {
+			if s.first == "copy" && isCopyFromStdin(stmt.Text) {
+				stmt.CopyData = true
+				stmt.CopyRows, err = s.skipCopyData()
+				if err != nil {
+					s.err = err
+					return false
+				}
+			}
+			s.stmt = stmt
+			return true
+		}
+		if err == io.EOF {
+			return false
+		}
+	}
+}
+
+func (s *StatementScanner) Statement() Statement {
+
+	return s.stmt
+}
+
+// Err returns the error which stopped Scan, other than reaching the end of
+// the stream.
+func (s *StatementScanner) Err() error {
+
+	return s.err
+}
+
+const (
+	scanNormal = iota
+	scanQuote
+	scanEscapeQuote
+	scanIdent
+	scanDollarQuote
+	scanLineComment
+	scanBlockComment
+)
+
+// split reads the next statement up to and including its semicolon, or the
+// end of the stream. ok is false if nothing but comments and whitespace was
+// read.
+func (s *StatementScanner) split() (stmt Statement, ok bool, err error) {
+
+	var buf bytes.Buffer
+	stmt.Line = s.line
+	s.first = ""
+	var (
+		state  = scanNormal
+		ended  bool
+		parens int
+		// commentDepth is the nesting of block comments
+		commentDepth int
+		// delim is the delimiter of the dollar quote being read
+		delim string
+		// wordStart is where the word being read starts in buf, if any
+		wordStart = -1
+		prev      byte
+		// words holds the first letter of each of the leading words of a
+		// CREATE [OR REPLACE] FUNCTION or PROCEDURE statement
+		words      []byte
+		wordCount  int
+		beginDepth int
+	)
+	// word handles a word having been read, tracking BEGIN ... END blocks
+	// in SQL function bodies the same way psql does
+	word := func(w string) {
+		w = strings.ToLower(w)
+		if wordCount == 0 {
+			s.first = w
+		}
+		switch w {
+		case "create", "or", "replace", "function", "procedure":
+			if wordCount < 4 {
+				words = append(words, w[0])
+			}
+		}
+		wordCount++
+		routine := len(words) >= 2 && words[0] == 'c' && (words[1] == 'f' || words[1] == 'p' ||
+			(len(words) >= 4 && words[1] == 'o' && words[2] == 'r' && (words[3] == 'f' || words[3] == 'p')))
+		if !routine || parens > 0 {
+			return
+		}
+		switch w {
+		case "begin":
+			beginDepth++
+		case "case":
+			if beginDepth > 0 {
+				beginDepth++
+			}
+		case "end":
+			if beginDepth > 0 {
+				beginDepth--
+			}
+		}
+	}
+	done := func() (Statement, bool, error) {
+		stmt.Text = buf.String()
+		return stmt, ok, nil
+	}
+
+	for {
+		b, err := s.r.ReadByte()
+		if err != nil {
+			if wordStart >= 0 {
+				word(buf.String()[wordStart:])
+			}
+			stmt.Text = buf.String()
+			return stmt, ok, err
+		}
+		if b == '\n' {
+			s.line++
+		}
+
+		switch state {
+		case scanNormal:
+			{
+				if wordStart >= 0 && !isIdentChar(b) {
+					w := buf.String()[wordStart:]
+					wordStart = -1
+					// E'...' is an escape string rather than an identifier
+					if b == '\'' && (w == "e" || w == "E") {
+						state = scanEscapeQuote
+						buf.WriteByte(b)
+						continue
+					}
+					word(w)
+				}
+				comment := s.startsComment(b)
+				if ended && !isSpace(b) && !comment {
+					err := s.r.UnreadByte()
+					if err != nil {
+						return stmt, ok, err
+					}
+					return done()
+				}
+				if b == '\\' && s.Psql {
+					if ok {
+						// As with \g, the meta-command ends the statement
+						// before it
+						err := s.r.UnreadByte()
+						if err != nil {
+							return stmt, ok, err
+						}
+						return done()
+					}
+					// Meta-commands run to the end of the line
+					stmt.Offset, stmt.Meta = buf.Len(), true
+					buf.WriteByte(b)
+					rest, err := s.r.ReadString('\n')
+					buf.WriteString(rest)
+					if strings.HasSuffix(rest, "\n") {
+						s.line++
+					}
+					stmt.Text = buf.String()
+					return stmt, true, err
+				}
+				buf.WriteByte(b)
+				if ended && b == '\n' {
+					return done()
+				}
+				if !ok && !isSpace(b) && !comment && b != ';' {
+					ok = true
+					stmt.Offset = buf.Len() - 1
+				}
+				switch {
+				case comment && b == '-':
+					{
+						s.r.ReadByte()
+						buf.WriteByte('-')
+						state = scanLineComment
+					}
+				case comment && b == '/':
+					{
+						s.r.ReadByte()
+						buf.WriteByte('*')
+						state, commentDepth = scanBlockComment, 1
+					}
+				case b == '\'':
+					state = scanQuote
+				case b == '"':
+					state = scanIdent
+				case b == '$' && !isIdentChar(prev):
+					{
+						tag, isTag := s.dollarTag()
+						if isTag {
+							s.r.Discard(len(tag) - 1)
+							buf.WriteString(tag[1:])
+							state, delim = scanDollarQuote, tag
+						}
+					}
+				case b == ':' && s.Psql:
+					{
+						next, _ := s.r.Peek(1)
+						if len(next) > 0 && next[0] == ':' {
+							// A cast rather than a variable
+							s.r.ReadByte()
+							buf.WriteByte(':')
+						} else if value, set := s.variable(); set {
+							buf.Truncate(buf.Len() - 1)
+							buf.WriteString(value)
+						}
+					}
+				case b == '(':
+					parens++
+				case b == ')':
+					if parens > 0 {
+						parens--
+					}
+				case b == ';':
+					if parens == 0 && beginDepth == 0 {
+						ended = true
+					}
+				case isIdentStart(b) && !isIdentChar(prev):
+					wordStart = buf.Len() - 1
+				}
+				prev = b
+			}
+		case scanQuote, scanIdent:
+			{
+				buf.WriteByte(b)
+				// A doubled quote is read as two adjacent quoted strings
+				if (state == scanQuote && b == '\'') || (state == scanIdent && b == '"') {
+					state, prev = scanNormal, b
+				}
+			}
+		case scanEscapeQuote:
+			{
+				buf.WriteByte(b)
+				if b == '\\' {
+					next, err := s.r.ReadByte()
+					if err != nil {
+						stmt.Text = buf.String()
+						return stmt, ok, err
+					}
+					if next == '\n' {
+						s.line++
+					}
+					buf.WriteByte(next)
+				} else if b == '\'' {
+					state, prev = scanNormal, b
+				}
+			}
+		case scanDollarQuote:
+			{
+				buf.WriteByte(b)
+				if b != '$' {
+					continue
+				}
+				rest, _ := s.r.Peek(len(delim) - 1)
+				if string(rest) == delim[1:] {
+					s.r.Discard(len(rest))
+					buf.Write(rest)
+					state, prev = scanNormal, b
+				}
+			}
+		case scanLineComment:
+			{
+				buf.WriteByte(b)
+				if b == '\n' {
+					if ended {
+						return done()
+					}
+					state, prev = scanNormal, b
+				}
+			}
+		case scanBlockComment:
+			{
+				buf.WriteByte(b)
+				next, _ := s.r.Peek(1)
+				if len(next) == 0 {
+					continue
+				}
+				if b == '/' && next[0] == '*' {
+					commentDepth++
+				} else if b == '*' && next[0] == '/' {
+					commentDepth--
+				} else {
+					continue
+				}
+				s.r.ReadByte()
+				buf.WriteByte(next[0])
+				if commentDepth == 0 {
+					// Comments separate tokens like whitespace does
+					state, prev = scanNormal, ' '
+				}
+			}
+		}
+	}
+}
+
+// startsComment reports whether b, followed by the next byte to be read,
+// starts a comment.
+func (s *StatementScanner) startsComment(b byte) bool {
+
+	if b != '-' && b != '/' {
+		return false
+	}
+	next, _ := s.r.Peek(1)
+	if len(next) == 0 {
+		return false
+	}
+	return (b == '-' && next[0] == '-') || (b == '/' && next[0] == '*')
+}
+
+// dollarTag returns the delimiter of a dollar quote if the '$' just read
+// starts one.
+func (s *StatementScanner) dollarTag() (string, bool) {
+
+	for n := 1; n <= 64; n++ {
+		p, err := s.r.Peek(n)
+		if err != nil {
+			return "", false
+		}
+		c := p[n-1]
+		if c == '$' {
+			return "$" + string(p), true
+		}
+		if !isIdentChar(c) || (n == 1 && c >= '0' && c <= '9') {
+			return "", false
+		}
+	}
+	return "", false
+}
+
+// variable reads the reference to a psql variable following a ':', which
+// is either its name, or its name in single or double quotes to substitute
+// it as a literal or identifier. Nothing is read if the variable isn't set,
+// leaving the reference as it is.
+func (s *StatementScanner) variable() (string, bool) {
+
+	p, _ := s.r.Peek(1)
+	if len(p) == 0 {
+		return "", false
+	}
+	var quote byte
+	start := 0
+	if p[0] == '\'' || p[0] == '"' {
+		quote, start = p[0], 1
+	}
+	n := start
+	for ; n < 256; n++ {
+		p, _ = s.r.Peek(n + 1)
+		if len(p) <= n || !isIdentChar(p[n]) || p[n] == '$' || (n == start && !isIdentStart(p[n])) {
+			break
+		}
+	}
+	if n == start {
+		return "", false
+	}
+	name, length := string(p[start:n]), n
+	if quote != 0 {
+		if len(p) <= n || p[n] != quote {
+			return "", false
+		}
+		length++
+	}
+	value, ok := s.Vars[name]
+	if !ok {
+		return "", false
+	}
+	s.r.Discard(length)
+	switch quote {
+	case '\'':
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'", true
+	case '"':
+		return `"` + strings.ReplaceAll(value, `"`, `""`) + `"`, true
+	}
+	return value, true
+}
+
+// skipCopyData discards the data following COPY ... FROM stdin, up to the
+// line holding only \. which ends it, returning the number of rows in it.
+func (s *StatementScanner) skipCopyData() (int, error) {
+
+	start := s.line
+	lineStart := true
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// The line is too long to be the end of the data
+			lineStart = false
+			continue
+		}
+		if lineStart && strings.TrimRight(string(chunk), "\r\n") == `\.` {
+			if err == nil {
+				s.line++
+			}
+			return s.line - start - 1, nil
+		}
+		if err == io.EOF {
+			return 0, fmt.Errorf("COPY data starting on line %d isn't ended by \\.", start+1)
+		}
+		if err != nil {
+			return 0, err
+		}
+		s.line++
+		lineStart = true
+	}
+}
+
+// StripCopyData replaces the data following each COPY ... FROM stdin in src
+// with blank lines, so that the statements of a pg_dump can be parsed while
+// keeping their line numbers. The number of rows each COPY had is returned
+// in order.
+func StripCopyData(src string) (string, []int, error) {
+
+	// The data always ends with a line holding only \.
+	if !strings.Contains(src, "\n\\.") {
+		return src, nil, nil
+	}
+	var sb strings.Builder
+	var rows []int
+	line := 0
+	scanner := NewStatementScanner(strings.NewReader(src))
+	for scanner.Scan() {
+		stmt := scanner.Statement()
+		sb.WriteString(strings.Repeat("\n", stmt.Line-line))
+		sb.WriteString(stmt.Text)
+		line = stmt.Line + strings.Count(stmt.Text, "\n")
+		if stmt.CopyData {
+			rows = append(rows, stmt.CopyRows)
+		}
+	}
+	if scanner.Err() != nil {
+		return "", nil, scanner.Err()
+	}
+	return sb.String(), rows, nil
+}
+
+// isCopyFromStdin reports whether the statement is a COPY reading its data
+// from the lines following it.
+func isCopyFromStdin(stmt string) bool {
+
+	scan, err := pg_query.Scan(stmt)
+	if err != nil {
+		return false
+	}
+	var tokens []pg_query.Token
+	for _, tok := range scan.Tokens {
+		if tok.Token != pg_query.Token_SQL_COMMENT && tok.Token != pg_query.Token_C_COMMENT {
+			tokens = append(tokens, tok.Token)
+		}
+	}
+	if len(tokens) == 0 || tokens[0] != pg_query.Token_COPY {
+		return false
+	}
+	for i := 1; i < len(tokens)-1; i++ {
+		if tokens[i] == pg_query.Token_FROM && tokens[i+1] == pg_query.Token_STDIN {
+			return true
+		}
+	}
+	return false
+}
+
+func isSpace(b byte) bool {
+
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f' || b == '\v'
+}
+
+func isIdentStart(b byte) bool {
+
+	return b == '_' || (b >= 'a' && b <= 'z') || (b >= 'A' && b <= 'Z') || b >= 0x80
+}
+
+func isIdentChar(b byte) bool {
+
+	return isIdentStart(b) || (b >= '0' && b <= '9') || b == '$'
+}
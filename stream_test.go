@@ -0,0 +1,136 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func scanStatements(t *testing.T, sql string) []Statement {
+
+	scanner := NewStatementScanner(strings.NewReader(sql))
+	var ret []Statement
+	for scanner.Scan() {
+		ret = append(ret, scanner.Statement())
+	}
+	require.Nil(t, scanner.Err())
+	return ret
+}
+
+func TestStatementScanner(t *testing.T) {
+	const sql = `-- leading comment
+CREATE TABLE a (s text DEFAULT ';', "x;y" int, e text DEFAULT E'\';'); -- @note trailing
+/* block /* nested; */ ; */
+CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;
+CREATE FUNCTION g() RETURNS int LANGUAGE sql
+BEGIN ATOMIC
+  SELECT CASE WHEN true THEN 1 END;
+  SELECT 2;
+END;
+COPY a (s) FROM stdin;
+one;
+two
+\.
+;;
+CREATE RULE r AS ON INSERT TO a DO ALSO (NOTIFY a; NOTIFY b); SELECT $1;
+SELECT 1`
+
+	stmts := scanStatements(t, sql)
+	var texts []string
+	for _, s := range stmts {
+		texts = append(texts, strings.TrimSpace(s.Text[s.Offset:]))
+	}
+	assert.Equal(t, []string{
+		`CREATE TABLE a (s text DEFAULT ';', "x;y" int, e text DEFAULT E'\';'); -- @note trailing`,
+		`CREATE FUNCTION f() RETURNS int AS $body$ SELECT 1; $body$ LANGUAGE sql;`,
+		"CREATE FUNCTION g() RETURNS int LANGUAGE sql\nBEGIN ATOMIC\n  SELECT CASE WHEN true THEN 1 END;\n  SELECT 2;\nEND;",
+		"COPY a (s) FROM stdin;",
+		"CREATE RULE r AS ON INSERT TO a DO ALSO (NOTIFY a; NOTIFY b);",
+		"SELECT $1;",
+		"SELECT 1",
+	}, texts)
+	assert.Equal(t, []int{1, 3, 4, 9, 14, 14, 15}, []int{
+		stmts[0].StartLine(), stmts[1].StartLine(), stmts[2].StartLine(), stmts[3].StartLine(),
+		stmts[4].StartLine(), stmts[5].StartLine(), stmts[6].StartLine(),
+	})
+	assert.True(t, strings.HasPrefix(stmts[0].Text, "-- leading comment\n"))
+}
+
+func TestStripCopyData(t *testing.T) {
+	const sql = `CREATE TABLE a (id int, s text);
+COPY a (id, s) FROM stdin;
+1	x;y
+2	\N
+\.
+COPY a FROM stdin;
+\.
+CREATE TABLE b (id int);
+`
+	stripped, rows, err := StripCopyData(sql)
+	require.Nil(t, err)
+	assert.Equal(t, "CREATE TABLE a (id int, s text);\nCOPY a (id, s) FROM stdin;\n\n\n\nCOPY a FROM stdin;\n\nCREATE TABLE b (id int);\n", stripped)
+	assert.Equal(t, []int{2, 0}, rows)
+
+	c := NewCompiler()
+	require.Nil(t, c.Compile(sql))
+	assertTable(t, c, "public.b")
+	assert.Equal(t, map[string]int{"public.a": 2}, c.CopyRows)
+}
+
+func TestStatementScanner_UnterminatedCopy(t *testing.T) {
+	scanner := NewStatementScanner(strings.NewReader("COPY a FROM stdin;\n1\n2\n"))
+	require.False(t, scanner.Scan())
+	require.NotNil(t, scanner.Err())
+	assert.Contains(t, scanner.Err().Error(), "line 2")
+}
+
+func TestCompiler_CompileReader(t *testing.T) {
+	const sql = `CREATE TABLE users (
+	-- @seed email
+	contact text NOT NULL
+);
+COPY users (contact) FROM stdin;
+a@example.com
+\.
+
+CREATE TABLE posts (
+	id int PRIMARY KEY,
+	author text -- @pii
+);
+`
+	streamed := NewCompiler()
+	require.Nil(t, streamed.CompileReader(strings.NewReader(sql)))
+	whole := NewCompiler()
+	require.Nil(t, whole.Compile(sql))
+	var wholeDDL, streamedDDL strings.Builder
+	require.Nil(t, (&DDLGenerator{}).Generate(&wholeDDL, whole.Catalog))
+	require.Nil(t, (&DDLGenerator{}).Generate(&streamedDDL, streamed.Catalog))
+	assert.Equal(t, wholeDDL.String(), streamedDDL.String())
+	contact, err := streamed.FindColumn("public", "users", "contact")
+	require.Nil(t, err)
+	assert.Equal(t, Annotations{"seed": "email"}, contact.Annotations)
+	assert.Equal(t, map[string]int{"public.users": 1}, streamed.CopyRows)
+
+	err = NewCompiler().CompileReader(strings.NewReader(sql + "\nALTER TABLE missing ADD COLUMN x int;\n"))
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "at line 14")
+
+	c := NewCompiler()
+	c.TargetVersion, c.WarnUnsupported = 14, true
+	require.Nil(t, c.CompileReader(strings.NewReader(sql+"CREATE TABLE u (x int, UNIQUE NULLS NOT DISTINCT (x));\n")))
+	require.Len(t, c.Warnings, 1)
+	assert.Contains(t, c.Warnings[0], "(line 13)")
+}
+
+func TestCompiler_CompileFilesStreaming(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dump.sql")
+	require.Nil(t, os.WriteFile(path, []byte("CREATE TABLE a (id int);\nCOPY a FROM stdin;\n1\n\\.\nCREATE TABLE b (id int);\n"), 0o644))
+	c := NewCompiler()
+	c.StreamSize = 1
+	require.Nil(t, c.CompileFiles([]string{path}))
+	assertTable(t, c, "public.a")
+	assertTable(t, c, "public.b")
+}
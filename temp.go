@@ -0,0 +1,135 @@
+package main
+
+import (
+	"fmt"
+	"github.com/henges/pgmodelparse/collections"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"slices"
+	"strings"
+)
+
+// TempSchema is the schema temporary tables, sequences and views are
+// created in. Postgres gives each session a pg_temp_N schema of its own,
+// which pg_temp refers to, and looks in it before the search path for the
+// relations named by unqualified names, as the compiler does. Temporary
+// objects are gone once the session ends, so the CLI drops them once
+// compiling is done unless asked to keep them; see DropTemporary.
+const TempSchema = "pg_temp"
+
+// Relation persistences, as a RangeVar's Relpersistence gives them.
+const (
+	persistenceTemporary = "t"
+	persistenceUnlogged  = "u"
+)
+
+// createSchema returns the schema a relation given rv is created in, which
+// is TempSchema if it's temporary, creating TempSchema if it's the first.
+func (c *Compiler) createSchema(rv *pg_query.RangeVar) (string, error) {
+
+	schema := rv.Schemaname
+	temp := rv.Relpersistence == persistenceTemporary
+	switch {
+	case temp && schema != "" && schema != TempSchema:
+		return "", fmt.Errorf("cannot create temporary relation in non-temporary schema %s", schema)
+	case temp, schema == TempSchema:
+		c.tempSchema()
+		return TempSchema, nil
+	case schema == "":
+		return c.SearchPath, nil
+	}
+	return schema, nil
+}
+
+// tempSchema returns TempSchema, creating it if it doesn't exist yet.
+func (c *Compiler) tempSchema() *Schema {
+
+	if sch, ok := c.Catalog.Schemas.Get(TempSchema); ok {
+		return sch
+	}
+	sch := &Schema{
+		Name:      TempSchema,
+		Tables:    collections.NewOrderedMap[string, *Table](),
+		Enums:     collections.NewOrderedMap[string, *Enum](),
+		Sequences: collections.NewOrderedMap[string, *Sequence](),
+	}
+	c.Catalog.Schemas.Add(sch.Name, sch)
+	return sch
+}
+
+// relationSchema returns the schema of the relation named by schema and
+// name: schema if it's given, otherwise TempSchema if it has a table,
+// sequence, index or view of that name, and the search path if it
+// doesn't.
+func (c *Compiler) relationSchema(schema, name string) string {
+
+	if schema != "" {
+		return schema
+	}
+	if c.temporaryRelation(name) {
+		return TempSchema
+	}
+	return c.SearchPath
+}
+
+// temporaryRelation reports whether TempSchema has a table, sequence,
+// index or view named name.
+func (c *Compiler) temporaryRelation(name string) bool {
+
+	sch, ok := c.Catalog.Schemas.Get(TempSchema)
+	if !ok {
+		return false
+	}
+	if _, ok := sch.Tables.Get(name); ok {
+		return true
+	}
+	if _, ok := sch.Sequences.Get(name); ok {
+		return true
+	}
+	if _, ok := c.Catalog.Depends.IndexesByName[TempSchema+"."+name]; ok {
+		return true
+	}
+	return slices.ContainsFunc(c.Catalog.Raw, func(raw *RawStatement) bool {
+		return raw.Kind == "CREATE VIEW" && raw.Name == TempSchema+"."+name
+	})
+}
+
+// relationName qualifies the name of a relation given by names as
+// relationSchema does.
+func (c *Compiler) relationName(names []string) string {
+
+	if len(names) == 1 {
+		return c.relationSchema("", names[0]) + "." + names[0]
+	}
+	return strings.Join(names, ".")
+}
+
+// temporaryObject reports whether obj, a dependency of a view, is a
+// temporary table, one of its columns or a temporary view.
+func temporaryObject(obj any) bool {
+
+	switch o := obj.(type) {
+	case *Table:
+		return o.Temporary()
+	case *Column:
+		return o.Table.Temporary()
+	case *RawStatement:
+		return strings.HasPrefix(o.Name, TempSchema+".")
+	}
+	return false
+}
+
+// Temporary reports whether t is a temporary table.
+func (t *Table) Temporary() bool {
+
+	return t.Schema == TempSchema
+}
+
+// DropTemporary drops the temporary objects, as ending the session which
+// created them does, along with the objects depending on them.
+func (c *Compiler) DropTemporary() error {
+
+	if _, ok := c.Catalog.Schemas.Get(TempSchema); !ok {
+		return nil
+	}
+	return c.DropSchema(TempSchema, false, DropBehaviourCascade)
+}
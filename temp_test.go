@@ -0,0 +1,99 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_Temporary(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY, name text);
+	CREATE TEMP TABLE staging (id int, name text);
+	CREATE TEMPORARY SEQUENCE staging_seq;
+	CREATE INDEX staging_id ON staging (id);
+	ALTER TABLE staging ADD COLUMN seen boolean;
+	CREATE TEMP VIEW staged AS SELECT id FROM staging;
+	CREATE VIEW named AS SELECT name FROM staging;
+	CREATE TABLE pg_temp.scratch (n int);
+	CREATE TEMP TABLE copied AS SELECT id FROM users;
+	`)
+	tmp, ok := c.Catalog.Schemas.Get(TempSchema)
+	require.True(t, ok)
+	assert.Equal(t, []string{"staging", "scratch", "copied"}, tableNames(tmp))
+	staging := assertTable(t, c, "pg_temp.staging")
+	assert.True(t, staging.Temporary())
+	assertColumn(t, staging, "seen", Boolean, ColumnAttributes{})
+	assert.NotNil(t, c.Catalog.Depends.IndexesByName["pg_temp.staging_id"])
+	_, ok = tmp.Sequences.Get("staging_seq")
+	assert.True(t, ok)
+	// A view using a temporary table is temporary too
+	assert.NotNil(t, c.findOpaque("CREATE VIEW", "pg_temp.staged"))
+	assert.NotNil(t, c.findOpaque("CREATE VIEW", "pg_temp.named"))
+
+	// Temporary tables hide permanent ones of the same name
+	require.Nil(t, c.Compile(`CREATE TEMP TABLE users (id int); INSERT INTO users VALUES (1); ALTER TABLE users ADD COLUMN temp_only int`))
+	assert.Len(t, assertTable(t, c, "pg_temp.users").Columns.List(), 2)
+	assert.Len(t, assertTable(t, c, "public.users").Columns.List(), 2)
+	require.Nil(t, c.Compile(`DROP TABLE users`))
+	assertTable(t, c, "public.users")
+	require.Nil(t, c.Compile(`DROP INDEX staging_id; DROP VIEW named`))
+
+	require.Nil(t, c.DropTemporary())
+	_, ok = c.Catalog.Schemas.Get(TempSchema)
+	assert.False(t, ok)
+	assert.Nil(t, c.findOpaque("CREATE VIEW", "pg_temp.staged"))
+	assertTable(t, c, "public.users")
+
+	require.Nil(t, c.Compile(`CREATE TEMP TABLE t (id int); DISCARD TEMP`))
+	_, ok = c.Catalog.Schemas.Get(TempSchema)
+	assert.False(t, ok)
+
+	assertParseError(t, `CREATE SCHEMA app; CREATE TEMP TABLE app.t (id int)`, "cannot create temporary relation in non-temporary schema app")
+	assertParseError(t, `
+	CREATE TEMP TABLE codes (code text PRIMARY KEY);
+	CREATE TABLE items (code text REFERENCES codes (code));
+	`, "constraints on permanent tables may reference only permanent tables")
+	assertParseError(t, `CREATE TEMP TABLE t (id int); ALTER TABLE t SET UNLOGGED`, "because it is temporary")
+}
+
+func TestCompiler_Unlogged(t *testing.T) {
+	c := assertParse(t, `
+	CREATE UNLOGGED TABLE cache (key text, value text);
+	CREATE TABLE events (id int);
+	ALTER TABLE events SET UNLOGGED;
+	CREATE UNLOGGED SEQUENCE cache_seq;
+	CREATE SEQUENCE events_seq;
+	ALTER SEQUENCE events_seq SET UNLOGGED;
+	ALTER SEQUENCE cache_seq SET LOGGED;
+	`)
+	cache := assertTable(t, c, "cache")
+	assert.True(t, cache.Unlogged)
+	assert.True(t, assertTable(t, c, "events").Unlogged)
+	assert.False(t, c.findSequence("", "cache_seq").Unlogged)
+	assert.True(t, c.findSequence("", "events_seq").Unlogged)
+	assert.True(t, strings.HasPrefix(TableDefinition(c.Catalog, cache, cache.Columns.List()), "CREATE UNLOGGED TABLE cache ("))
+
+	to := assertParse(t, `
+	CREATE TABLE cache (key text, value text);
+	CREATE UNLOGGED TABLE events (id int);
+	CREATE UNLOGGED TABLE sessions (id int);
+	CREATE SEQUENCE cache_seq;
+	CREATE SEQUENCE events_seq;
+	`)
+	assert.Equal(t, []string{
+		"CREATE UNLOGGED TABLE sessions (\n    id integer\n);",
+		"ALTER TABLE cache SET LOGGED;",
+		"ALTER SEQUENCE events_seq SET LOGGED;",
+	}, Diff(c.Catalog, to.Catalog, DiffOptions{}).SQL())
+}
+
+func tableNames(sch *Schema) []string {
+
+	var names []string
+	for _, t := range sch.Tables.List() {
+		names = append(names, t.Name)
+	}
+	return names
+}
@@ -0,0 +1,218 @@
+// Package testutil helps projects using pgmodelgen keep their schema under
+// regression tests, by comparing snapshots of the catalog their migrations
+// compile to against golden files checked in beside them.
+//
+// pgmodelgen is a command rather than a library, so fixtures are compiled
+// by running it: set PGMODELGEN to the path of the binary, or put it on the
+// PATH. Set PGMODELGEN_UPDATE=1 to write the snapshots compared to golden
+// files into them instead, after a schema changes on purpose.
+package testutil
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// Snapshot is the text `pgmodelgen snapshot` renders a catalog as: sorted,
+// one object per line, and without OIDs or source locations, so that it
+// only changes when the schema does.
+type Snapshot string
+
+// CompileFixture compiles the migrations at path, a file or a directory,
+// and returns a snapshot of the catalog they create. Any further args are
+// passed to pgmodelgen before path, such as -lenient or -search-path.
+func CompileFixture(t testing.TB, path string, args ...string) Snapshot {
+
+	t.Helper()
+	bin := os.Getenv("PGMODELGEN")
+	if bin == "" {
+		var err error
+		bin, err = exec.LookPath("pgmodelgen")
+		if err != nil {
+			t.Fatalf("pgmodelgen isn't on the PATH, install it or set PGMODELGEN to its path: %v", err)
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(bin, append(append([]string{"snapshot"}, args...), path)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if err != nil {
+		t.Fatalf("while compiling %s: %v\n%s", path, err, stderr.String())
+	}
+	return Snapshot(stdout.String())
+}
+
+// AssertCatalogGolden fails t with a diff of the lines that differ if
+// snapshot isn't the same as the golden file at goldenPath. The golden
+// file is written instead when PGMODELGEN_UPDATE is set, or when it
+// doesn't exist yet, in which case t is failed too so that the new file
+// is looked over before it's checked in.
+func AssertCatalogGolden(t testing.TB, snapshot Snapshot, goldenPath string) {
+
+	t.Helper()
+	want, err := os.ReadFile(goldenPath)
+	missing := errors.Is(err, os.ErrNotExist)
+	if err != nil && !missing {
+		t.Fatalf("while reading golden file: %v", err)
+	}
+	if missing || os.Getenv("PGMODELGEN_UPDATE") != "" {
+		err = os.MkdirAll(filepath.Dir(goldenPath), 0o755)
+		if err == nil {
+			err = os.WriteFile(goldenPath, []byte(snapshot), 0o644)
+		}
+		if err != nil {
+			t.Fatalf("while writing golden file: %v", err)
+		}
+		if missing {
+			t.Errorf("golden file %s didn't exist and was written, check it over", goldenPath)
+		}
+		return
+	}
+	// Golden files checked out on Windows may have gained carriage returns
+	got := string(snapshot)
+	wantText := strings.ReplaceAll(string(want), "\r\n", "\n")
+	if got != wantText {
+		t.Errorf("catalog differs from golden file %s, set PGMODELGEN_UPDATE=1 to update it:\n%s",
+			goldenPath, DiffLines(wantText, got))
+	}
+}
+
+// DiffLines returns the lines of want and got which differ, prefixed with
+// - for those only in want and + for those only in got. Unchanged lines
+// are shown only around changes, with @@ marking where lines were left out.
+func DiffLines(want, got string) string {
+
+	const context = 2
+	a, b := splitLines(want), splitLines(got)
+	// Snapshots mostly differ in a few places, so the lines they start and
+	// end with in common are left out of the diff proper
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var ops []diffOp
+	for _, line := range a[:prefix] {
+		ops = append(ops, diffOp{' ', line})
+	}
+	ops = diffMiddle(ops, a[prefix:len(a)-suffix], b[prefix:len(b)-suffix])
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, diffOp{' ', line})
+	}
+
+	near := make([]bool, len(ops))
+	for k, o := range ops {
+		if o.prefix == ' ' {
+			continue
+		}
+		for n := max(k-context, 0); n <= min(k+context, len(ops)-1); n++ {
+			near[n] = true
+		}
+	}
+	var sb strings.Builder
+	skipped := false
+	for k, o := range ops {
+		if !near[k] {
+			skipped = true
+			continue
+		}
+		if skipped {
+			sb.WriteString("@@\n")
+			skipped = false
+		}
+		fmt.Fprintf(&sb, "%c %s\n", o.prefix, o.line)
+	}
+	return sb.String()
+}
+
+// diffOp is a line of a diff, with the prefix it's shown with.
+type diffOp struct {
+	prefix byte
+	line   string
+}
+
+// diffMiddle appends the diff of a and b to ops, following the longest
+// common subsequence of their lines. It's found by Hirschberg's algorithm,
+// which splits a in half and b where the subsequences of the halves meet,
+// so that it only needs space linear in the lengths of a and b.
+func diffMiddle(ops []diffOp, a, b []string) []diffOp {
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			ops = append(ops, diffOp{'+', line})
+		}
+		return ops
+	case len(b) == 0:
+		for _, line := range a {
+			ops = append(ops, diffOp{'-', line})
+		}
+		return ops
+	case len(a) == 1:
+		{
+			k := slices.Index(b, a[0])
+			if k < 0 {
+				ops = append(ops, diffOp{'-', a[0]})
+				return diffMiddle(ops, nil, b)
+			}
+			ops = diffMiddle(ops, nil, b[:k])
+			ops = append(ops, diffOp{' ', a[0]})
+			return diffMiddle(ops, nil, b[k+1:])
+		}
+	}
+	mid := len(a) / 2
+	front := lcsLengths(a[:mid], b, false)
+	back := lcsLengths(a[mid:], b, true)
+	split, best := 0, -1
+	for k := 0; k <= len(b); k++ {
+		if n := front[k] + back[len(b)-k]; n > best {
+			split, best = k, n
+		}
+	}
+	ops = diffMiddle(ops, a[:mid], b[:split])
+	return diffMiddle(ops, a[mid:], b[split:])
+}
+
+// lcsLengths returns the lengths of the longest common subsequences of a
+// and each prefix of b, indexed by the prefix's length, or with reverse of
+// a and each suffix of b, indexed by the suffix's length.
+func lcsLengths(a, b []string, reverse bool) []int {
+
+	line := func(s []string, i int) string {
+		if reverse {
+			return s[len(s)-1-i]
+		}
+		return s[i]
+	}
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if line(a, i) == line(b, j) {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+func splitLines(s string) []string {
+
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
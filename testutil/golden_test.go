@@ -0,0 +1,119 @@
+package testutil
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// recorder records how a test would have failed, so that failing is what
+// can be tested.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...any) {
+	r.Errorf(format, args...)
+}
+
+func TestAssertCatalogGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "schema.golden")
+	snapshot := Snapshot("schema public\n  table public.users\n    column id integer NOT NULL\n")
+
+	// A missing golden file is written, but still fails
+	r := &recorder{TB: t}
+	AssertCatalogGolden(r, snapshot, path)
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "didn't exist and was written")
+	written, err := os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, string(snapshot), string(written))
+
+	r = &recorder{TB: t}
+	AssertCatalogGolden(r, snapshot, path)
+	assert.Empty(t, r.errors)
+
+	r = &recorder{TB: t}
+	AssertCatalogGolden(r, snapshot+"    column name text\n", path)
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "    column id integer NOT NULL\n+     column name text\n")
+
+	t.Setenv("PGMODELGEN_UPDATE", "1")
+	r = &recorder{TB: t}
+	AssertCatalogGolden(r, snapshot+"    column name text\n", path)
+	assert.Empty(t, r.errors)
+	written, err = os.ReadFile(path)
+	require.Nil(t, err)
+	assert.Equal(t, string(snapshot)+"    column name text\n", string(written))
+}
+
+func TestDiffLines(t *testing.T) {
+	want := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	got := "a\nb\nc\nD\ne\nf\ng\nh\ni\nj\nk\n"
+	assert.Equal(t, "@@\n  b\n  c\n- d\n+ D\n  e\n  f\n@@\n  i\n  j\n+ k\n", DiffLines(want, got))
+	assert.Equal(t, "", DiffLines(want, want))
+	assert.Equal(t, "+ a\n", DiffLines("", "a\n"))
+}
+
+func TestDiffLines_Large(t *testing.T) {
+	var want, got strings.Builder
+	for i := range 5000 {
+		fmt.Fprintf(&want, "line %d\n", i)
+		if i == 2500 {
+			got.WriteString("inserted\n")
+		}
+		if i%2000 != 0 {
+			fmt.Fprintf(&got, "line %d\n", i)
+		}
+	}
+	assert.Equal(t, "- line 0\n  line 1\n  line 2\n@@\n  line 1998\n  line 1999\n- line 2000\n  line 2001\n  line 2002\n"+
+		"@@\n  line 2498\n  line 2499\n+ inserted\n  line 2500\n  line 2501\n"+
+		"@@\n  line 3998\n  line 3999\n- line 4000\n  line 4001\n  line 4002\n",
+		DiffLines(want.String(), got.String()))
+}
+
+// buildPGModelGen builds pgmodelgen from the module this package is in,
+// for CompileFixture to run.
+func buildPGModelGen(t *testing.T) string {
+
+	t.Helper()
+	gobin, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go isn't on the PATH to build pgmodelgen with")
+	}
+	bin := filepath.Join(t.TempDir(), "pgmodelgen")
+	out, err := exec.Command(gobin, "build", "-o", bin, "..").CombinedOutput()
+	require.Nil(t, err, string(out))
+	return bin
+}
+
+func TestCompileFixture(t *testing.T) {
+	t.Setenv("PGMODELGEN", buildPGModelGen(t))
+	dir := t.TempDir()
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "001_users.sql"), []byte("CREATE TABLE users (id int PRIMARY KEY);\n"), 0o644))
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "002_name.sql"), []byte("ALTER TABLE users ADD COLUMN name text;\n"), 0o644))
+
+	snapshot := CompileFixture(t, dir)
+	assert.Equal(t, "schema public\n  table users\n    column id integer\n    column name text\n    CONSTRAINT users_pkey PRIMARY KEY (id)\n", string(snapshot))
+	golden := filepath.Join(t.TempDir(), "schema.golden")
+	require.Nil(t, os.WriteFile(golden, []byte(snapshot), 0o644))
+	AssertCatalogGolden(t, snapshot, golden)
+
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "003_broken.sql"), []byte("ALTER TABLE missing ADD COLUMN x int;\n"), 0o644))
+	r := &recorder{TB: t}
+	CompileFixture(r, dir)
+	require.Len(t, r.errors, 1)
+	assert.Contains(t, r.errors[0], "while compiling "+dir)
+}
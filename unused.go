@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// UnusedObjects returns the objects of cat which nothing uses, as
+// candidates for cleaning up: sequences no column owns or takes its default
+// from, and enums no column is of. Sequences mentioned by a statement kept
+// verbatim, such as a view calling nextval, are assumed to be used. Indexes
+// and constraints are dropped along with their columns, so none can be
+// left over.
+func UnusedObjects(cat *Catalog) []*Diagnostic {
+
+	var ret []*Diagnostic
+	report := func(rule string, loc SourceLocation, format string, args ...any) {
+		ret = append(ret, &Diagnostic{File: loc.File, Line: loc.Line, Severity: SeverityNote, Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+	cols := FindColumns(cat)
+	for _, sch := range cat.Schemas.List() {
+		for _, seq := range sch.Sequences.List() {
+			if seq.OwnedBy != nil || sequenceUsed(cat, cols, seq) {
+				continue
+			}
+			report(RuleOrphanedSequence, seq.Defined, "sequence %s.%s isn't owned by a column and no column's default uses it", seq.Schema, seq.Name)
+		}
+		for _, e := range sch.Enums.List() {
+			if len(EnumColumns(cat, e)) == 0 {
+				report(RuleUnusedEnum, e.Defined, "enum %s.%s isn't the type of any column", e.Schema, e.Name)
+			}
+		}
+	}
+	return ret
+}
+
+func sequenceUsed(cat *Catalog, cols Columns, seq *Sequence) bool {
+
+	name := seq.Schema + "." + seq.Name
+	for _, col := range cols {
+		if col.Sequence() == name {
+			return true
+		}
+	}
+	for _, raw := range cat.Raw {
+		if strings.Contains(raw.SQL, seq.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func runUnused(args []string) error {
+
+	fs := flag.NewFlagSet("unused", flag.ExitOnError)
+	format := fs.String("format", "text", "output format, one of: text, json, sarif")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	fail := fs.Bool("fail", false, "exit with an error if any unused objects are found")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "sarif" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	unused := UnusedObjects(c.Catalog)
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	if *format != "text" {
+		if unused == nil {
+			unused = []*Diagnostic{}
+		}
+		err = WriteDiagnostics(w, unused, *format)
+	} else {
+		bw := bufio.NewWriter(w)
+		for _, d := range unused {
+			fmt.Fprintf(bw, "%s: %s [%s]\n", SourceLocation{File: d.File, Line: d.Line}, d.Message, d.Rule)
+		}
+		err = bw.Flush()
+	}
+	if err != nil {
+		return err
+	}
+	if *fail && len(unused) > 0 {
+		return fmt.Errorf("found %d unused objects", len(unused))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestUnusedObjects(t *testing.T) {
+	c := NewCompiler()
+	require.Nil(t, c.Compile(`CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE TYPE colour AS ENUM ('red');
+CREATE SEQUENCE counter;
+CREATE SEQUENCE ticket_seq;
+CREATE SEQUENCE owned_seq;
+CREATE SEQUENCE view_seq;
+CREATE TABLE people (id int, mood mood, ticket int DEFAULT nextval('ticket_seq'));
+ALTER SEQUENCE owned_seq OWNED BY people.id;
+CREATE VIEW next_ids AS SELECT nextval('view_seq');
+`))
+	assert.Equal(t, []*Diagnostic{
+		{Line: 3, Severity: SeverityNote, Rule: RuleOrphanedSequence, Message: "sequence public.counter isn't owned by a column and no column's default uses it"},
+		{Line: 2, Severity: SeverityNote, Rule: RuleUnusedEnum, Message: "enum public.colour isn't the type of any column"},
+	}, UnusedObjects(c.Catalog))
+
+	// Dropping the owning column drops the sequence, rather than orphaning it
+	require.Nil(t, c.Compile(`ALTER TABLE people DROP COLUMN id; ALTER TABLE people DROP COLUMN ticket;`))
+	var messages []string
+	for _, d := range UnusedObjects(c.Catalog) {
+		messages = append(messages, d.Message)
+	}
+	assert.Equal(t, []string{
+		"sequence public.counter isn't owned by a column and no column's default uses it",
+		"sequence public.ticket_seq isn't owned by a column and no column's default uses it",
+		"enum public.colour isn't the type of any column",
+	}, messages)
+}
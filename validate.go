@@ -0,0 +1,310 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// Validate checks that the catalog is consistent with itself: that objects
+// are kept under their names, point back to the tables and schemas they
+// belong to, are all registered in Depends, and only refer to objects
+// still in the catalog. A compiler bug, rather than a bad schema, makes a
+// catalog inconsistent, so compiling doesn't validate the catalog unless
+// Compiler.ValidateCatalog is set. Every problem found is returned.
+func (c *Catalog) Validate() error {
+
+	v := &validator{cat: c, live: make(map[any]bool), oids: make(map[OID]string)}
+	v.objects()
+	v.depends()
+	v.graph()
+	return errors.Join(v.errs...)
+}
+
+type validator struct {
+	cat *Catalog
+	// live holds the tables, columns, constraints, indexes, statistics and
+	// raw statements of the catalog.
+	live map[any]bool
+	// oids names the object each OID was given to.
+	oids map[OID]string
+	errs []error
+}
+
+func (v *validator) errorf(format string, args ...any) {
+
+	v.errs = append(v.errs, fmt.Errorf(format, args...))
+}
+
+// describe names obj for errors, such as "column public.users.id".
+func describe(obj any) string {
+
+	kind, name := objectName(obj)
+	return kind + " " + name
+}
+
+// oid checks that no other object was given the OID of the object named.
+func (v *validator) oid(oid OID, name string) {
+
+	if oid == 0 {
+		return
+	}
+	if oid < firstOID || oid > v.cat.lastOID {
+		v.errorf("%s has OID %d, which wasn't given out", name, oid)
+	}
+	if other, ok := v.oids[oid]; ok {
+		v.errorf("%s has the OID %d of %s", name, oid, other)
+	}
+	v.oids[oid] = name
+}
+
+// objects checks the schemas and everything in them, recording the objects
+// found as live.
+func (v *validator) objects() {
+
+	for _, sch := range v.cat.Schemas.List() {
+		if got, _ := v.cat.Schemas.Get(sch.Name); got != sch {
+			v.errorf("schema %s isn't kept under its name", sch.Name)
+		}
+		for _, t := range sch.Tables.List() {
+			if got, _ := sch.Tables.Get(t.Name); got != t {
+				v.errorf("table %s.%s isn't kept under its name", sch.Name, t.Name)
+			}
+			if t.Schema != sch.Name {
+				v.errorf("table %s.%s is in schema %s", t.Schema, t.Name, sch.Name)
+			}
+			v.live[t] = true
+			v.oid(t.OID, describe(t))
+			for _, col := range t.Columns.List() {
+				if got, _ := t.Columns.Get(col.Name); got != col {
+					v.errorf("%s isn't kept under its name", describe(col))
+				}
+				if col.Table != t {
+					v.errorf("column %s of %s belongs to table %s", col.Name, describe(t), col.Table.Name)
+				}
+				if col.Type == nil || col.Attrs == nil {
+					v.errorf("%s has no type or attributes", describe(col))
+				}
+				v.live[col] = true
+				v.oid(col.OID, describe(col))
+			}
+		}
+		for _, e := range sch.Enums.List() {
+			if got, _ := sch.Enums.Get(e.Name); got != e || e.Schema != sch.Name {
+				v.errorf("enum %s.%s isn't kept under its name", e.Schema, e.Name)
+			}
+			v.oid(e.OID, "enum "+EnumIdent(e))
+		}
+		for _, seq := range sch.Sequences.List() {
+			if got, _ := sch.Sequences.Get(seq.Name); got != seq || seq.Schema != sch.Name {
+				v.errorf("sequence %s.%s isn't kept under its name", seq.Schema, seq.Name)
+			}
+			if seq.OwnedBy != nil && !v.live[seq.OwnedBy] {
+				v.errorf("sequence %s.%s is owned by a column which was dropped", seq.Schema, seq.Name)
+			}
+			v.oid(seq.OID, "sequence "+seq.Schema+"."+seq.Name)
+		}
+	}
+	for _, raw := range v.cat.Raw {
+		v.live[raw] = true
+		if raw.Table != nil && !v.live[raw.Table] {
+			v.errorf("%s %s is on a table which was dropped", raw.Kind, raw.Name)
+		}
+		v.oid(raw.OID, raw.Kind+" "+raw.Name)
+	}
+	for _, trig := range v.cat.EventTriggers.List() {
+		if got, _ := v.cat.EventTriggers.Get(trig.Name); got != trig {
+			v.errorf("event trigger %s isn't kept under its name", trig.Name)
+		}
+		v.oid(trig.OID, "event trigger "+trig.Name)
+	}
+}
+
+// depends checks the constraints, indexes and statistics registered in
+// Depends, and that the columns they use are indexed by column.
+func (v *validator) depends() {
+
+	d := v.cat.Depends
+	columns := func(obj any, t *Table, cols Columns) {
+		for _, col := range cols {
+			if !v.live[col] {
+				v.errorf("%s uses column %s, which was dropped", describe(obj), col.Name)
+			} else if t != nil && col.Table != t {
+				v.errorf("%s uses column %s of another table", describe(obj), describe(col))
+			}
+		}
+	}
+	for key, con := range d.ConstraintsByName {
+		v.live[con] = true
+		if con.Name != key.Name {
+			v.errorf("constraint %s is kept under the name %s", con.Name, key.Name)
+		} else if con.Table != key.Table {
+			v.errorf("constraint %s is kept under another table", con.Name)
+		}
+		if !v.live[con.Table] {
+			v.errorf("constraint %s is on a table which was dropped", con.Name)
+			continue
+		}
+		v.oid(con.OID, describe(con))
+		columns(con, con.Table, con.Constrains)
+		columns(con, nil, con.Refers)
+		columns(con, con.Table, con.SetColumns)
+		if con.Type == ConstraintTypeForeignKey {
+			if len(con.Refers) != len(con.Constrains) {
+				v.errorf("%s has %d columns but refers to %d", describe(con), len(con.Constrains), len(con.Refers))
+			}
+			for _, col := range con.Refers {
+				if col.Table != con.Refers[0].Table {
+					v.errorf("%s refers to columns of more than one table", describe(con))
+					break
+				}
+			}
+		}
+		if con.Index != nil && d.IndexesByName[con.Index.QualifiedName()] == con.Index {
+			v.errorf("%s owns index %s, which is also registered on its own", describe(con), con.Index.Name)
+		}
+		for _, col := range con.Depends() {
+			if cons, _ := d.ConstraintsByColumn.Get(col); !slices.Contains(cons, con) {
+				v.errorf("%s isn't registered on column %s", describe(con), col.Name)
+			}
+		}
+	}
+	for name, idx := range d.IndexesByName {
+		v.live[idx] = true
+		if idx.QualifiedName() != name {
+			v.errorf("index %s is kept under the name %s", idx.QualifiedName(), name)
+		}
+		if !v.live[idx.Table] {
+			v.errorf("index %s is on a table which was dropped", idx.Name)
+			continue
+		}
+		v.oid(idx.OID, describe(idx))
+		columns(idx, idx.Table, idx.Columns)
+		for _, col := range idx.Columns {
+			if idxs, _ := d.IndexesByColumn.Get(col); !slices.Contains(idxs, idx) {
+				v.errorf("%s isn't registered on column %s", describe(idx), col.Name)
+			}
+		}
+	}
+	for name, s := range d.StatisticsByName {
+		v.live[s] = true
+		if s.QualifiedName() != name {
+			v.errorf("statistics object %s is kept under the name %s", s.QualifiedName(), name)
+		}
+		if !v.live[s.Table] {
+			v.errorf("statistics object %s is on a table which was dropped", s.Name)
+			continue
+		}
+		v.oid(s.OID, describe(s))
+		columns(s, s.Table, s.Columns)
+		for _, col := range s.Columns {
+			if stats, _ := d.StatisticsByColumn.Get(col); !slices.Contains(stats, s) {
+				v.errorf("%s isn't registered on column %s", describe(s), col.Name)
+			}
+		}
+	}
+
+	for _, col := range d.ConstraintsByColumn.Keys() {
+		cons, _ := d.ConstraintsByColumn.Get(col)
+		for _, con := range cons {
+			if d.ConstraintsByName[con.Key()] != con {
+				v.errorf("constraint %s is registered on column %s but was dropped", con.Name, col.Name)
+			}
+		}
+	}
+	for _, col := range d.IndexesByColumn.Keys() {
+		idxs, _ := d.IndexesByColumn.Get(col)
+		for _, idx := range idxs {
+			if d.IndexesByName[idx.QualifiedName()] != idx {
+				v.errorf("index %s is registered on column %s but was dropped", idx.Name, col.Name)
+			}
+		}
+	}
+	for _, col := range d.StatisticsByColumn.Keys() {
+		stats, _ := d.StatisticsByColumn.Get(col)
+		for _, s := range stats {
+			if d.StatisticsByName[s.QualifiedName()] != s {
+				v.errorf("statistics object %s is registered on column %s but was dropped", s.Name, col.Name)
+			}
+		}
+	}
+
+	for _, sch := range v.cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			v.table(t)
+		}
+	}
+}
+
+// table checks what a table refers to besides its columns.
+func (v *validator) table(t *Table) {
+
+	d := v.cat.Depends
+	for _, col := range t.Columns.List() {
+		if !col.Attrs.Pkey {
+			continue
+		}
+		pkey := false
+		cons, _ := d.ConstraintsByColumn.Get(col)
+		for _, con := range cons {
+			pkey = pkey || (con.Type == ConstraintTypePrimary && con.Table == t && slices.Contains(con.Constrains, col))
+		}
+		if !pkey {
+			v.errorf("%s is marked as part of a primary key, but isn't", describe(col))
+		}
+	}
+	if t.ReplicaIdentity == ReplicaIdentityIndex {
+		if t.ReplicaIndex == nil || !v.live[t.ReplicaIndex] && !v.constraintIndex(t.ReplicaIndex) {
+			v.errorf("%s uses an index as its replica identity which was dropped", describe(t))
+		}
+	}
+	if t.ClusterIndex != "" {
+		_, isIndex := d.IndexesByName[t.Schema+"."+t.ClusterIndex]
+		_, isConstraint := d.Constraint(t, t.ClusterIndex)
+		if !isIndex && !isConstraint {
+			v.errorf("%s is clustered on index %s, which doesn't exist", describe(t), t.ClusterIndex)
+		}
+	}
+	if t.PartitionOf != nil && !v.live[t.PartitionOf] {
+		v.errorf("%s is a partition of a table which was dropped", describe(t))
+	}
+}
+
+// constraintIndex reports whether idx belongs to a constraint.
+func (v *validator) constraintIndex(idx *Index) bool {
+
+	for _, con := range v.cat.Depends.ConstraintsByName {
+		if con.Index == idx {
+			return true
+		}
+	}
+	return false
+}
+
+// graph checks that the dependency graph only has edges between live
+// objects, and that each edge is recorded in both directions.
+func (v *validator) graph() {
+
+	d := v.cat.Depends
+	for _, dependent := range d.dependencies.Keys() {
+		deps, _ := d.dependencies.Get(dependent)
+		for _, dep := range deps {
+			if !v.live[dep.Dependent] {
+				v.errorf("%s depends on %s but was dropped", describe(dep.Dependent), describe(dep.Object))
+			} else if !v.live[dep.Object] {
+				v.errorf("%s depends on %s, which was dropped", describe(dep.Dependent), describe(dep.Object))
+			}
+			if back, _ := d.dependents.Get(dep.Object); !slices.Contains(back, dep) {
+				v.errorf("%s depends on %s, but isn't one of its dependents", describe(dep.Dependent), describe(dep.Object))
+			}
+		}
+	}
+	for _, obj := range d.dependents.Keys() {
+		deps, _ := d.dependents.Get(obj)
+		for _, dep := range deps {
+			if forward, _ := d.dependencies.Get(dep.Dependent); !slices.Contains(forward, dep) {
+				v.errorf("%s is a dependent of %s, but doesn't depend on it", describe(dep.Dependent), describe(obj))
+			}
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestCatalog_Validate(t *testing.T) {
+	const schema = `
+	CREATE TABLE users (id int PRIMARY KEY, email text UNIQUE);
+	CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id), title text);
+	CREATE INDEX posts_title ON posts (title);
+	CREATE STATISTICS posts_stats ON author, title FROM posts;
+	ALTER TABLE posts CLUSTER ON posts_title;
+	`
+	c := assertParse(t, schema)
+	require.Nil(t, c.Catalog.Validate())
+
+	// Dropping a table behind the compiler's back leaves the foreign key
+	// referring to it dangling
+	c = assertParse(t, schema)
+	sch, _ := c.Catalog.Schemas.Get("public")
+	sch.Tables.Remove("users")
+	err := c.Catalog.Validate()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "constraint users_pkey is on a table which was dropped")
+	assert.Contains(t, err.Error(), "constraint public.posts.posts_author_fkey uses column id, which was dropped")
+	assert.Contains(t, err.Error(), "constraint public.posts.posts_author_fkey depends on column public.users.id, which was dropped")
+
+	c = assertParse(t, schema)
+	posts := assertTable(t, c, "posts")
+	title, _ := posts.Columns.Get("title")
+	title.Name = "headline"
+	title.Table = assertTable(t, c, "users")
+	delete(c.Catalog.Depends.IndexesByName, "public.posts_title")
+	err = c.Catalog.Validate()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "column public.users.headline isn't kept under its name")
+	assert.Contains(t, err.Error(), "column headline of table public.posts belongs to table users")
+	assert.Contains(t, err.Error(), "index posts_title is registered on column headline but was dropped")
+	assert.Contains(t, err.Error(), "table public.posts is clustered on index posts_title, which doesn't exist")
+
+	c = assertParse(t, schema)
+	id, _ := assertTable(t, c, "users").Columns.Get("id")
+	c.Catalog.Depends.RemoveConstraint(constraintsByName(c)["users_pkey"])
+	id.Attrs.Pkey = true
+	posts = assertTable(t, c, "posts")
+	posts.OID = id.OID
+	err = c.Catalog.Validate()
+	require.NotNil(t, err)
+	assert.Contains(t, err.Error(), "column public.users.id is marked as part of a primary key, but isn't")
+	assert.Contains(t, err.Error(), "has the OID")
+}
+
+func TestCompiler_ValidateCatalog(t *testing.T) {
+	schema := SyntheticSchema{Tables: 20, Columns: 5, Seed: 1}
+	c := NewCompiler()
+	c.ValidateCatalog = true
+	require.Nil(t, c.Compile(schema.SQL()))
+	require.Nil(t, c.Compile(schema.Changed()))
+	require.Nil(t, c.Compile(`DROP TABLE t0 CASCADE; ALTER TABLE t1 DROP COLUMN c0 CASCADE;`))
+}
@@ -0,0 +1,399 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ScratchDatabase checks the compiler against Postgres itself: migrations
+// are applied to a database created for the purpose, and the catalog
+// Postgres ends up with is compared against the compiler's. Statements are
+// run with psql rather than a driver, so that migrations using psql's
+// meta-commands run as they would in production.
+type ScratchDatabase struct {
+	// Server is a connection string, as psql accepts, for a database of the
+	// server to create the scratch database on. The role connecting needs
+	// CREATEDB.
+	Server string
+	// Psql is the psql binary to run, or empty to find psql in PATH.
+	Psql string
+}
+
+// Discrepancy is an object the compiler's catalog and the database's
+// disagree about.
+type Discrepancy struct {
+	// Object is the kind and name of the object, such as "column
+	// public.users.id".
+	Object string
+	// Compiler and Database describe the object as each has it. Objects
+	// which are only described by existing, such as tables, have empty
+	// descriptions.
+	Compiler string `json:",omitempty"`
+	Database string `json:",omitempty"`
+	// MissingFrom is "compiler" or "database" if only the other has the
+	// object.
+	MissingFrom string `json:",omitempty"`
+}
+
+func (d Discrepancy) String() string {
+
+	switch d.MissingFrom {
+	case "database":
+		return strings.TrimSuffix(d.Object+" is only in the compiler's catalog: "+d.Compiler, ": ")
+	case "compiler":
+		return strings.TrimSuffix(d.Object+" is only in the database: "+d.Database, ": ")
+	}
+	return fmt.Sprintf("%s differs: the compiler has %s, the database has %s", d.Object, d.Compiler, d.Database)
+}
+
+// Verify applies the migrations at paths to a scratch database, which is
+// dropped afterwards, and compares the catalog it ends up with against
+// that of c, which has compiled the same paths.
+func (s *ScratchDatabase) Verify(c *Compiler, paths []string) ([]Discrepancy, error) {
+
+	files, err := expandPaths(paths, c.Migrations)
+	if err != nil {
+		return nil, err
+	}
+	var script strings.Builder
+	for _, f := range files {
+		src, err := os.ReadFile(f.path)
+		if err != nil {
+			return nil, err
+		}
+		script.WriteString(f.source.Up(string(src)))
+		script.WriteString("\n")
+	}
+
+	name := fmt.Sprintf("pgmodelgen_verify_%d", time.Now().UnixNano())
+	_, err = s.psql(s.Server, nil, "-c", "CREATE DATABASE "+name)
+	if err != nil {
+		return nil, fmt.Errorf("while creating the scratch database: %w", err)
+	}
+	defer s.psql(s.Server, nil, "-c", "DROP DATABASE IF EXISTS "+name)
+	scratch := withDatabase(s.Server, name)
+	_, err = s.psql(scratch, strings.NewReader(script.String()), "-f", "-")
+	if err != nil {
+		return nil, fmt.Errorf("while applying migrations: %w", err)
+	}
+	out, err := s.psql(scratch, nil, "-A", "-t", "-F", "\t", "-c", databaseFactsQuery)
+	if err != nil {
+		return nil, fmt.Errorf("while reading the database's catalog: %w", err)
+	}
+	database, err := databaseFacts(out)
+	if err != nil {
+		return nil, err
+	}
+	return compareFacts(compilerFacts(c.Catalog), database), nil
+}
+
+// psql runs psql against the database conn connects to, with stdin as its
+// input, returning what it writes to stdout.
+func (s *ScratchDatabase) psql(conn string, stdin io.Reader, args ...string) (string, error) {
+
+	args = append([]string{"-X", "-q", "-v", "ON_ERROR_STOP=1", "-d", conn}, args...)
+	cmd := exec.Command(cmp.Or(s.Psql, "psql"), args...)
+	cmd.Stdin = stdin
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if msg := strings.TrimSpace(stderr.String()); err != nil && msg != "" {
+		return "", fmt.Errorf("%s: %w", msg, err)
+	}
+	return string(out), err
+}
+
+// withDatabase returns the connection string conn, either a URI or
+// keyword/value pairs, connecting to the database named instead.
+func withDatabase(conn, name string) string {
+
+	if strings.HasPrefix(conn, "postgres://") || strings.HasPrefix(conn, "postgresql://") {
+		if u, err := url.Parse(conn); err == nil {
+			u.Path = "/" + name
+			return u.String()
+		}
+	}
+	// A keyword given twice takes the last value
+	return strings.TrimSpace(conn + " dbname=" + name)
+}
+
+// The facts compared describe the objects both catalogs model in the same
+// terms. Expressions, such as defaults and index predicates, are left out
+// since Postgres rewrites them when storing them, as are sequences owned by
+// columns, which the compiler doesn't record for serial columns, and the
+// objects of extensions.
+const (
+	factSchema     = "schema"
+	factTable      = "table"
+	factView       = "view"
+	factColumn     = "column"
+	factConstraint = "constraint"
+	factIndex      = "index"
+	factEnum       = "type"
+	factSequence   = "sequence"
+	// factAttnum is the number of a column, which differs if the compiler
+	// doesn't add and drop columns as Postgres does, leaving them in a
+	// different order.
+	factAttnum = "attnum"
+)
+
+// databaseFactsQuery lists the facts of a database, as the kind, schema,
+// name and description of each object, separated by tabs. System schemas
+// are filtered out after.
+const databaseFactsQuery = `
+WITH rels AS (
+    SELECT c.oid, c.relname, c.relkind, n.nspname
+    FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+    WHERE NOT EXISTS (SELECT FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = c.oid AND d.deptype = 'e')
+), cols AS (
+    SELECT a.attrelid, a.attnum, a.attname FROM pg_attribute a WHERE a.attnum > 0 AND NOT a.attisdropped
+)
+SELECT 'schema', n.nspname, '', ''
+FROM pg_namespace n
+UNION ALL
+SELECT CASE r.relkind WHEN 'v' THEN 'view' ELSE 'table' END, r.nspname, r.relname, ''
+FROM rels r WHERE r.relkind IN ('r', 'p', 'v')
+UNION ALL
+SELECT 'column', r.nspname, r.relname || '.' || a.attname,
+    format_type(a.atttypid, a.atttypmod)
+    || CASE WHEN a.attnotnull THEN ' not null' ELSE '' END
+    || CASE WHEN a.atthasdef THEN ' default' ELSE '' END
+    || CASE a.attidentity WHEN 'a' THEN ' generated always as identity' WHEN 'd' THEN ' generated by default as identity' ELSE '' END
+FROM rels r JOIN pg_attribute a ON a.attrelid = r.oid
+WHERE r.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+UNION ALL
+SELECT 'attnum', r.nspname, r.relname || '.' || a.attname, a.attnum::text
+FROM rels r JOIN pg_attribute a ON a.attrelid = r.oid
+WHERE r.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+UNION ALL
+SELECT 'constraint', r.nspname, r.relname || '.' || con.conname,
+    CASE con.contype WHEN 'p' THEN 'primary key' WHEN 'u' THEN 'unique' WHEN 'c' THEN 'check' ELSE 'foreign key' END
+    || CASE WHEN con.contype = 'c' THEN '' ELSE ' (' || (SELECT string_agg(a.attname, ', ' ORDER BY k.ord)
+        FROM unnest(con.conkey) WITH ORDINALITY k(attnum, ord) JOIN cols a ON a.attrelid = con.conrelid AND a.attnum = k.attnum) || ')' END
+    || CASE WHEN con.contype = 'f' THEN ' references ' || fn.nspname || '.' || f.relname
+        || ' (' || (SELECT string_agg(a.attname, ', ' ORDER BY k.ord) FROM unnest(con.confkey) WITH ORDINALITY k(attnum, ord)
+            JOIN cols a ON a.attrelid = con.confrelid AND a.attnum = k.attnum) || ')' ELSE '' END
+    || CASE WHEN NOT con.convalidated THEN ' not valid' ELSE '' END
+FROM pg_constraint con JOIN rels r ON r.oid = con.conrelid
+    LEFT JOIN pg_class f ON f.oid = con.confrelid LEFT JOIN pg_namespace fn ON fn.oid = f.relnamespace
+WHERE con.contype IN ('p', 'u', 'f', 'c')
+UNION ALL
+SELECT 'index', r.nspname, i.relname,
+    'on ' || r.relname || CASE WHEN x.indisunique THEN ' unique' ELSE '' END || ' using ' || am.amname
+    || ' (' || (SELECT string_agg(coalesce(a.attname, '(expression)'), ', ' ORDER BY k.ord)
+        FROM unnest(x.indkey) WITH ORDINALITY k(attnum, ord)
+        LEFT JOIN cols a ON a.attrelid = x.indrelid AND a.attnum = k.attnum WHERE k.ord <= x.indnkeyatts) || ')'
+FROM pg_index x JOIN rels r ON r.oid = x.indrelid JOIN pg_class i ON i.oid = x.indexrelid JOIN pg_am am ON am.oid = i.relam
+WHERE NOT EXISTS (SELECT FROM pg_constraint con WHERE con.conindid = x.indexrelid AND con.contype IN ('p', 'u', 'x'))
+UNION ALL
+SELECT 'type', n.nspname, t.typname, (SELECT string_agg(e.enumlabel, ', ' ORDER BY e.enumsortorder) FROM pg_enum e WHERE e.enumtypid = t.oid)
+FROM pg_type t JOIN pg_namespace n ON n.oid = t.typnamespace
+WHERE t.typtype = 'e' AND NOT EXISTS (SELECT FROM pg_depend d WHERE d.classid = 'pg_type'::regclass AND d.objid = t.oid AND d.deptype = 'e')
+UNION ALL
+SELECT 'sequence', r.nspname, r.relname, ''
+FROM rels r
+WHERE r.relkind = 'S' AND NOT EXISTS (SELECT FROM pg_depend d WHERE d.classid = 'pg_class'::regclass AND d.objid = r.oid AND d.deptype IN ('a', 'i'))
+`
+
+// databaseFacts reads the output of databaseFactsQuery into facts keyed by
+// object.
+func databaseFacts(out string) (map[string]string, error) {
+
+	facts := make(map[string]string)
+	sc := bufio.NewScanner(strings.NewReader(out))
+	for sc.Scan() {
+		if sc.Text() == "" {
+			continue
+		}
+		fields := strings.Split(sc.Text(), "\t")
+		if len(fields) != 4 {
+			return nil, fmt.Errorf("unexpected row of the database's catalog: %q", sc.Text())
+		}
+		kind, schema, name, desc := fields[0], fields[1], fields[2], fields[3]
+		if systemSchema(schema) {
+			continue
+		}
+		if kind == factSchema {
+			facts[kind+" "+schema] = desc
+		} else {
+			facts[kind+" "+schema+"."+name] = desc
+		}
+	}
+	return facts, sc.Err()
+}
+
+// compilerFacts describes the objects of cat in the same terms as
+// databaseFactsQuery.
+func compilerFacts(cat *Catalog) map[string]string {
+
+	facts := make(map[string]string)
+	for _, sch := range cat.Schemas.List() {
+		facts[factSchema+" "+sch.Name] = ""
+		for _, t := range sch.Tables.List() {
+			if t.Extension != "" {
+				continue
+			}
+			facts[factTable+" "+sch.Name+"."+t.Name] = ""
+			for _, col := range t.Columns.List() {
+				def := definitionOf(col, true)
+				desc := def.Type
+				// Postgres makes the columns of a primary key not null
+				if def.NotNull || col.Attrs.Pkey {
+					desc += " not null"
+				}
+				if def.Default != "" {
+					desc += " default"
+				}
+				if def.Identity != IdentityNone {
+					desc += " generated " + strings.ToLower(identityDefinition(def.Identity)) + " as identity"
+				}
+				facts[factColumn+" "+sch.Name+"."+t.Name+"."+col.Name] = desc
+				facts[factAttnum+" "+sch.Name+"."+t.Name+"."+col.Name] = strconv.Itoa(col.Attnum)
+			}
+			for _, con := range cat.Depends.TableConstraints(t) {
+				facts[factConstraint+" "+sch.Name+"."+t.Name+"."+con.Name] = constraintFact(con)
+			}
+			for _, idx := range cat.Depends.TableIndexes(t) {
+				facts[factIndex+" "+idx.QualifiedName()] = indexFact(idx)
+			}
+		}
+		for _, e := range sch.Enums.List() {
+			facts[factEnum+" "+sch.Name+"."+e.Name] = strings.Join(e.Labels, ", ")
+		}
+		for _, seq := range sch.Sequences.List() {
+			if seq.OwnedBy == nil {
+				facts[factSequence+" "+sch.Name+"."+seq.Name] = ""
+			}
+		}
+	}
+	for _, raw := range cat.Raw {
+		if raw.Kind == "CREATE VIEW" && raw.Extension == "" {
+			facts[factView+" "+raw.Name] = ""
+		}
+	}
+	return facts
+}
+
+func constraintFact(con *Constraint) string {
+
+	var desc string
+	switch con.Type {
+	case ConstraintTypePrimary:
+		desc = "primary key"
+	case ConstraintTypeUnique:
+		desc = "unique"
+	case ConstraintTypeForeignKey:
+		desc = "foreign key"
+	case ConstraintTypeCheck:
+		// Postgres normalizes the expression differently, so only
+		// whether there's a check of the name is compared
+		desc = "check"
+	}
+	if con.Type != ConstraintTypeCheck {
+		desc += " (" + columnNames(con.Constrains) + ")"
+	}
+	if con.Type == ConstraintTypeForeignKey {
+		t := con.Refers[0].Table
+		desc += " references " + t.Schema + "." + t.Name + " (" + columnNames(con.Refers) + ")"
+	}
+	if con.NotValid {
+		desc += " not valid"
+	}
+	return desc
+}
+
+func indexFact(idx *Index) string {
+
+	desc := "on " + idx.Table.Name
+	if idx.Unique {
+		desc += " unique"
+	}
+	elems := make([]string, 0, len(idx.Elems))
+	for _, elem := range idx.Elems {
+		if elem.Column != nil {
+			elems = append(elems, elem.Column.Name)
+		} else {
+			elems = append(elems, "(expression)")
+		}
+	}
+	return desc + " using " + cmp.Or(idx.Method, "btree") + " (" + strings.Join(elems, ", ") + ")"
+}
+
+// compareFacts returns the objects described differently by compiler and
+// database, sorted by object.
+func compareFacts(compiler, database map[string]string) []Discrepancy {
+
+	var ret []Discrepancy
+	for obj, desc := range compiler {
+		other, ok := database[obj]
+		switch {
+		case !ok:
+			ret = append(ret, Discrepancy{Object: obj, Compiler: desc, MissingFrom: "database"})
+		case other != desc:
+			ret = append(ret, Discrepancy{Object: obj, Compiler: desc, Database: other})
+		}
+	}
+	for obj, desc := range database {
+		if _, ok := compiler[obj]; !ok {
+			ret = append(ret, Discrepancy{Object: obj, Database: desc, MissingFrom: "compiler"})
+		}
+	}
+	slices.SortFunc(ret, func(a, b Discrepancy) int { return strings.Compare(a.Object, b.Object) })
+	return ret
+}
+
+func runVerify(args []string) error {
+
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	db := &ScratchDatabase{}
+	fs.StringVar(&db.Server, "db", "", "connection string of the server to create the scratch database on, defaulting to psql's")
+	fs.StringVar(&db.Psql, "psql-bin", "", "psql binary to apply migrations with, defaults to psql in PATH")
+	out := fs.String("out", "", "file to write to, defaults to stdout")
+	compile := compilerFlags(fs)
+	err := fs.Parse(args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return fmt.Errorf("no input files")
+	}
+	c, err := compile(fs.Args())
+	if err != nil {
+		return err
+	}
+	discrepancies, err := db.Verify(c, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	for _, d := range discrepancies {
+		_, err = fmt.Fprintln(w, d)
+		if err != nil {
+			return err
+		}
+	}
+	if len(discrepancies) > 0 {
+		return fmt.Errorf("the compiler's catalog and the database's differ in %s", countOf(len(discrepancies), "object"))
+	}
+	return nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+const verifySchema = `
+CREATE TABLE users (id serial PRIMARY KEY, email text NOT NULL);
+CREATE TABLE posts (id int GENERATED ALWAYS AS IDENTITY, user_id int REFERENCES users (id));
+CREATE INDEX posts_user_id ON posts (user_id);
+CREATE TYPE mood AS ENUM ('happy', 'sad');
+CREATE VIEW emails AS SELECT email FROM users;
+`
+
+// verifyFacts is what databaseFactsQuery returns for verifySchema.
+const verifyFacts = "schema\tpublic\t\t\n" +
+	"schema\tpg_catalog\t\t\n" +
+	"table\tpublic\tusers\t\n" +
+	"column\tpublic\tusers.id\tinteger not null default\n" +
+	"column\tpublic\tusers.email\ttext not null\n" +
+	"attnum\tpublic\tusers.id\t1\n" +
+	"attnum\tpublic\tusers.email\t2\n" +
+	"constraint\tpublic\tusers.users_pkey\tprimary key (id)\n" +
+	"table\tpublic\tposts\t\n" +
+	"column\tpublic\tposts.id\tinteger not null generated always as identity\n" +
+	"column\tpublic\tposts.user_id\tinteger\n" +
+	"attnum\tpublic\tposts.id\t1\n" +
+	"attnum\tpublic\tposts.user_id\t2\n" +
+	"constraint\tpublic\tposts.posts_user_id_fkey\tforeign key (user_id) references public.users (id)\n" +
+	"index\tpublic\tposts_user_id\ton posts using btree (user_id)\n" +
+	"type\tpublic\tmood\thappy, sad\n" +
+	"view\tpublic\temails\t\n" +
+	"table\tpg_catalog\tpg_class\t\n"
+
+func TestCompareFacts(t *testing.T) {
+	c := assertParse(t, verifySchema)
+	database, err := databaseFacts(verifyFacts)
+	require.Nil(t, err)
+	assert.Empty(t, compareFacts(compilerFacts(c.Catalog), database))
+
+	database, err = databaseFacts(strings.ReplaceAll(verifyFacts, "integer not null default", "bigint not null default") +
+		"attnum\tpublic\tposts.user_id\t3\n" +
+		"table\tpublic\taudit\t\n")
+	require.Nil(t, err)
+	delete(database, "view public.emails")
+	var got []string
+	for _, d := range compareFacts(compilerFacts(c.Catalog), database) {
+		got = append(got, d.String())
+	}
+	assert.Equal(t, []string{
+		"attnum public.posts.user_id differs: the compiler has 2, the database has 3",
+		"column public.users.id differs: the compiler has integer not null default, the database has bigint not null default",
+		"table public.audit is only in the database",
+		"view public.emails is only in the compiler's catalog",
+	}, got)
+
+	_, err = databaseFacts("schema\tpublic\n")
+	assert.ErrorContains(t, err, "unexpected row")
+}
+
+func TestWithDatabase(t *testing.T) {
+	assert.Equal(t, "postgres://u:p@localhost:5432/scratch?sslmode=disable", withDatabase("postgres://u:p@localhost:5432/postgres?sslmode=disable", "scratch"))
+	assert.Equal(t, "host=localhost dbname=postgres dbname=scratch", withDatabase("host=localhost dbname=postgres", "scratch"))
+	assert.Equal(t, "dbname=scratch", withDatabase("", "scratch"))
+}
+
+func TestScratchDatabase_Verify(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the fake psql is a shell script")
+	}
+	dir := t.TempDir()
+	migration := filepath.Join(dir, "schema.sql")
+	require.Nil(t, os.WriteFile(migration, []byte(verifySchema), 0o644))
+	facts := filepath.Join(dir, "facts")
+	require.Nil(t, os.WriteFile(facts, []byte(verifyFacts), 0o644))
+	log := filepath.Join(dir, "log")
+	psql := filepath.Join(dir, "psql")
+	require.Nil(t, os.WriteFile(psql, []byte(fmt.Sprintf(`#!/bin/sh
+for arg; do
+	case "$arg" in *"WITH rels"*) cat %[1]q; exit 0;; esac
+done
+echo "$6 $8" >> %[2]q
+cat >> %[2]q
+`, facts, log)), 0o755))
+
+	c := assertParse(t, verifySchema)
+	db := &ScratchDatabase{Server: "host=localhost", Psql: psql}
+	discrepancies, err := db.Verify(c, []string{migration})
+	require.Nil(t, err)
+	assert.Empty(t, discrepancies)
+	logged, err := os.ReadFile(log)
+	require.Nil(t, err)
+	lines := strings.Split(strings.TrimSpace(string(logged)), "\n")
+	assert.Regexp(t, `^host=localhost CREATE DATABASE pgmodelgen_verify_\d+$`, lines[0])
+	assert.Regexp(t, `^host=localhost dbname=pgmodelgen_verify_\d+ -$`, lines[1])
+	assert.Contains(t, string(logged), "CREATE TYPE mood")
+	assert.Regexp(t, `^host=localhost DROP DATABASE IF EXISTS pgmodelgen_verify_\d+$`, lines[len(lines)-1])
+
+	db.Psql = filepath.Join(dir, "missing")
+	_, err = db.Verify(c, []string{migration})
+	assert.ErrorContains(t, err, "while creating the scratch database")
+}
+
+// TestScratchDatabase_VerifyPostgres runs against a real server, such as the
+// one in docker-compose.yaml, when PGMODELGEN_TEST_DATABASE is set to its
+// connection string.
+func TestScratchDatabase_VerifyPostgres(t *testing.T) {
+	conn := os.Getenv("PGMODELGEN_TEST_DATABASE")
+	if conn == "" {
+		t.Skip("PGMODELGEN_TEST_DATABASE isn't set")
+	}
+	migration := filepath.Join(t.TempDir(), "schema.sql")
+	require.Nil(t, os.WriteFile(migration, []byte(verifySchema), 0o644))
+	c := assertParse(t, verifySchema)
+	discrepancies, err := (&ScratchDatabase{Server: conn}).Verify(c, []string{migration})
+	require.Nil(t, err)
+	assert.Empty(t, discrepancies)
+}
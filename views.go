@@ -0,0 +1,884 @@
+package main
+
+import (
+	"fmt"
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"slices"
+	"strings"
+)
+
+// Views aren't modeled beyond what they use, so they're kept as raw
+// statements. Their queries are resolved against the catalog to find the
+// tables, columns and other views they use, so that dropping any of those
+// fails, or drops the view too with CASCADE, as it does in Postgres.
+// Relations the catalog doesn't have, such as those in pg_catalog, are
+// ignored.
+
+// CreateView keeps a CREATE VIEW statement, along with the dependencies of
+// its query.
+func (c *Compiler) CreateView(stmt *pg_query.ViewStmt) error {
+
+	// A view using temporary objects is temporary itself
+	deps := c.queryDependencies(stmt.Query)
+	if slices.ContainsFunc(deps, temporaryObject) && stmt.View.Schemaname == "" {
+		stmt.View.Relpersistence = persistenceTemporary
+	}
+	schema, err := c.createSchema(stmt.View)
+	if err != nil {
+		return err
+	}
+	name := schema + "." + stmt.View.Relname
+	if _, err := c.FindTableFromSchemaAndName(schema, stmt.View.Relname); err == nil {
+		return fmt.Errorf("relation %s already exists", name)
+	}
+	sql, err := c.statementSQL()
+	if err != nil {
+		return err
+	}
+	cols := c.viewColumns(stmt.Query, stmt.Aliases)
+	view := c.findOpaque("CREATE VIEW", name)
+	switch {
+	case view == nil:
+		{
+			view = &RawStatement{Kind: "CREATE VIEW", Name: name, SQL: sql, Columns: cols}
+			c.Catalog.AddRaw(view)
+		}
+	case !stmt.Replace:
+		return fmt.Errorf("view %s already exists", name)
+	default:
+		{
+			err = checkReplaceView(view.Columns, cols)
+			if err != nil {
+				return err
+			}
+			// Views depending on this one still do, so it's replaced in place
+			view.SQL, view.Columns = sql, cols
+			c.Catalog.Depends.removeDependencies(view)
+		}
+	}
+	for _, obj := range deps {
+		c.Catalog.Depends.addDependency(view, obj, DropBehaviourRestrict)
+	}
+	return nil
+}
+
+// DropView drops a view, and the views depending on it if the drop
+// cascades.
+func (c *Compiler) DropView(names []string, missingOk bool, behav DropBehaviour) error {
+
+	name := c.relationName(names)
+	view := c.findOpaque("CREATE VIEW", name)
+	if view == nil {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("view %s not found", name)
+	}
+	deps := c.Catalog.Depends.DependentsOf(view)
+	if behav != DropBehaviourCascade {
+		if blocking := restrictingDependents(deps, nil); blocking != "" {
+			return fmt.Errorf("can't drop view %s because other objects depend on it and cascade was not specified: %s", name, blocking)
+		}
+	}
+	c.dropDependents(deps)
+	c.Catalog.RemoveRaw(func(raw *RawStatement) bool {
+		return raw == view
+	})
+	return nil
+}
+
+// queryDependencies returns the tables, columns and views query uses. A
+// column is used if a reference to it is qualified by its table or the
+// table's alias, or if it isn't qualified and it's in any of the tables the
+// query uses, so more columns may be found than Postgres would.
+func (c *Compiler) queryDependencies(query *pg_query.Node) []any {
+
+	var relations []*pg_query.RangeVar
+	var refs []*pg_query.ColumnRef
+	ctes := make(map[string]bool)
+	walkNodes(query.ProtoReflect(), func(m protoreflect.Message) {
+		switch n := m.Interface().(type) {
+		case *pg_query.RangeVar:
+			relations = append(relations, n)
+		case *pg_query.ColumnRef:
+			refs = append(refs, n)
+		case *pg_query.CommonTableExpr:
+			ctes[n.Ctename] = true
+		}
+	})
+
+	var deps []any
+	seen := make(map[any]bool)
+	add := func(obj any) {
+		if !seen[obj] {
+			seen[obj] = true
+			deps = append(deps, obj)
+		}
+	}
+	var tables []*Table
+	aliases := make(map[string]*Table)
+	for _, rv := range relations {
+		if rv.Schemaname == "" && ctes[rv.Relname] {
+			continue
+		}
+		if t, err := c.FindTableFromRangeVar(rv); err == nil {
+			add(t)
+			tables = append(tables, t)
+			aliases[t.Name] = t
+			if rv.Alias != nil {
+				aliases[rv.Alias.Aliasname] = t
+			}
+			continue
+		}
+		if view := c.findOpaque("CREATE VIEW", c.relationName(rangeVarNames(rv))); view != nil {
+			add(view)
+		}
+	}
+	for _, ref := range refs {
+		var names []string
+		star := false
+		for _, f := range ref.Fields {
+			if f.GetAStar() != nil {
+				star = true
+			} else if s := f.GetString_(); s != nil {
+				names = append(names, s.Sval)
+			}
+		}
+		var name string
+		if !star {
+			name, names = names[len(names)-1], names[:len(names)-1]
+		}
+		scope := tables
+		if len(names) > 0 {
+			// Qualified by the table or its alias
+			t, ok := aliases[names[len(names)-1]]
+			if !ok {
+				continue
+			}
+			scope = []*Table{t}
+		}
+		for _, t := range scope {
+			if star {
+				for _, col := range t.Columns.List() {
+					add(col)
+				}
+			} else if col, ok := t.Columns.Get(name); ok {
+				add(col)
+			}
+		}
+	}
+	return deps
+}
+
+// rangeVarNames returns the parts of a relation's name.
+func rangeVarNames(rv *pg_query.RangeVar) []string {
+
+	if rv.Schemaname != "" {
+		return []string{rv.Schemaname, rv.Relname}
+	}
+	return []string{rv.Relname}
+}
+
+// restrictingDependents lists the dependents in deps which prevent dropping
+// the object they depend on without CASCADE, other than those belonging to
+// the table owner, or an empty string if there are none.
+func restrictingDependents(deps []*Dependency, owner *Table) string {
+
+	var names []string
+	seen := make(map[any]bool)
+	for _, dep := range deps {
+		if dep.Behaviour != DropBehaviourRestrict || seen[dep.Dependent] {
+			continue
+		}
+		seen[dep.Dependent] = true
+		if owner != nil && objectTable(dep.Dependent) == owner {
+			continue
+		}
+		kind, name := objectName(dep.Dependent)
+		names = append(names, kind+" "+name)
+	}
+	return strings.Join(names, ", ")
+}
+
+// ViewColumn is an output column of a view.
+type ViewColumn struct {
+	Name string
+	// Type is the column's type, or empty if it couldn't be resolved.
+	Type string
+	// Sources are the columns of tables the column's values are taken
+	// from, through any views, subqueries and common table expressions
+	// between.
+	Sources Columns
+}
+
+// checkReplaceView checks that a view's columns can be replaced by cols.
+// As in Postgres, columns can only be added after the existing ones.
+func checkReplaceView(old, cols []*ViewColumn) error {
+
+	if len(cols) < len(old) {
+		return fmt.Errorf("cannot drop columns from view")
+	}
+	for i, col := range old {
+		if cols[i].Name != col.Name {
+			return fmt.Errorf("cannot change name of view column %q to %q", col.Name, cols[i].Name)
+		}
+		if col.Type != "" && cols[i].Type != "" && cols[i].Type != col.Type {
+			return fmt.Errorf("cannot change data type of view column %q from %s to %s", col.Name, col.Type, cols[i].Type)
+		}
+	}
+	return nil
+}
+
+// viewColumns resolves the output columns of a view's query, named by
+// aliases if it gives any.
+func (c *Compiler) viewColumns(query *pg_query.Node, aliases []*pg_query.Node) []*ViewColumn {
+
+	cols := c.selectColumns(query.GetSelectStmt(), nil)
+	for i, alias := range aliases {
+		if i < len(cols) {
+			if name, err := NodeString(alias); err == nil {
+				cols[i].Name = name
+			}
+		}
+	}
+	return cols
+}
+
+// queryRelation is a relation in a query's FROM clause.
+type queryRelation struct {
+	name    string
+	columns []*ViewColumn
+}
+
+// selectColumns resolves the output columns of sel, where ctes are the
+// columns of the common table expressions in scope.
+func (c *Compiler) selectColumns(sel *pg_query.SelectStmt, ctes map[string][]*ViewColumn) []*ViewColumn {
+
+	if sel == nil {
+		return nil
+	}
+	if sel.Op != pg_query.SetOperation_SETOP_NONE {
+		// Each column is named by the left query but takes its values from
+		// both
+		cols := c.selectColumns(sel.Larg, ctes)
+		for i, col := range c.selectColumns(sel.Rarg, ctes) {
+			if i < len(cols) {
+				cols[i].Sources = appendSources(cols[i].Sources, col.Sources...)
+			}
+		}
+		return cols
+	}
+	if sel.WithClause != nil {
+		inner := make(map[string][]*ViewColumn, len(ctes))
+		for name, cols := range ctes {
+			inner[name] = cols
+		}
+		for _, n := range sel.WithClause.Ctes {
+			cte := n.GetCommonTableExpr()
+			inner[cte.Ctename] = renameViewColumns(c.selectColumns(cte.Ctequery.GetSelectStmt(), inner), cte.Aliascolnames)
+		}
+		ctes = inner
+	}
+	if len(sel.ValuesLists) > 0 {
+		var cols []*ViewColumn
+		for i, n := range sel.ValuesLists[0].GetList().GetItems() {
+			cols = append(cols, &ViewColumn{Name: fmt.Sprintf("column%d", i+1), Type: c.exprType(n, nil)})
+		}
+		return cols
+	}
+
+	var rels []*queryRelation
+	for _, n := range sel.FromClause {
+		rels = append(rels, c.fromRelations(n, ctes)...)
+	}
+	var cols []*ViewColumn
+	for _, n := range sel.TargetList {
+		rt := n.GetResTarget()
+		if ref := rt.Val.GetColumnRef(); ref != nil && ref.Fields[len(ref.Fields)-1].GetAStar() != nil {
+			for _, rel := range rels {
+				if len(ref.Fields) == 1 || rel.name == ref.Fields[len(ref.Fields)-2].GetString_().GetSval() {
+					for _, col := range rel.columns {
+						cols = append(cols, &ViewColumn{Name: col.Name, Type: col.Type, Sources: col.Sources})
+					}
+				}
+			}
+			continue
+		}
+		name := rt.Name
+		if name == "" {
+			name = exprName(rt.Val)
+		}
+		cols = append(cols, &ViewColumn{Name: name, Type: c.exprType(rt.Val, rels), Sources: c.exprSources(rt.Val, rels, ctes)})
+	}
+	return cols
+}
+
+// fromRelations returns the relations an item of a FROM clause provides.
+func (c *Compiler) fromRelations(n *pg_query.Node, ctes map[string][]*ViewColumn) []*queryRelation {
+
+	switch n := n.Node.(type) {
+	case *pg_query.Node_RangeVar:
+		{
+			rv := n.RangeVar
+			rel := &queryRelation{name: rv.Relname}
+			if cols, ok := ctes[rv.Relname]; ok && rv.Schemaname == "" {
+				rel.columns = cols
+			} else if t, err := c.FindTableFromRangeVar(rv); err == nil {
+				for _, col := range t.Columns.List() {
+					rel.columns = append(rel.columns, &ViewColumn{Name: col.Name, Type: col.FormatType(), Sources: Columns{col}})
+				}
+			} else if view := c.findOpaque("CREATE VIEW", c.relationName(rangeVarNames(rv))); view != nil {
+				rel.columns = view.Columns
+			}
+			if rv.Alias != nil {
+				rel.name = rv.Alias.Aliasname
+				rel.columns = renameViewColumns(rel.columns, rv.Alias.Colnames)
+			}
+			return []*queryRelation{rel}
+		}
+	case *pg_query.Node_JoinExpr:
+		return append(c.fromRelations(n.JoinExpr.Larg, ctes), c.fromRelations(n.JoinExpr.Rarg, ctes)...)
+	case *pg_query.Node_RangeSubselect:
+		{
+			rel := &queryRelation{columns: c.selectColumns(n.RangeSubselect.Subquery.GetSelectStmt(), ctes)}
+			if alias := n.RangeSubselect.Alias; alias != nil {
+				rel.name = alias.Aliasname
+				rel.columns = renameViewColumns(rel.columns, alias.Colnames)
+			}
+			return []*queryRelation{rel}
+		}
+	default:
+		return nil
+	}
+}
+
+// renameViewColumns returns a copy of cols with the first renamed by
+// names.
+func renameViewColumns(cols []*ViewColumn, names []*pg_query.Node) []*ViewColumn {
+
+	ret := make([]*ViewColumn, len(cols))
+	for i, col := range cols {
+		ret[i] = &ViewColumn{Name: col.Name, Type: col.Type, Sources: col.Sources}
+		if i < len(names) {
+			if name, err := NodeString(names[i]); err == nil {
+				ret[i].Name = name
+			}
+		}
+	}
+	return ret
+}
+
+// exprName returns the name Postgres gives an output column without an
+// alias.
+func exprName(n *pg_query.Node) string {
+
+	switch n := n.Node.(type) {
+	case *pg_query.Node_ColumnRef:
+		return lastName(n.ColumnRef.Fields)
+	case *pg_query.Node_TypeCast:
+		{
+			if n.TypeCast.Arg.GetColumnRef() != nil {
+				return exprName(n.TypeCast.Arg)
+			}
+			return lastName(n.TypeCast.TypeName.GetNames())
+		}
+	case *pg_query.Node_FuncCall:
+		return lastName(n.FuncCall.Funcname)
+	default:
+		return "?column?"
+	}
+}
+
+// lastName returns the last part of a name, or the name Postgres gives
+// output columns it can't name if that isn't a string.
+func lastName(names []*pg_query.Node) string {
+
+	if len(names) == 0 {
+		return "?column?"
+	}
+	name, err := NodeString(names[len(names)-1])
+	if err != nil {
+		return "?column?"
+	}
+	return name
+}
+
+// exprType returns the type of an output column's expression, or empty if
+// it can't be resolved.
+func (c *Compiler) exprType(n *pg_query.Node, rels []*queryRelation) string {
+
+	switch n := n.Node.(type) {
+	case *pg_query.Node_ColumnRef:
+		{
+			names, err := NodeStrings(n.ColumnRef.Fields)
+			if err != nil || len(names) == 0 {
+				return ""
+			}
+			name := names[len(names)-1]
+			for _, rel := range rels {
+				if len(names) > 1 && rel.name != names[len(names)-2] {
+					continue
+				}
+				for _, col := range rel.columns {
+					if col.Name == name {
+						return col.Type
+					}
+				}
+			}
+			return ""
+		}
+	case *pg_query.Node_TypeCast:
+		{
+			tn := n.TypeCast.TypeName
+			name, err := typeNameString(tn)
+			if err != nil {
+				return ""
+			}
+			if t := LookupType(strings.TrimSuffix(name, strings.Repeat("[]", len(tn.ArrayBounds)))); t != nil {
+				return t.Format(TypeModsFromNode(tn)) + strings.Repeat("[]", len(tn.ArrayBounds))
+			}
+			return name
+		}
+	case *pg_query.Node_AConst:
+		switch n.AConst.Val.(type) {
+		case *pg_query.A_Const_Ival:
+			return Integer.Name
+		case *pg_query.A_Const_Fval:
+			return "numeric"
+		case *pg_query.A_Const_Boolval:
+			return Boolean.Name
+		case *pg_query.A_Const_Sval:
+			return Text.Name
+		}
+	case *pg_query.Node_FuncCall:
+		return c.funcCallType(n.FuncCall, rels)
+	case *pg_query.Node_SqlvalueFunction:
+		return sqlValueFunctionTypes[n.SqlvalueFunction.Op]
+	case *pg_query.Node_CoalesceExpr:
+		for _, arg := range n.CoalesceExpr.Args {
+			if typ := c.exprType(arg, rels); typ != "" {
+				return typ
+			}
+		}
+	}
+	return ""
+}
+
+// functionTypes are the types returned by built in functions whose type
+// doesn't depend on their arguments'.
+var functionTypes = map[string]string{
+	"count":                 Bigint.Name,
+	"row_number":            Bigint.Name,
+	"rank":                  Bigint.Name,
+	"dense_rank":            Bigint.Name,
+	"ntile":                 Integer.Name,
+	"percent_rank":          Double.Name,
+	"cume_dist":             Double.Name,
+	"random":                Double.Name,
+	"date_part":             Double.Name,
+	"extract":               Numeric.Format(nil),
+	"length":                Integer.Name,
+	"char_length":           Integer.Name,
+	"octet_length":          Integer.Name,
+	"position":              Integer.Name,
+	"bool_and":              Boolean.Name,
+	"bool_or":               Boolean.Name,
+	"every":                 Boolean.Name,
+	"lower":                 Text.Name,
+	"upper":                 Text.Name,
+	"initcap":               Text.Name,
+	"concat":                Text.Name,
+	"concat_ws":             Text.Name,
+	"format":                Text.Name,
+	"btrim":                 Text.Name,
+	"ltrim":                 Text.Name,
+	"rtrim":                 Text.Name,
+	"substring":             Text.Name,
+	"substr":                Text.Name,
+	"replace":               Text.Name,
+	"string_agg":            Text.Name,
+	"md5":                   Text.Name,
+	"to_char":               Text.Name,
+	"now":                   Timestamptz.Format(nil),
+	"clock_timestamp":       Timestamptz.Format(nil),
+	"statement_timestamp":   Timestamptz.Format(nil),
+	"transaction_timestamp": Timestamptz.Format(nil),
+	"to_timestamp":          Timestamptz.Format(nil),
+	"gen_random_uuid":       UUID.Name,
+	"json_agg":              JSON.Name,
+	"json_build_object":     JSON.Name,
+	"json_build_array":      JSON.Name,
+	"to_json":               JSON.Name,
+	"jsonb_agg":             JSONB.Name,
+	"jsonb_build_object":    JSONB.Name,
+	"jsonb_build_array":     JSONB.Name,
+	"to_jsonb":              JSONB.Name,
+}
+
+// sqlValueFunctionTypes are the types of the functions called without
+// parentheses, such as CURRENT_TIMESTAMP.
+var sqlValueFunctionTypes = map[pg_query.SQLValueFunctionOp]string{
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_DATE:        Date.Name,
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIME:        Timetz.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIME_N:      Timetz.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIMESTAMP:   Timestamptz.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIMESTAMP_N: Timestamptz.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_LOCALTIME:           Time.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_LOCALTIME_N:         Time.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_LOCALTIMESTAMP:      Timestamp.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_LOCALTIMESTAMP_N:    Timestamp.Format(nil),
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_ROLE:        "name",
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_USER:        "name",
+	pg_query.SQLValueFunctionOp_SVFOP_USER:                "name",
+	pg_query.SQLValueFunctionOp_SVFOP_SESSION_USER:        "name",
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_CATALOG:     "name",
+	pg_query.SQLValueFunctionOp_SVFOP_CURRENT_SCHEMA:      "name",
+}
+
+// funcCallType returns the type of a call to a built in function, or empty
+// if the function isn't one whose type is known. The types of aggregates
+// over an argument are those Postgres gives them for the argument's type,
+// which is without its modifiers.
+func (c *Compiler) funcCallType(fn *pg_query.FuncCall, rels []*queryRelation) string {
+
+	names, err := NodeStrings(fn.Funcname)
+	if err != nil || len(names) > 2 || len(names) == 2 && names[0] != "pg_catalog" {
+		return ""
+	}
+	name := names[len(names)-1]
+	if typ, ok := functionTypes[name]; ok {
+		return typ
+	}
+	if name == "date_trunc" && len(fn.Args) > 1 {
+		// Dates are truncated as timestamps with time zones
+		if typ := c.exprType(fn.Args[1], rels); typ != Date.Name {
+			return typ
+		}
+		return Timestamptz.Format(nil)
+	}
+	if len(fn.Args) == 0 {
+		return ""
+	}
+	argType := c.exprType(fn.Args[0], rels)
+	if argType == "" {
+		return ""
+	}
+	arg, _, dims, err := parseTypeString(argType)
+	if err != nil {
+		return ""
+	}
+	argType = arg.Format(nil) + strings.Repeat("[]", dims)
+	switch name {
+	case "min", "max", "abs":
+		return argType
+	case "array_agg":
+		return argType + "[]"
+	case "sum":
+		switch arg {
+		case Smallint, Integer:
+			return Bigint.Name
+		case Bigint:
+			return Numeric.Format(nil)
+		case Numeric, Real, Double, Money, Interval:
+			return argType
+		}
+	case "avg":
+		switch arg {
+		case Smallint, Integer, Bigint, Numeric:
+			return Numeric.Format(nil)
+		case Real, Double:
+			return Double.Name
+		case Interval:
+			return argType
+		}
+	}
+	return ""
+}
+
+// RenameView handles ALTER VIEW ... RENAME TO.
+func (c *Compiler) RenameView(rv *pg_query.RangeVar, newName string, missingOk bool) error {
+
+	return c.moveView(rv, "", newName, missingOk)
+}
+
+// SetViewSchema handles ALTER VIEW ... SET SCHEMA.
+func (c *Compiler) SetViewSchema(rv *pg_query.RangeVar, schema string, missingOk bool) error {
+
+	if _, ok := c.Catalog.Schemas.Get(schema); !ok {
+		return fmt.Errorf("no such schema: %s", schema)
+	}
+	return c.moveView(rv, schema, "", missingOk)
+}
+
+// moveView renames the view rv, or moves it to another schema, updating
+// the views which use it. Views are kept as statements, so they're
+// rewritten with the new name.
+func (c *Compiler) moveView(rv *pg_query.RangeVar, schema, name string, missingOk bool) error {
+
+	oldName := c.relationName(rangeVarNames(rv))
+	view := c.findOpaque("CREATE VIEW", oldName)
+	if view == nil {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("view %s not found", oldName)
+	}
+	oldSchema, rel, _ := strings.Cut(oldName, ".")
+	if schema == "" {
+		schema = oldSchema
+	}
+	if name == "" {
+		name = rel
+	}
+	newName := schema + "." + name
+	if _, err := c.FindTableFromSchemaAndName(schema, name); err == nil || c.findOpaque("CREATE VIEW", newName) != nil {
+		return fmt.Errorf("relation %s already exists", newName)
+	}
+	rename := func(rv *pg_query.RangeVar) {
+		if c.relationName(rangeVarNames(rv)) != oldName {
+			return
+		}
+		rv.Relname = name
+		if rv.Schemaname != "" || schema != c.SearchPath {
+			rv.Schemaname = schema
+		}
+	}
+	err := c.rewriteView(view, func(stmt *pg_query.ViewStmt) {
+		rename(stmt.View)
+	})
+	if err != nil {
+		return err
+	}
+	for _, dep := range c.Catalog.Depends.DependentsOf(view) {
+		dependent, ok := dep.Dependent.(*RawStatement)
+		if !ok {
+			continue
+		}
+		err = c.rewriteView(dependent, func(stmt *pg_query.ViewStmt) {
+			walkNodes(stmt.Query.ProtoReflect(), func(m protoreflect.Message) {
+				if rv, ok := m.Interface().(*pg_query.RangeVar); ok {
+					rename(rv)
+				}
+			})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	view.Name = newName
+	return nil
+}
+
+// RenameViewColumn handles ALTER VIEW ... RENAME COLUMN.
+func (c *Compiler) RenameViewColumn(rv *pg_query.RangeVar, oldName, newName string, missingOk bool) error {
+
+	name := c.relationName(rangeVarNames(rv))
+	view := c.findOpaque("CREATE VIEW", name)
+	if view == nil {
+		if missingOk {
+			return nil
+		}
+		return fmt.Errorf("view %s not found", name)
+	}
+	var renamed *ViewColumn
+	for _, col := range view.Columns {
+		switch col.Name {
+		case newName:
+			return fmt.Errorf("column %s of view %s already exists", newName, name)
+		case oldName:
+			renamed = col
+		}
+	}
+	if renamed == nil {
+		return fmt.Errorf("couldn't find column %s in view %s", oldName, name)
+	}
+	renamed.Name = newName
+	// The view's column list names its columns
+	return c.rewriteView(view, func(stmt *pg_query.ViewStmt) {
+		stmt.Aliases = nil
+		for _, col := range view.Columns {
+			stmt.Aliases = append(stmt.Aliases, pg_query.MakeStrNode(col.Name))
+		}
+	})
+}
+
+// rewriteView changes the statement of a view by parsing it, calling
+// rewrite on it, then deparsing it.
+func (c *Compiler) rewriteView(view *RawStatement, rewrite func(*pg_query.ViewStmt)) error {
+
+	parsed, err := pg_query.Parse(view.SQL)
+	if err != nil {
+		return fmt.Errorf("while parsing view %s: %w", view.Name, err)
+	}
+	stmt := parsed.Stmts[0].Stmt.GetViewStmt()
+	if stmt == nil {
+		return fmt.Errorf("expected ViewStmt for view %s but got %T", view.Name, parsed.Stmts[0].Stmt.Node)
+	}
+	rewrite(stmt)
+	sql, err := pg_query.Deparse(&pg_query.ParseResult{Stmts: []*pg_query.RawStmt{{Stmt: parsed.Stmts[0].Stmt}}})
+	if err != nil {
+		return err
+	}
+	view.SQL = sql
+	return nil
+}
+
+// dependentViews returns the views using obj.
+func (c *Compiler) dependentViews(obj any) []*RawStatement {
+
+	var ret []*RawStatement
+	for _, dep := range c.Catalog.Depends.DependentsOf(obj) {
+		if view, ok := dep.Dependent.(*RawStatement); ok && view.Kind == "CREATE VIEW" && !slices.Contains(ret, view) {
+			ret = append(ret, view)
+		}
+	}
+	return ret
+}
+
+// queryTables finds the relations and column references of query, and the
+// tables its relations are, other than common table expressions.
+func (c *Compiler) queryTables(query *pg_query.Node) (map[*pg_query.RangeVar]*Table, []*pg_query.ColumnRef) {
+
+	var relations []*pg_query.RangeVar
+	var refs []*pg_query.ColumnRef
+	ctes := make(map[string]bool)
+	walkNodes(query.ProtoReflect(), func(m protoreflect.Message) {
+		switch n := m.Interface().(type) {
+		case *pg_query.RangeVar:
+			relations = append(relations, n)
+		case *pg_query.ColumnRef:
+			refs = append(refs, n)
+		case *pg_query.CommonTableExpr:
+			ctes[n.Ctename] = true
+		}
+	})
+	tables := make(map[*pg_query.RangeVar]*Table)
+	for _, rv := range relations {
+		if rv.Schemaname == "" && ctes[rv.Relname] {
+			continue
+		}
+		if t, err := c.FindTableFromRangeVar(rv); err == nil {
+			tables[rv] = t
+		}
+	}
+	return tables, refs
+}
+
+// columnRefNames returns the names of the fields of ref, or nil if it ends
+// with a star.
+func columnRefNames(ref *pg_query.ColumnRef) []*pg_query.String {
+
+	var ret []*pg_query.String
+	for _, f := range ref.Fields {
+		s := f.GetString_()
+		if s == nil {
+			return nil
+		}
+		ret = append(ret, s)
+	}
+	return ret
+}
+
+// renameTableInViews rewrites the views using t for t being renamed to
+// newName. Postgres keeps views as parsed queries, so they follow the
+// table, and so must their statements here.
+func (c *Compiler) renameTableInViews(t *Table, newName string) error {
+
+	for _, view := range c.dependentViews(t) {
+		err := c.rewriteView(view, func(stmt *pg_query.ViewStmt) {
+			c.renameInQuery(stmt.Query, map[*Table]string{t: newName}, nil)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameColumnInViews rewrites the views using col for col being renamed
+// to newName.
+func (c *Compiler) renameColumnInViews(col *Column, newName string) error {
+
+	for _, view := range c.dependentViews(col) {
+		err := c.rewriteView(view, func(stmt *pg_query.ViewStmt) {
+			c.renameInQuery(stmt.Query, nil, map[*Column]string{col: newName})
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// renameInQuery rewrites query for the tables and columns given being
+// renamed to the names they map to. References to renamed columns which
+// name a column of the query are given the old name as an alias, so the
+// query's columns keep their names. As in queryDependencies, an
+// unqualified reference is taken to be to a column of any table in the
+// query which has a column of that name.
+func (c *Compiler) renameInQuery(query *pg_query.Node, tables map[*Table]string, columns map[*Column]string) {
+
+	rels, refs := c.queryTables(query)
+	// Columns are qualified by their table's name where it has no alias
+	aliases := make(map[string]*Table)
+	named := make(map[*Table]bool)
+	var scope []*Table
+	for rv, t := range rels {
+		aliases[t.Name] = t
+		if rv.Alias != nil {
+			aliases[rv.Alias.Aliasname] = t
+		} else {
+			named[t] = true
+		}
+		scope = append(scope, t)
+	}
+	renamedCols := make(map[*pg_query.ColumnRef]*Column)
+	renamedTables := make(map[*pg_query.ColumnRef]*Table)
+	for _, ref := range refs {
+		names := columnRefNames(ref)
+		if len(names) == 0 {
+			continue
+		}
+		inScope := scope
+		if len(names) > 1 {
+			t, ok := aliases[names[len(names)-2].Sval]
+			if !ok || len(names) > 2 && names[len(names)-3].Sval != t.Schema {
+				continue
+			}
+			inScope = []*Table{t}
+			if _, ok := tables[t]; ok && named[t] && names[len(names)-2].Sval == t.Name {
+				renamedTables[ref] = t
+			}
+		}
+		for _, t := range inScope {
+			if col, ok := t.Columns.Get(names[len(names)-1].Sval); ok {
+				if _, ok := columns[col]; ok {
+					renamedCols[ref] = col
+				}
+				break
+			}
+		}
+	}
+
+	walkNodes(query.ProtoReflect(), func(m protoreflect.Message) {
+		if rt, ok := m.Interface().(*pg_query.ResTarget); ok && rt.Name == "" {
+			if col, ok := renamedCols[rt.Val.GetColumnRef()]; ok {
+				rt.Name = col.Name
+			}
+		}
+	})
+	for ref, col := range renamedCols {
+		names := columnRefNames(ref)
+		names[len(names)-1].Sval = columns[col]
+	}
+	for ref, t := range renamedTables {
+		names := columnRefNames(ref)
+		names[len(names)-2].Sval = tables[t]
+	}
+	for rv, t := range rels {
+		if newName, ok := tables[t]; ok {
+			rv.Relname = newName
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestCompiler_Views(t *testing.T) {
+	const schema = `
+	CREATE TABLE users (id int PRIMARY KEY, email text, name text, legacy text);
+	CREATE TABLE posts (id int PRIMARY KEY, author int REFERENCES users (id), title text);
+	CREATE VIEW named AS SELECT u.id, name FROM users u WHERE u.name IS NOT NULL;
+	CREATE VIEW titles AS WITH t AS (SELECT title FROM posts) SELECT * FROM t;
+	CREATE VIEW everything AS SELECT * FROM named JOIN posts p ON p.author = named.id;
+	`
+	c := assertParse(t, schema)
+	require.Len(t, c.Catalog.Raw, 3)
+
+	// Columns the views don't use can be dropped
+	require.Nil(t, c.Compile(`ALTER TABLE users DROP COLUMN legacy, DROP COLUMN email`))
+
+	assertParseError(t, schema+`ALTER TABLE users DROP COLUMN name;`,
+		"can't drop name because other objects depend on it: view public.named")
+	assertParseError(t, schema+`DROP TABLE posts;`,
+		"can't drop table posts because other objects depend on it and cascade was not specified: view public.titles, view public.everything")
+	assertParseError(t, schema+`DROP VIEW named;`,
+		"can't drop view public.named because other objects depend on it and cascade was not specified: view public.everything")
+	assertParseError(t, schema+`CREATE VIEW users AS SELECT 1;`, "relation public.users already exists")
+	assertParseError(t, schema+`CREATE VIEW named AS SELECT 1;`, "view public.named already exists")
+	assertParseError(t, `DROP VIEW missing`, "view public.missing not found")
+
+	// Replacing a view keeps the views depending on it
+	require.Nil(t, c.Compile(`CREATE OR REPLACE VIEW named AS SELECT id, name FROM users`))
+	assert.Equal(t, "CREATE OR REPLACE VIEW named AS SELECT id, name FROM users", c.Catalog.Raw[0].SQL)
+	assert.Len(t, c.Catalog.Depends.DependentsOf(c.Catalog.Raw[0]), 1)
+
+	// Dropping a table with CASCADE drops the views using it, and the views
+	// using those
+	require.Nil(t, c.Compile(`DROP TABLE users CASCADE`))
+	var names []string
+	for _, raw := range c.Catalog.Raw {
+		names = append(names, raw.Name)
+	}
+	assert.Equal(t, []string{"public.titles"}, names)
+	require.Nil(t, c.Compile(`DROP VIEW titles; DROP VIEW IF EXISTS titles`))
+	assert.Empty(t, c.Catalog.Raw)
+}
+
+func TestDependents_Views(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE users (id int PRIMARY KEY, name text);
+	CREATE VIEW named AS SELECT name FROM users;
+	CREATE VIEW upper_named AS SELECT upper(name) FROM named;
+	`)
+	obj, err := findObject(c, "column", "users.name")
+	require.Nil(t, err)
+	var sb strings.Builder
+	writeDependents(&sb, c.Catalog.Dependents(obj), 0)
+	assert.Equal(t, "view public.named\n  view public.upper_named\n", sb.String())
+}
+
+func TestCompiler_ReplaceView(t *testing.T) {
+	const schema = `
+	CREATE TABLE users (id int PRIMARY KEY, email varchar(100), created timestamptz);
+	CREATE VIEW recent (user_id, email) AS SELECT u.id, u.email::text FROM users u;
+	`
+	c := assertParse(t, schema)
+	users := assertTable(t, c, "users")
+	col := func(name string) Columns {
+		col, _ := users.Columns.Get(name)
+		return Columns{col}
+	}
+	assert.Equal(t, []*ViewColumn{{Name: "user_id", Type: "integer", Sources: col("id")}, {Name: "email", Type: "text", Sources: col("email")}}, c.Catalog.Raw[0].Columns)
+
+	// Columns can be added at the end, and of types which couldn't be resolved
+	require.Nil(t, c.Compile(`CREATE OR REPLACE VIEW recent AS SELECT id AS user_id, app.normalize(email) AS email, created, 1 AS one FROM users`))
+	assert.Equal(t, []*ViewColumn{
+		{Name: "user_id", Type: "integer", Sources: col("id")},
+		{Name: "email", Sources: col("email")},
+		{Name: "created", Type: "timestamp with time zone", Sources: col("created")},
+		{Name: "one", Type: "integer"},
+	}, c.Catalog.Raw[0].Columns)
+
+	assertParseError(t, schema+`CREATE OR REPLACE VIEW recent AS SELECT id FROM users;`, "cannot drop columns from view")
+	assertParseError(t, schema+`CREATE OR REPLACE VIEW recent AS SELECT email, id FROM users;`,
+		`cannot change name of view column "user_id" to "email"`)
+	assertParseError(t, schema+`CREATE OR REPLACE VIEW recent AS SELECT id AS user_id, email FROM users;`,
+		`cannot change data type of view column "email" from text to character varying(100)`)
+
+	c = assertParse(t, `
+	CREATE TABLE users (id int, name text);
+	CREATE VIEW named AS WITH n AS (SELECT id, name AS label FROM users) SELECT * FROM n JOIN (SELECT 1) AS x (one) ON true;
+	CREATE VIEW everything AS SELECT * FROM named, users;
+	`)
+	users = assertTable(t, c, "users")
+	assert.Equal(t, []*ViewColumn{{Name: "id", Type: "integer", Sources: col("id")}, {Name: "label", Type: "text", Sources: col("name")}, {Name: "one", Type: "integer"}}, c.Catalog.Raw[0].Columns)
+	assert.Len(t, c.Catalog.Raw[1].Columns, 5)
+}
+
+func TestCompiler_AlterView(t *testing.T) {
+	c := assertParse(t, `
+	CREATE SCHEMA reports;
+	CREATE TABLE users (id int, name text);
+	CREATE VIEW named AS SELECT id, name FROM users;
+	CREATE VIEW upper_named AS SELECT upper(name) FROM named;
+	ALTER VIEW named RENAME TO people;
+	ALTER VIEW people RENAME COLUMN name TO full_name;
+	ALTER VIEW people SET SCHEMA reports;
+	ALTER VIEW IF EXISTS missing RENAME TO other;
+	`)
+	var sqls []string
+	for _, raw := range c.Catalog.Raw {
+		sqls = append(sqls, raw.Name+": "+raw.SQL)
+	}
+	assert.Equal(t, []string{
+		"reports.people: CREATE VIEW reports.people (id, full_name) AS SELECT id, name FROM users",
+		"public.upper_named: CREATE VIEW upper_named AS SELECT upper(name) FROM reports.people",
+	}, sqls)
+	assert.Equal(t, "full_name", c.Catalog.Raw[0].Columns[1].Name)
+	// The view is still depended on
+	require.NotNil(t, c.Compile(`DROP VIEW reports.people`))
+
+	assertParseError(t, `CREATE VIEW v AS SELECT 1 AS a, 2 AS b; ALTER VIEW v RENAME COLUMN a TO b`, "column b of view public.v already exists")
+	assertParseError(t, `CREATE TABLE t (id int); CREATE VIEW v AS SELECT 1; ALTER VIEW v RENAME TO t`, "relation public.t already exists")
+	assertParseError(t, `CREATE VIEW v AS SELECT 1; ALTER VIEW v SET SCHEMA nope`, "no such schema: nope")
+}
+
+func TestCompiler_RenamesInViews(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE t (a int, b int);
+	CREATE TABLE u (id int, a int);
+	CREATE VIEW v AS SELECT a FROM t;
+	CREATE VIEW w AS SELECT t.a, x.b, u.a AS ua FROM t x, t, u WHERE public.t.b > 0;
+	ALTER TABLE t RENAME TO t2;
+	CREATE TABLE t (q int);
+	ALTER TABLE t2 RENAME COLUMN a TO a2;
+	ALTER TABLE t2 RENAME COLUMN b TO b2;
+	`)
+	var sqls []string
+	for _, raw := range c.Catalog.Raw {
+		sqls = append(sqls, raw.Name+": "+raw.SQL)
+	}
+	assert.Equal(t, []string{
+		"public.v: CREATE VIEW v AS SELECT a2 AS a FROM t2",
+		"public.w: CREATE VIEW w AS SELECT t2.a2 AS a, x.b2 AS b, u.a AS ua FROM t2 x, t2, u WHERE public.t2.b2 > 0",
+	}, sqls)
+	// The views still depend on the renamed table
+	require.NotNil(t, c.Compile(`DROP TABLE t2`))
+	require.Nil(t, c.Compile(`DROP TABLE t`))
+}
+
+func TestCompiler_ViewColumnsOfStars(t *testing.T) {
+	c := assertParse(t, `
+	CREATE TABLE t (id int);
+	CREATE VIEW v AS SELECT t.*::text FROM t;
+	`)
+	require.Len(t, c.Catalog.Raw, 1)
+	assert.Equal(t, "?column?", c.Catalog.Raw[0].Columns[0].Name)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+	"google.golang.org/protobuf/proto"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// ParseCache holds parsed files, so that compiling files again only parses
+// those which have changed. Files are assumed unchanged while their size
+// and modification time are.
+type ParseCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedParse
+}
+
+type cachedParse struct {
+	stamp  fileStamp
+	parsed *ParsedSource
+}
+
+// fileStamp is what's compared to tell whether a file has changed.
+type fileStamp struct {
+	size    int64
+	modTime int64
+}
+
+func stampOf(info fs.FileInfo) fileStamp {
+
+	return fileStamp{size: info.Size(), modTime: info.ModTime().UnixNano()}
+}
+
+func NewParseCache() *ParseCache {
+
+	return &ParseCache{entries: make(map[string]cachedParse)}
+}
+
+// get returns the parse of the file at path if info shows it hasn't
+// changed since. A nil cache holds nothing.
+func (p *ParseCache) get(path string, info fs.FileInfo) *ParsedSource {
+
+	if p == nil {
+		return nil
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	e, ok := p.entries[path]
+	if !ok || e.stamp != stampOf(info) {
+		return nil
+	}
+	// The catalog keeps and modifies parts of the parse tree, such as the
+	// expressions of indexes, so each compile needs its own
+	parsed := *e.parsed
+	parsed.parse = proto.Clone(e.parsed.parse).(*pg_query.ParseResult)
+	return &parsed
+}
+
+func (p *ParseCache) put(path string, info fs.FileInfo, parsed *ParsedSource) {
+
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	stored := *parsed
+	stored.parse = proto.Clone(parsed.parse).(*pg_query.ParseResult)
+	p.entries[path] = cachedParse{stamp: stampOf(info), parsed: &stored}
+}
+
+// watchInterval is how often Watch checks for changes.
+const watchInterval = 500 * time.Millisecond
+
+// Watch calls changed whenever the files of paths, or the files directly
+// inside directories of paths, are created, modified or removed, until done
+// is closed. Files are polled every interval.
+func Watch(paths []string, interval time.Duration, done <-chan struct{}, changed func()) {
+
+	last := watchSnapshot(paths)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+		}
+		snapshot := watchSnapshot(paths)
+		if !maps.Equal(last, snapshot) {
+			last = snapshot
+			changed()
+		}
+	}
+}
+
+// watchSnapshot records the size and modification time of each file.
+// Files which can't be read are left out, so that they count as changed
+// once they can be.
+func watchSnapshot(paths []string) map[string]fileStamp {
+
+	ret := make(map[string]fileStamp)
+	add := func(path string) {
+		info, err := os.Stat(path)
+		if err == nil {
+			ret[path] = stampOf(info)
+		}
+	}
+	for _, path := range paths {
+		add(path)
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			continue
+		}
+		for _, e := range entries {
+			if !e.IsDir() {
+				add(filepath.Join(path, e.Name()))
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompiler_ParseCache(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, sql string) {
+		require.Nil(t, os.WriteFile(filepath.Join(dir, name), []byte(sql), 0o644))
+	}
+	write("001.sql", "CREATE TABLE t (a int, b int);\nCREATE INDEX t_sum ON t ((a + b));\n")
+	write("002.sql", "ALTER TABLE t RENAME COLUMN a TO c;\n")
+
+	cache := NewParseCache()
+	compile := func() string {
+		c := NewCompiler()
+		c.ParseCache = cache
+		require.Nil(t, c.CompileFiles([]string{dir}))
+		var sb strings.Builder
+		require.Nil(t, (&DDLGenerator{}).Generate(&sb, c.Catalog))
+		return sb.String()
+	}
+	first := compile()
+	assert.Contains(t, first, "((c + b))")
+	assert.Len(t, cache.entries, 2)
+	// Renaming the column in the first compile mustn't affect the second
+	assert.Equal(t, first, compile())
+
+	write("002.sql", "ALTER TABLE t RENAME COLUMN b TO d;\n")
+	// Make sure the modification time changes however coarse it is
+	later := time.Now().Add(time.Minute)
+	require.Nil(t, os.Chtimes(filepath.Join(dir, "002.sql"), later, later))
+	assert.Contains(t, compile(), "((a + d))")
+}
+
+func TestWatch(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "001.sql")
+	require.Nil(t, os.WriteFile(path, []byte("CREATE TABLE t (a int);\n"), 0o644))
+
+	done := make(chan struct{})
+	changed := make(chan struct{}, 10)
+	go Watch([]string{dir}, 10*time.Millisecond, done, func() { changed <- struct{}{} })
+	defer close(done)
+
+	select {
+	case <-changed:
+		t.Fatal("changed before any change")
+	case <-time.After(50 * time.Millisecond):
+	}
+	require.Nil(t, os.WriteFile(filepath.Join(dir, "002.sql"), []byte("CREATE TABLE u (a int);\n"), 0o644))
+	select {
+	case <-changed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("no change seen after adding a file")
+	}
+}
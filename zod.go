@@ -0,0 +1,332 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// ZodGenerator writes a Zod schema for each of the catalog's ValueSets and
+// tables, with the TypeScript types inferred from them, so that values can
+// be validated by the rules the database would apply to them. Besides the
+// columns' types, lengths and nullability, the parts of check constraints
+// which CheckRules understands refine the columns they're on; the others
+// are noted in a comment. Each table also gets a schema for inserting
+// rows, in which the columns the database can fill in are optional.
+type ZodGenerator struct{}
+
+func NewZodGenerator(_ *flag.FlagSet) Generator {
+
+	return &ZodGenerator{}
+}
+
+// zodIntegerRanges are the bounds of the integer types. Bigints are left
+// unbounded, as their bounds aren't numbers JavaScript can hold exactly.
+var zodIntegerRanges = map[*PostgresType][2]string{
+	Smallint:    {"-32768", "32767"},
+	Smallserial: {"-32768", "32767"},
+	Integer:     {"-2147483648", "2147483647"},
+	Serial:      {"-2147483648", "2147483647"},
+}
+
+// zodTypes are the schemas of the types which aren't numbers or strings,
+// or are strings of a particular form. Types which aren't present are
+// strings.
+var zodTypes = map[*PostgresType]string{
+	Boolean:     "z.boolean()",
+	Bytea:       "z.instanceof(Uint8Array)",
+	Date:        "z.coerce.date()",
+	JSON:        "z.unknown()",
+	JSONB:       "z.unknown()",
+	Timestamp:   "z.coerce.date()",
+	Timestamptz: "z.coerce.date()",
+	UUID:        "z.string().uuid()",
+}
+
+func zodNumber(typ *PostgresType) bool {
+
+	_, ok := serialTypes[typ]
+	return ok || slices.Contains([]*PostgresType{Smallint, Integer, Bigint, Numeric, Real, Double}, typ)
+}
+
+func zodString(typ *PostgresType) bool {
+
+	return typ == Text || typ == CharacterVarying || typ == Character
+}
+
+// zodApplies reports whether rule can refine the schema of its column.
+func zodApplies(rule *CheckRule) bool {
+
+	col := rule.Column
+	if col.ArrayDims > 0 {
+		return false
+	}
+	switch rule.Kind {
+	case CheckRuleMin, CheckRuleMax:
+		return zodNumber(col.Type)
+	case CheckRuleMinLength, CheckRuleMaxLength, CheckRulePattern:
+		return zodString(col.Type)
+	default:
+		return zodNumber(col.Type) || zodString(col.Type)
+	}
+}
+
+// zodNames names the schemas and types of objects, qualifying those whose
+// names are used in more than one schema.
+type zodNames struct {
+	counts map[string]int
+}
+
+func (n *zodNames) name(schema, name string) (schemaName, typeName string) {
+
+	if n.counts[name] > 1 && schema != "public" {
+		name = schema + "_" + name
+	}
+	return camelCase(name, false) + "Schema", camelCase(name, true)
+}
+
+func (g *ZodGenerator) Generate(w io.Writer, cat *Catalog) error {
+
+	sets := cat.ValueSets()
+	names := &zodNames{counts: make(map[string]int)}
+	for _, set := range sets {
+		names.counts[set.Name]++
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			names.counts[singularize(t.Name)]++
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "// Generated by pgmodelgen.")
+	fmt.Fprintln(bw, `import { z } from "zod";`)
+	enums := make(map[*Column]string)
+	for _, set := range sets {
+		schemaName, typeName := names.name(set.Schema, set.Name)
+		for _, col := range set.Columns {
+			enums[col] = schemaName
+		}
+		schema := "z.enum([" + tsStrings(set.Values) + "])"
+		if len(set.Values) == 0 {
+			schema = "z.never()"
+		}
+		fmt.Fprintln(bw)
+		fmt.Fprintf(bw, "export const %s = %s;\n", schemaName, schema)
+		fmt.Fprintf(bw, "export type %s = z.infer<typeof %s>;\n", typeName, schemaName)
+	}
+	for _, sch := range cat.Schemas.List() {
+		for _, t := range sch.Tables.List() {
+			if t.PartitionOf == nil && t.Extension == "" {
+				schemaName, typeName := names.name(sch.Name, singularize(t.Name))
+				g.writeTable(bw, cat, t, schemaName, typeName, enums)
+			}
+		}
+	}
+	return bw.Flush()
+}
+
+func (g *ZodGenerator) writeTable(w io.Writer, cat *Catalog, t *Table, schemaName, typeName string, enums map[*Column]string) {
+
+	rules := make(map[*Column][]*CheckRule)
+	fmt.Fprintln(w)
+	for _, con := range cat.Depends.TableConstraints(t) {
+		if con.Type != ConstraintTypeCheck {
+			continue
+		}
+		conRules, ok := CheckRules(con)
+		if ok && !slices.ContainsFunc(conRules, func(rule *CheckRule) bool { return !zodApplies(rule) }) {
+			for _, rule := range conRules {
+				rules[rule.Column] = append(rules[rule.Column], rule)
+			}
+			continue
+		}
+		fmt.Fprintf(w, "// %s isn't validated: CHECK (%s)\n", con.Name, con.Expr)
+	}
+
+	var omit, optional []string
+	fmt.Fprintf(w, "export const %s = z.object({\n", schemaName)
+	for _, col := range t.Columns.List() {
+		fmt.Fprintf(w, "  %s: %s,\n", tsKey(col.Name), g.columnSchema(col, rules[col], enums))
+		switch {
+		case col.Attrs.Identity == IdentityAlways:
+			omit = append(omit, tsKey(col.Name)+": true")
+		case !col.Attrs.NotNull && !col.Attrs.Pkey, col.Attrs.Default != "", col.generatesValues():
+			optional = append(optional, tsKey(col.Name)+": true")
+		}
+	}
+	fmt.Fprintln(w, "});")
+	fmt.Fprintf(w, "export type %s = z.infer<typeof %s>;\n", typeName, schemaName)
+	insert := schemaName
+	if len(omit) > 0 {
+		insert += ".omit({ " + strings.Join(omit, ", ") + " })"
+	}
+	if len(optional) > 0 {
+		insert += ".partial({ " + strings.Join(optional, ", ") + " })"
+	}
+	fmt.Fprintf(w, "export const new%sSchema = %s;\n", typeName, insert)
+	fmt.Fprintf(w, "export type New%s = z.infer<typeof new%sSchema>;\n", typeName, typeName)
+}
+
+// columnSchema returns the schema of col, which is that of its ValueSet,
+// in enums, if it's limited to one.
+func (g *ZodGenerator) columnSchema(col *Column, rules []*CheckRule, enums map[*Column]string) string {
+
+	var schema string
+	var oneOf []string
+	for _, rule := range rules {
+		if rule.Kind != CheckRuleOneOf {
+			continue
+		}
+		if oneOf == nil {
+			oneOf = rule.Values
+		} else {
+			oneOf = slices.DeleteFunc(slices.Clone(oneOf), func(v string) bool { return !slices.Contains(rule.Values, v) })
+		}
+	}
+	enum, isEnum := enums[col]
+	switch {
+	case isEnum:
+		schema = enum
+	case zodNumber(col.Type):
+		{
+			if oneOf != nil {
+				schema = zodLiterals(oneOf)
+				break
+			}
+			schema = "z.number()"
+			var low, high checkBound
+			if _, ok := serialTypes[col.Type]; ok || col.Type == Smallint || col.Type == Integer || col.Type == Bigint {
+				schema += ".int()"
+			}
+			if r, ok := zodIntegerRanges[col.Type]; ok {
+				low.tighten(r[0], false, false)
+				high.tighten(r[1], false, true)
+			}
+			if col.Type == Numeric && len(col.TypeMods) > 0 {
+				scale := int32(0)
+				if len(col.TypeMods) > 1 {
+					scale = col.TypeMods[1]
+				}
+				limit := fmt.Sprintf("1e%d", col.TypeMods[0]-scale)
+				low.tighten("-"+limit, true, false)
+				high.tighten(limit, true, true)
+			}
+			for _, rule := range rules {
+				switch rule.Kind {
+				case CheckRuleMin:
+					low.tighten(rule.Value, rule.Exclusive, false)
+				case CheckRuleMax:
+					high.tighten(rule.Value, rule.Exclusive, true)
+				}
+			}
+			if op := low.op("gt"); op != "" {
+				schema += "." + op + "(" + low.value + ")"
+			}
+			if op := high.op("lt"); op != "" {
+				schema += "." + op + "(" + high.value + ")"
+			}
+		}
+	case zodTypes[col.Type] != "":
+		schema = zodTypes[col.Type]
+	default:
+		{
+			if oneOf != nil {
+				schema = "z.enum([" + tsStrings(oneOf) + "])"
+				if len(oneOf) == 0 {
+					schema = "z.never()"
+				}
+				break
+			}
+			schema = "z.string()"
+			minLength, maxLength := -1, -1
+			if (col.Type == CharacterVarying || col.Type == Character) && len(col.TypeMods) > 0 {
+				maxLength = int(col.TypeMods[0])
+			}
+			var patterns []string
+			for _, rule := range rules {
+				n, _ := strconv.Atoi(rule.Value)
+				switch rule.Kind {
+				case CheckRuleMinLength:
+					minLength = max(minLength, n)
+				case CheckRuleMaxLength:
+					if maxLength < 0 || n < maxLength {
+						maxLength = n
+					}
+				case CheckRulePattern:
+					patterns = append(patterns, rule.Value)
+				}
+			}
+			if minLength > 0 {
+				schema += fmt.Sprintf(".min(%d)", minLength)
+			}
+			if maxLength >= 0 {
+				schema += fmt.Sprintf(".max(%d)", maxLength)
+			}
+			for _, p := range patterns {
+				schema += ".regex(new RegExp(" + tsString(p) + "))"
+			}
+		}
+	}
+	for range col.ArrayDims {
+		schema = "z.array(" + schema + ")"
+	}
+	if !col.Attrs.NotNull && !col.Attrs.Pkey {
+		schema += ".nullable()"
+	}
+	return schema
+}
+
+// zodLiterals returns the schema of one of the numbers values.
+func zodLiterals(values []string) string {
+
+	switch len(values) {
+	case 0:
+		return "z.never()"
+	case 1:
+		return "z.literal(" + values[0] + ")"
+	}
+	lits := make([]string, 0, len(values))
+	for _, v := range values {
+		lits = append(lits, "z.literal("+v+")")
+	}
+	return "z.union([" + strings.Join(lits, ", ") + "])"
+}
+
+var tsIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// tsKey returns name as the key of an object literal, quoting it unless
+// it's an identifier.
+func tsKey(name string) string {
+
+	if tsIdentifier.MatchString(name) {
+		return name
+	}
+	return tsString(name)
+}
+
+// tsString quotes s as a TypeScript string literal.
+func tsString(s string) string {
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
+
+func tsStrings(ss []string) string {
+
+	quoted := make([]string, 0, len(ss))
+	for _, s := range ss {
+		quoted = append(quoted, tsString(s))
+	}
+	return strings.Join(quoted, ", ")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"strings"
+	"testing"
+)
+
+func TestZodGenerator_Generate(t *testing.T) {
+	const sql = `
+	CREATE TYPE mood AS ENUM ('happy', 'sad');
+	CREATE TABLE users (
+		id int GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+		username varchar(50) NOT NULL CHECK (length(username) >= 3),
+		email text NOT NULL CHECK (email ~ '^[^@]+@[^@]+$' AND email <> ''),
+		age smallint CHECK (age BETWEEN 0 AND 150),
+		balance numeric(10, 2) NOT NULL DEFAULT 0 CHECK (balance > 0),
+		status text NOT NULL CHECK (status IN ('active', 'banned')),
+		tier int CHECK (tier = ANY (ARRAY[1, 2, 3])),
+		mood mood,
+		tags text[],
+		created_at timestamptz NOT NULL DEFAULT now(),
+		"display-name" text,
+		CHECK (age < balance)
+	);
+	`
+	c := assertParse(t, sql)
+	var sb strings.Builder
+	require.Nil(t, (&ZodGenerator{}).Generate(&sb, c.Catalog))
+	assert.Equal(t, `// Generated by pgmodelgen.
+import { z } from "zod";
+
+export const moodSchema = z.enum(["happy", "sad"]);
+export type Mood = z.infer<typeof moodSchema>;
+
+export const userStatusSchema = z.enum(["active", "banned"]);
+export type UserStatus = z.infer<typeof userStatusSchema>;
+
+// users_age_check1 isn't validated: CHECK (age < balance)
+export const userSchema = z.object({
+  id: z.number().int().gte(-2147483648).lte(2147483647),
+  username: z.string().min(3).max(50),
+  email: z.string().min(1).regex(new RegExp("^[^@]+@[^@]+$")),
+  age: z.number().int().gte(0).lte(150).nullable(),
+  balance: z.number().gt(0).lt(1e8),
+  status: userStatusSchema,
+  tier: z.union([z.literal(1), z.literal(2), z.literal(3)]).nullable(),
+  mood: moodSchema.nullable(),
+  tags: z.array(z.string()).nullable(),
+  created_at: z.coerce.date(),
+  "display-name": z.string().nullable(),
+});
+export type User = z.infer<typeof userSchema>;
+export const newUserSchema = userSchema.omit({ id: true }).partial({ age: true, balance: true, tier: true, mood: true, tags: true, created_at: true, "display-name": true });
+export type NewUser = z.infer<typeof newUserSchema>;
+`, sb.String())
+}